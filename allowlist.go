@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// droppedAttributesKey counts the attributes removed by the allowlist
+const droppedAttributesKey = "otel_logger.dropped_attributes"
+
+// attributeAllowlist keeps only explicitly named attributes. A name ending
+// in ".*" allows every attribute under that prefix.
+type attributeAllowlist struct {
+	patterns fieldPatterns
+	count    bool
+	audit    *ruleAudit
+}
+
+func newAttributeAllowlist(names []string, count bool) *attributeAllowlist {
+	return &attributeAllowlist{patterns: newFieldPatterns(names), count: count}
+}
+
+func (a *attributeAllowlist) allows(key string) bool {
+	return a.patterns.matches(key)
+}
+
+// fieldPatterns matches keys by exact name, or by prefix for names ending in *
+type fieldPatterns struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+func newFieldPatterns(names []string) fieldPatterns {
+	patterns := fieldPatterns{names: make(map[string]bool)}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			patterns.prefixes = append(patterns.prefixes, prefix)
+		} else if name != "" {
+			patterns.names[name] = true
+		}
+	}
+	return patterns
+}
+
+func (p fieldPatterns) matches(key string) bool {
+	if p.names[key] {
+		return true
+	}
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter drops attributes that are not allowed, in place
+func (a *attributeAllowlist) filter(attrs []log.KeyValue) []log.KeyValue {
+	kept := attrs[:0]
+	for _, kv := range attrs {
+		if a.allows(kv.Key) {
+			kept = append(kept, kv)
+		}
+	}
+
+	dropped := len(attrs) - len(kept)
+	a.audit.hit("attr-allowlist", dropped)
+	if a.count && dropped > 0 {
+		kept = append(kept, log.Int(droppedAttributesKey, dropped))
+	}
+	return kept
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLogProcessorAttributeAllowlist(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		count     bool
+		expected  map[string]string
+	}{
+		{
+			name:      "exact names",
+			allowlist: []string{"user_id", "log.iostream"},
+			expected:  map[string]string{"user_id": "42", "log.iostream": "stdout"},
+		},
+		{
+			name:      "prefix wildcard",
+			allowlist: []string{"http.*"},
+			expected:  map[string]string{"http.method": "GET", "http.status": "200"},
+		},
+		{
+			name:      "dropped attributes are counted",
+			allowlist: []string{"user_id"},
+			count:     true,
+			expected:  map[string]string{"user_id": "42", droppedAttributesKey: "5"},
+		},
+		{
+			name:      "nothing allowed",
+			allowlist: []string{"missing"},
+			expected:  map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, exporter := newRecordingProvider()
+			processor := NewLogProcessor(provider.Logger("test"))
+			processor.SetAttributeAllowlist(newAttributeAllowlist(tt.allowlist, tt.count))
+
+			extractor := NewJSONExtractor("", getDefaultFieldMappings())
+			entry, _ := extractor.ParseLogEntry(`{"msg":"request","user_id":42,"email":"a@example.com","http.method":"GET","http.status":200}`)
+			entry.Stream, entry.TimestampSource = "stdout", ""
+			processor.ProcessLogEntry(context.Background(), entry)
+
+			records := exporter.Records()
+			if len(records) != 1 {
+				t.Fatalf("Expected 1 record, got %d", len(records))
+			}
+			if got := recordAttributes(records[0]); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected attributes %v, got %v", tt.expected, got)
+			}
+			if records[0].Body().String() != "request" {
+				t.Errorf("Expected body to be kept, got %q", records[0].Body().String())
+			}
+		})
+	}
+}
+
+func TestAttributeAllowlistKeepsOwnAttributes(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetAttributeAllowlist(newAttributeAllowlist([]string{"user_id"}, false))
+	processor.SetPipelineAnnotation(pipelineAttributes(&FileConfig{}))
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, _ := extractor.ParseLogEntry(`{"time":"2024-01-15T10:30:45Z","msg":"request","user_id":42,"email":"a@example.com","log.iostream":"app"}`)
+	entry.Stream = "stdout"
+	processor.emitEntry(context.Background(), entry, 0.5)
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	attrs := recordAttributes(records[0])
+	for _, key := range []string{"user_id", samplingRatioKey, timestampSourceKey, duplicateKeysKey, pipelineVersionKey, pipelineConfigHashKey} {
+		if _, ok := attrs[key]; !ok {
+			t.Errorf("Expected %s to be exported, got %v", key, attrs)
+		}
+	}
+	for _, key := range []string{"email", "log.iostream"} {
+		if _, ok := attrs[key]; ok {
+			t.Errorf("Expected %s to be dropped, got %v", key, attrs)
+		}
+	}
+}
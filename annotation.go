@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// Attributes of --annotate-pipeline, which identify the shipper that parsed a
+// record so backend queries can be segmented across a rollout
+const (
+	pipelineVersionKey    = "otel_logger.version"
+	pipelineConfigHashKey = "otel_logger.config.hash"
+	pipelinePresetKey     = "otel_logger.preset"
+)
+
+// pipelineAnnotation holds the attributes added to every record. They change
+// when the config file is reloaded.
+type pipelineAnnotation struct {
+	mu    sync.RWMutex
+	attrs []log.KeyValue
+}
+
+// pipelineAttributes describes the effective parsing settings: the otel-logger
+// version, a hash of the settings with any preset applied, and the preset
+func pipelineAttributes(settings *FileConfig) []log.KeyValue {
+	attrs := []log.KeyValue{
+		log.String(pipelineVersionKey, version),
+		log.String(pipelineConfigHashKey, settingsHash(settings)),
+	}
+	if !isParserFormat(settings.Format) {
+		attrs = append(attrs, log.String(pipelinePresetKey, settings.Format))
+	}
+	return attrs
+}
+
+// settingsHash is a short hash of the settings that changes whenever they
+// would parse lines differently, including edits to a preset between versions
+func settingsHash(settings *FileConfig) string {
+	data, err := json.Marshal(settings.withPreset())
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+func (a *pipelineAnnotation) set(attrs []log.KeyValue) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attrs = attrs
+}
+
+func (a *pipelineAnnotation) get() []log.KeyValue {
+	if a == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.attrs
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipelineAttributes(t *testing.T) {
+	version = "1.2.3"
+	defer func() { version = "dev" }()
+
+	attrs := map[string]string{}
+	for _, kv := range pipelineAttributes(&FileConfig{Format: "pino"}) {
+		attrs[kv.Key] = kv.Value.AsString()
+	}
+	if attrs[pipelineVersionKey] != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, got %q", attrs[pipelineVersionKey])
+	}
+	if attrs[pipelinePresetKey] != "pino" {
+		t.Errorf("Expected preset pino, got %q", attrs[pipelinePresetKey])
+	}
+	if len(attrs[pipelineConfigHashKey]) != 12 {
+		t.Errorf("Expected a 12 character hash, got %q", attrs[pipelineConfigHashKey])
+	}
+
+	for _, kv := range pipelineAttributes(&FileConfig{Format: formatLogfmt}) {
+		if kv.Key == pipelinePresetKey {
+			t.Errorf("Expected no preset for a parser format, got %q", kv.Value.AsString())
+		}
+	}
+}
+
+func TestSettingsHash(t *testing.T) {
+	a := settingsHash(&FileConfig{LevelFields: []string{"level"}})
+	if a != settingsHash(&FileConfig{LevelFields: []string{"level"}}) {
+		t.Error("Expected equal settings to hash alike")
+	}
+	if a == settingsHash(&FileConfig{LevelFields: []string{"severity"}}) {
+		t.Error("Expected different settings to hash differently")
+	}
+	if settingsHash(&FileConfig{Format: "bunyan"}) == settingsHash(&FileConfig{}) {
+		t.Error("Expected a preset to change the hash")
+	}
+}
+
+func TestPipelineAnnotationReload(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetPipelineAnnotation(pipelineAttributes(&FileConfig{}))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("format: zap\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config := &Config{ConfigFile: path}
+	watcher := newConfigWatcher(config, extractor, processor, nil, &FileConfig{}, "")
+
+	entry := &LogEntry{Level: "info", Message: "before", Fields: map[string]any{}}
+	processor.ProcessLogEntry(context.Background(), entry)
+	watcher.check(context.Background())
+	entry = &LogEntry{Level: "info", Message: "after", Fields: map[string]any{}}
+	processor.ProcessLogEntry(context.Background(), entry)
+
+	records := exporter.Records()
+	before, after := recordAttributes(records[0]), recordAttributes(records[len(records)-1])
+	if _, ok := before[pipelinePresetKey]; ok {
+		t.Errorf("Expected no preset before the reload, got %v", before)
+	}
+	if after[pipelinePresetKey] != "zap" {
+		t.Errorf("Expected the reloaded preset, got %v", after)
+	}
+	if before[pipelineConfigHashKey] == after[pipelineConfigHashKey] {
+		t.Error("Expected the config hash to change on reload")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// --attr-order values
+const (
+	attrOrderSorted = "sorted" // parsed fields by key
+	attrOrderSource = "source" // parsed fields in the order the line has them
+)
+
+func validAttrOrder(order string) error {
+	switch order {
+	case "", attrOrderSorted, attrOrderSource:
+		return nil
+	default:
+		return fmt.Errorf("invalid --attr-order %q (supported: %s, %s)", order, attrOrderSorted, attrOrderSource)
+	}
+}
+
+// orderedKeys lists the keys of fields in the given source order. Keys the
+// source order does not know, such as fields derived while parsing, follow in
+// key order. Without a source order all keys are sorted.
+func orderedKeys(fields map[string]any, order []string) []string {
+	return orderedKeysInto(nil, fields, order)
+}
+
+// orderedKeysInto is orderedKeys appending to keys
+func orderedKeysInto(keys []string, fields map[string]any, order []string) []string {
+	keys = slices.Grow(keys, len(fields))
+	if len(order) == 0 {
+		keys = slices.AppendSeq(keys, maps.Keys(fields))
+		slices.Sort(keys)
+		return keys
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for _, key := range order {
+		if _, ok := fields[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == len(fields) {
+		return keys
+	}
+	var rest []string
+	for key := range fields {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(rest)
+	return append(keys, rest...)
+}
+
+// jsonKeyOrder returns the top-level keys of a JSON object in the order they
+// appear
+func jsonKeyOrder(s string) []string {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return keys
+		}
+		key, ok := token.(string)
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestJSONKeyOrder(t *testing.T) {
+	got := jsonKeyOrder(`{"z":1,"a":{"nested":[1,2]},"m":"x"}`)
+	if expected := []string{"z", "a", "m"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := jsonKeyOrder(`[1,2]`); got != nil {
+		t.Errorf("Expected no keys for an array, got %v", got)
+	}
+}
+
+func TestLogfmtKeyOrder(t *testing.T) {
+	got := logfmtKeyOrder(`z=1 a="two words" m=x`)
+	if expected := []string{"z", "a", "m"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestOrderedKeys(t *testing.T) {
+	fields := map[string]any{"b": 1, "a": 2, "derived2": 3, "derived1": 4}
+	tests := []struct {
+		name     string
+		order    []string
+		expected []string
+	}{
+		{"sorted", nil, []string{"a", "b", "derived1", "derived2"}},
+		{"source order then the rest sorted", []string{"msg", "b", "a", "b"}, []string{"b", "a", "derived1", "derived2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderedKeys(fields, tt.order); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func attributeKeys(record sdklog.Record) []string {
+	var keys []string
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		keys = append(keys, kv.Key)
+		return true
+	})
+	return keys
+}
+
+func TestLogProcessorAttributeOrder(t *testing.T) {
+	const line = `{"msg":"hi","zeta":1,"alpha":2,"mid":3,"beta":4}`
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{attrOrderSorted, []string{"alpha", "beta", "mid", "zeta", "log.iostream"}},
+		{attrOrderSource, []string{"zeta", "alpha", "mid", "beta", "log.iostream"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			provider, exporter := newRecordingProvider()
+			processor := NewLogProcessor(provider.Logger("test"))
+			processor.SetAttributeOrder(tt.order)
+			extractor := NewJSONExtractor("", getDefaultFieldMappings())
+			extractor.SetSourceOrder(tt.order == attrOrderSource)
+
+			// Repeat to catch map iteration order leaking through
+			for range 20 {
+				entry, _ := extractor.ParseLogEntry(line)
+				entry.Raw, entry.TimestampSource = "", ""
+				entry.Stream = "stdout"
+				processor.ProcessLogEntry(context.Background(), entry)
+			}
+
+			for i, record := range exporter.Records() {
+				if got := attributeKeys(record); !reflect.DeepEqual(got, tt.expected) {
+					t.Fatalf("Record %d: expected %v, got %v", i, tt.expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"text/tabwriter"
+
+	"go.opentelemetry.io/otel/log"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// bodyStatsKey stands for the record body in the attribute stats
+const bodyStatsKey = "(body)"
+
+// attrStats adds up how many OTLP bytes each attribute key contributes to the
+// exported records, so the fields driving ingest volume can be found before
+// writing drop or allowlist rules. Sizes are the protobuf encoding of the
+// key-value pair as sent to the collector.
+type attrStats struct {
+	mu      sync.Mutex
+	keys    map[string]*attrStat
+	records int64
+	top     int
+	w       io.Writer
+	closeFn func() error
+}
+
+type attrStat struct {
+	key     string
+	records int64
+	bytes   int64
+}
+
+// openAttrStats opens the report sink: "stderr", or a file that is replaced
+func openAttrStats(dest string, top int) (*attrStats, error) {
+	stats := &attrStats{keys: make(map[string]*attrStat), top: top}
+	if dest == "stderr" {
+		stats.w = os.Stderr
+		return stats, nil
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --attr-stats file: %w", err)
+	}
+	stats.w = file
+	stats.closeFn = file.Close
+	return stats, nil
+}
+
+// add accounts for one exported record; a nil attrStats does nothing
+func (s *attrStats) add(record log.Record) {
+	if s == nil {
+		return
+	}
+	sizes := make(map[string]int, record.AttributesLen()+1)
+	sizes[bodyStatsKey] = proto.Size(otlpValue(record.Body()))
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		sizes[kv.Key] += proto.Size(&commonpb.KeyValue{Key: kv.Key, Value: otlpValue(kv.Value)})
+		return true
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records++
+	for key, size := range sizes {
+		stat, ok := s.keys[key]
+		if !ok {
+			stat = &attrStat{key: key}
+			s.keys[key] = stat
+		}
+		stat.records++
+		stat.bytes += int64(size)
+	}
+}
+
+// report writes the keys with the most bytes, largest first
+func (s *attrStats) report() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]*attrStat, 0, len(s.keys))
+	var total int64
+	for _, stat := range s.keys {
+		stats = append(stats, stat)
+		total += stat.bytes
+	}
+	slices.SortFunc(stats, func(a, b *attrStat) int {
+		return cmp.Or(cmp.Compare(b.bytes, a.bytes), cmp.Compare(a.key, b.key))
+	})
+	if s.top > 0 && len(stats) > s.top {
+		stats = stats[:s.top]
+	}
+
+	fmt.Fprintf(s.w, "Attribute sizes over %d records (%s total):\n", s.records, formatBytes(total))
+	tw := tabwriter.NewWriter(s.w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "bytes\tshare\trecords\tavg\tkey")
+	for _, stat := range stats {
+		share := 0.0
+		if total > 0 {
+			share = float64(stat.bytes) * 100 / float64(total)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%d\t%d\t%s\n",
+			formatBytes(stat.bytes), share, stat.records, stat.bytes/stat.records, stat.key)
+	}
+	return tw.Flush()
+}
+
+func (s *attrStats) Close() error {
+	if s.closeFn == nil {
+		return nil
+	}
+	return s.closeFn()
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// otlpValue converts a log API value to its OTLP protobuf form
+func otlpValue(v log.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case log.KindBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case log.KindInt64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case log.KindFloat64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case log.KindString:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case log.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case log.KindSlice:
+		var values []*commonpb.AnyValue
+		for _, item := range v.AsSlice() {
+			values = append(values, otlpValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case log.KindMap:
+		var kvs []*commonpb.KeyValue
+		for _, kv := range v.AsMap() {
+			kvs = append(kvs, &commonpb.KeyValue{Key: kv.Key, Value: otlpValue(kv.Value)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
+
+// SetAttrStats accounts for the size of every exported attribute until
+// Finish writes the report
+func (p *LogProcessor) SetAttrStats(stats *attrStats) {
+	p.stats = stats
+}
+
+// finishAttrStats writes the size report and closes its sink
+func (p *LogProcessor) finishAttrStats() {
+	if p.stats == nil {
+		return
+	}
+	if err := p.stats.report(); err != nil {
+		logError("Failed to write attribute stats: %v\n", err)
+	}
+	if err := p.stats.Close(); err != nil {
+		logError("Failed to close attribute stats: %v\n", err)
+	}
+	p.stats = nil
+}
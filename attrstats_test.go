@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAttrStatsReport(t *testing.T) {
+	var out bytes.Buffer
+	stats := &attrStats{keys: make(map[string]*attrStat), top: 2, w: &out}
+
+	provider, _ := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetAttrStats(stats)
+
+	ctx := context.Background()
+	for range 3 {
+		processor.ProcessLogEntry(ctx, &LogEntry{
+			Message: "request",
+			Fields:  map[string]any{"stack": strings.Repeat("x", 500), "user": "u1"},
+		})
+	}
+
+	if stats.records != 3 {
+		t.Errorf("Expected 3 records, got %d", stats.records)
+	}
+	expected := int64(3 * proto.Size(&commonpb.KeyValue{
+		Key:   "stack",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Repeat("x", 500)}},
+	}))
+	if got := stats.keys["stack"].bytes; got != expected {
+		t.Errorf("Expected stack to account for %d bytes, got %d", expected, got)
+	}
+
+	processor.Finish(ctx)
+	report := out.String()
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header, column names and the top 2 keys, got:\n%s", report)
+	}
+	if !strings.HasPrefix(lines[0], "Attribute sizes over 3 records") {
+		t.Errorf("Unexpected summary line %q", lines[0])
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[2]), "stack") || !strings.HasSuffix(strings.TrimSpace(lines[3]), "user") {
+		t.Errorf("Expected the largest keys first, got:\n%s", report)
+	}
+}
+
+func TestOTLPValueSize(t *testing.T) {
+	value := log.MapValue(log.String("a", "b"), log.Slice("c", log.Int64Value(1), log.BoolValue(true)))
+	converted := otlpValue(value)
+	kvs := converted.GetKvlistValue().GetValues()
+	if len(kvs) != 2 || kvs[0].GetValue().GetStringValue() != "b" || len(kvs[1].GetValue().GetArrayValue().GetValues()) != 2 {
+		t.Errorf("Unexpected conversion %v", converted)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, expected := range map[int64]string{512: "512 B", 2048: "2.0 KiB", 3 << 20: "3.0 MiB"} {
+		if got := formatBytes(n); got != expected {
+			t.Errorf("formatBytes(%d) = %q, expected %q", n, got, expected)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// avroSchema is a parsed Avro schema. Named types are shared by pointer, so
+// recursive records refer back to themselves.
+type avroSchema struct {
+	kind     string // a primitive name, record, enum, array, map, fixed or union
+	name     string
+	logical  string
+	fields   []avroSchemaField
+	items    *avroSchema // array items and map values
+	branches []*avroSchema
+	symbols  []string
+	size     int
+}
+
+type avroSchemaField struct {
+	name   string
+	schema *avroSchema
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// parseAvroSchema parses the JSON form of an Avro schema
+func parseAvroSchema(text []byte) (*avroSchema, error) {
+	var raw any
+	if err := json.Unmarshal(text, &raw); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	return newAvroSchemaParser().parse(raw, "")
+}
+
+type avroSchemaParser struct {
+	named map[string]*avroSchema
+}
+
+func newAvroSchemaParser() *avroSchemaParser {
+	return &avroSchemaParser{named: make(map[string]*avroSchema)}
+}
+
+func (p *avroSchemaParser) parse(raw any, namespace string) (*avroSchema, error) {
+	switch s := raw.(type) {
+	case string:
+		if avroPrimitives[s] {
+			return &avroSchema{kind: s}, nil
+		}
+		if named, ok := p.named[avroFullName(s, namespace)]; ok {
+			return named, nil
+		}
+		if named, ok := p.named[s]; ok {
+			return named, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type %q", s)
+	case []any:
+		union := &avroSchema{kind: "union"}
+		for _, branch := range s {
+			parsed, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, parsed)
+		}
+		return union, nil
+	case map[string]any:
+		return p.parseObject(s, namespace)
+	default:
+		return nil, fmt.Errorf("invalid Avro schema %v", raw)
+	}
+}
+
+func (p *avroSchemaParser) parseObject(s map[string]any, namespace string) (*avroSchema, error) {
+	kind, _ := s["type"].(string)
+	logical, _ := s["logicalType"].(string)
+	if avroPrimitives[kind] {
+		return &avroSchema{kind: kind, logical: logical}, nil
+	}
+	if kind == "" {
+		// {"type": {...}} wraps another schema
+		return p.parse(s["type"], namespace)
+	}
+
+	schema := &avroSchema{kind: kind, logical: logical}
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		name, _ := s["name"].(string)
+		if ns, ok := s["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		schema.name = avroFullName(name, namespace)
+		if i := strings.LastIndexByte(schema.name, '.'); i >= 0 {
+			namespace = schema.name[:i]
+		}
+		p.named[schema.name] = schema
+	}
+
+	switch kind {
+	case "record", "error":
+		schema.kind = "record"
+		fields, _ := s["fields"].([]any)
+		for _, f := range fields {
+			field, _ := f.(map[string]any)
+			name, _ := field["name"].(string)
+			parsed, err := p.parse(field["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			schema.fields = append(schema.fields, avroSchemaField{name, parsed})
+		}
+	case "enum":
+		symbols, _ := s["symbols"].([]any)
+		for _, symbol := range symbols {
+			name, _ := symbol.(string)
+			schema.symbols = append(schema.symbols, name)
+		}
+	case "fixed":
+		size, _ := s["size"].(float64)
+		schema.size = int(size)
+	case "array", "map":
+		key := "items"
+		if kind == "map" {
+			key = "values"
+		}
+		items, err := p.parse(s[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		schema.items = items
+	default:
+		return nil, fmt.Errorf("unknown Avro type %q", kind)
+	}
+	return schema, nil
+}
+
+func avroFullName(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// decodeAvro decodes a value of the schema from Avro's binary encoding.
+// Records and maps keep their field order, unions become the value of the
+// branch written, enums their symbol and bytes are encoded as base64;
+// timestamp and date logical types become times.
+func decodeAvro(r *recordReader, schema *avroSchema, depth int) (any, error) {
+	if depth > maxRecordDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxRecordDepth)
+	}
+
+	switch schema.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.byte()
+		return b != 0, err
+	case "int", "long":
+		n, err := r.avroLong()
+		if err != nil {
+			return nil, err
+		}
+		return avroLogical(schema.logical, n), nil
+	case "float":
+		data, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return recordFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))), nil
+	case "double":
+		data, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return recordFloat(math.Float64frombits(binary.LittleEndian.Uint64(data))), nil
+	case "bytes", "string":
+		data, err := r.avroBytes()
+		if err != nil {
+			return nil, err
+		}
+		if schema.kind == "string" {
+			return string(data), nil
+		}
+		return append([]byte(nil), data...), nil
+	case "fixed":
+		data, err := r.next(uint64(schema.size))
+		return append([]byte(nil), data...), err
+	case "enum":
+		i, err := r.avroLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(schema.symbols)) {
+			return nil, fmt.Errorf("enum index %d out of range for %s", i, schema.name)
+		}
+		return schema.symbols[i], nil
+	case "union":
+		i, err := r.avroLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(schema.branches)) {
+			return nil, fmt.Errorf("union index %d out of range", i)
+		}
+		return decodeAvro(r, schema.branches[i], depth+1)
+	case "record":
+		fields := make(orderedMap, 0, len(schema.fields))
+		for _, field := range schema.fields {
+			value, err := decodeAvro(r, field.schema, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, recordField{field.name, value})
+		}
+		return fields, nil
+	case "array":
+		items := []any{}
+		err := r.avroBlocks(func() error {
+			item, err := decodeAvro(r, schema.items, depth+1)
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case "map":
+		fields := orderedMap{}
+		err := r.avroBlocks(func() error {
+			key, err := r.avroBytes()
+			if err != nil {
+				return err
+			}
+			value, err := decodeAvro(r, schema.items, depth+1)
+			fields = append(fields, recordField{string(key), value})
+			return err
+		})
+		return fields, err
+	default:
+		return nil, fmt.Errorf("unknown Avro type %q", schema.kind)
+	}
+}
+
+// avroLogical converts ints and longs with a time logical type
+func avroLogical(logical string, n int64) any {
+	switch logical {
+	case "timestamp-millis", "local-timestamp-millis":
+		return time.UnixMilli(n).UTC()
+	case "timestamp-micros", "local-timestamp-micros":
+		return time.UnixMicro(n).UTC()
+	case "timestamp-nanos", "local-timestamp-nanos":
+		return time.Unix(0, n).UTC()
+	case "date":
+		return time.Unix(n*24*60*60, 0).UTC().Format(time.DateOnly)
+	default:
+		return n
+	}
+}
+
+// avroLong reads a zigzag-encoded variable-length integer
+func (r *recordReader) avroLong() (int64, error) {
+	var u uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		u |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return int64(u>>1) ^ -int64(u&1), nil
+		}
+	}
+	return 0, errors.New("Avro integer is too long")
+}
+
+// avroBytes reads a length-prefixed byte sequence
+func (r *recordReader) avroBytes() ([]byte, error) {
+	n, err := r.avroLong()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("negative Avro length %d", n)
+	}
+	return r.next(uint64(n))
+}
+
+// avroBlocks reads the blocks of an array or map, calling item once per
+// item. A negative block count is followed by the block's size in bytes.
+func (r *recordReader) avroBlocks(item func() error) error {
+	for {
+		count, err := r.avroLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := r.avroLong(); err != nil {
+				return err
+			}
+		}
+		for range count {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// avroMagic starts every Avro object container file
+var avroMagic = []byte("Obj\x01")
+
+// avroSyncSize is the length of the marker after each block of a container
+const avroSyncSize = 16
+
+// avroContainer is the state of --framing avro while reading an object
+// container file: its header, then blocks of records
+type avroContainer struct {
+	schema  *avroSchema
+	codec   string
+	sync    []byte
+	pending [][]byte // records of the last block not yet handed on
+}
+
+// scanAvroContainer returns a split function for Avro object container
+// files, as written by Avro's DataFileWriter, Spark and Kafka Connect. The
+// schema comes from the file header; blocks may be uncompressed or
+// compressed with deflate.
+func scanAvroContainer() func(data []byte, atEOF bool) (int, []byte, error) {
+	container := &avroContainer{}
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(container.pending) > 0 {
+			token, container.pending = container.pending[0], container.pending[1:]
+			return 0, token, nil
+		}
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		r := &recordReader{data: data}
+		if container.schema == nil {
+			err = container.readHeader(r)
+		} else {
+			err = container.readBlock(r)
+		}
+		if errors.Is(err, errShortRecord) {
+			if atEOF {
+				return 0, nil, errors.New("truncated Avro container")
+			}
+			return 0, nil, nil
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid Avro container: %w", err)
+		}
+		if len(container.pending) > 0 {
+			token, container.pending = container.pending[0], container.pending[1:]
+		}
+		return r.pos, token, nil
+	}
+}
+
+func (c *avroContainer) readHeader(r *recordReader) error {
+	magic, err := r.next(uint64(len(avroMagic)))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(magic, avroMagic) {
+		return errors.New("not an Avro object container file")
+	}
+	metadata := make(map[string][]byte)
+	err = r.avroBlocks(func() error {
+		key, err := r.avroBytes()
+		if err != nil {
+			return err
+		}
+		value, err := r.avroBytes()
+		metadata[string(key)] = value
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sync, err := r.next(avroSyncSize)
+	if err != nil {
+		return err
+	}
+
+	schema, err := parseAvroSchema(metadata["avro.schema"])
+	if err != nil {
+		return err
+	}
+	c.codec = string(metadata["avro.codec"])
+	switch c.codec {
+	case "", "null", "deflate":
+	default:
+		return fmt.Errorf("unsupported codec %q (supported: null, deflate)", c.codec)
+	}
+	c.schema, c.sync = schema, append([]byte(nil), sync...)
+	return nil
+}
+
+func (c *avroContainer) readBlock(r *recordReader) error {
+	count, err := r.avroLong()
+	if err != nil {
+		return err
+	}
+	block, err := r.avroBytes()
+	if err != nil {
+		return err
+	}
+	sync, err := r.next(avroSyncSize)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sync, c.sync) {
+		return errors.New("sync marker mismatch")
+	}
+	if c.codec == "deflate" {
+		if block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+			return err
+		}
+	}
+
+	records := &recordReader{data: block}
+	for range count {
+		record, err := decodeAvro(records, c.schema, 0)
+		if errors.Is(err, errShortRecord) {
+			return errors.New("block ends inside a record")
+		}
+		if err != nil {
+			return err
+		}
+		token, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		c.pending = append(c.pending, token)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const avroTestSchema = `{
+  "type": "record", "name": "Event", "namespace": "acme.logging",
+  "fields": [
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["DEBUG", "INFO", "ERROR"]}},
+    {"name": "message", "type": "string"},
+    {"name": "user", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "labels", "type": {"type": "map", "values": "long"}},
+    {"name": "cause", "type": ["null", "Event"]}
+  ]
+}`
+
+// avroLongBytes encodes n as a zigzag varint
+func avroLongBytes(n int64) []byte {
+	u := uint64(n<<1) ^ uint64(n>>63)
+	var out []byte
+	for u >= 0x80 {
+		out = append(out, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(out, byte(u))
+}
+
+func avroStringBytes(s string) []byte {
+	return append(avroLongBytes(int64(len(s))), s...)
+}
+
+// avroTestRecord encodes an Event whose cause is a second Event
+func avroTestRecord(message string) []byte {
+	var out []byte
+	out = append(out, avroLongBytes(1705314645123)...)
+	out = append(out, avroLongBytes(2)...) // ERROR
+	out = append(out, avroStringBytes(message)...)
+	out = append(out, avroLongBytes(1)...) // string branch
+	out = append(out, avroStringBytes("alice")...)
+	out = append(out, avroLongBytes(2)...)
+	out = append(out, avroStringBytes("a")...)
+	out = append(out, avroStringBytes("b")...)
+	out = append(out, 0)
+	out = append(out, avroLongBytes(-1)...) // one entry, with the block size
+	out = append(out, avroLongBytes(3)...)
+	out = append(out, avroStringBytes("n")...)
+	out = append(out, avroLongBytes(-7)...)
+	out = append(out, 0)
+	out = append(out, avroLongBytes(1)...) // Event branch
+	out = append(out, avroLongBytes(0)...)
+	out = append(out, avroLongBytes(1)...) // INFO
+	out = append(out, avroStringBytes("root")...)
+	out = append(out, avroLongBytes(0)...) // null
+	out = append(out, 0, 0)
+	out = append(out, avroLongBytes(0)...) // null
+	return out
+}
+
+func avroTestJSON(message string) string {
+	return `{"time":"2024-01-15T10:30:45.123Z","level":"ERROR","message":"` + message + `","user":"alice","tags":["a","b"],"labels":{"n":-7},` +
+		`"cause":{"time":"1970-01-01T00:00:00Z","level":"INFO","message":"root","user":null,"tags":[],"labels":{},"cause":null}}`
+}
+
+func TestDecodeAvro(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(avroTestSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	record, err := decodeAvro(&recordReader{data: avroTestRecord("boom")}, schema, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	encoded, _ := json.Marshal(record)
+	if string(encoded) != avroTestJSON("boom") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", avroTestJSON("boom"), encoded)
+	}
+
+	for _, bad := range []string{`{"type": "record", "name": "R", "fields": [{"name": "x", "type": "Missing"}]}`, `{"type": "tuple"}`, `not json`} {
+		if _, err := parseAvroSchema([]byte(bad)); err == nil {
+			t.Errorf("Expected error for schema %s", bad)
+		}
+	}
+}
+
+// avroTestContainer writes an object container file with one block per
+// record
+func avroTestContainer(codec string, messages ...string) string {
+	sync := []byte("0123456789abcdef")
+	var out bytes.Buffer
+	out.Write(avroMagic)
+	out.Write(avroLongBytes(2))
+	out.Write(avroStringBytes("avro.schema"))
+	out.Write(avroStringBytes(avroTestSchema))
+	out.Write(avroStringBytes("avro.codec"))
+	out.Write(avroStringBytes(codec))
+	out.WriteByte(0)
+	out.Write(sync)
+
+	for i := 0; i < len(messages); i += 2 {
+		var block []byte
+		batch := messages[i:min(i+2, len(messages))]
+		for _, message := range batch {
+			block = append(block, avroTestRecord(message)...)
+		}
+		if codec == "deflate" {
+			var compressed bytes.Buffer
+			w, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+			w.Write(block)
+			w.Close()
+			block = compressed.Bytes()
+		}
+		out.Write(avroLongBytes(int64(len(batch))))
+		out.Write(avroLongBytes(int64(len(block))))
+		out.Write(block)
+		out.Write(sync)
+	}
+	return out.String()
+}
+
+func TestScanAvroContainer(t *testing.T) {
+	expected := []string{avroTestJSON("one"), avroTestJSON("two"), avroTestJSON("three")}
+	for _, codec := range []string{"null", "deflate"} {
+		t.Run(codec, func(t *testing.T) {
+			input := avroTestContainer(codec, "one", "two", "three")
+			if tokens := scanAll(t, input, scanAvroContainer()); !reflect.DeepEqual(tokens, expected) {
+				t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tokens, "\n"))
+			}
+		})
+	}
+
+	input := avroTestContainer("snappy", "one")
+	scanner := scannerWithSplit(input, scanAvroContainer())
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), "snappy") {
+		t.Errorf("Expected an unsupported codec error, got %v", err)
+	}
+}
+
+func TestScanConfluentAvro(t *testing.T) {
+	var fetches atomic.Int32
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/schemas/ids/7" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]string{"schema": avroTestSchema})
+	}))
+	defer registry.Close()
+
+	url := strings.Replace(registry.URL, "http://", "http://key:secret@", 1)
+	split, err := splitFunc(&Config{Framing: framingAvroConfluent, SchemaRegistry: url})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var input []byte
+	for _, message := range []string{"one", "two"} {
+		input = append(input, confluentMagic, 0, 0, 0, 7)
+		input = append(input, avroTestRecord(message)...)
+	}
+	expected := []string{avroTestJSON("one"), avroTestJSON("two")}
+	if tokens := scanAll(t, string(input), split); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tokens, "\n"))
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the schema to be fetched once, got %d", fetches.Load())
+	}
+
+	scanner := scannerWithSplit("\x00\x00\x00\x00\x08\x00", split)
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected an unknown schema error, got %v", err)
+	}
+
+	if _, err := splitFunc(&Config{Framing: framingAvroConfluent}); err == nil {
+		t.Error("Expected error without --schema-registry")
+	}
+}
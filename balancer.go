@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"google.golang.org/grpc"
+)
+
+// roundRobinServiceConfig makes a gRPC client connect to every address the
+// target resolves to and spread calls across the healthy ones
+const roundRobinServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
+// balancedEndpoint is one collector replica and when it may be tried again
+// after a failed export
+type balancedEndpoint struct {
+	url       string
+	exporter  sdklog.Exporter
+	downUntil time.Time
+}
+
+// balancedExporter sends each batch to the next replica in turn. A replica
+// whose export fails is skipped for the retry interval and the batch goes to
+// the next one, so a dead replica costs one attempt rather than its share of
+// the batches. If every replica is marked down they are all tried anyway.
+type balancedExporter struct {
+	endpoints []*balancedEndpoint
+	retry     time.Duration
+	verbose   bool
+
+	mu   sync.Mutex
+	next int
+}
+
+func (e *balancedExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	order := make([]*balancedEndpoint, 0, len(e.endpoints))
+	var down []*balancedEndpoint
+	for i := range e.endpoints {
+		ep := e.endpoints[(e.next+i)%len(e.endpoints)]
+		if now.Before(ep.downUntil) {
+			down = append(down, ep)
+		} else {
+			order = append(order, ep)
+		}
+	}
+	if len(order) == 0 {
+		order = down
+	}
+	e.next = (e.next + 1) % len(e.endpoints)
+
+	var errs []error
+	for _, ep := range order {
+		err := ep.exporter.Export(ctx, records)
+		if err == nil {
+			ep.downUntil = time.Time{}
+			return nil
+		}
+		if ep.downUntil.IsZero() {
+			logError("Export to %s failed, trying the next endpoint: %v\n", ep.url, err)
+		} else {
+			logDebug(e.verbose, "Export to %s failed again: %v\n", ep.url, err)
+		}
+		ep.downUntil = time.Now().Add(e.retry)
+		errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *balancedExporter) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, ep := range e.endpoints {
+		errs = append(errs, ep.exporter.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *balancedExporter) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, ep := range e.endpoints {
+		errs = append(errs, ep.exporter.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// primaryEndpoints returns the --endpoints replicas, or the endpoint from the
+// environment when none are given
+func primaryEndpoints(config *Config, protocol string) ([]*otlpEndpoint, error) {
+	if len(config.Endpoints) == 0 {
+		ep, err := resolveEndpoint(protocol)
+		if err != nil {
+			return nil, err
+		}
+		return []*otlpEndpoint{ep}, nil
+	}
+
+	var eps []*otlpEndpoint
+	for _, raw := range config.Endpoints {
+		ep, err := flagEndpoint(raw, protocol)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --endpoints: %w", err)
+		}
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+// newBalancedExporter creates an exporter per --endpoints replica
+func newBalancedExporter(ctx context.Context, config *Config, protocol string) (sdklog.Exporter, error) {
+	eps, err := primaryEndpoints(config, protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := config.EndpointProbeInterval
+	if retry <= 0 {
+		retry = 30 * time.Second
+	}
+	balanced := &balancedExporter{retry: retry, verbose: config.Verbose}
+	for _, ep := range eps {
+		exporter, err := newEndpointExporter(ctx, config, ep, nil)
+		if err != nil {
+			balanced.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to create exporter for %s: %w", ep.URL, err)
+		}
+		balanced.endpoints = append(balanced.endpoints, &balancedEndpoint{url: ep.URL.String(), exporter: exporter})
+	}
+	return balanced, nil
+}
+
+// roundRobinDialOptions spreads gRPC calls across all resolved addresses of
+// the collector hostname when --grpc-round-robin is set
+func roundRobinDialOptions(config *Config) []grpc.DialOption {
+	if !config.GRPCRoundRobin {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithDefaultServiceConfig(roundRobinServiceConfig)}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/middle-management/otel-logger/otlptest"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestBalancedExporterRoundRobin(t *testing.T) {
+	replicas := []*flakyExporter{{}, {}, {}}
+	exporter := &balancedExporter{retry: time.Hour}
+	for i, replica := range replicas {
+		exporter.endpoints = append(exporter.endpoints, &balancedEndpoint{url: string(rune('a' + i)), exporter: replica})
+	}
+
+	ctx := context.Background()
+	batch := []sdklog.Record{{}}
+	for range 6 {
+		if err := exporter.Export(ctx, batch); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for i, replica := range replicas {
+		if got := len(replica.Records()); got != 2 {
+			t.Errorf("Replica %d: expected 2 batches, got %d", i, got)
+		}
+	}
+
+	// A failing replica hands its batch on and is skipped until the retry interval
+	replicas[1].down = true
+	for range 6 {
+		if err := exporter.Export(ctx, batch); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if got := len(replicas[1].Records()); got != 2 {
+		t.Errorf("Expected no batches on the failing replica, got %d", got-2)
+	}
+	if got := len(replicas[0].Records()) + len(replicas[2].Records()); got != 10 {
+		t.Errorf("Expected the other replicas to take all 6 batches, got %d", got-4)
+	}
+}
+
+func TestBalancedExporterAllDown(t *testing.T) {
+	replicas := []*flakyExporter{{down: true}, {down: true}}
+	exporter := &balancedExporter{retry: time.Hour}
+	for _, replica := range replicas {
+		exporter.endpoints = append(exporter.endpoints, &balancedEndpoint{url: "replica", exporter: replica})
+	}
+
+	ctx := context.Background()
+	if err := exporter.Export(ctx, []sdklog.Record{{}}); err == nil {
+		t.Fatal("Expected an error when every replica fails")
+	}
+
+	// Marked down replicas are still tried rather than dropping the batch
+	replicas[0].down = false
+	if err := exporter.Export(ctx, []sdklog.Record{{}}); err != nil {
+		t.Errorf("Expected a recovered replica to be used, got %v", err)
+	}
+}
+
+func TestBalancedExporterAcrossReceivers(t *testing.T) {
+	var receivers []*otlptest.Receiver
+	var endpoints []string
+	for range 2 {
+		receiver, err := otlptest.NewReceiver()
+		if err != nil {
+			t.Fatalf("Failed to start receiver: %v", err)
+		}
+		defer receiver.Close()
+		receivers = append(receivers, receiver)
+		endpoints = append(endpoints, receiver.HTTPEndpoint())
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", otlptest.ProtocolHTTPProtobuf)
+
+	ctx := context.Background()
+	config := &Config{Timeout: 5 * time.Second, Endpoints: endpoints}
+	exporter, err := newBalancedExporter(ctx, config, resolveProtocol())
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	defer exporter.Shutdown(ctx)
+
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	processor := NewLogProcessor(provider.Logger("test"))
+	for _, message := range []string{"one", "two", "three", "four"} {
+		processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "info", Message: message})
+	}
+
+	for i, receiver := range receivers {
+		if got := len(receiver.Records()); got != 2 {
+			t.Errorf("Receiver %d: expected 2 records, got %d", i, got)
+		}
+	}
+}
+
+func TestPrimaryEndpoints(t *testing.T) {
+	eps, err := primaryEndpoints(&Config{Endpoints: []string{"http://a:4318", "http://b:4318"}}, "http/protobuf")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var urls []string
+	for _, ep := range eps {
+		urls = append(urls, ep.URL.String())
+	}
+	if got := strings.Join(urls, ","); got != "http://a:4318/v1/logs,http://b:4318/v1/logs" {
+		t.Errorf("Unexpected endpoints %s", got)
+	}
+
+	if _, err := primaryEndpoints(&Config{Endpoints: []string{"http://:1"}}, "grpc"); err == nil {
+		t.Error("Expected error for an invalid endpoint")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// maxPooledAttributes caps the buffers kept for reuse, so one record with
+// thousands of fields does not pin their memory
+const maxPooledAttributes = 1024
+
+// emitBuffers is the scratch space a record's attributes are built in.
+// Records copy their attributes, so the buffers are reused from one record
+// to the next.
+type emitBuffers struct {
+	fieldKeys []string       // the entry's field keys in export order
+	attrs     []log.KeyValue // attributes in the order they are added
+	deduped   []log.KeyValue // attrs with repeated keys resolved
+	index     map[string]int // position of each key in deduped
+}
+
+var emitBufferPool = sync.Pool{
+	New: func() any { return &emitBuffers{index: map[string]int{}} },
+}
+
+// getEmitBuffers takes buffers from the pool with room for size attributes
+func getEmitBuffers(size int) *emitBuffers {
+	buffers := emitBufferPool.Get().(*emitBuffers)
+	buffers.attrs = slices.Grow(buffers.attrs[:0], size)
+	buffers.deduped = slices.Grow(buffers.deduped[:0], size)
+	return buffers
+}
+
+// putEmitBuffers returns buffers to the pool unless they grew too large
+func putEmitBuffers(buffers *emitBuffers) {
+	if cap(buffers.fieldKeys) > maxPooledAttributes || cap(buffers.attrs) > maxPooledAttributes ||
+		cap(buffers.deduped) > maxPooledAttributes {
+		return
+	}
+	clear(buffers.index)
+	emitBufferPool.Put(buffers)
+}
+
+// ProcessLogEntries exports a batch of parsed entries in one pass. It is
+// ProcessLogEntry for each entry in order, except that the attribute buffers
+// are sized once for the largest entry and shared by the whole batch, and
+// --flush-on flushes once after the batch rather than after each record
+// that calls for it. Embedders feeding many entries at a time should prefer
+// it.
+func (p *LogProcessor) ProcessLogEntries(ctx context.Context, entries []*LogEntry) {
+	size := 0
+	for _, entry := range entries {
+		size = max(size, len(entry.Fields))
+	}
+	buffers := getEmitBuffers(size + 8)
+	defer putEmitBuffers(buffers)
+
+	s := p.sampler.Load()
+	flushLevel := ""
+	for _, entry := range entries {
+		if p.belowMinSeverity(entry) {
+			continue
+		}
+		samplingRatio := 1.0
+		if s != nil && !entry.Internal && !p.keep.Load().keeps(entry, entry.severity()) {
+			var sampled bool
+			if sampled, samplingRatio = s.sample(entry); !sampled {
+				continue
+			}
+		}
+		if p.emit(ctx, entry, samplingRatio, buffers) && flushLevel == "" {
+			flushLevel = entry.Level
+		}
+	}
+	if flushLevel != "" {
+		p.flushAfter(ctx, flushLevel)
+	}
+}
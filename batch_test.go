@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// batchEntries are entries with different fields, so attributes left in a
+// reused buffer would show up on the next record
+func batchEntries() []*LogEntry {
+	stamp := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	return []*LogEntry{
+		{Timestamp: stamp, Level: "info", Message: "many fields", Stream: "stdout", Fields: map[string]any{
+			"a": "1", "b": 2.0, "c": true, "d": "4", "e": "5", "f": "6", "g": "7", "h": "8", "i": "9", "j": "10",
+		}},
+		{Timestamp: stamp, Level: "debug", Message: "dropped by --min-level", Fields: map[string]any{"a": "x"}},
+		{Timestamp: stamp, Level: "error", Message: "few fields", Fields: map[string]any{"k": "11"}, FieldOrder: []string{"k"}},
+		{Timestamp: stamp, Level: "warn", Message: "no fields", File: "/var/log/app.log"},
+		{Timestamp: stamp, Level: "fatal", Message: "repeated key", Stream: "stderr", Fields: map[string]any{"log.iostream": "parsed"}},
+	}
+}
+
+func TestProcessLogEntries(t *testing.T) {
+	var exported [2][]string
+	var flushes [2]int
+	for i, process := range []func(*LogProcessor, context.Context, []*LogEntry){
+		func(p *LogProcessor, ctx context.Context, entries []*LogEntry) {
+			for _, entry := range entries {
+				p.ProcessLogEntry(ctx, entry)
+			}
+		},
+		(*LogProcessor).ProcessLogEntries,
+	} {
+		provider, exporter := newRecordingProvider()
+		processor := NewLogProcessor(provider.Logger("test"))
+		processor.SetMinSeverity(log.SeverityInfo)
+		processor.SetDuplicateKeys(duplicateKeysSuffix)
+		processor.SetFlusher(func(ctx context.Context) error {
+			flushes[i]++
+			return nil
+		})
+		processor.SetFlushOn(log.SeverityError)
+		process(processor, context.Background(), batchEntries())
+
+		for _, record := range exporter.Records() {
+			exported[i] = append(exported[i], fmt.Sprintf("%s %v", record.Body().AsString(), recordAttributes(record)))
+		}
+	}
+
+	if len(exported[0]) != 4 {
+		t.Fatalf("Expected 4 records, got %q", exported[0])
+	}
+	if !reflect.DeepEqual(exported[1], exported[0]) {
+		t.Errorf("Expected the batch to export\n%q\ngot\n%q", exported[0], exported[1])
+	}
+	// error and fatal flush each on their own, the batch once for both
+	if flushes != [2]int{2, 1} {
+		t.Errorf("Expected 2 flushes entry by entry and 1 for the batch, got %v", flushes)
+	}
+}
+
+// discardExporter drops records, to measure otel-logger rather than export
+type discardExporter struct{}
+
+func (discardExporter) Export(ctx context.Context, records []sdklog.Record) error { return nil }
+func (discardExporter) Shutdown(ctx context.Context) error                        { return nil }
+func (discardExporter) ForceFlush(ctx context.Context) error                      { return nil }
+
+// BenchmarkProcessLogEntries compares emitting parsed entries one by one
+// and in batches of 256
+func BenchmarkProcessLogEntries(b *testing.B) {
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entries := make([]*LogEntry, 256)
+	for i := range entries {
+		entry, err := extractor.ParseLogEntry(fmt.Sprintf(`{"timestamp":"2024-01-15T10:30:45Z","level":"info","msg":"request handled","request_id":"r-%08d","user_id":12345,"path":"/api/orders","status":200,"duration_ms":12}`, i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		entries[i] = entry
+	}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(discardExporter{})))
+	processor := NewLogProcessor(provider.Logger("bench"))
+	ctx := context.Background()
+
+	b.Run("entry", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, entry := range entries {
+				processor.ProcessLogEntry(ctx, entry)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			processor.ProcessLogEntries(ctx, entries)
+		}
+	})
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+const defaultBigQueryEndpoint = "https://bigquery.googleapis.com"
+
+// BigQuery column types used for record fields
+const (
+	bigQueryTimestamp = "TIMESTAMP"
+	bigQueryInteger   = "INTEGER"
+	bigQueryFloat     = "FLOAT"
+	bigQueryBoolean   = "BOOLEAN"
+	bigQueryString    = "STRING"
+)
+
+// bigQueryField is a column in a BigQuery table schema
+type bigQueryField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+type bigQuerySchema struct {
+	Fields []bigQueryField `json:"fields"`
+}
+
+// bigQueryFixedFields are the columns every table gets, in the order of
+// normalizedRecord
+var bigQueryFixedFields = []bigQueryField{
+	{Name: "timestamp", Type: bigQueryTimestamp, Mode: "NULLABLE"},
+	{Name: "observed_timestamp", Type: bigQueryTimestamp, Mode: "NULLABLE"},
+	{Name: "severity_number", Type: bigQueryInteger, Mode: "NULLABLE"},
+	{Name: "severity_text", Type: bigQueryString, Mode: "NULLABLE"},
+	{Name: "body", Type: bigQueryString, Mode: "NULLABLE"},
+	{Name: "event_name", Type: bigQueryString, Mode: "NULLABLE"},
+	{Name: "trace_id", Type: bigQueryString, Mode: "NULLABLE"},
+	{Name: "span_id", Type: bigQueryString, Mode: "NULLABLE"},
+	{Name: "scope_name", Type: bigQueryString, Mode: "NULLABLE"},
+}
+
+// bigQueryExporter streams records into a BigQuery table with the
+// tabledata.insertAll API. The table is created on first use, and a column is
+// added for each attribute key not seen before, so new fields show up without
+// a schema migration. Values that do not fit an existing column's type are
+// converted where possible and left out of the row otherwise.
+type bigQueryExporter struct {
+	mu       sync.Mutex
+	client   *http.Client
+	endpoint string
+	project  string
+	dataset  string
+	table    string
+	token    *gcpTokenSource
+	verbose  bool
+
+	columns    map[string]string // column name to type, nil until loaded
+	mismatched map[string]bool   // columns already reported for type mismatches
+}
+
+// parseBigQueryTable accepts PROJECT.DATASET.TABLE or PROJECT:DATASET.TABLE
+func parseBigQueryTable(spec string) (project, dataset, table string, err error) {
+	parts := strings.Split(strings.Replace(spec, ":", ".", 1), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid --bigquery-table %q (expected PROJECT.DATASET.TABLE)", spec)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func newBigQueryExporter(config *Config) (*bigQueryExporter, error) {
+	if config.ExportFile != "" || len(config.Endpoints) > 0 || config.EndpointFallback != "" || config.TenantHeaders != "" {
+		return nil, fmt.Errorf("--bigquery-table cannot be combined with --export-file, --endpoints, --endpoint-fallback or --tenant-headers")
+	}
+	project, dataset, table, err := parseBigQueryTable(config.BigQueryTable)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := config.BigQueryEndpoint
+	if endpoint == "" {
+		endpoint = defaultBigQueryEndpoint
+	}
+	client := outboundTLS(config).httpClient(config.Timeout)
+	return &bigQueryExporter{
+		client:     client,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		project:    project,
+		dataset:    dataset,
+		table:      table,
+		token:      &gcpTokenSource{client: client},
+		verbose:    config.Verbose,
+		mismatched: make(map[string]bool),
+	}, nil
+}
+
+func (e *bigQueryExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := make([]map[string]any, len(records))
+	for i, record := range records {
+		rows[i] = bigQueryRow(normalizeRecord(record))
+	}
+
+	evolved, err := e.ensureColumns(ctx, rows)
+	if err != nil {
+		return err
+	}
+	// The insert ID lets BigQuery drop duplicates when a row is retried, so
+	// each row keeps its ID across attempts
+	pending := make([]bigQueryInsertRow, len(rows))
+	for i, row := range rows {
+		e.coerceRow(row)
+		id := make([]byte, 16)
+		rand.Read(id)
+		pending[i] = bigQueryInsertRow{InsertID: hex.EncodeToString(id), JSON: row}
+	}
+
+	// Columns added a moment ago can take a while to reach the streaming
+	// backend, so rows rejected right after a schema change are retried
+	for attempt := 0; ; attempt++ {
+		failed, err := e.insert(ctx, pending)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		if !evolved || attempt == 2 {
+			return fmt.Errorf("BigQuery rejected %d of %d rows: %s", len(failed), len(pending), failed[0].message)
+		}
+		retry := make([]bigQueryInsertRow, len(failed))
+		for i, f := range failed {
+			retry[i] = pending[f.index]
+		}
+		pending = retry
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * time.Second):
+		}
+	}
+}
+
+// bigQueryRow lays out a normalized record as an insertAll row. Dotted keys
+// become underscores, since BigQuery column names allow only letters, digits
+// and underscores, and are case-insensitive.
+func bigQueryRow(r normalizedRecord) map[string]any {
+	row := make(map[string]any, len(r.attributes)+len(bigQueryFixedFields))
+	set := func(name string, value any) {
+		if value == nil || value == "" {
+			return
+		}
+		if _, exists := row[name]; !exists {
+			row[name] = value
+		}
+	}
+	if !r.timestamp.IsZero() {
+		set("timestamp", r.timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	if !r.observed.IsZero() {
+		set("observed_timestamp", r.observed.UTC().Format(time.RFC3339Nano))
+	}
+	set("severity_number", int64(r.severity))
+	set("severity_text", r.severityText)
+	set("body", r.body)
+	set("event_name", r.eventName)
+	set("trace_id", r.traceID)
+	set("span_id", r.spanID)
+	set("scope_name", r.scope)
+
+	keys := make([]string, 0, len(r.attributes))
+	for key := range r.attributes {
+		keys = append(keys, key)
+	}
+	// Sorted so colliding keys resolve the same way every time
+	slices.Sort(keys)
+	for _, key := range keys {
+		set(bigQueryColumnName(key), r.attributes[key])
+	}
+	return row
+}
+
+// bigQueryColumnName turns an attribute key into a valid column name
+func bigQueryColumnName(key string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(key) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	if len(name) > 300 {
+		name = name[:300]
+	}
+	return name
+}
+
+// bigQueryType is the column type a new column gets for value
+func bigQueryType(value any) string {
+	switch value.(type) {
+	case bool:
+		return bigQueryBoolean
+	case int64:
+		return bigQueryInteger
+	case float64:
+		return bigQueryFloat
+	default:
+		return bigQueryString
+	}
+}
+
+// coerceRow converts values to the type of their column, dropping the ones
+// that cannot be converted
+func (e *bigQueryExporter) coerceRow(row map[string]any) {
+	for name, value := range row {
+		columnType := e.columns[name]
+		if columnType == "" || columnType == bigQueryTimestamp || columnType == bigQueryType(value) {
+			continue
+		}
+		switch v := value.(type) {
+		case int64:
+			if columnType == bigQueryFloat {
+				row[name] = float64(v)
+				continue
+			}
+		case float64:
+			if columnType == bigQueryInteger && v == float64(int64(v)) {
+				row[name] = int64(v)
+				continue
+			}
+		}
+		if columnType == bigQueryString {
+			row[name] = fmt.Sprint(value)
+			continue
+		}
+		delete(row, name)
+		if !e.mismatched[name] {
+			e.mismatched[name] = true
+			logError("BigQuery column %s is %s; leaving out values that do not fit, such as %v\n", name, columnType, value)
+		}
+	}
+}
+
+// ensureColumns loads the table schema on first use, creating the table if
+// needed, and adds columns for fields the rows have that the table lacks. It
+// reports whether the schema changed.
+func (e *bigQueryExporter) ensureColumns(ctx context.Context, rows []map[string]any) (bool, error) {
+	if e.columns == nil {
+		schema, err := e.getSchema(ctx)
+		if err != nil {
+			return false, err
+		}
+		if schema == nil {
+			if err := e.call(ctx, http.MethodPost, e.tablesURL(), map[string]any{
+				"tableReference": map[string]string{"projectId": e.project, "datasetId": e.dataset, "tableId": e.table},
+				"schema":         bigQuerySchema{Fields: bigQueryFixedFields},
+			}, nil); err != nil {
+				return false, fmt.Errorf("failed to create BigQuery table: %w", err)
+			}
+			logInfo(e.verbose, "Created BigQuery table %s.%s.%s\n", e.project, e.dataset, e.table)
+			schema = &bigQuerySchema{Fields: bigQueryFixedFields}
+		}
+		e.setColumns(schema.Fields)
+	}
+
+	var added []bigQueryField
+	for _, row := range rows {
+		for name, value := range row {
+			if _, ok := e.columns[name]; ok || slices.ContainsFunc(added, func(f bigQueryField) bool { return f.Name == name }) {
+				continue
+			}
+			added = append(added, bigQueryField{Name: name, Type: bigQueryType(value), Mode: "NULLABLE"})
+		}
+	}
+	if len(added) == 0 {
+		return false, nil
+	}
+	slices.SortFunc(added, func(a, b bigQueryField) int { return strings.Compare(a.Name, b.Name) })
+
+	// Patch with the current schema so columns added by another writer in the
+	// meantime are kept
+	schema, err := e.getSchema(ctx)
+	if err != nil {
+		return false, err
+	}
+	if schema == nil {
+		return false, fmt.Errorf("BigQuery table %s.%s.%s disappeared", e.project, e.dataset, e.table)
+	}
+	e.setColumns(schema.Fields)
+	fields := schema.Fields
+	for _, field := range added {
+		if _, ok := e.columns[field.Name]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == len(schema.Fields) {
+		return false, nil
+	}
+	if err := e.call(ctx, http.MethodPatch, e.tableURL(), map[string]any{"schema": bigQuerySchema{Fields: fields}}, nil); err != nil {
+		return false, fmt.Errorf("failed to add BigQuery columns: %w", err)
+	}
+	logInfo(e.verbose, "Added %d columns to BigQuery table %s\n", len(fields)-len(schema.Fields), e.table)
+	e.setColumns(fields)
+	return true, nil
+}
+
+func (e *bigQueryExporter) setColumns(fields []bigQueryField) {
+	// Tables created with SQL report the standard SQL type names
+	legacy := map[string]string{"INT64": bigQueryInteger, "FLOAT64": bigQueryFloat, "BOOL": bigQueryBoolean}
+	e.columns = make(map[string]string, len(fields))
+	for _, field := range fields {
+		columnType := field.Type
+		if name, ok := legacy[columnType]; ok {
+			columnType = name
+		}
+		e.columns[strings.ToLower(field.Name)] = columnType
+	}
+}
+
+// getSchema returns the table schema, or nil if the table does not exist
+func (e *bigQueryExporter) getSchema(ctx context.Context) (*bigQuerySchema, error) {
+	var table struct {
+		Schema bigQuerySchema `json:"schema"`
+	}
+	err := e.call(ctx, http.MethodGet, e.tableURL(), nil, &table)
+	var apiErr *bigQueryError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BigQuery table: %w", err)
+	}
+	return &table.Schema, nil
+}
+
+type bigQueryRowError struct {
+	index   int
+	message string
+}
+
+// bigQueryInsertRow is a row of an insertAll request
+type bigQueryInsertRow struct {
+	InsertID string         `json:"insertId"`
+	JSON     map[string]any `json:"json"`
+}
+
+// insert streams the rows and returns the ones BigQuery rejected
+func (e *bigQueryExporter) insert(ctx context.Context, rows []bigQueryInsertRow) ([]bigQueryRowError, error) {
+	request := struct {
+		SkipInvalidRows bool                `json:"skipInvalidRows"`
+		Rows            []bigQueryInsertRow `json:"rows"`
+	}{SkipInvalidRows: true, Rows: rows}
+
+	var response struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	if err := e.call(ctx, http.MethodPost, e.tableURL()+"/insertAll", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to stream rows to BigQuery: %w", err)
+	}
+
+	var failed []bigQueryRowError
+	for _, insertErr := range response.InsertErrors {
+		message := "unknown error"
+		for _, detail := range insertErr.Errors {
+			// Valid rows of a request with invalid ones are reported as "stopped"
+			if detail.Reason != "stopped" {
+				message = detail.Reason + ": " + detail.Message
+				break
+			}
+		}
+		failed = append(failed, bigQueryRowError{index: insertErr.Index, message: message})
+	}
+	return failed, nil
+}
+
+func (e *bigQueryExporter) tablesURL() string {
+	return fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s/tables",
+		e.endpoint, url.PathEscape(e.project), url.PathEscape(e.dataset))
+}
+
+func (e *bigQueryExporter) tableURL() string {
+	return e.tablesURL() + "/" + url.PathEscape(e.table)
+}
+
+// bigQueryError is an error response from the BigQuery API
+type bigQueryError struct {
+	status  int
+	message string
+}
+
+func (e *bigQueryError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), e.message)
+}
+
+// call sends a JSON request to the BigQuery API and decodes the response into out
+func (e *bigQueryExporter) call(ctx context.Context, method, target string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := e.token.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			message = apiErr.Error.Message
+		}
+		return &bigQueryError{status: resp.StatusCode, message: message}
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (e *bigQueryExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func (e *bigQueryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// gcpTokenSource provides OAuth access tokens: GOOGLE_OAUTH_ACCESS_TOKEN if
+// set (e.g. from gcloud auth print-access-token), otherwise the service
+// account of the GCE, GKE or Cloud Run metadata server, refreshed before it
+// expires
+type gcpTokenSource struct {
+	client  *http.Client
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *gcpTokenSource) Token(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expires) > time.Minute {
+		return s.token, nil
+	}
+
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no Google credentials: set GOOGLE_OAUTH_ACCESS_TOKEN or run on Google Cloud (%w)", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get access token from metadata server: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
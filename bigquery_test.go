@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// fakeBigQuery serves the table and insertAll calls of the BigQuery API
+type fakeBigQuery struct {
+	mu      sync.Mutex
+	exists  bool
+	fields  []bigQueryField
+	rows    []map[string]any
+	patches int
+	auth    string
+
+	insertIDs []string // of every row sent, including rejected ones
+	reject    int      // insertAll calls to reject every row of, as if the new columns had not arrived yet
+}
+
+func (f *fakeBigQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = r.Header.Get("Authorization")
+
+	const table = "/bigquery/v2/projects/proj/datasets/logs/tables"
+	var body struct {
+		Schema bigQuerySchema `json:"schema"`
+		Rows   []struct {
+			InsertID string         `json:"insertId"`
+			JSON     map[string]any `json:"json"`
+		} `json:"rows"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == table+"/app":
+		if !f.exists {
+			http.Error(w, `{"error":{"message":"Not found: Table proj:logs.app"}}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"schema": bigQuerySchema{Fields: f.fields}})
+	case r.Method == http.MethodPost && r.URL.Path == table:
+		f.exists, f.fields = true, body.Schema.Fields
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodPatch && r.URL.Path == table+"/app":
+		f.fields = body.Schema.Fields
+		f.patches++
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodPost && r.URL.Path == table+"/app/insertAll":
+		var insertErrors []map[string]any
+		for i, row := range body.Rows {
+			f.insertIDs = append(f.insertIDs, row.InsertID)
+			if f.reject > 0 {
+				insertErrors = append(insertErrors, map[string]any{"index": i, "errors": []map[string]string{{"reason": "invalid", "message": "no such field"}}})
+				continue
+			}
+			f.rows = append(f.rows, row.JSON)
+		}
+		if f.reject > 0 {
+			f.reject--
+		}
+		json.NewEncoder(w).Encode(map[string]any{"insertErrors": insertErrors})
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func (f *fakeBigQuery) column(name string) string {
+	for _, field := range f.fields {
+		if field.Name == name {
+			return field.Type
+		}
+	}
+	return ""
+}
+
+func TestBigQueryExporterEvolvesSchema(t *testing.T) {
+	fake := &fakeBigQuery{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+
+	exporter, err := newBigQueryExporter(&Config{
+		BigQueryTable:    "proj:logs.app",
+		BigQueryEndpoint: server.URL,
+		Timeout:          5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	export := func(attrs ...log.KeyValue) {
+		t.Helper()
+		provider, source := newRecordingProvider()
+		var record log.Record
+		record.SetTimestamp(time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC))
+		record.SetBody(log.StringValue("request done"))
+		record.AddAttributes(attrs...)
+		provider.Logger("otel-logger").Emit(ctx, record)
+		if err := exporter.Export(ctx, source.Records()); err != nil {
+			t.Fatalf("Unexpected export error: %v", err)
+		}
+	}
+
+	export(log.Int("http.status", 200))
+	if fake.column("attributes_http_status") != bigQueryInteger || fake.column("body") != bigQueryString {
+		t.Fatalf("Expected the table created with an integer status column, got %v", fake.fields)
+	}
+
+	export(log.String("http.status", "oops"), log.Bool("Cache-Hit", true))
+	if fake.column("attributes_cache_hit") != bigQueryBoolean {
+		t.Errorf("Expected a new boolean column, got %v", fake.fields)
+	}
+	if fake.patches != 2 {
+		t.Errorf("Expected a schema patch per export with new columns, got %d", fake.patches)
+	}
+
+	if len(fake.rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(fake.rows))
+	}
+	first, second := fake.rows[0], fake.rows[1]
+	if first["attributes_http_status"] != float64(200) || first["timestamp"] != "2025-01-02T15:04:05Z" || first["body"] != "request done" {
+		t.Errorf("Unexpected first row %v", first)
+	}
+	if _, ok := second["attributes_http_status"]; ok || second["attributes_cache_hit"] != true {
+		t.Errorf("Expected the mismatched status left out of the second row, got %v", second)
+	}
+	if fake.auth != "Bearer test-token" {
+		t.Errorf("Expected the access token to be sent, got %q", fake.auth)
+	}
+}
+
+func TestBigQueryExporterRetryKeepsInsertIDs(t *testing.T) {
+	fake := &fakeBigQuery{reject: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+
+	exporter, err := newBigQueryExporter(&Config{
+		BigQueryTable:    "proj:logs.app",
+		BigQueryEndpoint: server.URL,
+		Timeout:          5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	provider, source := newRecordingProvider()
+	for _, body := range []string{"first", "second"} {
+		var record log.Record
+		record.SetBody(log.StringValue(body))
+		provider.Logger("otel-logger").Emit(ctx, record)
+	}
+	if err := exporter.Export(ctx, source.Records()); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+
+	if len(fake.rows) != 2 || len(fake.insertIDs) != 4 {
+		t.Fatalf("Expected 2 rows stored after a rejected attempt, got %d rows from %d sent", len(fake.rows), len(fake.insertIDs))
+	}
+	if fake.insertIDs[0] == fake.insertIDs[1] || fake.insertIDs[0] != fake.insertIDs[2] || fake.insertIDs[1] != fake.insertIDs[3] {
+		t.Errorf("Expected each row to keep its own insert ID when retried, got %v", fake.insertIDs)
+	}
+}
+
+func TestBigQueryColumnName(t *testing.T) {
+	tests := map[string]string{
+		"attributes.http.status": "attributes_http_status",
+		"resource.K8s-Pod":       "resource_k8s_pod",
+		"1st":                    "_1st",
+	}
+	for key, expected := range tests {
+		if got := bigQueryColumnName(key); got != expected {
+			t.Errorf("bigQueryColumnName(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+	if _, _, _, err := parseBigQueryTable("dataset.table"); err == nil || !strings.Contains(err.Error(), "PROJECT.DATASET.TABLE") {
+		t.Errorf("Expected error for a table without project, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Policies for streams that turn out to carry binary data (e.g. tar to stdout)
+const (
+	binaryOutputSample      = "sample"
+	binaryOutputSkip        = "skip"
+	binaryOutputPassthrough = "passthrough"
+)
+
+// binarySampleSize is how many leading bytes are base64 encoded in the sample policy
+const binarySampleSize = 64
+
+func validateBinaryOutput(policy string) error {
+	switch policy {
+	case "", binaryOutputSample, binaryOutputSkip, binaryOutputPassthrough:
+		return nil
+	default:
+		return fmt.Errorf("invalid --binary-output %q (supported: %s, %s, %s)", policy, binaryOutputSample, binaryOutputSkip, binaryOutputPassthrough)
+	}
+}
+
+// looksBinary reports whether a chunk of output is binary rather than text:
+// more than 30% control characters, NULs included, and invalid UTF-8, so a
+// stray NUL in a line of text does not count. Escape sequences for terminal
+// colors count as text.
+func looksBinary(p []byte) bool {
+	suspicious := 0
+	for i := 0; i < len(p); {
+		r, size := utf8.DecodeRune(p[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			// A rune split across reads is not evidence of binary data
+			if !utf8.FullRune(p[i:]) {
+				i = len(p)
+				continue
+			}
+			suspicious++
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != '\v' && r != '\b' && r != 0x1b:
+			suspicious++
+		case r == 0x7f:
+			suspicious++
+		}
+		i += size
+	}
+
+	return suspicious*10 > len(p)*3
+}
+
+// binaryGuard sits in front of the line scanner. Chunks that look binary are
+// copied to divert (raw passthrough or io.Discard) instead of being parsed
+// into garbage records; the scanner sees the text around them, so a stream
+// that turns back to text is parsed again.
+type binaryGuard struct {
+	r        io.Reader
+	divert   io.Writer
+	expected bool // binary framings: the data is decoded, never diverted
+
+	size   int64 // bytes diverted
+	sample []byte
+	passed int64        // bytes handed to the scanner
+	skips  []binarySkip // where diverted chunks were cut out
+}
+
+// binarySkip is a diverted chunk, found after passed bytes of text
+type binarySkip struct {
+	passed, size int64
+}
+
+func newBinaryGuard(r io.Reader, divert io.Writer) *binaryGuard {
+	if divert == nil {
+		divert = io.Discard
+	}
+	return &binaryGuard{r: r, divert: divert}
+}
+
+func (g *binaryGuard) Read(p []byte) (int, error) {
+	for {
+		n, err := g.r.Read(p)
+		if n == 0 || g.expected || !looksBinary(p[:n]) {
+			g.passed += int64(n)
+			return n, err
+		}
+
+		if g.size == 0 {
+			g.sample = append([]byte(nil), p[:min(n, binarySampleSize)]...)
+		}
+		g.divert.Write(p[:n])
+		g.size += int64(n)
+		if last := len(g.skips) - 1; last >= 0 && g.skips[last].passed == g.passed {
+			g.skips[last].size += int64(n)
+		} else {
+			g.skips = append(g.skips, binarySkip{passed: g.passed, size: int64(n)})
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// offset returns how far into the underlying reader the scanner is once it
+// has consumed passed bytes, counting the diverted chunks up to there
+func (g *binaryGuard) offset(passed int64) int64 {
+	offset := passed
+	for _, skip := range g.skips {
+		if skip.passed > passed {
+			break
+		}
+		offset += skip.size
+	}
+	return offset
+}
+
+// Entry describes the diverted binary data, or returns nil if the stream was text
+func (g *binaryGuard) Entry(stream, policy string) *LogEntry {
+	if g.size == 0 || policy == binaryOutputSkip {
+		return nil
+	}
+
+	name := stream
+	if name == "" {
+		name = "stdin"
+	}
+
+	entry := &LogEntry{
+		Timestamp: time.Now(),
+		Level:     "warn",
+		Message:   fmt.Sprintf("Binary data on %s (%d bytes) was not exported", name, g.size),
+		Fields: map[string]any{
+			"binary.size": g.size,
+		},
+		Raw:    fmt.Sprintf("binary data: %d bytes", g.size),
+		Stream: stream,
+	}
+	if policy == "" || policy == binaryOutputSample {
+		entry.Fields["binary.sample"] = base64.StdEncoding.EncodeToString(g.sample)
+	}
+	return entry
+}
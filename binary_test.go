@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected bool
+	}{
+		{"plain text", []byte("hello world\n"), false},
+		{"json", []byte(`{"level":"info","message":"ok"}` + "\n"), false},
+		{"ansi colors", []byte("\x1b[32mOK\x1b[0m\n"), false},
+		{"utf-8", []byte("naïve café ✓\n"), false},
+		{"latin-1 text", []byte("caf\xe9 au lait\n"), false},
+		{"split rune at end", []byte("caf\xc3"), false},
+		{"stray nul in text", []byte("user=\x00 logged in\n"), false},
+		{"nul bytes", []byte("ustar\x00\x00\x00"), true},
+		{"control heavy", []byte{0x01, 0x02, 0x03, 0x04, 'a', 0x05, 0x06}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := looksBinary(tt.input); got != tt.expected {
+				t.Errorf("looksBinary(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBinaryGuard(t *testing.T) {
+	binary := append([]byte("archive\x00"), bytes.Repeat([]byte{0xff, 0x00, 0x13}, 100)...)
+
+	tests := []struct {
+		name         string
+		policy       string
+		divert       bool
+		expectEntry  bool
+		expectSample bool
+	}{
+		{name: "sample", policy: binaryOutputSample, expectEntry: true, expectSample: true},
+		{name: "skip", policy: binaryOutputSkip},
+		{name: "passthrough", policy: binaryOutputPassthrough, divert: true, expectEntry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diverted bytes.Buffer
+			var divert io.Writer
+			if tt.divert {
+				divert = &diverted
+			}
+			guard := newBinaryGuard(bytes.NewReader(binary), divert)
+
+			var lines []string
+			for line := range multilineLogIteratorWith(guard, defaultContinuationPattern, multilineOptions{split: scanLinesCollapsingCR}) {
+				lines = append(lines, line)
+			}
+			if len(lines) != 0 {
+				t.Errorf("Expected no parsed lines from binary data, got %q", lines)
+			}
+
+			if tt.divert && !bytes.Equal(diverted.Bytes(), binary) {
+				t.Errorf("Expected all %d bytes to be diverted, got %d", len(binary), diverted.Len())
+			}
+
+			entry := guard.Entry("stdout", tt.policy)
+			if (entry != nil) != tt.expectEntry {
+				t.Fatalf("Expected entry=%v, got %+v", tt.expectEntry, entry)
+			}
+			if entry == nil {
+				return
+			}
+
+			if entry.Fields["binary.size"] != int64(len(binary)) {
+				t.Errorf("Expected size %d, got %v", len(binary), entry.Fields["binary.size"])
+			}
+			sample, hasSample := entry.Fields["binary.sample"].(string)
+			if hasSample != tt.expectSample {
+				t.Errorf("Expected sample=%v, got %v", tt.expectSample, entry.Fields["binary.sample"])
+			}
+			if hasSample {
+				decoded, _ := base64.StdEncoding.DecodeString(sample)
+				if !bytes.Equal(decoded, binary[:binarySampleSize]) {
+					t.Errorf("Sample does not match the first %d bytes", binarySampleSize)
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryGuardPassesText(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	guard := newBinaryGuard(strings.NewReader("one\ntwo\n"), nil)
+	for line := range multilineLogIteratorWith(guard, defaultContinuationPattern, multilineOptions{split: scanLinesCollapsingCR}) {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	if guard.Entry("stdout", binaryOutputSample) != nil {
+		t.Error("Text stream should not produce a binary notice")
+	}
+	if got := len(exporter.Records()); got != 2 {
+		t.Errorf("Expected 2 records, got %d", got)
+	}
+}
+
+// chunkReader returns one chunk per Read, as a pipe does one write
+type chunkReader struct {
+	chunks [][]byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	if r.chunks[0] = r.chunks[0][n:]; len(r.chunks[0]) == 0 {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestBinaryGuardReturnsToText(t *testing.T) {
+	binary := bytes.Repeat([]byte{0xff, 0x00, 0x13}, 100)
+	chunks := [][]byte{
+		[]byte("one\n"),
+		[]byte("two \x00 three\n"),
+		binary,
+		[]byte("four\n"),
+	}
+	guard := newBinaryGuard(&chunkReader{chunks: slices.Clone(chunks)}, nil)
+
+	var lines []string
+	for line := range multilineLogIteratorWith(guard, defaultContinuationPattern, multilineOptions{split: scanLinesCollapsingCR}) {
+		lines = append(lines, line)
+	}
+	if want := []string{"one", "two \x00 three", "four"}; !slices.Equal(lines, want) {
+		t.Errorf("Expected %q, got %q", want, lines)
+	}
+
+	entry := guard.Entry("stdout", binaryOutputSample)
+	if entry == nil || entry.Fields["binary.size"] != int64(len(binary)) {
+		t.Fatalf("Expected a notice for the %d binary bytes, got %+v", len(binary), entry)
+	}
+
+	// Offsets into the stream count the diverted bytes from where they were
+	textBefore := int64(len(chunks[0]) + len(chunks[1]))
+	if got := guard.offset(textBefore - 1); got != textBefore-1 {
+		t.Errorf("Expected offset %d before the binary data, got %d", textBefore-1, got)
+	}
+	if got, want := guard.offset(textBefore+5), textBefore+int64(len(binary))+5; got != want {
+		t.Errorf("Expected offset %d after the binary data, got %d", want, got)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errShortRecord means a binary record continues past the data read so far
+var errShortRecord = errors.New("record is incomplete")
+
+// maxRecordDepth bounds the nesting of decoded maps and arrays, so a corrupt
+// or hostile record cannot exhaust the stack
+const maxRecordDepth = 100
+
+// binaryFraming reports whether a framing reads binary records, which the
+// binary guard must let through rather than divert
+func binaryFraming(framing string) bool {
+	switch framing {
+	case framingJournalExport, framingMsgpack, framingCBOR, framingProtobuf, framingAvro, framingAvroConfluent:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordReader reads the bytes of one binary record from the scanner buffer
+type recordReader struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next n bytes, or errShortRecord if they have not been
+// read yet
+func (r *recordReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, errShortRecord
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes
+func (r *recordReader) uint(size int) (uint64, error) {
+	data, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// capacity bounds the space allocated for n items by the bytes left, which
+// a corrupt count must not exceed
+func (r *recordReader) capacity(n uint64) int {
+	if left := uint64(len(r.data) - r.pos); n > left {
+		return int(left)
+	}
+	return int(n)
+}
+
+func (r *recordReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// scanRecords returns a split function that decodes one record at a time
+// with decode and hands it on as a line of JSON, so that it is parsed like
+// any other JSON log. Records cannot be resynchronized after corrupt data,
+// so a decoding error ends the input.
+func scanRecords(format string, decode func(*recordReader) (any, error)) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		r := &recordReader{data: data}
+		record, err := decode(r)
+		if errors.Is(err, errShortRecord) {
+			if atEOF {
+				return 0, nil, fmt.Errorf("truncated %s record", format)
+			}
+			return 0, nil, nil
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid %s record: %w", format, err)
+		}
+		token, err = json.Marshal(record)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid %s record: %w", format, err)
+		}
+		return r.pos, token, nil
+	}
+}
+
+// recordField is one key of a decoded map
+type recordField struct {
+	key   string
+	value any
+}
+
+// orderedMap is a decoded map that keeps its keys in the order they were
+// written, so attributes come out in the producer's order
+type orderedMap []recordField
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, field := range m {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// recordKey turns a map key, which need not be a string in MessagePack or
+// CBOR, into an attribute name
+func recordKey(key any) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+// recordFloat keeps NaN and infinities, which JSON cannot represent, as text
+func recordFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestScanMsgpack(t *testing.T) {
+	input := "\x83\xa3msg\xa2hi\xa1n\x01\xa5level\xa4warn" +
+		"\x84\xa1i\xd0\xff\xa1u\xcd\x01\x00\xa1t\xd6\xff\x00\x00\x00\x01\xa1f\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00" +
+		"\x82\xa1a\x92\xc3\xc0\xa1b\xc4\x02\x01\x02"
+	expected := []string{
+		`{"msg":"hi","n":1,"level":"warn"}`,
+		`{"i":-1,"u":256,"t":"1970-01-01T00:00:01Z","f":1.5}`,
+		`{"a":[true,null],"b":"AQI="}`,
+	}
+	if tokens := scanAll(t, input, scanRecords("MessagePack", decodeMsgpack)); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tokens, "\n"))
+	}
+}
+
+func TestScanCBOR(t *testing.T) {
+	input := "\xa3\x63msg\x62hi\x61n\x21\x64tags\x81\x61a" +
+		"\xbf\x61a\xf5\x61t\xc1\x1a\x00\x00\x00\x01\x61h\xf9\x3c\x00\xff"
+	expected := []string{
+		`{"msg":"hi","n":-2,"tags":["a"]}`,
+		`{"a":true,"t":"1970-01-01T00:00:01Z","h":1}`,
+	}
+	if tokens := scanAll(t, input, scanRecords("CBOR", decodeCBOR)); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tokens, "\n"))
+	}
+}
+
+func TestScanRecordsErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"truncated": "\x82\xa3msg",
+		"invalid":   "\xc1",
+		"deep":      strings.Repeat("\x91", maxRecordDepth+2),
+	} {
+		t.Run(name, func(t *testing.T) {
+			scanner := scannerWithSplit(input, scanRecords("MessagePack", decodeMsgpack))
+			for scanner.Scan() {
+			}
+			if scanner.Err() == nil {
+				t.Error("Expected a decoding error")
+			}
+		})
+	}
+}
+
+func TestScanProtobuf(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("record.proto"),
+		Package: proto.String("acme.logging"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Record"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("message"), JsonName: proto.String("message"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("status_code"), JsonName: proto.String("statusCode"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatalf("Failed to encode descriptor set: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "record.pb")
+	if err := os.WriteFile(path, set, 0o644); err != nil {
+		t.Fatalf("Failed to write descriptor set: %v", err)
+	}
+
+	config := &Config{Framing: framingProtobuf, ProtoDescriptor: path, ProtoMessage: "acme.logging.Record"}
+	message, err := loadProtoMessage(config.ProtoDescriptor, config.ProtoMessage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var input []byte
+	for _, status := range []int32{200, 503} {
+		record := dynamicpb.NewMessage(message)
+		record.Set(message.Fields().ByName("message"), protoreflect.ValueOfString("served"))
+		record.Set(message.Fields().ByName("status_code"), protoreflect.ValueOfInt32(status))
+		data, err := proto.Marshal(record)
+		if err != nil {
+			t.Fatalf("Failed to encode record: %v", err)
+		}
+		input = protowire.AppendVarint(input, uint64(len(data)))
+		input = append(input, data...)
+	}
+
+	split, err := splitFunc(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{`{"message":"served","status_code":200}`, `{"message":"served","status_code":503}`}
+	if tokens := scanAll(t, string(input), split); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected %q, got %q", expected, tokens)
+	}
+
+	for _, bad := range []*Config{
+		{Framing: framingProtobuf},
+		{Framing: framingProtobuf, ProtoDescriptor: path, ProtoMessage: "acme.logging.Missing"},
+	} {
+		if _, err := splitFunc(bad); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+}
+
+func TestBinaryFramingBypassesGuard(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	config := &Config{Framing: framingMsgpack, ContinuationPattern: `^[ \t]`}
+
+	input := "\x82\xa3msg\xa5first\xa4zero\x00\x81\xa3msg\xa6second"
+	if err := processReader(context.Background(), config, extractor, processor, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var bodies []string
+	for _, record := range exporter.Records() {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	if !reflect.DeepEqual(bodies, []string{"first", "second"}) {
+		t.Errorf("Expected both records to be exported, got %q", bodies)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import "fmt"
+
+// --body-mode values
+const (
+	bodyModeMessage = "message" // the message string, with the other fields as attributes
+	bodyModeObject  = "object"  // the whole parsed object as a map
+)
+
+func validBodyMode(config *Config) error {
+	switch config.BodyMode {
+	case "", bodyModeMessage:
+		return nil
+	case bodyModeObject:
+		if len(config.BodyFields) > 0 {
+			return fmt.Errorf("--body-field cannot be combined with --body-mode %s, which already puts every field in the body", bodyModeObject)
+		}
+		// These only apply to fields exported as attributes, so the object
+		// would carry the fields they are meant to hide or change
+		for _, option := range []struct {
+			flag   string
+			values []string
+		}{
+			{"--hash-field", config.HashFields},
+			{"--attr-allowlist", config.AttrAllowlist},
+			{"--drop-field", config.DropFields},
+			{"--rename-field", config.RenameFields},
+			{"--lookup", config.Lookups},
+		} {
+			if len(option.values) > 0 {
+				return fmt.Errorf("%s cannot be combined with --body-mode %s, which exports the parsed object as it is", option.flag, bodyModeObject)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid --body-mode %q (supported: %s, %s)", config.BodyMode, bodyModeMessage, bodyModeObject)
+	}
+}
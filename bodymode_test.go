@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestBodyModeObject(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetBodyMode(bodyModeObject)
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	extractor.SetKeepObject(true)
+
+	for _, line := range []string{
+		`{"time":"2025-01-02T15:04:05Z","level":"warn","msg":"slow","ms":120,"http":{"method":"GET"}}`,
+		`plain text`,
+	} {
+		entry, _ := extractor.ParseLogEntry(line)
+		entry.Raw, entry.TimestampSource = "", ""
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	records := exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	body := records[0].Body()
+	if body.Kind() != log.KindMap {
+		t.Fatalf("Expected a map body, got %v", body.Kind())
+	}
+	expected := map[string]any{
+		"time":  "2025-01-02T15:04:05Z",
+		"level": "warn",
+		"msg":   "slow",
+		"ms":    int64(120),
+		"http":  map[string]any{"method": "GET"},
+	}
+	if got := plainValue(body); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected body %v, got %v", expected, got)
+	}
+	if records[0].Severity() != log.SeverityWarn1 {
+		t.Errorf("Expected the level to still set the severity, got %v", records[0].Severity())
+	}
+	if attrs := recordAttributes(records[0]); len(attrs) != 0 {
+		t.Errorf("Expected no field attributes, got %v", attrs)
+	}
+
+	if body := records[1].Body(); body.Kind() != log.KindString || body.AsString() != "plain text" {
+		t.Errorf("Expected plain lines to keep a string body, got %v", body)
+	}
+}
+
+func TestValidBodyMode(t *testing.T) {
+	for _, config := range []Config{{}, {BodyMode: bodyModeMessage}, {BodyMode: bodyModeObject}, {BodyMode: bodyModeMessage, BodyFields: []string{"a"}}, {BodyMode: bodyModeMessage, HashFields: []string{"user_id"}, AttrAllowlist: []string{"msg"}}} {
+		if err := validBodyMode(&config); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", config, err)
+		}
+	}
+	for _, config := range []Config{
+		{BodyMode: "json"},
+		{BodyMode: bodyModeObject, BodyFields: []string{"a"}},
+		{BodyMode: bodyModeObject, HashFields: []string{"user_id"}},
+		{BodyMode: bodyModeObject, AttrAllowlist: []string{"msg"}},
+		{BodyMode: bodyModeObject, DropFields: []string{"ssn"}},
+		{BodyMode: bodyModeObject, RenameFields: []string{"userId=enduser.id"}},
+		{BodyMode: bodyModeObject, Lookups: []string{"tenant_id=tenants.csv"}},
+	} {
+		if err := validBodyMode(&config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
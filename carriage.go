@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+// Modes for lines rewritten in place with carriage returns (progress bars)
+const (
+	carriageReturnCollapse = "collapse"
+	carriageReturnKeep     = "keep"
+)
+
+// lineSplitFunc returns the scanner split function for the configured
+// carriage-return handling
+func lineSplitFunc(mode string) (bufio.SplitFunc, error) {
+	switch mode {
+	case "", carriageReturnCollapse:
+		return scanLinesCollapsingCR, nil
+	case carriageReturnKeep:
+		return bufio.ScanLines, nil
+	default:
+		return nil, fmt.Errorf("invalid --carriage-return %q (supported: %s, %s)", mode, carriageReturnCollapse, carriageReturnKeep)
+	}
+}
+
+// scanLinesCollapsingCR is like bufio.ScanLines but keeps only the final state
+// of lines redrawn with '\r' (npm, pip, docker pull progress). Superseded
+// states are discarded as they arrive, so a long-running progress bar without
+// a newline never grows past the scanner buffer.
+func scanLinesCollapsingCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line := dropTrailingCR(data[:i])
+		if j := bytes.LastIndexByte(line, '\r'); j >= 0 {
+			line = lastRedraw(line, j)
+		}
+		return i + 1, line, nil
+	}
+
+	// No complete line yet: drop states already overwritten by a later redraw,
+	// keeping a trailing '\r' in case it is the first half of "\r\n"
+	if j := bytes.LastIndexByte(data[:len(data)-1], '\r'); j >= 0 && !atEOF {
+		if rest := data[j+1:]; len(bytes.TrimRight(rest, "\r")) > 0 {
+			return j + 1, nil, nil
+		}
+	}
+
+	if atEOF {
+		line := dropTrailingCR(data)
+		if j := bytes.LastIndexByte(line, '\r'); j >= 0 {
+			line = lastRedraw(line, j)
+		}
+		return len(data), line, nil
+	}
+
+	return 0, nil, nil
+}
+
+// lastRedraw returns the text after the last carriage return, falling back to
+// the previous state when the final redraw is empty (a bare trailing "\r")
+func lastRedraw(line []byte, lastCR int) []byte {
+	if lastCR+1 < len(line) {
+		return line[lastCR+1:]
+	}
+	trimmed := bytes.TrimRight(line, "\r")
+	if j := bytes.LastIndexByte(trimmed, '\r'); j >= 0 {
+		return trimmed[j+1:]
+	}
+	return trimmed
+}
+
+func dropTrailingCR(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == '\r' {
+		return data[:len(data)-1]
+	}
+	return data
+}
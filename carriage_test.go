@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanLinesCollapsingCR(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "plain lines",
+			input:    "first\nsecond\n",
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "progress bar collapses to final state",
+			input:    "Downloading 10%\rDownloading 50%\rDownloading 100%\nDone\n",
+			expected: []string{"Downloading 100%", "Done"},
+		},
+		{
+			name:     "CRLF line endings are not redraws",
+			input:    "first\r\nsecond\r\n",
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "trailing carriage return keeps last state",
+			input:    "10%\r100%\r\nnext\n",
+			expected: []string{"100%", "next"},
+		},
+		{
+			name:     "final line without newline",
+			input:    "10%\r20%\r30%",
+			expected: []string{"30%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time exercises the partial-line path
+			scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(tt.input)))
+			scanner.Split(scanLinesCollapsingCR)
+
+			var lines []string
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("Unexpected scanner error: %v", err)
+			}
+			if !reflect.DeepEqual(lines, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, lines)
+			}
+		})
+	}
+}
+
+func TestScanLinesCollapsingCRLongProgress(t *testing.T) {
+	// Far more redraws than fit in the default 64KiB scanner buffer
+	var input strings.Builder
+	for i := 0; i < 20000; i++ {
+		input.WriteString("\rprogress ")
+		input.WriteString(strings.Repeat("#", i%40))
+	}
+	input.WriteString("\rcomplete\n")
+
+	scanner := bufio.NewScanner(strings.NewReader(input.String()))
+	scanner.Split(scanLinesCollapsingCR)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected scanner error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"complete"}) {
+		t.Errorf("Expected only the final state, got %d lines", len(lines))
+	}
+}
+
+func TestLineSplitFunc(t *testing.T) {
+	for _, mode := range []string{"", carriageReturnCollapse, carriageReturnKeep} {
+		if _, err := lineSplitFunc(mode); err != nil {
+			t.Errorf("Unexpected error for mode %q: %v", mode, err)
+		}
+	}
+	if _, err := lineSplitFunc("squash"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CBOR tags with a meaning for log records (RFC 8949)
+const (
+	cborTagDateTime = 0 // RFC 3339 text
+	cborTagEpoch    = 1 // seconds since the epoch
+)
+
+// cborBreak ends the items of an indefinite-length value
+const cborBreak = 0xff
+
+// decodeCBOR decodes one CBOR data item. Maps keep their key order, byte
+// strings are encoded as base64 and date/time tags become times; other tags
+// are dropped in favor of the value they enclose.
+func decodeCBOR(r *recordReader) (any, error) {
+	return decodeCBORValue(r, 0)
+}
+
+func decodeCBORValue(r *recordReader, depth int) (any, error) {
+	if depth > maxRecordDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxRecordDepth)
+	}
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := b>>5, b&0x1f
+
+	if major == 7 {
+		return decodeCBORSimple(r, info)
+	}
+	if info == 31 {
+		return decodeCBORIndefinite(r, major, depth)
+	}
+	n, err := cborArgument(r, info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 1:
+		if n > math.MaxInt64 {
+			return -1 - float64(n), nil
+		}
+		return -1 - int64(n), nil
+	case 2:
+		data, err := r.next(n)
+		return append([]byte(nil), data...), err
+	case 3:
+		data, err := r.next(n)
+		return string(data), err
+	case 4:
+		items := make([]any, 0, r.capacity(n))
+		for range n {
+			item, err := decodeCBORValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		fields := make(orderedMap, 0, r.capacity(n*2)/2)
+		for range n {
+			field, err := decodeCBORField(r, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+		}
+		return fields, nil
+	default: // 6, a tag
+		value, err := decodeCBORValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTagged(n, value), nil
+	}
+}
+
+// cborArgument reads the argument of an item: its value, length or count
+func cborArgument(r *recordReader, info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return r.uint(1 << (info - 24))
+	default:
+		return 0, fmt.Errorf("reserved CBOR additional information %d", info)
+	}
+}
+
+func decodeCBORField(r *recordReader, depth int) (recordField, error) {
+	key, err := decodeCBORValue(r, depth+1)
+	if err != nil {
+		return recordField{}, err
+	}
+	value, err := decodeCBORValue(r, depth+1)
+	if err != nil {
+		return recordField{}, err
+	}
+	return recordField{recordKey(key), value}, nil
+}
+
+// decodeCBORIndefinite reads the chunks of an indefinite-length string or
+// the items of an indefinite-length array or map, up to the break
+func decodeCBORIndefinite(r *recordReader, major byte, depth int) (any, error) {
+	var chunks []byte
+	var items []any
+	var fields orderedMap
+	for {
+		if r.pos >= len(r.data) {
+			return nil, errShortRecord
+		}
+		if r.data[r.pos] == cborBreak {
+			r.pos++
+			break
+		}
+		switch major {
+		case 2, 3:
+			chunk, err := decodeCBORValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			switch c := chunk.(type) {
+			case []byte:
+				chunks = append(chunks, c...)
+			case string:
+				chunks = append(chunks, c...)
+			default:
+				return nil, fmt.Errorf("invalid chunk in CBOR string")
+			}
+		case 4:
+			item, err := decodeCBORValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case 5:
+			field, err := decodeCBORField(r, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+		default:
+			return nil, fmt.Errorf("CBOR major type %d cannot have an indefinite length", major)
+		}
+	}
+
+	switch major {
+	case 2:
+		return append([]byte{}, chunks...), nil
+	case 3:
+		return string(chunks), nil
+	case 4:
+		return append([]any{}, items...), nil
+	default:
+		return append(orderedMap{}, fields...), nil
+	}
+}
+
+// decodeCBORSimple reads the simple values and floats of major type 7
+func decodeCBORSimple(r *recordReader, info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 24:
+		value, err := r.byte()
+		return int64(value), err
+	case 25:
+		data, err := r.next(2)
+		if err != nil {
+			return nil, err
+		}
+		return recordFloat(halfFloat(binary.BigEndian.Uint16(data))), nil
+	case 26:
+		data, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return recordFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data)))), nil
+	case 27:
+		data, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return recordFloat(math.Float64frombits(binary.BigEndian.Uint64(data))), nil
+	case 31:
+		return nil, fmt.Errorf("unexpected CBOR break")
+	default:
+		if info > 27 {
+			return nil, fmt.Errorf("reserved CBOR additional information %d", info)
+		}
+		return int64(info), nil
+	}
+}
+
+// halfFloat converts an IEEE 754 half-precision float
+func halfFloat(h uint16) float64 {
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// cborTagged interprets the value enclosed by a tag
+func cborTagged(tag uint64, value any) any {
+	switch tag {
+	case cborTagDateTime:
+		if text, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+				return t
+			}
+		}
+	case cborTagEpoch:
+		switch seconds := value.(type) {
+		case int64:
+			return time.Unix(seconds, 0).UTC()
+		case float64:
+			return epochTime(seconds, timestampSeconds).UTC()
+		}
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// checkpointFile holds the read offsets of the --file files in --checkpoint-dir
+const checkpointFile = "files.json"
+
+// checkpointFingerprintSize is how much of the start of a file identifies it
+// along with its inode, which a new file can reuse
+const checkpointFingerprintSize = 1024
+
+// fileCheckpoint is how far the records exported from a file go
+type fileCheckpoint struct {
+	Inode       uint64 `json:"inode"`
+	Fingerprint string `json:"fingerprint,omitempty"` // SHA-256 of the first bytes, up to the offset
+	Offset      int64  `json:"offset"`
+	Complete    bool   `json:"complete,omitempty"` // a compressed archive, read to its end
+}
+
+// validateCheckpoints checks --checkpoint-dir and --checkpoint-interval
+func validateCheckpoints(config *Config) error {
+	if config.CheckpointDir == "" {
+		return nil
+	}
+	if len(config.Files) == 0 && !config.Journald {
+		return errors.New("--checkpoint-dir requires --file or --journald")
+	}
+	if config.CheckpointInterval <= 0 {
+		return fmt.Errorf("invalid --checkpoint-interval %s: must be positive", config.CheckpointInterval)
+	}
+	return nil
+}
+
+// checkpointStore keeps the offsets of followed files in a state file, so a
+// restarted otel-logger resumes each file where the last one's exported
+// records end rather than at its end or beginning
+type checkpointStore struct {
+	path string
+
+	mu    sync.Mutex
+	saved map[string]fileCheckpoint // by path, loaded at startup and not resumed yet
+}
+
+// openCheckpointStore loads the checkpoints in dir, or returns nil when dir
+// is empty
+func openCheckpointStore(dir string) (*checkpointStore, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	s := &checkpointStore{path: filepath.Join(dir, checkpointFile), saved: make(map[string]fileCheckpoint)}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.saved); err != nil {
+		// Starting over beats refusing to ship logs
+		logError("Ignoring unreadable checkpoints in %s: %v\n", s.path, err)
+		s.saved = make(map[string]fileCheckpoint)
+	}
+	return s, nil
+}
+
+// take returns the saved checkpoint for path, once
+func (s *checkpointStore) take(path string) (fileCheckpoint, bool) {
+	if s == nil {
+		return fileCheckpoint{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, ok := s.saved[path]
+	delete(s.saved, path)
+	return checkpoint, ok
+}
+
+// takeArchives returns the saved archives read in full, by path
+func (s *checkpointStore) takeArchives() map[string]fileCheckpoint {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archives := make(map[string]fileCheckpoint)
+	for path, checkpoint := range s.saved {
+		if checkpoint.Complete {
+			archives[path] = checkpoint
+			delete(s.saved, path)
+		}
+	}
+	return archives
+}
+
+// save replaces the state file
+func (s *checkpointStore) save(checkpoints map[string]fileCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(data, '\n'))
+}
+
+// writeFileAtomic replaces the file at path, atomically so a crash leaves
+// the old one
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// fileFingerprint hashes the start of file up to offset, which does not
+// change as the file is appended to
+func fileFingerprint(file io.ReaderAt, offset int64) (string, error) {
+	size := int64(checkpointFingerprintSize)
+	if offset < size {
+		size = offset
+	}
+	if size == 0 {
+		return "", nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// splitCounter wraps a split function to track how far into the stream the
+// tokens it returns go, so the offset an entry ends at is known
+type splitCounter struct {
+	split     bufio.SplitFunc
+	consumed  int64 // end of the last token
+	lineStart int64 // start of the last token
+}
+
+func (c *splitCounter) Split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := c.split(data, atEOF)
+	if token != nil {
+		c.lineStart = c.consumed
+	}
+	c.consumed += int64(advance)
+	return advance, token, err
+}
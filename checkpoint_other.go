@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileInode is always 0 without inodes, so a checkpoint is only matched to
+// a file by its path, its size and the fingerprint of its first bytes
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// followFiles runs processFiles until want records are read and returns the
+// bodies of those exported. The last record is only complete, and exported,
+// once reading stops.
+func followFiles(t *testing.T, config *Config, want int) []string {
+	t.Helper()
+	provider, exporter := newRecordingProvider()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- processFiles(ctx, config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test")))
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(exporter.Records()) < want-1 {
+		time.Sleep(20 * time.Millisecond)
+	}
+	// Time for the last record to be read, and anything read twice
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var bodies []string
+	for _, record := range exporter.Records() {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	return bodies
+}
+
+func TestFileCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, `{"msg":"one"}`+"\n"+`{"msg":"two"}`+"\n")
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		Files:               []string{path},
+		FileFromStart:       true,
+		CheckpointDir:       filepath.Join(dir, "state"),
+		CheckpointInterval:  time.Hour,
+	}
+
+	if bodies := followFiles(t, config, 2); !slices.Equal(bodies, []string{"one", "two"}) {
+		t.Fatalf("Expected both records, got %v", bodies)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "state", checkpointFile))
+	if err != nil {
+		t.Fatalf("Expected checkpoints to be saved on shutdown: %v", err)
+	}
+	var saved map[string]fileCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	if saved[path].Offset != info.Size() || saved[path].Fingerprint == "" {
+		t.Errorf("Expected the checkpoint at the end of the file, got %+v", saved[path])
+	}
+
+	// A restart picks up where the last run stopped
+	appendFile(t, path, `{"msg":"three"}`+"\n")
+	if bodies := followFiles(t, config, 1); !slices.Equal(bodies, []string{"three"}) {
+		t.Errorf("Expected only the new record after a restart, got %v", bodies)
+	}
+
+	// A different file at the path is read from the beginning
+	os.Remove(path)
+	appendFile(t, path, `{"msg":"rotated"}`+"\n")
+	if bodies := followFiles(t, config, 1); !slices.Equal(bodies, []string{"rotated"}) {
+		t.Errorf("Expected the replaced file to be read in full, got %v", bodies)
+	}
+}
+
+func TestValidateCheckpoints(t *testing.T) {
+	if err := validateCheckpoints(&Config{CheckpointDir: "state", CheckpointInterval: time.Second}); err == nil {
+		t.Error("Expected error without --file")
+	}
+	if err := validateCheckpoints(&Config{CheckpointDir: "state", Files: []string{"*.log"}}); err == nil {
+		t.Error("Expected error for a zero interval")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Ways --scrub-child-env hides a variable from the wrapped command
+const (
+	scrubChildEnvRemove = "remove"
+	scrubChildEnvMask   = "mask" // keep the variable, set to [REDACTED]
+)
+
+// minScrubbedValue is the shortest scrubbed value masked in captured
+// metadata; shorter ones such as "1" or "true" would mask unrelated text
+const minScrubbedValue = 4
+
+// envScrubber hides environment variables matching any of its patterns,
+// shell globs such as AWS_* matched against the variable name
+type envScrubber struct {
+	patterns []string
+	mask     bool
+}
+
+// newEnvScrubber parses the --scrub-child-env values, each one or more
+// comma-separated patterns; it returns nil when there are none
+func newEnvScrubber(values []string, mode string) (*envScrubber, error) {
+	s := &envScrubber{}
+	switch mode {
+	case "", scrubChildEnvRemove:
+	case scrubChildEnvMask:
+		s.mask = true
+	default:
+		return nil, fmt.Errorf("invalid --scrub-child-env-mode %q (supported: %s, %s)", mode, scrubChildEnvRemove, scrubChildEnvMask)
+	}
+	for _, value := range values {
+		for _, pattern := range strings.Split(value, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid --scrub-child-env pattern %q: %w", pattern, err)
+			}
+			s.patterns = append(s.patterns, pattern)
+		}
+	}
+	if len(s.patterns) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+func (s *envScrubber) matches(name string) bool {
+	for _, pattern := range s.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// environ returns env, as from os.Environ, without the matching variables or
+// with their values masked
+func (s *envScrubber) environ(env []string) []string {
+	if s == nil {
+		return env
+	}
+	scrubbed := make([]string, 0, len(env))
+	for _, variable := range env {
+		name, _, _ := strings.Cut(variable, "=")
+		if !s.matches(name) {
+			scrubbed = append(scrubbed, variable)
+		} else if s.mask {
+			scrubbed = append(scrubbed, name+"="+redactionMask)
+		}
+	}
+	return scrubbed
+}
+
+// maskValues replaces the values of the matching variables in env wherever
+// they appear in args, so the command line a shell expanded them into is
+// not captured either
+func (s *envScrubber) maskValues(env, args []string) []string {
+	if s == nil {
+		return args
+	}
+	var values []string
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		if len(value) >= minScrubbedValue && s.matches(name) {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return args
+	}
+	masked := make([]string, len(args))
+	for i, arg := range args {
+		for _, value := range values {
+			arg = strings.ReplaceAll(arg, value, redactionMask)
+		}
+		masked[i] = arg
+	}
+	return masked
+}
+
+// childEnvScrubber returns the scrubber configured for the wrapped command
+func childEnvScrubber(config *Config) (*envScrubber, error) {
+	return newEnvScrubber(config.ScrubChildEnv, config.ScrubChildEnvMode)
+}
+
+// scrubbedArgs masks scrubbed values in captured command lines; the
+// configuration was validated before anything is captured
+func scrubbedArgs(config *Config, args []string) []string {
+	scrubber, _ := childEnvScrubber(config)
+	return scrubber.maskValues(os.Environ(), args)
+}
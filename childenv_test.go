@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnvScrubber(t *testing.T) {
+	env := []string{"AWS_SECRET_ACCESS_KEY=abcd1234", "SECRET_TOKEN=s3cr3t", "PATH=/usr/bin", "AWS=1"}
+
+	scrubber, err := newEnvScrubber([]string{"AWS_*, SECRET_*"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := scrubber.environ(env); !reflect.DeepEqual(got, []string{"PATH=/usr/bin", "AWS=1"}) {
+		t.Errorf("Expected matching variables to be removed, got %v", got)
+	}
+
+	scrubber, _ = newEnvScrubber([]string{"AWS_*", "SECRET_*"}, scrubChildEnvMask)
+	expected := []string{"AWS_SECRET_ACCESS_KEY=[REDACTED]", "SECRET_TOKEN=[REDACTED]", "PATH=/usr/bin", "AWS=1"}
+	if got := scrubber.environ(env); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected matching variables to be masked, got %v", got)
+	}
+
+	args := scrubber.maskValues(env, []string{"deploy", "--key=abcd1234", "--token", "s3cr3t"})
+	if !reflect.DeepEqual(args, []string{"deploy", "--key=[REDACTED]", "--token", "[REDACTED]"}) {
+		t.Errorf("Expected scrubbed values to be masked in arguments, got %v", args)
+	}
+
+	if s, err := newEnvScrubber([]string{" , "}, ""); s != nil || err != nil {
+		t.Errorf("Expected no scrubber without patterns, got %v (%v)", s, err)
+	}
+	if got := (*envScrubber)(nil).environ(env); !reflect.DeepEqual(got, env) {
+		t.Errorf("Expected a nil scrubber to keep the environment, got %v", got)
+	}
+	if _, err := newEnvScrubber([]string{"AWS_["}, ""); err == nil {
+		t.Error("Expected error for an invalid pattern")
+	}
+	if _, err := newEnvScrubber([]string{"AWS_*"}, "shred"); err == nil {
+		t.Error("Expected error for an unknown mode")
+	}
+}
+
+func TestExecuteCommandScrubsChildEnv(t *testing.T) {
+	t.Setenv("SCRUB_TEST_TOKEN", "hunter22")
+	t.Setenv("SCRUB_TEST_KEPT", "visible")
+	dir := filepath.Join(t.TempDir(), "session")
+
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		ScrubChildEnv:       []string{"SCRUB_TEST_TOKEN"},
+		RecordSession:       dir,
+		Command:             []string{"sh", "-c", `echo "token=${SCRUB_TEST_TOKEN:-unset} kept=$SCRUB_TEST_KEPT"`, "hunter22"},
+	}
+	provider, exporter := newRecordingProvider()
+	if err := executeCommand(context.Background(), config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var bodies []string
+	for _, record := range exporter.Records() {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	if !slices.Contains(bodies, "token=unset kept=visible") {
+		t.Errorf("Expected the child not to see the scrubbed variable, got %q", bodies)
+	}
+
+	info, err := os.ReadFile(filepath.Join(dir, sessionInfoFile))
+	if err != nil {
+		t.Fatalf("Failed to read session info: %v", err)
+	}
+	if strings.Contains(string(info), "hunter22") || !strings.Contains(string(info), redactionMask) {
+		t.Errorf("Expected the scrubbed value to be masked in the session info, got:\n%s", info)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clfPattern matches the Common Log Format written by Apache and nginx, and
+// the Combined Log Format that adds the referer and user agent:
+// host ident authuser [date] "request" status bytes ["referer" "user-agent"]
+var clfPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?\s*$`)
+
+// clfTimestamp is the layout of the [date] field
+const clfTimestamp = "02/Jan/2006:15:04:05 -0700"
+
+// ParseCLFEntry decodes an access log line in Common or Combined Log Format
+// into HTTP semantic convention attributes. The request line is the message
+// and the status code sets the level: error for 5xx, warn for 4xx. Lines that
+// are not access log lines become plain messages.
+func (je *JSONExtractor) ParseCLFEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseCLF(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseCLF(line string) (*LogEntry, bool) {
+	m := clfPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	timestamp, err := time.Parse(clfTimestamp, m[4])
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &LogEntry{
+		Timestamp: timestamp,
+		Level:     "info",
+		Message:   unescapeCLF(m[5]),
+		Fields:    make(map[string]any),
+		Raw:       line,
+	}
+	set := func(key, value string) {
+		if value != "" && value != "-" {
+			entry.Fields[key] = value
+		}
+	}
+
+	set("client.address", m[1])
+	set("user.name", m[3])
+	setRequestLine(set, entry.Message)
+
+	status, _ := strconv.Atoi(m[6])
+	entry.Fields["http.response.status_code"] = status
+	entry.Level = statusLevel(status)
+	if size, err := strconv.Atoi(m[7]); err == nil {
+		entry.Fields["http.response.body.size"] = size
+	}
+	set("http.request.header.referer", unescapeCLF(m[8]))
+	set("user_agent.original", unescapeCLF(m[9]))
+	return entry, true
+}
+
+// setRequestLine stores the method, path, query and protocol of an HTTP
+// request line such as "GET /index.html?q=1 HTTP/1.1"
+func setRequestLine(set func(key, value string), request string) {
+	method, rest, ok := strings.Cut(request, " ")
+	if !ok {
+		return
+	}
+	target, protocol, _ := strings.Cut(rest, " ")
+	set("http.request.method", method)
+	path, query, _ := strings.Cut(target, "?")
+	set("url.path", path)
+	set("url.query", query)
+	if name, version, ok := strings.Cut(protocol, "/"); ok {
+		set("network.protocol.name", strings.ToLower(name))
+		set("network.protocol.version", version)
+	}
+}
+
+// statusLevel is the level of a response: error for 5xx, warn for 4xx and
+// info otherwise
+func statusLevel(status int) string {
+	switch {
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "warn"
+	}
+	return "info"
+}
+
+// unescapeCLF undoes the backslash escaping of quoted fields
+func unescapeCLF(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCLFEntry(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		line            string
+		expectedLevel   string
+		expectedMessage string
+		expectedFields  map[string]any
+	}{
+		{
+			name:            "common log format",
+			line:            `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?x=1 HTTP/1.0" 200 2326`,
+			expectedLevel:   "info",
+			expectedMessage: "GET /apache_pb.gif?x=1 HTTP/1.0",
+			expectedFields: map[string]any{
+				"client.address":            "127.0.0.1",
+				"user.name":                 "frank",
+				"http.request.method":       "GET",
+				"url.path":                  "/apache_pb.gif",
+				"url.query":                 "x=1",
+				"network.protocol.name":     "http",
+				"network.protocol.version":  "1.0",
+				"http.response.status_code": 200,
+				"http.response.body.size":   2326,
+			},
+		},
+		{
+			name:            "combined log format",
+			line:            `10.0.0.5 - - [02/Jan/2025:11:00:00 +0000] "POST /api/orders HTTP/1.1" 503 - "https://shop.example/" "Mozilla/5.0 (\"quoted\")"`,
+			expectedLevel:   "error",
+			expectedMessage: "POST /api/orders HTTP/1.1",
+			expectedFields: map[string]any{
+				"client.address":              "10.0.0.5",
+				"http.request.method":         "POST",
+				"url.path":                    "/api/orders",
+				"network.protocol.name":       "http",
+				"network.protocol.version":    "1.1",
+				"http.response.status_code":   503,
+				"http.request.header.referer": "https://shop.example/",
+				"user_agent.original":         `Mozilla/5.0 ("quoted")`,
+			},
+		},
+		{
+			name:            "client error",
+			line:            `::1 - - [02/Jan/2025:11:00:00 +0000] "-" 400 0`,
+			expectedLevel:   "warn",
+			expectedMessage: "-",
+			expectedFields: map[string]any{
+				"client.address":            "::1",
+				"http.response.status_code": 400,
+				"http.response.body.size":   0,
+			},
+		},
+		{
+			name:            "not an access log",
+			line:            `GET /index.html 200`,
+			expectedLevel:   "info",
+			expectedMessage: `GET /index.html 200`,
+			expectedFields:  map[string]any{},
+		},
+	}
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	extractor.now = func() time.Time { return now }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseCLFEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Level != tt.expectedLevel || entry.Message != tt.expectedMessage {
+				t.Errorf("Expected level %q message %q, got %q %q", tt.expectedLevel, tt.expectedMessage, entry.Level, entry.Message)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.expectedFields) {
+				t.Errorf("Expected fields %v, got %v", tt.expectedFields, entry.Fields)
+			}
+		})
+	}
+
+	entry, _ := extractor.ParseCLFEntry(`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 1`)
+	if expected := time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC); !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, entry.Timestamp)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+// CodeLocation is the source location a log call was made from
+type CodeLocation struct {
+	FilePath string
+	LineNo   int
+	Function string
+}
+
+// extractCodeLocation recognizes the caller fields written by common loggers
+// and removes the ones it used from jsonData:
+//
+//	zap, klog:  "caller":"pkg/file.go:42"
+//	logrus:     "file":"/src/main.go:42", "func":"main.main"
+//	bunyan:     "src":{"file":"main.js","line":42,"func":"handler"}
+//
+// Fields that do not look like a code location, such as "file":"report.csv",
+// are left alone.
+func extractCodeLocation(jsonData map[string]any) *CodeLocation {
+	var code CodeLocation
+
+	for _, field := range []string{"caller", "src"} {
+		switch v := jsonData[field].(type) {
+		case string:
+			if path, line, ok := splitFileLine(v); ok {
+				code.FilePath, code.LineNo = path, line
+				delete(jsonData, field)
+			}
+		case map[string]any:
+			if fromObject(&code, v) {
+				delete(jsonData, field)
+			}
+		}
+		if code.FilePath != "" {
+			break
+		}
+	}
+
+	if code.FilePath == "" {
+		if file, ok := jsonData["file"].(string); ok {
+			if path, line, ok := splitFileLine(file); ok {
+				code.FilePath, code.LineNo = path, line
+				delete(jsonData, "file")
+			} else if line, ok := positiveInt(jsonData["line"]); ok {
+				code.FilePath, code.LineNo = file, line
+				delete(jsonData, "file")
+				delete(jsonData, "line")
+			}
+		}
+	}
+
+	if code.Function == "" {
+		if function, ok := jsonData["func"].(string); ok && function != "" {
+			code.Function = function
+			delete(jsonData, "func")
+		}
+	}
+
+	if code == (CodeLocation{}) {
+		return nil
+	}
+	return &code
+}
+
+// fromObject reads a {"file","line","func"} object, reporting whether it
+// held a file
+func fromObject(code *CodeLocation, src map[string]any) bool {
+	file, ok := src["file"].(string)
+	if !ok || file == "" {
+		return false
+	}
+	code.FilePath = file
+	code.LineNo, _ = positiveInt(src["line"])
+	for _, key := range []string{"func", "function"} {
+		if function, ok := src[key].(string); ok {
+			code.Function = function
+			break
+		}
+	}
+	return true
+}
+
+// splitFileLine splits "path/file.go:42" into its path and line number
+func splitFileLine(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil || line <= 0 {
+		return "", 0, false
+	}
+	return s[:i], line, true
+}
+
+// positiveInt accepts a JSON number or numeric string greater than zero
+func positiveInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v > 0 && v == float64(int(v)) {
+			return int(v), true
+		}
+	case string:
+		if line, err := strconv.Atoi(v); err == nil && line > 0 {
+			return line, true
+		}
+	}
+	return 0, false
+}
+
+// attributes returns the code.* semantic convention attributes
+func (c *CodeLocation) attributes() []log.KeyValue {
+	var attrs []log.KeyValue
+	if c.FilePath != "" {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.CodeFilePath(c.FilePath)))
+	}
+	if c.LineNo > 0 {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.CodeLineNumber(c.LineNo)))
+	}
+	if c.Function != "" {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.CodeFunctionName(c.Function)))
+	}
+	return attrs
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestExtractCodeLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  *CodeLocation
+		remaining []string // fields that must stay ordinary attributes
+	}{
+		{
+			name:     "zap caller",
+			input:    `{"msg":"x","caller":"api/main.go:42"}`,
+			expected: &CodeLocation{FilePath: "api/main.go", LineNo: 42},
+		},
+		{
+			name:     "logrus file and func",
+			input:    `{"msg":"x","file":"/src/app/main.go:17","func":"main.handler"}`,
+			expected: &CodeLocation{FilePath: "/src/app/main.go", LineNo: 17, Function: "main.handler"},
+		},
+		{
+			name:     "separate file and line",
+			input:    `{"msg":"x","file":"server.py","line":"88"}`,
+			expected: &CodeLocation{FilePath: "server.py", LineNo: 88},
+		},
+		{
+			name:     "bunyan src object",
+			input:    `{"msg":"x","src":{"file":"lib/app.js","line":12,"func":"onRequest"}}`,
+			expected: &CodeLocation{FilePath: "lib/app.js", LineNo: 12, Function: "onRequest"},
+		},
+		{
+			name:      "file that is not a code location",
+			input:     `{"msg":"uploaded","file":"report.csv"}`,
+			remaining: []string{"file"},
+		},
+		{
+			name:      "caller without a line",
+			input:     `{"msg":"x","caller":"billing-service"}`,
+			remaining: []string{"caller"},
+		},
+	}
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (entry.Code == nil) != (tt.expected == nil) || (entry.Code != nil && *entry.Code != *tt.expected) {
+				t.Errorf("Expected code location %+v, got %+v", tt.expected, entry.Code)
+			}
+			for _, field := range tt.remaining {
+				if _, ok := entry.Fields[field]; !ok {
+					t.Errorf("Expected %q to remain an attribute, got %v", field, entry.Fields)
+				}
+			}
+			if tt.expected != nil {
+				for _, field := range []string{"caller", "src", "file", "line", "func"} {
+					if _, ok := entry.Fields[field]; ok {
+						t.Errorf("Expected %q to be consumed, got %v", field, entry.Fields)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLogProcessorCodeLocationAttributes(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{
+		Message: "x",
+		Code:    &CodeLocation{FilePath: "api/main.go", LineNo: 42, Function: "main.run"},
+	})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	values := make(map[string]log.Value)
+	records[0].WalkAttributes(func(kv log.KeyValue) bool {
+		values[kv.Key] = kv.Value
+		return true
+	})
+
+	if v := values["code.file.path"]; v.AsString() != "api/main.go" {
+		t.Errorf("Expected code.file.path=api/main.go, got %v", v)
+	}
+	if v := values["code.line.number"]; v.Kind() != log.KindInt64 || v.AsInt64() != 42 {
+		t.Errorf("Expected integer code.line.number=42, got %v (%v)", v, v.Kind())
+	}
+	if v := values["code.function.name"]; v.AsString() != "main.run" {
+		t.Errorf("Expected code.function.name=main.run, got %v", v)
+	}
+}
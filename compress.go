@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats of input, told apart by their magic bytes
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+var compressionMagic = []struct {
+	format string
+	magic  []byte
+}{
+	{compressionGzip, []byte{0x1f, 0x8b}},
+	{compressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// compressionMagicSize is how much of the input detectCompression looks at
+const compressionMagicSize = 4
+
+// detectCompression returns the compression format head starts with, or ""
+// for uncompressed input
+func detectCompression(head []byte) string {
+	for _, c := range compressionMagic {
+		if bytes.HasPrefix(head, c.magic) {
+			return c.format
+		}
+	}
+	return ""
+}
+
+// decompressReader decompresses r, which holds data of the given format.
+// Concatenated gzip members and zstd frames are read one after another, as
+// left by appending to an archive.
+func decompressReader(format string, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case compressionGzip:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip data: %w", err)
+		}
+		return reader, nil
+	case compressionZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd data: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", format)
+	}
+}
+
+// autoDecompress returns r decompressed if it starts with gzip or zstd data,
+// as it is otherwise
+func autoDecompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	// A shorter input is returned as it is, along with what is left of it
+	head, _ := buffered.Peek(compressionMagicSize)
+	format := detectCompression(head)
+	if format == "" {
+		return buffered, nil
+	}
+	return decompressReader(format, buffered)
+}
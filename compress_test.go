@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoDecompress(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{compressionGzip, compressionZstd} {
+		path := filepath.Join(dir, format)
+		writeArchive(t, path, format, "compressed line\n")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := detectCompression(data); got != format {
+			t.Errorf("Expected %s to be detected, got %q", format, got)
+		}
+		reader, err := autoDecompress(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to decompress %s: %v", format, err)
+		}
+		if out, err := io.ReadAll(reader); err != nil || string(out) != "compressed line\n" {
+			t.Errorf("Expected the %s data decompressed, got %q (%v)", format, out, err)
+		}
+	}
+
+	for _, plain := range []string{"plain line\n", "x", ""} {
+		reader, err := autoDecompress(bytes.NewReader([]byte(plain)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if out, _ := io.ReadAll(reader); string(out) != plain {
+			t.Errorf("Expected %q to pass through, got %q", plain, out)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig holds the processing rules that can be set in the --config file
+// and changed while otel-logger is running. Flags given on the command line
+// take precedence over the file.
+type FileConfig struct {
+	JSONPrefix       string   `yaml:"json_prefix"`
+	TimestampFields  []string `yaml:"timestamp_fields"`
+	LevelFields      []string `yaml:"level_fields"`
+	MessageFields    []string `yaml:"message_fields"`
+	LoggerNameFields []string `yaml:"logger_name_fields"`
+	Format           string   `yaml:"format"`
+
+	// LevelNumbers translates numeric levels such as bunyan's 30 to level names
+	LevelNumbers map[string]string `yaml:"level_numbers"`
+	// TimestampUnit is the unit of numeric (epoch) timestamps: s, ms, us or
+	// ns. Without it the unit is told by the magnitude.
+	TimestampUnit string `yaml:"timestamp_unit"`
+	// LevelScale is how other numeric levels are read: bunyan, syslog or none
+	LevelScale string `yaml:"level_scale"`
+	// SeverityMap gives nonstandard levels an OTel severity number (1-24)
+	SeverityMap map[string]int `yaml:"severity_map"`
+	// LogLinePrefix is PostgreSQL's log_line_prefix, for format postgres
+	LogLinePrefix string `yaml:"log_line_prefix"`
+	// MDCFields are objects, such as Logback's MDC, whose keys become attributes
+	MDCFields []string `yaml:"mdc_fields"`
+	// StacktraceFields hold stack traces to export as exception.* attributes
+	StacktraceFields []string `yaml:"stacktrace_fields"`
+	// NumberFields are exported as numbers when their string values are
+	NumberFields []string `yaml:"number_fields"`
+	// MessageTemplate builds the message of lines without a message field
+	MessageTemplate string `yaml:"message_template"`
+	// RenameFields exports fields under other names, like --rename-field
+	RenameFields map[string]string `yaml:"rename_fields"`
+
+	// Redact and RedactPatterns mask secrets, like --redact and --redact-pattern
+	Redact         []string `yaml:"redact"`
+	RedactPatterns []string `yaml:"redact_patterns"`
+	// MinLevel drops records below a level, like --min-level
+	MinLevel string `yaml:"min_level"`
+	// SampleRatios and SampleRates export a share of records, like
+	// --sample-ratio and --sample-rate
+	SampleRatios []string `yaml:"sample_ratios"`
+	SampleRates  []string `yaml:"sample_rates"`
+	// AlwaysKeepEvents exempts events from being dropped, like --always-keep-event
+	AlwaysKeepEvents []string `yaml:"always_keep_events"`
+	// DropFields are not exported at all, like --drop-field
+	DropFields []string `yaml:"drop_fields"`
+}
+
+// parseFileConfig decodes a YAML config file, rejecting unknown keys so typos
+// are reported instead of silently ignored
+func parseFileConfig(data []byte) (*FileConfig, error) {
+	fc := &FileConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(fc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if _, err := compilePrefix(fc.JSONPrefix); err != nil {
+		return nil, fmt.Errorf("invalid json_prefix: %w", err)
+	}
+	if err := validFormat(fc.Format); err != nil {
+		return nil, err
+	}
+	if err := validTimestampUnit(fc.TimestampUnit); err != nil {
+		return nil, err
+	}
+	if err := validLevelScale(fc.LevelScale); err != nil {
+		return nil, err
+	}
+	if err := validSeverityMap(fc.SeverityMap); err != nil {
+		return nil, fmt.Errorf("invalid severity_map: %w", err)
+	}
+	if _, err := compilePostgresLog(fc.LogLinePrefix); err != nil {
+		return nil, err
+	}
+	for old, new := range fc.RenameFields {
+		if old == "" || new == "" {
+			return nil, fmt.Errorf("invalid rename_fields: cannot rename %q to %q", old, new)
+		}
+	}
+	if _, err := newRedactor(fc.Redact, fc.RedactPatterns); err != nil {
+		return nil, err
+	}
+	if _, _, err := fc.controlSettings().filters(); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// loadFileConfig reads and parses the config file, returning its checksum so
+// the watcher can tell when it changes
+func loadFileConfig(path string) (*FileConfig, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	fc, err := parseFileConfig(data)
+	if err != nil {
+		return nil, hex.EncodeToString(sum[:]), fmt.Errorf("%s: %w", path, err)
+	}
+	return fc, hex.EncodeToString(sum[:]), nil
+}
+
+// mergeFileConfig returns the effective rules: command-line flags over the
+// file settings
+func mergeFileConfig(file *FileConfig, config *Config) *FileConfig {
+	merged := *file
+	if config.JSONPrefix != "" {
+		merged.JSONPrefix = config.JSONPrefix
+	}
+	if len(config.TimestampFields) > 0 {
+		merged.TimestampFields = config.TimestampFields
+	}
+	if len(config.LevelFields) > 0 {
+		merged.LevelFields = config.LevelFields
+	}
+	if len(config.MessageFields) > 0 {
+		merged.MessageFields = config.MessageFields
+	}
+	if len(config.LoggerNameFields) > 0 {
+		merged.LoggerNameFields = config.LoggerNameFields
+	}
+	if config.Format != "" {
+		merged.Format = config.Format
+	}
+	if config.LevelScale != "" {
+		merged.LevelScale = config.LevelScale
+	}
+	// The flag adds to the file's table; processInput has checked it
+	if severities, _ := parseSeverityMap(config.SeverityMap); len(severities) > 0 {
+		merged.SeverityMap = maps.Clone(file.SeverityMap)
+		if merged.SeverityMap == nil {
+			merged.SeverityMap = make(map[string]int)
+		}
+		maps.Copy(merged.SeverityMap, severities)
+	}
+	if len(config.Redact) > 0 {
+		merged.Redact = config.Redact
+	}
+	if len(config.RedactPatterns) > 0 {
+		merged.RedactPatterns = config.RedactPatterns
+	}
+	if config.MinLevel != "" {
+		merged.MinLevel = config.MinLevel
+	}
+	if len(config.SampleRatios) > 0 {
+		merged.SampleRatios = config.SampleRatios
+	}
+	if len(config.SampleRates) > 0 {
+		merged.SampleRates = config.SampleRates
+	}
+	if len(config.AlwaysKeepEvents) > 0 {
+		merged.AlwaysKeepEvents = config.AlwaysKeepEvents
+	}
+	if len(config.DropFields) > 0 {
+		merged.DropFields = config.DropFields
+	}
+	return &merged
+}
+
+// controlSettings returns the filters the control socket can change
+func (fc *FileConfig) controlSettings() controlSettings {
+	return controlSettings{minLevel: fc.MinLevel, ratios: fc.SampleRatios, rates: fc.SampleRates}
+}
+
+// fileFilters are the redaction, filtering and sampling settings of the
+// config file and command line, ready to hand to a processor
+type fileFilters struct {
+	redactor  *redactor
+	keep      *keepRules
+	routing   *fieldRouting
+	control   controlSettings
+	threshold log.Severity
+	sampler   *sampler
+}
+
+// filters builds the settings' redaction, filtering and sampling
+func (fc *FileConfig) filters(config *Config) (*fileFilters, error) {
+	if config.BodyMode == bodyModeObject && len(fc.DropFields) > 0 {
+		return nil, fmt.Errorf("drop_fields cannot be combined with --body-mode %s, which exports the parsed object as it is", bodyModeObject)
+	}
+	f := &fileFilters{control: fc.controlSettings()}
+	var err error
+	if len(fc.Redact) > 0 || len(fc.RedactPatterns) > 0 {
+		if f.redactor, err = newRedactor(fc.Redact, fc.RedactPatterns); err != nil {
+			return nil, err
+		}
+	}
+	if f.keep, err = newKeepRules(config.AlwaysKeepLevel, fc.AlwaysKeepEvents); err != nil {
+		return nil, fmt.Errorf("invalid --always-keep-level: %w", err)
+	}
+	if f.threshold, f.sampler, err = f.control.filters(); err != nil {
+		return nil, err
+	}
+	if len(config.BodyFields) > 0 || len(fc.DropFields) > 0 {
+		f.routing = newFieldRouting(config.BodyFields, fc.DropFields)
+	}
+	return f, nil
+}
+
+// apply hands the filters to the processor. The minimum level and sampling
+// become the control socket's initial settings when there is one. A
+// processor that hands off entries is left alone: the export helper filters
+// them, watching the config file itself.
+func (f *fileFilters) apply(processor *LogProcessor, control *controlServer) error {
+	if processor.handoff != nil {
+		return nil
+	}
+	if control != nil {
+		if err := control.setInitial(f.control); err != nil {
+			return err
+		}
+	} else {
+		processor.SetMinSeverity(f.threshold)
+		processor.SetSampler(f.sampler)
+	}
+	processor.SetRedactor(f.redactor)
+	processor.SetKeepRules(f.keep)
+	processor.SetFieldRouting(f.routing)
+	return nil
+}
+
+// fieldMappings fills in the defaults for any field list left unset. A format
+// naming a preset fills them in from the preset first.
+func (fc *FileConfig) fieldMappings() *FieldMappings {
+	fc = fc.withPreset()
+	fieldMappings := getDefaultFieldMappings()
+	if len(fc.TimestampFields) > 0 {
+		fieldMappings.TimestampFields = fc.TimestampFields
+	}
+	if len(fc.MessageFields) > 0 {
+		fieldMappings.MessageFields = fc.MessageFields
+	}
+	if len(fc.LevelFields) > 0 {
+		fieldMappings.LevelFields = fc.LevelFields
+	}
+	fieldMappings.LoggerNameFields = fc.LoggerNameFields
+	fieldMappings.Format = fc.Format
+	fieldMappings.LevelNumbers = fc.LevelNumbers
+	fieldMappings.TimestampUnit = fc.TimestampUnit
+	fieldMappings.LevelScale = fc.LevelScale
+	fieldMappings.SeverityMap = severityTable(fc.SeverityMap)
+	fieldMappings.LogLinePrefix = fc.LogLinePrefix
+	fieldMappings.MDCFields = fc.MDCFields
+	fieldMappings.StacktraceFields = fc.StacktraceFields
+	fieldMappings.NumberFields = fc.NumberFields
+	fieldMappings.MessageTemplate = fc.MessageTemplate
+	if len(fc.RenameFields) > 0 {
+		fieldMappings.RenameFields = fieldRenames(fc.RenameFields)
+	}
+	return fieldMappings
+}
+
+// withPreset returns the settings laid over the preset named by the format,
+// if it names one: keys set here win over the preset's. The preset's own
+// format, JSON unless it says otherwise, takes the place of its name.
+func (fc *FileConfig) withPreset() *FileConfig {
+	if isParserFormat(fc.Format) {
+		return fc
+	}
+	data, err := readPreset(fc.Format)
+	if err != nil {
+		return fc
+	}
+	preset, err := parseFileConfig(data)
+	if err != nil {
+		return fc
+	}
+
+	merged := *preset
+	mergedValue, own := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(*fc)
+	for i := 0; i < own.NumField(); i++ {
+		if field := own.Field(i); !field.IsZero() && own.Type().Field(i).Name != "Format" {
+			mergedValue.Field(i).Set(field)
+		}
+	}
+	return &merged
+}
+
+// changedKeys lists the config file keys whose effective value differs
+func changedKeys(old, new *FileConfig) []string {
+	var keys []string
+	oldValue, newValue := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			keys = append(keys, oldValue.Type().Field(i).Tag.Get("yaml"))
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// configWatcher polls the config file and applies changes to the running
+// extractor and processor, recording each reload attempt as an audit record.
+// Without an extractor, as in the export helper, it only applies the
+// processor's filters and leaves the records to the wrapper's watcher.
+type configWatcher struct {
+	path      string
+	interval  time.Duration
+	config    *Config
+	extractor *JSONExtractor
+	processor *LogProcessor
+	control   *controlServer // optional; takes the minimum level and sampling
+
+	current *FileConfig // effective rules currently applied
+	sum     string      // checksum of the last file contents seen
+}
+
+func newConfigWatcher(config *Config, extractor *JSONExtractor, processor *LogProcessor, control *controlServer, current *FileConfig, sum string) *configWatcher {
+	return &configWatcher{
+		path:      config.ConfigFile,
+		interval:  config.ConfigReloadInterval,
+		config:    config,
+		extractor: extractor,
+		processor: processor,
+		control:   control,
+		current:   current,
+		sum:       sum,
+	}
+}
+
+func (w *configWatcher) run(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+// check reloads the file if its contents changed. An invalid file keeps the
+// previous rules in place; it is reported once rather than on every poll.
+func (w *configWatcher) check(ctx context.Context) {
+	file, sum, err := loadFileConfig(w.path)
+	if sum == "" && err != nil {
+		// Editors often replace files by rename; try again next tick
+		logDebug(w.config.Verbose, "Config file %s not readable: %v\n", w.path, err)
+		return
+	}
+	if sum == w.sum {
+		return
+	}
+	w.sum = sum
+
+	if err != nil {
+		w.failed(ctx, sum, err)
+		return
+	}
+
+	merged := mergeFileConfig(file, w.config)
+	changed := changedKeys(w.current, merged)
+	filters, err := merged.filters(w.config)
+	if err != nil {
+		w.failed(ctx, sum, err)
+		return
+	}
+	if w.extractor != nil {
+		if err := w.extractor.Reload(merged.JSONPrefix, merged.fieldMappings()); err != nil {
+			w.failed(ctx, sum, err)
+			return
+		}
+	}
+	if err := filters.apply(w.processor, w.control); err != nil {
+		w.failed(ctx, sum, err)
+		return
+	}
+	w.current = merged
+	if w.extractor == nil {
+		return
+	}
+	w.processor.annotation.set(pipelineAttributes(merged))
+
+	w.processor.ProcessLogEntry(ctx, configReloadEntry(w.path, sum, "info",
+		fmt.Sprintf("Config reloaded from %s", w.path),
+		map[string]any{"config.changed": strings.Join(changed, ",")}))
+}
+
+// failed records a reload that keeps the previous settings
+func (w *configWatcher) failed(ctx context.Context, sum string, err error) {
+	if w.extractor == nil {
+		return
+	}
+	w.processor.ProcessLogEntry(ctx, configReloadEntry(w.path, sum, "error",
+		fmt.Sprintf("Config reload failed, keeping previous settings: %v", err),
+		map[string]any{"config.error": err.Error()}))
+}
+
+func configReloadEntry(path, sum, level, message string, fields map[string]any) *LogEntry {
+	fields["config.path"] = path
+	fields["config.sha256"] = sum
+	return &LogEntry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Fields:    fields,
+		Raw:       message,
+		Stream:    "system",
+		Internal:  true,
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestParseFileConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    *FileConfig
+		expectError bool
+	}{
+		{
+			name:     "empty file",
+			input:    "",
+			expected: &FileConfig{},
+		},
+		{
+			name:  "field mappings",
+			input: "json_prefix: '^\\[app\\] (.*)$'\nlevel_fields: [sev]\nmessage_fields:\n  - body\n",
+			expected: &FileConfig{
+				JSONPrefix:    `^\[app\] (.*)$`,
+				LevelFields:   []string{"sev"},
+				MessageFields: []string{"body"},
+			},
+		},
+		{
+			name:        "unknown key",
+			input:       "level_field: [sev]\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid prefix",
+			input:       "json_prefix: '(['\n",
+			expectError: true,
+		},
+		{
+			name:     "logfmt format",
+			input:    "format: logfmt\n",
+			expected: &FileConfig{Format: formatLogfmt},
+		},
+		{
+			name:        "unknown format",
+			input:       "format: xml\n",
+			expectError: true,
+		},
+		{
+			name:  "filters",
+			input: "redact: [email]\nredact_patterns: ['token=(\\S+)']\nmin_level: warn\nsample_ratios: [debug=0.1]\nalways_keep_events: [audit]\ndrop_fields: [password]\n",
+			expected: &FileConfig{
+				Redact:           []string{"email"},
+				RedactPatterns:   []string{`token=(\S+)`},
+				MinLevel:         "warn",
+				SampleRatios:     []string{"debug=0.1"},
+				AlwaysKeepEvents: []string{"audit"},
+				DropFields:       []string{"password"},
+			},
+		},
+		{
+			name:        "unknown redaction",
+			input:       "redact: [phone]\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid min level",
+			input:       "min_level: loud\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid sample rate",
+			input:       "sample_rates: [5/d]\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, err := parseFileConfig([]byte(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(fc, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, fc)
+			}
+		})
+	}
+}
+
+func TestMergeFileConfig(t *testing.T) {
+	file := &FileConfig{
+		JSONPrefix:  "^(.*)$",
+		LevelFields: []string{"sev"},
+	}
+	config := &Config{LevelFields: []string{"lvl"}}
+
+	merged := mergeFileConfig(file, config)
+	if merged.JSONPrefix != "^(.*)$" {
+		t.Errorf("Expected file prefix to be kept, got %q", merged.JSONPrefix)
+	}
+	if !reflect.DeepEqual(merged.LevelFields, []string{"lvl"}) {
+		t.Errorf("Expected flag to override file, got %v", merged.LevelFields)
+	}
+	if !reflect.DeepEqual(file.LevelFields, []string{"sev"}) {
+		t.Error("Merge should not modify the file config")
+	}
+}
+
+func TestConfigWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel-logger.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("message_fields: [msg]\n")
+
+	config := &Config{ConfigFile: path}
+	file, sum, err := loadFileConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	settings := mergeFileConfig(file, config)
+
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor(settings.JSONPrefix, settings.fieldMappings())
+	watcher := newConfigWatcher(config, extractor, processor, nil, settings, sum)
+	ctx := context.Background()
+
+	// Unchanged file produces no audit record
+	watcher.check(ctx)
+	if got := len(exporter.Records()); got != 0 {
+		t.Fatalf("Expected no records for unchanged file, got %d", got)
+	}
+
+	write("message_fields: [body]\n")
+	watcher.check(ctx)
+
+	entry, _ := extractor.ParseLogEntry(`{"body":"reloaded","msg":"old"}`)
+	if entry.Message != "reloaded" {
+		t.Errorf("Expected new message field to apply, got %q", entry.Message)
+	}
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 audit record, got %d", len(records))
+	}
+	attrs := recordAttributes(records[0])
+	if attrs["config.changed"] != "message_fields" {
+		t.Errorf("Expected config.changed message_fields, got %q", attrs["config.changed"])
+	}
+	if attrs["config.path"] != path {
+		t.Errorf("Expected config.path %q, got %q", path, attrs["config.path"])
+	}
+
+	// An invalid file is reported once and the previous rules stay in place
+	write("message_fields: [body\n")
+	watcher.check(ctx)
+	watcher.check(ctx)
+
+	entry, _ = extractor.ParseLogEntry(`{"body":"still here"}`)
+	if entry.Message != "still here" {
+		t.Errorf("Expected previous rules to be kept, got %q", entry.Message)
+	}
+
+	records = exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records after invalid reload, got %d", len(records))
+	}
+	if records[1].Severity().String() != "ERROR" {
+		t.Errorf("Expected reload failure at ERROR, got %s", records[1].Severity())
+	}
+}
+
+func TestConfigWatcherReloadFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel-logger.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("message_fields: [msg]\n")
+
+	config := &Config{ConfigFile: path, AlwaysKeepLevel: "error"}
+	file, sum, err := loadFileConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	settings := mergeFileConfig(file, config)
+
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor(settings.JSONPrefix, settings.fieldMappings())
+	watcher := newConfigWatcher(config, extractor, processor, nil, settings, sum)
+	ctx := context.Background()
+
+	// process returns the record exported for a line, or nil if dropped
+	process := func(line string) map[string]string {
+		t.Helper()
+		before := len(exporter.Records())
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(ctx, entry)
+		records := exporter.Records()
+		if len(records) == before {
+			return nil
+		}
+		attrs := recordAttributes(records[len(records)-1])
+		attrs["body"] = records[len(records)-1].Body().AsString()
+		return attrs
+	}
+
+	if process(`{"level":"info","msg":"started"}`) == nil {
+		t.Fatal("Expected info records to be exported before the reload")
+	}
+
+	write("message_fields: [msg]\nredact: [email]\nmin_level: warn\ndrop_fields: [password]\n")
+	watcher.check(ctx)
+	if got := exporter.Records(); recordAttributes(got[len(got)-1])["config.changed"] != "drop_fields,min_level,redact" {
+		t.Errorf("Unexpected config.changed %q", recordAttributes(got[len(got)-1])["config.changed"])
+	}
+
+	if process(`{"level":"info","msg":"started"}`) != nil {
+		t.Error("Expected info records to be dropped after min_level warn")
+	}
+	attrs := process(`{"level":"warn","msg":"login by jane@example.com","password":"hunter2"}`)
+	if attrs == nil {
+		t.Fatal("Expected warn records to be exported")
+	}
+	if attrs["body"] != "login by [REDACTED]" {
+		t.Errorf("Expected the reloaded redaction to apply, got %q", attrs["body"])
+	}
+	if _, ok := attrs["password"]; ok {
+		t.Error("Expected the reloaded drop_fields to drop password")
+	}
+
+	write("message_fields: [msg]\n")
+	watcher.check(ctx)
+
+	attrs = process(`{"level":"info","msg":"login by jane@example.com","password":"hunter2"}`)
+	if attrs == nil {
+		t.Fatal("Expected info records to be exported once min_level is removed")
+	}
+	if attrs["body"] != "login by jane@example.com" || attrs["password"] != "hunter2" {
+		t.Errorf("Expected redaction and dropping to be off, got %v", attrs)
+	}
+}
+
+func TestConfigWatcherReloadControlBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel-logger.yaml")
+	if err := os.WriteFile(path, []byte("min_level: warn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{ConfigFile: path, ControlSocket: controlSocketPath(t)}
+
+	provider, _ := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	control, err := startControlServer(context.Background(), config, processor)
+	if err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer control.Close()
+	watcher := newConfigWatcher(config, nil, processor, control, &FileConfig{}, "")
+	ctx := context.Background()
+
+	watcher.check(ctx)
+	if got := processor.minSeverity.Load(); got != int64(log.SeverityWarn) {
+		t.Errorf("Expected the file's min_level to apply, got %d", got)
+	}
+
+	// A change through the socket keeps precedence over a reload, and reset
+	// restores what the file now says
+	if _, err := control.handle(ctx, "min-level debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("min_level: error\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watcher.check(ctx)
+	if got := processor.minSeverity.Load(); got != int64(log.SeverityDebug) {
+		t.Errorf("Expected the socket's min-level to stay, got %d", got)
+	}
+	if status, err := control.handle(ctx, "reset"); err != nil || status != "min-level=error sample-ratio=off sample-rate=off" {
+		t.Errorf("Unexpected reset %q, %v", status, err)
+	}
+	if got := processor.minSeverity.Load(); got != int64(log.SeverityError) {
+		t.Errorf("Expected reset to restore the file's min_level, got %d", got)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -243,6 +244,65 @@ func TestEnvironmentVariables(t *testing.T) {
 	t.Log("Environment variables are handled by OpenTelemetry SDK exporters")
 }
 
+func TestConfigFlagEnvironmentVariables(t *testing.T) {
+	t.Setenv("OTEL_LOGGER_BATCH_SIZE", "7")
+	t.Setenv("OTEL_LOGGER_FLUSH_INTERVAL", "250ms")
+	t.Setenv("OTEL_LOGGER_LEVEL_FIELDS", "sev,lvl")
+	t.Setenv("OTEL_LOGGER_PASSTHROUGH_STDOUT", "true")
+	t.Setenv("OTEL_LOGGER_CONTEXT_LEVEL", "debug")
+
+	var config Config
+	p, err := arg.NewParser(arg.Config{}, &config)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	if err := p.Parse([]string{"--context-level", "warn"}); err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if config.BatchSize != 7 {
+		t.Errorf("Expected BatchSize 7 from environment, got %d", config.BatchSize)
+	}
+	if config.FlushInterval != 250*time.Millisecond {
+		t.Errorf("Expected FlushInterval 250ms from environment, got %v", config.FlushInterval)
+	}
+	if !reflect.DeepEqual(config.LevelFields, []string{"sev", "lvl"}) {
+		t.Errorf("Expected LevelFields [sev lvl] from environment, got %v", config.LevelFields)
+	}
+	if !config.PassthroughStdout {
+		t.Error("Expected PassthroughStdout from environment")
+	}
+	if config.ContextLevel != "warn" {
+		t.Errorf("Expected flag to override environment, got %q", config.ContextLevel)
+	}
+}
+
+func TestConfigEveryFlagHasEnvironmentVariable(t *testing.T) {
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		tag := field.Tag.Get("arg")
+		if tag == "positional" {
+			continue
+		}
+
+		var long, env string
+		for _, part := range strings.Split(tag, ",") {
+			switch {
+			case strings.HasPrefix(part, "--"):
+				long = part[2:]
+			case strings.HasPrefix(part, "env:"):
+				env = part[4:]
+			}
+		}
+
+		expected := "OTEL_LOGGER_" + strings.ToUpper(strings.ReplaceAll(long, "-", "_"))
+		if env != expected {
+			t.Errorf("Expected --%s to read %s, got %q", long, expected, env)
+		}
+	}
+}
+
 func TestConfigDescription(t *testing.T) {
 	config := Config{}
 	description := config.Description()
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// contextAttribute marks records that were held back and exported retroactively
+const contextAttribute = "otel_logger.context"
+
+// bufferedRecord is a record held back together with the logger it was meant
+// for and the context carrying its trace
+type bufferedRecord struct {
+	ctx    context.Context
+	logger log.Logger
+	record log.Record
+}
+
+// contextBuffer is a ring buffer of the most recent low-severity records. They
+// are not exported unless a record at or above the trigger severity follows,
+// giving debug context around errors without shipping all debug logs.
+type contextBuffer struct {
+	mu      sync.Mutex
+	below   log.Severity // records below this severity are held back
+	trigger log.Severity // records at or above this severity release the buffer
+	records []bufferedRecord
+	next    int
+	full    bool
+}
+
+func newContextBuffer(size int, below, trigger log.Severity) *contextBuffer {
+	return &contextBuffer{
+		below:   below,
+		trigger: trigger,
+		records: make([]bufferedRecord, size),
+	}
+}
+
+// holdsForContext reports whether the context buffer holds back an entry
+// of the given severity; otel-logger's own records and those the keep rules
+// exempt are exported right away
+func (p *LogProcessor) holdsForContext(entry *LogEntry, severity log.Severity) bool {
+	return p.ring != nil && severity < p.ring.below && !entry.Internal && !p.keep.Load().keeps(entry, severity)
+}
+
+// hold buffers the record if it is below the threshold, evicting the oldest
+// record when full. It reports whether the record was held back.
+func (b *contextBuffer) hold(ctx context.Context, logger log.Logger, record log.Record) bool {
+	if record.Severity() >= b.below {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.records[b.next] = bufferedRecord{ctx: ctx, logger: logger, record: record}
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	return true
+}
+
+// release returns the buffered records in arrival order and empties the buffer
+// if the record severity reaches the trigger
+func (b *contextBuffer) release(severity log.Severity) []bufferedRecord {
+	if severity < b.trigger {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out []bufferedRecord
+	if b.full {
+		out = append(out, b.records[b.next:]...)
+	}
+	out = append(out, b.records[:b.next]...)
+
+	clear(b.records)
+	b.next = 0
+	b.full = false
+
+	for i := range out {
+		out[i].record.AddAttributes(log.Bool(contextAttribute, true))
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestContextBufferReleasesOnError(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetContextBuffer(2, log.SeverityInfo1)
+
+	ctx := context.Background()
+	for _, msg := range []string{"debug 1", "debug 2", "debug 3"} {
+		processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "debug", Message: msg, Fields: map[string]any{}})
+	}
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "info", Message: "info", Fields: map[string]any{}})
+
+	if got := len(exporter.Records()); got != 1 {
+		t.Fatalf("Expected only the info record to be exported before an error, got %d", got)
+	}
+
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "error", Message: "boom", Fields: map[string]any{}})
+
+	records := exporter.Records()
+	expected := []string{"info", "debug 2", "debug 3", "boom"}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if body := record.Body().AsString(); body != expected[i] {
+			t.Errorf("Record %d: expected body %q, got %q", i, expected[i], body)
+		}
+	}
+
+	isContext := func(i int) bool {
+		found := false
+		records[i].WalkAttributes(func(kv log.KeyValue) bool {
+			if kv.Key == contextAttribute {
+				found = kv.Value.AsBool()
+			}
+			return true
+		})
+		return found
+	}
+	if !isContext(1) || !isContext(2) {
+		t.Error("Released records should be marked as context")
+	}
+	if isContext(3) {
+		t.Error("The triggering record should not be marked as context")
+	}
+
+	// The buffer is empty after release
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "error", Message: "again", Fields: map[string]any{}})
+	if got := len(exporter.Records()); got != len(expected)+1 {
+		t.Errorf("Expected buffer to be empty after release, got %d records", got)
+	}
+}
+
+func TestContextBufferBelowMinSeverity(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetMinSeverity(log.SeverityWarn1)
+	processor.SetContextBuffer(10, log.SeverityInfo1)
+
+	ctx := context.Background()
+	for _, level := range []string{"debug", "info", "warn"} {
+		processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: level, Message: level, Fields: map[string]any{}})
+	}
+	processor.ProcessLogEntry(ctx, commandExitEntry([]string{"true"}, 0, false))
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "error", Message: "boom", Fields: map[string]any{}})
+
+	// Info is dropped by --min-level, but debug is held as context; the exit
+	// record is neither
+	var bodies []string
+	for _, record := range exporter.Records() {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	expected := []string{"warn", "Command completed with exit code 0", "debug", "boom"}
+	if !slices.Equal(bodies, expected) {
+		t.Errorf("Expected %v, got %v", expected, bodies)
+	}
+}
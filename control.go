@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// controlSettings are the filters the control socket can change, as given
+// on the command line or in the config file; empty ones are off
+type controlSettings struct {
+	minLevel      string
+	ratios, rates []string
+}
+
+func (s controlSettings) String() string {
+	value := func(values ...string) string {
+		if len(values) == 0 || values[0] == "" {
+			return "off"
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprintf("min-level=%s sample-ratio=%s sample-rate=%s", value(s.minLevel), value(s.ratios...), value(s.rates...))
+}
+
+// controlServer lets operators change the minimum level and sampling of a
+// running otel-logger without restarting it, e.g. to export debug logs for a
+// while during an incident:
+//
+//	echo 'min-level debug for 15m' | nc -U /run/otel-logger.sock
+//
+// Each line is a command answered by a line starting with "ok" or "error".
+// Every change is recorded as a system record.
+type controlServer struct {
+	listener  net.Listener
+	processor *LogProcessor
+
+	mu         sync.Mutex
+	initial    controlSettings // from the command line and config file, restored by reset
+	current    controlSettings
+	generation int // of current, so a revert does not undo a later change
+}
+
+// startControlServer listens on config.ControlSocket, or returns nil when it
+// is not set
+func startControlServer(ctx context.Context, config *Config, processor *LogProcessor) (*controlServer, error) {
+	if config.ControlSocket == "" {
+		return nil, nil
+	}
+	if err := removeStaleSocket(config.ControlSocket); err != nil {
+		return nil, err
+	}
+	// Changing what is exported is for the user running otel-logger
+	listener, err := listenControl(config.ControlSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on --control-socket: %w", err)
+	}
+
+	settings := controlSettings{minLevel: config.MinLevel, ratios: config.SampleRatios, rates: config.SampleRates}
+	s := &controlServer{listener: listener, processor: processor, initial: settings, current: settings}
+	go s.serve(ctx)
+	return s, nil
+}
+
+// removeStaleSocket removes a socket left behind by an otel-logger that did
+// not shut down cleanly, but not one still in use
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		// Listening reports anything else in the way
+		return nil
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("--control-socket %s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+func (s *controlServer) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *controlServer) serve(ctx context.Context) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logError("Control socket failed: %v\n", err)
+			}
+			return
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+func (s *controlServer) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		status, err := s.handle(ctx, line)
+		if err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(conn, "ok %s\n", status)
+	}
+}
+
+// handle runs a command and returns the settings in effect after it:
+//
+//	status
+//	min-level LEVEL|off [for DURATION]
+//	sample-ratio [LEVEL=]RATIO...|off [for DURATION]
+//	sample-rate [LEVEL=]COUNT/UNIT...|off [for DURATION]
+//	reset
+//
+// A change made for a duration reverts to the initial settings once it
+// passes, unless changed again before.
+func (s *controlServer) handle(ctx context.Context, line string) (string, error) {
+	fields := strings.Fields(line)
+	var duration time.Duration
+	if n := len(fields); n >= 3 && fields[n-2] == "for" {
+		d, err := time.ParseDuration(fields[n-1])
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid duration %q", fields[n-1])
+		}
+		duration, fields = d, fields[:n-2]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.current
+	switch command, args := fields[0], fields[1:]; {
+	case (command == "status" || command == "reset") && len(args) > 0:
+		return "", fmt.Errorf("%s takes no arguments", command)
+	case (command == "status" || command == "reset") && duration > 0:
+		return "", fmt.Errorf("%s cannot be limited to a duration", command)
+	case command == "status":
+		return s.current.String(), nil
+	case command == "reset":
+		next = s.initial
+	case len(args) == 0:
+		return "", fmt.Errorf("%s needs a value, or off", command)
+	case command == "min-level":
+		if len(args) > 1 {
+			return "", errors.New("min-level takes a single level")
+		}
+		next.minLevel = args[0]
+		if next.minLevel == "off" {
+			next.minLevel = ""
+		}
+	case command == "sample-ratio":
+		next.ratios = controlValues(args)
+	case command == "sample-rate":
+		next.rates = controlValues(args)
+	default:
+		return "", fmt.Errorf("unknown command %q (status, min-level, sample-ratio, sample-rate, reset)", command)
+	}
+
+	if err := s.apply(next); err != nil {
+		return "", err
+	}
+	s.current = next
+	s.generation++
+	if duration > 0 {
+		generation := s.generation
+		time.AfterFunc(duration, func() { s.revert(ctx, generation) })
+	}
+
+	message := fmt.Sprintf("Control socket changed settings: %s", next)
+	if duration > 0 {
+		message += fmt.Sprintf(" for %s", duration)
+	}
+	// Recorded regardless of the filters it changes
+	s.processor.emitEntry(ctx, controlEntry(message, line, next), 1)
+	return next.String(), nil
+}
+
+// controlValues splits the values of a command, which may also be separated
+// by commas; off is none
+func controlValues(args []string) []string {
+	var values []string
+	for _, arg := range args {
+		for _, value := range strings.Split(arg, ",") {
+			if value != "" && value != "off" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// filters builds the minimum severity and sampler the settings stand for
+func (s controlSettings) filters() (log.Severity, *sampler, error) {
+	var threshold log.Severity
+	if s.minLevel != "" {
+		var err error
+		if threshold, err = parseMinLevel(s.minLevel); err != nil {
+			return 0, nil, err
+		}
+	}
+	sampler, err := newSampler(s.ratios, s.rates)
+	if err != nil {
+		return 0, nil, err
+	}
+	return threshold, sampler, nil
+}
+
+// apply validates the settings and hands them to the processor
+func (s *controlServer) apply(settings controlSettings) error {
+	threshold, sampler, err := settings.filters()
+	if err != nil {
+		return err
+	}
+	s.processor.SetMinSeverity(threshold)
+	s.processor.SetSampler(sampler)
+	return nil
+}
+
+// setInitial replaces the settings that reset and expiring changes restore,
+// as a config reload does. They apply at once unless a change made through
+// the socket is in effect, which keeps precedence until it ends.
+func (s *controlServer) setInitial(settings controlSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current.String() == s.initial.String() {
+		if err := s.apply(settings); err != nil {
+			return err
+		}
+		s.current = settings
+	}
+	s.initial = settings
+	return nil
+}
+
+// revert restores the initial settings once a temporary change ends
+func (s *controlServer) revert(ctx context.Context, generation int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if generation != s.generation {
+		return
+	}
+	if err := s.apply(s.initial); err != nil {
+		logError("Failed to restore settings: %v\n", err)
+		return
+	}
+	s.current = s.initial
+	s.generation++
+	s.processor.emitEntry(ctx, controlEntry(fmt.Sprintf("Control socket change expired, restored settings: %s", s.initial), "reset", s.initial), 1)
+}
+
+func controlEntry(message, command string, settings controlSettings) *LogEntry {
+	return &LogEntry{
+		Timestamp: time.Now(),
+		Level:     "info",
+		Message:   message,
+		Fields: map[string]any{
+			"control.command":  command,
+			"control.settings": settings.String(),
+		},
+		Raw:      message,
+		Stream:   "system",
+		Internal: true,
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "net"
+
+// listenControl listens on a unix socket, which takes the access rights of
+// the directory it is created in, as Windows has no file modes
+func listenControl(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
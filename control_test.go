@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// controlSocketPath returns a socket path short enough for the limit on
+// unix socket addresses, which t.TempDir paths can exceed
+func controlSocketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "sock")
+}
+
+func TestControlServer(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetMinSeverity(log.SeverityWarn)
+	config := &Config{MinLevel: "warn", ControlSocket: controlSocketPath(t)}
+
+	server, err := startControlServer(context.Background(), config, processor)
+	if err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer server.Close()
+	if info, err := os.Stat(config.ControlSocket); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the socket to be created owner-only, got %v", info.Mode().Perm())
+	}
+	conn, err := net.Dial("unix", config.ControlSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	responses := bufio.NewScanner(conn)
+	send := func(command string) string {
+		t.Helper()
+		conn.Write([]byte(command + "\n"))
+		if !responses.Scan() {
+			t.Fatalf("No response to %q", command)
+		}
+		return responses.Text()
+	}
+	exported := func(level string) bool {
+		t.Helper()
+		before := len(exporter.Records())
+		processor.ProcessLogEntry(context.Background(), &LogEntry{Timestamp: time.Now(), Level: level, Message: level})
+		return len(exporter.Records()) > before
+	}
+
+	if got := send("status"); got != "ok min-level=warn sample-ratio=off sample-rate=off" {
+		t.Errorf("Unexpected status %q", got)
+	}
+	if exported("debug") {
+		t.Error("Expected debug records to be dropped at startup")
+	}
+
+	if got := send("min-level debug for 200ms"); got != "ok min-level=debug sample-ratio=off sample-rate=off" {
+		t.Errorf("Unexpected response %q", got)
+	}
+	if !exported("debug") {
+		t.Error("Expected debug records to be exported after lowering the level")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(send("status"), "min-level=warn") {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if exported("debug") {
+		t.Error("Expected the level to be restored once the duration passed")
+	}
+
+	for _, bad := range []string{"min-level loud", "sample-ratio 2", "sample-rate 5/d", "status now", "reset for 1m", "min-level info for ever", "louder"} {
+		if got := send(bad); !strings.HasPrefix(got, "error: ") {
+			t.Errorf("Expected an error for %q, got %q", bad, got)
+		}
+	}
+
+	send("sample-ratio 0")
+	if exported("error") {
+		t.Error("Expected every record to be sampled away")
+	}
+	if got := send("reset"); got != "ok min-level=warn sample-ratio=off sample-rate=off" {
+		t.Errorf("Unexpected response %q", got)
+	}
+	if !exported("error") {
+		t.Error("Expected reset to restore the command line settings")
+	}
+
+	var changes int
+	for _, record := range exporter.Records() {
+		if strings.HasPrefix(record.Body().AsString(), "Control socket") {
+			changes++
+		}
+	}
+	if changes != 4 {
+		t.Errorf("Expected a record for each of the 4 changes, got %d", changes)
+	}
+}
+
+func TestControlSocketInUse(t *testing.T) {
+	path := controlSocketPath(t)
+
+	// Left behind by a process that did not shut down cleanly
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	processor := NewLogProcessor(nil)
+	server, err := startControlServer(context.Background(), &Config{ControlSocket: path}, processor)
+	if err != nil {
+		t.Fatalf("Expected a stale socket to be replaced, got %v", err)
+	}
+	defer server.Close()
+
+	if _, err := startControlServer(context.Background(), &Config{ControlSocket: path}, processor); err == nil {
+		t.Error("Expected error for a socket in use")
+	}
+}
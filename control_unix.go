@@ -0,0 +1,17 @@
+//go:build unix
+
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenControl listens on a unix socket only its owner can connect to. The
+// umask applies as the socket is created, so it is never open to others,
+// not even until a chmod.
+func listenControl(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"regexp/syntax"
+	"strings"
+	"time"
+)
+
+// criLinePattern matches the prefix the kubelet writes before each line of a
+// container's output in the CRI log format: the time it was received, the
+// stream and the tags, F for a full line or P for part of a longer one
+const criLinePattern = `^\S+ (?:stdout|stderr) [^ ]+(?: |$)`
+
+// scanCRI cuts CRI log lines, as written under /var/log/pods, into records.
+// The parts of a line the kubelet split with the P tag are joined into one
+// line with the F tag and the first part's time. Lines without content are
+// skipped and lines not in the CRI format pass as they are.
+//
+// Parts are joined from the buffer rather than remembered between calls, so
+// files and streams can share the split function and checkpoints stay on
+// line boundaries. A line is cut short, and the rest read as the next one,
+// when another stream's line comes between its parts or its parts fill
+// maxLine bytes, the most the scanner buffers.
+func scanCRI(maxLine int) bufio.SplitFunc {
+	return scanLogParts(maxLine, cutCRIPart)
+}
+
+// logPart is a line of a container runtime's log, or part of one
+type logPart struct {
+	prefix  []byte // "<time> <stream> " of the CRI line it becomes
+	stream  []byte
+	partial bool
+	content []byte
+	line    []byte // the line itself if it is a full CRI line already
+}
+
+// scanLogParts cuts lines with cut and joins the parts of longer lines, as
+// described for scanCRI, into CRI lines. Lines cut does not recognize pass
+// as they are.
+func scanLogParts(maxLine int, cut func(line []byte) (logPart, bool)) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		var prefix, joined []byte // of the parts so far
+		var stream []byte
+		i := 0
+		for i < len(data) {
+			end := bytes.IndexByte(data[i:], '\n')
+			var line []byte
+			next := len(data)
+			if end >= 0 {
+				line, next = data[i:i+end], i+end+1
+			} else if atEOF {
+				line = data[i:]
+			} else {
+				break
+			}
+
+			part, ok := cut(line)
+			if !ok {
+				if prefix != nil {
+					return i, criJoined(prefix, joined), nil
+				}
+				return next, line, nil
+			}
+			if prefix == nil && !part.partial {
+				// A full line, the usual case
+				if len(part.content) == 0 {
+					return next, nil, nil
+				}
+				if part.line != nil {
+					return next, part.line, nil
+				}
+				return next, criJoined(part.prefix, part.content), nil
+			}
+			if prefix != nil && !bytes.Equal(part.stream, stream) {
+				return i, criJoined(prefix, joined), nil
+			}
+			if prefix == nil {
+				prefix, stream = part.prefix, part.stream
+			}
+			joined = append(joined, part.content...)
+			i = next
+			if !part.partial {
+				return i, criJoined(prefix, joined), nil
+			}
+		}
+		if prefix != nil && (atEOF || len(data) >= maxLine) {
+			return i, criJoined(prefix, joined), nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// cutCRIPart cuts a line of the CRI log format
+func cutCRIPart(line []byte) (logPart, bool) {
+	stream, partial, content, ok := cutCRILine(line)
+	if !ok {
+		return logPart{}, false
+	}
+	part := logPart{stream: stream, partial: partial, content: content}
+	if partial {
+		part.prefix = line[:len(line)-len(content)-len("P ")]
+	} else {
+		part.line = line
+	}
+	return part, true
+}
+
+// cutCRILine splits a CRI log line into its stream, whether it is part of a
+// longer line, and its content
+func cutCRILine(line []byte) (stream []byte, partial bool, content []byte, ok bool) {
+	stamp, rest, ok := bytes.Cut(line, []byte(" "))
+	if !ok || len(stamp) < len("2006-01-02T15:04:05Z") || stamp[4] != '-' || stamp[10] != 'T' {
+		return nil, false, nil, false
+	}
+	stream, rest, ok = bytes.Cut(rest, []byte(" "))
+	if !ok || (string(stream) != "stdout" && string(stream) != "stderr") {
+		return nil, false, nil, false
+	}
+	tags, content, found := bytes.Cut(rest, []byte(" "))
+	switch {
+	case bytes.HasPrefix(tags, []byte("F")):
+		return stream, false, content, true
+	case string(tags) == "P" && found:
+		return stream, true, content, true
+	}
+	return nil, false, nil, false
+}
+
+// criJoined is a full CRI line with the prefix of a line's first part,
+// without its tag, and the joined content
+func criJoined(prefix, content []byte) []byte {
+	line := make([]byte, 0, len(prefix)+len("F ")+len(content))
+	line = append(line, prefix...)
+	line = append(line, "F "...)
+	return append(line, content...)
+}
+
+// criEnvelope is what the CRI prefix says about a record
+type criEnvelope struct {
+	stream string
+	time   time.Time
+}
+
+// decodeCRI strips the CRI prefixes of the lines of an entry, returning its
+// content and the first line's stream and time; ok is false if the entry is
+// not in the CRI format
+func decodeCRI(entry string) (content string, envelope criEnvelope, ok bool) {
+	lines := strings.Split(entry, "\n")
+	for i, line := range lines {
+		stream, _, rest, lineOK := cutCRILine([]byte(line))
+		if !lineOK {
+			continue
+		}
+		if !ok {
+			stamp, _, _ := strings.Cut(line, " ")
+			envelope.stream = string(stream)
+			envelope.time, _ = time.Parse(time.RFC3339Nano, stamp)
+			ok = true
+		}
+		lines[i] = string(rest)
+	}
+	if !ok {
+		return entry, criEnvelope{}, false
+	}
+	return strings.Join(lines, "\n"), envelope, true
+}
+
+// apply tags the entry with the stream and, unless the content had a
+// timestamp, gives it the time the kubelet received the line
+func (envelope criEnvelope) apply(entry *LogEntry) {
+	if entry.Stream == "" {
+		entry.Stream = envelope.stream
+	}
+	if entry.TimestampSource == timestampSourceIngest && !envelope.time.IsZero() {
+		entry.Timestamp, entry.TimestampSource = envelope.time, timestampSourceRecord
+	}
+}
+
+// criContinuation rewrites a continuation pattern to test the content of
+// CRI lines: anchors at the start of the pattern match where the content
+// starts. Lines of only closing brackets are continuations, as without the
+// prefix.
+func criContinuation(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	content := ".*?(?:" + pattern + ")"
+	if anchored := stripBeginText(re); anchored {
+		content = "(?:" + re.String() + ")"
+	}
+	return criLinePattern + "(?:" + content + `|\s*[\]}],?\s*$)`, nil
+}
+
+// stripBeginText replaces the start-of-text anchors of re with empty
+// matches, reporting whether there were any
+func stripBeginText(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpBeginText || re.Op == syntax.OpBeginLine {
+		re.Op = syntax.OpEmptyMatch
+		return true
+	}
+	stripped := false
+	for _, sub := range re.Sub {
+		if stripBeginText(sub) {
+			stripped = true
+		}
+	}
+	return stripped
+}
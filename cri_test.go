@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanCRI(t *testing.T) {
+	input := "2024-01-15T10:30:45.1Z stdout F full line\n" +
+		"2024-01-15T10:30:45.2Z stdout P {\"msg\":\"a \n" +
+		"2024-01-15T10:30:45.3Z stdout P long \n" +
+		"2024-01-15T10:30:45.4Z stdout F line\"}\n" +
+		"2024-01-15T10:30:45.5Z stderr F \n" +
+		"2024-01-15T10:30:45.6Z stderr P cut by \n" +
+		"2024-01-15T10:30:45.7Z stdout F the other stream\n" +
+		"2024-01-15T10:30:45.8Z stderr F the other stream\n" +
+		"not a CRI line\n" +
+		"2024-01-15T10:30:45.9Z stdout P left at the end"
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(scanCRI(bufio.MaxScanTokenSize))
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"2024-01-15T10:30:45.1Z stdout F full line",
+		`2024-01-15T10:30:45.2Z stdout F {"msg":"a long line"}`,
+		"2024-01-15T10:30:45.6Z stderr F cut by ",
+		"2024-01-15T10:30:45.7Z stdout F the other stream",
+		"2024-01-15T10:30:45.8Z stderr F the other stream",
+		"not a CRI line",
+		"2024-01-15T10:30:45.9Z stdout F left at the end",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// Parts filling the buffer are exported as they are
+	part := strings.Repeat("x", 16*1024)
+	var long strings.Builder
+	for i := 0; i < 8; i++ {
+		long.WriteString("2024-01-15T10:30:45Z stdout P " + part + "\n")
+	}
+	long.WriteString("2024-01-15T10:30:45Z stdout F end\n")
+	scanner = bufio.NewScanner(strings.NewReader(long.String()))
+	scanner.Split(scanCRI(bufio.MaxScanTokenSize))
+	var total int
+	for scanner.Scan() {
+		_, _, content, _ := cutCRILine(scanner.Bytes())
+		total += len(content)
+	}
+	if err := scanner.Err(); err != nil || total != 8*len(part)+len("end") {
+		t.Errorf("Expected the long line to be read in pieces, got %d bytes (%v)", total, err)
+	}
+}
+
+func TestCRIContinuation(t *testing.T) {
+	for pattern, cases := range map[string]map[string]bool{
+		`^[ \t]`: {
+			"2024-01-15T10:30:45Z stderr F \tat com.example.Main.run(Main.java:42)": true,
+			"2024-01-15T10:30:45Z stderr F }":                                       true,
+			"2024-01-15T10:30:45Z stderr F Exception in thread main":                false,
+			"\tnot a CRI line": false,
+		},
+		`^(?:Caused by|\s)`: {
+			"2024-01-15T10:30:45Z stderr F Caused by: java.io.IOException": true,
+			"2024-01-15T10:30:45Z stderr F Started":                        false,
+		},
+		`\.\.\. \d+ more$`: {
+			"2024-01-15T10:30:45Z stderr F ... 12 more": true,
+			"2024-01-15T10:30:45Z stderr F 12 more":     false,
+		},
+	} {
+		extractor := NewJSONExtractor("", getDefaultFieldMappings())
+		extractor.SetCRI(true)
+		continuation, err := continuationFor(pattern, extractor)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", pattern, err)
+		}
+		for line, expected := range cases {
+			if got := continuation.MatchString(line); got != expected {
+				t.Errorf("Expected %q to match %v with %q, got %v", line, expected, pattern, got)
+			}
+		}
+	}
+}
+
+func TestProcessCRI(t *testing.T) {
+	input := "2024-01-15T10:30:45.000000001Z stdout P {\"level\":\"warn\",\"msg\":\"split \n" +
+		"2024-01-15T10:30:45.000000002Z stdout F by the kubelet\",\"time\":\"2024-01-15T10:30:44Z\"}\n" +
+		"2024-01-15T10:30:46.5Z stderr F Exception in thread \"main\" java.lang.IllegalStateException\n" +
+		"2024-01-15T10:30:46.6Z stderr F \tat com.example.Main.run(Main.java:42)\n" +
+		"2024-01-15T10:30:47Z stdout F plain text\n"
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	extractor.SetCRI(true)
+	config := &Config{Framing: framingCRI, ContinuationPattern: `^[ \t]`}
+	if err := processReader(context.Background(), config, extractor, processor, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, record := range exporter.Records() {
+		got = append(got, record.Body().AsString()+" | "+recordAttributes(record)["log.iostream"]+" | "+record.Timestamp().UTC().Format(time.RFC3339Nano))
+	}
+	expected := []string{
+		// The line's own timestamp wins over the kubelet's
+		"split by the kubelet | stdout | 2024-01-15T10:30:44Z",
+		"Exception in thread \"main\" java.lang.IllegalStateException\n\tat com.example.Main.run(Main.java:42) | stderr | 2024-01-15T10:30:46.5Z",
+		"plain text | stdout | 2024-01-15T10:30:47Z",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// DiffArgs are the arguments of `otel-logger config-diff`
+type DiffArgs struct {
+	Config              string `arg:"--config,required" help:"Config file to evaluate"`
+	Against             string `arg:"--against" help:"Config file to compare with (default: built-in defaults)"`
+	Sample              string `arg:"--sample,required" help:"Log file to run through both configs (- for stdin)"`
+	ContinuationPattern string `arg:"--continuation-pattern" default:"^[ \\t]" help:"Regex pattern for continuation lines"`
+	All                 bool   `arg:"--all" help:"Also show records that do not change"`
+}
+
+func (DiffArgs) Description() string {
+	return `Run a sample log through two configurations and show, per record, how the
+exported output changes. Nothing is sent to a collector.`
+}
+
+// diffIngestTime stands in for the ingest time of records without a parsed
+// timestamp, so they compare equal between the two runs
+var diffIngestTime = time.Unix(0, 0).UTC()
+
+// diffPipeline parses entries the way a running otel-logger with the given
+// config file would and captures the records it emits
+type diffPipeline struct {
+	extractor *JSONExtractor
+	processor *LogProcessor
+	exporter  *recordingExporter
+}
+
+func newDiffPipeline(path string) (*diffPipeline, error) {
+	settings := &FileConfig{}
+	if path != "" {
+		file, _, err := loadFileConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		settings = file
+	}
+
+	exporter := &recordingExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	extractor := NewJSONExtractor(settings.JSONPrefix, settings.fieldMappings())
+	extractor.now = func() time.Time { return diffIngestTime }
+
+	return &diffPipeline{
+		extractor: extractor,
+		processor: NewLogProcessor(provider.Logger("otel-logger")),
+		exporter:  exporter,
+	}, nil
+}
+
+// process runs one entry through the pipeline and returns the records it
+// exported
+func (p *diffPipeline) process(ctx context.Context, line string) ([]sdklog.Record, error) {
+	before := len(p.exporter.Records())
+
+	entry, err := p.extractor.ParseLogEntry(line)
+	if err != nil {
+		return nil, err
+	}
+	p.processor.ProcessLogEntry(ctx, entry)
+	return p.exporter.Records()[before:], nil
+}
+
+// normalize runs one entry through the pipeline, returning the exported
+// records rendered as sorted "field: value" lines
+func (p *diffPipeline) normalize(ctx context.Context, line string) []string {
+	records, err := p.process(ctx, line)
+	if err != nil {
+		return []string{"error: " + err.Error()}
+	}
+
+	var lines []string
+	for _, record := range records {
+		lines = append(lines, renderRecord(record)...)
+	}
+	return lines
+}
+
+func renderRecord(record sdklog.Record) []string {
+	timestamp := record.Timestamp().UTC().Format(time.RFC3339Nano)
+	if record.Timestamp().Equal(diffIngestTime) {
+		timestamp = "(ingest time)"
+	}
+
+	lines := []string{
+		"timestamp: " + timestamp,
+		fmt.Sprintf("severity: %s (%d)", record.SeverityText(), record.Severity()),
+		"body: " + record.Body().String(),
+	}
+
+	var attrs []string
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		// The original line is the input, identical on both sides
+		if kv.Key != "log.record.original" {
+			attrs = append(attrs, "attributes."+kv.Key+": "+kv.Value.String())
+		}
+		return true
+	})
+	slices.Sort(attrs)
+	return append(lines, attrs...)
+}
+
+// lineDiff returns the lines only in before prefixed with "- " followed by
+// the lines only in after prefixed with "+ "
+func lineDiff(before, after []string) []string {
+	var diff []string
+	for _, line := range before {
+		if !slices.Contains(after, line) {
+			diff = append(diff, "- "+line)
+		}
+	}
+	for _, line := range after {
+		if !slices.Contains(before, line) {
+			diff = append(diff, "+ "+line)
+		}
+	}
+	return diff
+}
+
+// writeDiff compares the two pipelines over every entry of the sample and
+// reports the number of records that changed
+func writeDiff(ctx context.Context, w io.Writer, sample io.Reader, continuation *regexp.Regexp, before, after *diffPipeline, all bool) (int, error) {
+	var errp error
+	records, changed := 0, 0
+	for line := range multilineLogIteratorWith(sample, continuation, multilineOptions{split: scanLinesCollapsingCR, err: &errp}) {
+		records++
+		diff := lineDiff(before.normalize(ctx, line), after.normalize(ctx, line))
+		if len(diff) == 0 && !all {
+			continue
+		}
+		if len(diff) > 0 {
+			changed++
+		}
+
+		fmt.Fprintf(w, "record %d: %s\n", records, truncateForDisplay(line, 120))
+		if len(diff) == 0 {
+			diff = []string{"(unchanged)"}
+		}
+		for _, d := range diff {
+			fmt.Fprintf(w, "  %s\n", d)
+		}
+	}
+	if errp != nil {
+		return changed, fmt.Errorf("failed to read sample: %w", errp)
+	}
+
+	fmt.Fprintf(w, "%d of %d records change\n", changed, records)
+	return changed, nil
+}
+
+func truncateForDisplay(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// runDiff implements `otel-logger config-diff`
+func runDiff(args []string) error {
+	var diffArgs DiffArgs
+	parser, err := arg.NewParser(arg.Config{Program: "otel-logger config-diff"}, &diffArgs)
+	if err != nil {
+		return err
+	}
+	parser.MustParse(args)
+
+	before, err := newDiffPipeline(diffArgs.Against)
+	if err != nil {
+		return fmt.Errorf("failed to load --against config: %w", err)
+	}
+	after, err := newDiffPipeline(diffArgs.Config)
+	if err != nil {
+		return fmt.Errorf("failed to load --config: %w", err)
+	}
+	// Lines are grouped once for both, as the new config would group them
+	continuation, err := continuationFor(diffArgs.ContinuationPattern, after.extractor)
+	if err != nil {
+		return fmt.Errorf("invalid continuation pattern: %w", err)
+	}
+
+	sample := io.Reader(os.Stdin)
+	if diffArgs.Sample != "-" {
+		file, err := os.Open(diffArgs.Sample)
+		if err != nil {
+			return fmt.Errorf("failed to open sample: %w", err)
+		}
+		defer file.Close()
+		sample = file
+	}
+
+	_, err = writeDiff(context.Background(), os.Stdout, sample, continuation, before, after, diffArgs.All)
+	return err
+}
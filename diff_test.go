@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	before := []string{"body: a", "severity: INFO (9)"}
+	after := []string{"body: b", "severity: INFO (9)"}
+
+	expected := []string{"- body: a", "+ body: b"}
+	if got := lineDiff(before, after); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := lineDiff(before, before); len(got) != 0 {
+		t.Errorf("Expected no diff for identical input, got %q", got)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.yaml")
+	if err := os.WriteFile(path, []byte("level_fields: [sev]\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	before, err := newDiffPipeline("")
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+	after, err := newDiffPipeline(path)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	sample := strings.Join([]string{
+		`{"level":"info","sev":"error","msg":"changed"}`,
+		`{"msg":"no level anywhere"}`,
+		`plain text`,
+	}, "\n")
+
+	var out bytes.Buffer
+	changed, err := writeDiff(context.Background(), &out, strings.NewReader(sample), defaultContinuationPattern, before, after, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if changed != 1 {
+		t.Errorf("Expected 1 changed record, got %d:\n%s", changed, out.String())
+	}
+	for _, expected := range []string{
+		"record 1:",
+		"- severity: info (9)",
+		"+ severity: error (17)",
+		"+ attributes.level: info",
+		"1 of 3 records change",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	// Records without timestamps must not show up as changed
+	if strings.Contains(out.String(), "record 2:") || strings.Contains(out.String(), "record 3:") {
+		t.Errorf("Expected only record 1 in output, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// djangoServerPattern matches the django.server logger's default
+	// format, as runserver writes it: [date] "request" status size
+	djangoServerPattern = regexp.MustCompile(`^\[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)$`)
+	// djangoRequestPattern matches the django.request logger's messages
+	// about failed requests, such as "Not Found: /favicon.ico" or
+	// "Forbidden (CSRF cookie not set.): /api/orders/"
+	djangoRequestPattern = regexp.MustCompile(`^([A-Z][A-Za-z' -]*?)(?: \((.*)\))?: (/\S*)$`)
+	// pythonTraceback groups a traceback with the message before it:
+	// its header, indented frames and the closing exception line
+	pythonTraceback = regexp.MustCompile(`^(?:Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|[A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt|Warning)(?::|$))`)
+)
+
+// djangoServerTimestamp is the layout of the django.server [date], in the
+// server's local time
+const djangoServerTimestamp = "02/Jan/2006 15:04:05"
+
+// httpStatusCodes looks up a status code by its reason phrase
+var httpStatusCodes = func() map[string]int {
+	codes := make(map[string]int)
+	for code := 100; code < 600; code++ {
+		if text := http.StatusText(code); text != "" {
+			codes[text] = code
+		}
+	}
+	return codes
+}()
+
+// ParseDjangoEntry decodes Django's default request logging: django.server
+// lines from runserver become HTTP attributes like access log lines, and
+// django.request messages such as "Not Found: /favicon.ico" get the path and
+// the status of their reason phrase. A Python traceback grouped with a
+// message becomes exception.* attributes. Other lines become plain messages.
+func (je *JSONExtractor) ParseDjangoEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseDjango(line); ok {
+		return entry, nil
+	}
+	entry := je.plainEntry(line)
+	setPythonTraceback(entry)
+	return entry, nil
+}
+
+func (je *JSONExtractor) parseDjango(line string) (*LogEntry, bool) {
+	first, _, _ := strings.Cut(line, "\n")
+	entry := &LogEntry{Fields: make(map[string]any), Raw: line, Level: "info"}
+	set := func(key, value string) {
+		if value != "" && value != "-" {
+			entry.Fields[key] = value
+		}
+	}
+
+	if m := djangoServerPattern.FindStringSubmatch(first); m != nil {
+		timestamp, err := time.ParseInLocation(djangoServerTimestamp, m[1], time.Local)
+		if err != nil {
+			return nil, false
+		}
+		entry.Timestamp = timestamp
+		entry.Message = unescapeCLF(m[2])
+		setRequestLine(set, entry.Message)
+		status, _ := strconv.Atoi(m[3])
+		entry.Fields["http.response.status_code"] = status
+		entry.Level = statusLevel(status)
+		if size, err := strconv.Atoi(m[4]); err == nil {
+			entry.Fields["http.response.body.size"] = size
+		}
+		return entry, true
+	}
+
+	m := djangoRequestPattern.FindStringSubmatch(first)
+	if m == nil {
+		return nil, false
+	}
+	status, ok := httpStatusCodes[m[1]]
+	if !ok {
+		return nil, false
+	}
+	je.stampIngestTime(entry)
+	entry.Message = line
+	entry.Fields["http.response.status_code"] = status
+	entry.Level = statusLevel(status)
+	set("url.path", m[3])
+	setPythonTraceback(entry)
+	return entry, true
+}
+
+// setPythonTraceback moves a traceback after the first line of the message
+// to exception.stacktrace, with exception.type and exception.message from
+// its last line, as in "ValueError: invalid literal"
+func setPythonTraceback(entry *LogEntry) {
+	message, traceback, ok := strings.Cut(entry.Message, "\nTraceback (most recent call last):")
+	if !ok {
+		return
+	}
+	traceback = "Traceback (most recent call last):" + traceback
+	entry.Message = strings.TrimSpace(message)
+	entry.Fields[exceptionStacktraceKey] = traceback
+
+	lines := strings.Split(strings.TrimRight(traceback, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if typ, msg, _ := strings.Cut(last, ": "); !strings.HasPrefix(last, " ") {
+		entry.Fields[exceptionTypeKey] = typ
+		if msg != "" {
+			entry.Fields[exceptionMessageKey] = msg
+		}
+	}
+}
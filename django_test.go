@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func djangoExtractor() *JSONExtractor {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatDjango
+	return NewJSONExtractor("", mappings)
+}
+
+func TestParseDjangoEntry(t *testing.T) {
+	extractor := djangoExtractor()
+
+	entry, _ := extractor.ParseLogEntry(`[15/Jan/2024 10:30:45] "GET /api/users/?page=2 HTTP/1.1" 404 1234`)
+	if !entry.Timestamp.Equal(time.Date(2024, 1, 15, 10, 30, 45, 0, time.Local)) {
+		t.Errorf("Expected the runserver timestamp, got %v", entry.Timestamp)
+	}
+	expected := map[string]any{
+		"http.request.method":       "GET",
+		"url.path":                  "/api/users/",
+		"url.query":                 "page=2",
+		"network.protocol.name":     "http",
+		"network.protocol.version":  "1.1",
+		"http.response.status_code": 404,
+		"http.response.body.size":   1234,
+	}
+	if entry.Level != "warn" || !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("Expected warn with %v, got %s with %v", expected, entry.Level, entry.Fields)
+	}
+
+	entry, _ = extractor.ParseLogEntry("Forbidden (CSRF cookie not set.): /api/orders/")
+	if entry.Level != "warn" || entry.Fields["http.response.status_code"] != 403 || entry.Fields["url.path"] != "/api/orders/" {
+		t.Errorf("Expected a 403 for /api/orders/, got %s with %v", entry.Level, entry.Fields)
+	}
+
+	entry, _ = extractor.ParseLogEntry("Watching for file changes with StatReloader")
+	if len(entry.Fields) != 0 || entry.Message != "Watching for file changes with StatReloader" {
+		t.Errorf("Expected a plain message, got %+v", entry)
+	}
+}
+
+func TestDjangoTraceback(t *testing.T) {
+	extractor := djangoExtractor()
+	continuation, err := continuationFor(`^[ \t]`, extractor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := strings.Join([]string{
+		"Internal Server Error: /api/orders/",
+		"Traceback (most recent call last):",
+		`  File "/app/orders/views.py", line 12, in create`,
+		"    quantity = int(request.POST['quantity'])",
+		"ValueError: invalid literal for int() with base 10: 'x'",
+		`[15/Jan/2024 10:30:45] "POST /api/orders/ HTTP/1.1" 500 145`,
+	}, "\n")
+	entries := slices.Collect(multilineLogIterator(strings.NewReader(input), continuation))
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 grouped entries, got %d: %q", len(entries), entries)
+	}
+
+	entry, _ := extractor.ParseLogEntry(entries[0])
+	if entry.Level != "error" || entry.Message != "Internal Server Error: /api/orders/" {
+		t.Errorf("Expected the error message without the traceback, got %s/%q", entry.Level, entry.Message)
+	}
+	if entry.Fields["exception.type"] != "ValueError" || entry.Fields["exception.message"] != "invalid literal for int() with base 10: 'x'" {
+		t.Errorf("Expected the exception from the last line, got %v", entry.Fields)
+	}
+	if stacktrace, _ := entry.Fields["exception.stacktrace"].(string); !strings.HasPrefix(stacktrace, "Traceback (most recent call last):\n") {
+		t.Errorf("Expected the traceback as the stack trace, got %q", stacktrace)
+	}
+}
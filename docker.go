@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultDockerHost is the Docker daemon's socket when DOCKER_HOST is unset
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerPollInterval is how often stopped containers are checked for a
+// restart, and with --docker-all the containers listed for new ones
+var dockerPollInterval = time.Second
+
+// Attributes of records read from containers
+const (
+	containerIDKey        = "container.id"
+	containerNameKey      = "container.name"
+	containerImageNameKey = "container.image.name"
+)
+
+// errDockerNotFound is returned for containers the daemon does not know
+var errDockerNotFound = errors.New("no such container")
+
+// dockerClient talks to the Docker Engine API, over the daemon's unix socket
+// or a tcp:// DOCKER_HOST
+type dockerClient struct {
+	http *http.Client
+	base string
+}
+
+func newDockerClient(host string) (*dockerClient, error) {
+	if host == "" {
+		host = defaultDockerHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		var dialer net.Dialer
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", u.Path)
+			},
+		}
+		return &dockerClient{http: &http.Client{Transport: transport}, base: "http://docker"}, nil
+	case "tcp", "http":
+		return &dockerClient{http: &http.Client{}, base: "http://" + u.Host}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DOCKER_HOST %q (supported: unix://, tcp://)", host)
+	}
+}
+
+// get requests an API path, returning errDockerNotFound for a 404 and the
+// daemon's message for other errors
+func (c *dockerClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the Docker daemon: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errDockerNotFound
+	}
+	var body struct{ Message string }
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	return nil, fmt.Errorf("docker API %s: %s %s", path, resp.Status, body.Message)
+}
+
+func (c *dockerClient) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// dockerContainer is what records are tagged with and how the logs are read
+type dockerContainer struct {
+	ID      string
+	Name    string
+	Image   string
+	TTY     bool
+	Running bool
+}
+
+// inspect looks up a container by name or ID
+func (c *dockerClient) inspect(ctx context.Context, ref string) (dockerContainer, error) {
+	var inspected struct {
+		ID     string `json:"Id"`
+		Name   string
+		Config struct {
+			Image string
+			Tty   bool
+		}
+		State struct{ Running bool }
+	}
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(ref)+"/json", nil, &inspected); err != nil {
+		return dockerContainer{}, err
+	}
+	return dockerContainer{
+		ID:      inspected.ID,
+		Name:    strings.TrimPrefix(inspected.Name, "/"),
+		Image:   inspected.Config.Image,
+		TTY:     inspected.Config.Tty,
+		Running: inspected.State.Running,
+	}, nil
+}
+
+// list returns the IDs of the containers with the labels, by whether they
+// are running
+func (c *dockerClient) list(ctx context.Context, labels []string) (map[string]bool, error) {
+	query := url.Values{"all": {"1"}}
+	if len(labels) > 0 {
+		filters, _ := json.Marshal(map[string][]string{"label": labels})
+		query.Set("filters", string(filters))
+	}
+	var containers []struct {
+		ID    string `json:"Id"`
+		State string
+	}
+	if err := c.getJSON(ctx, "/containers/json", query, &containers); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		ids[container.ID] = container.State == "running"
+	}
+	return ids, nil
+}
+
+// logs follows a container's output from since on, or from its start when
+// since is zero
+func (c *dockerClient) logs(ctx context.Context, id string, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	if !since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+	}
+	resp, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// demuxDockerStream splits the logs of a container without a TTY, which the
+// API sends as frames of an 8-byte header (stream, 3 zero bytes, big endian
+// length) and the data, into its stdout and stderr
+func demuxDockerStream(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var w io.Writer
+		switch header[0] {
+		case 0, 1: // stdin, which the logs do not have, or stdout
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			return fmt.Errorf("invalid docker log stream %d", header[0])
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// dockerSource reads the logs of containers, following them across
+// restarts
+type dockerSource struct {
+	client    *dockerClient
+	extractor *JSONExtractor
+	processor *LogProcessor
+	opts      streamOptions
+}
+
+// follow reads a running container's logs until it stops
+func (s *dockerSource) follow(ctx context.Context, container dockerContainer, since time.Time) error {
+	body, err := s.client.logs(ctx, container.ID, since)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if container.TTY {
+		// A TTY merges the streams
+		s.export(ctx, body, "stdout", container)
+		return nil
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	var wg sync.WaitGroup
+	for stream, r := range map[string]*io.PipeReader{"stdout": stdoutReader, "stderr": stderrReader} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.export(ctx, r, stream, container)
+			io.Copy(io.Discard, r)
+		}()
+	}
+	err = demuxDockerStream(body, stdoutWriter, stderrWriter)
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// export parses the lines of a stream, tagged with the container
+func (s *dockerSource) export(ctx context.Context, r io.Reader, stream string, container dockerContainer) {
+	var readErr error
+	for logEntry, readAt := range multilineLogIteratorWith(r, s.opts.continuationPattern, multilineOptions{split: s.opts.split, bufferSize: s.opts.readBuffer, err: &readErr}) {
+		entry, err := s.extractor.ParseLogEntry(logEntry)
+		if err != nil {
+			logError("Error parsing log entry from container %s: %v\n", container.Name, err)
+			continue
+		}
+		entry.Stream, entry.ReadAt = stream, readAt
+		for key, value := range map[string]string{containerIDKey: container.ID, containerNameKey: container.Name, containerImageNameKey: container.Image} {
+			if _, ok := entry.Fields[key]; !ok && entry.FieldOrder != nil {
+				entry.FieldOrder = append(entry.FieldOrder, key)
+			}
+			entry.Fields[key] = value
+		}
+		s.processor.ProcessLogEntry(ctx, entry)
+	}
+}
+
+// followRef follows the container a --docker-container name or ID refers
+// to, from now on. Once it stops, it is followed again when it restarts,
+// or when a container of the same name replaces it, from where it stopped.
+func (s *dockerSource) followRef(ctx context.Context, ref string) {
+	since := time.Now()
+	for ctx.Err() == nil {
+		container, err := s.client.inspect(ctx, ref)
+		switch {
+		case err == nil && container.Running:
+			if err := s.follow(ctx, container, since); err != nil {
+				logError("Error reading logs of container %s: %v\n", ref, err)
+			}
+			since = time.Now()
+		case err != nil && !errors.Is(err, errDockerNotFound) && ctx.Err() == nil:
+			logError("Error inspecting container %s: %v\n", ref, err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(dockerPollInterval):
+		}
+	}
+}
+
+// followAll follows every running container with the labels. Containers
+// running at startup are followed from now on, ones started later from their
+// start, and restarted ones from where they stopped.
+func (s *dockerSource) followAll(ctx context.Context, labels []string) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	active := make(map[string]bool)
+	stopped := make(map[string]time.Time) // when each container's logs ended
+
+	start := time.Now()
+	for first := true; ctx.Err() == nil; first = false {
+		ids, err := s.client.list(ctx, labels)
+		if err != nil && ctx.Err() == nil {
+			logError("Error listing containers: %v\n", err)
+		}
+		mu.Lock()
+		for id := range stopped {
+			if _, ok := ids[id]; !ok {
+				// Removed, so it cannot restart
+				delete(stopped, id)
+			}
+		}
+		for id, running := range ids {
+			if !running || active[id] {
+				continue
+			}
+			since, restarted := stopped[id]
+			if first {
+				since = start
+			} else if !restarted {
+				since = time.Time{}
+			}
+			container, err := s.client.inspect(ctx, id)
+			if err != nil {
+				if !errors.Is(err, errDockerNotFound) && ctx.Err() == nil {
+					logError("Error inspecting container %s: %v\n", id, err)
+				}
+				continue
+			}
+			active[id] = true
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.follow(ctx, container, since); err != nil {
+					logError("Error reading logs of container %s: %v\n", container.Name, err)
+				}
+				mu.Lock()
+				delete(active, id)
+				stopped[id] = time.Now()
+				mu.Unlock()
+			}()
+		}
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(dockerPollInterval):
+		}
+	}
+	wg.Wait()
+}
+
+// dockerLabelPattern is a --docker-label filter: a label, or label=value
+var dockerLabelPattern = regexp.MustCompile(`^[^=\s]+(=.*)?$`)
+
+// validateDocker checks the --docker-* flags
+func validateDocker(config *Config) error {
+	if len(config.DockerContainers) > 0 && config.DockerAll {
+		return errors.New("--docker-container cannot be combined with --docker-all")
+	}
+	if len(config.DockerLabels) > 0 && !config.DockerAll {
+		return errors.New("--docker-label requires --docker-all")
+	}
+	for _, label := range config.DockerLabels {
+		if !dockerLabelPattern.MatchString(label) {
+			return fmt.Errorf("invalid --docker-label %q: expected LABEL or LABEL=VALUE", label)
+		}
+	}
+	return nil
+}
+
+// processDocker follows the --docker-container containers, or with
+// --docker-all every one with the --docker-label labels, until interrupted
+func processDocker(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	if err := validateDocker(config); err != nil {
+		return err
+	}
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
+	if err != nil {
+		return fmt.Errorf("failed to compile continuation pattern: %w", err)
+	}
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+	client, err := newDockerClient(os.Getenv("DOCKER_HOST"))
+	if err != nil {
+		return err
+	}
+	if resp, err := client.get(ctx, "/_ping", nil); err != nil {
+		return err
+	} else {
+		resp.Body.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := enterSandbox(config); err != nil {
+		return err
+	}
+
+	s := &dockerSource{
+		client:    client,
+		extractor: extractor,
+		processor: processor,
+		opts:      streamOptions{continuationPattern: continuationPattern, split: split, readBuffer: readBuffer},
+	}
+	if config.DockerAll {
+		s.followAll(ctx, config.DockerLabels)
+		return nil
+	}
+	var wg sync.WaitGroup
+	for _, ref := range config.DockerContainers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.followRef(ctx, ref)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
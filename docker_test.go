@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dockerFrame is a frame of a container's multiplexed logs
+func dockerFrame(stream byte, data string) []byte {
+	header := make([]byte, 8, 8+len(data))
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestDemuxDockerStream(t *testing.T) {
+	var input bytes.Buffer
+	input.Write(dockerFrame(1, "out 1\nout"))
+	input.Write(dockerFrame(2, "err 1\n"))
+	input.Write(dockerFrame(1, " 2\n"))
+	var stdout, stderr bytes.Buffer
+	if err := demuxDockerStream(&input, &stdout, &stderr); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stdout.String() != "out 1\nout 2\n" || stderr.String() != "err 1\n" {
+		t.Errorf("Expected the streams split, got %q and %q", stdout.String(), stderr.String())
+	}
+
+	for _, bad := range [][]byte{dockerFrame(3, "x"), dockerFrame(1, "short")[:10], {1, 0, 0}} {
+		if err := demuxDockerStream(bytes.NewReader(bad), &stdout, &stderr); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// fakeDocker stands in for the Docker daemon with a container named web
+// that runs once, stops and is restarted, recording the logs requests
+type fakeDocker struct {
+	mu       sync.Mutex
+	running  bool
+	restarts int
+	logs     []string // query of each logs request
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch r.URL.Path {
+	case "/_ping":
+		w.Write([]byte("OK"))
+	case "/containers/web/json", "/containers/c0ffee/json":
+		json.NewEncoder(w).Encode(map[string]any{
+			"Id":     "c0ffee",
+			"Name":   "/web",
+			"Config": map[string]any{"Image": "nginx:1.25", "Tty": false},
+			"State":  map[string]any{"Running": d.running},
+		})
+		d.restart()
+	case "/containers/json":
+		state := "exited"
+		if d.running {
+			state = "running"
+		}
+		json.NewEncoder(w).Encode([]map[string]any{{"Id": "c0ffee", "State": state}})
+		d.restart()
+	case "/containers/c0ffee/logs":
+		d.logs = append(d.logs, r.URL.Query().Get("since"))
+		n := len(d.logs)
+		w.Write(dockerFrame(1, `{"level":"info","msg":"started `+strings.Repeat("again ", n-1)+`"}`+"\n"))
+		w.Write(dockerFrame(2, "warning on stderr\n"))
+		// The container stops, ending the logs
+		d.running = false
+	default:
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+	}
+}
+
+// restart starts the container again once it has been seen stopped
+func (d *fakeDocker) restart() {
+	if !d.running && d.restarts < 1 {
+		d.restarts++
+		d.running = true
+	}
+}
+
+func TestProcessDocker(t *testing.T) {
+	previous := dockerPollInterval
+	dockerPollInterval = 10 * time.Millisecond
+	defer func() { dockerPollInterval = previous }()
+
+	for _, config := range []*Config{
+		{DockerContainers: []string{"web"}, ContinuationPattern: `^[ \t]`},
+		{DockerAll: true, DockerLabels: []string{"app=web"}, ContinuationPattern: `^[ \t]`},
+	} {
+		daemon := &fakeDocker{running: true}
+		server := httptest.NewServer(daemon)
+		t.Setenv("DOCKER_HOST", "tcp://"+server.Listener.Addr().String())
+
+		provider, exporter := newRecordingProvider()
+		processor := NewLogProcessor(provider.Logger("test"))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- processDocker(ctx, config, NewJSONExtractor("", getDefaultFieldMappings()), processor) }()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(exporter.Records()) < 4 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		server.Close()
+
+		messages := make(map[string]bool)
+		for _, record := range exporter.Records() {
+			attrs := recordAttributes(record)
+			messages[strings.TrimSpace(record.Body().AsString())+"@"+attrs["log.iostream"]] = true
+			for key, value := range map[string]string{"container.name": "web", "container.id": "c0ffee", "container.image.name": "nginx:1.25"} {
+				if attrs[key] != value {
+					t.Errorf("Expected %s=%s, got %v", key, value, attrs)
+				}
+			}
+		}
+		expected := map[string]bool{"started@stdout": true, "started again@stdout": true, "warning on stderr@stderr": true}
+		if !reflect.DeepEqual(messages, expected) {
+			t.Errorf("Expected %v, got %v", expected, messages)
+		}
+		// Both attaches read from a point in time, the second from when the
+		// container stopped
+		if len(daemon.logs) != 2 || daemon.logs[0] == "" || daemon.logs[1] <= daemon.logs[0] {
+			t.Errorf("Expected the restart to be followed from where it stopped, got %q", daemon.logs)
+		}
+	}
+}
+
+func TestProcessDockerErrors(t *testing.T) {
+	for _, config := range []*Config{
+		{DockerContainers: []string{"web"}, DockerAll: true},
+		{DockerContainers: []string{"web"}, DockerLabels: []string{"app"}},
+		{DockerAll: true, DockerLabels: []string{"=web"}},
+	} {
+		if err := processDocker(context.Background(), config, nil, nil); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+
+	for _, host := range []string{"ssh://docker", "npipe:////./pipe/docker_engine"} {
+		if _, err := newDockerClient(host); err == nil {
+			t.Errorf("Expected error for %q", host)
+		}
+	}
+
+	t.Setenv("DOCKER_HOST", "unix:///nonexistent/docker.sock")
+	if err := processDocker(context.Background(), &Config{DockerAll: true}, NewJSONExtractor("", getDefaultFieldMappings()), nil); err == nil || !strings.Contains(err.Error(), "Docker daemon") {
+		t.Errorf("Expected an unreachable daemon to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// dockerJSONLine is a line of the log files Docker's json-file driver writes
+// under /var/lib/docker/containers, one JSON object per line of output
+type dockerJSONLine struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+// scanDockerJSON cuts the lines of Docker's json-file logs into records in
+// the CRI log format, so they are decoded like kubelet logs: the envelope's
+// time and stream make the prefix and its log field the content. Docker
+// splits lines longer than 16KiB into entries without the trailing newline;
+// these are joined again as scanCRI joins P parts.
+func scanDockerJSON(maxLine int) bufio.SplitFunc {
+	return scanLogParts(maxLine, cutDockerJSONPart)
+}
+
+// cutDockerJSONPart decodes a line of Docker's json-file format
+func cutDockerJSONPart(line []byte) (logPart, bool) {
+	if !bytes.HasPrefix(line, []byte(`{`)) || !bytes.Contains(line, []byte(`"log"`)) {
+		return logPart{}, false
+	}
+	var decoded dockerJSONLine
+	if err := json.Unmarshal(line, &decoded); err != nil || decoded.Log == nil {
+		return logPart{}, false
+	}
+	if decoded.Stream != "stdout" && decoded.Stream != "stderr" {
+		return logPart{}, false
+	}
+	if _, err := time.Parse(time.RFC3339Nano, decoded.Time); err != nil {
+		return logPart{}, false
+	}
+
+	content, full := strings.CutSuffix(*decoded.Log, "\n")
+	if full {
+		content = strings.TrimSuffix(content, "\r")
+	}
+	// Docker cuts the output at newlines, so any left in the content were
+	// not written by the container as line breaks; keep the record one line
+	content = strings.ReplaceAll(content, "\n", " ")
+	return logPart{
+		prefix:  []byte(decoded.Time + " " + decoded.Stream + " "),
+		stream:  []byte(decoded.Stream),
+		partial: !full,
+		content: []byte(content),
+	}, true
+}
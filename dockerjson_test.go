@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanDockerJSON(t *testing.T) {
+	input := `{"log":"full line\n","stream":"stdout","time":"2024-01-15T10:30:45.1Z"}` + "\n" +
+		`{"log":"{\"msg\":\"a ","stream":"stdout","time":"2024-01-15T10:30:45.2Z"}` + "\n" +
+		`{"log":"long line\"}\n","stream":"stdout","time":"2024-01-15T10:30:45.3Z"}` + "\n" +
+		`{"log":"\n","stream":"stderr","time":"2024-01-15T10:30:45.4Z"}` + "\n" +
+		`{"log":"from a tty\r\n","stream":"stdout","attrs":{"tag":"web"},"time":"2024-01-15T10:30:45.5Z"}` + "\n" +
+		`{"level":"info","msg":"not Docker's"}` + "\n" +
+		`{"log":"left at the end","stream":"stderr","time":"2024-01-15T10:30:45.6Z"}`
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(scanDockerJSON(bufio.MaxScanTokenSize))
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"2024-01-15T10:30:45.1Z stdout F full line",
+		`2024-01-15T10:30:45.2Z stdout F {"msg":"a long line"}`,
+		"2024-01-15T10:30:45.5Z stdout F from a tty",
+		`{"level":"info","msg":"not Docker's"}`,
+		"2024-01-15T10:30:45.6Z stderr F left at the end",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcessDockerJSON(t *testing.T) {
+	input := `{"log":"{\"level\":\"warn\",\"msg\":\"with its own\",\"time\":\"2024-01-15T10:30:44Z\"}\n","stream":"stdout","time":"2024-01-15T10:30:45.000000001Z"}` + "\n" +
+		`{"log":"Exception in thread \"main\" java.lang.IllegalStateException\n","stream":"stderr","time":"2024-01-15T10:30:46.5Z"}` + "\n" +
+		`{"log":"\tat com.example.Main.run(Main.java:42)\n","stream":"stderr","time":"2024-01-15T10:30:46.6Z"}` + "\n" +
+		`{"log":"plain text\n","stream":"stdout","time":"2024-01-15T10:30:47Z"}` + "\n"
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	extractor.SetCRI(true)
+	config := &Config{Framing: framingDockerJSON, ContinuationPattern: `^[ \t]`}
+	if err := processReader(context.Background(), config, extractor, processor, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, record := range exporter.Records() {
+		got = append(got, record.Body().AsString()+" | "+record.SeverityText()+" | "+recordAttributes(record)["log.iostream"]+" | "+record.Timestamp().UTC().Format(time.RFC3339Nano))
+	}
+	expected := []string{
+		"with its own | warn | stdout | 2024-01-15T10:30:44Z",
+		"Exception in thread \"main\" java.lang.IllegalStateException\n\tat com.example.Main.run(Main.java:42) | info | stderr | 2024-01-15T10:30:46.5Z",
+		"plain text | info | stdout | 2024-01-15T10:30:47Z",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// --duplicate-keys policies for attributes that end up with the same key
+const (
+	duplicateKeysFirst  = "first"  // keep the first value
+	duplicateKeysLast   = "last"   // keep the last value
+	duplicateKeysSuffix = "suffix" // keep every value, renaming repeats to key_2, key_3, ...
+)
+
+// duplicateKeysKey counts the duplicate keys resolved on a record
+const duplicateKeysKey = "otel_logger.duplicate_keys"
+
+func validDuplicateKeysPolicy(policy string) error {
+	switch policy {
+	case "", duplicateKeysFirst, duplicateKeysLast, duplicateKeysSuffix:
+		return nil
+	default:
+		return fmt.Errorf("invalid --duplicate-keys %q (supported: %s, %s, %s)", policy,
+			duplicateKeysFirst, duplicateKeysLast, duplicateKeysSuffix)
+	}
+}
+
+// dedupeAttributes resolves repeated keys according to policy, keeping each
+// key at the position it first appeared. Parsed fields come first in key
+// order, followed by the attributes otel-logger adds itself, so "last" lets
+// those win and "first" lets the log line win. It returns how many
+// duplicates were resolved.
+func dedupeAttributes(attrs []log.KeyValue, policy string) ([]log.KeyValue, int) {
+	return dedupeAttributesInto(nil, make(map[string]int, len(attrs)), attrs, policy)
+}
+
+// dedupeAttributesInto is dedupeAttributes appending to out, which must not
+// share memory with attrs, and indexing keys in the empty map index
+func dedupeAttributesInto(out []log.KeyValue, index map[string]int, attrs []log.KeyValue, policy string) ([]log.KeyValue, int) {
+	duplicates := 0
+	for _, kv := range attrs {
+		i, seen := index[kv.Key]
+		if !seen {
+			index[kv.Key] = len(out)
+			out = append(out, kv)
+			continue
+		}
+		duplicates++
+		switch policy {
+		case duplicateKeysFirst:
+		case duplicateKeysSuffix:
+			for n := 2; ; n++ {
+				key := kv.Key + "_" + strconv.Itoa(n)
+				if _, taken := index[key]; !taken {
+					index[key] = len(out)
+					out = append(out, log.KeyValue{Key: key, Value: kv.Value})
+					break
+				}
+			}
+		default:
+			out[i].Value = kv.Value
+		}
+	}
+	return out, duplicates
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestDedupeAttributes(t *testing.T) {
+	attrs := []log.KeyValue{
+		log.String("a", "1"),
+		log.String("b", "2"),
+		log.String("a", "3"),
+		log.String("a_2", "4"),
+		log.String("a", "5"),
+	}
+
+	tests := []struct {
+		policy     string
+		expected   []log.KeyValue
+		duplicates int
+	}{
+		{
+			policy:     duplicateKeysFirst,
+			expected:   []log.KeyValue{log.String("a", "1"), log.String("b", "2"), log.String("a_2", "4")},
+			duplicates: 2,
+		},
+		{
+			policy:     duplicateKeysLast,
+			expected:   []log.KeyValue{log.String("a", "5"), log.String("b", "2"), log.String("a_2", "4")},
+			duplicates: 2,
+		},
+		{
+			policy: duplicateKeysSuffix,
+			expected: []log.KeyValue{
+				log.String("a", "1"), log.String("b", "2"), log.String("a_2", "3"),
+				log.String("a_2_2", "4"), log.String("a_3", "5"),
+			},
+			duplicates: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			in := append([]log.KeyValue(nil), attrs...)
+			got, duplicates := dedupeAttributes(in, tt.policy)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if duplicates != tt.duplicates {
+				t.Errorf("Expected %d duplicates, got %d", tt.duplicates, duplicates)
+			}
+			if !reflect.DeepEqual(in, attrs) {
+				t.Errorf("Expected input to be left alone, got %v", in)
+			}
+		})
+	}
+}
+
+func TestLogProcessorDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected map[string]string
+	}{
+		{
+			policy:   duplicateKeysFirst,
+			expected: map[string]string{"log.iostream": "parsed", "errors.0.code": "E1", duplicateKeysKey: "2"},
+		},
+		{
+			policy:   duplicateKeysLast,
+			expected: map[string]string{"log.iostream": "stdout", "errors.0.code": "literal", duplicateKeysKey: "2"},
+		},
+		{
+			policy: duplicateKeysSuffix,
+			expected: map[string]string{
+				"log.iostream": "parsed", "log.iostream_2": "stdout",
+				"errors.0.code": "E1", "errors.0.code_2": "literal",
+				duplicateKeysKey: "2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			provider, exporter := newRecordingProvider()
+			processor := NewLogProcessor(provider.Logger("test"))
+			processor.SetObjectArrays(objectArraysExplode)
+			processor.SetDuplicateKeys(tt.policy)
+
+			extractor := NewJSONExtractor("", getDefaultFieldMappings())
+			entry, _ := extractor.ParseLogEntry(`{"msg":"x","log.iostream":"parsed","errors":[{"code":"E1"}],"errors.0.code":"literal"}`)
+			entry.Raw, entry.TimestampSource = "", ""
+			entry.Stream = "stdout"
+			processor.ProcessLogEntry(context.Background(), entry)
+
+			records := exporter.Records()
+			if len(records) != 1 {
+				t.Fatalf("Expected 1 record, got %d", len(records))
+			}
+			if got := recordAttributes(records[0]); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/middle-management/otel-logger/otlptest"
+)
+
+func TestEndToEndExport(t *testing.T) {
+	for _, protocol := range []string{otlptest.ProtocolGRPC, otlptest.ProtocolHTTPProtobuf, otlptest.ProtocolHTTPJSON} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver, err := otlptest.NewReceiver()
+			if err != nil {
+				t.Fatalf("Failed to start receiver: %v", err)
+			}
+			defer receiver.Close()
+
+			for _, kv := range receiver.Env(protocol) {
+				key, value, _ := strings.Cut(kv, "=")
+				t.Setenv(key, value)
+			}
+
+			config := &Config{
+				Timeout:             5 * time.Second,
+				BatchSize:           50,
+				FlushInterval:       time.Second,
+				ContinuationPattern: "^[ \\t]",
+				Command: []string{"sh", "-c",
+					`echo '{"level":"warn","msg":"disk almost full","disk":"/var"}'; echo 'plain stderr' >&2; exit 3`},
+			}
+			if err := runCommand(config); err == nil || !strings.Contains(err.Error(), "exit code 3") {
+				t.Fatalf("Expected the command's exit code to be reported, got %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			records, err := receiver.WaitForRecords(ctx, 3)
+			if err != nil {
+				t.Fatalf("Expected 3 records: %v", err)
+			}
+
+			byBody := make(map[string]otlptest.Record)
+			for _, r := range records {
+				byBody[r.Body] = r
+			}
+
+			warn, ok := byBody["disk almost full"]
+			if !ok {
+				t.Fatalf("Expected the JSON record, got %+v", records)
+			}
+			if warn.SeverityText != "warn" || warn.SeverityNumber != 13 {
+				t.Errorf("Expected warn (13), got %s (%d)", warn.SeverityText, warn.SeverityNumber)
+			}
+			if warn.Attributes["disk"] != "/var" || warn.Attributes["log.iostream"] != "stdout" {
+				t.Errorf("Expected disk and stream attributes, got %v", warn.Attributes)
+			}
+			if byBody["plain stderr"].Attributes["log.iostream"] != "stderr" {
+				t.Errorf("Expected stderr record, got %+v", records)
+			}
+		})
+	}
+}
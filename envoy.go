@@ -0,0 +1,230 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field names of Istio's JSON access log encoding, which the positions of
+// the text formats are named after too
+var (
+	// envoyTextFields are the fields of Envoy's default access log format
+	// after the [start time]
+	envoyTextFields = []string{
+		"request", "response_code", "response_flags", "bytes_received", "bytes_sent",
+		"duration", "upstream_service_time", "x_forwarded_for", "user_agent", "request_id",
+		"authority", "upstream_host",
+	}
+	// istioTextFields are the fields of Istio's default (TEXT) access log
+	// format after the [start time]
+	istioTextFields = []string{
+		"request", "response_code", "response_flags", "response_code_details", "connection_termination_details",
+		"upstream_transport_failure_reason", "bytes_received", "bytes_sent", "duration", "upstream_service_time",
+		"x_forwarded_for", "user_agent", "request_id", "authority", "upstream_host",
+		"upstream_cluster", "upstream_local_address", "downstream_local_address", "downstream_remote_address", "requested_server_name",
+		"route_name",
+	}
+)
+
+// envoyKeys are the attributes of the access log fields. Fields not listed
+// here, from custom formats, keep their names.
+var envoyKeys = map[string]string{
+	"response_flags":                    "envoy.response_flags",
+	"response_code_details":             "envoy.response_code_details",
+	"connection_termination_details":    "envoy.connection_termination_details",
+	"upstream_transport_failure_reason": "envoy.upstream_transport_failure_reason",
+	"bytes_received":                    "http.request.body.size",
+	"bytes_sent":                        "http.response.body.size",
+	"duration":                          "envoy.duration_ms",
+	"upstream_service_time":             "envoy.upstream_service_time_ms",
+	"x_forwarded_for":                   "http.request.header.x-forwarded-for",
+	"user_agent":                        "user_agent.original",
+	"request_id":                        "http.request.header.x-request-id",
+	"upstream_host":                     "envoy.upstream_host",
+	"upstream_cluster":                  "envoy.upstream_cluster",
+	"upstream_local_address":            "envoy.upstream_local_address",
+	"downstream_local_address":          "envoy.downstream_local_address",
+	"requested_server_name":             "tls.client.server_name",
+	"route_name":                        "envoy.route_name",
+}
+
+// envoyIntegers are the fields exported as integers
+var envoyIntegers = map[string]bool{
+	"bytes_received": true, "bytes_sent": true, "duration": true, "upstream_service_time": true,
+}
+
+// envoyJSONRequestKeys are the JSON keys read into the request, timestamp
+// and address attributes rather than through envoyKeys
+var envoyJSONRequestKeys = map[string]bool{
+	"start_time": true, "method": true, "path": true, "protocol": true,
+	"response_code": true, "authority": true, "downstream_remote_address": true,
+}
+
+// ParseEnvoyEntry decodes an Envoy access log line in Envoy's default
+// format, Istio's default TEXT format or Istio's JSON encoding. The request
+// becomes http.* and url.* attributes and the message, the status code sets
+// the level like --format clf, and the response flags, upstream cluster and
+// host and the durations become envoy.* attributes. Other lines become plain
+// messages.
+func (je *JSONExtractor) ParseEnvoyEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseEnvoy(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseEnvoy(line string) (*LogEntry, bool) {
+	var fields map[string]string
+	var extra map[string]any
+	if strings.HasPrefix(line, "{") {
+		fields, extra = envoyJSONFields(line)
+	} else {
+		fields = envoyTextLine(line)
+	}
+	if fields == nil {
+		return nil, false
+	}
+
+	entry := &LogEntry{Fields: make(map[string]any), Raw: line, Level: "info"}
+	for key, value := range extra {
+		entry.Fields[key] = value
+	}
+	if timestamp, err := time.Parse(time.RFC3339Nano, fields["start_time"]); err == nil {
+		entry.Timestamp = timestamp
+	} else {
+		je.stampIngestTime(entry)
+	}
+
+	set := func(key, value string) {
+		if value != "" && value != "-" {
+			entry.Fields[key] = value
+		}
+	}
+	request := fields["request"]
+	if request == "" {
+		request = strings.Join([]string{fields["method"], fields["path"], fields["protocol"]}, " ")
+	}
+	if method, _, _ := strings.Cut(request, " "); method != "-" {
+		setRequestLine(set, request)
+		entry.Message = request
+	} else {
+		// TCP proxying has no request; the message names the upstream
+		entry.Message = strings.TrimSpace("TCP " + strings.Trim(fields["upstream_host"], "-"))
+	}
+
+	if status, err := strconv.Atoi(fields["response_code"]); err == nil && status > 0 {
+		entry.Fields["http.response.status_code"] = status
+		entry.Level = statusLevel(status)
+	}
+	if authority := fields["authority"]; authority != "" && authority != "-" {
+		setHostPort(entry, "server", authority)
+	}
+	if remote := fields["downstream_remote_address"]; remote != "" && remote != "-" {
+		setHostPort(entry, "client", remote)
+	}
+	for field, value := range fields {
+		key, ok := envoyKeys[field]
+		if !ok {
+			continue
+		}
+		if envoyIntegers[field] {
+			if n, err := strconv.Atoi(value); err == nil {
+				entry.Fields[key] = n
+			}
+			continue
+		}
+		set(key, value)
+	}
+	return entry, true
+}
+
+// envoyTextLine splits a text access log line into the fields of Envoy's or
+// Istio's default format, told apart by how many fields there are
+func envoyTextLine(line string) map[string]string {
+	if !strings.HasPrefix(line, "[") {
+		return nil
+	}
+	end := strings.IndexByte(line, ']')
+	if end == -1 {
+		return nil
+	}
+	fields := map[string]string{"start_time": line[1:end]}
+
+	var values []string
+	rest := strings.TrimLeft(line[end+1:], " ")
+	for rest != "" {
+		var value string
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing == -1 {
+				return nil
+			}
+			value, rest = rest[1:closing+1], rest[closing+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+		values = append(values, value)
+		rest = strings.TrimLeft(rest, " ")
+	}
+
+	var names []string
+	switch len(values) {
+	case len(envoyTextFields):
+		names = envoyTextFields
+	case len(istioTextFields):
+		names = istioTextFields
+	default:
+		return nil
+	}
+	for i, name := range names {
+		fields[name] = values[i]
+	}
+	if _, err := strconv.Atoi(fields["response_code"]); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// envoyJSONFields reads Istio's JSON access log encoding. Keys it does not
+// define, from custom formats, are returned separately to be kept as they
+// are.
+func envoyJSONFields(line string) (map[string]string, map[string]any) {
+	data, ok := decodeJSONObject(line)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := data["response_code"]; !ok {
+		return nil, nil
+	}
+	fields := make(map[string]string)
+	extra := make(map[string]any)
+	for key, value := range data {
+		if _, known := envoyKeys[key]; !known && !envoyJSONRequestKeys[key] {
+			extra[key] = value
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			fields[key] = v
+		case float64:
+			fields[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return fields, extra
+}
+
+// setHostPort stores an address such as "httpbin:8000" as <prefix>.address
+// and <prefix>.port
+func setHostPort(entry *LogEntry, prefix, address string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		entry.Fields[prefix+".address"] = address
+		return
+	}
+	entry.Fields[prefix+".address"] = host
+	if n, err := strconv.Atoi(port); err == nil {
+		entry.Fields[prefix+".port"] = n
+	}
+}
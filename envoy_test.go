@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEnvoyEntry(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatEnvoy
+	extractor := NewJSONExtractor("", mappings)
+
+	tests := []struct {
+		name      string
+		line      string
+		message   string
+		level     string
+		timestamp time.Time
+		fields    map[string]any
+	}{
+		{
+			name:      "Envoy default",
+			line:      `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`,
+			message:   "POST /api/v1/locations HTTP/2",
+			level:     "info",
+			timestamp: time.Date(2016, 4, 15, 20, 17, 0, 310e6, time.UTC),
+			fields: map[string]any{
+				"http.request.method":                 "POST",
+				"url.path":                            "/api/v1/locations",
+				"network.protocol.name":               "http",
+				"network.protocol.version":            "2",
+				"http.response.status_code":           204,
+				"http.request.body.size":              154,
+				"http.response.body.size":             0,
+				"envoy.duration_ms":                   226,
+				"envoy.upstream_service_time_ms":      100,
+				"http.request.header.x-forwarded-for": "10.0.35.28",
+				"user_agent.original":                 "nsq2http",
+				"http.request.header.x-request-id":    "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2",
+				"server.address":                      "locations",
+				"envoy.upstream_host":                 "tcp://10.0.2.1:80",
+			},
+		},
+		{
+			name:      "Istio TEXT",
+			line:      `[2020-11-25T21:26:18.409Z] "GET /status/418 HTTP/1.1" 418 - via_upstream - "-" 0 135 4 4 "-" "curl/7.73.0-DEV" "84961386-6d84-929d-98bd-c5aee93b5c88" "httpbin:8000" "10.44.1.27:80" outbound|8000||httpbin.foo.svc.cluster.local 10.44.1.23:37652 10.0.45.184:8000 10.44.1.23:46520 - default`,
+			message:   "GET /status/418 HTTP/1.1",
+			level:     "warn",
+			timestamp: time.Date(2020, 11, 25, 21, 26, 18, 409e6, time.UTC),
+			fields: map[string]any{
+				"http.request.method":              "GET",
+				"url.path":                         "/status/418",
+				"network.protocol.name":            "http",
+				"network.protocol.version":         "1.1",
+				"http.response.status_code":        418,
+				"envoy.response_code_details":      "via_upstream",
+				"http.request.body.size":           0,
+				"http.response.body.size":          135,
+				"envoy.duration_ms":                4,
+				"envoy.upstream_service_time_ms":   4,
+				"user_agent.original":              "curl/7.73.0-DEV",
+				"http.request.header.x-request-id": "84961386-6d84-929d-98bd-c5aee93b5c88",
+				"server.address":                   "httpbin",
+				"server.port":                      8000,
+				"envoy.upstream_host":              "10.44.1.27:80",
+				"envoy.upstream_cluster":           "outbound|8000||httpbin.foo.svc.cluster.local",
+				"envoy.upstream_local_address":     "10.44.1.23:37652",
+				"envoy.downstream_local_address":   "10.0.45.184:8000",
+				"client.address":                   "10.44.1.23",
+				"client.port":                      46520,
+				"envoy.route_name":                 "default",
+			},
+		},
+		{
+			name:      "Istio JSON",
+			line:      `{"start_time":"2020-11-25T21:26:18.409Z","method":"GET","path":"/headers","protocol":"HTTP/1.1","response_code":503,"response_flags":"UF,URX","bytes_received":0,"bytes_sent":91,"duration":12,"upstream_service_time":null,"upstream_cluster":"outbound|8000||httpbin","authority":"httpbin:8000","tenant":"acme"}`,
+			message:   "GET /headers HTTP/1.1",
+			level:     "error",
+			timestamp: time.Date(2020, 11, 25, 21, 26, 18, 409e6, time.UTC),
+			fields: map[string]any{
+				"http.request.method":       "GET",
+				"url.path":                  "/headers",
+				"network.protocol.name":     "http",
+				"network.protocol.version":  "1.1",
+				"http.response.status_code": 503,
+				"envoy.response_flags":      "UF,URX",
+				"http.request.body.size":    0,
+				"http.response.body.size":   91,
+				"envoy.duration_ms":         12,
+				"envoy.upstream_cluster":    "outbound|8000||httpbin",
+				"server.address":            "httpbin",
+				"server.port":               8000,
+				"tenant":                    "acme",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Message != tt.message || entry.Level != tt.level {
+				t.Errorf("Expected %s/%q, got %s/%q", tt.level, tt.message, entry.Level, entry.Message)
+			}
+			if !entry.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.timestamp, entry.Timestamp)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.fields) {
+				t.Errorf("Expected fields %v, got %v", tt.fields, entry.Fields)
+			}
+		})
+	}
+
+	entry, _ := extractor.ParseLogEntry("[2020-11-25 21:26:18.409][1][info][main] starting main dispatch loop")
+	if len(entry.Fields) != 0 {
+		t.Errorf("Expected Envoy's own logs to be plain messages, got %v", entry.Fields)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel/log"
+)
+
+// eventNameFields are the fields whose value names the event a record is about
+var eventNameFields = []string{"event", "event.name", "event_name"}
+
+// keepRules exempts records from every stage that drops or holds back
+// records, so aggressive reduction can be deployed without losing the
+// records incidents are investigated with
+type keepRules struct {
+	minSeverity log.Severity    // records at or above this are kept; 0 for none
+	events      map[string]bool // event names that are always kept
+}
+
+func newKeepRules(level string, events []string) (*keepRules, error) {
+	rules := &keepRules{events: make(map[string]bool)}
+	if level != "" {
+		severity, err := parseSeverityLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		rules.minSeverity = severity
+	}
+	for _, event := range events {
+		rules.events[event] = true
+	}
+	return rules, nil
+}
+
+// keeps reports whether the entry must be exported; a nil keepRules keeps
+// nothing in particular
+func (k *keepRules) keeps(entry *LogEntry, severity log.Severity) bool {
+	if k == nil {
+		return false
+	}
+	if k.minSeverity != 0 && severity >= k.minSeverity {
+		return true
+	}
+	if len(k.events) == 0 {
+		return false
+	}
+	for _, field := range eventNameFields {
+		if name, ok := entry.Fields[field].(string); ok && k.events[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// SetKeepRules exempts matching records from being held back or dropped;
+// config reloads replace them
+func (p *LogProcessor) SetKeepRules(rules *keepRules) {
+	p.keep.Store(rules)
+}
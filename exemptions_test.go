@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestKeepRules(t *testing.T) {
+	rules, err := newKeepRules("error", []string{"payment.refunded"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		entry    *LogEntry
+		severity log.Severity
+		expected bool
+	}{
+		{"error", &LogEntry{}, log.SeverityError1, true},
+		{"fatal", &LogEntry{}, log.SeverityFatal1, true},
+		{"debug", &LogEntry{}, log.SeverityDebug1, false},
+		{"named event", &LogEntry{Fields: map[string]any{"event": "payment.refunded"}}, log.SeverityDebug1, true},
+		{"event.name", &LogEntry{Fields: map[string]any{"event.name": "payment.refunded"}}, log.SeverityDebug1, true},
+		{"other event", &LogEntry{Fields: map[string]any{"event": "cache.miss"}}, log.SeverityDebug1, false},
+	}
+	for _, tt := range tests {
+		if got := rules.keeps(tt.entry, tt.severity); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	var none *keepRules
+	if none.keeps(&LogEntry{}, log.SeverityFatal4) {
+		t.Error("Expected a nil keepRules to keep nothing")
+	}
+	if _, err := newKeepRules("loud", nil); err == nil {
+		t.Error("Expected error for an unknown level")
+	}
+}
+
+func TestKeepRulesBypassContextBuffer(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetContextBuffer(10, log.SeverityInfo1)
+	rules, _ := newKeepRules("error", []string{"user.signup"})
+	processor.SetKeepRules(rules)
+
+	ctx := context.Background()
+	processor.ProcessLogEntry(ctx, &LogEntry{Level: "debug", Message: "held"})
+	processor.ProcessLogEntry(ctx, &LogEntry{Level: "debug", Message: "signup", Fields: map[string]any{"event": "user.signup"}})
+
+	records := exporter.Records()
+	if len(records) != 1 || records[0].Body().AsString() != "signup" {
+		t.Fatalf("Expected only the exempt record to be exported, got %d records", len(records))
+	}
+	if _, ok := recordAttributes(records[0])[contextAttribute]; ok {
+		t.Error("Expected the exempt record to be exported directly, not as buffered context")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// exportPathAttribute records which endpoint delivered a record when
+// --endpoint-fallback is set
+const exportPathAttribute = "otel_logger.export.path"
+
+// Export paths
+const (
+	exportPathPrimary  = "primary"
+	exportPathFallback = "fallback"
+)
+
+// newEndpointExporter creates an exporter for an explicit endpoint rather than
+// the one in the environment. Non-nil headers replace the configured ones.
+func newEndpointExporter(ctx context.Context, config *Config, ep *otlpEndpoint, headers map[string]string) (sdklog.Exporter, error) {
+	policy := outboundTLS(config)
+	if ep.Protocol == "grpc" {
+		opts := []otlploggrpc.Option{otlploggrpc.WithEndpointURL(ep.URL.String())}
+		if headers != nil {
+			opts = append(opts, otlploggrpc.WithHeaders(headers))
+		}
+		creds, err := policy.grpcCredentials("LOGS", ep.Insecure)
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			opts = append(opts, otlploggrpc.WithTLSCredentials(creds))
+		}
+		return newGRPCExporter(ctx, config, opts...)
+	}
+
+	opts := []otlploghttp.Option{otlploghttp.WithEndpointURL(ep.URL.String())}
+	if headers != nil {
+		opts = append(opts, otlploghttp.WithHeaders(headers))
+	}
+	switch {
+	case ep.Protocol == "http/json":
+		client, err := newJSONHTTPClient(config.Timeout, policy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otlploghttp.WithHTTPClient(client))
+	case policy != nil && !ep.Insecure:
+		cfg, err := otlpTLSConfig("LOGS")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otlploghttp.WithTLSClientConfig(policy.apply(cfg)))
+	}
+	return otlploghttp.New(ctx, opts...)
+}
+
+// flagEndpoint parses an endpoint given on the command line like
+// OTEL_EXPORTER_OTLP_ENDPOINT, as a base URL for HTTP unless it already names
+// the logs path
+func flagEndpoint(raw, protocol string) (*otlpEndpoint, error) {
+	ep, err := parseEndpoint(raw, protocol, false)
+	if err != nil {
+		return nil, err
+	}
+	if protocol != "grpc" && !strings.HasSuffix(ep.URL.Path, "/v1/logs") {
+		ep.URL.Path = strings.TrimSuffix(ep.URL.Path, "/") + "/v1/logs"
+	}
+	return ep, nil
+}
+
+// failoverExporter sends batches to the primary collector and, once an
+// export there fails, to the fallback. While on the fallback the primary is
+// probed at most once per interval, and export switches back as soon as a
+// probe succeeds.
+type failoverExporter struct {
+	primary  sdklog.Exporter
+	fallback sdklog.Exporter
+	probe    func(ctx context.Context) error // checks the primary is reachable
+	interval time.Duration
+	verbose  bool
+
+	mu         sync.Mutex
+	onFallback bool
+	lastProbe  time.Time
+}
+
+func (e *failoverExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.onFallback && time.Since(e.lastProbe) >= e.interval {
+		e.lastProbe = time.Now()
+		if err := e.probe(ctx); err == nil {
+			logError("Primary OTLP endpoint is reachable again; switching back from fallback\n")
+			e.onFallback = false
+		} else {
+			logDebug(e.verbose, "Primary OTLP endpoint still unavailable: %v\n", err)
+		}
+	}
+
+	if !e.onFallback {
+		// Leave the fallback time to deliver the batch if the primary times out
+		primaryCtx, cancel := splitDeadline(ctx)
+		err := e.primary.Export(primaryCtx, markExportPath(records, exportPathPrimary))
+		cancel()
+		if err == nil {
+			return nil
+		}
+		logError("Export to primary OTLP endpoint failed, switching to fallback: %v\n", err)
+		e.onFallback = true
+		e.lastProbe = time.Now()
+	}
+
+	return e.fallback.Export(ctx, markExportPath(records, exportPathFallback))
+}
+
+func (e *failoverExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.primary.ForceFlush(ctx), e.fallback.ForceFlush(ctx))
+}
+
+func (e *failoverExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.primary.Shutdown(ctx), e.fallback.Shutdown(ctx))
+}
+
+// splitDeadline returns a context with half of the time left before ctx's
+// deadline, or ctx itself if it has none
+func splitDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/2)
+}
+
+func markExportPath(records []sdklog.Record, path string) []sdklog.Record {
+	marked := make([]sdklog.Record, len(records))
+	for i, record := range records {
+		marked[i] = record.Clone()
+		marked[i].AddAttributes(log.String(exportPathAttribute, path))
+	}
+	return marked
+}
+
+// withEndpointFallback wraps the primary exporter with failover to
+// --endpoint-fallback, if set
+func withEndpointFallback(ctx context.Context, config *Config, primary sdklog.Exporter) (sdklog.Exporter, error) {
+	if config.EndpointFallback == "" {
+		return primary, nil
+	}
+
+	protocol := resolveProtocol()
+	fallbackEp, err := flagEndpoint(config.EndpointFallback, protocol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --endpoint-fallback: %w", err)
+	}
+	primaryEps, err := primaryEndpoints(config, protocol)
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := newEndpointExporter(ctx, config, fallbackEp, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fallback exporter: %w", err)
+	}
+
+	interval := config.EndpointProbeInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	logInfo(config.Verbose, "Exporting with fallback to %s\n", fallbackEp.URL)
+
+	return &failoverExporter{
+		primary:  primary,
+		fallback: fallback,
+		probe: func(ctx context.Context) error {
+			// Any reachable replica makes the primary usable again
+			var errs []error
+			for _, ep := range primaryEps {
+				err := probeWithTimeout(ctx, config, ep)
+				if err == nil {
+					return nil
+				}
+				errs = append(errs, err)
+			}
+			return errors.Join(errs...)
+		},
+		interval: interval,
+		verbose:  config.Verbose,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// flakyExporter records exports like recordingExporter but fails while down
+type flakyExporter struct {
+	recordingExporter
+	down bool
+}
+
+func (e *flakyExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if e.down {
+		return errors.New("connection refused")
+	}
+	return e.recordingExporter.Export(ctx, records)
+}
+
+func TestFailoverExporter(t *testing.T) {
+	primary := &flakyExporter{}
+	fallback := &recordingExporter{}
+	primaryReachable := false
+	exporter := &failoverExporter{
+		primary:  primary,
+		fallback: fallback,
+		probe: func(ctx context.Context) error {
+			if !primaryReachable {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		interval: time.Hour,
+	}
+
+	ctx := context.Background()
+
+	// Records from a provider carry its attribute limits, unlike a zero Record
+	provider, source := newRecordingProvider()
+	provider.Logger("test").Emit(ctx, log.Record{})
+	batch := source.Records()
+
+	export := func() {
+		t.Helper()
+		if err := exporter.Export(ctx, batch); err != nil {
+			t.Fatalf("Unexpected export error: %v", err)
+		}
+	}
+
+	export()
+	if len(primary.Records()) != 1 || recordAttributes(primary.Records()[0])[exportPathAttribute] != exportPathPrimary {
+		t.Fatalf("Expected the first batch on the primary, marked primary")
+	}
+
+	primary.down = true
+	export()
+	if len(fallback.Records()) != 1 || recordAttributes(fallback.Records()[0])[exportPathAttribute] != exportPathFallback {
+		t.Fatalf("Expected the batch to fail over, marked fallback")
+	}
+
+	// The primary is not retried until the probe interval passes
+	primary.down = false
+	export()
+	if len(fallback.Records()) != 2 || len(primary.Records()) != 1 {
+		t.Errorf("Expected to stay on the fallback before the next probe")
+	}
+
+	// A failing probe keeps the fallback
+	exporter.lastProbe = time.Time{}
+	export()
+	if len(fallback.Records()) != 3 {
+		t.Errorf("Expected to stay on the fallback while the probe fails")
+	}
+
+	primaryReachable = true
+	exporter.lastProbe = time.Time{}
+	export()
+	if len(primary.Records()) != 2 || len(fallback.Records()) != 3 {
+		t.Errorf("Expected to switch back to the primary once the probe succeeds")
+	}
+}
+
+func TestSplitDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	split, splitCancel := splitDeadline(ctx)
+	defer splitCancel()
+	deadline, ok := split.Deadline()
+	if !ok || time.Until(deadline) > 5*time.Second+100*time.Millisecond {
+		t.Errorf("Expected about half the time left, got %v", time.Until(deadline))
+	}
+
+	if unbounded, unboundedCancel := splitDeadline(context.Background()); unbounded != context.Background() {
+		t.Error("Expected a context without deadline to be used as-is")
+	} else {
+		unboundedCancel()
+	}
+}
+
+func TestFlagEndpoint(t *testing.T) {
+	tests := []struct {
+		raw      string
+		protocol string
+		expected string
+	}{
+		{"http://backup:4318", "http/protobuf", "http://backup:4318/v1/logs"},
+		{"http://backup:4318/v1/logs", "http/protobuf", "http://backup:4318/v1/logs"},
+		{"https://backup", "http/protobuf", "https://backup/v1/logs"},
+		{"backup", "http/protobuf", "https://backup:4318/v1/logs"},
+		{"http://backup", "grpc", "http://backup"},
+	}
+	for _, tt := range tests {
+		ep, err := flagEndpoint(tt.raw, tt.protocol)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.raw, err)
+			continue
+		}
+		if ep.URL.String() != tt.expected {
+			t.Errorf("%s (%s): expected %s, got %s", tt.raw, tt.protocol, tt.expected, ep.URL)
+		}
+	}
+
+	if _, err := flagEndpoint("http://:4318", "grpc"); err == nil {
+		t.Error("Expected error for an endpoint without host")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// messagePlaceholder is a {field} in a message_template
+var messagePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// convertNumbers turns the number_fields given as strings, as every logfmt
+// and kv value is, into integers or doubles. Values that are not numbers
+// stay strings.
+func convertNumbers(fields map[string]any, names []string) {
+	for _, name := range names {
+		s, ok := fields[name].(string)
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			fields[name] = n
+		} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+			fields[name] = f
+		}
+	}
+}
+
+// expandMessageTemplate builds the message of a line without a message
+// field, such as "{method} {path} {status}" for Lograge. The fields stay
+// attributes; missing ones expand to nothing.
+func expandMessageTemplate(template string, fields map[string]any) string {
+	return messagePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := fields[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return ""
+		}
+		return fmt.Sprint(value)
+	})
+}
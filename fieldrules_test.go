@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLogragePreset(t *testing.T) {
+	fc, err := parseFileConfig([]byte("format: lograge\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	extractor := NewJSONExtractor("", fc.fieldMappings())
+
+	entry, err := extractor.ParseLogEntry("method=GET path=/jobs/833552.json format=json controller=JobsController action=show status=200 duration=58.33 view=40.43 db=15.26")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Message != "GET /jobs/833552.json 200" {
+		t.Errorf("Expected the message from the template, got %q", entry.Message)
+	}
+	expected := map[string]any{
+		"http.request.method":       "GET",
+		"url.path":                  "/jobs/833552.json",
+		"http.response.status_code": int64(200),
+		"rails.format":              "json",
+		"rails.controller":          "JobsController",
+		"rails.action":              "show",
+		"rails.duration_ms":         58.33,
+		"rails.view_ms":             40.43,
+		"rails.db_ms":               15.26,
+	}
+	if !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, entry.Fields)
+	}
+}
+
+func TestConvertNumbers(t *testing.T) {
+	fields := map[string]any{"a": "42", "b": "-1.5", "c": "fast", "d": true}
+	convertNumbers(fields, []string{"a", "b", "c", "d", "missing"})
+	expected := map[string]any{"a": int64(42), "b": -1.5, "c": "fast", "d": true}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, fields)
+	}
+}
+
+func TestExpandMessageTemplate(t *testing.T) {
+	got := expandMessageTemplate("{method} {path} took {ms}ms{missing}", map[string]any{"method": "GET", "path": "/", "ms": 1.5})
+	if got != "GET / took 1.5ms" {
+		t.Errorf("Expected expanded template, got %q", got)
+	}
+}
+
+func TestParseFileConfigRenameFields(t *testing.T) {
+	if _, err := parseFileConfig([]byte("rename_fields:\n  userId: \"\"\n")); err == nil {
+		t.Error("Expected error for an empty new name")
+	}
+	fc, err := parseFileConfig([]byte("rename_fields:\n  userId: enduser.id\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry, _ := NewJSONExtractor("", fc.fieldMappings()).ParseLogEntry(`{"msg":"x","userId":"u1"}`)
+	if entry.Fields["enduser.id"] != "u1" {
+		t.Errorf("Expected userId to be renamed, got %v", entry.Fields)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	collogpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// fileExporter writes each batch as one line of OTLP/JSON, the format of the
+// collector's file exporter, which its otlpjsonfile receiver can read back.
+// The file is rotated once it reaches maxSize bytes or is older than maxAge.
+type fileExporter struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	compress bool
+	now      func() time.Time
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// newFileExporter opens path for appending
+func newFileExporter(path string, maxSize int64, maxAge time.Duration, compress bool) (*fileExporter, error) {
+	e := &fileExporter{
+		path:     path,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		compress: compress,
+		now:      time.Now,
+	}
+	if err := e.open(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *fileExporter) open() error {
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	e.file, e.size, e.opened = file, info.Size(), e.now()
+	return nil
+}
+
+func (e *fileExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body, err := proto.Marshal(otlpRequest(records))
+	if err != nil {
+		return fmt.Errorf("failed to encode export request: %w", err)
+	}
+	line, err := protobufToOTLPJSON(body)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.file == nil {
+		return fmt.Errorf("export file %s is closed", e.path)
+	}
+	if e.size > 0 && e.dueForRotation(len(line)) {
+		if err := e.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := e.file.Write(line)
+	e.size += int64(n)
+	return err
+}
+
+func (e *fileExporter) dueForRotation(next int) bool {
+	if e.maxSize > 0 && e.size+int64(next) > e.maxSize {
+		return true
+	}
+	return e.maxAge > 0 && e.now().Sub(e.opened) >= e.maxAge
+}
+
+// rotate moves the current file aside, named after the time it was opened,
+// compresses it if configured, and starts a new one
+func (e *fileExporter) rotate() error {
+	if err := e.file.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+	e.file = nil
+
+	rotated := rotatedFileName(e.path, e.opened)
+	if err := os.Rename(e.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate export file: %w", err)
+	}
+	if e.compress {
+		if err := gzipFile(rotated); err != nil {
+			logError("Failed to compress rotated export file %s: %v\n", rotated, err)
+		}
+	}
+	return e.open()
+}
+
+func (e *fileExporter) ForceFlush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	return e.file.Sync()
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// rotatedFileName inserts the time the file was started before its
+// extension, e.g. logs.jsonl becomes logs-20250102T150405Z.jsonl, adding a
+// counter if that name is already taken
+func rotatedFileName(path string, started time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "-" + started.UTC().Format("20060102T150405Z")
+	name := base + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// gzipFile replaces path with path.gz
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(out)
+	if _, err := io.Copy(w, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := w.Close(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Remove(path)
+}
+
+// parseRotation reads --export-file-rotate: a size such as 100MB, a duration
+// such as 1h, or both separated by a comma
+func parseRotation(spec string) (maxSize int64, maxAge time.Duration, err error) {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(part); err == nil && d > 0 {
+			maxAge = d
+			continue
+		}
+		size, err := parseByteSize(part)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid --export-file-rotate %q: expected a size (e.g. 100MB) or a duration (e.g. 1h)", part)
+		}
+		maxSize = size
+	}
+	return maxSize, maxAge, nil
+}
+
+// byteUnits are the size suffixes parseByteSize accepts, longest first
+var byteUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes like 100MB, 512KiB or 4096
+func parseByteSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper, factor = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix)), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// otlpRequest groups records by resource and instrumentation scope into an
+// export request, as the OTLP exporters do
+func otlpRequest(records []sdklog.Record) *collogpb.ExportLogsServiceRequest {
+	request := &collogpb.ExportLogsServiceRequest{}
+	resources := make(map[attribute.Distinct]*logspb.ResourceLogs)
+	scopes := make(map[*logspb.ResourceLogs]map[string]*logspb.ScopeLogs)
+
+	for _, record := range records {
+		res := record.Resource()
+		rl, ok := resources[res.Equivalent()]
+		if !ok {
+			rl = &logspb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: otlpAttributes(res.Iter())},
+				SchemaUrl: res.SchemaURL(),
+			}
+			resources[res.Equivalent()] = rl
+			scopes[rl] = make(map[string]*logspb.ScopeLogs)
+			request.ResourceLogs = append(request.ResourceLogs, rl)
+		}
+
+		scope := record.InstrumentationScope()
+		key := scope.Name + "\x00" + scope.Version + "\x00" + scope.SchemaURL
+		sl, ok := scopes[rl][key]
+		if !ok {
+			sl = &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{
+					Name:       scope.Name,
+					Version:    scope.Version,
+					Attributes: otlpAttributes(scope.Attributes.Iter()),
+				},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[rl][key] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, otlpRecord(record))
+	}
+	return request
+}
+
+func otlpAttributes(iter attribute.Iterator) []*commonpb.KeyValue {
+	var kvs []*commonpb.KeyValue
+	for iter.Next() {
+		kv := iter.Attribute()
+		kvs = append(kvs, &commonpb.KeyValue{Key: string(kv.Key), Value: otlpAttributeValue(kv.Value)})
+	}
+	return kvs
+}
+
+func otlpAttributeValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	default:
+		// Slices are rare on resources; keep their text form
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+// newExportFileExporter writes to --export-file instead of a collector
+func newExportFileExporter(config *Config) (sdklog.Exporter, error) {
+	if len(config.Endpoints) > 0 || config.EndpointFallback != "" || config.TenantHeaders != "" {
+		return nil, fmt.Errorf("--export-file cannot be combined with --endpoints, --endpoint-fallback or --tenant-headers")
+	}
+	maxSize, maxAge, err := parseRotation(config.ExportFileRotate)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.ExportFormat {
+	case "", exportFormatOTLPJSON:
+		if config.ExportFileCompress && maxSize == 0 && maxAge == 0 {
+			return nil, fmt.Errorf("--export-file-compress requires --export-file-rotate")
+		}
+		return newFileExporter(config.ExportFile, maxSize, maxAge, config.ExportFileCompress)
+	case exportFormatParquet:
+		if maxSize == 0 && maxAge == 0 {
+			maxSize = defaultParquetRotation
+		}
+		return newParquetExporter(config.ExportFile, maxSize, maxAge, config.ExportFileCompress)
+	default:
+		return nil, fmt.Errorf("invalid --export-format %q (supported: %s, %s)", config.ExportFormat, exportFormatOTLPJSON, exportFormatParquet)
+	}
+}
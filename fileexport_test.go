@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestParseRotation(t *testing.T) {
+	tests := []struct {
+		spec    string
+		size    int64
+		age     time.Duration
+		wantErr bool
+	}{
+		{spec: "100MB", size: 100 * 1000 * 1000},
+		{spec: "512KiB", size: 512 << 10},
+		{spec: "1h", age: time.Hour},
+		{spec: "10MB, 30m", size: 10 * 1000 * 1000, age: 30 * time.Minute},
+		{spec: ""},
+		{spec: "often", wantErr: true},
+		{spec: "-5MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		size, age, err := parseRotation(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRotation(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if size != tt.size || age != tt.age {
+			t.Errorf("parseRotation(%q) = %d, %v; expected %d, %v", tt.spec, size, age, tt.size, tt.age)
+		}
+	}
+}
+
+// exportFileBatch emits one record per message through a provider, so
+// records carry a resource and scope like real ones
+func exportFileBatch(messages ...string) []sdklog.Record {
+	provider, source := newRecordingProvider()
+	logger := provider.Logger("otel-logger")
+	for _, message := range messages {
+		var record log.Record
+		record.SetBody(log.StringValue(message))
+		record.SetSeverity(log.SeverityInfo)
+		record.AddAttributes(log.String("stream", "stdout"))
+		logger.Emit(context.Background(), record)
+	}
+	return source.Records()
+}
+
+func TestFileExporterWritesOTLPJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	exporter, err := newFileExporter(path, 0, 0, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if err := exporter.Export(ctx, exportFileBatch("first", "second")); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+	exporter.Shutdown(ctx)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				LogRecords []struct {
+					Body struct {
+						StringValue string `json:"stringValue"`
+					} `json:"body"`
+					SeverityNumber int `json:"severityNumber"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("Export file is not OTLP/JSON: %v\n%s", err, data)
+	}
+	if len(request.ResourceLogs) != 1 || len(request.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("Expected one resource and scope, got %s", data)
+	}
+	scope := request.ResourceLogs[0].ScopeLogs[0]
+	if scope.Scope.Name != "otel-logger" || len(scope.LogRecords) != 2 ||
+		scope.LogRecords[1].Body.StringValue != "second" || scope.LogRecords[1].SeverityNumber != int(log.SeverityInfo) {
+		t.Errorf("Unexpected export file contents: %s", data)
+	}
+}
+
+func TestFileExporterRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs.jsonl")
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	exporter, err := newFileExporter(path, 0, time.Hour, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exporter.now = func() time.Time { return now }
+	exporter.opened = now
+
+	ctx := context.Background()
+	exporter.Export(ctx, exportFileBatch("in the first hour"))
+	now = now.Add(30 * time.Minute)
+	exporter.Export(ctx, exportFileBatch("still the first hour"))
+	now = now.Add(40 * time.Minute)
+	exporter.Export(ctx, exportFileBatch("second hour"))
+	exporter.Shutdown(ctx)
+
+	rotated := filepath.Join(dir, "logs-20250102T150405Z.jsonl.gz")
+	file, err := os.Open(rotated)
+	if err != nil {
+		t.Fatalf("Expected compressed rotated file: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines int
+	for scanner := bufio.NewScanner(gz); scanner.Scan(); lines++ {
+	}
+	if lines != 2 {
+		t.Errorf("Expected 2 batches in the rotated file, got %d", lines)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(current), "second hour") || strings.Count(string(current), "\n") != 1 {
+		t.Errorf("Expected only the latest batch in the current file, got %s", current)
+	}
+}
+
+func TestFileExporterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs.jsonl")
+	exporter, err := newFileExporter(path, 1, 0, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	for range 3 {
+		exporter.Export(ctx, exportFileBatch("record"))
+	}
+	exporter.Shutdown(ctx)
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("Expected the file and two rotated files, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,663 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileRescanInterval is how often the --file patterns are globbed again, to
+// pick up directories created since, such as a new pod's log directory, and
+// files whose creation event was missed
+const fileRescanInterval = 10 * time.Second
+
+// filePollInterval is how often a followed file is checked for new data
+// without a write event, which network filesystems do not deliver, unless
+// --io-poll-interval says otherwise
+const filePollInterval = time.Second
+
+// validFilePatterns checks the --file globs before anything is watched
+func validFilePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --file pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// fileWatcher follows every file matching the --file patterns, each in its
+// own goroutine through multilineLogIterator, and starts following files as
+// they are created. Files are followed across rotation and truncation and
+// until they are deleted.
+type fileWatcher struct {
+	patterns  []string
+	watcher   *fsnotify.Watcher
+	extractor *JSONExtractor
+	processor *LogProcessor
+	opts      streamOptions
+
+	checkpoints        *checkpointStore // nil without --checkpoint-dir
+	checkpointInterval time.Duration
+	pollInterval       time.Duration // --io-poll-interval, filePollInterval when 0
+
+	mu       sync.Mutex
+	watched  map[string]bool                    // directories added to the watcher
+	tails    map[string]*fileTail               // by path
+	archives map[fileCheckpoint]archiveSighting // read in full, by identity
+	wg       sync.WaitGroup
+}
+
+// fileTail is a file being followed; wake is signalled on events for it
+type fileTail struct {
+	path string
+	wake chan struct{}
+	poll time.Duration // how often it is checked without an event, filePollInterval when 0
+
+	// Where the records processed so far end, for --checkpoint-dir
+	mu            sync.Mutex
+	position      fileCheckpoint
+	file          *os.File // that position is in
+	base          int64    // of the reader when fingerprinted
+	fingerprinted int64    // bytes the fingerprint covers
+}
+
+// commit records that the records read up to pos, a position in the stream
+// r returns, were processed
+func (t *fileTail) commit(r *tailReader, pos int64) {
+	offset := pos - r.base
+	if offset < 0 {
+		// Still in a file rotated away since
+		return
+	}
+	covered := offset
+	if covered > checkpointFingerprintSize {
+		covered = checkpointFingerprintSize
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Only the start of a file, until it is rotated or truncated, is hashed
+	if r.file != t.file || r.base != t.base || covered != t.fingerprinted {
+		fingerprint, err := fileFingerprint(r.file, offset)
+		if err != nil {
+			return
+		}
+		t.position.Fingerprint, t.file, t.base, t.fingerprinted = fingerprint, r.file, r.base, covered
+	}
+	t.position.Inode, t.position.Offset = r.inode, offset
+}
+
+// checkpoint returns the last committed position
+func (t *fileTail) checkpoint() (fileCheckpoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.position, t.file != nil
+}
+
+// pollInterval is how often the file is checked for new data without a
+// write event
+func (t *fileTail) pollInterval() time.Duration {
+	if t.poll > 0 {
+		return t.poll
+	}
+	return filePollInterval
+}
+
+// processFiles follows the files matching config.Files until interrupted
+func processFiles(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	if err := validFilePatterns(config.Files); err != nil {
+		return err
+	}
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
+	if err != nil {
+		return fmt.Errorf("failed to compile continuation pattern: %w", err)
+	}
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+	if err := validateBinaryOutput(config.BinaryOutput); err != nil {
+		return err
+	}
+	if err := validateCheckpoints(config); err != nil {
+		return err
+	}
+	checkpoints, err := openCheckpointStore(config.CheckpointDir)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch --file directories: %w", err)
+	}
+	defer watcher.Close()
+
+	// Files are followed until interrupted; the records read so far are
+	// flushed on the way out
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &fileWatcher{
+		patterns:  config.Files,
+		watcher:   watcher,
+		extractor: extractor,
+		processor: processor,
+		opts: streamOptions{
+			continuationPattern: continuationPattern,
+			split:               split,
+			readBuffer:          readBuffer,
+			binaryPolicy:        config.BinaryOutput,
+			binaryFraming:       binaryFraming(config.Framing),
+		},
+		checkpoints:        checkpoints,
+		checkpointInterval: config.CheckpointInterval,
+		pollInterval:       config.IOPollInterval,
+		watched:            make(map[string]bool),
+		tails:              make(map[string]*fileTail),
+		archives:           make(map[fileCheckpoint]archiveSighting),
+	}
+	for path, id := range checkpoints.takeArchives() {
+		w.archives[id] = archiveSighting{path: path, at: time.Now()}
+	}
+	w.scan(ctx, config.FileFromStart)
+	if err := enterSandbox(config); err != nil {
+		return err
+	}
+	w.run(ctx)
+	w.wg.Wait()
+	if w.checkpoints != nil {
+		w.checkpoint(context.WithoutCancel(ctx))
+	}
+	return nil
+}
+
+// run handles watcher events until ctx is done
+func (w *fileWatcher) run(ctx context.Context) {
+	rescan := time.NewTicker(fileRescanInterval)
+	defer rescan.Stop()
+	var checkpoint <-chan time.Time
+	if w.checkpoints != nil {
+		ticker := time.NewTicker(w.checkpointInterval)
+		defer ticker.Stop()
+		checkpoint = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rescan.C:
+			w.scan(ctx, true)
+		case <-checkpoint:
+			w.checkpoint(ctx)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logError("Error watching --file directories: %v\n", err)
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(ctx, event)
+		}
+	}
+}
+
+func (w *fileWatcher) handle(ctx context.Context, event fsnotify.Event) {
+	w.mu.Lock()
+	tail := w.tails[event.Name]
+	w.mu.Unlock()
+	if tail != nil {
+		// Writes, and the rotation or removal the tail notices on waking
+		select {
+		case tail.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if !event.Has(fsnotify.Create) {
+		return
+	}
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		w.scan(ctx, true)
+		return
+	}
+	if w.matches(event.Name) {
+		w.follow(ctx, event.Name, true)
+	}
+}
+
+func (w *fileWatcher) matches(path string) bool {
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scan watches the directories the patterns could match files in and
+// follows the matching files not followed yet, from the beginning if
+// fromStart is set and otherwise from their end
+func (w *fileWatcher) scan(ctx context.Context, fromStart bool) {
+	for _, pattern := range w.patterns {
+		dirs, _ := filepath.Glob(filepath.Dir(pattern))
+		for _, dir := range dirs {
+			if w.watched[dir] {
+				continue
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := w.watcher.Add(dir); err != nil {
+				logError("Failed to watch %s: %v\n", dir, err)
+				continue
+			}
+			w.watched[dir] = true
+		}
+
+		files, _ := filepath.Glob(pattern)
+		for _, path := range files {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				w.follow(ctx, path, fromStart)
+			}
+		}
+	}
+}
+
+// follow starts following path unless it already is
+func (w *fileWatcher) follow(ctx context.Context, path string, fromStart bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.tails[path] != nil {
+		return
+	}
+	tail := &fileTail{path: path, wake: make(chan struct{}, 1), poll: w.pollInterval}
+	w.tails[path] = tail
+	w.wg.Add(1)
+	go w.read(ctx, tail, fromStart)
+}
+
+// checkpoint saves how far each followed file's records go, once they are
+// flushed
+func (w *fileWatcher) checkpoint(ctx context.Context) {
+	// Taken before flushing, so the flush covers every record up to them
+	w.mu.Lock()
+	checkpoints := make(map[string]fileCheckpoint, len(w.tails))
+	for path, tail := range w.tails {
+		if checkpoint, ok := tail.checkpoint(); ok {
+			checkpoints[path] = checkpoint
+		}
+	}
+	for id, seen := range w.archives {
+		// Every rescan finds the archives still there
+		if time.Since(seen.at) > 3*fileRescanInterval {
+			delete(w.archives, id)
+		} else if _, ok := checkpoints[seen.path]; !ok {
+			checkpoints[seen.path] = id
+		}
+	}
+	w.mu.Unlock()
+
+	if err := w.processor.Flush(ctx); err != nil {
+		logError("Failed to flush logs, not saving checkpoints: %v\n", err)
+		return
+	}
+	if err := w.checkpoints.save(checkpoints); err != nil {
+		logError("Failed to save checkpoints: %v\n", err)
+	}
+}
+
+// read exports the records of a followed file until it is deleted or ctx
+// is done, tagging each with log.file.path. Compressed archives are read
+// once instead.
+func (w *fileWatcher) read(ctx context.Context, tail *fileTail, fromStart bool) {
+	defer w.wg.Done()
+	watching := ctx
+	defer func() {
+		// Kept on shutdown for the final checkpoint
+		if watching.Err() == nil {
+			w.mu.Lock()
+			delete(w.tails, tail.path)
+			w.mu.Unlock()
+		}
+	}()
+	defer reportPanic(ctx, w.processor, "file "+tail.path)
+
+	format, err := fileCompression(tail.path)
+	if err != nil {
+		logError("Failed to follow %s: %v\n", tail.path, err)
+		return
+	}
+	if format != "" {
+		w.readArchive(ctx, tail, format)
+		return
+	}
+
+	// ctx ends reading; what was read is still exported
+	reader, err := openTailReader(ctx, tail, fromStart)
+	if err != nil {
+		logError("Failed to follow %s: %v\n", tail.path, err)
+		return
+	}
+	defer reader.Close()
+	if checkpoint, ok := w.checkpoints.take(tail.path); ok {
+		reader.resume(checkpoint)
+	}
+	tail.commit(reader, 0)
+
+	w.export(context.WithoutCancel(ctx), tail, reader, func(pos int64) {
+		tail.commit(reader, pos)
+	})
+}
+
+// readArchive exports the records of a gzip or zstd compressed file from
+// its beginning to its end, once it is no longer being written. Archives
+// are told apart by their inode, size and first bytes, so one is read once
+// even when rotation renames it; with --checkpoint-dir, also across
+// restarts.
+func (w *fileWatcher) readArchive(ctx context.Context, tail *fileTail, format string) {
+	file, err := os.Open(tail.path)
+	if err != nil {
+		logError("Failed to read %s: %v\n", tail.path, err)
+		return
+	}
+	defer file.Close()
+	id, err := archiveIdentity(file)
+	if err != nil {
+		logError("Failed to read %s: %v\n", tail.path, err)
+		return
+	}
+	if w.seenArchive(id, tail.path) {
+		return
+	}
+	if !waitStable(ctx, file, tail.pollInterval()) {
+		return
+	}
+	if id, err = archiveIdentity(file); err != nil {
+		logError("Failed to read %s: %v\n", tail.path, err)
+		return
+	}
+
+	// ctx stops reading; the archive is then read again from the start
+	reader, err := decompressReader(format, &contextReader{ctx: ctx, r: file})
+	if err == nil {
+		w.export(context.WithoutCancel(ctx), tail, reader, nil)
+		reader.Close()
+	} else {
+		logError("Failed to read %s: %v\n", tail.path, err)
+	}
+	// Broken archives are not retried either
+	if ctx.Err() == nil {
+		w.mu.Lock()
+		w.archives[id] = archiveSighting{path: tail.path, at: time.Now()}
+		w.mu.Unlock()
+	}
+}
+
+// archiveSighting is where an archive read before was last found
+type archiveSighting struct {
+	path string
+	at   time.Time
+}
+
+// archiveIdentity identifies an archive by its inode, size and first bytes
+func archiveIdentity(file *os.File) (fileCheckpoint, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return fileCheckpoint{}, err
+	}
+	fingerprint, err := fileFingerprint(file, info.Size())
+	return fileCheckpoint{Inode: fileInode(info), Fingerprint: fingerprint, Offset: info.Size(), Complete: true}, err
+}
+
+// seenArchive reports whether the archive was read before, noting where it
+// is now
+func (w *fileWatcher) seenArchive(id fileCheckpoint, path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.archives[id]; !ok {
+		return false
+	}
+	w.archives[id] = archiveSighting{path: path, at: time.Now()}
+	return true
+}
+
+// export parses and exports the records read from r, tagged with the tail's
+// path, passing commit how far into r the records processed so far go. It
+// reports whether r was read to its end.
+func (w *fileWatcher) export(ctx context.Context, tail *fileTail, r io.Reader, commit func(pos int64)) bool {
+	if commit == nil {
+		commit = func(int64) {}
+	}
+	guard := newBinaryGuard(r, nil)
+	guard.expected = w.opts.binaryFraming
+	counter := &splitCounter{split: w.opts.split}
+	var readErr error
+	for logEntry, readAt := range multilineLogIteratorWith(guard, w.opts.continuationPattern, multilineOptions{split: counter.Split, bufferSize: w.opts.readBuffer, err: &readErr}) {
+		entry, err := w.extractor.ParseLogEntry(logEntry)
+		if err != nil {
+			logError("Error parsing log entry from %s: %v\n", tail.path, err)
+			continue
+		}
+		entry.File, entry.ReadAt = tail.path, readAt
+		w.processor.ProcessLogEntry(ctx, entry)
+		// The entry ends where the line that completed it starts
+		commit(guard.offset(counter.lineStart))
+	}
+
+	if entry := guard.Entry("", w.opts.binaryPolicy); entry != nil {
+		entry.File = tail.path
+		w.processor.ProcessLogEntry(ctx, entry)
+	}
+	if readErr != nil && !errors.Is(readErr, context.Canceled) {
+		entry := streamReadErrorEntry(tail.path, readErr)
+		entry.Stream, entry.File = "", tail.path
+		w.processor.ProcessLogEntry(ctx, entry)
+	}
+	commit(guard.offset(counter.consumed))
+	return readErr == nil
+}
+
+// fileCompression returns the compression format of the file at path, or ""
+// if it is not compressed
+func fileCompression(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	head := make([]byte, compressionMagicSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return detectCompression(head[:n]), nil
+}
+
+// waitStable waits until file stops changing, as an archive being
+// compressed into is only readable once complete
+func waitStable(ctx context.Context, file *os.File, poll time.Duration) bool {
+	var last os.FileInfo
+	for {
+		info, err := file.Stat()
+		if err != nil {
+			return false
+		}
+		if last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+			return true
+		}
+		last = info
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(poll):
+		}
+	}
+}
+
+// contextReader stops reading once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// tailReader reads a file as it grows, like tail -F. At the end of the file
+// it waits for more data, reopens the path once the file was rotated away
+// and the old one is drained, starts over when it was truncated, and ends
+// when the path is deleted or ctx is done.
+type tailReader struct {
+	ctx    context.Context
+	tail   *fileTail
+	file   *os.File
+	inode  uint64
+	offset int64
+	read   int64 // bytes returned across files
+	base   int64 // where in those bytes file starts
+}
+
+func openTailReader(ctx context.Context, tail *fileTail, fromStart bool) (*tailReader, error) {
+	file, err := os.Open(tail.path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r := &tailReader{ctx: ctx, tail: tail, file: file, inode: fileInode(info)}
+	if !fromStart {
+		if r.offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			return nil, err
+		}
+		r.base = -r.offset
+	}
+	return r, nil
+}
+
+// resume continues from a checkpoint, before anything is read, if it is for
+// the file now at the path
+func (r *tailReader) resume(checkpoint fileCheckpoint) bool {
+	info, err := r.file.Stat()
+	if err != nil || fileInode(info) != checkpoint.Inode || info.Size() < checkpoint.Offset {
+		return false
+	}
+	if fingerprint, err := fileFingerprint(r.file, checkpoint.Offset); err != nil || fingerprint != checkpoint.Fingerprint {
+		return false
+	}
+	if _, err := r.file.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return false
+	}
+	r.offset, r.base = checkpoint.Offset, -checkpoint.Offset
+	return true
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		r.offset += int64(n)
+		r.read += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		reopened, err := r.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if reopened {
+			continue
+		}
+		select {
+		case <-r.ctx.Done():
+			return 0, io.EOF
+		case <-r.tail.wake:
+		case <-time.After(r.tail.pollInterval()):
+		}
+	}
+}
+
+// reopen checks, at the end of the file, whether the path now names another
+// file or the file shrank, and if so reads that from the beginning. A
+// deleted path ends the reader.
+func (r *tailReader) reopen() (bool, error) {
+	info, err := os.Stat(r.tail.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, io.EOF
+	}
+	if err != nil {
+		return false, err
+	}
+	current, err := r.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(info, current) {
+		file, err := os.Open(r.tail.path)
+		if err != nil {
+			// Rotated away but not recreated yet
+			return false, nil
+		}
+		r.file.Close()
+		r.file, r.inode, r.offset, r.base = file, fileInode(info), 0, r.read
+		return true, nil
+	}
+	if current.Size() < r.offset {
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		r.offset, r.base = 0, r.read
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *tailReader) Close() error {
+	return r.file.Close()
+}
+
+// fileWatchRoots are the directories the --file patterns can match files
+// beneath: each pattern's directory up to its first wildcard
+func fileWatchRoots(patterns []string) []string {
+	var roots []string
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		for globMeta.MatchString(dir) {
+			dir = filepath.Dir(dir)
+		}
+		roots = append(roots, dir)
+	}
+	return roots
+}
+
+// globMeta matches the characters filepath.Match treats specially
+var globMeta = regexp.MustCompile(`[*?\[\\]`)
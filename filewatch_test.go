@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tail := &fileTail{path: path, wake: make(chan struct{}, 1)}
+	reader, err := openTailReader(ctx, tail, false)
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	defer reader.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got, ok := <-lines:
+			if !ok || got != want {
+				t.Fatalf("Expected %q, got %q (open: %v)", want, got, ok)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+
+	appendFile(t, path, "appended\n")
+	tail.wake <- struct{}{}
+	expect("appended")
+
+	// Truncated in place, as by copytruncate, and noticed before the file
+	// grows past where the reader was
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	tail.wake <- struct{}{}
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, path, "after truncate\n")
+	expect("after truncate")
+
+	// Rotated by renaming and creating a new file
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "after rotate\n")
+	tail.wake <- struct{}{}
+	expect("after rotate")
+
+	// Deleted
+	os.Remove(path)
+	tail.wake <- struct{}{}
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Error("Expected no more lines after the file was deleted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the reader to end when the file was deleted")
+	}
+}
+
+func TestProcessFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.log")
+	appendFile(t, existing, `{"msg":"before start"}`+"\n")
+
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		Files:               []string{filepath.Join(dir, "*.log")},
+	}
+	provider, exporter := newRecordingProvider()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- processFiles(ctx, config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test")))
+	}()
+
+	created := filepath.Join(dir, "created.log")
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(exporter.Records()) < 2 {
+		// Written until picked up, as the watcher may not be set up yet
+		appendFile(t, existing, `{"msg":"appended"}`+"\n")
+		appendFile(t, created, `{"msg":"created"}`+"\n")
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, record := range exporter.Records() {
+		body := record.Body().AsString()
+		if body == "before start" {
+			t.Error("Expected lines written before startup to be skipped")
+		}
+		files[body] = recordAttributes(record)["log.file.path"]
+	}
+	if files["appended"] != existing || files["created"] != created {
+		t.Errorf("Expected records tagged with their file, got %v", files)
+	}
+}
+
+func TestFileWatchRoots(t *testing.T) {
+	roots := fileWatchRoots([]string{"/var/log/containers/*.log", "/var/log/pods/*/*/*.log", "app.log"})
+	expected := []string{"/var/log/containers", "/var/log/pods", "."}
+	if !reflect.DeepEqual(roots, expected) {
+		t.Errorf("Expected %v, got %v", expected, roots)
+	}
+	if err := validFilePatterns([]string{"/var/log/[.log"}); err == nil {
+		t.Error("Expected error for an invalid pattern")
+	}
+	if !slices.Contains(sandboxPathsFor(&Config{Files: []string{"/var/log/pods/*/*/*.log"}}).read, "/var/log/pods") {
+		t.Error("Expected the watched directories to be readable in the sandbox")
+	}
+}
+
+func writeArchive(t *testing.T, path, format, data string) {
+	t.Helper()
+	var buf bytes.Buffer
+	switch format {
+	case compressionGzip:
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(data))
+		w.Close()
+	case compressionZstd:
+		w, _ := zstd.NewWriter(&buf)
+		w.Write([]byte(data))
+		w.Close()
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessFilesArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, filepath.Join(dir, "app.log.1.gz"), compressionGzip, `{"msg":"gzip"}`+"\n")
+	writeArchive(t, filepath.Join(dir, "app.log.2.zst"), compressionZstd, `{"msg":"zstd one"}`+"\n"+`{"msg":"zstd two"}`+"\n")
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		Files:               []string{filepath.Join(dir, "app.log.*")},
+		CheckpointDir:       filepath.Join(dir, "state"),
+		CheckpointInterval:  time.Hour,
+	}
+
+	// Archives are read once their size settles, after a poll interval
+	run := func(during func()) []string {
+		provider, exporter := newRecordingProvider()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- processFiles(ctx, config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test")))
+		}()
+		time.Sleep(filePollInterval + 500*time.Millisecond)
+		during()
+		time.Sleep(filePollInterval + 500*time.Millisecond)
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var bodies []string
+		for _, record := range exporter.Records() {
+			bodies = append(bodies, record.Body().AsString())
+		}
+		slices.Sort(bodies)
+		return bodies
+	}
+
+	bodies := run(func() {
+		// Renamed by rotation, which must not read it again
+		os.Rename(filepath.Join(dir, "app.log.1.gz"), filepath.Join(dir, "app.log.3.gz"))
+	})
+	if !slices.Equal(bodies, []string{"gzip", "zstd one", "zstd two"}) {
+		t.Errorf("Expected each archive to be read once, got %v", bodies)
+	}
+
+	// Nor does a restart
+	if bodies := run(func() {}); len(bodies) != 0 {
+		t.Errorf("Expected archives read before to be skipped, got %v", bodies)
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// forwardMaxMessageSize bounds a Forward protocol message, which carries a
+// whole chunk of records; Fluentd's default chunk limit is 8MiB
+const forwardMaxMessageSize = 64 << 20
+
+// forwardTagKey is the attribute with the Fluentd tag a record was sent with
+const forwardTagKey = "fluent.tag"
+
+// forwardServer receives records from Fluentd and Fluent Bit agents over the
+// Forward protocol, MessagePack over TCP, so their aggregators can be
+// replaced by otel-logger
+type forwardServer struct {
+	extractor *JSONExtractor
+	processor *LogProcessor
+
+	listeners []net.Listener
+	wg        sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// listenForward opens the --listen-forward addresses, tcp://HOST:PORT
+func listenForward(config *Config, extractor *JSONExtractor, processor *LogProcessor) (*forwardServer, error) {
+	s := &forwardServer{extractor: extractor, processor: processor, conns: make(map[net.Conn]bool)}
+	for _, address := range config.ListenForward {
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			s.Close()
+			return nil, fmt.Errorf("invalid --listen-forward %q: expected tcp://HOST:PORT", address)
+		}
+		if u.Scheme != "tcp" {
+			s.Close()
+			return nil, fmt.Errorf("invalid --listen-forward %q: unsupported scheme %q (supported: tcp)", address, u.Scheme)
+		}
+		listener, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to listen on --listen-forward %s: %w", address, err)
+		}
+		s.listeners = append(s.listeners, listener)
+	}
+	return s, nil
+}
+
+// serve receives records until ctx is done, then waits for the connections
+// to be closed and the records read so far to be processed
+func (s *forwardServer) serve(ctx context.Context) {
+	for _, listener := range s.listeners {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.accept(ctx, listener)
+		}()
+	}
+	<-ctx.Done()
+	s.Close()
+	s.wg.Wait()
+}
+
+// Close stops receiving records
+func (s *forwardServer) Close() {
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *forwardServer) accept(ctx context.Context, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logError("Forward listener failed: %v\n", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.read(ctx, conn)
+		}()
+	}
+}
+
+// read handles a connection, a stream of MessagePack arrays. A message that
+// cannot be decoded ends it, as the stream cannot be resynchronized.
+func (s *forwardServer) read(ctx context.Context, conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	var message any
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), forwardMaxMessageSize)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		r := &recordReader{data: data}
+		value, err := decodeMsgpack(r)
+		if errors.Is(err, errShortRecord) {
+			if atEOF {
+				return 0, nil, errors.New("truncated Forward message")
+			}
+			return 0, nil, nil
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid Forward message: %w", err)
+		}
+		message = value
+		return r.pos, data[:r.pos], nil
+	})
+	for scanner.Scan() {
+		readAt := time.Now()
+		chunk, err := s.export(ctx, message, conn.RemoteAddr(), readAt)
+		if err != nil {
+			logError("Invalid Forward message from %s: %v\n", conn.RemoteAddr(), err)
+			return
+		}
+		if chunk != "" {
+			// The agent resends the chunk until it is acknowledged, so it is
+			// only acknowledged once its records are exported
+			if err := s.processor.Barrier(ctx); err != nil {
+				logError("Not acknowledging Forward chunk from %s, export failed: %v\n", conn.RemoteAddr(), err)
+				continue
+			}
+			if _, err := conn.Write(forwardAck(chunk)); err != nil {
+				logError("Failed to acknowledge Forward chunk to %s: %v\n", conn.RemoteAddr(), err)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logError("Error reading Forward messages from %s: %v\n", conn.RemoteAddr(), err)
+	}
+}
+
+// export hands the records of a message to the processor and returns the
+// chunk ID the agent wants acknowledged, if any. Messages come in three
+// modes: [tag, time, record] for one record, [tag, [[time, record], ...]]
+// and [tag, packed entries], the entries' MessagePack concatenated and
+// optionally gzipped. Each may end with a map of options.
+func (s *forwardServer) export(ctx context.Context, message any, addr net.Addr, readAt time.Time) (chunk string, err error) {
+	array, ok := message.([]any)
+	if !ok || len(array) < 2 {
+		return "", errors.New("expected an array of a tag and entries")
+	}
+	tag, ok := array[0].(string)
+	if !ok {
+		return "", errors.New("expected a tag")
+	}
+
+	var events []any
+	var packed []byte
+	optionsAt := 2
+	switch entries := array[1].(type) {
+	case int64, uint64, float64, time.Time:
+		// Message mode, told apart by its time as the other modes can end
+		// with a map too
+		if len(array) < 3 {
+			return "", errors.New("expected a time and record")
+		}
+		events = []any{[]any{array[1], array[2]}}
+		optionsAt = 3
+	case []any:
+		// Forward mode
+		events = entries
+	case []byte:
+		// PackedForward and CompressedPackedForward modes
+		packed = entries
+	case string:
+		// Older agents send packed entries as str rather than bin
+		packed = []byte(entries)
+	default:
+		return "", fmt.Errorf("expected a time or entries, got %T", entries)
+	}
+	var options orderedMap
+	if len(array) > optionsAt {
+		options, _ = array[optionsAt].(orderedMap)
+	}
+	if packed != nil {
+		if events, err = unpackForwardEntries(packed, forwardOption(options, "compressed")); err != nil {
+			return "", err
+		}
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	batch := make([]*LogEntry, 0, len(events))
+	for _, event := range events {
+		entry, err := s.entry(tag, event)
+		if err != nil {
+			logError("Skipping Forward record tagged %s from %s: %v\n", tag, addr, err)
+			continue
+		}
+		entry.Fields[syslogClientAddressKey] = host
+		entry.Fields[syslogTransportKey] = "tcp"
+		if entry.FieldOrder != nil {
+			entry.FieldOrder = append(entry.FieldOrder, forwardTagKey, syslogClientAddressKey, syslogTransportKey)
+		}
+		entry.ReadAt = readAt
+		batch = append(batch, entry)
+	}
+	s.processor.ProcessLogEntries(ctx, batch)
+	return forwardOption(options, "chunk"), nil
+}
+
+// entry parses a [time, record] pair. The record is parsed like a line of
+// JSON, with a "log" field, as Fluent Bit's tail input and Docker's fluentd
+// driver send, as the message if it has none of the message fields. The
+// event time is the record's timestamp.
+func (s *forwardServer) entry(tag string, event any) (*LogEntry, error) {
+	pair, ok := event.([]any)
+	if !ok || len(pair) < 2 {
+		return nil, errors.New("expected a time and record")
+	}
+	stamp, err := forwardTime(pair[0])
+	if err != nil {
+		return nil, err
+	}
+	record, ok := pair[1].(orderedMap)
+	if !ok {
+		return nil, errors.New("expected the record to be a map")
+	}
+
+	_, fieldMappings := s.extractor.snapshot()
+	if logField := forwardMessageField(record, fieldMappings.MessageFields); logField >= 0 {
+		record = append(orderedMap(nil), record...)
+		record[logField].key = fieldMappings.MessageFields[0]
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.extractor.ParseLogEntryAs(string(line), formatJSON)
+	if err != nil {
+		return nil, err
+	}
+	entry.Timestamp, entry.TimestampSource = stamp, timestampSourceRecord
+	entry.Fields[forwardTagKey] = tag
+	return entry, nil
+}
+
+// forwardMessageField returns the index of the "log" field of a record that
+// has none of the message fields, or -1
+func forwardMessageField(record orderedMap, messageFields []string) int {
+	if len(messageFields) == 0 {
+		return -1
+	}
+	logField := -1
+	for i, field := range record {
+		for _, name := range messageFields {
+			if field.key == name {
+				return -1
+			}
+		}
+		if _, ok := field.value.(string); ok && field.key == "log" {
+			logField = i
+		}
+	}
+	return logField
+}
+
+// forwardTime decodes an event time: seconds since the epoch, or Fluentd's
+// EventTime, which decodeMsgpack returns as a time
+func forwardTime(value any) (time.Time, error) {
+	switch t := value.(type) {
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case time.Time:
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid event time %v", value)
+}
+
+// unpackForwardEntries decodes the concatenated entries of PackedForward
+// mode, gunzipping them first if compressed is "gzip"
+func unpackForwardEntries(packed []byte, compressed string) ([]any, error) {
+	switch compressed {
+	case "":
+	case "gzip":
+		reader, err := gzip.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return nil, fmt.Errorf("invalid compressed entries: %w", err)
+		}
+		if packed, err = io.ReadAll(io.LimitReader(reader, forwardMaxMessageSize+1)); err != nil {
+			return nil, fmt.Errorf("invalid compressed entries: %w", err)
+		}
+		if len(packed) > forwardMaxMessageSize {
+			return nil, fmt.Errorf("compressed entries of more than %d bytes", forwardMaxMessageSize)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compressed)
+	}
+
+	var events []any
+	r := &recordReader{data: packed}
+	for r.pos < len(packed) {
+		event, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid packed entries: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// forwardOption is a string option of a message, or empty
+func forwardOption(options orderedMap, name string) string {
+	for _, option := range options {
+		if option.key != name {
+			continue
+		}
+		switch value := option.value.(type) {
+		case string:
+			return value
+		case []byte:
+			return string(value)
+		}
+	}
+	return ""
+}
+
+// forwardAck encodes the response acknowledging a chunk, {"ack": chunk}
+func forwardAck(chunk string) []byte {
+	out := []byte{0x81, 0xa3, 'a', 'c', 'k'}
+	switch n := len(chunk); {
+	case n < 32:
+		out = append(out, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		out = append(out, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		out = binary.BigEndian.AppendUint16(append(out, 0xda), uint16(n))
+	default:
+		out = binary.BigEndian.AppendUint32(append(out, 0xdb), uint32(n))
+	}
+	return append(out, chunk...)
+}
+
+// processForward receives Forward protocol records until interrupted
+func processForward(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	server, err := listenForward(config, extractor, processor)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := enterSandbox(config); err != nil {
+		server.Close()
+		return err
+	}
+	server.serve(ctx)
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// forwardEventTime is Fluentd's EventTime, encoded as extension type 0
+type forwardEventTime time.Time
+
+// encodeMsgpack encodes the values Forward messages are made of; maps are
+// orderedMaps so their keys keep the order written
+func encodeMsgpack(out *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case string:
+		out.WriteByte(0xd9)
+		out.WriteByte(byte(len(v)))
+		out.WriteString(v)
+	case int:
+		out.WriteByte(0xd3)
+		binary.Write(out, binary.BigEndian, int64(v))
+	case []byte:
+		out.WriteByte(0xc6)
+		binary.Write(out, binary.BigEndian, uint32(len(v)))
+		out.Write(v)
+	case forwardEventTime:
+		t := time.Time(v)
+		out.Write([]byte{0xd7, 0x00})
+		binary.Write(out, binary.BigEndian, uint32(t.Unix()))
+		binary.Write(out, binary.BigEndian, uint32(t.Nanosecond()))
+	case []any:
+		out.WriteByte(0xdc)
+		binary.Write(out, binary.BigEndian, uint16(len(v)))
+		for _, item := range v {
+			encodeMsgpack(out, item)
+		}
+	case orderedMap:
+		out.WriteByte(0xde)
+		binary.Write(out, binary.BigEndian, uint16(len(v)))
+		for _, field := range v {
+			encodeMsgpack(out, field.key)
+			encodeMsgpack(out, field.value)
+		}
+	default:
+		panic(value)
+	}
+}
+
+func msgpackBytes(values ...any) []byte {
+	var out bytes.Buffer
+	for _, value := range values {
+		encodeMsgpack(&out, value)
+	}
+	return out.Bytes()
+}
+
+func TestForwardServer(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	var exportedAtFlush atomic.Int64
+	processor.SetFlusher(func(ctx context.Context) error {
+		exportedAtFlush.Store(int64(len(exporter.Records())))
+		return nil
+	})
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	server, err := listenForward(&Config{ListenForward: []string{"tcp://127.0.0.1:0"}}, extractor, processor)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.serve(ctx)
+		close(done)
+	}()
+
+	stamp := time.Date(2024, 1, 15, 10, 30, 45, 123456789, time.UTC)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(msgpackBytes([]any{forwardEventTime(stamp), orderedMap{{"message", "gzipped"}}}))
+	gz.Close()
+
+	conn, err := net.Dial("tcp", server.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(msgpackBytes(
+		// Message mode
+		[]any{"app.web", 1705314645, orderedMap{{"level", "warn"}, {"message", "message mode"}}},
+		// Message mode with an EventTime, as Fluent Bit sends it
+		[]any{"app.bit", forwardEventTime(stamp), orderedMap{{"log", "from fluent bit"}}},
+		// Forward mode
+		[]any{"app.web", []any{
+			[]any{forwardEventTime(stamp), orderedMap{{"log", "from tail"}, {"path", "/var/log/app.log"}}},
+			[]any{forwardEventTime(stamp), "not a record"},
+		}},
+		// PackedForward mode, acknowledged
+		[]any{"app.db", msgpackBytes(
+			[]any{forwardEventTime(stamp), orderedMap{{"msg", "packed 1"}}},
+			[]any{forwardEventTime(stamp), orderedMap{{"msg", "packed 2"}, {"log", "stays"}}},
+		), orderedMap{{"chunk", "Y2h1bmsx"}, {"size", 2}}},
+		// CompressedPackedForward mode
+		[]any{"app.gz", gzipped.Bytes(), orderedMap{{"compressed", "gzip"}}},
+	))
+
+	ack := make([]byte, 14)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, ack); err != nil || !bytes.Equal(ack, forwardAck("Y2h1bmsx")) {
+		t.Errorf("Expected the chunk to be acknowledged, got %q (%v)", ack, err)
+	}
+	if exported := exportedAtFlush.Load(); exported != 5 {
+		t.Errorf("Expected the chunk to be acknowledged after exporting the 5 records up to it, got %d", exported)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exporter.Records()) < 6 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	type got struct {
+		body, level, tag, path, log string
+		time                        time.Time
+	}
+	var records []got
+	for _, record := range exporter.Records() {
+		attrs := recordAttributes(record)
+		if attrs["client.address"] != "127.0.0.1" || attrs["network.transport"] != "tcp" {
+			t.Errorf("Expected where the record came from, got %v", attrs)
+		}
+		records = append(records, got{record.Body().AsString(), record.SeverityText(), attrs[forwardTagKey], attrs["path"], attrs["log"], record.Timestamp().UTC()})
+	}
+	expected := []got{
+		{"message mode", "warn", "app.web", "", "", time.Unix(1705314645, 0).UTC()},
+		{"from fluent bit", "info", "app.bit", "", "", stamp},
+		{"from tail", "info", "app.web", "/var/log/app.log", "", stamp},
+		{"packed 1", "info", "app.db", "", "", stamp},
+		{"packed 2", "info", "app.db", "", "stays", stamp},
+		{"gzipped", "info", "app.gz", "", "", stamp},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, records)
+	}
+}
+
+func TestForwardAckAfterFailedExport(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetFlusher(func(ctx context.Context) error { return errors.New("collector unavailable") })
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	server, err := listenForward(&Config{ListenForward: []string{"tcp://127.0.0.1:0"}}, extractor, processor)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.serve(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	conn, err := net.Dial("tcp", server.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(msgpackBytes([]any{"app", msgpackBytes(
+		[]any{1705314645, orderedMap{{"msg", "unexported"}}},
+	), orderedMap{{"chunk", "Y2h1bmsx"}}}))
+
+	// The agent is left to resend the chunk
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if n, err := conn.Read(make([]byte, 16)); n > 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected no acknowledgement when the export failed, got %d bytes (%v)", n, err)
+	}
+	if len(exporter.Records()) != 1 {
+		t.Errorf("Expected the record to be processed, got %d", len(exporter.Records()))
+	}
+}
+
+func TestForwardAck(t *testing.T) {
+	for _, size := range []int{8, 40, 300} {
+		chunk := string(bytes.Repeat([]byte("c"), size))
+		value, err := decodeMsgpack(&recordReader{data: forwardAck(chunk)})
+		if err != nil || !reflect.DeepEqual(value, orderedMap{{"ack", chunk}}) {
+			t.Errorf("Expected {ack: %d bytes}, got %v (%v)", size, value, err)
+		}
+	}
+}
+
+func TestListenForwardErrors(t *testing.T) {
+	for _, config := range []*Config{
+		{ListenForward: []string{"127.0.0.1:24224"}},
+		{ListenForward: []string{"udp://127.0.0.1:0"}},
+		{ListenForward: []string{"tcp://127.0.0.1:0/path"}},
+	} {
+		if server, err := listenForward(config, nil, nil); err == nil {
+			server.Close()
+			t.Errorf("Expected error for %v", config.ListenForward)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Input formats selected by --format
+const (
+	formatJSON      = "json"
+	formatLogfmt    = "logfmt"
+	formatSyslog    = "syslog"
+	formatCLF       = "clf"
+	formatKV        = "kv"            // free text with a key=value tail; not detected by auto
+	formatPostgres  = "postgres"      // PostgreSQL stderr logs; not detected by auto
+	formatMySQLSlow = "mysql-slow"    // MySQL/MariaDB slow query logs; not detected by auto
+	formatJVM       = "jvm"           // JVM unified logging (-Xlog); not detected by auto
+	formatDjango    = "django-server" // Django runserver and request logging; not detected by auto
+	formatEnvoy     = "envoy"         // Envoy and Istio access logs; not detected by auto
+	formatAuto      = "auto"
+)
+
+// detectableFormats are the formats --format auto chooses from, in the order
+// they are tried before the sample settles on one
+var detectableFormats = []string{formatJSON, formatSyslog, formatCLF, formatLogfmt}
+
+// detectSampleLines is how many structured lines --format auto looks at
+// before it settles on a format
+const detectSampleLines = 20
+
+// isParserFormat reports whether format names a way of decoding lines rather
+// than a preset
+func isParserFormat(format string) bool {
+	return format == "" || format == formatAuto || format == formatKV || format == formatPostgres || format == formatMySQLSlow || format == formatJVM || format == formatDjango || format == formatEnvoy || slices.Contains(detectableFormats, format)
+}
+
+// validFormat accepts the parser formats and the names of the built-in
+// presets, such as bunyan or zap, which decode JSON with their field mappings
+func validFormat(format string) error {
+	if isParserFormat(format) {
+		return nil
+	}
+	if _, err := readPreset(format); err == nil {
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (supported: %s, %s, %s, %s, %s, %s, %s, %s, or a preset from `otel-logger presets list`)", format,
+		strings.Join(detectableFormats, ", "), formatKV, formatPostgres, formatMySQLSlow, formatJVM, formatDjango, formatEnvoy, formatAuto)
+}
+
+// formatDetector picks the format for --format auto. Each of the first
+// detectSampleLines structured lines votes for the format that read it; the
+// leading format is tried first, and once the sample is in it stays first.
+// Every line still falls back to the other formats and then to a plain
+// message, so a stray line in another format is not lost.
+type formatDetector struct {
+	mu      sync.Mutex
+	votes   map[string]int
+	sampled int
+	leader  string
+}
+
+// order returns the formats in the order to try them
+func (d *formatDetector) order() []string {
+	d.mu.Lock()
+	leader := d.leader
+	d.mu.Unlock()
+
+	if leader == "" || leader == detectableFormats[0] {
+		return detectableFormats
+	}
+	order := []string{leader}
+	for _, format := range detectableFormats {
+		if format != leader {
+			order = append(order, format)
+		}
+	}
+	return order
+}
+
+// vote counts a line read as format while the sample is being taken
+func (d *formatDetector) vote(format string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sampled >= detectSampleLines {
+		return
+	}
+	if d.votes == nil {
+		d.votes = make(map[string]int)
+	}
+	d.votes[format]++
+	d.sampled++
+	if d.leader == "" || d.votes[format] > d.votes[d.leader] {
+		d.leader = format
+	}
+}
+
+// parseDetected reads the line with the first format that accepts it
+func (je *JSONExtractor) parseDetected(line string) *LogEntry {
+	for _, format := range je.detector.order() {
+		var entry *LogEntry
+		var ok bool
+		switch format {
+		case formatJSON:
+			entry, ok = je.parseJSON(line)
+		case formatSyslog:
+			entry, ok = je.parseSyslog(line)
+		case formatCLF:
+			entry, ok = je.parseCLF(line)
+		case formatLogfmt:
+			entry, ok = je.parseLogfmt(line)
+		}
+		if ok {
+			je.detector.vote(format)
+			return entry
+		}
+	}
+	return je.plainEntry(line)
+}
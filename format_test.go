@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseLogEntryAutoFormat(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatAuto
+	extractor := NewJSONExtractor("", mappings)
+
+	tests := []struct {
+		line            string
+		expectedMessage string
+		expectedField   string
+	}{
+		{`{"level":"warn","msg":"json line","user":"a"}`, "json line", "user"},
+		{`level=warn msg="logfmt line" user=a`, "logfmt line", "user"},
+		{`<13>1 2025-01-02T15:04:05Z host app - - - syslog line`, "syslog line", "syslog.appname"},
+		{`Jan  2 15:04:05 host sshd[1]: bsd syslog line`, "bsd syslog line", "host.name"},
+		{`127.0.0.1 - - [02/Jan/2025:15:04:05 +0000] "GET / HTTP/1.1" 200 5`, "GET / HTTP/1.1", "http.response.status_code"},
+		{`2025-01-02T15:04:05Z INFO plain text line`, "2025-01-02T15:04:05Z INFO plain text line", ""},
+	}
+	for _, tt := range tests {
+		entry, err := extractor.ParseLogEntry(tt.line)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if entry.Message != tt.expectedMessage {
+			t.Errorf("%s: expected message %q, got %q", tt.line, tt.expectedMessage, entry.Message)
+		}
+		if _, ok := entry.Fields[tt.expectedField]; tt.expectedField != "" && !ok {
+			t.Errorf("%s: expected field %s, got %v", tt.line, tt.expectedField, entry.Fields)
+		}
+		if tt.expectedField == "" && len(entry.Fields) != 0 {
+			t.Errorf("%s: expected a plain message, got %v", tt.line, entry.Fields)
+		}
+	}
+}
+
+func TestFormatDetectorSettles(t *testing.T) {
+	var detector formatDetector
+	if got := detector.order(); !reflect.DeepEqual(got, detectableFormats) {
+		t.Errorf("Expected default order before any vote, got %v", got)
+	}
+
+	for i := range detectSampleLines {
+		if i%4 == 0 {
+			detector.vote(formatJSON)
+		} else {
+			detector.vote(formatLogfmt)
+		}
+	}
+	expected := []string{formatLogfmt, formatJSON, formatSyslog, formatCLF}
+	if got := detector.order(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected logfmt first, got %v", got)
+	}
+
+	// Votes after the sample do not change the order
+	for range 2 * detectSampleLines {
+		detector.vote(formatJSON)
+	}
+	if got := detector.order(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected order to stay settled, got %v", got)
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	for _, format := range []string{"", "json", "logfmt", "syslog", "clf", "kv", "postgres", "mysql-slow", "jvm", "django-server", "envoy", "auto", "bunyan", "jvm-gc", "lograge", "django", "istio", "zap"} {
+		if err := validFormat(format); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", format, err)
+		}
+	}
+	err := validFormat("xml")
+	if expected := "unknown format \"xml\" (supported: json, syslog, clf, logfmt, kv, postgres, mysql-slow, jvm, django-server, envoy, auto, or a preset from `otel-logger presets list`)"; fmt.Sprint(err) != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Input framings: how the input is cut into records before multiline grouping
+const (
+	framingLines         = "lines"
+	framingJSONSeq       = "json-seq"       // RFC 7464 JSON text sequences
+	framingJournalExport = "journal-export" // journalctl -o export
+	framingMsgpack       = "msgpack"        // concatenated MessagePack maps
+	framingCBOR          = "cbor"           // concatenated CBOR maps
+	framingProtobuf      = "protobuf"       // varint length-prefixed messages
+	framingAvro          = "avro"           // Avro object container files
+	framingAvroConfluent = "avro-confluent" // Avro in the Confluent wire format
+	framingCRI           = "cri"            // kubelet container logs
+	framingDockerJSON    = "docker-json"    // Docker's json-file container logs
+)
+
+// jsonSeqRS starts every text of a JSON text sequence
+const jsonSeqRS = 0x1E
+
+// splitFunc returns the scanner split function for the configured framing
+// and, for line framing, carriage-return handling
+func splitFunc(config *Config) (bufio.SplitFunc, error) {
+	switch config.Framing {
+	case "", framingLines:
+		return lineSplitFunc(config.CarriageReturn)
+	case framingJSONSeq:
+		return scanJSONSeq, nil
+	case framingJournalExport:
+		return scanJournalExport, nil
+	case framingMsgpack:
+		return scanRecords("MessagePack", decodeMsgpack), nil
+	case framingCBOR:
+		return scanRecords("CBOR", decodeCBOR), nil
+	case framingProtobuf:
+		message, err := loadProtoMessage(config.ProtoDescriptor, config.ProtoMessage)
+		if err != nil {
+			return nil, err
+		}
+		return scanRecords("protobuf", decodeProtobuf(message)), nil
+	case framingAvro:
+		return scanAvroContainer(), nil
+	case framingCRI, framingDockerJSON:
+		readBuffer, err := readBufferSize(config)
+		if err != nil {
+			return nil, err
+		}
+		maxLine := readBuffer
+		if maxLine < bufio.MaxScanTokenSize {
+			maxLine = bufio.MaxScanTokenSize
+		}
+		if config.Framing == framingDockerJSON {
+			return scanDockerJSON(maxLine), nil
+		}
+		return scanCRI(maxLine), nil
+	case framingAvroConfluent:
+		registry, err := newSchemaRegistry(config.SchemaRegistry, config.Timeout, outboundTLS(config))
+		if err != nil {
+			return nil, err
+		}
+		return scanRecords("Avro", decodeConfluentAvro(registry)), nil
+	default:
+		return nil, fmt.Errorf("invalid --framing %q (supported: %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)", config.Framing,
+			framingLines, framingJSONSeq, framingJournalExport, framingMsgpack, framingCBOR, framingProtobuf, framingAvro, framingAvroConfluent, framingCRI,
+			framingDockerJSON)
+	}
+}
+
+// scanJSONSeq splits RFC 7464 JSON text sequences, in which each text starts
+// with a record separator (0x1E) and usually ends with a newline. Texts are
+// compacted onto one line, so pretty-printed ones are not cut up by
+// multiline grouping; texts that are not valid JSON, such as ones truncated
+// by a crashed writer, are passed on as they are.
+func scanJSONSeq(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := bytes.IndexByte(data, jsonSeqRS)
+	if start < 0 {
+		// Data before the first separator is not part of any text
+		if atEOF {
+			return len(data), compactJSONText(data), nil
+		}
+		return 0, nil, nil
+	}
+	if start > 0 {
+		return start, compactJSONText(data[:start]), nil
+	}
+	if end := bytes.IndexByte(data[1:], jsonSeqRS); end >= 0 {
+		return end + 1, compactJSONText(data[1 : end+1]), nil
+	}
+	if atEOF {
+		return len(data), compactJSONText(data[1:]), nil
+	}
+	return 0, nil, nil
+}
+
+// compactJSONText trims a text of a sequence and removes the insignificant
+// whitespace of valid JSON
+func compactJSONText(text []byte) []byte {
+	text = bytes.TrimSpace(text)
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, text); err != nil {
+		return text
+	}
+	return compacted.Bytes()
+}
+
+// Journal fields that become the timestamp, level and message of a record
+// read with --framing journal-export
+const (
+	journalRealtimeField = "__REALTIME_TIMESTAMP" // microseconds since the epoch
+	journalPriorityField = "PRIORITY"
+	journalFacilityField = "SYSLOG_FACILITY"
+	journalMessageField  = "MESSAGE"
+)
+
+// journalField is one field of a journal export record
+type journalField struct {
+	name  string
+	value []byte
+}
+
+// scanJournalExport splits the journal export format written by
+// journalctl -o export into records, each of which becomes a JSON object so
+// that it is parsed like any other JSON log. Records are runs of "NAME=value"
+// lines ended by an empty line; fields that are binary or contain newlines
+// are written as the name, a newline, the length as a little-endian 64-bit
+// integer and the raw value.
+func scanJournalExport(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	var fields []journalField
+	i := 0
+	for {
+		end := bytes.IndexByte(data[i:], '\n')
+		if end < 0 {
+			break
+		}
+		line := data[i : i+end]
+		if len(line) == 0 {
+			if len(fields) == 0 {
+				// Blank lines between records
+				i += end + 1
+				continue
+			}
+			return i + end + 1, journalRecordJSON(fields), nil
+		}
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields = append(fields, journalField{string(name), value})
+			i += end + 1
+			continue
+		}
+
+		// Binary field: the length and value follow the name
+		valueStart := i + end + 1 + 8
+		if len(data) < valueStart {
+			break
+		}
+		size := binary.LittleEndian.Uint64(data[i+end+1 : valueStart])
+		if size > uint64(len(data)-valueStart) {
+			break
+		}
+		valueEnd := valueStart + int(size)
+		fields = append(fields, journalField{string(line), data[valueStart:valueEnd]})
+		i = valueEnd
+		if i < len(data) && data[i] == '\n' {
+			i++
+		}
+	}
+
+	if !atEOF {
+		return 0, nil, nil
+	}
+	if len(fields) == 0 {
+		// Blank lines or a truncated field at the end of the input
+		return len(data), bytes.TrimSpace(data[i:]), nil
+	}
+	return len(data), journalRecordJSON(fields), nil
+}
+
+// journalRecordJSON encodes the fields of a journal record as a JSON object
+// in their original order. The realtime timestamp, priority and message
+// become the timestamp, priority (or level without a facility) and message
+// fields; the other fields keep their journal names.
+func journalRecordJSON(fields []journalField) []byte {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		values[field.name] = string(field.value)
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	write := func(name string, value any) {
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		encoded, _ := json.Marshal(value)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(encoded)
+	}
+
+	if us, err := strconv.ParseInt(values[journalRealtimeField], 10, 64); err == nil {
+		write("timestamp", time.UnixMicro(us).UTC().Format(time.RFC3339Nano))
+	}
+	priority, priorityErr := strconv.Atoi(values[journalPriorityField])
+	facility, facilityErr := strconv.Atoi(values[journalFacilityField])
+	validPriority := priorityErr == nil && priority >= 0 && priority < len(syslogSeverityNames)
+	combined := validPriority && facilityErr == nil && facility >= 0 && facility < len(syslogFacilityNames)
+	switch {
+	case combined:
+		write("priority", facility*8+priority)
+	case validPriority:
+		write("level", syslogSeverityNames[priority])
+	}
+	if message, ok := values[journalMessageField]; ok {
+		write("message", message)
+	}
+
+	for _, field := range fields {
+		switch field.name {
+		case journalRealtimeField, journalMessageField:
+			continue
+		case journalPriorityField:
+			if validPriority {
+				continue
+			}
+		case journalFacilityField:
+			if combined {
+				continue
+			}
+		}
+		write(field.name, string(field.value))
+	}
+	out.WriteByte('}')
+	return out.Bytes()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// scannerWithSplit reads input one byte at a time, which exercises the
+// partial-record path of split
+func scannerWithSplit(input string, split bufio.SplitFunc) *bufio.Scanner {
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(input)))
+	scanner.Split(split)
+	return scanner
+}
+
+func scanAll(t *testing.T, input string, split bufio.SplitFunc) []string {
+	t.Helper()
+	scanner := scannerWithSplit(input, split)
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected scanner error: %v", err)
+	}
+	return tokens
+}
+
+func TestScanJSONSeq(t *testing.T) {
+	input := "\x1e{\"msg\":\"one\"}\n" +
+		"\x1e{\n  \"msg\": \"two\",\n  \"n\": 2\n}\n" +
+		"\x1e{\"msg\":\"trunc\n" +
+		"\x1e{\"msg\":\"last\"}"
+	expected := []string{`{"msg":"one"}`, `{"msg":"two","n":2}`, `{"msg":"trunc`, `{"msg":"last"}`}
+	if tokens := scanAll(t, input, scanJSONSeq); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected %q, got %q", expected, tokens)
+	}
+
+	// Pretty-printed texts stay one entry each through multiline grouping
+	split, err := splitFunc(&Config{Framing: framingJSONSeq})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var entries []string
+	for entry := range multilineLogIteratorWith(strings.NewReader(input), defaultContinuationPattern, multilineOptions{split: split}) {
+		entries = append(entries, entry)
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected entries %q, got %q", expected, entries)
+	}
+}
+
+// journalBinaryField encodes a field in the binary form of the export format
+func journalBinaryField(name, value string) string {
+	var out bytes.Buffer
+	out.WriteString(name + "\n")
+	binary.Write(&out, binary.LittleEndian, uint64(len(value)))
+	out.WriteString(value + "\n")
+	return out.String()
+}
+
+func TestScanJournalExport(t *testing.T) {
+	input := "__CURSOR=s=1\n" +
+		"__REALTIME_TIMESTAMP=1705314645123456\n" +
+		"PRIORITY=3\n" +
+		"SYSLOG_FACILITY=3\n" +
+		"_SYSTEMD_UNIT=nginx.service\n" +
+		journalBinaryField("MESSAGE", "upstream failed\nretrying") +
+		"\n" +
+		"PRIORITY=6\n" +
+		"MESSAGE=ready\n" +
+		"\n" +
+		"MESSAGE=no trailing blank line\n"
+
+	expected := []string{
+		`{"timestamp":"2024-01-15T10:30:45.123456Z","priority":27,"message":"upstream failed\nretrying","__CURSOR":"s=1","_SYSTEMD_UNIT":"nginx.service"}`,
+		`{"level":"info","message":"ready"}`,
+		`{"message":"no trailing blank line"}`,
+	}
+	if tokens := scanAll(t, input, scanJournalExport); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tokens, "\n"))
+	}
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, err := extractor.ParseLogEntry(expected[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Message != "upstream failed\nretrying" || entry.Level != "err" {
+		t.Errorf("Expected the journal message and level, got %q and %q", entry.Message, entry.Level)
+	}
+	if !entry.Timestamp.Equal(time.UnixMicro(1705314645123456)) {
+		t.Errorf("Expected the realtime timestamp, got %v", entry.Timestamp)
+	}
+	if entry.Fields["syslog.facility"] != "daemon" || entry.Fields["_SYSTEMD_UNIT"] != "nginx.service" {
+		t.Errorf("Expected the facility and journal fields as attributes, got %v", entry.Fields)
+	}
+}
+
+func TestSplitFunc(t *testing.T) {
+	for _, framing := range []string{"", framingLines, framingJSONSeq, framingJournalExport} {
+		if _, err := splitFunc(&Config{Framing: framing}); err != nil {
+			t.Errorf("Unexpected error for framing %q: %v", framing, err)
+		}
+	}
+	if _, err := splitFunc(&Config{Framing: "nul"}); err == nil {
+		t.Error("Expected error for unknown framing")
+	}
+	if _, err := splitFunc(&Config{CarriageReturn: "squash"}); err == nil {
+		t.Error("Expected error for unknown carriage-return mode with line framing")
+	}
+}
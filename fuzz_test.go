@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzParseLine(f *testing.F) {
+	for _, seed := range []string{
+		`{"timestamp": "2024-01-15T10:30:45Z", "level": "info", "message": "test"}`,
+		`2024-01-15T10:30:45.123Z {"level": "error", "msg": "prefixed"}`,
+		`{"ts": 1705315845, "lvl": "warn", "text": "numeric time", "nested": {"a": [1, 2]}}`,
+		`{"level": 3, "message": null}`,
+		`plain text`,
+		`{`,
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, line []byte) {
+		entry, err := ParseLine(line)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) failed: %v", line, err)
+		}
+		if entry.Raw != string(line) {
+			t.Errorf("Expected raw line to be kept, got %q", entry.Raw)
+		}
+		if entry.Level == "" {
+			t.Errorf("Expected a level for %q", line)
+		}
+		if entry.Timestamp.IsZero() {
+			t.Errorf("Expected a timestamp for %q", line)
+		}
+	})
+}
+
+func FuzzParseTimestamp(f *testing.F) {
+	for _, seed := range []string{
+		"2024-01-15T10:30:45Z",
+		"2024-01-15T10:30:45.123456789+02:00",
+		"2024-01-15 10:30:45",
+		"not-a-timestamp",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		ts, err := parseTimestamp(input)
+		if err == nil && ts.IsZero() && !strings.HasPrefix(input, "0001-01-01") {
+			t.Errorf("parseTimestamp(%q) returned the zero time without an error", input)
+		}
+	})
+}
+
+func FuzzMultilineLogIterator(f *testing.F) {
+	for _, seed := range []string{
+		"first\nsecond\n",
+		"Exception\n\tat Foo\n\tat Bar\nnext\n",
+		"{\n  \"level\": \"info\"\n}\n",
+		"progress 10%\rprogress 100%\ndone",
+		"\n\n  \n",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var assembled strings.Builder
+		for entry := range multilineLogIterator(strings.NewReader(input), defaultContinuationPattern) {
+			if strings.TrimSpace(entry) == "" {
+				t.Fatalf("Empty entry assembled from %q", input)
+			}
+			assembled.WriteString(entry)
+		}
+
+		// Every non-blank line after the first entry start ends up in some
+		// entry; orphaned continuation lines before it are dropped by design
+		started := false
+		for _, line := range strings.Split(input, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if !started {
+				trimmed := strings.TrimSpace(line)
+				started = trimmed != "" && !defaultContinuationPattern.MatchString(line) &&
+					trimmed != "]" && trimmed != "}" && trimmed != "]," && trimmed != "},"
+			}
+			if started && strings.TrimSpace(line) != "" && utf8.ValidString(line) && !strings.Contains(assembled.String(), line) {
+				t.Fatalf("Line %q lost from %q", line, input)
+			}
+		}
+	})
+}
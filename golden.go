@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// TestArgs are the arguments of `otel-logger config-test`
+type TestArgs struct {
+	Config              string `arg:"--config" help:"Config file to test (default: built-in defaults)"`
+	Input               string `arg:"--input,required" help:"Log file to run through the config (- for stdin)"`
+	Golden              string `arg:"--golden,required" help:"NDJSON file with the expected records"`
+	Update              bool   `arg:"--update" help:"Write the current output to the golden file instead of comparing"`
+	ContinuationPattern string `arg:"--continuation-pattern" default:"^[ \\t]" help:"Regex pattern for continuation lines"`
+}
+
+func (TestArgs) Description() string {
+	return `Run a log file through a configuration and compare the records it would
+export with a golden file, one JSON record per line. Exits nonzero when the
+output drifts, so log shipping configs can be checked in CI without a
+collector. Nothing is sent to a collector.`
+}
+
+// goldenRecord is the normalized form of an exported record in a golden
+// file. Records without a parsed timestamp leave it out, as their ingest time
+// differs on every run, and so does log.timestamp.source, which only repeats
+// that. The original line is left out as it is the input.
+type goldenRecord struct {
+	Timestamp      string         `json:"timestamp,omitempty"`
+	SeverityNumber int            `json:"severity_number"`
+	SeverityText   string         `json:"severity_text,omitempty"`
+	Body           any            `json:"body,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+}
+
+func newGoldenRecord(record sdklog.Record) goldenRecord {
+	out := goldenRecord{
+		SeverityNumber: int(record.Severity()),
+		SeverityText:   record.SeverityText(),
+		Body:           plainValue(record.Body()),
+	}
+	if !record.Timestamp().Equal(diffIngestTime) {
+		out.Timestamp = record.Timestamp().UTC().Format(time.RFC3339Nano)
+	}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		if kv.Key == "log.record.original" || kv.Key == timestampSourceKey {
+			return true
+		}
+		if out.Attributes == nil {
+			out.Attributes = make(map[string]any)
+		}
+		out.Attributes[kv.Key] = plainValue(kv.Value)
+		return true
+	})
+	return out
+}
+
+// goldenOutput runs the input through the pipeline and returns one NDJSON
+// line per exported record
+func goldenOutput(ctx context.Context, input io.Reader, continuation *regexp.Regexp, pipeline *diffPipeline) ([]string, error) {
+	var errp error
+	var lines []string
+	for line := range multilineLogIteratorWith(input, continuation, multilineOptions{split: scanLinesCollapsingCR, err: &errp}) {
+		records, err := pipeline.process(ctx, line)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			data, err := json.Marshal(newGoldenRecord(record))
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, string(data))
+		}
+	}
+	if errp != nil {
+		return nil, fmt.Errorf("failed to read input: %w", errp)
+	}
+	return lines, nil
+}
+
+// readGolden reads the lines of a golden file, skipping blank ones
+func readGolden(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// writeGoldenDrift compares the output with the golden lines record by
+// record, reports the records that differ and returns how many did
+func writeGoldenDrift(w io.Writer, golden, actual []string) int {
+	drifted := 0
+	for i := 0; i < max(len(golden), len(actual)); i++ {
+		var want, got string
+		if i < len(golden) {
+			want = golden[i]
+		}
+		if i < len(actual) {
+			got = actual[i]
+		}
+		if goldenEqual(want, got) {
+			continue
+		}
+
+		drifted++
+		fmt.Fprintf(w, "record %d:\n", i+1)
+		if want != "" {
+			fmt.Fprintf(w, "  - %s\n", want)
+		}
+		if got != "" {
+			fmt.Fprintf(w, "  + %s\n", got)
+		}
+	}
+	return drifted
+}
+
+// goldenEqual compares two records as JSON, so hand-edited golden files may
+// order or space their keys differently
+func goldenEqual(want, got string) bool {
+	if want == got {
+		return true
+	}
+	var a, b any
+	if json.Unmarshal([]byte(want), &a) != nil || json.Unmarshal([]byte(got), &b) != nil {
+		return false
+	}
+	wantJSON, _ := json.Marshal(a)
+	gotJSON, _ := json.Marshal(b)
+	return bytes.Equal(wantJSON, gotJSON)
+}
+
+// runTest implements `otel-logger config-test`
+func runTest(args []string) error {
+	var testArgs TestArgs
+	parser, err := arg.NewParser(arg.Config{Program: "otel-logger config-test"}, &testArgs)
+	if err != nil {
+		return err
+	}
+	parser.MustParse(args)
+
+	pipeline, err := newDiffPipeline(testArgs.Config)
+	if err != nil {
+		return fmt.Errorf("failed to load --config: %w", err)
+	}
+	continuation, err := continuationFor(testArgs.ContinuationPattern, pipeline.extractor)
+	if err != nil {
+		return fmt.Errorf("invalid continuation pattern: %w", err)
+	}
+
+	input := io.Reader(os.Stdin)
+	if testArgs.Input != "-" {
+		file, err := os.Open(testArgs.Input)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	actual, err := goldenOutput(context.Background(), input, continuation, pipeline)
+	if err != nil {
+		return err
+	}
+
+	if testArgs.Update {
+		var out bytes.Buffer
+		for _, line := range actual {
+			out.WriteString(line + "\n")
+		}
+		if err := os.WriteFile(testArgs.Golden, out.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("failed to write golden file: %w", err)
+		}
+		fmt.Printf("Wrote %d records to %s\n", len(actual), testArgs.Golden)
+		return nil
+	}
+
+	file, err := os.Open(testArgs.Golden)
+	if err != nil {
+		return fmt.Errorf("failed to open golden file (create it with --update): %w", err)
+	}
+	defer file.Close()
+	golden, err := readGolden(file)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file: %w", err)
+	}
+
+	if drifted := writeGoldenDrift(os.Stdout, golden, actual); drifted > 0 {
+		return fmt.Errorf("%d of %d records differ from %s", drifted, max(len(golden), len(actual)), testArgs.Golden)
+	}
+	fmt.Printf("%d records match %s\n", len(actual), testArgs.Golden)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoldenOutput(t *testing.T) {
+	pipeline, err := newDiffPipeline("")
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	input := strings.Join([]string{
+		`{"time":"2025-01-02T15:04:05Z","level":"warn","msg":"slow","ms":120}`,
+		`plain text`,
+	}, "\n")
+	lines, err := goldenOutput(context.Background(), strings.NewReader(input), defaultContinuationPattern, pipeline)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`{"timestamp":"2025-01-02T15:04:05Z","severity_number":13,"severity_text":"warn","body":"slow","attributes":{"ms":120}}`,
+		`{"severity_number":9,"severity_text":"info","body":"plain text"}`,
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestGoldenEqual(t *testing.T) {
+	if !goldenEqual(`{"a":1,"b":{"c":2}}`, `{ "b": {"c":2}, "a": 1 }`) {
+		t.Error("Expected key order and spacing to be ignored")
+	}
+	if goldenEqual(`{"a":1}`, `{"a":2}`) {
+		t.Error("Expected different values to differ")
+	}
+	if goldenEqual(`{"a":1}`, ``) {
+		t.Error("Expected a missing record to differ")
+	}
+}
+
+func TestWriteGoldenDrift(t *testing.T) {
+	var out bytes.Buffer
+	drifted := writeGoldenDrift(&out, []string{`{"a":1}`, `{"a":2}`}, []string{`{"a":1}`, `{"a":3}`, `{"a":4}`})
+	if drifted != 2 {
+		t.Errorf("Expected 2 drifted records, got %d", drifted)
+	}
+	for _, expected := range []string{"record 2:\n  - {\"a\":2}\n  + {\"a\":3}\n", "record 3:\n  + {\"a\":4}\n"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "record 1:") {
+		t.Errorf("Expected matching record to be skipped, got:\n%s", out.String())
+	}
+}
+
+func TestRunTestGolden(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	input := filepath.Join(dir, "in.log")
+	golden := filepath.Join(dir, "out.ndjson")
+
+	writeFile := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	writeFile(config, "level_fields: [sev]\n")
+	writeFile(input, `{"sev":"error","msg":"boom"}`+"\n"+`{"msg":"fine"}`+"\n")
+
+	args := []string{"--config", config, "--input", input, "--golden", golden}
+	if err := runTest(append(args, "--update")); err != nil {
+		t.Fatalf("Failed to write golden file: %v", err)
+	}
+	if err := runTest(args); err != nil {
+		t.Fatalf("Expected output to match the golden file: %v", err)
+	}
+
+	// Changing the config drifts from the golden file
+	writeFile(config, "level_fields: [level]\n")
+	err := runTest(args)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 records differ") {
+		t.Errorf("Expected drift to be reported, got %v", err)
+	}
+}
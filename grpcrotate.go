@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// newGRPCExporter creates an OTLP/gRPC exporter that, with
+// --grpc-reconnect-interval, is replaced by a fresh one periodically
+func newGRPCExporter(ctx context.Context, config *Config, opts ...otlploggrpc.Option) (sdklog.Exporter, error) {
+	if dialOpts := roundRobinDialOptions(config); len(dialOpts) > 0 {
+		opts = append(opts, otlploggrpc.WithDialOption(dialOpts...))
+	}
+	create := func(ctx context.Context) (sdklog.Exporter, error) {
+		return otlploggrpc.New(ctx, opts...)
+	}
+	if config.GRPCReconnectInterval <= 0 {
+		return create(ctx)
+	}
+	return newRotatingExporter(ctx, create, config.GRPCReconnectInterval, config.Verbose)
+}
+
+// rotatingExporter replaces its exporter, and with it the gRPC connection,
+// once the exporter is older than the interval. A new connection resolves the
+// collector hostname again, so a sidecar behind a headless service follows
+// collectors being scaled or replaced instead of staying pinned to the pods
+// it first connected to. Collectors that set MAX_CONNECTION_AGE get the same
+// effect server-side; gRPC reconnects on their GOAWAY without this.
+type rotatingExporter struct {
+	create   func(context.Context) (sdklog.Exporter, error)
+	interval time.Duration
+	verbose  bool
+
+	mu      sync.Mutex
+	current sdklog.Exporter
+	created time.Time
+}
+
+func newRotatingExporter(ctx context.Context, create func(context.Context) (sdklog.Exporter, error), interval time.Duration, verbose bool) (*rotatingExporter, error) {
+	exporter, err := create(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatingExporter{
+		create:   create,
+		interval: interval,
+		verbose:  verbose,
+		current:  exporter,
+		created:  time.Now(),
+	}, nil
+}
+
+func (e *rotatingExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if time.Since(e.created) >= e.interval {
+		e.rotate(ctx)
+	}
+	return e.current.Export(ctx, records)
+}
+
+// rotate swaps in a new exporter. Exports hold the lock, so nothing is in
+// flight on the old one when it is shut down.
+func (e *rotatingExporter) rotate(ctx context.Context) {
+	next, err := e.create(ctx)
+	if err != nil {
+		// Keep the working connection and try again on the next export
+		logError("Failed to open new gRPC connection, keeping the current one: %v\n", err)
+		return
+	}
+
+	old := e.current
+	e.current = next
+	e.created = time.Now()
+	if err := old.Shutdown(ctx); err != nil {
+		logDebug(e.verbose, "Error closing previous gRPC connection: %v\n", err)
+	}
+	logDebug(e.verbose, "Rotated gRPC connection to the collector\n")
+}
+
+func (e *rotatingExporter) ForceFlush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.current.ForceFlush(ctx)
+}
+
+func (e *rotatingExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.current.Shutdown(ctx)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// closingExporter records whether it was shut down
+type closingExporter struct {
+	recordingExporter
+	closed bool
+}
+
+func (e *closingExporter) Shutdown(ctx context.Context) error {
+	e.closed = true
+	return nil
+}
+
+func TestRotatingExporter(t *testing.T) {
+	var created []*closingExporter
+	create := func(ctx context.Context) (sdklog.Exporter, error) {
+		exporter := &closingExporter{}
+		created = append(created, exporter)
+		return exporter, nil
+	}
+
+	ctx := context.Background()
+	exporter, err := newRotatingExporter(ctx, create, time.Hour, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	batch := []sdklog.Record{{}}
+	exporter.Export(ctx, batch)
+	if len(created) != 1 || len(created[0].Records()) != 1 {
+		t.Fatalf("Expected the first exporter to be used before the interval passes")
+	}
+
+	exporter.created = time.Now().Add(-2 * time.Hour)
+	exporter.Export(ctx, batch)
+	if len(created) != 2 {
+		t.Fatalf("Expected a new exporter after the interval, got %d", len(created))
+	}
+	if !created[0].closed {
+		t.Error("Expected the previous exporter to be shut down")
+	}
+	if len(created[1].Records()) != 1 {
+		t.Error("Expected the batch to go through the new exporter")
+	}
+
+	exporter.Shutdown(ctx)
+	if !created[1].closed {
+		t.Error("Expected Shutdown to close the current exporter")
+	}
+}
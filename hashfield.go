@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+)
+
+// fieldHasher pseudonymizes configured fields with a keyed hash: equal values
+// still hash equally, so they can be joined on, but without the salt they
+// cannot be reversed or brute-forced from a list of candidates.
+type fieldHasher struct {
+	fields map[string]bool
+	salt   []byte
+	audit  *ruleAudit
+}
+
+// newFieldHasher reads the salt from the named environment variable. Hashing
+// without a salt would be reversible for small value spaces, so it is refused.
+func newFieldHasher(fields []string, saltEnv string) (*fieldHasher, error) {
+	if saltEnv == "" {
+		return nil, fmt.Errorf("--hash-field requires --hash-salt-env")
+	}
+	salt := os.Getenv(saltEnv)
+	if salt == "" {
+		return nil, fmt.Errorf("--hash-salt-env %s is empty or unset", saltEnv)
+	}
+
+	hasher := &fieldHasher{fields: make(map[string]bool), salt: []byte(salt)}
+	for _, field := range fields {
+		hasher.fields[field] = true
+	}
+	return hasher, nil
+}
+
+// hash returns the hex HMAC-SHA256 of the value, truncated to 128 bits
+func (h *fieldHasher) hash(value any) string {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		text = string(data)
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	mac := hmac.New(sha256.New, h.salt)
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// apply returns the entry with configured fields hashed. The original line
+// would reveal the values, so it is dropped from entries that were changed.
+func (h *fieldHasher) apply(entry *LogEntry) *LogEntry {
+	fields, changed := entry.Fields, false
+	for field := range h.fields {
+		if hashed, ok := h.hashPath(fields, field); ok {
+			fields, changed = hashed, true
+			h.audit.hit("hash-field:"+field, 1)
+		}
+	}
+	if !changed {
+		return entry
+	}
+
+	pseudonymized := *entry
+	pseudonymized.Fields = fields
+	pseudonymized.Raw = ""
+	return &pseudonymized
+}
+
+// hashPath returns a copy of fields with the value at path hashed, and
+// whether there was one. The path is a key as it is, or leads through nested
+// objects at its dots, as user.id does in {"user":{"id":7}}. The objects on
+// the way are copied, so fields is left alone.
+func (h *fieldHasher) hashPath(fields map[string]any, path string) (map[string]any, bool) {
+	if value, ok := fields[path]; ok {
+		hashed := maps.Clone(fields)
+		hashed[path] = h.hash(value)
+		return hashed, true
+	}
+	for i := range len(path) {
+		if path[i] != '.' {
+			continue
+		}
+		child, ok := fields[path[:i]].(map[string]any)
+		if !ok {
+			continue
+		}
+		if hashedChild, ok := h.hashPath(child, path[i+1:]); ok {
+			hashed := maps.Clone(fields)
+			hashed[path[:i]] = hashedChild
+			return hashed, true
+		}
+	}
+	return fields, false
+}
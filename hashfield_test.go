@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewFieldHasherRequiresSalt(t *testing.T) {
+	if _, err := newFieldHasher([]string{"user_id"}, ""); err == nil {
+		t.Error("Expected error without --hash-salt-env")
+	}
+	t.Setenv("OTEL_LOGGER_TEST_SALT", "")
+	if _, err := newFieldHasher([]string{"user_id"}, "OTEL_LOGGER_TEST_SALT"); err == nil {
+		t.Error("Expected error for an empty salt")
+	}
+}
+
+func TestLogProcessorHashesFields(t *testing.T) {
+	t.Setenv("OTEL_LOGGER_TEST_SALT", "pepper")
+	hasher, err := newFieldHasher([]string{"user_id", "email"}, "OTEL_LOGGER_TEST_SALT")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetFieldHasher(hasher)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	lines := []string{
+		`{"msg":"login","user_id":42,"email":"a@example.com","ip":"10.0.0.1"}`,
+		`{"msg":"logout","user_id":42}`,
+		`{"msg":"unrelated","path":"/health"}`,
+	}
+	var entries []*LogEntry
+	for _, line := range lines {
+		entry, _ := extractor.ParseLogEntry(line)
+		entries = append(entries, entry)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	records := exporter.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	login, logout, unrelated := recordAttributes(records[0]), recordAttributes(records[1]), recordAttributes(records[2])
+
+	if login["user_id"] == "42" || len(login["user_id"]) != 32 {
+		t.Errorf("Expected user_id to be hashed, got %q", login["user_id"])
+	}
+	if login["user_id"] != logout["user_id"] {
+		t.Error("Expected equal values to hash equally so they stay joinable")
+	}
+	if login["email"] == "a@example.com" || login["ip"] != "10.0.0.1" {
+		t.Errorf("Expected only configured fields to be hashed, got %v", login)
+	}
+	if _, ok := login["log.record.original"]; ok {
+		t.Error("Expected the original line to be dropped when a field was hashed")
+	}
+	if unrelated["log.record.original"] == "" {
+		t.Error("Expected the original line to be kept when nothing was hashed")
+	}
+	if entries[0].Fields["user_id"] != float64(42) {
+		t.Error("Hashing should not modify the caller's entry")
+	}
+
+	// A different salt gives unrelated hashes
+	t.Setenv("OTEL_LOGGER_TEST_SALT", "salt")
+	other, _ := newFieldHasher([]string{"user_id"}, "OTEL_LOGGER_TEST_SALT")
+	if other.hash(float64(42)) == login["user_id"] {
+		t.Error("Expected the hash to depend on the salt")
+	}
+}
+
+func TestFieldHasherNestedPaths(t *testing.T) {
+	t.Setenv("OTEL_LOGGER_TEST_SALT", "pepper")
+	hasher, err := newFieldHasher([]string{"user.id", "http.request.client_ip"}, "OTEL_LOGGER_TEST_SALT")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, _ := extractor.ParseLogEntry(`{"msg":"login","user":{"id":7,"name":"jane"},"http.request":{"client_ip":"10.0.0.1"},"id":7}`)
+	hashed := hasher.apply(entry)
+
+	user := hashed.Fields["user"].(map[string]any)
+	if user["id"] != hasher.hash(float64(7)) {
+		t.Errorf("Expected user.id to be hashed, got %v", user["id"])
+	}
+	if user["name"] != "jane" || hashed.Fields["id"] != float64(7) {
+		t.Errorf("Expected only the named paths to be hashed, got %v", hashed.Fields)
+	}
+	if ip := hashed.Fields["http.request"].(map[string]any)["client_ip"]; ip == "10.0.0.1" {
+		t.Error("Expected a path through a dotted key to be hashed")
+	}
+	if entry.Fields["user"].(map[string]any)["id"] != float64(7) {
+		t.Error("Hashing should not modify the caller's nested objects")
+	}
+	if hashed.Raw != "" {
+		t.Error("Expected the original line to be dropped")
+	}
+
+	// A flat key with the dotted name is hashed as it is
+	flat, _ := extractor.ParseLogEntry(`{"msg":"login","user.id":7}`)
+	if got := hasher.apply(flat).Fields["user.id"]; got != hasher.hash(float64(7)) {
+		t.Errorf("Expected the flat user.id to be hashed, got %v", got)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// exportHelperEnv marks a re-executed otel-logger as the export helper. It is
+// distinct from OTEL_LOGGER_EXPORT_HELPER, the env equivalent of --export-helper.
+const exportHelperEnv = "OTEL_LOGGER_INTERNAL_EXPORT_HELPER"
+
+func isExportHelper() bool {
+	return os.Getenv(exportHelperEnv) == "1"
+}
+
+// handoffWriter sends parsed entries to the export helper as JSON lines. Once a
+// write returns the entry sits in the kernel pipe buffer, so it survives the
+// wrapper being killed.
+type handoffWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	failed  bool
+}
+
+func newHandoffWriter(w io.Writer) *handoffWriter {
+	return &handoffWriter{encoder: json.NewEncoder(w)}
+}
+
+// Send writes the entry to the helper. After the first failure entries are
+// dropped and the error is reported once.
+func (h *handoffWriter) Send(entry *LogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failed {
+		return
+	}
+	if err := h.encoder.Encode(entry); err != nil {
+		h.failed = true
+		logError("Export helper is gone, dropping further logs: %v\n", err)
+	}
+}
+
+// SendBarrier asks the helper to flush the entries sent so far before it
+// processes the ones that follow. It is sent as a null line.
+func (h *handoffWriter) SendBarrier() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failed {
+		return
+	}
+	if err := h.encoder.Encode(nil); err != nil {
+		h.failed = true
+		logError("Export helper is gone, dropping further logs: %v\n", err)
+	}
+}
+
+// NewHandoffLogProcessor creates a processor that forwards entries to the export helper
+func NewHandoffLogProcessor(w io.Writer) *LogProcessor {
+	return &LogProcessor{handoff: newHandoffWriter(w)}
+}
+
+// readHandoff decodes entries written by a handoffWriter until EOF, and
+// flushes at each barrier
+func readHandoff(ctx context.Context, r io.Reader, processor *LogProcessor) error {
+	decoder := json.NewDecoder(r)
+	// Integers would otherwise come back as float64 and large ones lose
+	// precision, unlike in the wrapper
+	decoder.UseNumber()
+	for {
+		var entry *LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// A truncated final entry means the wrapper died mid-write
+				return nil
+			}
+			return fmt.Errorf("failed to decode handed-off entry: %w", err)
+		}
+		if entry == nil {
+			if err := processor.Flush(ctx); err != nil {
+				logError("Failed to flush handed-off logs: %v\n", err)
+			}
+			continue
+		}
+		restoreHandoffNumbers(entry.Fields)
+		restoreHandoffNumbers(entry.Object)
+		processor.ProcessLogEntry(ctx, entry)
+	}
+}
+
+// restoreHandoffNumbers turns the json.Numbers of a decoded value back into
+// int64, or float64 for those that are not integers, in place
+func restoreHandoffNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, child := range v {
+			v[key] = restoreHandoffNumbers(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = restoreHandoffNumbers(child)
+		}
+	}
+	return value
+}
+
+// startExportHelper re-executes otel-logger as a detached export helper with
+// the same arguments and environment. On Unix the helper lives in its own
+// process group so terminal signals aimed at the wrapper do not cut its export
+// short.
+func startExportHelper(config *Config) (*exec.Cmd, io.WriteCloser, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to locate otel-logger executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), exportHelperEnv+"=1")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = exportHelperAttr()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create export helper pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start export helper: %w", err)
+	}
+	logInfo(config.Verbose, "Started export helper (pid %d)\n", cmd.Process.Pid)
+
+	return cmd, stdin, nil
+}
+
+// runWithExportHelper parses input in this process and leaves exporting to the helper
+func runWithExportHelper(ctx context.Context, config *Config) error {
+	helper, pipe, err := startExportHelper(config)
+	if err != nil {
+		return err
+	}
+
+	processor := NewHandoffLogProcessor(pipe)
+	processingErr := func() error {
+		defer reportPanic(ctx, processor, "log processing")
+		return processInput(ctx, config, processor, nil)
+	}()
+
+	// Closing the pipe tells the helper to flush and exit
+	pipe.Close()
+	if err := helper.Wait(); err != nil {
+		logError("Export helper failed: %v\n", err)
+	}
+
+	return processingErr
+}
+
+// runExportHelper is the helper side: it exports entries read from stdin
+// until the wrapper closes the pipe or dies
+func runExportHelper(config *Config) error {
+	// The wrapper decides when we are done by closing the pipe
+	signal.Ignore(syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	ctx := context.Background()
+
+	runPreflight(ctx, config)
+
+	provider, err := createLoggerProvider(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create logger provider: %w", err)
+	}
+	defer func() {
+		if err := provider.Shutdown(ctx); err != nil {
+			logError("Error shutting down logger provider: %v\n", err)
+		}
+	}()
+
+	// The helper's export errors are the ones worth exporting; the wrapper
+	// only reports its own on stderr, having no exporter
+	if !config.NoInternalErrors {
+		defer exportInternalErrors(provider.Logger(internalErrorScope))()
+	}
+
+	processor, err := configureLogProcessor(ctx, provider, config)
+	if err != nil {
+		return err
+	}
+	// Records are filtered here rather than in the wrapper
+	control, err := startControlServer(ctx, config, processor)
+	if err != nil {
+		return err
+	}
+	defer control.Close()
+	// So are config file changes to the filters; the wrapper records them
+	if config.ConfigFile != "" {
+		file, sum, err := loadFileConfig(config.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		settings := mergeFileConfig(file, config)
+		filters, err := settings.filters(config)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		if err := filters.apply(processor, control); err != nil {
+			return err
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go newConfigWatcher(config, nil, processor, control, settings, sum).run(watchCtx)
+	}
+
+	readErr := func() error {
+		defer reportPanic(ctx, processor, "export helper")
+		return readHandoff(ctx, os.Stdin, processor)
+	}()
+	processor.Finish(ctx)
+
+	if err := provider.ForceFlush(ctx); err != nil {
+		return fmt.Errorf("failed to flush logs: %w", err)
+	}
+
+	return readErr
+}
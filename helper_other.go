@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+// exportHelperAttr leaves the export helper in the wrapper's process group,
+// as process groups are only set up on Unix
+func exportHelperAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHandoffRoundTrip(t *testing.T) {
+	var pipe bytes.Buffer
+	sender := NewHandoffLogProcessor(&pipe)
+
+	ctx := context.Background()
+	timestamp := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	sender.ProcessLogEntry(ctx, &LogEntry{
+		Timestamp: timestamp,
+		Level:     "error",
+		Message:   "handed off",
+		Fields:    map[string]any{"user": "alice", "attempt": 3, "id": int64(1<<60 + 1), "ratio": 0.25},
+		Raw:       `{"level":"error"}`,
+		Stream:    "stderr",
+	})
+	sender.ProcessLogEntry(ctx, &LogEntry{Timestamp: timestamp, Level: "info", Message: "second", Fields: map[string]any{}})
+
+	// Simulate the wrapper dying halfway through a write
+	pipe.WriteString(`{"Timestamp":"2024-01-15T10:30:45Z","Lev`)
+
+	provider, exporter := newRecordingProvider()
+	receiver := NewLogProcessor(provider.Logger("test"))
+	if err := readHandoff(ctx, &pipe, receiver); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records := exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	first := records[0]
+	if first.Body().AsString() != "handed off" {
+		t.Errorf("Expected body 'handed off', got %q", first.Body().AsString())
+	}
+	if !first.Timestamp().Equal(timestamp) {
+		t.Errorf("Expected timestamp %v, got %v", timestamp, first.Timestamp())
+	}
+	if first.SeverityText() != "error" {
+		t.Errorf("Expected severity text 'error', got %q", first.SeverityText())
+	}
+
+	attrs := recordAttributes(first)
+	if attrs["user"] != "alice" || attrs["attempt"] != "3" || attrs["log.iostream"] != "stderr" {
+		t.Errorf("Attributes not preserved: %v", attrs)
+	}
+	// Numbers keep their type and precision through JSON
+	if attrs["id"] != "1152921504606846977" || attrs["ratio"] != "0.25" {
+		t.Errorf("Numbers not preserved: %v", attrs)
+	}
+}
+
+func TestHandoffBarrier(t *testing.T) {
+	var pipe bytes.Buffer
+	sender := NewHandoffLogProcessor(&pipe)
+
+	ctx := context.Background()
+	sender.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "info", Message: "output"})
+	if err := sender.Barrier(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sender.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "info", Message: "exit"})
+
+	provider, exporter := newRecordingProvider()
+	receiver := NewLogProcessor(provider.Logger("test"))
+	var exportedAtFlush []int
+	receiver.SetFlusher(func(ctx context.Context) error {
+		exportedAtFlush = append(exportedAtFlush, len(exporter.Records()))
+		return nil
+	})
+	if err := readHandoff(ctx, &pipe, receiver); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(exporter.Records()) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(exporter.Records()))
+	}
+	if len(exportedAtFlush) != 1 || exportedAtFlush[0] != 1 {
+		t.Errorf("Expected the helper to flush once, between the records, got %v", exportedAtFlush)
+	}
+}
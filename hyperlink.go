@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// Placeholders of a --trace-url template
+const (
+	traceURLTraceID = "{trace_id}"
+	traceURLSpanID  = "{span_id}"
+)
+
+// traceLinker turns the trace IDs in passthrough output into OSC 8 terminal
+// hyperlinks to a trace UI, colored by the entry's severity
+type traceLinker struct {
+	template string
+	color    bool
+}
+
+// compileTraceLinks checks --trace-url. Like --grep it applies to the entries
+// passthrough re-emits, so raw passthrough cannot carry links.
+func compileTraceLinks(config *Config) (*traceLinker, error) {
+	if config.TraceURL == "" {
+		return nil, nil
+	}
+	if !config.PassthroughStdout && !config.PassthroughStderr {
+		return nil, fmt.Errorf("--trace-url requires --passthrough-stdout or --passthrough-stderr")
+	}
+	if config.PassthroughRaw {
+		return nil, fmt.Errorf("--trace-url cannot be combined with --passthrough-raw")
+	}
+	if !strings.Contains(config.TraceURL, traceURLTraceID) {
+		return nil, fmt.Errorf("--trace-url must contain %s", traceURLTraceID)
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &traceLinker{template: config.TraceURL, color: !noColor}, nil
+}
+
+// forTerminal returns the linker if f is a terminal and nil otherwise, so
+// escape sequences never end up in files or pipes
+func (l *traceLinker) forTerminal(f *os.File) *traceLinker {
+	if l == nil {
+		return nil
+	}
+	if info, err := f.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return l
+}
+
+// render links the first occurrence of the entry's trace ID in line. Lines
+// without a trace context are returned unchanged.
+func (l *traceLinker) render(line string, entry *LogEntry) string {
+	if l == nil || entry == nil || entry.Trace == nil {
+		return line
+	}
+	// Hex IDs were lowercased when extracted; lowering keeps byte offsets
+	start := strings.Index(strings.ToLower(line), entry.Trace.TraceID)
+	if start == -1 {
+		return line
+	}
+	end := start + len(entry.Trace.TraceID)
+
+	url := strings.NewReplacer(traceURLTraceID, entry.Trace.TraceID, traceURLSpanID, entry.Trace.SpanID).Replace(l.template)
+	text := line[start:end]
+	if color := severityColor(entry.severity()); l.color && color != "" {
+		text = "\x1b[" + color + "m" + text + "\x1b[0m"
+	}
+	return line[:start] + "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\" + line[end:]
+}
+
+// severityColor is the SGR parameter for a severity: red for errors and
+// above, yellow for warnings, dim below info and the default color for info
+func severityColor(severity log.Severity) string {
+	switch {
+	case severity >= log.SeverityFatal:
+		return "1;31"
+	case severity >= log.SeverityError:
+		return "31"
+	case severity >= log.SeverityWarn:
+		return "33"
+	case severity >= log.SeverityInfo:
+		return ""
+	default:
+		return "2"
+	}
+}
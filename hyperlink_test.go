@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTraceLinkerRender(t *testing.T) {
+	linker := &traceLinker{template: "https://traces.example.com/trace/{trace_id}?span={span_id}", color: true}
+	entry := &LogEntry{
+		Level: "error",
+		Trace: &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+	}
+
+	line := `{"level":"error","trace_id":"4BF92F3577B34DA6A3CE929D0E0E4736","span_id":"00f067aa0ba902b7"}`
+	expected := `{"level":"error","trace_id":"` +
+		"\x1b]8;;https://traces.example.com/trace/4bf92f3577b34da6a3ce929d0e0e4736?span=00f067aa0ba902b7\x1b\\" +
+		"\x1b[31m4BF92F3577B34DA6A3CE929D0E0E4736\x1b[0m\x1b]8;;\x1b\\" +
+		`","span_id":"00f067aa0ba902b7"}`
+	if got := linker.render(line, entry); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	linker.color = false
+	entry.Level = "info"
+	if got := linker.render(line, entry); strings.Contains(got, "\x1b[") {
+		t.Errorf("Expected no color without color, got %q", got)
+	}
+
+	if got := linker.render("no trace here", &LogEntry{Level: "info"}); got != "no trace here" {
+		t.Errorf("Expected lines without a trace to be unchanged, got %q", got)
+	}
+	var none *traceLinker
+	if got := none.render(line, entry); got != line {
+		t.Errorf("Expected a nil linker to leave lines unchanged, got %q", got)
+	}
+}
+
+func TestProcessStreamTraceLinks(t *testing.T) {
+	provider, _ := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	input := `{"level":"warn","msg":"slow","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}` + "\n" + `plain text`
+
+	var output bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	processStream(context.Background(), strings.NewReader(input), "stdout", extractor, processor, &wg, streamOptions{
+		passthrough:         true,
+		output:              &output,
+		links:               &traceLinker{template: "https://t.example/{trace_id}"},
+		continuationPattern: defaultContinuationPattern,
+		split:               bufio.ScanLines,
+	})
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 passthrough lines, got %q", output.String())
+	}
+	if !strings.Contains(lines[0], "\x1b]8;;https://t.example/4bf92f3577b34da6a3ce929d0e0e4736\x1b\\") {
+		t.Errorf("Expected the trace ID to be linked, got %q", lines[0])
+	}
+	if lines[1] != "plain text" {
+		t.Errorf("Expected the plain line unchanged, got %q", lines[1])
+	}
+}
+
+func TestCompileTraceLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{name: "unset", config: Config{}},
+		{name: "with passthrough", config: Config{TraceURL: "https://t.example/{trace_id}", PassthroughStdout: true}},
+		{name: "without passthrough", config: Config{TraceURL: "https://t.example/{trace_id}"}, expectError: true},
+		{name: "raw passthrough", config: Config{TraceURL: "https://t.example/{trace_id}", PassthroughStdout: true, PassthroughRaw: true}, expectError: true},
+		{name: "no placeholder", config: Config{TraceURL: "https://t.example/", PassthroughStdout: true}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileTraceLinks(&tt.config)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
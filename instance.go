@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+// loadInstanceID returns the service instance ID stored at path, generating
+// and saving a new random UUID the first time so restarts keep reporting the
+// same instance
+func loadInstanceID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(data))
+		if _, err := uuid.Parse(id); err != nil {
+			return "", fmt.Errorf("invalid instance ID in %s: %w", path, err)
+		}
+		return id, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read instance ID: %w", err)
+	}
+
+	id := uuid.NewString()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create instance ID directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a partial ID
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".instance-id-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save instance ID: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(id + "\n"); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to save instance ID: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to save instance ID: %w", err)
+	}
+	// Another otel-logger starting at the same time may have won the race;
+	// link fails rather than replacing its ID
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return loadInstanceID(path)
+		}
+		return "", fmt.Errorf("failed to save instance ID: %w", err)
+	}
+	return id, nil
+}
+
+// createResource describes this otel-logger to the backend: the SDK defaults
+// and OTEL_RESOURCE_ATTRIBUTES, plus a persisted service.instance.id when
+// --instance-id-file is set and the attributes of --resource-attrs-file.
+// Attributes set in the environment win.
+func createResource(config *Config) (*resource.Resource, error) {
+	res := resource.Default()
+	if config.InstanceIDFile != "" {
+		id, err := loadInstanceID(config.InstanceIDFile)
+		if err != nil {
+			return nil, err
+		}
+		if res, err = resource.Merge(res, resource.NewSchemaless(semconv.ServiceInstanceID(id))); err != nil {
+			return nil, err
+		}
+	}
+	if config.ResourceAttrsFile != "" {
+		attrs, err := loadResourceAttrs(config.ResourceAttrsFile)
+		if err != nil {
+			return nil, err
+		}
+		if res, err = resource.Merge(res, resource.NewSchemaless(attrs...)); err != nil {
+			return nil, err
+		}
+	}
+	env, err := environmentResource()
+	if err != nil {
+		return nil, err
+	}
+	return resource.Merge(res, env)
+}
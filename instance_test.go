@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+func TestLoadInstanceIDPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "instance-id")
+
+	first, err := loadInstanceID(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := loadInstanceID(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first == "" || first != second {
+		t.Errorf("Expected the same instance ID across restarts, got %q and %q", first, second)
+	}
+
+	if err := os.WriteFile(path, []byte("not-a-uuid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadInstanceID(path); err == nil {
+		t.Error("Expected error for a corrupt instance ID file")
+	}
+}
+
+func TestCreateResourceInstanceID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instance-id")
+	id := "0b4b8b6e-3c52-4b7e-9a55-3f0f1b6f2c11"
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := createResource(&Config{InstanceIDFile: path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, ok := res.Set().Value(semconv.ServiceInstanceIDKey); !ok || value.AsString() != id {
+		t.Errorf("Expected service.instance.id %s, got %v", id, value.AsString())
+	}
+
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.instance.id=from-env")
+	res, err = createResource(&Config{InstanceIDFile: path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, _ := res.Set().Value(semconv.ServiceInstanceIDKey); value.AsString() != "from-env" {
+		t.Errorf("Expected OTEL_RESOURCE_ATTRIBUTES to win, got %v", value.AsString())
+	}
+}
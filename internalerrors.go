@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+)
+
+// internalErrorScope is the instrumentation scope otel-logger's own errors
+// are exported under, apart from the logs it ships
+const internalErrorScope = "otel-logger/internal"
+
+// Kinds of internal errors
+const (
+	internalErrorRuntime = "runtime" // reported by otel-logger, e.g. a line it could not parse
+	internalErrorExport  = "export"  // reported by the OpenTelemetry SDK, e.g. a failed export
+)
+
+// Attributes of internal error records
+const (
+	internalErrorKindKey       = "otel_logger.error.kind"
+	internalErrorSuppressedKey = "otel_logger.errors.suppressed"
+)
+
+// At most internalErrorBurst errors are exported per internalErrorWindow, so
+// an unreachable endpoint, whose export errors are themselves exported, or a
+// stream of unparsable lines cannot flood the pipeline. The errors left out
+// are counted on the next record.
+const (
+	internalErrorBurst  = 20
+	internalErrorWindow = time.Minute
+)
+
+// internalErrors receives the errors logged while a logger provider is
+// running; nil before and after
+var internalErrors atomic.Pointer[internalErrorSink]
+
+type internalErrorSink struct {
+	logger log.Logger
+
+	mu         sync.Mutex
+	start      time.Time // of the current window
+	count      int       // exported in the current window
+	suppressed int       // left out since the last exported error
+}
+
+// exportInternalErrors sends the errors otel-logger logs, and those of the
+// OpenTelemetry SDK, to the logger as well as stderr until stopped
+func exportInternalErrors(logger log.Logger) (stop func()) {
+	internalErrors.Store(&internalErrorSink{logger: logger})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		message := fmt.Sprintf("OpenTelemetry error: %v", err)
+		fmt.Fprintln(os.Stderr, message)
+		reportInternalError(internalErrorExport, message)
+	}))
+	return func() { internalErrors.Store(nil) }
+}
+
+// reportInternalError exports an error if a sink is installed
+func reportInternalError(kind, message string) {
+	if sink := internalErrors.Load(); sink != nil {
+		sink.emit(kind, strings.TrimSpace(message), time.Now())
+	}
+}
+
+func (s *internalErrorSink) emit(kind, message string, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.start) >= internalErrorWindow {
+		s.start, s.count = now, 0
+	}
+	if s.count >= internalErrorBurst {
+		s.suppressed++
+		s.mu.Unlock()
+		return
+	}
+	s.count++
+	suppressed := s.suppressed
+	s.suppressed = 0
+	s.mu.Unlock()
+
+	var record log.Record
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(now)
+	record.SetSeverity(log.SeverityError)
+	record.SetSeverityText("error")
+	record.SetBody(log.StringValue(message))
+	record.AddAttributes(log.String(internalErrorKindKey, kind))
+	if suppressed > 0 {
+		record.AddAttributes(log.Int(internalErrorSuppressedKey, suppressed))
+	}
+	s.logger.Emit(context.Background(), record)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestInternalErrors(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	stop := exportInternalErrors(provider.Logger(internalErrorScope))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	logError("Error parsing log entry: %v\n", errors.New("bad line"))
+	otel.Handle(errors.New("exporter unreachable"))
+	stop()
+	logError("After stopping\n")
+
+	records := exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	for i, want := range []struct{ body, kind string }{
+		{"Error parsing log entry: bad line", internalErrorRuntime},
+		{"OpenTelemetry error: exporter unreachable", internalErrorExport},
+	} {
+		record := records[i]
+		if record.Body().AsString() != want.body || record.Severity() != log.SeverityError {
+			t.Errorf("Expected %q at error, got %q at %v", want.body, record.Body().AsString(), record.Severity())
+		}
+		if scope := record.InstrumentationScope().Name; scope != internalErrorScope {
+			t.Errorf("Expected scope %s, got %s", internalErrorScope, scope)
+		}
+		if kind := recordAttributes(record)[internalErrorKindKey]; kind != want.kind {
+			t.Errorf("Expected kind %s, got %s", want.kind, kind)
+		}
+	}
+}
+
+func TestInternalErrorsRateLimited(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	sink := &internalErrorSink{logger: provider.Logger(internalErrorScope)}
+
+	start := time.Now()
+	for i := 0; i < internalErrorBurst+5; i++ {
+		sink.emit(internalErrorRuntime, "failed", start)
+	}
+	if got := len(exporter.Records()); got != internalErrorBurst {
+		t.Fatalf("Expected %d records within the window, got %d", internalErrorBurst, got)
+	}
+
+	sink.emit(internalErrorRuntime, "failed again", start.Add(internalErrorWindow))
+	records := exporter.Records()
+	last := records[len(records)-1]
+	if last.Body().AsString() != "failed again" || recordAttributes(last)[internalErrorSuppressedKey] != "5" {
+		t.Errorf("Expected the next window's record to count the 5 left out, got %q %v", last.Body().AsString(), recordAttributes(last))
+	}
+}
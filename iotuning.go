@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// readBufferSize is the --read-buffer-size in bytes, 0 for the scanner's
+// default of 4KiB growing to 64KiB
+func readBufferSize(config *Config) (int, error) {
+	if config.ReadBufferSize == "" {
+		return 0, nil
+	}
+	size, err := parseByteSize(config.ReadBufferSize)
+	if err != nil || size > maxReadBufferSize {
+		return 0, fmt.Errorf("invalid --read-buffer-size %q: expected a size up to 1GiB, e.g. 1MiB", config.ReadBufferSize)
+	}
+	return int(size), nil
+}
+
+// maxReadBufferSize bounds --read-buffer-size and --pipe-buffer-size
+const maxReadBufferSize = 1 << 30
+
+// scannerBuffer gives scanner a buffer of size bytes to read into. Lines up
+// to that long, and never less than the default 64KiB, fit.
+func scannerBuffer(scanner *bufio.Scanner, size int) {
+	if size <= 0 {
+		return
+	}
+	maxLine := size
+	if maxLine < bufio.MaxScanTokenSize {
+		maxLine = bufio.MaxScanTokenSize
+	}
+	scanner.Buffer(make([]byte, size), maxLine)
+}
+
+// resizePipes grows the wrapped command's output pipes to --pipe-buffer-size,
+// so a burst of output does not block the command while it is parsed. The
+// kernel caps unprivileged pipes at /proc/sys/fs/pipe-max-size; a pipe that
+// cannot be grown keeps working at its size.
+func resizePipes(config *Config, pipes ...*os.File) error {
+	if config.PipeBufferSize == "" {
+		return nil
+	}
+	size, err := parseByteSize(config.PipeBufferSize)
+	if err != nil || size > maxReadBufferSize {
+		return fmt.Errorf("invalid --pipe-buffer-size %q: expected a size up to 1GiB, e.g. 1MiB", config.PipeBufferSize)
+	}
+	for _, pipe := range pipes {
+		got, err := setPipeSize(pipe, int(size))
+		if err != nil {
+			logError("Failed to grow the command's pipe to %d bytes, keeping it at its size: %v\n", size, err)
+			continue
+		}
+		logInfo(config.Verbose, "Pipe buffer is %d bytes\n", got)
+	}
+	return nil
+}
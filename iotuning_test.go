@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadBufferSize(t *testing.T) {
+	for value, expected := range map[string]int{"": 0, "1MiB": 1 << 20, "256k": 256 << 10, "4096": 4096} {
+		got, err := readBufferSize(&Config{ReadBufferSize: value})
+		if err != nil || got != expected {
+			t.Errorf("Expected %d for %q, got %d (%v)", expected, value, got, err)
+		}
+	}
+	for _, bad := range []string{"0", "-1MiB", "lots", "2GiB"} {
+		if _, err := readBufferSize(&Config{ReadBufferSize: bad}); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestReadBufferLongLines(t *testing.T) {
+	line := strings.Repeat("x", 100*1024)
+	input := "short\n" + line + "\nafter\n"
+
+	var readErr error
+	for range multilineLogIteratorWith(strings.NewReader(input), defaultContinuationPattern, multilineOptions{err: &readErr}) {
+	}
+	if !errors.Is(readErr, bufio.ErrTooLong) {
+		t.Errorf("Expected a line over 64KiB to fail by default, got %v", readErr)
+	}
+
+	var got []string
+	for entry := range multilineLogIteratorWith(strings.NewReader(input), defaultContinuationPattern, multilineOptions{bufferSize: 128 * 1024, err: &readErr}) {
+		got = append(got, entry)
+	}
+	if readErr != nil || len(got) != 3 || got[1] != line {
+		t.Errorf("Expected the long line to fit a 128KiB buffer, got %d entries (%v)", len(got), readErr)
+	}
+}
+
+func TestResizePipes(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	got, err := setPipeSize(r, 256*1024)
+	if runtime.GOOS != "linux" {
+		if err == nil {
+			t.Error("Expected resizing pipes to be unsupported")
+		}
+		return
+	}
+	if err != nil || got < 256*1024 {
+		t.Fatalf("Expected the pipe to hold 256KiB, got %d (%v)", got, err)
+	}
+	// A full pipe of that size is written without a reader
+	if n, err := w.Write(make([]byte, 256*1024)); err != nil || n != 256*1024 {
+		t.Errorf("Expected to fill the pipe, wrote %d (%v)", n, err)
+	}
+
+	if err := resizePipes(&Config{PipeBufferSize: "huge"}, r); err == nil {
+		t.Error("Expected an invalid size to be rejected")
+	}
+}
+
+// benchmarkLines is the output of a chatty command, as JSON logs
+func benchmarkLines(lines int) []byte {
+	var out strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&out, `{"level":"info","msg":"request handled","request_id":"r-%08d","duration_ms":12}`+"\n", i)
+	}
+	return []byte(out.String())
+}
+
+// pipeInput writes data to a pipe of pipeSize bytes, or the default when 0,
+// in 4KiB writes like a command's stdio, and returns its read end and how
+// long the writer took
+func pipeInput(b *testing.B, data []byte, pipeSize int) (*os.File, <-chan time.Duration) {
+	b.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if pipeSize > 0 {
+		if _, err := setPipeSize(r, pipeSize); err != nil {
+			b.Skipf("Cannot resize pipes: %v", err)
+		}
+	}
+	took := make(chan time.Duration, 1)
+	go func() {
+		defer w.Close()
+		start := time.Now()
+		for rest := data; len(rest) > 0; {
+			chunk := rest[:min(len(rest), 4096)]
+			if _, err := w.Write(chunk); err != nil {
+				break
+			}
+			rest = rest[len(chunk):]
+		}
+		took <- time.Since(start)
+	}()
+	return r, took
+}
+
+// BenchmarkReadBuffer reads a fast writer's output through buffers of
+// --read-buffer-size; larger ones drain the pipe in fewer reads
+func BenchmarkReadBuffer(b *testing.B) {
+	const lines = 100000
+	data := benchmarkLines(lines)
+	for _, size := range []int{0, 64 * 1024, 1 << 20} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				r, _ := pipeInput(b, data, 0)
+				var n int
+				for range multilineLogIteratorWith(r, defaultContinuationPattern, multilineOptions{split: scanLinesCollapsingCR, bufferSize: size}) {
+					n++
+				}
+				r.Close()
+				if n != lines {
+					b.Fatalf("Expected %d entries, got %d", lines, n)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPipeBuffer parses a command's burst of output through pipes of
+// --pipe-buffer-size. The writer-ns/op metric is how long the command spent
+// writing it: with a larger pipe it blocks less on entries being parsed.
+func BenchmarkPipeBuffer(b *testing.B) {
+	data := benchmarkLines(10000)
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, size := range []int{0, 1 << 20} {
+		b.Run(fmt.Sprintf("pipe=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			var writer time.Duration
+			for i := 0; i < b.N; i++ {
+				r, took := pipeInput(b, data, size)
+				for entry := range multilineLogIteratorWith(r, defaultContinuationPattern, multilineOptions{split: scanLinesCollapsingCR}) {
+					extractor.ParseLogEntry(entry)
+				}
+				io.Copy(io.Discard, r)
+				r.Close()
+				writer += <-took
+			}
+			b.ReportMetric(float64(writer.Nanoseconds())/float64(b.N), "writer-ns/op")
+		})
+	}
+}
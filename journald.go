@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// journalctlCommand reads the journal; a variable so tests can stand in
+var journalctlCommand = "journalctl"
+
+// journaldCursorFile holds the cursor of the last exported journal entry in
+// --checkpoint-dir
+const journaldCursorFile = "journald.cursor"
+
+// journalAttributes are the journal fields exported as attributes, under
+// their semantic convention names where there is one. Other fields set by
+// journald itself, starting with _, are left out; fields set by the
+// application are exported as they are.
+var journalAttributes = map[string]string{
+	"_SYSTEMD_UNIT":      "systemd.unit",
+	"_SYSTEMD_USER_UNIT": "systemd.user_unit",
+	"SYSLOG_IDENTIFIER":  syslogAppNameKey,
+	"_HOSTNAME":          syslogHostnameKey,
+	"_MACHINE_ID":        "host.id",
+	"_BOOT_ID":           "journald.boot_id",
+	"_TRANSPORT":         "journald.transport",
+	"_COMM":              "process.executable.name",
+	"_EXE":               "process.executable.path",
+	"_CMDLINE":           "process.command_line",
+	"_UID":               "process.user.id",
+}
+
+// journalSkipped are application fields that repeat what the entry already
+// holds
+var journalSkipped = map[string]bool{
+	"MESSAGE":          true,
+	"PRIORITY":         true,
+	"SYSLOG_FACILITY":  true,
+	"SYSLOG_PID":       true,
+	"SYSLOG_TIMESTAMP": true,
+	"SYSLOG_RAW":       true,
+	"CODE_FILE":        true,
+	"CODE_LINE":        true,
+	"CODE_FUNC":        true,
+	"TID":              true,
+}
+
+// journalEntry converts an entry of journalctl's JSON output: MESSAGE is the
+// message, PRIORITY and SYSLOG_FACILITY the level and syslog.facility, the
+// PID, TID and CODE_* fields the process, thread and code location, and the
+// unit, host and process fields attributes
+func journalEntry(fields map[string]any) *LogEntry {
+	message, _ := journalString(fields["MESSAGE"])
+	entry := &LogEntry{
+		Fields:          make(map[string]any),
+		Raw:             message,
+		Message:         strings.TrimSpace(message),
+		Level:           "info",
+		Timestamp:       time.Now(),
+		TimestampSource: timestampSourceIngest,
+	}
+
+	if priority, ok := journalInt(fields["PRIORITY"]); ok && priority >= 0 && priority < len(syslogSeverityNames) {
+		entry.Level = syslogSeverityNames[priority]
+	}
+	if facility, ok := journalInt(fields["SYSLOG_FACILITY"]); ok && facility >= 0 && facility < len(syslogFacilityNames) {
+		entry.Fields["syslog.facility"] = syslogFacilityNames[facility]
+	}
+
+	// The time the entry was logged at, over when journald received it
+	for _, field := range []string{"_SOURCE_REALTIME_TIMESTAMP", "__REALTIME_TIMESTAMP"} {
+		value, _ := journalString(fields[field])
+		if t, ok := epochString(value, timestampMicroseconds); ok {
+			entry.Timestamp, entry.TimestampSource = t, timestampSourceRecord
+			break
+		}
+	}
+
+	pid, _ := journalInt(fields["_PID"])
+	tid, _ := journalInt(fields["TID"])
+	if pid > 0 || tid > 0 {
+		entry.Thread = &ThreadInfo{PID: pid, ThreadID: tid}
+	}
+	file, _ := journalString(fields["CODE_FILE"])
+	function, _ := journalString(fields["CODE_FUNC"])
+	line, _ := journalInt(fields["CODE_LINE"])
+	if file != "" || function != "" {
+		entry.Code = &CodeLocation{FilePath: file, LineNo: line, Function: function}
+	}
+
+	for field, value := range fields {
+		if key, ok := journalAttributes[field]; ok {
+			if n, ok := journalInt(value); ok && field == "_UID" {
+				entry.Fields[key] = n
+			} else if s, ok := journalString(value); ok {
+				entry.Fields[key] = s
+			}
+			continue
+		}
+		if strings.HasPrefix(field, "_") || journalSkipped[field] {
+			continue
+		}
+		if values, ok := value.([]any); ok && !isJournalBytes(values) {
+			// A field given more than once
+			strs := make([]any, 0, len(values))
+			for _, v := range values {
+				if s, ok := journalString(v); ok {
+					strs = append(strs, s)
+				}
+			}
+			entry.Fields[field] = strs
+		} else if s, ok := journalString(value); ok {
+			entry.Fields[field] = s
+		}
+	}
+	return entry
+}
+
+// journalString returns a field value, which journalctl writes as a string,
+// as an array of bytes when it is not valid UTF-8, or as an array of either
+// when the field is given more than once, in which case the last one counts
+func journalString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []any:
+		if isJournalBytes(v) {
+			data := make([]byte, len(v))
+			for i, b := range v {
+				data[i] = byte(b.(float64))
+			}
+			return string(data), true
+		}
+		if len(v) > 0 {
+			return journalString(v[len(v)-1])
+		}
+	}
+	return "", false
+}
+
+func isJournalBytes(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(float64); !ok {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func journalInt(value any) (int, bool) {
+	s, ok := journalString(value)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	return n, err == nil
+}
+
+// journalArgs are the arguments of journalctl: follow the journal as JSON
+// from after the saved cursor, or from now on
+func journalArgs(cursor string, matches []string) []string {
+	args := []string{"--output=json", "--follow", "--all"}
+	if cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	return append(args, matches...)
+}
+
+// processJournal follows the systemd journal through journalctl until
+// interrupted, saving the cursor of the exported entries with
+// --checkpoint-dir so a restart resumes after them
+func processJournal(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	if err := validateCheckpoints(config); err != nil {
+		return err
+	}
+	var cursorPath, cursor string
+	if config.CheckpointDir != "" {
+		if err := os.MkdirAll(config.CheckpointDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create checkpoint directory: %w", err)
+		}
+		cursorPath = filepath.Join(config.CheckpointDir, journaldCursorFile)
+		data, err := os.ReadFile(cursorPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read the journald cursor: %w", err)
+		}
+		cursor = strings.TrimSpace(string(data))
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd := exec.CommandContext(ctx, journalctlCommand, journalArgs(cursor, config.JournaldMatches)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to read journalctl: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start journalctl: %w", err)
+	}
+	if err := enterSandbox(config); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	var mu, saving sync.Mutex
+	saved := cursor
+	save := func(ctx context.Context) {
+		saving.Lock()
+		defer saving.Unlock()
+		mu.Lock()
+		current := cursor
+		mu.Unlock()
+		if cursorPath == "" || current == saved {
+			return
+		}
+		// The cursor only moves past entries once they are exported
+		if err := processor.Flush(ctx); err != nil {
+			logError("Failed to flush logs, not saving the journald cursor: %v\n", err)
+			return
+		}
+		if err := writeFileAtomic(cursorPath, []byte(current+"\n")); err != nil {
+			logError("Failed to save the journald cursor: %v\n", err)
+			return
+		}
+		saved = current
+	}
+	if cursorPath != "" {
+		ticker := time.NewTicker(config.CheckpointInterval)
+		defer ticker.Stop()
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					save(ctx)
+				}
+			}
+		}()
+	}
+
+	_, fieldMappings := extractor.snapshot()
+	decoder := json.NewDecoder(stdout)
+	var decodeErr error
+	for {
+		var fields map[string]any
+		if err := decoder.Decode(&fields); err != nil {
+			if !errors.Is(err, io.EOF) {
+				decodeErr = err
+			}
+			break
+		}
+		entry := journalEntry(fields)
+		entry.ReadAt = time.Now()
+		applySeverityMap(entry, fieldMappings.SeverityMap)
+		processor.ProcessLogEntry(ctx, entry)
+		if next, ok := journalString(fields["__CURSOR"]); ok {
+			mu.Lock()
+			cursor = next
+			mu.Unlock()
+		}
+	}
+	if decodeErr != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	interrupted := ctx.Err() != nil
+	// The entries read so far are exported and saved even when interrupted
+	save(context.WithoutCancel(ctx))
+
+	switch {
+	case interrupted:
+		return nil
+	case decodeErr != nil:
+		return fmt.Errorf("failed to read journalctl output: %w", decodeErr)
+	case waitErr != nil:
+		return fmt.Errorf("journalctl failed: %w", waitErr)
+	}
+	return nil
+}
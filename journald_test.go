@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalEntry(t *testing.T) {
+	entry := journalEntry(map[string]any{
+		"MESSAGE":                    "Started nginx",
+		"PRIORITY":                   "3",
+		"SYSLOG_FACILITY":            "3",
+		"SYSLOG_IDENTIFIER":          "nginx",
+		"_SYSTEMD_UNIT":              "nginx.service",
+		"_HOSTNAME":                  "web-1",
+		"_PID":                       "1234",
+		"_UID":                       "33",
+		"_SOURCE_REALTIME_TIMESTAMP": "1705315845123456",
+		"__REALTIME_TIMESTAMP":       "1705315846000000",
+		"__CURSOR":                   "s=abc",
+		"_CAP_EFFECTIVE":             "0",
+		"CODE_FILE":                  "src/main.c",
+		"CODE_LINE":                  "42",
+		"CODE_FUNC":                  "main",
+		"REQUEST_ID":                 "r-1",
+		"TAG":                        []any{"a", "b"},
+	})
+
+	if entry.Message != "Started nginx" || entry.Level != "err" {
+		t.Errorf("Expected an err record with the message, got %q at %q", entry.Message, entry.Level)
+	}
+	if !entry.Timestamp.Equal(time.UnixMicro(1705315845123456)) || entry.TimestampSource != timestampSourceRecord {
+		t.Errorf("Expected the source timestamp, got %v (%s)", entry.Timestamp, entry.TimestampSource)
+	}
+	if entry.Thread == nil || entry.Thread.PID != 1234 {
+		t.Errorf("Expected process.pid 1234, got %+v", entry.Thread)
+	}
+	if entry.Code == nil || *entry.Code != (CodeLocation{FilePath: "src/main.c", LineNo: 42, Function: "main"}) {
+		t.Errorf("Expected the code location, got %+v", entry.Code)
+	}
+	expected := map[string]any{
+		"syslog.facility": "daemon",
+		"syslog.appname":  "nginx",
+		"systemd.unit":    "nginx.service",
+		"host.name":       "web-1",
+		"process.user.id": 33,
+		"REQUEST_ID":      "r-1",
+		"TAG":             []any{"a", "b"},
+	}
+	if !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, entry.Fields)
+	}
+
+	// Not valid UTF-8, and without a timestamp
+	entry = journalEntry(map[string]any{"MESSAGE": []any{float64('h'), float64('i'), float64(0xff)}})
+	if entry.Message != "hi\xff" || entry.Level != "info" || entry.TimestampSource != timestampSourceIngest {
+		t.Errorf("Expected a byte array message at info, got %q at %q (%s)", entry.Message, entry.Level, entry.TimestampSource)
+	}
+}
+
+// fakeJournalctl stands in for journalctl with a script that records its
+// arguments, prints the given entries and waits to be stopped
+func fakeJournalctl(t *testing.T, entries ...string) (argsPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsPath = filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsPath + "\ncat <<'EOF'\n" + strings.Join(entries, "\n") + "\nEOF\nexec sleep 30\n"
+	path := filepath.Join(dir, "journalctl")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	previous := journalctlCommand
+	journalctlCommand = path
+	t.Cleanup(func() { journalctlCommand = previous })
+	return argsPath
+}
+
+func TestProcessJournal(t *testing.T) {
+	argsPath := fakeJournalctl(t,
+		`{"__CURSOR":"s=1","MESSAGE":"first","PRIORITY":"6","__REALTIME_TIMESTAMP":"1705315845000000"}`,
+		`{"__CURSOR":"s=2","MESSAGE":"second","PRIORITY":"4","__REALTIME_TIMESTAMP":"1705315846000000"}`,
+	)
+	config := &Config{
+		Journald:           true,
+		JournaldMatches:    []string{"_SYSTEMD_UNIT=nginx.service"},
+		CheckpointDir:      t.TempDir(),
+		CheckpointInterval: time.Hour,
+	}
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	follow := func() []string {
+		t.Helper()
+		provider, exporter := newRecordingProvider()
+		processor := NewLogProcessor(provider.Logger("test"))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- processJournal(ctx, config, extractor, processor) }()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(exporter.Records()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var messages []string
+		for _, record := range exporter.Records() {
+			messages = append(messages, record.Body().AsString()+"@"+record.SeverityText())
+		}
+		args, _ := os.ReadFile(argsPath)
+		return append(messages, strings.TrimSpace(string(args)))
+	}
+
+	got := follow()
+	expected := []string{"first@info", "second@warning", "--output=json --follow --all --lines=0 _SYSTEMD_UNIT=nginx.service"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	cursor, err := os.ReadFile(filepath.Join(config.CheckpointDir, journaldCursorFile))
+	if err != nil || string(cursor) != "s=2\n" {
+		t.Errorf("Expected the cursor of the last entry to be saved, got %q (%v)", cursor, err)
+	}
+
+	if got := follow(); got[len(got)-1] != "--output=json --follow --all --after-cursor=s=2 _SYSTEMD_UNIT=nginx.service" {
+		t.Errorf("Expected a restart to resume after the saved cursor, got %q", got[len(got)-1])
+	}
+}
+
+func TestProcessJournalFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journalctl")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho 'No journal files were found.' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	previous := journalctlCommand
+	journalctlCommand = path
+	defer func() { journalctlCommand = previous }()
+
+	processor := NewLogProcessor(nil)
+	err := processJournal(context.Background(), &Config{Journald: true}, NewJSONExtractor("", getDefaultFieldMappings()), processor)
+	if err == nil || !strings.Contains(err.Error(), "journalctl failed") {
+		t.Errorf("Expected journalctl failing to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attributes of records read with --format jvm
+const (
+	jvmUptimeKey     = "jvm.uptime_ms"
+	jvmGCIDKey       = "jvm.gc.id"
+	jvmGCPhaseKey    = "jvm.gc.phase"
+	jvmGCCauseKey    = "jvm.gc.cause"
+	jvmGCPauseKey    = "jvm.gc.pause_ms"    // for stop-the-world pauses
+	jvmGCDurationKey = "jvm.gc.duration_ms" // for concurrent phases
+	jvmHeapBeforeKey = "jvm.gc.heap.before_bytes"
+	jvmHeapAfterKey  = "jvm.gc.heap.after_bytes"
+	jvmHeapCommitKey = "jvm.gc.heap.committed_bytes"
+)
+
+// jvmTimeMillisFrom tells the timemillis decoration from uptimemillis, as
+// both end in ms
+const jvmTimeMillisFrom = 1e12
+
+var (
+	jvmDecorations = regexp.MustCompile(`^((?:\[[^\]\[]*\])+) ?(.*)$`)
+	jvmTags        = regexp.MustCompile(`^[a-z0-9]+(?:,[a-z0-9]+)*$`)
+	jvmGCID        = regexp.MustCompile(`^GC\((\d+)\) (.*)$`)
+	jvmGCDuration  = regexp.MustCompile(` (\d+(?:\.\d+)?)ms$`)
+	jvmGCHeap      = regexp.MustCompile(` (\d+)([BKMGT])(?:\(\d+%\))?->(\d+)([BKMGT])(?:\(\d+%\))?(?:\((\d+)([BKMGT])\))?$`)
+)
+
+// jvmLevels are the levels of unified logging, as -Xlog writes them
+var jvmLevels = map[string]string{
+	"trace":   "trace",
+	"debug":   "debug",
+	"info":    "info",
+	"warning": "warn",
+	"error":   "error",
+}
+
+// jvmSizeUnits are the multipliers of the heap sizes in GC lines
+var jvmSizeUnits = map[string]int{"B": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// ParseJVMEntry decodes a line of JVM unified logging (-Xlog, Java 9 and
+// later) such as "[0.123s][info][gc] GC(3) Pause Young (Normal) (G1
+// Evacuation Pause) 24M->4M(256M) 3.456ms". The time, uptime, level, tags
+// and pid/tid decorations are read in any combination; the tags become the
+// logger name. GC lines add the GC ID, phase, cause, pause or phase duration
+// and heap sizes before and after as typed jvm.gc.* attributes. Lines that
+// are not unified logging become plain messages.
+func (je *JSONExtractor) ParseJVMEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseJVM(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseJVM(line string) (*LogEntry, bool) {
+	m := jvmDecorations.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	entry := &LogEntry{Fields: make(map[string]any), Raw: line, Message: strings.TrimSpace(m[2])}
+	for _, decoration := range strings.Split(strings.Trim(m[1], "[]"), "][") {
+		if !setJVMDecoration(entry, strings.TrimSpace(decoration)) {
+			return nil, false
+		}
+	}
+	if entry.Level == "" && entry.LoggerName == "" {
+		return nil, false
+	}
+	if entry.Level == "" {
+		entry.Level = "info"
+	}
+	if entry.Timestamp.IsZero() {
+		je.stampIngestTime(entry)
+	}
+	parseJVMGC(entry)
+	return entry, true
+}
+
+// setJVMDecoration stores one [...] decoration, reporting whether it is one
+// that -Xlog writes
+func setJVMDecoration(entry *LogEntry, decoration string) bool {
+	if level, ok := jvmLevels[decoration]; ok {
+		entry.Level = level
+		return true
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05.000-0700", decoration); err == nil {
+		entry.Timestamp = t
+		return true
+	}
+	if seconds, ok := strings.CutSuffix(decoration, "s"); ok && strings.Contains(seconds, ".") {
+		if f, err := strconv.ParseFloat(seconds, 64); err == nil {
+			entry.Fields[jvmUptimeKey] = f * 1000
+			return true
+		}
+	}
+	if ms, ok := strings.CutSuffix(decoration, "ms"); ok {
+		if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			if n >= jvmTimeMillisFrom {
+				entry.Timestamp = time.UnixMilli(n)
+			} else {
+				entry.Fields[jvmUptimeKey] = float64(n)
+			}
+			return true
+		}
+	}
+	if ns, ok := strings.CutSuffix(decoration, "ns"); ok {
+		if n, err := strconv.ParseInt(ns, 10, 64); err == nil {
+			if n >= jvmTimeMillisFrom*1e6 {
+				entry.Timestamp = time.Unix(0, n)
+			} else {
+				entry.Fields[jvmUptimeKey] = float64(n) / 1e6
+			}
+			return true
+		}
+	}
+	if n, err := strconv.Atoi(decoration); err == nil {
+		// pid comes before tid when both are decorations
+		if entry.Thread == nil {
+			entry.Thread = &ThreadInfo{PID: n}
+		} else {
+			entry.Thread.ThreadID = n
+		}
+		return true
+	}
+	if jvmTags.MatchString(decoration) {
+		entry.LoggerName = decoration
+		return true
+	}
+	return false
+}
+
+// parseJVMGC reads the attributes of a "GC(n) ..." message. The duration and
+// heap sizes come last; what precedes them is the phase, and its last
+// parenthesized part the cause, as in "Pause Young (Normal) (G1 Evacuation
+// Pause)". Other lines of a collection, such as "GC(3) Eden regions: ...",
+// only get the ID.
+func parseJVMGC(entry *LogEntry) {
+	m := jvmGCID.FindStringSubmatch(entry.Message)
+	if m == nil {
+		return
+	}
+	id, _ := strconv.Atoi(m[1])
+	entry.Fields[jvmGCIDKey] = id
+
+	rest := " " + m[2]
+	duration, measured := -1.0, false
+	if d := jvmGCDuration.FindStringSubmatch(rest); d != nil {
+		duration, _ = strconv.ParseFloat(d[1], 64)
+		rest, measured = rest[:len(rest)-len(d[0])], true
+	}
+	if h := jvmGCHeap.FindStringSubmatch(rest); h != nil {
+		setJVMSize(entry, jvmHeapBeforeKey, h[1], h[2])
+		setJVMSize(entry, jvmHeapAfterKey, h[3], h[4])
+		setJVMSize(entry, jvmHeapCommitKey, h[5], h[6])
+		rest, measured = rest[:len(rest)-len(h[0])], true
+	}
+	phase := strings.TrimSpace(rest)
+	if !measured && !strings.HasPrefix(phase, "Pause ") {
+		return
+	}
+
+	if cause, before, ok := jvmGCCause(phase); ok {
+		entry.Fields[jvmGCCauseKey] = cause
+		phase = before
+	}
+	entry.Fields[jvmGCPhaseKey] = phase
+	if duration >= 0 {
+		if strings.HasPrefix(phase, "Pause ") {
+			entry.Fields[jvmGCPauseKey] = duration
+		} else {
+			entry.Fields[jvmGCDurationKey] = duration
+		}
+	}
+}
+
+// jvmGCCause splits the last parenthesized part, which may itself contain
+// parentheses as in "(System.gc())", off a GC phase
+func jvmGCCause(phase string) (cause, before string, ok bool) {
+	if !strings.HasSuffix(phase, ")") {
+		return "", phase, false
+	}
+	depth := 0
+	for i := len(phase) - 1; i >= 0; i-- {
+		switch phase[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				before = strings.TrimSpace(phase[:i])
+				if before == "" {
+					return "", phase, false
+				}
+				return phase[i+1 : len(phase)-1], before, true
+			}
+		}
+	}
+	return "", phase, false
+}
+
+// setJVMSize stores a heap size such as 24M in bytes
+func setJVMSize(entry *LogEntry, key, value, unit string) {
+	if n, err := strconv.Atoi(value); err == nil {
+		entry.Fields[key] = n * jvmSizeUnits[unit]
+	}
+}
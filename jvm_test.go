@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJVMEntry(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatJVM
+	extractor := NewJSONExtractor("", mappings)
+
+	tests := []struct {
+		name   string
+		line   string
+		level  string
+		logger string
+		fields map[string]any
+	}{
+		{
+			name:   "G1 young pause",
+			line:   "[0.123s][info][gc] GC(3) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms",
+			level:  "info",
+			logger: "gc",
+			fields: map[string]any{
+				"jvm.uptime_ms":               123.0,
+				"jvm.gc.id":                   3,
+				"jvm.gc.phase":                "Pause Young (Normal)",
+				"jvm.gc.cause":                "G1 Evacuation Pause",
+				"jvm.gc.pause_ms":             3.456,
+				"jvm.gc.heap.before_bytes":    24 << 20,
+				"jvm.gc.heap.after_bytes":     4 << 20,
+				"jvm.gc.heap.committed_bytes": 256 << 20,
+			},
+		},
+		{
+			name:   "full pause with nested cause",
+			line:   "[12.5s][warning][gc] GC(9) Pause Full (System.gc()) 1G->512M(2G) 120.0ms",
+			level:  "warn",
+			logger: "gc",
+			fields: map[string]any{
+				"jvm.uptime_ms":               12500.0,
+				"jvm.gc.id":                   9,
+				"jvm.gc.phase":                "Pause Full",
+				"jvm.gc.cause":                "System.gc()",
+				"jvm.gc.pause_ms":             120.0,
+				"jvm.gc.heap.before_bytes":    1 << 30,
+				"jvm.gc.heap.after_bytes":     512 << 20,
+				"jvm.gc.heap.committed_bytes": 2 << 30,
+			},
+		},
+		{
+			name:   "concurrent phase",
+			line:   "[1.000s][info][gc,marking   ] GC(4) Concurrent Mark Cycle 12.3ms",
+			level:  "info",
+			logger: "gc,marking",
+			fields: map[string]any{
+				"jvm.uptime_ms":      1000.0,
+				"jvm.gc.id":          4,
+				"jvm.gc.phase":       "Concurrent Mark Cycle",
+				"jvm.gc.duration_ms": 12.3,
+			},
+		},
+		{
+			name:   "ZGC without a committed size",
+			line:   "[2.0s][info][gc] GC(0) Garbage Collection (Warmup) 34M(1%)->12M(0%)",
+			level:  "info",
+			logger: "gc",
+			fields: map[string]any{
+				"jvm.uptime_ms":            2000.0,
+				"jvm.gc.id":                0,
+				"jvm.gc.phase":             "Garbage Collection",
+				"jvm.gc.cause":             "Warmup",
+				"jvm.gc.heap.before_bytes": 34 << 20,
+				"jvm.gc.heap.after_bytes":  12 << 20,
+			},
+		},
+		{
+			name:   "other GC line",
+			line:   "[0.124s][info][gc,heap] GC(3) Eden regions: 24->0(150)",
+			level:  "info",
+			logger: "gc,heap",
+			fields: map[string]any{"jvm.uptime_ms": 124.0, "jvm.gc.id": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Level != tt.level || entry.LoggerName != tt.logger {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.level, tt.logger, entry.Level, entry.LoggerName)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.fields) {
+				t.Errorf("Expected fields %v, got %v", tt.fields, entry.Fields)
+			}
+		})
+	}
+}
+
+func TestJVMDecorations(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatJVM
+	extractor := NewJSONExtractor("", mappings)
+
+	entry, _ := extractor.ParseLogEntry("[1705314645123ms][4242][4243][debug][safepoint] Safepoint \"Cleanup\"")
+	if !entry.Timestamp.Equal(time.UnixMilli(1705314645123)) {
+		t.Errorf("Expected timemillis to set the timestamp, got %v", entry.Timestamp)
+	}
+	if entry.Thread == nil || entry.Thread.PID != 4242 || entry.Thread.ThreadID != 4243 {
+		t.Errorf("Expected pid and tid, got %+v", entry.Thread)
+	}
+	if entry.Level != "debug" || entry.Message != `Safepoint "Cleanup"` {
+		t.Errorf("Expected debug message, got %s/%q", entry.Level, entry.Message)
+	}
+
+	for _, line := range []string{"[INFO] Building project", "Exception in thread \"main\""} {
+		entry, _ := extractor.ParseLogEntry(line)
+		if entry.Message != line || entry.LoggerName != "" {
+			t.Errorf("Expected %q to be a plain message, got %+v", line, entry)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// k8sServiceAccountDir holds the credentials of a pod's service account; a
+// variable so tests can stand in
+var k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// k8sPollInterval is how often the pods are fetched for containers that
+// started or restarted
+var k8sPollInterval = 2 * time.Second
+
+// Attributes of records read from pods
+const (
+	k8sNamespaceKey = "k8s.namespace.name"
+	k8sPodNameKey   = "k8s.pod.name"
+	k8sPodUIDKey    = "k8s.pod.uid"
+	k8sContainerKey = "k8s.container.name"
+)
+
+// errK8sNotFound is returned for pods the API server does not know
+var errK8sNotFound = errors.New("no such pod")
+
+// k8sClient talks to the Kubernetes API, in the cluster with the pod's
+// service account or through --k8s-api, e.g. kubectl proxy
+type k8sClient struct {
+	http      *http.Client
+	base      string
+	tokenPath string // read for every request, as projected tokens rotate
+}
+
+func newK8sClient(api string) (*k8sClient, error) {
+	if api != "" {
+		u, err := url.Parse(api)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid --k8s-api %q: expected http://HOST:PORT or https://HOST:PORT", api)
+		}
+		return &k8sClient{http: &http.Client{}, base: strings.TrimSuffix(api, "/")}, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes pod; point --k8s-api at the API server, e.g. kubectl proxy's http://127.0.0.1:8001")
+	}
+	ca, err := os.ReadFile(filepath.Join(k8sServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid service account CA")
+	}
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}
+	return &k8sClient{
+		http:      &http.Client{Transport: transport},
+		base:      "https://" + net.JoinHostPort(host, port),
+		tokenPath: filepath.Join(k8sServiceAccountDir, "token"),
+	}, nil
+}
+
+// k8sNamespace is --k8s-namespace, or the pod's own namespace in the
+// cluster, or default
+func k8sNamespace(config *Config) string {
+	if config.K8sNamespace != "" {
+		return config.K8sNamespace
+	}
+	if data, err := os.ReadFile(filepath.Join(k8sServiceAccountDir, "namespace")); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return "default"
+}
+
+// get requests an API path, returning errK8sNotFound for a 404 and the
+// API server's message for other errors
+func (c *k8sClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.tokenPath != "" {
+		token, err := os.ReadFile(c.tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the Kubernetes API: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errK8sNotFound
+	}
+	var status struct{ Message string }
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&status)
+	return nil, fmt.Errorf("kubernetes API %s: %s %s", path, resp.Status, status.Message)
+}
+
+// k8sPod is what records are tagged with and which containers run
+type k8sPod struct {
+	Namespace  string
+	Name       string
+	UID        string
+	Containers []string
+	Running    map[string]bool // by container
+}
+
+type k8sPodObject struct {
+	Metadata struct {
+		Namespace string
+		Name      string
+		UID       string
+	}
+	Spec struct {
+		Containers []struct{ Name string }
+	}
+	Status struct {
+		ContainerStatuses []struct {
+			Name  string
+			State struct {
+				Running *struct{}
+			}
+		}
+	}
+}
+
+func (o k8sPodObject) pod() k8sPod {
+	pod := k8sPod{
+		Namespace: o.Metadata.Namespace,
+		Name:      o.Metadata.Name,
+		UID:       o.Metadata.UID,
+		Running:   make(map[string]bool),
+	}
+	for _, container := range o.Spec.Containers {
+		pod.Containers = append(pod.Containers, container.Name)
+	}
+	for _, status := range o.Status.ContainerStatuses {
+		pod.Running[status.Name] = status.State.Running != nil
+	}
+	return pod
+}
+
+func (c *k8sClient) pod(ctx context.Context, namespace, name string) (k8sPod, error) {
+	resp, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods/"+url.PathEscape(name), nil)
+	if err != nil {
+		return k8sPod{}, err
+	}
+	defer resp.Body.Close()
+	var object k8sPodObject
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return k8sPod{}, err
+	}
+	return object.pod(), nil
+}
+
+func (c *k8sClient) pods(ctx context.Context, namespace, selector string) ([]k8sPod, error) {
+	resp, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods", url.Values{"labelSelector": {selector}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var list struct{ Items []k8sPodObject }
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	pods := make([]k8sPod, 0, len(list.Items))
+	for _, object := range list.Items {
+		pods = append(pods, object.pod())
+	}
+	return pods, nil
+}
+
+// logs follows a container's output with each line prefixed by the time
+// the kubelet received it, from since on to the second, or from its start
+// when since is zero
+func (c *k8sClient) logs(ctx context.Context, pod k8sPod, container string, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{"container": {container}, "follow": {"true"}, "timestamps": {"true"}}
+	if !since.IsZero() {
+		query.Set("sinceTime", since.UTC().Format(time.RFC3339))
+	}
+	resp, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(pod.Namespace)+"/pods/"+url.PathEscape(pod.Name)+"/log", query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// k8sLines strips the kubelet's timestamps from the lines of r, writing
+// those after since to w, and returns the last timestamp seen
+func k8sLines(r io.Reader, w io.Writer, since time.Time) (time.Time, error) {
+	last := since
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			stamp, rest, ok := strings.Cut(line, " ")
+			t, parseErr := time.Parse(time.RFC3339Nano, stamp)
+			switch {
+			case !ok || parseErr != nil:
+				// Not prefixed, as when the line was cut short
+				rest = line
+			case !t.After(since):
+				// Already read before the container restarted
+				rest = ""
+			default:
+				last = t
+			}
+			if _, werr := io.WriteString(w, rest); werr != nil {
+				return last, werr
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return last, nil
+			}
+			return last, err
+		}
+	}
+}
+
+// k8sSource reads the logs of pods' containers, following them across
+// restarts
+type k8sSource struct {
+	client     *k8sClient
+	extractor  *JSONExtractor
+	processor  *LogProcessor
+	opts       streamOptions
+	containers []string // --k8s-container, all when empty
+}
+
+// follow reads a running container's logs until it stops, returning when
+// the last line read was logged
+func (s *k8sSource) follow(ctx context.Context, pod k8sPod, container string, since time.Time) (time.Time, error) {
+	body, err := s.client.logs(ctx, pod, container, since)
+	if err != nil {
+		return since, err
+	}
+	defer body.Close()
+
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.export(ctx, r, pod, container)
+		io.Copy(io.Discard, r)
+	}()
+	last, err := k8sLines(body, w, since)
+	w.Close()
+	<-done
+	if ctx.Err() != nil {
+		return last, nil
+	}
+	return last, err
+}
+
+// export parses the lines of a container, tagged with its pod
+func (s *k8sSource) export(ctx context.Context, r io.Reader, pod k8sPod, container string) {
+	var readErr error
+	for logEntry, readAt := range multilineLogIteratorWith(r, s.opts.continuationPattern, multilineOptions{split: s.opts.split, bufferSize: s.opts.readBuffer, err: &readErr}) {
+		entry, err := s.extractor.ParseLogEntry(logEntry)
+		if err != nil {
+			logError("Error parsing log entry from %s/%s: %v\n", pod.Name, container, err)
+			continue
+		}
+		entry.ReadAt = readAt
+		for key, value := range map[string]string{k8sNamespaceKey: pod.Namespace, k8sPodNameKey: pod.Name, k8sPodUIDKey: pod.UID, k8sContainerKey: container} {
+			if _, ok := entry.Fields[key]; !ok && entry.FieldOrder != nil {
+				entry.FieldOrder = append(entry.FieldOrder, key)
+			}
+			entry.Fields[key] = value
+		}
+		s.processor.ProcessLogEntry(ctx, entry)
+	}
+}
+
+// run follows the running containers of the pods list returns. Containers
+// running at startup are followed from now on, ones started later from
+// their start, and restarted ones from the last line read.
+func (s *k8sSource) run(ctx context.Context, list func(context.Context) ([]k8sPod, error)) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	active := make(map[string]bool)
+	resume := make(map[string]time.Time) // by pod/container, once stopped
+
+	start := time.Now()
+	for first := true; ctx.Err() == nil; first = false {
+		pods, err := list(ctx)
+		if err != nil && ctx.Err() == nil {
+			logError("Error listing pods: %v\n", err)
+		}
+		mu.Lock()
+		seen := make(map[string]bool)
+		for _, pod := range pods {
+			for _, container := range pod.Containers {
+				if len(s.containers) > 0 && !slices.Contains(s.containers, container) {
+					continue
+				}
+				key := pod.Name + "/" + container
+				seen[key] = true
+				if !pod.Running[container] || active[key] {
+					continue
+				}
+				since, restarted := resume[key]
+				if first {
+					since = start
+				} else if !restarted {
+					since = time.Time{}
+				}
+				active[key] = true
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					last, err := s.follow(ctx, pod, container, since)
+					if err != nil {
+						logError("Error reading logs of %s: %v\n", key, err)
+					}
+					mu.Lock()
+					delete(active, key)
+					resume[key] = last
+					mu.Unlock()
+				}()
+			}
+		}
+		if err == nil {
+			for key := range resume {
+				if !seen[key] && !active[key] {
+					// The pod is gone
+					delete(resume, key)
+				}
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(k8sPollInterval):
+		}
+	}
+	wg.Wait()
+}
+
+// processK8s follows the --k8s-pod pods, or those matching --k8s-selector,
+// until interrupted
+func processK8s(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	if len(config.K8sPods) > 0 && config.K8sSelector != "" {
+		return errors.New("--k8s-pod cannot be combined with --k8s-selector")
+	}
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
+	if err != nil {
+		return fmt.Errorf("failed to compile continuation pattern: %w", err)
+	}
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+	client, err := newK8sClient(config.K8sAPI)
+	if err != nil {
+		return err
+	}
+	namespace := k8sNamespace(config)
+
+	list := func(ctx context.Context) ([]k8sPod, error) {
+		return client.pods(ctx, namespace, config.K8sSelector)
+	}
+	if len(config.K8sPods) > 0 {
+		list = func(ctx context.Context) ([]k8sPod, error) {
+			var pods []k8sPod
+			for _, name := range config.K8sPods {
+				pod, err := client.pod(ctx, namespace, name)
+				if errors.Is(err, errK8sNotFound) {
+					// Not created yet, or being replaced
+					continue
+				} else if err != nil {
+					return pods, err
+				}
+				pods = append(pods, pod)
+			}
+			return pods, nil
+		}
+	}
+	// Fail early on credentials or permissions rather than retrying forever
+	if _, err := list(ctx); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := enterSandbox(config); err != nil {
+		return err
+	}
+
+	s := &k8sSource{
+		client:     client,
+		extractor:  extractor,
+		processor:  processor,
+		opts:       streamOptions{continuationPattern: continuationPattern, split: split, readBuffer: readBuffer},
+		containers: config.K8sContainers,
+	}
+	s.run(ctx, list)
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestK8sLines(t *testing.T) {
+	since := time.Date(2024, 1, 15, 10, 30, 0, 500, time.UTC)
+	input := "2024-01-15T10:30:00.0000005Z already read\n" +
+		"2024-01-15T10:30:00.000001Z {\"msg\":\"new\"}\n" +
+		"continued without a stamp\n" +
+		"2024-01-15T10:30:02Z last"
+	var out bytes.Buffer
+	last, err := k8sLines(strings.NewReader(input), &out, since)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != "{\"msg\":\"new\"}\ncontinued without a stamp\nlast" {
+		t.Errorf("Expected the stamps stripped and the line already read skipped, got %q", out.String())
+	}
+	if !last.Equal(time.Date(2024, 1, 15, 10, 30, 2, 0, time.UTC)) {
+		t.Errorf("Expected the last stamp, got %v", last)
+	}
+}
+
+// fakeK8s stands in for the Kubernetes API with a pod web-0 whose app
+// container runs, stops and is restarted, recording its logs requests
+type fakeK8s struct {
+	token string // required when set
+
+	mu       sync.Mutex
+	running  bool
+	restarts int
+	lines    []string // stamped lines the app has logged
+	logs     []string // sinceTime of each logs request
+}
+
+func (k *fakeK8s) pod() map[string]any {
+	state := map[string]any{"terminated": map[string]any{}}
+	if k.running {
+		state = map[string]any{"running": map[string]any{}}
+	}
+	return map[string]any{
+		"metadata": map[string]any{"namespace": "prod", "name": "web-0", "uid": "u-1"},
+		"spec":     map[string]any{"containers": []map[string]any{{"name": "app"}, {"name": "sidecar"}}},
+		"status": map[string]any{"containerStatuses": []map[string]any{
+			{"name": "app", "state": state},
+			{"name": "sidecar", "state": map[string]any{"running": map[string]any{}}},
+		}},
+	}
+}
+
+// restart starts the app again once it has been seen stopped
+func (k *fakeK8s) restart() {
+	if !k.running && k.restarts < 1 {
+		k.restarts++
+		k.running = true
+	}
+}
+
+func (k *fakeK8s) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.token != "" && r.Header.Get("Authorization") != "Bearer "+k.token {
+		http.Error(w, `{"message":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/api/v1/namespaces/prod/pods/web-0":
+		json.NewEncoder(w).Encode(k.pod())
+		k.restart()
+	case r.URL.Path == "/api/v1/namespaces/prod/pods" && r.URL.Query().Get("labelSelector") == "app=web":
+		json.NewEncoder(w).Encode(map[string]any{"items": []any{k.pod()}})
+		k.restart()
+	case r.URL.Path == "/api/v1/namespaces/prod/pods/web-0/log" && r.URL.Query().Get("container") == "app":
+		k.logs = append(k.logs, r.URL.Query().Get("sinceTime"))
+		stamp := time.Now().UTC().Format(time.RFC3339Nano)
+		if len(k.logs) == 1 {
+			k.lines = append(k.lines, stamp+` {"level":"info","msg":"started"}`)
+		} else {
+			k.lines = append(k.lines, stamp+` {"level":"warn","msg":"restarted"}`)
+		}
+		// The log of the container's previous run is sent again
+		w.Write([]byte(strings.Join(k.lines, "\n") + "\n"))
+		k.running = false
+	default:
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+	}
+}
+
+func TestProcessK8s(t *testing.T) {
+	previous := k8sPollInterval
+	k8sPollInterval = 10 * time.Millisecond
+	defer func() { k8sPollInterval = previous }()
+
+	// In the cluster, over TLS with the service account
+	inCluster := &fakeK8s{token: "secret", running: true}
+	secure := httptest.NewTLSServer(inCluster)
+	defer secure.Close()
+	accountDir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: secure.Certificate().Raw})
+	for name, data := range map[string]string{"ca.crt": string(ca), "token": "secret\n", "namespace": "prod"} {
+		if err := os.WriteFile(filepath.Join(accountDir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previousDir := k8sServiceAccountDir
+	k8sServiceAccountDir = accountDir
+	defer func() { k8sServiceAccountDir = previousDir }()
+	host, port, _ := net.SplitHostPort(secure.Listener.Addr().String())
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	// Through kubectl proxy
+	proxied := &fakeK8s{running: true}
+	proxy := httptest.NewServer(proxied)
+	defer proxy.Close()
+
+	for _, test := range []struct {
+		api    *fakeK8s
+		config *Config
+	}{
+		{inCluster, &Config{K8sPods: []string{"web-0"}, K8sContainers: []string{"app"}, ContinuationPattern: `^[ \t]`}},
+		{proxied, &Config{K8sSelector: "app=web", K8sNamespace: "prod", K8sContainers: []string{"app"}, K8sAPI: proxy.URL, ContinuationPattern: `^[ \t]`}},
+	} {
+		provider, exporter := newRecordingProvider()
+		processor := NewLogProcessor(provider.Logger("test"))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- processK8s(ctx, test.config, NewJSONExtractor("", getDefaultFieldMappings()), processor)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(exporter.Records()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// Nothing more is exported once the restart is read
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, record := range exporter.Records() {
+			attrs := recordAttributes(record)
+			got = append(got, fmt.Sprintf("%s@%s %s/%s/%s/%s", record.Body().AsString(), record.SeverityText(),
+				attrs["k8s.namespace.name"], attrs["k8s.pod.name"], attrs["k8s.pod.uid"], attrs["k8s.container.name"]))
+		}
+		expected := []string{"started@info prod/web-0/u-1/app", "restarted@warn prod/web-0/u-1/app"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+		test.api.mu.Lock()
+		if len(test.api.logs) != 2 || test.api.logs[0] == "" || test.api.logs[1] == "" {
+			t.Errorf("Expected both reads to start from a point in time, got %q", test.api.logs)
+		}
+		test.api.mu.Unlock()
+	}
+}
+
+func TestProcessK8sErrors(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, config := range []*Config{
+		{K8sPods: []string{"web-0"}, K8sSelector: "app=web"},
+		{K8sPods: []string{"web-0"}},
+		{K8sPods: []string{"web-0"}, K8sAPI: "127.0.0.1:8001"},
+	} {
+		if err := processK8s(context.Background(), config, extractor, nil); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+
+	// Missing permissions are reported rather than retried
+	api := &fakeK8s{token: "other"}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	err := processK8s(context.Background(), &Config{K8sSelector: "app=web", K8sNamespace: "prod", K8sAPI: server.URL}, extractor, nil)
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected the API error to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseKVEntry decodes a line of free text followed by key=value pairs, such
+// as `Pod status updated pod="default/nginx" status=Running` from kubelet,
+// HAProxy or Postfix. The pairs are read like logfmt and the text before them
+// becomes the message, unless a pair is itself a message field. Lines without
+// a key=value tail become plain messages.
+func (je *JSONExtractor) ParseKVEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseKV(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseKV(line string) (*LogEntry, bool) {
+	_, fieldMappings := je.snapshot()
+	decode := func(s string) (map[string]any, bool) {
+		text, pairs, ok := splitKVTail(s)
+		if !ok {
+			return nil, false
+		}
+		fields := make(map[string]any, len(pairs)+1)
+		for _, pair := range pairs {
+			fields[pair.key] = pair.value
+		}
+		if text != "" && len(fieldMappings.MessageFields) > 0 && !hasAnyField(fields, fieldMappings.MessageFields) {
+			fields[fieldMappings.MessageFields[0]] = text
+		}
+		return fields, true
+	}
+	keyOrder := func(s string) []string {
+		_, pairs, _ := splitKVTail(s)
+		keys := make([]string, len(pairs))
+		for i, pair := range pairs {
+			keys[i] = pair.key
+		}
+		return keys
+	}
+	return je.parseEntry(line, decode, keyOrder)
+}
+
+// splitKVTail finds the longest run of key=value pairs that ends the line and
+// returns the text before it. A quoted text, as klog writes its messages, is
+// unquoted.
+func splitKVTail(s string) (string, []logfmtPair, bool) {
+	for start := 0; start < len(s); start++ {
+		if start > 0 && s[start-1] != ' ' && s[start-1] != '\t' {
+			continue
+		}
+		if s[start] == ' ' || s[start] == '\t' {
+			continue
+		}
+		pairs, ok := splitLogfmt(s[start:])
+		if !ok {
+			continue
+		}
+		text := strings.TrimSpace(s[:start])
+		if unquoted, err := strconv.Unquote(text); err == nil && strings.HasPrefix(text, `"`) {
+			text = unquoted
+		}
+		return text, pairs, true
+	}
+	return "", nil, false
+}
+
+// hasAnyField reports whether fields holds any of the keys
+func hasAnyField(fields map[string]any, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := fields[key]; ok {
+			return true
+		}
+	}
+	return false
+}
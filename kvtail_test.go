@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKVEntry(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatKV
+	extractor := NewJSONExtractor("", mappings)
+
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		level   string
+		fields  map[string]any
+	}{
+		{
+			name:    "kubelet",
+			line:    `"Pod status updated" pod="default/nginx-7d4b9" status=Running`,
+			message: "Pod status updated",
+			level:   "info",
+			fields:  map[string]any{"pod": "default/nginx-7d4b9", "status": "Running"},
+		},
+		{
+			name:    "free text",
+			line:    `Connection closed by peer port=8080 reason="idle timeout" level=warn`,
+			message: "Connection closed by peer",
+			level:   "warn",
+			fields:  map[string]any{"port": "8080", "reason": "idle timeout"},
+		},
+		{
+			name:    "text containing an equals sign",
+			line:    `retrying a=b failed attempt=3`,
+			message: "retrying a=b failed",
+			level:   "info",
+			fields:  map[string]any{"attempt": "3"},
+		},
+		{
+			name:    "message pair wins over text",
+			line:    `handler done msg="request served" ms=12`,
+			message: "request served",
+			level:   "info",
+			fields:  map[string]any{"ms": "12"},
+		},
+		{
+			name:    "no pairs",
+			line:    `just a plain line`,
+			message: "just a plain line",
+			level:   "info",
+			fields:  map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, entry.Message)
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Expected level %q, got %q", tt.level, entry.Level)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.fields) {
+				t.Errorf("Expected fields %v, got %v", tt.fields, entry.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// latencyKey is the time in nanoseconds from reading a record's first line
+// to handing the record to the SDK, with --latency
+const latencyKey = "otel_logger.latency_ns"
+
+// Attributes of the latency summaries exported under internalErrorScope
+const (
+	latencyCountKey = "otel_logger.latency.count"
+	latencyP50Key   = "otel_logger.latency.p50_ns"
+	latencyP90Key   = "otel_logger.latency.p90_ns"
+	latencyP99Key   = "otel_logger.latency.p99_ns"
+	latencyMaxKey   = "otel_logger.latency.max_ns"
+)
+
+// Latencies are counted in buckets of latencySubBuckets per power of two,
+// so percentiles are reported at most an eighth above the true value
+const (
+	latencySubBits    = 3
+	latencySubBuckets = 1 << latencySubBits
+	latencyBuckets    = (64 - latencySubBits + 1) * latencySubBuckets
+)
+
+// latencyBucket is the bucket of a latency of ns nanoseconds
+func latencyBucket(ns uint64) int {
+	if ns < latencySubBuckets {
+		return int(ns)
+	}
+	shift := bits.Len64(ns) - latencySubBits - 1
+	return (shift+1)*latencySubBuckets + int(ns>>shift) - latencySubBuckets
+}
+
+// latencyBucketMax is the largest latency in bucket i
+func latencyBucketMax(i int) uint64 {
+	if i < latencySubBuckets {
+		return uint64(i)
+	}
+	shift := i/latencySubBuckets - 1
+	sub := uint64(i%latencySubBuckets + latencySubBuckets)
+	return (sub+1)<<shift - 1
+}
+
+// latencyTracker collects the latencies of exported records and exports a
+// summary of their percentiles every interval, so the delay otel-logger
+// adds can be compared with exporting from the application directly
+type latencyTracker struct {
+	logger log.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	counts [latencyBuckets]int64 // since the last summary
+	count  int64
+	max    time.Duration
+}
+
+func newLatencyTracker(logger log.Logger) *latencyTracker {
+	return &latencyTracker{logger: logger, now: time.Now}
+}
+
+// observe counts the latency of a record read at readAt and returns it
+func (t *latencyTracker) observe(readAt time.Time) time.Duration {
+	latency := t.now().Sub(readAt)
+	if latency < 0 {
+		// The clock went back, or the entry was read on another host
+		latency = 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[latencyBucket(uint64(latency))]++
+	t.count++
+	if latency > t.max {
+		t.max = latency
+	}
+	return latency
+}
+
+// report exports a summary of the latencies since the last one, if any
+func (t *latencyTracker) report() {
+	t.mu.Lock()
+	counts, count, maxLatency := t.counts, t.count, t.max
+	t.counts, t.count, t.max = [latencyBuckets]int64{}, 0, 0
+	t.mu.Unlock()
+	if count == 0 {
+		return
+	}
+
+	percentile := func(p float64) int64 {
+		rank := max(int64(math.Ceil(p*float64(count))), 1)
+		var seen int64
+		for i, n := range counts {
+			if seen += n; seen >= rank {
+				// The bucket's upper bound, but never above the largest seen
+				if bound := int64(latencyBucketMax(i)); bound < int64(maxLatency) {
+					return bound
+				}
+				return int64(maxLatency)
+			}
+		}
+		return int64(maxLatency)
+	}
+
+	now := t.now()
+	var record log.Record
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(now)
+	record.SetSeverity(log.SeverityInfo)
+	record.SetSeverityText("info")
+	record.SetBody(log.StringValue("latency"))
+	record.AddAttributes(
+		log.Int64(latencyCountKey, count),
+		log.Int64(latencyP50Key, percentile(0.50)),
+		log.Int64(latencyP90Key, percentile(0.90)),
+		log.Int64(latencyP99Key, percentile(0.99)),
+		log.Int64(latencyMaxKey, int64(maxLatency)),
+	)
+	t.logger.Emit(context.Background(), record)
+}
+
+func (t *latencyTracker) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.report()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// SetLatency stamps records with their latency and exports a summary of the
+// latencies every interval until Finish
+func (p *LogProcessor) SetLatency(tracker *latencyTracker, interval time.Duration) {
+	p.latency = tracker
+	p.stopLatency = make(chan struct{})
+	go tracker.run(interval, p.stopLatency)
+}
+
+// finishLatency exports the summary of the latencies since the last one
+func (p *LogProcessor) finishLatency() {
+	if p.latency == nil {
+		return
+	}
+	close(p.stopLatency)
+	p.latency.report()
+	p.latency = nil
+}
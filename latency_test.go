@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	previous := -1
+	for _, ns := range []uint64{0, 1, 7, 8, 15, 16, 17, 31, 32, 1000, 123456, uint64(time.Second), 1<<63 + 12345, 1<<64 - 1} {
+		i := latencyBucket(ns)
+		if i < previous || i >= latencyBuckets {
+			t.Errorf("Expected bucket %d of %d to follow %d", i, ns, previous)
+		}
+		previous = i
+		bound := latencyBucketMax(i)
+		if bound < ns || float64(bound-ns) > float64(ns)/8 {
+			t.Errorf("Expected bucket %d of %d to end within an eighth above it, got %d", i, ns, bound)
+		}
+	}
+}
+
+func TestLatencyTracker(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	tracker := newLatencyTracker(provider.Logger(internalErrorScope))
+	now := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	for ms := 1; ms <= 100; ms++ {
+		tracker.observe(now.Add(-time.Duration(ms) * time.Millisecond))
+	}
+	if latency := tracker.observe(now.Add(time.Second)); latency != 0 {
+		t.Errorf("Expected a read in the future to count as no latency, got %v", latency)
+	}
+	tracker.report()
+	tracker.report()
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected one summary, got %d", len(records))
+	}
+	attrs := recordAttributes(records[0])
+	if attrs[latencyCountKey] != "101" || attrs[latencyMaxKey] != "100000000" {
+		t.Errorf("Expected 101 latencies up to 100ms, got %v", attrs)
+	}
+	for key, expected := range map[string]time.Duration{latencyP50Key: 50 * time.Millisecond, latencyP90Key: 90 * time.Millisecond, latencyP99Key: 99 * time.Millisecond} {
+		got, err := time.ParseDuration(attrs[key] + "ns")
+		if err != nil || got < expected-time.Millisecond || got > expected+expected/8 {
+			t.Errorf("Expected %s near %v, got %s", key, expected, attrs[key])
+		}
+	}
+}
+
+func TestLogProcessorLatency(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	tracker := newLatencyTracker(provider.Logger(internalErrorScope))
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	processor.SetLatency(tracker, time.Hour)
+
+	ctx := context.Background()
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: now, Level: "info", Message: "read", ReadAt: now.Add(-5 * time.Millisecond)})
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: now, Level: "info", Message: "of otel-logger's own"})
+	processor.Finish(ctx)
+
+	records := exporter.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected two records and a summary, got %d", len(records))
+	}
+	if got := recordAttributes(records[0])[latencyKey]; got != "5000000" {
+		t.Errorf("Expected a latency of 5ms, got %q", got)
+	}
+	if got, ok := recordAttributes(records[1])[latencyKey]; ok {
+		t.Errorf("Expected no latency without a read time, got %q", got)
+	}
+	if records[2].Body().AsString() != "latency" || recordAttributes(records[2])[latencyCountKey] != "1" {
+		t.Errorf("Expected a summary of one latency on Finish, got %v", recordAttributes(records[2]))
+	}
+}
+
+func TestMultilineLogIteratorReadTime(t *testing.T) {
+	input := "first\n\tcontinued\nsecond\n"
+	before := time.Now()
+	var readAts []time.Time
+	for _, readAt := range multilineLogIteratorWith(strings.NewReader(input), defaultContinuationPattern, multilineOptions{}) {
+		readAts = append(readAts, readAt)
+	}
+	if len(readAts) != 2 || readAts[0].Before(before) || readAts[1].Before(readAts[0]) || readAts[1].After(time.Now()) {
+		t.Errorf("Expected the read time of each entry's first line, got %v", readAts)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// Scales for numeric levels that level_numbers does not name
+const (
+	levelScaleBunyan = "bunyan" // bunyan and pino: 10 trace, 20 debug, ... 60 fatal
+	levelScaleSyslog = "syslog" // syslog severity codes: 0 emerg ... 7 debug
+	levelScaleNone   = "none"   // numeric levels are left as attributes
+)
+
+func validLevelScale(scale string) error {
+	switch scale {
+	case "", levelScaleBunyan, levelScaleSyslog, levelScaleNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid level scale %q (supported: %s, %s, %s)", scale, levelScaleBunyan, levelScaleSyslog, levelScaleNone)
+	}
+}
+
+// bunyanLevels are the level names of the bunyan scale by tens, and the
+// first severity of each
+var bunyanLevels = [6]struct {
+	name     string
+	severity log.Severity
+}{
+	{"trace", log.SeverityTrace1},
+	{"debug", log.SeverityDebug1},
+	{"info", log.SeverityInfo1},
+	{"warn", log.SeverityWarn1},
+	{"error", log.SeverityError1},
+	{"fatal", log.SeverityFatal1},
+}
+
+// numericSeverity decodes a numeric level on the given scale (bunyan when
+// empty) into a level name and severity. On the bunyan scale the units spread
+// custom levels over the four severities of their ten, so pino's 35 falls
+// between info and warn as INFO3.
+func numericSeverity(level float64, scale string) (string, log.Severity, bool) {
+	switch scale {
+	case levelScaleNone:
+		return "", 0, false
+	case levelScaleSyslog:
+		if level != math.Trunc(level) || level < 0 || level >= float64(len(syslogSeverityNames)) {
+			return "", 0, false
+		}
+		name := syslogSeverityNames[int(level)]
+		return name, logLevelToSeverity(name), true
+	default:
+		n := int(math.Max(math.Min(level, 69), 10))
+		tens := bunyanLevels[n/10-1]
+		return tens.name, tens.severity + log.Severity(n%10*4/10), true
+	}
+}
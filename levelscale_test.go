@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestNumericSeverity(t *testing.T) {
+	tests := []struct {
+		level    float64
+		scale    string
+		name     string
+		severity log.Severity
+		ok       bool
+	}{
+		{10, "", "trace", log.SeverityTrace1, true},
+		{20, levelScaleBunyan, "debug", log.SeverityDebug1, true},
+		{30, "", "info", log.SeverityInfo1, true},
+		{35, "", "info", log.SeverityInfo3, true},
+		{39, "", "info", log.SeverityInfo4, true},
+		{40, "", "warn", log.SeverityWarn1, true},
+		{50, "", "error", log.SeverityError1, true},
+		{60, "", "fatal", log.SeverityFatal1, true},
+		{5, "", "trace", log.SeverityTrace1, true},
+		{100, "", "fatal", log.SeverityFatal4, true},
+		{3, levelScaleSyslog, "err", log.SeverityError1, true},
+		{0, levelScaleSyslog, "emerg", log.SeverityFatal2, true},
+		{8, levelScaleSyslog, "", 0, false},
+		{30, levelScaleNone, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		name, severity, ok := numericSeverity(tt.level, tt.scale)
+		if name != tt.name || severity != tt.severity || ok != tt.ok {
+			t.Errorf("numericSeverity(%v, %q) = %q, %v, %v; want %q, %v, %v",
+				tt.level, tt.scale, name, severity, ok, tt.name, tt.severity, tt.ok)
+		}
+	}
+}
+
+func TestNumericLevelRecord(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	for _, line := range []string{
+		`{"level":50,"msg":"request failed","name":"api"}`,
+		`{"level":35,"msg":"custom pino level"}`,
+	} {
+		entry, err := extractor.ParseLogEntry(line)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	records := exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Severity() != log.SeverityError1 || records[0].SeverityText() != "error" {
+		t.Errorf("Expected ERROR severity, got %v %q", records[0].Severity(), records[0].SeverityText())
+	}
+	if _, ok := recordAttributes(records[0])["level"]; ok {
+		t.Error("Expected the numeric level not to be kept as an attribute")
+	}
+	if records[1].Severity() != log.SeverityInfo3 {
+		t.Errorf("Expected INFO3 severity, got %v", records[1].Severity())
+	}
+}
+
+func TestNumericLevelScaleNone(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.LevelScale = levelScaleNone
+	entry, err := NewJSONExtractor("", mappings).ParseLogEntry(`{"level":50,"msg":"kept"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Level != "info" || entry.Fields["level"] != float64(50) {
+		t.Errorf("Expected the level to stay an attribute, got level %q and fields %v", entry.Level, entry.Fields)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// recordLimitOptions resolves the attribute count and value length limits.
+// A flag wins over the log record specific OTEL_LOGRECORD_* variables, which
+// win over the general OTEL_ATTRIBUTE_* ones; unset limits keep the SDK
+// defaults (128 attributes, no length limit). A negative limit disables it.
+func recordLimitOptions(config *Config) ([]sdklog.LoggerProviderOption, error) {
+	var opts []sdklog.LoggerProviderOption
+
+	count, err := resolveLimit(config.AttrCountLimit, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT")
+	if err != nil {
+		return nil, err
+	}
+	if count != nil {
+		opts = append(opts, sdklog.WithAttributeCountLimit(*count))
+	}
+
+	length, err := resolveLimit(config.AttrValueLengthLimit, "OTEL_LOGRECORD_ATTRIBUTE_VALUE_LENGTH_LIMIT", "OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT")
+	if err != nil {
+		return nil, err
+	}
+	if length != nil {
+		opts = append(opts, sdklog.WithAttributeValueLengthLimit(*length))
+	}
+
+	return opts, nil
+}
+
+func resolveLimit(flag *int, envVars ...string) (*int, error) {
+	if flag != nil {
+		return flag, nil
+	}
+	for _, name := range envVars {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be an integer", name, value)
+		}
+		return &limit, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestResolveLimit(t *testing.T) {
+	t.Setenv("OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "")
+	t.Setenv("OTEL_ATTRIBUTE_COUNT_LIMIT", "")
+
+	if limit, err := resolveLimit(nil, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT"); err != nil || limit != nil {
+		t.Errorf("Expected no limit when nothing is set, got %v, %v", limit, err)
+	}
+
+	t.Setenv("OTEL_ATTRIBUTE_COUNT_LIMIT", "64")
+	if limit, _ := resolveLimit(nil, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT"); limit == nil || *limit != 64 {
+		t.Errorf("Expected the general limit 64, got %v", limit)
+	}
+
+	t.Setenv("OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "32")
+	if limit, _ := resolveLimit(nil, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT"); limit == nil || *limit != 32 {
+		t.Errorf("Expected the log record limit 32 to win, got %v", limit)
+	}
+
+	flag := 8
+	if limit, _ := resolveLimit(&flag, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT"); limit == nil || *limit != 8 {
+		t.Errorf("Expected the flag to win, got %v", limit)
+	}
+
+	t.Setenv("OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "lots")
+	if _, err := resolveLimit(nil, "OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT", "OTEL_ATTRIBUTE_COUNT_LIMIT"); err == nil {
+		t.Error("Expected error for a non-integer limit")
+	}
+}
+
+func TestRecordLimitOptionsApplyToRecords(t *testing.T) {
+	t.Setenv("OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT", "5")
+	count := 2
+	opts, err := recordLimitOptions(&Config{AttrCountLimit: &count})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exporter := &recordingExporter{}
+	provider := sdklog.NewLoggerProvider(append([]sdklog.LoggerProviderOption{
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)),
+	}, opts...)...)
+
+	var record log.Record
+	record.AddAttributes(log.String("a", strings.Repeat("x", 20)), log.String("b", "y"), log.String("c", "z"))
+	provider.Logger("test").Emit(context.Background(), record)
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if records[0].AttributesLen() != 2 || records[0].DroppedAttributes() != 1 {
+		t.Errorf("Expected 2 attributes and 1 dropped, got %d and %d", records[0].AttributesLen(), records[0].DroppedAttributes())
+	}
+	if got := recordAttributes(records[0])["a"]; got != "xxxxx" {
+		t.Errorf("Expected value truncated to 5 characters, got %q", got)
+	}
+}
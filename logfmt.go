@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseLogfmtEntry decodes a logfmt line such as
+// `level=info msg="started" ts=2025-01-02T15:04:05Z` with the same field
+// mappings as JSON lines. Values stay strings, as logfmt has no types.
+func (je *JSONExtractor) ParseLogfmtEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseLogfmt(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseLogfmt(line string) (*LogEntry, bool) {
+	return je.parseEntry(line, decodeLogfmt, logfmtKeyOrder)
+}
+
+// logfmtPair is one key=value pair of a logfmt line
+type logfmtPair struct {
+	key, value string
+}
+
+// decodeLogfmt decodes the pairs of a logfmt line into fields. Repeated keys
+// keep the last value.
+func decodeLogfmt(s string) (map[string]any, bool) {
+	pairs, ok := splitLogfmt(s)
+	if !ok {
+		return nil, false
+	}
+	fields := make(map[string]any, len(pairs))
+	for _, pair := range pairs {
+		fields[pair.key] = pair.value
+	}
+	return fields, true
+}
+
+// logfmtKeyOrder returns the keys of a logfmt line in the order they appear
+func logfmtKeyOrder(s string) []string {
+	pairs, _ := splitLogfmt(s)
+	keys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.key
+	}
+	return keys
+}
+
+// splitLogfmt splits s into key=value pairs. Values may be double quoted
+// with Go escapes. Every word must be a pair, so text such as
+// "Listening on port=8080" stays a plain message.
+func splitLogfmt(s string) ([]logfmtPair, bool) {
+	var pairs []logfmtPair
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+
+		end := strings.IndexAny(s, "= \t")
+		if end == -1 {
+			end = len(s)
+		}
+		key := s[:end]
+		if key == "" || strings.ContainsRune(key, '"') {
+			return nil, false
+		}
+		s = s[end:]
+		if !strings.HasPrefix(s, "=") {
+			return nil, false
+		}
+		s = s[1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			quoted, ok := quotedPrefix(s)
+			if !ok {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, false
+			}
+			value, s = unquoted, s[len(quoted):]
+			if s != "" && s[0] != ' ' && s[0] != '\t' {
+				return nil, false
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end == -1 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+			if strings.ContainsRune(value, '"') {
+				return nil, false
+			}
+		}
+		pairs = append(pairs, logfmtPair{key: key, value: value})
+	}
+	return pairs, len(pairs) > 0
+}
+
+// quotedPrefix returns the double-quoted string at the start of s, including
+// the quotes
+func quotedPrefix(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return s[:i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeLogfmt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]any
+	}{
+		{
+			name:     "bare values",
+			input:    "level=info msg=started port=8080",
+			expected: map[string]any{"level": "info", "msg": "started", "port": "8080"},
+		},
+		{
+			name:     "quoted values with escapes",
+			input:    `level=warn msg="disk \"data\" almost full" path="/var/lib"`,
+			expected: map[string]any{"level": "warn", "msg": `disk "data" almost full`, "path": "/var/lib"},
+		},
+		{
+			name:     "empty values and extra spaces",
+			input:    "  a=  b=\"\"\tc=1 ",
+			expected: map[string]any{"a": "", "b": "", "c": "1"},
+		},
+		{
+			name:     "repeated key keeps the last value",
+			input:    "a=1 a=2",
+			expected: map[string]any{"a": "2"},
+		},
+		{
+			name:  "plain text",
+			input: "Listening on port=8080",
+		},
+		{
+			name:  "unterminated quote",
+			input: `msg="oops`,
+		},
+		{
+			name:  "text after a quoted value",
+			input: `msg="a"b c=1`,
+		},
+		{
+			name:  "quote inside a bare value",
+			input: `msg=a"b`,
+		},
+		{
+			name:  "empty line",
+			input: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, ok := decodeLogfmt(tt.input)
+			if ok != (tt.expected != nil) {
+				t.Fatalf("Expected ok=%v, got %v (%v)", tt.expected != nil, ok, fields)
+			}
+			if ok && !reflect.DeepEqual(fields, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
+
+func TestParseLogfmtEntry(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatLogfmt
+	extractor := NewJSONExtractor("", mappings)
+
+	entry, err := extractor.ParseLogEntry(`ts=2025-01-02T15:04:05Z level=error msg="payment failed" order_id=42`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !entry.Timestamp.Equal(time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected parsed timestamp, got %v", entry.Timestamp)
+	}
+	if entry.Level != "error" || entry.Message != "payment failed" {
+		t.Errorf("Expected error level and message, got %q %q", entry.Level, entry.Message)
+	}
+	if !reflect.DeepEqual(entry.Fields, map[string]any{"order_id": "42"}) {
+		t.Errorf("Expected remaining fields, got %v", entry.Fields)
+	}
+
+	plain, _ := extractor.ParseLogEntry("Listening on port=8080")
+	if plain.Message != "Listening on port=8080" || len(plain.Fields) != 0 {
+		t.Errorf("Expected plain message, got %+v", plain)
+	}
+
+	// JSON is not decoded in logfmt mode
+	jsonLine, _ := extractor.ParseLogEntry(`{"msg":"hello"}`)
+	if jsonLine.Message != `{"msg":"hello"}` {
+		t.Errorf("Expected JSON line as plain message, got %q", jsonLine.Message)
+	}
+}
+
+func TestParseLogfmtEntryCustomMappings(t *testing.T) {
+	extractor := NewJSONExtractor("", &FieldMappings{
+		TimestampFields: []string{"when"},
+		LevelFields:     []string{"sev"},
+		MessageFields:   []string{"text"},
+	})
+
+	entry, _ := extractor.ParseLogfmtEntry(`when=2025-01-02T15:04:05Z sev=warn text=slow`)
+	if entry.Level != "warn" || entry.Message != "slow" || entry.Timestamp.Year() != 2025 {
+		t.Errorf("Expected custom mappings to apply, got %+v", entry)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// Where the application's logger name (e.g. com.example.UserService) goes
+const (
+	loggerNameScope     = "scope"
+	loggerNameAttribute = "attribute"
+)
+
+// loggerNameKey is the attribute used when the logger name is not a scope
+const loggerNameKey = "log.logger"
+
+func validateLoggerNameTarget(target string) error {
+	switch target {
+	case "", loggerNameScope, loggerNameAttribute:
+		return nil
+	default:
+		return fmt.Errorf("invalid --logger-name-target %q (supported: %s, %s)", target, loggerNameScope, loggerNameAttribute)
+	}
+}
+
+// scopeLoggers hands out one logger per application logger name, so records
+// keep the hierarchy Java and Python logging users navigate by
+type scopeLoggers struct {
+	mu       sync.Mutex
+	provider log.LoggerProvider
+	loggers  map[string]log.Logger
+}
+
+func (s *scopeLoggers) logger(name string) log.Logger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if logger, ok := s.loggers[name]; ok {
+		return logger
+	}
+	logger := s.provider.Logger(name)
+	s.loggers[name] = logger
+	return logger
+}
+
+// SetLoggerNameScopes emits records that carry a logger name under an
+// instrumentation scope of that name instead of a log.logger attribute
+func (p *LogProcessor) SetLoggerNameScopes(provider log.LoggerProvider) {
+	p.namedScopes = &scopeLoggers{provider: provider, loggers: make(map[string]log.Logger)}
+}
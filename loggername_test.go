@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseLogEntryExtractsLoggerName(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.LoggerNameFields = []string{"logger", "name"}
+	extractor := NewJSONExtractor("", mappings)
+
+	entry, err := extractor.ParseLogEntry(`{"msg":"saved","name":"com.example.UserService","user":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.LoggerName != "com.example.UserService" {
+		t.Errorf("Expected logger name com.example.UserService, got %q", entry.LoggerName)
+	}
+	if _, ok := entry.Fields["name"]; ok {
+		t.Error("Expected the logger name field to be removed from attributes")
+	}
+
+	// Not configured by default: the field stays an ordinary attribute
+	entry, _ = NewJSONExtractor("", getDefaultFieldMappings()).ParseLogEntry(`{"msg":"saved","logger":"app.db"}`)
+	if entry.LoggerName != "" || entry.Fields["logger"] != "app.db" {
+		t.Errorf("Expected logger to stay an attribute by default, got %q / %v", entry.LoggerName, entry.Fields)
+	}
+}
+
+func TestLogProcessorLoggerNameScopes(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("otel-logger"))
+	processor.SetLoggerNameScopes(provider)
+
+	ctx := context.Background()
+	processor.ProcessLogEntry(ctx, &LogEntry{Message: "a", LoggerName: "com.example.UserService"})
+	processor.ProcessLogEntry(ctx, &LogEntry{Message: "b"})
+	processor.ProcessLogEntry(ctx, &LogEntry{Message: "c", LoggerName: "com.example.UserService"})
+
+	records := exporter.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	for i, expected := range []string{"com.example.UserService", "otel-logger", "com.example.UserService"} {
+		if scope := records[i].InstrumentationScope().Name; scope != expected {
+			t.Errorf("Record %d: expected scope %q, got %q", i, expected, scope)
+		}
+		if _, ok := recordAttributes(records[i])[loggerNameKey]; ok {
+			t.Errorf("Record %d: expected no %s attribute in scope mode", i, loggerNameKey)
+		}
+	}
+}
+
+func TestLogProcessorLoggerNameAttribute(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("otel-logger"))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{Message: "a", LoggerName: "app.db"})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if scope := records[0].InstrumentationScope().Name; scope != "otel-logger" {
+		t.Errorf("Expected default scope, got %q", scope)
+	}
+	if got := recordAttributes(records[0])[loggerNameKey]; got != "app.db" {
+		t.Errorf("Expected %s=app.db, got %q", loggerNameKey, got)
+	}
+}
+
+func TestValidateLoggerNameTarget(t *testing.T) {
+	for _, target := range []string{"", "scope", "attribute"} {
+		if err := validateLoggerNameTarget(target); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", target, err)
+		}
+	}
+	if err := validateLoggerNameTarget("resource"); err == nil {
+		t.Error("Expected error for unsupported target")
+	}
+}
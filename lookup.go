@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// lookupTable enriches records with the columns of the row whose key column
+// matches the value of a field, e.g. the tier and region of a tenant ID from
+// a table maintained elsewhere
+type lookupTable struct {
+	field   string
+	key     string
+	columns map[string]string // column -> field it is added as; nil adds all
+	rows    map[string]map[string]any
+}
+
+// lookupTables are applied in the order given
+type lookupTables []*lookupTable
+
+// loadLookups reads the --lookup rules and the tables they name
+func loadLookups(rules []string) (lookupTables, error) {
+	var tables lookupTables
+	for _, rule := range rules {
+		table, err := loadLookup(rule)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// loadLookup reads a rule of the form field=file[:key][->column[=name],...]:
+// the key column defaults to the field's name and the columns to all others
+func loadLookup(rule string) (*lookupTable, error) {
+	field, spec, ok := strings.Cut(rule, "=")
+	spec, columns, hasColumns := strings.Cut(spec, "->")
+	path, key := spec, field
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		path, key = spec[:i], spec[i+1:]
+	}
+	field, path, key = strings.TrimSpace(field), strings.TrimSpace(path), strings.TrimSpace(key)
+	if !ok || field == "" || path == "" || key == "" {
+		return nil, fmt.Errorf("invalid --lookup %q: expected field=file[:key][->column,...], e.g. tenant_id=tenants.csv:id->tier,region", rule)
+	}
+
+	table := &lookupTable{field: field, key: key}
+	if hasColumns {
+		table.columns = make(map[string]string)
+		for _, column := range strings.Split(columns, ",") {
+			column, name, renamed := strings.Cut(strings.TrimSpace(column), "=")
+			if !renamed {
+				name = column
+			}
+			if column == "" || name == "" {
+				return nil, fmt.Errorf("invalid --lookup %q: empty column", rule)
+			}
+			table.columns[column] = name
+		}
+	}
+
+	rows, err := readLookupRows(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load --lookup %s: %w", path, err)
+	}
+	table.rows = make(map[string]map[string]any, len(rows))
+	for i, row := range rows {
+		value, ok := row[key]
+		if !ok {
+			return nil, fmt.Errorf("--lookup %s: row %d has no %s column", path, i+1, key)
+		}
+		id := attributeString(value)
+		if _, ok := table.rows[id]; ok {
+			return nil, fmt.Errorf("--lookup %s: %s %q appears more than once", path, key, id)
+		}
+		delete(row, key)
+		table.rows[id] = row
+	}
+	for column := range table.columns {
+		if !slices.ContainsFunc(rows, func(row map[string]any) bool { _, ok := row[column]; return ok }) {
+			return nil, fmt.Errorf("--lookup %s has no %s column", path, column)
+		}
+	}
+	return table, nil
+}
+
+// readLookupRows reads a CSV or TSV file with a header row, or JSON objects,
+// on their own or in arrays, as a JSON file or JSON lines
+func readLookupRows(path string) ([]map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		reader := csv.NewReader(file)
+		if strings.EqualFold(filepath.Ext(path), ".tsv") {
+			reader.Comma = '\t'
+		}
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, errors.New("missing header row")
+		}
+		header, rows := records[0], make([]map[string]any, 0, len(records)-1)
+		for _, record := range records[1:] {
+			row := make(map[string]any, len(header))
+			for i, column := range header {
+				row[column] = record[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case ".json", ".jsonl", ".ndjson":
+		var rows []map[string]any
+		decoder := json.NewDecoder(file)
+		for {
+			var value any
+			if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+				return rows, nil
+			} else if err != nil {
+				return nil, err
+			}
+			values, ok := value.([]any)
+			if !ok {
+				values = []any{value}
+			}
+			for _, value := range values {
+				row, ok := value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("expected objects, got %T", value)
+				}
+				rows = append(rows, row)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q (supported: .csv, .tsv, .json, .jsonl)", filepath.Ext(path))
+	}
+}
+
+// apply returns the entry with the columns of the matching rows added.
+// Fields the entry already has are kept, and an entry without the field or
+// with a value not in the table is left as it is.
+func (t lookupTables) apply(entry *LogEntry) *LogEntry {
+	var fields map[string]any
+	var added []string
+	for _, table := range t {
+		value, ok := entry.Fields[table.field]
+		if !ok {
+			continue
+		}
+		row, ok := table.rows[attributeString(value)]
+		if !ok {
+			continue
+		}
+		for column, cell := range row {
+			name, ok := column, true
+			if table.columns != nil {
+				name, ok = table.columns[column]
+			}
+			if !ok {
+				continue
+			}
+			if _, exists := entry.Fields[name]; exists {
+				continue
+			}
+			if fields == nil {
+				fields = maps.Clone(entry.Fields)
+			}
+			if _, exists := fields[name]; !exists {
+				fields[name] = cell
+				added = append(added, name)
+			}
+		}
+	}
+	if fields == nil {
+		return entry
+	}
+
+	enriched := *entry
+	enriched.Fields = fields
+	if entry.FieldOrder != nil {
+		// Map order is random, so added fields go last in a fixed order
+		slices.Sort(added)
+		enriched.FieldOrder = append(slices.Clone(entry.FieldOrder), added...)
+	}
+	return &enriched
+}
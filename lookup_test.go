@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeLookup(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupTablesApply(t *testing.T) {
+	tenants := writeLookup(t, "tenants.csv", "id,tier,region\nt1,gold,eu\nt2,free,us\n")
+	users := writeLookup(t, "users.json", `[{"user_id": 42, "team": "payments", "name": "Ann"}]`)
+	lookups, err := loadLookups([]string{
+		"tenant_id=" + tenants + ":id->tier,region=cloud.region",
+		"user_id=" + users,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entry := &LogEntry{
+		Fields:     map[string]any{"tenant_id": "t1", "user_id": float64(42), "name": "kept"},
+		FieldOrder: []string{"tenant_id", "user_id", "name"},
+	}
+	enriched := lookups.apply(entry)
+	expected := map[string]any{"tenant_id": "t1", "user_id": float64(42), "name": "kept", "tier": "gold", "cloud.region": "eu", "team": "payments"}
+	if !reflect.DeepEqual(enriched.Fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, enriched.Fields)
+	}
+	if order := []string{"tenant_id", "user_id", "name", "cloud.region", "team", "tier"}; !reflect.DeepEqual(enriched.FieldOrder, order) {
+		t.Errorf("Expected order %v, got %v", order, enriched.FieldOrder)
+	}
+	if _, ok := entry.Fields["tier"]; ok {
+		t.Error("Expected the original entry to be left alone")
+	}
+
+	for _, unmatched := range []*LogEntry{
+		{Fields: map[string]any{"tenant_id": "t3"}},
+		{Fields: map[string]any{"other": "t1"}},
+	} {
+		if lookups.apply(unmatched) != unmatched {
+			t.Errorf("Expected %v to be returned as is", unmatched.Fields)
+		}
+	}
+}
+
+func TestLoadLookupErrors(t *testing.T) {
+	tenants := writeLookup(t, "tenants.csv", "id,tier\nt1,gold\n")
+	duplicates := writeLookup(t, "dup.tsv", "id\ttier\nt1\tgold\nt1\tfree\n")
+	notObjects := writeLookup(t, "bad.jsonl", "[1, 2]\n")
+	unsupported := writeLookup(t, "tenants.xml", "<tenants/>")
+
+	for _, rule := range []string{
+		"tenant_id",
+		"=" + tenants,
+		"tenant_id=" + tenants,                 // no tenant_id column
+		"tenant_id=" + tenants + ":id->region", // no region column
+		"tenant_id=" + tenants + ":id->tier,",  // empty column
+		"tenant_id=" + duplicates + ":id",      // t1 twice
+		"tenant_id=" + notObjects + ":id",      // not objects
+		"tenant_id=" + unsupported + ":id",     // not a known format
+		"tenant_id=" + tenants + ".missing:id", // no such file
+	} {
+		if _, err := loadLookups([]string{rule}); err == nil {
+			t.Errorf("Expected error for %q", rule)
+		}
+	}
+}
+
+func TestLogProcessorLookups(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	lookups, err := loadLookups([]string{"enduser.id=" + writeLookup(t, "users.csv", "id,team\nu1,payments\n") + ":id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.SetFieldRenames(fieldRenames{"userId": "enduser.id"})
+	processor.SetLookups(lookups)
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{Message: "x", Fields: map[string]any{"userId": "u1"}})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if attrs := recordAttributes(records[0]); attrs["team"] != "payments" {
+		t.Errorf("Expected the team looked up by the renamed field, got %v", attrs)
+	}
+}
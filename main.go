@@ -7,18 +7,23 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"maps"
+	"math"
 	"os"
 	"os/exec"
 	"os/signal"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/google/uuid"
 
-	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
@@ -32,18 +37,129 @@ var (
 
 // Config holds all command-line arguments
 type Config struct {
-	Timeout             time.Duration `arg:"--timeout" default:"10s" help:"Request timeout"`
-	JSONPrefix          string        `arg:"--json-prefix" help:"Regex pattern to extract JSON from prefixed logs"`
-	BatchSize           int           `arg:"--batch-size" default:"50" help:"Number of log entries to batch before sending"`
-	FlushInterval       time.Duration `arg:"--flush-interval" default:"5s" help:"Interval to flush batched logs"`
-	TimestampFields     []string      `arg:"--timestamp-fields,separate" help:"JSON field names for timestamps (default: timestamp,ts,time,@timestamp)"`
-	LevelFields         []string      `arg:"--level-fields,separate" help:"JSON field names for log levels (default: level,lvl,severity,priority)"`
-	MessageFields       []string      `arg:"--message-fields,separate" help:"JSON field names for log messages (default: message,msg,text,content)"`
-	PassthroughStdout   bool          `arg:"--passthrough-stdout" help:"Pass command stdout to our stdout in addition to logging"`
-	PassthroughStderr   bool          `arg:"--passthrough-stderr" help:"Pass command stderr to our stderr in addition to logging"`
-	Verbose             bool          `arg:"--verbose,-v" help:"Enable verbose logging output"`
-	ContinuationPattern string        `arg:"--continuation-pattern" default:"^[ \\t]" help:"Regex pattern for continuation lines (default: lines starting with whitespace; closing brackets ] } are also treated as continuations)"`
-	Command             []string      `arg:"positional" help:"Command to execute and capture logs from (if not provided, reads from stdin)"`
+	Timeout               time.Duration `arg:"--timeout,env:OTEL_LOGGER_TIMEOUT" default:"10s" help:"Request timeout"`
+	JSONPrefix            string        `arg:"--json-prefix,env:OTEL_LOGGER_JSON_PREFIX" help:"Regex pattern to extract JSON from prefixed logs"`
+	BatchSize             int           `arg:"--batch-size,env:OTEL_LOGGER_BATCH_SIZE" default:"50" help:"Number of log entries to batch before sending"`
+	FlushInterval         time.Duration `arg:"--flush-interval,env:OTEL_LOGGER_FLUSH_INTERVAL" default:"5s" help:"Interval to flush batched logs"`
+	TimestampFields       []string      `arg:"--timestamp-fields,separate,env:OTEL_LOGGER_TIMESTAMP_FIELDS" help:"JSON field names for timestamps (default: timestamp,ts,time,@timestamp)"`
+	LevelFields           []string      `arg:"--level-fields,separate,env:OTEL_LOGGER_LEVEL_FIELDS" help:"JSON field names for log levels (default: level,lvl,severity,priority)"`
+	MessageFields         []string      `arg:"--message-fields,separate,env:OTEL_LOGGER_MESSAGE_FIELDS" help:"JSON field names for log messages (default: message,msg,text,content)"`
+	SeverityMap           string        `arg:"--severity-map,env:OTEL_LOGGER_SEVERITY_MAP" help:"Severity numbers (1-24) for nonstandard levels, names or numbers matched regardless of case, e.g. NOTICE=10,CRIT=21,verbose=5; adds to severity_map in --config"`
+	LevelScale            string        `arg:"--level-scale,env:OTEL_LOGGER_LEVEL_SCALE" help:"Scale of numeric levels: bunyan (default; pino too: 10 trace, 20 debug, 30 info, 40 warn, 50 error, 60 fatal, with values in between mapped to the severities between), syslog (0 emerg to 7 debug), or none to keep them as attributes"`
+	Format                string        `arg:"--format,env:OTEL_LOGGER_FORMAT" help:"Format of the log lines: json (default), logfmt (key=value pairs such as level=info msg=\"started\") syslog (RFC 5424 or RFC 3164), clf (Apache/nginx access logs), kv (free text followed by key=value pairs, as kubelet or HAProxy write), postgres (PostgreSQL stderr logs, see log_line_prefix in --config), mysql-slow (MySQL/MariaDB slow query logs), jvm (JVM unified logging such as GC logs), django-server (Django runserver and request logging), envoy (Envoy and Istio sidecar access logs, text or JSON) or auto (detected from the first lines), or a preset such as bunyan, pino, zap, logrus or winston for JSON from that framework, or lograge for Rails; lines that do not parse become plain messages"`
+	LoggerNameFields      []string      `arg:"--logger-name-fields,separate,env:OTEL_LOGGER_LOGGER_NAME_FIELDS" help:"JSON field names holding the application's logger name, e.g. logger,name (default: none)"`
+	LoggerNameTarget      string        `arg:"--logger-name-target,env:OTEL_LOGGER_LOGGER_NAME_TARGET" default:"scope" help:"Export the logger name as the instrumentation scope, or as a log.logger attribute"`
+	GRPCReconnectInterval time.Duration `arg:"--grpc-reconnect-interval,env:OTEL_LOGGER_GRPC_RECONNECT_INTERVAL" help:"Open a new gRPC connection, resolving the collector hostname again, this often (e.g. 5m; default: keep one connection)"`
+	GRPCRoundRobin        bool          `arg:"--grpc-round-robin,env:OTEL_LOGGER_GRPC_ROUND_ROBIN" help:"Spread gRPC exports across every address the collector hostname resolves to"`
+	Endpoints             []string      `arg:"--endpoints,separate,env:OTEL_LOGGER_ENDPOINTS" help:"Collector replicas to spread batches across, in place of OTEL_EXPORTER_OTLP_ENDPOINT"`
+	InstanceIDFile        string        `arg:"--instance-id-file,env:OTEL_LOGGER_INSTANCE_ID_FILE" help:"File holding a service.instance.id that is kept across restarts; a random UUID is saved there on first start"`
+	ResourceAttrsFile     string        `arg:"--resource-attrs-file,env:OTEL_LOGGER_RESOURCE_ATTRS_FILE" help:"File of key=value lines or a JSON object with resource attributes, such as a Kubernetes downward API labels file; here and in OTEL_RESOURCE_ATTRIBUTES, a value of @path is read from that file, and OTEL_RESOURCE_ATTRIBUTES wins"`
+	TenantHeaders         string        `arg:"--tenant-headers,env:OTEL_LOGGER_TENANT_HEADERS" help:"YAML file mapping an attribute value (e.g. team or namespace) to the export headers, such as API keys, for that tenant"`
+	ExportFile            string        `arg:"--export-file,env:OTEL_LOGGER_EXPORT_FILE" help:"Write records to this file as OTLP/JSON lines instead of sending them to a collector"`
+	ExportReceipts        string        `arg:"--export-receipts,env:OTEL_LOGGER_EXPORT_RECEIPTS" help:"Tag each exported batch with otel_logger.batch.id and append a JSON receipt per batch (batch ID, record count, time range, result) to this file, or - for stderr, to reconcile with what the collector received"`
+	ExportFormat          string        `arg:"--export-format,env:OTEL_LOGGER_EXPORT_FORMAT" default:"otlp-json" help:"Format of --export-file: otlp-json, or parquet (timestamp, severity, body and one column per attribute) for data lakes"`
+	ExportFileRotate      string        `arg:"--export-file-rotate,env:OTEL_LOGGER_EXPORT_FILE_ROTATE" help:"Start a new --export-file once it reaches a size (e.g. 100MB), an age (e.g. 1h), or either (100MB,1h)"`
+	ExportFileCompress    bool          `arg:"--export-file-compress,env:OTEL_LOGGER_EXPORT_FILE_COMPRESS" help:"Gzip export files once they are rotated (parquet: compress the column data)"`
+	BigQueryTable         string        `arg:"--bigquery-table,env:OTEL_LOGGER_BIGQUERY_TABLE" help:"Stream records into this BigQuery table (PROJECT.DATASET.TABLE) instead of a collector, adding a column for each new attribute"`
+	BigQueryEndpoint      string        `arg:"--bigquery-endpoint,env:OTEL_LOGGER_BIGQUERY_ENDPOINT" help:"BigQuery API endpoint, e.g. for an emulator (default: https://bigquery.googleapis.com)"`
+	MaxRecordSize         int           `arg:"--max-record-size,env:OTEL_LOGGER_MAX_RECORD_SIZE" help:"Split or truncate records larger than this many encoded bytes instead of failing their batch (e.g. 4000000 for a collector's default 4 MiB gRPC limit; default: no limit)"`
+	Oversize              string        `arg:"--oversize,env:OTEL_LOGGER_OVERSIZE" default:"split" help:"Records over --max-record-size: split them into linked parts, or truncate them"`
+	OversizeDir           string        `arg:"--oversize-dir,env:OTEL_LOGGER_OVERSIZE_DIR" help:"With --oversize truncate, keep a full OTLP JSON copy of each truncated record in this directory"`
+	EndpointFallback      string        `arg:"--endpoint-fallback,env:OTEL_LOGGER_ENDPOINT_FALLBACK" help:"Backup OTLP endpoint to export to while the primary collector is down"`
+	EndpointProbeInterval time.Duration `arg:"--endpoint-probe-interval,env:OTEL_LOGGER_ENDPOINT_PROBE_INTERVAL" default:"30s" help:"How often to retry the primary endpoint while exporting to the fallback, or a replica whose export failed"`
+	TLSMinVersion         string        `arg:"--tls-min-version,env:OTEL_LOGGER_TLS_MIN_VERSION" help:"Minimum TLS version for outbound connections: 1.2 or 1.3"`
+	TLSCiphers            []string      `arg:"--tls-ciphers,separate,env:OTEL_LOGGER_TLS_CIPHERS" help:"Comma-separated TLS 1.2 cipher suites allowed for outbound connections, e.g. TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 (repeatable)"`
+	TLSPin                []string      `arg:"--tls-pin,separate,env:OTEL_LOGGER_TLS_PIN" help:"Only connect to servers whose verified certificate chain has a certificate with this public key, as sha256/BASE64 of its SPKI (repeatable; any pin may match)"`
+	AttrStats             string        `arg:"--attr-stats,env:OTEL_LOGGER_ATTR_STATS" help:"On exit, report which attribute keys contribute the most exported bytes to stderr or FILE"`
+	AttrStatsTop          int           `arg:"--attr-stats-top,env:OTEL_LOGGER_ATTR_STATS_TOP" default:"20" help:"Number of attribute keys in the --attr-stats report (0 for all)"`
+	AttrCountLimit        *int          `arg:"--attr-count-limit,env:OTEL_LOGGER_ATTR_COUNT_LIMIT" help:"Maximum attributes per record, extra ones are dropped and counted (default: OTEL_LOGRECORD_ATTRIBUTE_COUNT_LIMIT, OTEL_ATTRIBUTE_COUNT_LIMIT or 128; negative for no limit)"`
+	AttrValueLengthLimit  *int          `arg:"--attr-value-length-limit,env:OTEL_LOGGER_ATTR_VALUE_LENGTH_LIMIT" help:"Truncate string attribute values to this length (default: OTEL_LOGRECORD_ATTRIBUTE_VALUE_LENGTH_LIMIT, OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT or no limit)"`
+	SpanEventLevel        string        `arg:"--span-event-level,env:OTEL_LOGGER_SPAN_EVENT_LEVEL" help:"Also record entries at or above this level that carry trace_id/span_id as exception events in their trace (e.g. error)"`
+	ScrubChildEnv         []string      `arg:"--scrub-child-env,separate,env:OTEL_LOGGER_SCRUB_CHILD_ENV" help:"Hide environment variables matching these comma-separated globs from the wrapped command, e.g. 'AWS_*,SECRET_*' (repeatable); their values are also masked in session recordings"`
+	ScrubChildEnvMode     string        `arg:"--scrub-child-env-mode,env:OTEL_LOGGER_SCRUB_CHILD_ENV_MODE" default:"remove" help:"How --scrub-child-env hides a variable: remove it, or mask its value as [REDACTED]"`
+	Sandbox               bool          `arg:"--sandbox,env:OTEL_LOGGER_SANDBOX" help:"Once inputs, the exporter and the wrapped command are set up, restrict otel-logger's own filesystem access (Landlock) and system calls (seccomp); Linux only, needs a CGO_ENABLED=0 build"`
+	SandboxAllow          []string      `arg:"--sandbox-allow,separate,env:OTEL_LOGGER_SANDBOX_ALLOW" help:"Directory otel-logger may still write to with --sandbox (repeatable)"`
+	Reexec                bool          `arg:"--reexec,env:OTEL_LOGGER_REEXEC" help:"On SIGUSR2, flush and re-execute the otel-logger binary at the same path, e.g. after an upgrade, while the wrapped command keeps running and no output is lost"`
+	PassthroughStdout     bool          `arg:"--passthrough-stdout,env:OTEL_LOGGER_PASSTHROUGH_STDOUT" help:"Pass command stdout to our stdout in addition to logging"`
+	PassthroughStderr     bool          `arg:"--passthrough-stderr,env:OTEL_LOGGER_PASSTHROUGH_STDERR" help:"Pass command stderr to our stderr in addition to logging"`
+	PassthroughRaw        bool          `arg:"--passthrough-raw,env:OTEL_LOGGER_PASSTHROUGH_RAW" help:"Copy passthrough output byte-for-byte as the command writes it instead of re-emitting parsed log entries"`
+	Grep                  string        `arg:"--grep,env:OTEL_LOGGER_GREP" help:"Only pass through entries matching this regex; everything is still exported (requires --passthrough-stdout or --passthrough-stderr)"`
+	TraceURL              string        `arg:"--trace-url,env:OTEL_LOGGER_TRACE_URL" help:"Trace UI URL template, e.g. https://jaeger.example.com/trace/{trace_id}; trace IDs in passthrough output to a terminal become clickable links (OSC 8) colored by severity ({span_id} is also replaced)"`
+	PassthroughClosed     string        `arg:"--passthrough-closed,env:OTEL_LOGGER_PASSTHROUGH_CLOSED" default:"continue" help:"When a passthrough output is closed (e.g. piped into head): continue exporting, or terminate the wrapped command"`
+	Verbose               bool          `arg:"--verbose,-v,env:OTEL_LOGGER_VERBOSE" help:"Enable verbose logging output"`
+	BinaryOutput          string        `arg:"--binary-output,env:OTEL_LOGGER_BINARY_OUTPUT" default:"sample" help:"When a stream turns binary: sample (export a notice with a base64 sample), skip (export nothing), or passthrough (copy it to the passthrough output only)"`
+	Files                 []string      `arg:"--file,separate,env:OTEL_LOGGER_FILE" help:"Follow the log files matching this glob, e.g. '/var/log/containers/*.log', including ones created later, instead of reading stdin (repeatable); records carry log.file.path"`
+	ListenSyslog          []string      `arg:"--listen-syslog,separate,env:OTEL_LOGGER_LISTEN_SYSLOG" help:"Receive RFC 5424 or RFC 3164 syslog on this address, udp://HOST:PORT, tcp://HOST:PORT or tls://HOST:PORT, instead of reading stdin (repeatable); records carry client.address and network.transport"`
+	SyslogTLSCert         string        `arg:"--syslog-tls-cert,env:OTEL_LOGGER_SYSLOG_TLS_CERT" help:"PEM certificate for --listen-syslog tls:// addresses"`
+	SyslogTLSKey          string        `arg:"--syslog-tls-key,env:OTEL_LOGGER_SYSLOG_TLS_KEY" help:"PEM private key for --listen-syslog tls:// addresses"`
+	ListenForward         []string      `arg:"--listen-forward,separate,env:OTEL_LOGGER_LISTEN_FORWARD" help:"Receive records from Fluentd and Fluent Bit over the Forward protocol on this address, tcp://HOST:PORT, instead of reading stdin (repeatable); records carry fluent.tag, client.address and network.transport"`
+	Journald              bool          `arg:"--journald,env:OTEL_LOGGER_JOURNALD" help:"Follow the systemd journal through journalctl instead of reading stdin; PRIORITY sets the severity and the unit, host and process fields become attributes"`
+	JournaldMatches       []string      `arg:"--journald-match,separate,env:OTEL_LOGGER_JOURNALD_MATCH" help:"Only follow journal entries matching this journalctl match, e.g. _SYSTEMD_UNIT=nginx.service (repeatable; matches of different fields must all hold, of the same field any)"`
+	DockerContainers      []string      `arg:"--docker-container,separate,env:OTEL_LOGGER_DOCKER_CONTAINER" help:"Follow the stdout and stderr of this container, by name or ID, through the Docker API (DOCKER_HOST) instead of reading stdin, across restarts (repeatable); records carry container.name, container.id and container.image.name"`
+	DockerAll             bool          `arg:"--docker-all,env:OTEL_LOGGER_DOCKER_ALL" help:"Follow every running container through the Docker API, including ones started later"`
+	DockerLabels          []string      `arg:"--docker-label,separate,env:OTEL_LOGGER_DOCKER_LABEL" help:"With --docker-all, only follow containers with this label, LABEL or LABEL=VALUE (repeatable; all must match)"`
+	K8sPods               []string      `arg:"--k8s-pod,separate,env:OTEL_LOGGER_K8S_POD" help:"Follow the logs of this pod's containers through the Kubernetes API instead of reading stdin, across container restarts (repeatable); records carry k8s.namespace.name, k8s.pod.name, k8s.pod.uid and k8s.container.name"`
+	K8sSelector           string        `arg:"--k8s-selector,env:OTEL_LOGGER_K8S_SELECTOR" help:"Follow every pod matching this label selector, e.g. app=web,tier!=cache, including pods created later"`
+	K8sNamespace          string        `arg:"--k8s-namespace,env:OTEL_LOGGER_K8S_NAMESPACE" help:"Namespace of --k8s-pod and --k8s-selector pods (default: otel-logger's own pod's namespace, or default)"`
+	K8sContainers         []string      `arg:"--k8s-container,separate,env:OTEL_LOGGER_K8S_CONTAINER" help:"Only follow the pods' containers of this name (repeatable; default: all)"`
+	K8sAPI                string        `arg:"--k8s-api,env:OTEL_LOGGER_K8S_API" help:"Kubernetes API server URL to use instead of the in-cluster service account, e.g. kubectl proxy's http://127.0.0.1:8001"`
+	FileFromStart         bool          `arg:"--file-from-start,env:OTEL_LOGGER_FILE_FROM_START" help:"Read the files --file matches at startup from the beginning, rather than only what is written to them from now on"`
+	Decompress            bool          `arg:"--decompress,env:OTEL_LOGGER_DECOMPRESS" help:"Decompress stdin if it is gzip or zstd data, told by its first bytes; --file archives are always decompressed"`
+	CheckpointDir         string        `arg:"--checkpoint-dir,env:OTEL_LOGGER_CHECKPOINT_DIR" help:"Save how far the records exported from each --file file, or the --journald journal, go in this directory, and resume from there on restart"`
+	CheckpointInterval    time.Duration `arg:"--checkpoint-interval,env:OTEL_LOGGER_CHECKPOINT_INTERVAL" default:"5s" help:"How often --checkpoint-dir offsets are saved, after flushing the records they cover"`
+	Framing               string        `arg:"--framing,env:OTEL_LOGGER_FRAMING" default:"lines" help:"How input is cut into records: lines, json-seq (RFC 7464 RS-delimited JSON), journal-export (journalctl -o export), msgpack or cbor (concatenated maps), protobuf (varint length-prefixed messages), avro (object container files), avro-confluent (Confluent wire format, with --schema-registry), cri (kubelet container logs) or docker-json (Docker json-file container logs)"`
+	ProtoDescriptor       string        `arg:"--proto-descriptor,env:OTEL_LOGGER_PROTO_DESCRIPTOR" help:"Descriptor set of --framing protobuf records (protoc --include_imports --descriptor_set_out)"`
+	ProtoMessage          string        `arg:"--proto-message,env:OTEL_LOGGER_PROTO_MESSAGE" help:"Fully qualified message type of --framing protobuf records, e.g. acme.logging.v1.Record"`
+	SchemaRegistry        string        `arg:"--schema-registry,env:OTEL_LOGGER_SCHEMA_REGISTRY" help:"URL of the Confluent-compatible schema registry for --framing avro-confluent; credentials may be given in the URL"`
+	CarriageReturn        string        `arg:"--carriage-return,env:OTEL_LOGGER_CARRIAGE_RETURN" default:"collapse" help:"Lines redrawn with \\r such as progress bars: collapse (export only the final state) or keep (export as-is)"`
+	ContinuationPattern   string        `arg:"--continuation-pattern,env:OTEL_LOGGER_CONTINUATION_PATTERN" default:"^[ \\t]" help:"Regex pattern for continuation lines (default: lines starting with whitespace; closing brackets ] } are also treated as continuations)"`
+	ReadBufferSize        string        `arg:"--read-buffer-size,env:OTEL_LOGGER_READ_BUFFER_SIZE" help:"Read input through a buffer of this size, e.g. 1MiB, so fast writers are drained in fewer reads; lines up to this long are accepted (default: 4KiB growing to 64KiB)"`
+	PipeBufferSize        string        `arg:"--pipe-buffer-size,env:OTEL_LOGGER_PIPE_BUFFER_SIZE" help:"Grow the wrapped command's stdout and stderr pipes to this size, e.g. 1MiB, so bursts of output do not block it (Linux only, up to /proc/sys/fs/pipe-max-size unless privileged; default: the kernel's 64KiB)"`
+	IOPollInterval        time.Duration `arg:"--io-poll-interval,env:OTEL_LOGGER_IO_POLL_INTERVAL" default:"1s" help:"How often --file files are checked for new data without a change notification, which network filesystems do not deliver"`
+	SkipPreflight         bool          `arg:"--skip-preflight,env:OTEL_LOGGER_SKIP_PREFLIGHT" help:"Skip probing the OTLP endpoint on startup"`
+	PreflightTimeout      time.Duration `arg:"--preflight-timeout,env:OTEL_LOGGER_PREFLIGHT_TIMEOUT" default:"2s" help:"Time allowed for the startup endpoint probe"`
+	MinLevel              string        `arg:"--min-level,env:OTEL_LOGGER_MIN_LEVEL" help:"Drop records below this level after parsing (trace, debug, info, warn, error, fatal, or a severity number 1-24); numeric levels and --severity-map are taken into account"`
+	SampleRatios          []string      `arg:"--sample-ratio,separate,env:OTEL_LOGGER_SAMPLE_RATIO" help:"Export this fraction of records, e.g. 0.1, or of one level's records, e.g. debug=0.01 (repeatable); records of a trace are kept or dropped together, and --always-keep-level and --always-keep-event records are never sampled"`
+	SampleRates           []string      `arg:"--sample-rate,separate,env:OTEL_LOGGER_SAMPLE_RATE" help:"Export at most this many records per second, minute or hour, e.g. 100/s, or of one level's records, e.g. info=1000/m (repeatable)"`
+	ControlSocket         string        `arg:"--control-socket,env:OTEL_LOGGER_CONTROL_SOCKET" help:"Listen on this unix socket for commands changing --min-level, --sample-ratio and --sample-rate while running, optionally for a while, e.g. 'min-level debug for 15m'"`
+	FlushOn               string        `arg:"--flush-on,env:OTEL_LOGGER_FLUSH_ON" help:"Force an immediate flush when a record at or above this level is seen (trace, debug, info, warn, error, fatal)"`
+	Redact                []string      `arg:"--redact,separate,env:OTEL_LOGGER_REDACT" help:"Mask values of this kind in the message, the original line and attribute values before export: email, credit-card, bearer-token, jwt or aws-access-key (repeatable)"`
+	RedactPatterns        []string      `arg:"--redact-pattern,separate,env:OTEL_LOGGER_REDACT_PATTERN" help:"Mask the matches of this regular expression like --redact, or only its capture groups if it has any, e.g. 'password=(\\S+)' (repeatable)"`
+	RenameFields          []string      `arg:"--rename-field,separate,env:OTEL_LOGGER_RENAME_FIELD" help:"Export a parsed top-level field under another name, as old=new, e.g. userId=enduser.id or status=http.response.status_code (repeatable); the other field options use the new name"`
+	Lookups               []string      `arg:"--lookup,separate,env:OTEL_LOGGER_LOOKUP" help:"Add the columns of a CSV, TSV or JSON table's row whose key matches a field, as field=file[:key][->column[=name],...], e.g. tenant_id=tenants.csv:id->tier,region (repeatable); fields the record has are kept"`
+	HashFields            []string      `arg:"--hash-field,separate,env:OTEL_LOGGER_HASH_FIELD" help:"Replace this field's value with a salted hash that stays joinable but is not reversible; dots lead into nested objects, as in user.id (repeatable)"`
+	HashSaltEnv           string        `arg:"--hash-salt-env,env:OTEL_LOGGER_HASH_SALT_ENV" help:"Name of the environment variable holding the secret salt for --hash-field"`
+	AttrAllowlist         []string      `arg:"--attr-allowlist,separate,env:OTEL_LOGGER_ATTR_ALLOWLIST" help:"Export only these attributes and drop all others, including log.record.original (a trailing * matches a prefix, e.g. http.*); the attributes otel-logger adds about its own processing, such as sampling.ratio, are kept"`
+	BodyMode              string        `arg:"--body-mode,env:OTEL_LOGGER_BODY_MODE" default:"message" help:"Record body: message (the message field, other fields as attributes) or object (the whole parsed object as a map, for backends that store the event as the body)"`
+	BodyFields            []string      `arg:"--body-field,separate,env:OTEL_LOGGER_BODY_FIELD" help:"Move this parsed field into a map body next to the message instead of an attribute (repeatable; a trailing * matches a prefix)"`
+	DropFields            []string      `arg:"--drop-field,separate,env:OTEL_LOGGER_DROP_FIELD" help:"Do not export this parsed field at all (repeatable; a trailing * matches a prefix)"`
+	ObjectArrays          string        `arg:"--object-arrays,env:OTEL_LOGGER_OBJECT_ARRAYS" default:"json" help:"How to export fields holding an array of objects: json (JSON text), slice (a slice of maps), explode (one attribute per element key, e.g. errors.0.code) or records (one child record per element)"`
+	Nested                string        `arg:"--nested,env:OTEL_LOGGER_NESTED" default:"map" help:"How fields holding a JSON object are exported: map (one map-valued attribute) or flatten (one attribute per leaf with joined keys, e.g. http.request.method)"`
+	FlattenDelimiter      string        `arg:"--flatten-delimiter,env:OTEL_LOGGER_FLATTEN_DELIMITER" default:"." help:"Separator between the keys joined by --nested flatten"`
+	FlattenDepth          int           `arg:"--flatten-depth,env:OTEL_LOGGER_FLATTEN_DEPTH" help:"Levels of nesting --nested flatten joins, deeper objects stay map values (0 for no limit)"`
+	DuplicateKeys         string        `arg:"--duplicate-keys,env:OTEL_LOGGER_DUPLICATE_KEYS" default:"last" help:"Attributes ending up with the same key: first, last (otel-logger's own attributes win over parsed fields) or suffix (keep all as key_2, key_3, ...); resolved keys are counted in otel_logger.duplicate_keys"`
+	AttrOrder             string        `arg:"--attr-order,env:OTEL_LOGGER_ATTR_ORDER" default:"sorted" help:"Order of the attributes from parsed fields, so output can be diffed: sorted (by key) or source (as the line has them); otel-logger's own attributes always follow"`
+	AnnotatePipeline      bool          `arg:"--annotate-pipeline,env:OTEL_LOGGER_ANNOTATE_PIPELINE" help:"Add otel_logger.version, otel_logger.config.hash (of the parsing settings, updated on reload) and otel_logger.preset to every record, to tell shipper versions and configs apart in the backend"`
+	AttrAllowlistCount    bool          `arg:"--attr-allowlist-count,env:OTEL_LOGGER_ATTR_ALLOWLIST_COUNT" help:"Record how many attributes the allowlist dropped in otel_logger.dropped_attributes"`
+	RuleAudit             string        `arg:"--rule-audit,env:OTEL_LOGGER_RULE_AUDIT" help:"Write per-rule hit counts of redaction, hashing and allowlist rules to this file (or stderr), locally and never exported"`
+	RuleAuditInterval     time.Duration `arg:"--rule-audit-interval,env:OTEL_LOGGER_RULE_AUDIT_INTERVAL" default:"1m" help:"How often --rule-audit writes a summary"`
+	NormalizeSeverityText string        `arg:"--normalize-severity-text,env:OTEL_LOGGER_NORMALIZE_SEVERITY_TEXT" default:"none" help:"Case of the exported SeverityText: lower, upper, title, or none (as found in the log)"`
+	ContextBuffer         int           `arg:"--context-buffer,env:OTEL_LOGGER_CONTEXT_BUFFER" help:"Hold back up to N records below --context-level and export them only when an error-level record follows"`
+	ContextLevel          string        `arg:"--context-level,env:OTEL_LOGGER_CONTEXT_LEVEL" default:"info" help:"Records below this level are held in the context buffer"`
+	AlwaysKeepLevel       string        `arg:"--always-keep-level,env:OTEL_LOGGER_ALWAYS_KEEP_LEVEL" default:"error" help:"Records at or above this level are never held back or dropped (empty for none)"`
+	AlwaysKeepEvents      []string      `arg:"--always-keep-event,separate,env:OTEL_LOGGER_ALWAYS_KEEP_EVENT" help:"Records whose event, event.name or event_name field has this value are never held back or dropped"`
+	ExportHelper          bool          `arg:"--export-helper,env:OTEL_LOGGER_EXPORT_HELPER" help:"Export from a detached helper process so handed-off logs are delivered even if otel-logger is killed"`
+	ScopePerStream        bool          `arg:"--scope-per-stream,env:OTEL_LOGGER_SCOPE_PER_STREAM" help:"Emit each stream under its own instrumentation scope (otel-logger/stdout, otel-logger/stderr, otel-logger/system)"`
+	NoInternalErrors      bool          `arg:"--no-internal-errors,env:OTEL_LOGGER_NO_INTERNAL_ERRORS" help:"Only report otel-logger's own errors, such as failed exports and unparsable lines, on stderr rather than also exporting them under the otel-logger/internal scope"`
+	Latency               bool          `arg:"--latency,env:OTEL_LOGGER_LATENCY" help:"Stamp each record with otel_logger.latency_ns, the time from reading its first line to handing it to the SDK, and export their percentiles every --latency-interval under the otel-logger/internal scope"`
+	LatencyInterval       time.Duration `arg:"--latency-interval,env:OTEL_LOGGER_LATENCY_INTERVAL" default:"1m" help:"How often --latency exports a summary of the latencies"`
+	ConfigFile            string        `arg:"--config,env:OTEL_LOGGER_CONFIG" help:"YAML file with processing rules (json_prefix, timestamp_fields, level_fields, message_fields, format, redact, min_level, drop_fields, ...); changes are applied without restarting"`
+	ConfigReloadInterval  time.Duration `arg:"--config-reload-interval,env:OTEL_LOGGER_CONFIG_RELOAD_INTERVAL" default:"2s" help:"How often to check the --config file for changes (0 disables reloading)"`
+	ProtocolFallback      bool          `arg:"--protocol-fallback,env:OTEL_LOGGER_PROTOCOL_FALLBACK" help:"If the endpoint does not answer the configured protocol on startup, fall back to the other OTLP protocol (grpc on 4317, http/protobuf on 4318)"`
+	RecordSession         string        `arg:"--record-session,env:OTEL_LOGGER_RECORD_SESSION" help:"Capture the raw input bytes and their timing into this directory, for reproducing parse problems with --replay-session; the input is recorded as read, not redacted"`
+	ReplaySession         string        `arg:"--replay-session,env:OTEL_LOGGER_REPLAY_SESSION" help:"Feed a session captured with --record-session through the pipeline instead of reading stdin or running a command"`
+	CheckUpdate           bool          `arg:"--check-update,env:OTEL_LOGGER_CHECK_UPDATE" help:"Report whether a newer release is available and exit"`
+	Command               []string      `arg:"positional" help:"Command to execute and capture logs from (if not provided, reads from stdin)"`
 }
 
 func (Config) Version() string {
@@ -55,7 +171,9 @@ func (Config) Description() string {
 It can handle JSON logs as well as partial JSON with prefixes like timestamps.
 Field mappings are configurable to support different logging frameworks.
 
-Configuration uses standard OpenTelemetry environment variables.
+Configuration uses standard OpenTelemetry environment variables. Every flag can
+also be set through an OTEL_LOGGER_* variable (e.g. --batch-size as
+OTEL_LOGGER_BATCH_SIZE); flags take precedence.
 
 Examples:
   # Read from stdin and send JSON logs via gRPC (uses default field mappings)
@@ -99,6 +217,13 @@ Examples:
   OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4317 cat app.log | otel-logger \
     --batch-size 100 --flush-interval 5s
 
+  # Use a built-in preset for a logging framework (see: otel-logger presets list)
+  otel-logger presets show logrus > logrus.yaml
+  cat app.log | otel-logger --config logrus.yaml
+
+  # Update a standalone install to the latest release (checksum verified)
+  otel-logger self-update
+
 Field Mapping Defaults:
   Timestamps: timestamp, ts, time, @timestamp
   Levels:     level, lvl, severity, priority
@@ -107,54 +232,167 @@ Field Mapping Defaults:
 When wrapping commands:
   - stdout logs are tagged with stream=stdout
   - stderr logs are tagged with stream=stderr
+  - --scope-per-stream additionally emits each stream under its own scope
   - Command exit code is logged as a final entry
+  - Streams closed early or failing to read are logged with a stream.event attribute
   - Signals are properly forwarded to the wrapped process`
 }
 
 // LogEntry represents a parsed log entry
 type LogEntry struct {
-	Timestamp time.Time
-	Level     string
-	Message   string
-	Fields    map[string]any
-	Raw       string
-	Stream    string // stdout, stderr, or empty for stdin
+	Timestamp       time.Time
+	TimestampSource string // record or ingest for parsed lines, empty for otel-logger's own records
+	Level           string
+	Message         string
+	Fields          map[string]any
+	Raw             string
+	Stream          string         // stdout, stderr, or empty for stdin
+	File            string         // file the record was read from with --file
+	LoggerName      string         // application logger name, e.g. com.example.UserService
+	Code            *CodeLocation  // where the log call was made, if the logger reported it
+	Thread          *ThreadInfo    // process and thread that wrote the record, if reported
+	Trace           *TraceContext  // span the record was written in, if logged
+	Severity        log.Severity   // set by numeric levels; otherwise derived from Level
+	Object          map[string]any // the whole decoded object, kept for --body-mode object
+	FieldOrder      []string       // keys in the order the line has them, for --attr-order source
+	ReadAt          time.Time      // when its first line was read, for --latency; zero if unknown
+	Internal        bool           // otel-logger's own record, such as the command's exit, which --min-level and sampling never drop
 }
 
 // FieldMappings defines configurable field name mappings for JSON log parsing
 type FieldMappings struct {
-	TimestampFields []string
-	LevelFields     []string
-	MessageFields   []string
+	TimestampFields  []string
+	LevelFields      []string
+	MessageFields    []string
+	LoggerNameFields []string
+	Format           string                  // how lines are decoded: json (the default), logfmt, syslog, clf, kv, postgres, mysql-slow, jvm, django-server, envoy or auto
+	LevelNumbers     map[string]string       // level names for numeric levels, e.g. "30": "info"
+	TimestampUnit    string                  // unit of numeric timestamps; told by their magnitude if empty
+	LevelScale       string                  // scale of numeric levels level_numbers does not name; bunyan if empty
+	SeverityMap      map[string]log.Severity // severities of levels by lowercased name or number
+	LogLinePrefix    string                  // PostgreSQL log_line_prefix for --format postgres
+	MDCFields        []string                // objects whose keys become attributes, e.g. Logback's MDC
+	StacktraceFields []string                // stack traces to export as exception.* attributes
+	NumberFields     []string                // string values to export as numbers, e.g. logfmt's status=200
+	MessageTemplate  string                  // message of lines without a message field, e.g. "{method} {path}"
+	RenameFields     fieldRenames            // fields exported under other names
 }
 
 // JSONExtractor helps extract JSON from potentially prefixed log lines
 type JSONExtractor struct {
+	mu            sync.RWMutex // guards the fields below against Reload
 	prefixRegex   *regexp.Regexp
 	fieldMappings *FieldMappings
+	now           func() time.Time // timestamp for entries that carry none
+	sourceOrder   bool             // record the order of keys for --attr-order source
+	keepObject    bool             // keep the whole decoded object for --body-mode object
+	cri           bool             // lines are in the CRI log format, for --framing cri and docker-json
+	detector      formatDetector   // picks the format for --format auto
 }
 
 // LogProcessor wraps the OpenTelemetry logger for stdin processing
 type LogProcessor struct {
-	logger log.Logger
+	handoff       *handoffWriter // when set, entries are sent to the export helper instead
+	logger        log.Logger
+	streamLoggers map[string]log.Logger        // optional per-stream scopes, keyed by stream name
+	flushOn       log.Severity                 // records at or above this severity trigger flush; 0 disables
+	flush         func(context.Context) error  // pushes emitted records to the exporter
+	ring          *contextBuffer               // optional ring buffer of held-back low-severity records
+	severityText  func(string) string          // optional SeverityText normalization
+	allowlist     *attributeAllowlist          // when set, only these attributes are exported
+	routing       atomic.Pointer[fieldRouting] // when set, moves fields into the body or drops them
+	objectArrays  string                       // --object-arrays policy; empty means JSON text
+	duplicateKeys string                       // --duplicate-keys policy; empty means last wins
+	sourceOrder   bool                         // export parsed fields in line order rather than by key
+	flattener     *flattener                   // when set, nested objects become one attribute per leaf
+	objectBody    bool                         // export decoded objects whole as a map body
+	redactor      atomic.Pointer[redactor]     // optional masking of secrets, before anything else sees the entry
+	minSeverity   atomic.Int64                 // log.Severity; records below it are dropped, 0 keeps all
+	sampler       atomic.Pointer[sampler]      // optional dropping of a share of records
+	renames       fieldRenames                 // optional renaming of fields before the rules below
+	lookups       lookupTables                 // optional enrichment from lookup tables
+	hasher        *fieldHasher                 // optional pseudonymization of field values
+	audit         *ruleAudit                   // optional local counts of rule hits
+	stopAudit     chan struct{}
+	namedScopes   *scopeLoggers // optional per-logger-name scopes
+	spanEvents    *spanEventEmitter
+	stats         *attrStats                // optional per-attribute size accounting
+	keep          atomic.Pointer[keepRules] // records exempt from being held back or dropped
+	annotation    *pipelineAnnotation       // optional version and config attributes on every record
+	latency       *latencyTracker           // optional read-to-emit latency of each record
+	stopLatency   chan struct{}
 }
 
+// defaultPrefixPattern matches common timestamp prefixes
+const defaultPrefixPattern = `^(\d{4}-\d{2}-\d{2}[T\s]\d{2}:\d{2}:\d{2}[.\d]*[Z\-+\d:]*\s*)?(.*)$`
+
 func NewJSONExtractor(prefix string, fieldMappings *FieldMappings) *JSONExtractor {
-	var regex *regexp.Regexp
-	if prefix != "" {
-		regex = regexp.MustCompile(prefix)
-	} else {
-		// Default pattern to match common timestamp prefixes
-		regex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}[T\s]\d{2}:\d{2}:\d{2}[.\d]*[Z\-+\d:]*\s*)?(.*)$`)
+	regex, err := compilePrefix(prefix)
+	if err != nil {
+		panic(err)
 	}
 	return &JSONExtractor{
 		prefixRegex:   regex,
 		fieldMappings: fieldMappings,
+		now:           time.Now,
+	}
+}
+
+func compilePrefix(prefix string) (*regexp.Regexp, error) {
+	if prefix == "" {
+		prefix = defaultPrefixPattern
 	}
+	return regexp.Compile(prefix)
+}
+
+// Reload atomically replaces the prefix pattern and field mappings, so a
+// running extractor can pick up configuration changes
+func (je *JSONExtractor) Reload(prefix string, fieldMappings *FieldMappings) error {
+	regex, err := compilePrefix(prefix)
+	if err != nil {
+		return fmt.Errorf("invalid JSON prefix pattern: %w", err)
+	}
+
+	je.mu.Lock()
+	defer je.mu.Unlock()
+	je.prefixRegex = regex
+	je.fieldMappings = fieldMappings
+	return nil
+}
+
+// SetSourceOrder records the order of the keys in each line, so attributes
+// can be exported in that order
+func (je *JSONExtractor) SetSourceOrder(enabled bool) {
+	je.sourceOrder = enabled
+}
+
+// SetKeepObject keeps a copy of each decoded object on the entry, so it can
+// be exported whole as the body
+func (je *JSONExtractor) SetKeepObject(enabled bool) {
+	je.keepObject = enabled
+}
+
+// SetCRI strips the CRI log format's prefix from each line before it is
+// parsed, taking the stream and, for lines without one, the timestamp from
+// it. --framing docker-json turns Docker's lines into this format too.
+func (je *JSONExtractor) SetCRI(enabled bool) {
+	je.cri = enabled
+}
+
+// snapshot returns the current prefix pattern and field mappings
+func (je *JSONExtractor) snapshot() (*regexp.Regexp, *FieldMappings) {
+	je.mu.RLock()
+	defer je.mu.RUnlock()
+	return je.prefixRegex, je.fieldMappings
 }
 
 func (je *JSONExtractor) ExtractJSON(line string) string {
-	matches := je.prefixRegex.FindStringSubmatch(line)
+	prefixRegex, _ := je.snapshot()
+	return extractJSON(prefixRegex, line)
+}
+
+func extractJSON(prefixRegex *regexp.Regexp, line string) string {
+	matches := prefixRegex.FindStringSubmatch(line)
 	if len(matches) == 0 {
 		return line
 	}
@@ -170,37 +408,136 @@ func (je *JSONExtractor) ExtractJSON(line string) string {
 	return line
 }
 
+// prefixCaptures returns the named groups of the prefix pattern, other than
+// the JSON group, e.g. the pid in `^\[(?P<pid>\d+)\] (.*)`
+func prefixCaptures(prefixRegex *regexp.Regexp, line string) map[string]string {
+	matches := prefixRegex.FindStringSubmatch(line)
+	if len(matches) < 3 {
+		return nil
+	}
+	captures := make(map[string]string)
+	for i, name := range prefixRegex.SubexpNames()[:len(matches)-1] {
+		if name != "" && matches[i] != "" {
+			captures[name] = matches[i]
+		}
+	}
+	return captures
+}
+
+// ParseLogEntry decodes a line in the configured format, JSON unless the
+// field mappings select another one, and applies the severity map. Records
+// without trace context fields are correlated by a traceparent in the message.
 func (je *JSONExtractor) ParseLogEntry(line string) (*LogEntry, error) {
+	_, fieldMappings := je.snapshot()
+	return je.ParseLogEntryAs(line, fieldMappings.Format)
+}
+
+// ParseLogEntryAs parses a line of the given format regardless of --format,
+// for input whose format is known, such as syslog received over the network
+func (je *JSONExtractor) ParseLogEntryAs(line, format string) (*LogEntry, error) {
+	_, fieldMappings := je.snapshot()
+	var envelope criEnvelope
+	var cri bool
+	if je.cri {
+		line, envelope, cri = decodeCRI(line)
+	}
+	entry, err := je.parseFormat(line, format)
+	if err == nil {
+		if cri {
+			envelope.apply(entry)
+		}
+		if entry.TimestampSource == "" {
+			entry.TimestampSource = timestampSourceRecord
+		}
+		applySeverityMap(entry, fieldMappings.SeverityMap)
+		if entry.Trace == nil {
+			entry.Trace = inlineTraceContext(entry.Message)
+		}
+	}
+	return entry, err
+}
+
+func (je *JSONExtractor) parseFormat(line, format string) (*LogEntry, error) {
+	switch format {
+	case formatLogfmt:
+		return je.ParseLogfmtEntry(line)
+	case formatSyslog:
+		return je.ParseSyslogEntry(line)
+	case formatCLF:
+		return je.ParseCLFEntry(line)
+	case formatKV:
+		return je.ParseKVEntry(line)
+	case formatPostgres:
+		return je.ParsePostgresEntry(line)
+	case formatMySQLSlow:
+		return je.ParseMySQLSlowEntry(line)
+	case formatJVM:
+		return je.ParseJVMEntry(line)
+	case formatDjango:
+		return je.ParseDjangoEntry(line)
+	case formatEnvoy:
+		return je.ParseEnvoyEntry(line)
+	case formatAuto:
+		return je.parseDetected(line), nil
+	}
+	if entry, ok := je.parseJSON(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+// parseJSON decodes a (possibly prefixed) JSON object line
+func (je *JSONExtractor) parseJSON(line string) (*LogEntry, bool) {
+	return je.parseEntry(line, decodeJSONObject, jsonKeyOrder)
+}
+
+// decodeJSONObject decodes a JSON object, reporting false for anything else
+func decodeJSONObject(s string) (map[string]any, bool) {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(s), &data); err != nil || data == nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// parseEntry decodes the part of the line after the prefix into fields and
+// picks out the timestamp, level, message and the other well-known fields
+// using the field mappings. It reports false for lines decode cannot read.
+// keyOrder lists the keys in the order the line has them, for --attr-order
+// source.
+func (je *JSONExtractor) parseEntry(line string, decode func(string) (map[string]any, bool), keyOrder func(string) []string) (*LogEntry, bool) {
+	prefixRegex, fieldMappings := je.snapshot()
+
+	// Extract the structured part from the line
+	structured := extractJSON(prefixRegex, line)
+	jsonData, ok := decode(structured)
+	if !ok {
+		return nil, false
+	}
+
 	entry := &LogEntry{
 		Fields: make(map[string]any),
 		Raw:    line,
 	}
-
-	// Extract JSON from the line
-	jsonStr := je.ExtractJSON(line)
-
-	// Try to parse as JSON
-	var jsonData map[string]any
-	if err := json.Unmarshal([]byte(jsonStr), &jsonData); err != nil {
-		// If JSON parsing fails, treat the entire line as a message
-		entry.Message = strings.TrimSpace(line)
-		entry.Timestamp = time.Now()
-		entry.Level = "info"
-		return entry, nil
+	if je.keepObject {
+		entry.Object = maps.Clone(jsonData)
 	}
 
 	// Extract timestamp using configurable field mappings
 	timestampExtracted := false
-	for _, field := range je.fieldMappings.TimestampFields {
+	for _, field := range fieldMappings.TimestampFields {
 		if timestampStr, ok := jsonData[field].(string); ok {
 			if t, err := parseTimestamp(timestampStr); err == nil {
 				entry.Timestamp = t
 				timestampExtracted = true
+			} else if t, ok := epochString(timestampStr, fieldMappings.TimestampUnit); ok {
+				entry.Timestamp = t
+				timestampExtracted = true
 			}
 			delete(jsonData, field)
 			break
 		} else if timestampNum, ok := jsonData[field].(float64); ok {
-			entry.Timestamp = time.Unix(int64(timestampNum), 0)
+			entry.Timestamp = epochTime(timestampNum, fieldMappings.TimestampUnit)
 			timestampExtracted = true
 			delete(jsonData, field)
 			break
@@ -208,12 +545,44 @@ func (je *JSONExtractor) ParseLogEntry(line string) (*LogEntry, error) {
 	}
 
 	if !timestampExtracted || entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
+		je.stampIngestTime(entry)
 	}
 
 	// Extract level using configurable field mappings
 	levelExtracted := false
-	for _, field := range je.fieldMappings.LevelFields {
+	for _, field := range fieldMappings.LevelFields {
+		if syslogPriorityFields[field] {
+			if facility, severity, ok := decodeSyslogPriority(jsonData[field]); ok {
+				entry.Level = severity
+				levelExtracted = true
+				delete(jsonData, field)
+				jsonData["syslog.facility"] = facility
+				break
+			}
+		}
+		// Numbers in --severity-map are kept as the level for it to map
+		if number, ok := jsonData[field].(float64); ok {
+			if key, _ := levelKey(number); fieldMappings.SeverityMap[key] != 0 {
+				entry.Level = key
+				levelExtracted = true
+				delete(jsonData, field)
+				break
+			}
+		}
+		if level, ok := levelNumber(jsonData[field], fieldMappings.LevelNumbers); ok {
+			entry.Level = level
+			levelExtracted = true
+			delete(jsonData, field)
+			break
+		}
+		if number, ok := jsonData[field].(float64); ok {
+			if level, severity, ok := numericSeverity(number, fieldMappings.LevelScale); ok {
+				entry.Level, entry.Severity = level, severity
+				levelExtracted = true
+				delete(jsonData, field)
+				break
+			}
+		}
 		if level, ok := jsonData[field].(string); ok {
 			entry.Level = level
 			levelExtracted = true
@@ -227,7 +596,7 @@ func (je *JSONExtractor) ParseLogEntry(line string) (*LogEntry, error) {
 
 	// Extract message using configurable field mappings
 	messageExtracted := false
-	for _, field := range je.fieldMappings.MessageFields {
+	for _, field := range fieldMappings.MessageFields {
 		if message, ok := jsonData[field].(string); ok {
 			entry.Message = message
 			messageExtracted = true
@@ -239,10 +608,195 @@ func (je *JSONExtractor) ParseLogEntry(line string) (*LogEntry, error) {
 		entry.Message = "Log entry"
 	}
 
+	// Named prefix groups fill in fields the JSON itself does not have
+	for name, value := range prefixCaptures(prefixRegex, line) {
+		if _, ok := jsonData[name]; !ok {
+			jsonData[name] = value
+		}
+	}
+
+	// MDC keys such as traceId are lifted before the fields below are found
+	liftMDC(jsonData, fieldMappings.MDCFields)
+	extractException(jsonData, fieldMappings.StacktraceFields)
+
+	entry.Code = extractCodeLocation(jsonData)
+	entry.Thread = extractThreadInfo(jsonData)
+	entry.Trace = extractTraceContext(jsonData)
+
+	// Extract the application's logger name, if configured
+	for _, field := range fieldMappings.LoggerNameFields {
+		if name, ok := jsonData[field].(string); ok && name != "" {
+			entry.LoggerName = name
+			delete(jsonData, field)
+			break
+		}
+	}
+
+	convertNumbers(jsonData, fieldMappings.NumberFields)
+	if !messageExtracted && fieldMappings.MessageTemplate != "" {
+		entry.Message = expandMessageTemplate(fieldMappings.MessageTemplate, jsonData)
+	}
+
 	// Store remaining fields
 	entry.Fields = jsonData
+	if je.sourceOrder {
+		entry.FieldOrder = keyOrder(structured)
+	}
+	if len(fieldMappings.RenameFields) > 0 {
+		entry = fieldMappings.RenameFields.apply(entry)
+	}
+
+	return entry, true
+}
+
+// defaultExtractor parses lines for ParseLine using the default field mappings
+var defaultExtractor = NewJSONExtractor("", getDefaultFieldMappings())
+
+// ParseLine parses a single log line with the default settings. It is the
+// entry point for fuzzers: arbitrary input never panics, a failure of the
+// parser is returned as an error instead.
+func ParseLine(line []byte) (entry LogEntry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			entry, err = LogEntry{}, fmt.Errorf("panic parsing line: %v", r)
+		}
+	}()
+
+	parsed, err := defaultExtractor.ParseLogEntry(string(line))
+	if err != nil {
+		return LogEntry{}, err
+	}
+	return *parsed, nil
+}
+
+// Units of numeric timestamps
+const (
+	timestampSeconds      = "s"
+	timestampMilliseconds = "ms"
+	timestampMicroseconds = "us"
+	timestampNanoseconds  = "ns"
+)
+
+func validTimestampUnit(unit string) error {
+	switch unit {
+	case "", timestampSeconds, timestampMilliseconds, timestampMicroseconds, timestampNanoseconds:
+		return nil
+	default:
+		return fmt.Errorf("invalid timestamp_unit %q (supported: s, ms, us, ns)", unit)
+	}
+}
+
+// epochTime converts a numeric timestamp in the given unit, keeping the
+// fraction of seconds such as zap's 1705315845.123 to the microsecond.
+// Without a unit it is told by the magnitude, see epochUnit.
+func epochTime(value float64, unit string) time.Time {
+	if unit == "" {
+		unit = epochUnit(math.Abs(value))
+	}
+	switch unit {
+	case timestampMilliseconds:
+		return time.UnixMicro(int64(math.Round(value * 1e3)))
+	case timestampMicroseconds:
+		return time.UnixMicro(int64(math.Round(value)))
+	case timestampNanoseconds:
+		return time.Unix(0, int64(value))
+	default:
+		seconds, fraction := math.Modf(value)
+		return time.Unix(int64(seconds), int64(math.Round(fraction*1e6))*1e3)
+	}
+}
+
+// epochUnit guesses the unit of an epoch timestamp from its magnitude, as
+// seconds, milliseconds, microseconds and nanoseconds since 1973 until 5138
+// do not overlap
+func epochUnit(magnitude float64) string {
+	switch {
+	case magnitude < 1e11:
+		return timestampSeconds
+	case magnitude < 1e14:
+		return timestampMilliseconds
+	case magnitude < 1e17:
+		return timestampMicroseconds
+	default:
+		return timestampNanoseconds
+	}
+}
+
+// epochDigits is how many fraction digits of each unit make a nanosecond
+var epochDigits = map[string]int{
+	timestampSeconds:      9,
+	timestampMilliseconds: 6,
+	timestampMicroseconds: 3,
+	timestampNanoseconds:  0,
+}
+
+// minEpochStringDigits keeps strings such as 20240115 from being read as
+// epochs; nine digits of seconds go back to 1973
+const minEpochStringDigits = 9
+
+// epochString parses a timestamp written as a string of digits, such as
+// "1705315845123" or logfmt's ts=1705315845.123456, exactly to the
+// nanosecond rather than through a float64
+func epochString(s, unit string) (time.Time, bool) {
+	whole, fraction, _ := strings.Cut(s, ".")
+	if len(whole) < minEpochStringDigits || !isDigits(whole) || !isDigits(fraction) {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if unit == "" {
+		unit = epochUnit(float64(n))
+	}
+	digits := epochDigits[unit]
+	scale := int64(math.Pow10(digits))
+	if n > math.MaxInt64/scale {
+		return time.Time{}, false
+	}
+	// Digits beyond the nanosecond are dropped
+	fraction = (fraction + strings.Repeat("0", digits))[:digits]
+	var nanos int64
+	if fraction != "" {
+		nanos, _ = strconv.ParseInt(fraction, 10, 64)
+	}
+	return time.Unix(0, n*scale+nanos), true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// levelNumber looks up a numeric level, given as a JSON number or a string
+// of digits, in the level_numbers table
+func levelNumber(value any, levels map[string]string) (string, bool) {
+	if len(levels) == 0 {
+		return "", false
+	}
+	key, ok := levelKey(value)
+	if !ok {
+		return "", false
+	}
+	level, ok := levels[key]
+	return level, ok
+}
 
-	return entry, nil
+// levelKey is a level value as text: strings as they are, numbers without a
+// trailing .0
+func levelKey(value any) (string, bool) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case string:
+		return v, true
+	default:
+		return "", false
+	}
 }
 
 func parseTimestamp(timeStr string) (time.Time, error) {
@@ -269,43 +823,411 @@ func NewLogProcessor(logger log.Logger) *LogProcessor {
 	return &LogProcessor{logger: logger}
 }
 
+// NewStreamScopedLogProcessor creates a processor that emits stdout, stderr and
+// system entries under separate instrumentation scopes named "<name>/<stream>".
+// Entries without a stream (stdin mode) use the "<name>" scope.
+func NewStreamScopedLogProcessor(provider log.LoggerProvider, name string) *LogProcessor {
+	processor := &LogProcessor{
+		logger:        provider.Logger(name),
+		streamLoggers: make(map[string]log.Logger),
+	}
+	for _, stream := range []string{"stdout", "stderr", "system"} {
+		processor.streamLoggers[stream] = provider.Logger(name + "/" + stream)
+	}
+	return processor
+}
+
+// SetFlusher sets the function used to push emitted records to the exporter
+func (p *LogProcessor) SetFlusher(flush func(context.Context) error) {
+	p.flush = flush
+}
+
+// SetFlushOn makes the processor flush synchronously after emitting any
+// record at or above the given severity
+func (p *LogProcessor) SetFlushOn(threshold log.Severity) {
+	p.flushOn = threshold
+}
+
+// SetContextBuffer holds back up to size records below the given severity and
+// exports them just before the next error-level record
+func (p *LogProcessor) SetContextBuffer(size int, below log.Severity) {
+	p.ring = newContextBuffer(size, below, log.SeverityError1)
+}
+
+// SetSeverityTextNormalizer sets the function applied to each exported
+// SeverityText; nil exports the level text as found
+func (p *LogProcessor) SetSeverityTextNormalizer(normalize func(string) string) {
+	p.severityText = normalize
+}
+
+// SetRedactor masks secrets and personal data in every entry before it is
+// processed or handed off; config reloads replace it
+func (p *LogProcessor) SetRedactor(r *redactor) {
+	if r != nil && p.audit != nil {
+		r.setAudit(p.audit)
+	}
+	p.redactor.Store(r)
+}
+
+// SetFieldRenames exports fields under other names; the field options
+// applied after it see the new names
+func (p *LogProcessor) SetFieldRenames(renames fieldRenames) {
+	p.renames = renames
+}
+
+// SetLookups adds fields from lookup tables, matched on the values the
+// fields have after renaming and before hashing
+func (p *LogProcessor) SetLookups(lookups lookupTables) {
+	p.lookups = lookups
+}
+
+// SetFieldHasher replaces the configured field values with keyed hashes
+func (p *LogProcessor) SetFieldHasher(hasher *fieldHasher) {
+	p.hasher = hasher
+}
+
+// SetRuleAudit counts the hits of the redaction, hashing and allowlist rules
+// set so far and reports them every interval until FinishRuleAudit
+func (p *LogProcessor) SetRuleAudit(audit *ruleAudit, interval time.Duration) {
+	p.audit = audit
+	if r := p.redactor.Load(); r != nil {
+		r.setAudit(audit)
+	}
+	if p.hasher != nil {
+		p.hasher.audit = audit
+		for field := range p.hasher.fields {
+			audit.register("hash-field:" + field)
+		}
+	}
+	if p.allowlist != nil {
+		p.allowlist.audit = audit
+		audit.register("attr-allowlist")
+	}
+
+	p.stopAudit = make(chan struct{})
+	go audit.run(interval, p.stopAudit)
+}
+
+// Finish stops the processor's background work and flushes what it exports
+// outside the logger provider. Call it once all entries are processed.
+func (p *LogProcessor) Finish(ctx context.Context) {
+	p.FinishRuleAudit()
+	p.finishAttrStats()
+	p.finishLatency()
+	if p.spanEvents != nil {
+		if err := p.spanEvents.shutdown(ctx); err != nil {
+			logError("Error shutting down span events: %v\n", err)
+		}
+	}
+}
+
+// Flush pushes the records emitted so far, and span events, to the exporters
+func (p *LogProcessor) Flush(ctx context.Context) error {
+	if p.flush != nil {
+		if err := p.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if p.spanEvents != nil && p.spanEvents.flush != nil {
+		return p.spanEvents.flush(ctx)
+	}
+	return nil
+}
+
+// Barrier returns once the records processed so far are exported, so the
+// records that follow reach the backend after them. Handing off to the
+// export helper, it has the helper flush before it goes on.
+func (p *LogProcessor) Barrier(ctx context.Context) error {
+	if p.handoff != nil {
+		p.handoff.SendBarrier()
+		return nil
+	}
+	return p.Flush(ctx)
+}
+
+// FinishRuleAudit reports the final counts and closes the audit sink
+func (p *LogProcessor) FinishRuleAudit() {
+	if p.audit == nil {
+		return
+	}
+	close(p.stopAudit)
+	p.audit.report()
+	if err := p.audit.Close(); err != nil {
+		logError("Failed to close rule audit: %v\n", err)
+	}
+	p.audit = nil
+}
+
+// SetAttributeAllowlist exports only the named attributes, dropping the rest
+func (p *LogProcessor) SetAttributeAllowlist(allowlist *attributeAllowlist) {
+	p.allowlist = allowlist
+}
+
+// SetFieldRouting moves parsed fields into the body or drops them instead of
+// exporting every field as an attribute; config reloads replace it
+func (p *LogProcessor) SetFieldRouting(routing *fieldRouting) {
+	p.routing.Store(routing)
+}
+
+// SetObjectArrays sets how fields holding an array of objects are exported
+func (p *LogProcessor) SetObjectArrays(policy string) {
+	p.objectArrays = policy
+}
+
+// SetDuplicateKeys sets how attributes sharing a key are resolved
+func (p *LogProcessor) SetDuplicateKeys(policy string) {
+	p.duplicateKeys = policy
+}
+
+// SetAttributeOrder sets the order parsed fields are exported in: by key, or
+// as the line has them
+func (p *LogProcessor) SetAttributeOrder(order string) {
+	p.sourceOrder = order == attrOrderSource
+}
+
+// SetBodyMode sets whether records carry the message or the whole decoded
+// object as their body
+func (p *LogProcessor) SetBodyMode(mode string) {
+	p.objectBody = mode == bodyModeObject
+}
+
+// SetFlattener flattens nested objects into attributes with joined keys
+// instead of exporting them as map values
+func (p *LogProcessor) SetFlattener(f *flattener) {
+	p.flattener = f
+}
+
+// SetPipelineAnnotation adds attributes describing the pipeline to every
+// record; config reloads replace them
+func (p *LogProcessor) SetPipelineAnnotation(attrs []log.KeyValue) {
+	if p.annotation == nil {
+		p.annotation = &pipelineAnnotation{}
+	}
+	p.annotation.set(attrs)
+}
+
+// loggerFor returns the logger responsible for the given stream
+func (p *LogProcessor) loggerFor(stream string) log.Logger {
+	if logger, ok := p.streamLoggers[stream]; ok {
+		return logger
+	}
+	return p.logger
+}
+
 func (p *LogProcessor) ProcessLogEntry(ctx context.Context, entry *LogEntry) {
+	if p.belowMinSeverity(entry) {
+		return
+	}
+	samplingRatio := 1.0
+	if s := p.sampler.Load(); s != nil && !entry.Internal && !p.keep.Load().keeps(entry, entry.severity()) {
+		var sampled bool
+		if sampled, samplingRatio = s.sample(entry); !sampled {
+			return
+		}
+	}
+	p.emitEntry(ctx, entry, samplingRatio)
+}
+
+// emitEntry exports an entry that --min-level and sampling let through
+func (p *LogProcessor) emitEntry(ctx context.Context, entry *LogEntry, samplingRatio float64) {
+	buffers := getEmitBuffers(0)
+	defer putEmitBuffers(buffers)
+	if p.emit(ctx, entry, samplingRatio, buffers) {
+		p.flushAfter(ctx, entry.Level)
+	}
+}
+
+// emit builds and exports the record for an entry in buffers, and reports
+// whether its severity calls for a flush, which is left to the caller
+func (p *LogProcessor) emit(ctx context.Context, entry *LogEntry, samplingRatio float64, buffers *emitBuffers) (flush bool) {
+	// Secrets are masked before anything else sees the entry. Entries handed
+	// off are masked by the export helper, which has the same options.
+	if r := p.redactor.Load(); r != nil {
+		entry = r.apply(entry)
+	}
+	if p.handoff != nil {
+		p.handoff.Send(entry)
+		return false
+	}
+
+	if p.renames != nil {
+		entry = p.renames.apply(entry)
+	}
+	if p.lookups != nil {
+		entry = p.lookups.apply(entry)
+	}
+	if p.hasher != nil {
+		entry = p.hasher.apply(entry)
+	}
+	ctx = contextWithTrace(ctx, entry)
+
 	// Create log record using OTEL API
 	var record log.Record
 	record.SetTimestamp(entry.Timestamp)
-	record.SetBody(log.StringValue(entry.Message))
-	record.SetSeverityText(entry.Level)
-	record.SetSeverity(logLevelToSeverity(entry.Level))
+	body, fields := log.StringValue(entry.Message), entry.Fields
+	if p.objectBody && entry.Object != nil {
+		// The fields are all in the body already
+		body, fields = fieldValue(entry.Object), nil
+	} else if routing := p.routing.Load(); routing != nil {
+		var bodyFields map[string]any
+		if bodyFields, fields = routing.route(entry.Fields); bodyFields != nil {
+			body = bodyValue(entry.Message, bodyFields)
+		}
+	}
+	record.SetBody(body)
+	severityText := entry.Level
+	if p.severityText != nil {
+		severityText = p.severityText(severityText)
+	}
+	record.SetSeverityText(severityText)
+	record.SetSeverity(entry.severity())
 
 	// Add attributes from parsed fields
-	attrs := make([]log.KeyValue, 0, len(entry.Fields)+3)
-	for key, value := range entry.Fields {
-		var valueStr string
-		switch v := value.(type) {
-		case map[string]any, []any:
-			if jsonBytes, err := json.Marshal(v); err == nil {
-				valueStr = string(jsonBytes)
-			} else {
-				valueStr = fmt.Sprintf("%v", v)
+	attrs := slices.Grow(buffers.attrs[:0], len(fields)+3)
+	defer func() { buffers.attrs = attrs[:0] }()
+	var childFields []objectArrayField
+	var order []string
+	if p.sourceOrder {
+		order = entry.FieldOrder
+	}
+	if p.flattener != nil {
+		var flatOrder []string
+		fields, flatOrder = p.flattener.flatten(fields, order)
+		if p.sourceOrder {
+			order = flatOrder
+		}
+	}
+	buffers.fieldKeys = orderedKeysInto(buffers.fieldKeys[:0], fields, order)
+	for _, key := range buffers.fieldKeys {
+		value := fields[key]
+		if objects, ok := objectArray(value); ok {
+			switch p.objectArrays {
+			case objectArraysSlice:
+				attrs = append(attrs, log.KeyValue{Key: key, Value: fieldValue(value)})
+			case objectArraysExplode:
+				attrs = append(attrs, explodeObjects(key, objects)...)
+			case objectArraysRecords:
+				childFields = append(childFields, objectArrayField{key: key, objects: objects})
+			default:
+				attrs = append(attrs, log.String(key, attributeString(value)))
 			}
-		default:
-			valueStr = fmt.Sprintf("%v", v)
+			continue
 		}
-		attrs = append(attrs, log.String(key, valueStr))
+		// Numbers, booleans, arrays and objects keep their type
+		attrs = append(attrs, log.KeyValue{Key: key, Value: fieldValue(value)})
 	}
 
 	// Add standard attributes
-	attrs = append(attrs, log.KeyValueFromAttribute(semconv.LogRecordOriginal(entry.Raw)))
+	if entry.Code != nil {
+		attrs = append(attrs, entry.Code.attributes()...)
+	}
+	if entry.Thread != nil {
+		attrs = append(attrs, entry.Thread.attributes()...)
+	}
+	if entry.Raw != "" {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.LogRecordOriginal(entry.Raw)))
+	}
 
 	// Add stream information if available
 	if entry.Stream != "" {
 		attrs = append(attrs, log.KeyValueFromAttribute(semconv.LogIostreamKey.String(entry.Stream)))
 	}
+	if entry.File != "" {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.LogFilePath(entry.File)))
+	}
+
+	logger := p.loggerFor(entry.Stream)
+	if entry.LoggerName != "" {
+		if p.namedScopes != nil {
+			logger = p.namedScopes.logger(entry.LoggerName)
+		} else {
+			attrs = append(attrs, log.String(loggerNameKey, entry.LoggerName))
+		}
+	}
+
+	clear(buffers.index)
+	deduped, duplicates := dedupeAttributesInto(buffers.deduped[:0], buffers.index, attrs, p.duplicateKeys)
+	defer func() { buffers.deduped = deduped[:0] }()
+
+	// The allowlist selects what is exported of the log's own data; the
+	// attributes otel-logger adds below describe how the record was
+	// processed and are exempt
+	if p.allowlist != nil {
+		deduped = p.allowlist.filter(deduped)
+	}
+	if duplicates > 0 {
+		deduped = append(deduped, log.Int(duplicateKeysKey, duplicates))
+	}
+	if samplingRatio < 1 {
+		deduped = append(deduped, log.Float64(samplingRatioKey, samplingRatio))
+	}
+	if entry.TimestampSource != "" {
+		deduped = append(deduped, log.String(timestampSourceKey, entry.TimestampSource))
+	}
+	if p.latency != nil && !entry.ReadAt.IsZero() {
+		deduped = append(deduped, log.Int64(latencyKey, int64(p.latency.observe(entry.ReadAt))))
+	}
+	deduped = append(deduped, p.annotation.get()...)
+
+	var children []log.Record
+	if len(childFields) > 0 {
+		id := uuid.NewString()
+		var extra []log.KeyValue
+		if entry.Stream != "" {
+			extra = append(extra, log.KeyValueFromAttribute(semconv.LogIostreamKey.String(entry.Stream)))
+		}
+		if entry.File != "" {
+			extra = append(extra, log.KeyValueFromAttribute(semconv.LogFilePath(entry.File)))
+		}
+		children = childRecords(record, id, childFields, extra, p.allowlist)
+		deduped = append(deduped, log.String(recordIDKey, id))
+	}
 
-	record.AddAttributes(attrs...)
+	// The record copies the attributes, so the buffers can take the next one
+	record.AddAttributes(deduped...)
+
+	if p.ring != nil {
+		if p.holdsForContext(entry, record.Severity()) && p.ring.hold(ctx, logger, record) {
+			for _, child := range children {
+				p.ring.hold(ctx, logger, child)
+			}
+			return false
+		}
+		for _, held := range p.ring.release(record.Severity()) {
+			p.stats.add(held.record)
+			held.logger.Emit(held.ctx, held.record)
+		}
+	}
 
 	// Emit the record through OTEL SDK
-	p.logger.Emit(ctx, record)
+	p.stats.add(record)
+	logger.Emit(ctx, record)
+	for _, child := range children {
+		p.stats.add(child)
+		logger.Emit(ctx, child)
+	}
+
+	if p.spanEvents != nil {
+		p.spanEvents.emit(ctx, entry, record.Severity())
+	}
+
+	return p.flush != nil && p.flushOn != 0 && record.Severity() >= p.flushOn
+}
+
+// flushAfter flushes for --flush-on after a record at level
+func (p *LogProcessor) flushAfter(ctx context.Context, level string) {
+	if err := p.flush(ctx); err != nil {
+		logError("Error flushing logs after %s record: %v\n", level, err)
+	}
+}
+
+// severity is the entry's severity, from its level name unless a numeric
+// level set it more precisely
+func (e *LogEntry) severity() log.Severity {
+	if e.Severity != 0 {
+		return e.Severity
+	}
+	return logLevelToSeverity(e.Level)
 }
 
 func logLevelToSeverity(level string) log.Severity {
@@ -316,38 +1238,106 @@ func logLevelToSeverity(level string) log.Severity {
 		return log.SeverityDebug1
 	case "info":
 		return log.SeverityInfo1
+	case "notice":
+		return log.SeverityInfo2
 	case "warn", "warning":
 		return log.SeverityWarn1
-	case "error":
+	case "error", "err":
 		return log.SeverityError1
-	case "fatal":
+	case "crit", "critical":
+		return log.SeverityError2
+	case "fatal", "alert":
 		return log.SeverityFatal1
+	case "emerg":
+		return log.SeverityFatal2
 	default:
 		return log.SeverityInfo1
 	}
 }
 
-func createExporter(ctx context.Context) (sdklog.Exporter, error) {
-	protocol := "http/protobuf"
-	if proto, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL"); ok {
-		protocol = proto
-	} else if proto, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_PROTOCOL"); ok {
-		protocol = proto
-	}
-	switch strings.ToLower(protocol) {
-	case "grpc":
-		return otlploggrpc.New(ctx)
-	case "http", "http/protobuf", "http/json":
-		return otlploghttp.New(ctx)
+// parseSeverityLevel strictly parses a level name given on the command line
+func parseSeverityLevel(level string) (log.Severity, error) {
+	switch strings.ToLower(level) {
+	case "trace", "debug", "info", "warn", "warning", "error", "fatal":
+		return logLevelToSeverity(level), nil
 	default:
-		return nil, fmt.Errorf("unsupported protocol (supported: grpc, http/protobuf, http/json): %s", protocol)
+		return 0, fmt.Errorf("unknown log level %q (supported: trace, debug, info, warn, error, fatal)", level)
 	}
 }
 
-func createLoggerProvider(ctx context.Context, config *Config) (*sdklog.LoggerProvider, error) {
-	exporter, err := createExporter(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create exporter: %w", err)
+func createExporter(ctx context.Context, config *Config) (sdklog.Exporter, error) {
+	if config.BigQueryTable != "" {
+		return newBigQueryExporter(config)
+	}
+	if config.ExportFile != "" {
+		return newExportFileExporter(config)
+	}
+
+	protocol := resolveProtocol()
+
+	if len(config.Endpoints) > 0 {
+		return newBalancedExporter(ctx, config, protocol)
+	}
+
+	if config.ProtocolFallback {
+		if ep := negotiateEndpoint(ctx, config, protocol); ep != nil {
+			return newEndpointExporter(ctx, config, ep, nil)
+		}
+	}
+
+	// Given TLS settings, the exporters no longer load their own from the
+	// environment, so under a TLS policy they get an explicit endpoint
+	policy := outboundTLS(config)
+	if policy != nil && (protocol == "grpc" || protocol == "http" || protocol == "http/protobuf") {
+		ep, err := resolveEndpoint(protocol)
+		if err != nil {
+			return nil, err
+		}
+		return newEndpointExporter(ctx, config, ep, nil)
+	}
+
+	switch protocol {
+	case "grpc":
+		return newGRPCExporter(ctx, config)
+	case "http", "http/protobuf":
+		return otlploghttp.New(ctx)
+	case "http/json":
+		client, err := newJSONHTTPClient(config.Timeout, policy)
+		if err != nil {
+			return nil, err
+		}
+		return otlploghttp.New(ctx, otlploghttp.WithHTTPClient(client))
+	default:
+		return nil, fmt.Errorf("unsupported protocol (supported: grpc, http/protobuf, http/json): %s", protocol)
+	}
+}
+
+func createLoggerProvider(ctx context.Context, config *Config) (*sdklog.LoggerProvider, error) {
+	limits, err := recordLimitOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := createResource(config)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := createExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+	if exporter, err = withEndpointFallback(ctx, config, exporter); err != nil {
+		return nil, err
+	}
+	if exporter, err = withTenantHeaders(ctx, config, exporter); err != nil {
+		return nil, err
+	}
+	if exporter, err = withRecordSizeLimit(config, exporter); err != nil {
+		return nil, err
+	}
+	if exporter, err = withExportReceipts(config, exporter); err != nil {
+		return nil, err
 	}
 
 	// Create processor with batching configuration
@@ -359,7 +1349,7 @@ func createLoggerProvider(ctx context.Context, config *Config) (*sdklog.LoggerPr
 
 	// Create logger provider
 	provider := sdklog.NewLoggerProvider(
-		sdklog.WithProcessor(processor),
+		append([]sdklog.LoggerProviderOption{sdklog.WithProcessor(processor), sdklog.WithResource(res)}, limits...)...,
 	)
 
 	return provider, nil
@@ -380,8 +1370,12 @@ func logInfo(verbose bool, format string, args ...any) {
 	}
 }
 
+// logError reports an error on stderr and, unless --no-internal-errors is
+// set, as a record of its own
 func logError(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, format, args...)
+	message := fmt.Sprintf(format, args...)
+	fmt.Fprint(os.Stderr, message)
+	reportInternalError(internalErrorRuntime, message)
 }
 
 func logDebug(verbose bool, format string, args ...any) {
@@ -393,10 +1387,44 @@ func logDebug(verbose bool, format string, args ...any) {
 // multilineLogIterator creates an iterator that combines multiline log entries
 // based on improved heuristics for detecting log entry starts
 func multilineLogIterator(reader io.Reader, continuationPattern *regexp.Regexp) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for entry := range multilineLogIteratorWith(reader, continuationPattern, multilineOptions{}) {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// utf8BOM is the byte order mark Windows tools write at the start of UTF-8 files
+const utf8BOM = "\ufeff"
+
+// cleanLine strips what Windows-produced logs add around a line: a byte order
+// mark, which also shows up mid-stream when files are concatenated, and
+// carriage returns left over from "\r\n" line endings
+func cleanLine(line string) string {
+	return strings.TrimRight(strings.TrimPrefix(line, utf8BOM), "\r")
+}
+
+// multilineOptions tune multilineLogIteratorWith; the zero value reads lines
+// as multilineLogIterator does
+type multilineOptions struct {
+	split      bufio.SplitFunc // splits the input into lines; bufio.ScanLines when nil
+	bufferSize int             // read buffer size, or the scanner's default when 0
+	err        *error          // when set, receives the read error, if any, once the input is exhausted
+}
+
+// multilineLogIteratorWith is multilineLogIterator with options, also
+// yielding when the first line of each entry was read
+func multilineLogIteratorWith(reader io.Reader, continuationPattern *regexp.Regexp, opts multilineOptions) iter.Seq2[string, time.Time] {
+	split := opts.split
+	if split == nil {
+		split = bufio.ScanLines
+	}
 
 	isLogEntryStart := func(line string) bool {
-		// Empty lines are not log starts
-		if len(line) == 0 {
+		// Empty and whitespace-only lines are not log starts
+		if strings.TrimSpace(line) == "" {
 			return false
 		}
 
@@ -415,12 +1443,15 @@ func multilineLogIterator(reader io.Reader, continuationPattern *regexp.Regexp)
 		return true
 	}
 
-	return func(yield func(string) bool) {
+	return func(yield func(string, time.Time) bool) {
 		scanner := bufio.NewScanner(reader)
+		scanner.Split(split)
+		scannerBuffer(scanner, opts.bufferSize)
 		var currentEntry strings.Builder
+		var readAt time.Time
 
 		for scanner.Scan() {
-			line := scanner.Text()
+			line := cleanLine(scanner.Text())
 
 			// Skip completely empty lines
 			if len(line) == 0 {
@@ -431,13 +1462,14 @@ func multilineLogIterator(reader io.Reader, continuationPattern *regexp.Regexp)
 			if isLogEntryStart(line) {
 				// If we have a current entry, yield it first
 				if currentEntry.Len() > 0 {
-					if !yield(currentEntry.String()) {
+					if !yield(currentEntry.String(), readAt) {
 						return
 					}
 					currentEntry.Reset()
 				}
 				// Start new entry
 				currentEntry.WriteString(line)
+				readAt = time.Now()
 			} else if currentEntry.Len() > 0 {
 				// This is a continuation line and we have an active entry, append to it
 				currentEntry.WriteString("\n")
@@ -447,43 +1479,121 @@ func multilineLogIterator(reader io.Reader, continuationPattern *regexp.Regexp)
 			// we ignore it as it's likely orphaned continuation
 		}
 
+		if opts.err != nil {
+			*opts.err = scanner.Err()
+		}
+
 		// Yield the final entry if we have one
 		if currentEntry.Len() > 0 {
-			yield(currentEntry.String())
+			yield(currentEntry.String(), readAt)
 		}
 	}
 }
 
 func processLogs(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
-	continuationPattern, err := regexp.Compile(config.ContinuationPattern)
+	recorder, err := startSessionRecording(config.RecordSession, sessionModeStdin, config)
+	if err != nil {
+		return err
+	}
+	defer recorder.Close()
+
+	if err := enterSandbox(config); err != nil {
+		return err
+	}
+	var input io.Reader = os.Stdin
+	if config.Decompress {
+		if input, err = autoDecompress(os.Stdin); err != nil {
+			return err
+		}
+	}
+	return processReader(ctx, config, extractor, processor, recorder.Reader("stdin", input))
+}
+
+// processReader parses logs from input, which stands in for stdin
+func processReader(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor, input io.Reader) error {
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
 	if err != nil {
 		return fmt.Errorf("failed to compile continuation pattern: %w", err)
 	}
 
-	for logEntry := range multilineLogIterator(os.Stdin, continuationPattern) {
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateBinaryOutput(config.BinaryOutput); err != nil {
+		return err
+	}
+
+	guard := newBinaryGuard(input, nil)
+	guard.expected = binaryFraming(config.Framing)
+	var readErr error
+	for logEntry, readAt := range multilineLogIteratorWith(guard, continuationPattern, multilineOptions{split: split, bufferSize: readBuffer, err: &readErr}) {
 		entry, err := extractor.ParseLogEntry(logEntry)
 		if err != nil {
 			logError("Error parsing log entry: %v\n", err)
 			continue
 		}
+		entry.ReadAt = readAt
 
 		processor.ProcessLogEntry(ctx, entry)
 	}
 
+	if entry := guard.Entry("", config.BinaryOutput); entry != nil {
+		processor.ProcessLogEntry(ctx, entry)
+	}
+
+	if readErr != nil {
+		processor.ProcessLogEntry(ctx, streamReadErrorEntry("", readErr))
+		return fmt.Errorf("failed to read stdin: %w", readErr)
+	}
+
 	return nil
 }
 
+// streamOptions controls how processStream reads and re-emits a stream
+type streamOptions struct {
+	passthrough         bool
+	output              io.Writer
+	grep                *regexp.Regexp // when set, only matching entries are passed through
+	links               *traceLinker   // when set, trace IDs are passed through as hyperlinks
+	continuationPattern *regexp.Regexp
+	split               bufio.SplitFunc
+	readBuffer          int // --read-buffer-size, 0 for the default
+	binaryPolicy        string
+	binaryFraming       bool            // records are binary, so the binary guard lets them through
+	exited              <-chan struct{} // closed when the command exits
+	paused              func() bool     // reports reading stopped for --reexec rather than at EOF
+}
+
 // processStream processes logs from a single stream (stdout or stderr)
-func processStream(ctx context.Context, reader io.Reader, stream string, extractor *JSONExtractor, processor *LogProcessor, wg *sync.WaitGroup, passthrough bool, output io.Writer, continuationPattern *regexp.Regexp) {
+func processStream(ctx context.Context, reader io.Reader, stream string, extractor *JSONExtractor, processor *LogProcessor, wg *sync.WaitGroup, opts streamOptions) {
 	defer wg.Done()
+	defer reportPanic(ctx, processor, stream+" stream")
+
+	// Binary data goes to passthrough only when asked to and entries are re-emitted;
+	// raw passthrough has already copied it
+	var divert io.Writer
+	if opts.binaryPolicy == binaryOutputPassthrough && opts.passthrough {
+		divert = opts.output
+	}
+	guard := newBinaryGuard(reader, divert)
+	guard.expected = opts.binaryFraming
+
+	var readErr error
+	for logEntry, readAt := range multilineLogIteratorWith(guard, opts.continuationPattern, multilineOptions{split: opts.split, bufferSize: opts.readBuffer, err: &readErr}) {
+		entry, err := extractor.ParseLogEntry(logEntry)
 
-	for logEntry := range multilineLogIterator(reader, continuationPattern) {
 		// If passthrough is enabled, write to output
-		if passthrough && output != nil {
-			fmt.Fprintln(output, logEntry)
+		if opts.passthrough && opts.output != nil && (opts.grep == nil || opts.grep.MatchString(logEntry)) {
+			fmt.Fprintln(opts.output, opts.links.render(logEntry, entry))
 		}
 
-		entry, err := extractor.ParseLogEntry(logEntry)
 		if err != nil {
 			logError("Error parsing log entry from %s: %v\n", stream, err)
 			continue
@@ -491,9 +1601,28 @@ func processStream(ctx context.Context, reader io.Reader, stream string, extract
 
 		// Tag with stream information
 		entry.Stream = stream
+		entry.ReadAt = readAt
 
 		processor.ProcessLogEntry(ctx, entry)
 	}
+
+	if entry := guard.Entry(stream, opts.binaryPolicy); entry != nil {
+		processor.ProcessLogEntry(ctx, entry)
+	}
+
+	if readErr != nil {
+		processor.ProcessLogEntry(ctx, streamReadErrorEntry(stream, readErr))
+		// Keep draining so the command does not block on a full pipe
+		io.Copy(io.Discard, reader)
+		return
+	}
+
+	if opts.paused != nil && opts.paused() {
+		return
+	}
+	if opts.exited != nil && !closedWithin(opts.exited, streamCloseGrace) {
+		processor.ProcessLogEntry(ctx, streamClosedEarlyEntry(stream))
+	}
 }
 
 // executeCommand executes the given command and processes its output
@@ -502,11 +1631,74 @@ func executeCommand(ctx context.Context, config *Config, extractor *JSONExtracto
 		return fmt.Errorf("no command specified")
 	}
 
-	continuationPattern, err := regexp.Compile(config.ContinuationPattern)
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
 	if err != nil {
 		return fmt.Errorf("failed to compile continuation pattern: %w", err)
 	}
 
+	if err := validatePassthroughClosed(config.PassthroughClosed); err != nil {
+		return err
+	}
+
+	grep, err := compilePassthroughGrep(config)
+	if err != nil {
+		return err
+	}
+
+	links, err := compileTraceLinks(config)
+	if err != nil {
+		return err
+	}
+
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateBinaryOutput(config.BinaryOutput); err != nil {
+		return err
+	}
+
+	scrubber, err := childEnvScrubber(config)
+	if err != nil {
+		return err
+	}
+
+	streamOpts := streamOptions{
+		continuationPattern: continuationPattern,
+		split:               split,
+		readBuffer:          readBuffer,
+		binaryPolicy:        config.BinaryOutput,
+		binaryFraming:       binaryFraming(config.Framing),
+		grep:                grep,
+		links:               links,
+	}
+
+	// A closed passthrough reader must surface as EPIPE rather than killing us
+	signal.Ignore(syscall.SIGPIPE)
+
+	// Carry on with the command a previous otel-logger handed over
+	if config.Reexec {
+		state, err := takeReexecState()
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			child, err := adoptCommand(state)
+			if err != nil {
+				return err
+			}
+			defer child.stdout.Close()
+			defer child.stderr.Close()
+			logInfo(config.Verbose, "Re-executed, continuing with command (pid %d)\n", state.PID)
+			return superviseCommand(ctx, config, child, nil, extractor, processor, streamOpts)
+		}
+	}
+
 	// Create command
 	var cmd *exec.Cmd
 	if len(config.Command) == 1 {
@@ -514,140 +1706,561 @@ func executeCommand(ctx context.Context, config *Config, extractor *JSONExtracto
 	} else {
 		cmd = exec.CommandContext(ctx, config.Command[0], config.Command[1:]...)
 	}
+	if scrubber != nil {
+		cmd.Env = scrubber.environ(os.Environ())
+	}
 
-	// Create pipes for stdout and stderr
-	stdoutPipe, err := cmd.StdoutPipe()
+	// Create pipes for stdout and stderr. We own the read ends, so cmd.Wait
+	// does not close them while output is still being read.
+	stdoutPipe, stdoutWriter, err := os.Pipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
+	defer stdoutPipe.Close()
 
-	stderrPipe, err := cmd.StderrPipe()
+	stderrPipe, stderrWriter, err := os.Pipe()
 	if err != nil {
+		stdoutWriter.Close()
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
+	defer stderrPipe.Close()
+	if err := resizePipes(config, stdoutPipe, stderrPipe); err != nil {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		return err
+	}
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	recorder, err := startSessionRecording(config.RecordSession, sessionModeCommand, config)
+	if err != nil {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		return err
+	}
+	defer recorder.Close()
 
 	cmd.Stdin = os.Stdin
 
 	// Start the command
 	logInfo(config.Verbose, "Starting command: %s\n", strings.Join(config.Command, " "))
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	// The child has its own copies of the write ends now
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
 		return fmt.Errorf("failed to start command: %w", err)
 	}
+	if err := enterSandbox(config); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	child := &commandChild{process: cmd.Process, wait: cmd.Wait, stdout: stdoutPipe, stderr: stderrPipe}
+	return superviseCommand(ctx, config, child, recorder, extractor, processor, streamOpts)
+}
+
+// commandChild is the running wrapped command: started by this process, or
+// by a previous otel-logger that re-executed into this one
+type commandChild struct {
+	process        *os.Process
+	wait           func() error
+	stdout, stderr *os.File
+	pending        map[string][]byte // partial lines the previous process read
+}
+
+// superviseCommand processes the output of the running command, forwards
+// signals to it and waits for it to exit
+func superviseCommand(ctx context.Context, config *Config, child *commandChild, recorder *sessionRecorder, extractor *JSONExtractor, processor *LogProcessor, streamOpts streamOptions) error {
+	onPassthroughClosed := func() {}
+	if config.PassthroughClosed == passthroughClosedTerminate {
+		onPassthroughClosed = func() {
+			logInfo(config.Verbose, "Passthrough closed, terminating command\n")
+			child.process.Signal(syscall.SIGTERM)
+		}
+	}
+	stdoutSink := newPassthroughSink("stdout", os.Stdout, onPassthroughClosed)
+	stderrSink := newPassthroughSink("stderr", os.Stderr, onPassthroughClosed)
+
+	// With --reexec the pipes are read a line at a time, so reading can stop
+	// between lines when handing over
+	var stdoutSource, stderrSource io.Reader = child.stdout, child.stderr
+	var reexecReaders map[string]*reexecReader
+	if config.Reexec {
+		reexecReaders = map[string]*reexecReader{
+			"stdout": newReexecReader(child.stdout, child.pending["stdout"]),
+			"stderr": newReexecReader(child.stderr, child.pending["stderr"]),
+		}
+		stdoutSource, stderrSource = reexecReaders["stdout"], reexecReaders["stderr"]
+	}
 
 	// Process streams concurrently
 	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go processStream(ctx, stdoutPipe, "stdout", extractor, processor, &wg, config.PassthroughStdout, os.Stdout, continuationPattern)
-	go processStream(ctx, stderrPipe, "stderr", extractor, processor, &wg, config.PassthroughStderr, os.Stderr, continuationPattern)
+	exited := make(chan struct{})
+
+	stdoutReader, stdoutPassthrough := teePassthrough(recorder.Reader("stdout", stdoutSource), config.PassthroughStdout, config.PassthroughRaw, stdoutSink)
+	stderrReader, stderrPassthrough := teePassthrough(recorder.Reader("stderr", stderrSource), config.PassthroughStderr, config.PassthroughRaw, stderrSink)
+
+	streamOpts.exited = exited
+	stdoutOpts, stderrOpts := streamOpts, streamOpts
+	stdoutOpts.passthrough, stdoutOpts.output = stdoutPassthrough, stdoutSink
+	stderrOpts.passthrough, stderrOpts.output = stderrPassthrough, stderrSink
+	stdoutOpts.links, stderrOpts.links = streamOpts.links.forTerminal(os.Stdout), streamOpts.links.forTerminal(os.Stderr)
+	stdoutOpts.paused, stderrOpts.paused = reexecReaders["stdout"].stopped, reexecReaders["stderr"].stopped
+
+	startStreams := func() {
+		wg.Add(2)
+		go processStream(ctx, stdoutReader, "stdout", extractor, processor, &wg, stdoutOpts)
+		go processStream(ctx, stderrReader, "stderr", extractor, processor, &wg, stderrOpts)
+	}
+	startStreams()
 
 	// Set up signal forwarding
+	signals := []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	if config.Reexec {
+		signals = append(signals, reexecSignal)
+	}
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, signals...)
 
 	// Wait for command completion or signal
 	done := make(chan error, 1)
 	go func() {
-		done <- cmd.Wait()
+		err := child.wait()
+		recorder.Exit(exitCodeOf(err), err != nil)
+		close(exited)
+		done <- err
 	}()
 
 	var cmdErr error
-	select {
-	case sig := <-sigChan:
-		logInfo(config.Verbose, "Received signal %v, forwarding to process...\n", sig)
-		if cmd.Process != nil {
-			cmd.Process.Signal(sig)
+wait:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == reexecSignal {
+				// Only returns if the handover failed; the streams are read again
+				logError("Re-exec failed, continuing: %v\n", handOverCommand(ctx, child, reexecReaders, &wg, exited, processor, startStreams))
+				continue
+			}
+			logInfo(config.Verbose, "Received signal %v, forwarding to process...\n", sig)
+			child.process.Signal(sig)
+			cmdErr = <-done
+			break wait
+		case cmdErr = <-done:
+			// Command completed normally
+			break wait
 		}
-		cmdErr = <-done
-	case cmdErr = <-done:
-		// Command completed normally
 	}
 
-	// Wait for stream processing to complete
-	wg.Wait()
+	// Wait for stream processing to complete. A background process that
+	// inherited the pipes can keep them open forever, so stop reading after a
+	// grace period; the stream reports that it was abandoned.
+	if !waitGroupWithin(&wg, streamDrainTimeout) {
+		logInfo(config.Verbose, "Output pipes still open after command exit, closing them\n")
+		child.stdout.Close()
+		child.stderr.Close()
+		wg.Wait()
+	}
 
 	// Log the command exit
-	exitCode := 0
-	if cmdErr != nil {
-		if exitError, ok := cmdErr.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		}
+	exitCode := exitCodeOf(cmdErr)
+	recordCommandExit(ctx, processor, commandExitEntry(config.Command, exitCode, cmdErr != nil))
+
+	logInfo(config.Verbose, "Command completed with exit code: %d\n", exitCode)
+
+	if cmdErr != nil && exitCode != 0 {
+		return fmt.Errorf("command failed with exit code %d", exitCode)
+	}
+
+	return nil
+}
+
+func exitCodeOf(err error) int {
+	if exitError, ok := err.(*exec.ExitError); ok {
+		return exitError.ExitCode()
 	}
+	return 0
+}
 
-	// Create a log entry for the command completion
-	exitEntry := &LogEntry{
+// recordCommandExit emits the exit record once the records of the command's
+// output are exported, so it is the last record of the run in the backend
+// even when an earlier batch is retried or exported concurrently
+func recordCommandExit(ctx context.Context, processor *LogProcessor, entry *LogEntry) {
+	if err := processor.Barrier(ctx); err != nil {
+		logError("Failed to export the command's logs before its exit record: %v\n", err)
+	}
+	processor.ProcessLogEntry(ctx, entry)
+}
+
+// commandExitEntry creates a log entry for the command completion
+func commandExitEntry(command []string, exitCode int, failed bool) *LogEntry {
+	level := "info"
+	if failed {
+		level = "error"
+	}
+	return &LogEntry{
 		Timestamp: time.Now(),
-		Level:     "info",
+		Level:     level,
 		Message:   fmt.Sprintf("Command completed with exit code %d", exitCode),
 		Fields: map[string]any{
-			"command":     strings.Join(config.Command, " "),
+			"command":     strings.Join(command, " "),
 			"exit_code":   exitCode,
-			"exit_status": cmdErr != nil,
+			"exit_status": failed,
 		},
-		Raw:    fmt.Sprintf("Command exit: %d", exitCode),
-		Stream: "system",
+		Raw:      fmt.Sprintf("Command exit: %d", exitCode),
+		Stream:   "system",
+		Internal: true,
 	}
+}
 
-	processor.ProcessLogEntry(ctx, exitEntry)
+// configureLogProcessor creates the processor for the provider with the
+// emission options selected on the command line
+func configureLogProcessor(ctx context.Context, provider *sdklog.LoggerProvider, config *Config) (*LogProcessor, error) {
+	var processor *LogProcessor
+	if config.ScopePerStream {
+		processor = NewStreamScopedLogProcessor(provider, "otel-logger")
+	} else {
+		processor = NewLogProcessor(provider.Logger("otel-logger"))
+	}
+	processor.SetFlusher(provider.ForceFlush)
 
-	logInfo(config.Verbose, "Command completed with exit code: %d\n", exitCode)
+	if config.MinLevel != "" {
+		threshold, err := parseMinLevel(config.MinLevel)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetMinSeverity(threshold)
+	}
 
-	if cmdErr != nil && exitCode != 0 {
-		return fmt.Errorf("command failed with exit code %d", exitCode)
+	sampler, err := newSampler(config.SampleRatios, config.SampleRates)
+	if err != nil {
+		return nil, err
 	}
+	processor.SetSampler(sampler)
 
-	return nil
-}
+	if config.FlushOn != "" {
+		threshold, err := parseSeverityLevel(config.FlushOn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --flush-on: %w", err)
+		}
+		processor.SetFlushOn(threshold)
+	}
 
-func runCommand(config *Config) error {
-	ctx := context.Background()
+	normalize, err := severityTextNormalizer(config.NormalizeSeverityText)
+	if err != nil {
+		return nil, err
+	}
+	processor.SetSeverityTextNormalizer(normalize)
 
-	// Create logger provider using OTEL SDK
-	provider, err := createLoggerProvider(ctx, config)
+	if err := validateLoggerNameTarget(config.LoggerNameTarget); err != nil {
+		return nil, err
+	}
+	if len(config.LoggerNameFields) > 0 && config.LoggerNameTarget != loggerNameAttribute {
+		processor.SetLoggerNameScopes(provider)
+	}
+
+	if len(config.Redact) > 0 || len(config.RedactPatterns) > 0 {
+		redactor, err := newRedactor(config.Redact, config.RedactPatterns)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetRedactor(redactor)
+	}
+
+	if len(config.RenameFields) > 0 {
+		renames, err := parseFieldRenames(config.RenameFields)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetFieldRenames(renames)
+	}
+
+	if len(config.Lookups) > 0 {
+		lookups, err := loadLookups(config.Lookups)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetLookups(lookups)
+	}
+
+	if len(config.HashFields) > 0 {
+		hasher, err := newFieldHasher(config.HashFields, config.HashSaltEnv)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetFieldHasher(hasher)
+	}
+
+	if err := validBodyMode(config); err != nil {
+		return nil, err
+	}
+	processor.SetBodyMode(config.BodyMode)
+
+	if len(config.BodyFields) > 0 || len(config.DropFields) > 0 {
+		processor.SetFieldRouting(newFieldRouting(config.BodyFields, config.DropFields))
+	}
+
+	if err := validObjectArraysPolicy(config.ObjectArrays); err != nil {
+		return nil, err
+	}
+	processor.SetObjectArrays(config.ObjectArrays)
+
+	if err := validDuplicateKeysPolicy(config.DuplicateKeys); err != nil {
+		return nil, err
+	}
+	processor.SetDuplicateKeys(config.DuplicateKeys)
+
+	if err := validAttrOrder(config.AttrOrder); err != nil {
+		return nil, err
+	}
+	processor.SetAttributeOrder(config.AttrOrder)
+
+	if err := validNestedPolicy(config.Nested); err != nil {
+		return nil, err
+	}
+	if config.Nested == nestedFlatten {
+		flattener, err := newFlattener(config.FlattenDelimiter, config.FlattenDepth)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetFlattener(flattener)
+	}
+
+	if len(config.AttrAllowlist) > 0 {
+		processor.SetAttributeAllowlist(newAttributeAllowlist(config.AttrAllowlist, config.AttrAllowlistCount))
+	}
+
+	if config.RuleAudit != "" {
+		if config.RuleAuditInterval <= 0 {
+			return nil, fmt.Errorf("invalid --rule-audit-interval %v", config.RuleAuditInterval)
+		}
+		audit, err := openRuleAudit(config.RuleAudit)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetRuleAudit(audit, config.RuleAuditInterval)
+	}
+
+	if config.Latency {
+		if config.LatencyInterval <= 0 {
+			return nil, fmt.Errorf("invalid --latency-interval %v", config.LatencyInterval)
+		}
+		processor.SetLatency(newLatencyTracker(provider.Logger(internalErrorScope)), config.LatencyInterval)
+	}
+
+	if config.AttrStats != "" {
+		stats, err := openAttrStats(config.AttrStats, config.AttrStatsTop)
+		if err != nil {
+			return nil, err
+		}
+		processor.SetAttrStats(stats)
+	}
+
+	keep, err := newKeepRules(config.AlwaysKeepLevel, config.AlwaysKeepEvents)
 	if err != nil {
-		return fmt.Errorf("failed to create logger provider: %w", err)
+		return nil, fmt.Errorf("invalid --always-keep-level: %w", err)
 	}
-	defer func() {
-		if err := provider.Shutdown(ctx); err != nil {
-			logError("Error shutting down logger provider: %v\n", err)
+	processor.SetKeepRules(keep)
+
+	if config.ContextBuffer > 0 {
+		below, err := parseSeverityLevel(config.ContextLevel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --context-level: %w", err)
 		}
-	}()
+		processor.SetContextBuffer(config.ContextBuffer, below)
+	}
 
-	// Create logger and processor
-	logger := provider.Logger("otel-logger")
-	processor := NewLogProcessor(logger)
+	if config.SpanEventLevel != "" {
+		threshold, err := parseSeverityLevel(config.SpanEventLevel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --span-event-level: %w", err)
+		}
+		res, err := createResource(config)
+		if err != nil {
+			return nil, err
+		}
+		emitter, err := newSpanEventEmitter(ctx, threshold, res, outboundTLS(config))
+		if err != nil {
+			return nil, err
+		}
+		processor.SetSpanEvents(emitter)
+	}
 
-	// Create field mappings
-	fieldMappings := getDefaultFieldMappings()
-	if len(config.TimestampFields) > 0 {
-		fieldMappings.TimestampFields = config.TimestampFields
+	return processor, nil
+}
+
+// processInput parses logs from the wrapped command or stdin and hands them
+// to the processor, whose filters the config file may change through control
+func processInput(ctx context.Context, config *Config, processor *LogProcessor, control *controlServer) error {
+	// Flags take precedence over the config file, which may change while running
+	settings := mergeFileConfig(&FileConfig{}, config)
+	var configSum string
+	if config.ConfigFile != "" {
+		file, sum, err := loadFileConfig(config.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		settings, configSum = mergeFileConfig(file, config), sum
+	}
+	if _, err := compilePrefix(settings.JSONPrefix); err != nil {
+		return fmt.Errorf("invalid --json-prefix: %w", err)
+	}
+	if err := validFormat(settings.Format); err != nil {
+		return fmt.Errorf("invalid --format: %w", err)
+	}
+	if err := validLevelScale(settings.LevelScale); err != nil {
+		return fmt.Errorf("invalid --level-scale: %w", err)
+	}
+	if _, err := parseSeverityMap(config.SeverityMap); err != nil {
+		return fmt.Errorf("invalid --severity-map: %w", err)
 	}
-	if len(config.MessageFields) > 0 {
-		fieldMappings.MessageFields = config.MessageFields
+	filters, err := settings.filters(config)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	if err := filters.apply(processor, control); err != nil {
+		return err
 	}
-	if len(config.LevelFields) > 0 {
-		fieldMappings.LevelFields = config.LevelFields
+
+	if config.AnnotatePipeline {
+		processor.SetPipelineAnnotation(pipelineAttributes(settings))
 	}
 
 	// Create JSON extractor
-	extractor := NewJSONExtractor(config.JSONPrefix, fieldMappings)
+	fieldMappings := settings.fieldMappings()
+	extractor := NewJSONExtractor(settings.JSONPrefix, fieldMappings)
+	extractor.SetSourceOrder(config.AttrOrder == attrOrderSource)
+	extractor.SetKeepObject(config.BodyMode == bodyModeObject)
+	extractor.SetCRI(config.Framing == framingCRI || config.Framing == framingDockerJSON)
+
+	if config.ConfigFile != "" {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go newConfigWatcher(config, extractor, processor, control, settings, configSum).run(watchCtx)
+	}
 
 	logInfo(config.Verbose, "Field mappings - Timestamp: %v, Level: %v, Message: %v\n",
 		fieldMappings.TimestampFields, fieldMappings.LevelFields, fieldMappings.MessageFields)
 
-	var processingErr error
+	if config.ReplaySession != "" {
+		logInfo(config.Verbose, "Replaying session from %s\n", config.ReplaySession)
+		return replaySession(ctx, config, extractor, processor)
+	}
+
+	if len(config.DockerContainers) > 0 || config.DockerAll {
+		if len(config.Command) > 0 || len(config.Files) > 0 || len(config.ListenSyslog) > 0 || len(config.ListenForward) > 0 || config.Journald || len(config.K8sPods) > 0 || config.K8sSelector != "" {
+			return fmt.Errorf("--docker-container and --docker-all cannot be combined with --k8s-pod, --k8s-selector, --file, --listen-syslog, --listen-forward, --journald or a wrapped command")
+		}
+		logInfo(config.Verbose, "Following docker containers and sending logs (batch_size=%d)\n", config.BatchSize)
+		return processDocker(ctx, config, extractor, processor)
+	}
+
+	if len(config.K8sPods) > 0 || config.K8sSelector != "" {
+		if len(config.Command) > 0 || len(config.Files) > 0 || len(config.ListenSyslog) > 0 || len(config.ListenForward) > 0 || config.Journald {
+			return fmt.Errorf("--k8s-pod and --k8s-selector cannot be combined with --file, --listen-syslog, --listen-forward, --journald or a wrapped command")
+		}
+		logInfo(config.Verbose, "Following pod logs and sending logs (batch_size=%d)\n", config.BatchSize)
+		return processK8s(ctx, config, extractor, processor)
+	}
+
+	if config.Journald {
+		if len(config.Command) > 0 || len(config.Files) > 0 || len(config.ListenSyslog) > 0 || len(config.ListenForward) > 0 {
+			return fmt.Errorf("--journald cannot be combined with --file, --listen-syslog, --listen-forward or a wrapped command")
+		}
+		logInfo(config.Verbose, "Following the systemd journal and sending logs (batch_size=%d)\n", config.BatchSize)
+		return processJournal(ctx, config, extractor, processor)
+	}
+
+	if len(config.ListenForward) > 0 {
+		if len(config.Command) > 0 || len(config.Files) > 0 || len(config.ListenSyslog) > 0 {
+			return fmt.Errorf("--listen-forward cannot be combined with --listen-syslog, --file or a wrapped command")
+		}
+		logInfo(config.Verbose, "Receiving Forward protocol records on %v and sending logs (batch_size=%d)\n", config.ListenForward, config.BatchSize)
+		return processForward(ctx, config, extractor, processor)
+	}
+
+	if len(config.ListenSyslog) > 0 {
+		if len(config.Command) > 0 || len(config.Files) > 0 {
+			return fmt.Errorf("--listen-syslog cannot be combined with --file or a wrapped command")
+		}
+		logInfo(config.Verbose, "Receiving syslog on %v and sending logs (batch_size=%d)\n", config.ListenSyslog, config.BatchSize)
+		return processSyslog(ctx, config, extractor, processor)
+	}
+
+	if len(config.Files) > 0 {
+		if len(config.Command) > 0 {
+			return fmt.Errorf("--file cannot be combined with a wrapped command")
+		}
+		logInfo(config.Verbose, "Following files %v and sending logs (batch_size=%d)\n", config.Files, config.BatchSize)
+		return processFiles(ctx, config, extractor, processor)
+	}
 
 	// Check if we should execute a command or read from stdin
 	if len(config.Command) > 0 {
 		// Execute command and process its output
 		logInfo(config.Verbose, "Executing command and sending logs (batch_size=%d)\n", config.BatchSize)
-		processingErr = executeCommand(ctx, config, extractor, processor)
-	} else {
-		// Process logs from stdin
-		logInfo(config.Verbose, "Reading logs from stdin and sending (batch_size=%d)\n", config.BatchSize)
-		processingErr = processLogs(ctx, config, extractor, processor)
+		return executeCommand(ctx, config, extractor, processor)
+	}
+
+	// Process logs from stdin
+	logInfo(config.Verbose, "Reading logs from stdin and sending (batch_size=%d)\n", config.BatchSize)
+	return processLogs(ctx, config, extractor, processor)
+}
+
+func runCommand(config *Config) error {
+	ctx := context.Background()
+
+	if _, err := newTLSPolicy(config.TLSMinVersion, config.TLSCiphers, config.TLSPin); err != nil {
+		return err
+	}
+	if err := validateReexec(config); err != nil {
+		return err
+	}
+	if err := validateCheckpoints(config); err != nil {
+		return err
+	}
+
+	if config.ExportHelper {
+		return runWithExportHelper(ctx, config)
+	}
+
+	// Catch endpoint misconfiguration before any logs are queued
+	runPreflight(ctx, config)
+
+	// Create logger provider using OTEL SDK
+	provider, err := createLoggerProvider(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create logger provider: %w", err)
+	}
+	defer func() {
+		if err := provider.Shutdown(ctx); err != nil {
+			logError("Error shutting down logger provider: %v\n", err)
+		}
+	}()
+
+	if !config.NoInternalErrors {
+		defer exportInternalErrors(provider.Logger(internalErrorScope))()
 	}
 
+	// Create logger and processor
+	processor, err := configureLogProcessor(ctx, provider, config)
+	if err != nil {
+		return err
+	}
+	control, err := startControlServer(ctx, config, processor)
+	if err != nil {
+		return err
+	}
+	defer control.Close()
+
+	processingErr := func() error {
+		defer reportPanic(ctx, processor, "log processing")
+		return processInput(ctx, config, processor, control)
+	}()
+	processor.Finish(ctx)
+
 	// Force flush before exit
 	if err := provider.ForceFlush(ctx); err != nil {
 		return fmt.Errorf("failed to flush logs: %w", err)
@@ -662,11 +2275,44 @@ func runCommand(config *Config) error {
 	return nil
 }
 
+// subcommands run instead of wrapping a command when named as the first
+// argument. A wrapped command with the same name can still be run after --,
+// but their names are picked not to clash with common commands.
+var subcommands = map[string]func(args []string) error{
+	"self-update": runSelfUpdate,
+	"presets":     runPresets,
+	"config-diff": runDiff,
+	"config-test": runTest,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				logError("%s\n", err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var config Config
 	arg.MustParse(&config)
 
-	if err := runCommand(&config); err != nil {
+	if config.CheckUpdate {
+		if err := checkForUpdate(context.Background(), newUpdater(), os.Stdout); err != nil {
+			logError("%s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	run := runCommand
+	if isExportHelper() {
+		run = runExportHelper
+	}
+
+	if err := run(&config); err != nil {
 		logError("%s\n", err.Error())
 		os.Exit(1)
 	}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 )
 
 func TestNewJSONExtractor(t *testing.T) {
@@ -124,6 +129,57 @@ func TestParseTimestamp(t *testing.T) {
 	}
 }
 
+func TestEpochTimestamps(t *testing.T) {
+	tests := []struct {
+		value    any
+		unit     string
+		expected time.Time
+	}{
+		{1705315845.123, "", time.Unix(1705315845, 123000000)},
+		{float64(1705315845123), "", time.UnixMilli(1705315845123)},
+		{float64(1705315845123456), "", time.UnixMicro(1705315845123456)},
+		{float64(1705315845), timestampMilliseconds, time.UnixMilli(1705315845)},
+		{"1705315845", "", time.Unix(1705315845, 0)},
+		{"1705315845.123456789", "", time.Unix(1705315845, 123456789)},
+		{"1705315845.1234567891", "", time.Unix(1705315845, 123456789)},
+		{"1705315845123", "", time.UnixMilli(1705315845123)},
+		{"1705315845123.5", "", time.Unix(1705315845, 123500000)},
+		{"1705315845123456789", "", time.Unix(1705315845, 123456789)},
+		{"1705315845", timestampMilliseconds, time.UnixMilli(1705315845)},
+	}
+	for _, tt := range tests {
+		var got time.Time
+		switch v := tt.value.(type) {
+		case float64:
+			got = epochTime(v, tt.unit)
+		case string:
+			var ok bool
+			if got, ok = epochString(v, tt.unit); !ok {
+				t.Errorf("Expected %q to parse as an epoch", v)
+				continue
+			}
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("%v (unit %q): expected %v, got %v", tt.value, tt.unit, tt.expected.UTC(), got.UTC())
+		}
+	}
+
+	for _, bad := range []string{"20240115", "", "1705315845.12a", "-1705315845", "1.7e9", "99999999999999999999"} {
+		if _, ok := epochString(bad, ""); ok {
+			t.Errorf("Expected %q not to parse as an epoch", bad)
+		}
+	}
+	if _, ok := epochString("99999999999", timestampSeconds); ok {
+		t.Error("Expected seconds beyond what nanoseconds can hold not to parse")
+	}
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, _ := extractor.ParseLogEntry(`{"timestamp":"1705315845123","message":"string epoch"}`)
+	if !entry.Timestamp.Equal(time.UnixMilli(1705315845123)) || entry.TimestampSource != timestampSourceRecord {
+		t.Errorf("Expected the string epoch to set the timestamp, got %v (%s)", entry.Timestamp, entry.TimestampSource)
+	}
+}
+
 func TestJSONExtractor_ParseLogEntry(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -499,6 +555,101 @@ func TestLogEntryStreamField(t *testing.T) {
 	}
 }
 
+// recordAttributes flattens a record's attributes into strings for comparison
+func recordAttributes(record sdklog.Record) map[string]string {
+	attrs := make(map[string]string)
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.String()
+		return true
+	})
+	return attrs
+}
+
+func newRecordingProvider() (*sdklog.LoggerProvider, *recordingExporter) {
+	exporter := &recordingExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	return provider, exporter
+}
+
+func TestStreamScopedLogProcessor(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewStreamScopedLogProcessor(provider, "otel-logger")
+
+	ctx := context.Background()
+	for _, stream := range []string{"stdout", "stderr", "system", ""} {
+		processor.ProcessLogEntry(ctx, &LogEntry{
+			Timestamp: time.Now(),
+			Level:     "info",
+			Message:   "message for " + stream,
+			Fields:    map[string]any{},
+			Stream:    stream,
+		})
+	}
+
+	expected := []string{"otel-logger/stdout", "otel-logger/stderr", "otel-logger/system", "otel-logger"}
+	records := exporter.Records()
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if scope := record.InstrumentationScope().Name; scope != expected[i] {
+			t.Errorf("Record %d: expected scope %s, got %s", i, expected[i], scope)
+		}
+	}
+}
+
+func TestLogProcessorFlushOn(t *testing.T) {
+	provider, _ := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	threshold, err := parseSeverityLevel("error")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	flushes := 0
+	processor.SetFlusher(func(ctx context.Context) error {
+		flushes++
+		return nil
+	})
+	processor.SetFlushOn(threshold)
+
+	ctx := context.Background()
+	for _, level := range []string{"debug", "info", "warn", "error", "fatal", "info"} {
+		processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: level, Message: level, Fields: map[string]any{}})
+	}
+
+	if flushes != 2 {
+		t.Errorf("Expected 2 flushes (error, fatal), got %d", flushes)
+	}
+
+	if _, err := parseSeverityLevel("loud"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+func TestRecordCommandExitFlushesFirst(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	var exportedAtFlush []int
+	processor.SetFlusher(func(ctx context.Context) error {
+		exportedAtFlush = append(exportedAtFlush, len(exporter.Records()))
+		return nil
+	})
+
+	ctx := context.Background()
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: time.Now(), Level: "info", Message: "output", Stream: "stdout"})
+	recordCommandExit(ctx, processor, commandExitEntry([]string{"true"}, 0, false))
+
+	if !reflect.DeepEqual(exportedAtFlush, []int{1}) {
+		t.Errorf("Expected one flush with the output record before the exit record, got %v", exportedAtFlush)
+	}
+	records := exporter.Records()
+	if len(records) != 2 || records[1].Body().AsString() != "Command completed with exit code 0" {
+		t.Errorf("Expected the exit record last, got %v", records)
+	}
+}
+
 // Example test showing realistic usage
 func ExampleJSONExtractor_ParseLogEntry() {
 	fieldMappings := &FieldMappings{
@@ -513,3 +664,41 @@ func ExampleJSONExtractor_ParseLogEntry() {
 	// Output would be used in real application
 	_ = entry.Message // "User logged in"
 }
+
+func TestProcessLogEntryTypedAttributes(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	entry, _ := extractor.ParseLogEntry(`{"msg":"done","status":200,"ratio":0.25,"cached":true,"missing":null,"http":{"method":"GET","retries":[1,2]}}`)
+	processor.ProcessLogEntry(context.Background(), entry)
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	kinds := make(map[string]log.Kind)
+	values := make(map[string]any)
+	records[0].WalkAttributes(func(kv log.KeyValue) bool {
+		kinds[kv.Key] = kv.Value.Kind()
+		values[kv.Key] = plainValue(kv.Value)
+		return true
+	})
+
+	expectedKinds := map[string]log.Kind{
+		"status":  log.KindInt64,
+		"ratio":   log.KindFloat64,
+		"cached":  log.KindBool,
+		"missing": log.KindEmpty,
+		"http":    log.KindMap,
+	}
+	for key, kind := range expectedKinds {
+		if kinds[key] != kind {
+			t.Errorf("Expected %s to be %v, got %v", key, kind, kinds[key])
+		}
+	}
+	expectedHTTP := map[string]any{"method": "GET", "retries": []any{int64(1), int64(2)}}
+	if !reflect.DeepEqual(values["http"], expectedHTTP) {
+		t.Errorf("Expected http %v, got %v", expectedHTTP, values["http"])
+	}
+}
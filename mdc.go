@@ -0,0 +1,64 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Exception semantic convention attributes
+const (
+	exceptionTypeKey       = "exception.type"
+	exceptionMessageKey    = "exception.message"
+	exceptionStacktraceKey = "exception.stacktrace"
+)
+
+// exceptionHeader is the first line of a Java or Kotlin stack trace, a
+// qualified class name and an optional message:
+// java.lang.IllegalStateException: connection closed
+var exceptionHeader = regexp.MustCompile(`^([A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)+)(?::\s*(.*))?$`)
+
+// liftMDC moves the keys of the mdc_fields objects, such as the MDC map
+// logstash-logback-encoder writes, up to top-level fields so each becomes an
+// attribute of its own. Keys the line already has at the top level win.
+func liftMDC(jsonData map[string]any, fields []string) {
+	for _, field := range fields {
+		mdc, ok := jsonData[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		delete(jsonData, field)
+		for key, value := range mdc {
+			setIfAbsent(jsonData, key, value)
+		}
+	}
+}
+
+// extractException moves the first stacktrace_fields value found to
+// exception.stacktrace, and the exception class and message of its first
+// line to exception.type and exception.message
+func extractException(jsonData map[string]any, fields []string) {
+	for _, field := range fields {
+		stacktrace, ok := jsonData[field].(string)
+		if !ok || stacktrace == "" {
+			continue
+		}
+		delete(jsonData, field)
+		jsonData[exceptionStacktraceKey] = stacktrace
+
+		first, _, _ := strings.Cut(stacktrace, "\n")
+		if m := exceptionHeader.FindStringSubmatch(strings.TrimSpace(first)); m != nil {
+			setIfAbsent(jsonData, exceptionTypeKey, m[1])
+			if m[2] != "" {
+				setIfAbsent(jsonData, exceptionMessageKey, m[2])
+			}
+		}
+		return
+	}
+}
+
+// setIfAbsent stores value under key unless the line already has that key
+func setIfAbsent(jsonData map[string]any, key string, value any) {
+	if _, exists := jsonData[key]; !exists {
+		jsonData[key] = value
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpringBootPreset(t *testing.T) {
+	fc, err := parseFileConfig([]byte("format: spring-boot\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	extractor := NewJSONExtractor("", fc.fieldMappings())
+
+	entry, err := extractor.ParseLogEntry(`{"@timestamp":"2024-01-15T10:30:45.123Z","@version":"1","message":"Order failed","logger_name":"com.example.OrderService","thread_name":"http-nio-8080-exec-1","level":"ERROR","level_value":40000,"tenant":"acme",` +
+		`"mdc":{"orderId":"42","traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7","tenant":"shadowed"},` +
+		`"stack_trace":"java.lang.IllegalStateException: connection closed\n\tat com.example.OrderService.place(OrderService.java:42)\n"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Level != "ERROR" || entry.Message != "Order failed" || entry.LoggerName != "com.example.OrderService" {
+		t.Errorf("Expected ERROR/Order failed/com.example.OrderService, got %s/%s/%s", entry.Level, entry.Message, entry.LoggerName)
+	}
+	if entry.Trace == nil || entry.Trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the MDC trace ID to correlate the record, got %+v", entry.Trace)
+	}
+	expected := map[string]any{
+		"@version":             "1",
+		"level_value":          float64(40000),
+		"orderId":              "42",
+		"tenant":               "acme",
+		"exception.type":       "java.lang.IllegalStateException",
+		"exception.message":    "connection closed",
+		"exception.stacktrace": "java.lang.IllegalStateException: connection closed\n\tat com.example.OrderService.place(OrderService.java:42)\n",
+	}
+	if !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, entry.Fields)
+	}
+}
+
+func TestExtractException(t *testing.T) {
+	tests := []struct {
+		stacktrace string
+		expected   map[string]any
+	}{
+		{
+			stacktrace: "com.example.NotFound\n\tat com.example.Repo.find(Repo.java:10)",
+			expected: map[string]any{
+				"exception.type":       "com.example.NotFound",
+				"exception.stacktrace": "com.example.NotFound\n\tat com.example.Repo.find(Repo.java:10)",
+			},
+		},
+		{
+			stacktrace: "something went wrong",
+			expected:   map[string]any{"exception.stacktrace": "something went wrong"},
+		},
+	}
+	for _, tt := range tests {
+		fields := map[string]any{"stack_trace": tt.stacktrace}
+		extractException(fields, []string{"stack_trace"})
+		if !reflect.DeepEqual(fields, tt.expected) {
+			t.Errorf("Expected %v, got %v", tt.expected, fields)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// minisignAlgorithm marks signatures over the message itself, made with
+// minisign -l; the default prehashed ones ("ED") need BLAKE2b
+const minisignAlgorithm = "Ed"
+
+// minisignPublicKey is an Ed25519 public key in minisign's format: the
+// base64 line minisign -G writes after the untrusted comment
+type minisignPublicKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+func parseMinisignPublicKey(encoded string) (*minisignPublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != minisignAlgorithm {
+		return nil, errors.New("invalid minisign public key")
+	}
+	return &minisignPublicKey{id: data[2:10], key: ed25519.PublicKey(data[10:])}, nil
+}
+
+// verify checks a .minisig file for message, made with minisign -l, and
+// returns its trusted comment
+func (k *minisignPublicKey) verify(message, minisig []byte) (string, error) {
+	lines := strings.Split(strings.TrimRight(string(minisig), "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment: ") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return "", errors.New("malformed signature file")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(signature) != 2+8+ed25519.SignatureSize {
+		return "", errors.New("malformed signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return "", errors.New("malformed trusted comment signature")
+	}
+
+	if algorithm := string(signature[:2]); algorithm != minisignAlgorithm {
+		return "", fmt.Errorf("unsupported signature algorithm %q; releases must be signed with minisign -l", algorithm)
+	}
+	if !bytes.Equal(signature[2:10], k.id) {
+		return "", errors.New("signed with another key")
+	}
+	if !ed25519.Verify(k.key, message, signature[10:]) {
+		return "", errors.New("signature does not match")
+	}
+	comment := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ed25519.Verify(k.key, slices.Concat(signature[10:], []byte(comment)), global) {
+		return "", errors.New("trusted comment signature does not match")
+	}
+	return comment, nil
+}
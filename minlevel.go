@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// parseMinLevel reads --min-level: a level name, or an OTel severity number
+// from 1 to 24 for thresholds between the named levels, such as 14 for
+// WARN2
+func parseMinLevel(level string) (log.Severity, error) {
+	if n, err := strconv.Atoi(level); err == nil {
+		if n < int(log.SeverityTrace1) || n > int(log.SeverityFatal4) {
+			return 0, fmt.Errorf("invalid --min-level %d: severity numbers are 1-24", n)
+		}
+		return log.Severity(n), nil
+	}
+	severity, err := parseSeverityLevel(level)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --min-level: %w", err)
+	}
+	return severity, nil
+}
+
+// SetMinSeverity drops records below the threshold, after parsing has set
+// their severity from a level name, a numeric level or --severity-map.
+// otel-logger's own records and events named by --always-keep-event are
+// exported regardless; the level threshold of the keep rules does not
+// override this one.
+func (p *LogProcessor) SetMinSeverity(threshold log.Severity) {
+	p.minSeverity.Store(int64(threshold))
+}
+
+// belowMinSeverity reports whether the entry is dropped by --min-level.
+// Entries the context buffer holds back are kept for it, so an error still
+// comes with the records before it.
+func (p *LogProcessor) belowMinSeverity(entry *LogEntry) bool {
+	threshold := log.Severity(p.minSeverity.Load())
+	if threshold == 0 {
+		return false
+	}
+	severity := entry.severity()
+	return severity < threshold && !entry.Internal && !p.keep.Load().keeps(entry, 0) && !p.holdsForContext(entry, severity)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestParseMinLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected log.Severity
+	}{
+		{"warn", log.SeverityWarn1},
+		{"ERROR", log.SeverityError1},
+		{"14", log.SeverityWarn2},
+	}
+	for _, tt := range tests {
+		if got, err := parseMinLevel(tt.level); err != nil || got != tt.expected {
+			t.Errorf("Expected %s to be %v, got %v (%v)", tt.level, tt.expected, got, err)
+		}
+	}
+	for _, level := range []string{"loud", "0", "25"} {
+		if _, err := parseMinLevel(level); err == nil {
+			t.Errorf("Expected error for %q", level)
+		}
+	}
+}
+
+func TestLogProcessorMinSeverity(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetMinSeverity(log.SeverityWarn1)
+	keep, _ := newKeepRules("error", []string{"deploy"})
+	processor.SetKeepRules(keep)
+
+	mappings := getDefaultFieldMappings()
+	mappings.LevelNumbers = map[string]string{"30": "info", "40": "warn"}
+	mappings.SeverityMap = severityTable(map[string]int{"notice": int(log.SeverityWarn2)})
+	extractor := NewJSONExtractor("", mappings)
+
+	for _, line := range []string{
+		`{"level":"debug","msg":"dropped"}`,
+		`{"level":30,"msg":"dropped"}`,
+		`{"level":40,"msg":"numeric warn"}`,
+		`{"level":"notice","msg":"mapped notice"}`,
+		`{"level":"error","msg":"error"}`,
+		`{"level":"debug","msg":"kept event","event":"deploy"}`,
+		`plain text is info`,
+	} {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	// otel-logger's own records are never dropped
+	processor.ProcessLogEntry(context.Background(), commandExitEntry([]string{"true"}, 0, false))
+	if entry := commandExitEntry([]string{"false"}, 1, true); entry.severity() != log.SeverityError1 {
+		t.Errorf("Expected a failed command's exit at error severity, got %v", entry.severity())
+	}
+
+	var bodies []string
+	for _, record := range exporter.Records() {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	expected := []string{"numeric warn", "mapped notice", "error", "kept event", "Command completed with exit code 0"}
+	if len(bodies) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, bodies)
+	}
+	for i := range expected {
+		if bodies[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, bodies)
+			break
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Extension types decoded as times: MessagePack timestamps, and Fluentd's
+// EventTime of seconds and nanoseconds that Fluent Bit sends by default
+const (
+	msgpackTimestampExt = -1
+	fluentdEventTimeExt = 0
+)
+
+// decodeMsgpack decodes one MessagePack value. Maps keep their key order,
+// binary data is encoded as base64 and timestamps and EventTimes become
+// times; other extension types keep their raw data.
+func decodeMsgpack(r *recordReader) (any, error) {
+	return decodeMsgpackValue(r, 0)
+}
+
+func decodeMsgpackValue(r *recordReader, depth int) (any, error) {
+	if depth > maxRecordDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxRecordDepth)
+	}
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return decodeMsgpackMap(r, uint64(b&0x0f), depth)
+	case b&0xf0 == 0x90:
+		return decodeMsgpackArray(r, uint64(b&0x0f), depth)
+	case b&0xe0 == 0xa0:
+		return msgpackString(r, uint64(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.next(n)
+		return append([]byte(nil), data...), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.uint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackExt(r, n)
+	case 0xca:
+		n, err := r.uint(4)
+		return recordFloat(float64(math.Float32frombits(uint32(n)))), err
+	case 0xcb:
+		n, err := r.uint(8)
+		return recordFloat(math.Float64frombits(n)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := r.uint(1 << (b - 0xcc))
+		if n > math.MaxInt64 {
+			return n, err
+		}
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := r.uint(size)
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeMsgpackExt(r, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, n, depth)
+	default:
+		return nil, fmt.Errorf("unused MessagePack type 0x%02x", b)
+	}
+}
+
+func msgpackString(r *recordReader, n uint64) (any, error) {
+	data, err := r.next(n)
+	return string(data), err
+}
+
+func decodeMsgpackArray(r *recordReader, n uint64, depth int) (any, error) {
+	items := make([]any, 0, r.capacity(n))
+	for range n {
+		item, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeMsgpackMap(r *recordReader, n uint64, depth int) (any, error) {
+	fields := make(orderedMap, 0, r.capacity(n*2)/2)
+	for range n {
+		key, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, recordField{recordKey(key), value})
+	}
+	return fields, nil
+}
+
+// decodeMsgpackExt reads an extension of n data bytes after its type
+func decodeMsgpackExt(r *recordReader, n uint64) (any, error) {
+	kind, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(kind) == fluentdEventTimeExt && len(data) == 8 {
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), int64(binary.BigEndian.Uint32(data[4:]))).UTC(), nil
+	}
+	if int8(kind) != msgpackTimestampExt {
+		return append([]byte(nil), data...), nil
+	}
+
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		n := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data)
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return time.Unix(sec, int64(nsec)).UTC(), nil
+	default:
+		return nil, fmt.Errorf("invalid MessagePack timestamp of %d bytes", len(data))
+	}
+}
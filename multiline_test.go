@@ -206,6 +206,15 @@ func TestMultilineLogIterator(t *testing.T) {
 				"2024-01-15T10:30:01Z INFO Next log entry",
 			},
 		},
+		{
+			name:  "windows line endings and byte order mark",
+			input: "\ufeff{\"msg\": \"first\"}\r\n{\"msg\": \"second\"}\r\r\n  continued\r\n\ufeff{\"msg\": \"next file\"}\r\n",
+			expected: []string{
+				`{"msg": "first"}`,
+				"{\"msg\": \"second\"}\n  continued",
+				`{"msg": "next file"}`,
+			},
+		},
 		{
 			name: "postgres explain analyze format",
 			input: `[
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mysqlSlowExtractor() *JSONExtractor {
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatMySQLSlow
+	return NewJSONExtractor("", mappings)
+}
+
+func TestParseMySQLSlowEntry(t *testing.T) {
+	extractor := mysqlSlowExtractor()
+
+	tests := []struct {
+		name      string
+		entry     string
+		message   string
+		timestamp time.Time
+		fields    map[string]any
+	}{
+		{
+			name: "MySQL 8",
+			entry: strings.Join([]string{
+				"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    42",
+				"# Query_time: 2.000123  Lock_time: 0.000050 Rows_sent: 1  Rows_examined: 100000",
+				"use shop;",
+				"SET timestamp=1705314645;",
+				"SELECT *",
+				"  FROM orders WHERE status = 'open';",
+				"# Time: 2024-01-15T10:30:50.000000Z",
+			}, "\n"),
+			message:   "SELECT *\n  FROM orders WHERE status = 'open';",
+			timestamp: time.Unix(1705314645, 0),
+			fields: map[string]any{
+				"user.name":           "app",
+				"client.address":      "10.0.0.5",
+				"mysql.thread_id":     42,
+				"mysql.query_time":    2.000123,
+				"mysql.lock_time":     0.00005,
+				"mysql.rows_sent":     1,
+				"mysql.rows_examined": 100000,
+				"db.namespace":        "shop",
+			},
+		},
+		{
+			name: "MariaDB",
+			entry: strings.Join([]string{
+				"# Time: 240115 10:30:45",
+				"# User@Host: root[root] @ localhost []",
+				"# Thread_id: 8  Schema: shop  QC_hit: No",
+				"# Query_time: 0.5  Lock_time: 0  Rows_sent: 0  Rows_examined: 12",
+				"SET timestamp=1705314645;",
+				"# administrator command: Quit;",
+			}, "\n"),
+			message:   "# administrator command: Quit;",
+			timestamp: time.Date(2024, 1, 15, 10, 30, 45, 0, time.Local),
+			fields: map[string]any{
+				"user.name":           "root",
+				"client.address":      "localhost",
+				"mysql.thread_id":     8,
+				"db.namespace":        "shop",
+				"mysql.qc_hit":        "No",
+				"mysql.query_time":    0.5,
+				"mysql.lock_time":     0,
+				"mysql.rows_sent":     0,
+				"mysql.rows_examined": 12,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.entry)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, entry.Message)
+			}
+			if !entry.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.timestamp, entry.Timestamp)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.fields) {
+				t.Errorf("Expected fields %v, got %v", tt.fields, entry.Fields)
+			}
+		})
+	}
+
+	entry, _ := extractor.ParseLogEntry("/usr/sbin/mysqld, Version: 8.0.35 (MySQL Community Server - GPL). started with:")
+	if len(entry.Fields) != 0 || !strings.HasPrefix(entry.Message, "/usr/sbin/mysqld") {
+		t.Errorf("Expected the banner to be a plain message, got %+v", entry)
+	}
+}
+
+func TestMySQLSlowContinuation(t *testing.T) {
+	extractor := mysqlSlowExtractor()
+	continuation, err := continuationFor(`^[ \t]`, extractor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := strings.Join([]string{
+		"# Time: 2024-01-15T10:30:45.123456Z",
+		"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    42",
+		"# Query_time: 2.0  Lock_time: 0.0 Rows_sent: 1  Rows_examined: 100000",
+		"SET timestamp=1705314645;",
+		"SELECT 1;",
+		"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    43",
+		"# Query_time: 3.0  Lock_time: 0.0 Rows_sent: 1  Rows_examined: 5",
+		"SET timestamp=1705314645;",
+		"SELECT 2;",
+	}, "\n")
+	entries := slices.Collect(multilineLogIterator(strings.NewReader(input), continuation))
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 grouped entries, got %d: %q", len(entries), entries)
+	}
+	for i, expected := range []string{"SELECT 1;", "SELECT 2;"} {
+		entry, _ := extractor.ParseLogEntry(entries[i])
+		if entry.Message != expected {
+			t.Errorf("Expected entry %d to be %q, got %q", i, expected, entry.Message)
+		}
+	}
+
+	for line, expected := range map[string]bool{"# User@Host: x": false, "# User": true, "#": true, "SELECT 1;": true} {
+		if got := continuation.MatchString(line); got != expected {
+			t.Errorf("Expected %q continuation %v, got %v", line, expected, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// --nested policies for fields holding a JSON object
+const (
+	nestedMap     = "map"     // one attribute holding a map value
+	nestedFlatten = "flatten" // one attribute per leaf, e.g. http.request.method
+)
+
+func validNestedPolicy(policy string) error {
+	switch policy {
+	case "", nestedMap, nestedFlatten:
+		return nil
+	default:
+		return fmt.Errorf("invalid --nested %q (supported: %s, %s)", policy, nestedMap, nestedFlatten)
+	}
+}
+
+// flattener joins the keys of nested objects into attribute keys
+type flattener struct {
+	delimiter string
+	depth     int // levels of nesting joined; 0 joins all of them
+}
+
+func newFlattener(delimiter string, depth int) (*flattener, error) {
+	if delimiter == "" {
+		return nil, fmt.Errorf("--flatten-delimiter must not be empty")
+	}
+	if depth < 0 {
+		return nil, fmt.Errorf("--flatten-depth must not be negative")
+	}
+	return &flattener{delimiter: delimiter, depth: depth}, nil
+}
+
+// flatten returns the fields with nested objects replaced by their leaves,
+// and the keys in order: each object's leaves take its place, sorted by key.
+// Objects deeper than the depth limit stay map values; empty objects stay
+// as they are.
+func (f *flattener) flatten(fields map[string]any, order []string) (map[string]any, []string) {
+	flat := make(map[string]any, len(fields))
+	var keys []string
+	var add func(key string, value any, level int)
+	add = func(key string, value any, level int) {
+		object, ok := value.(map[string]any)
+		if !ok || len(object) == 0 || (f.depth > 0 && level > f.depth) {
+			flat[key] = value
+			keys = append(keys, key)
+			return
+		}
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			add(key+f.delimiter+name, object[name], level+1)
+		}
+	}
+	for _, key := range orderedKeys(fields, order) {
+		add(key, fields[key], 1)
+	}
+	return flat, keys
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const nestedLine = `{"msg":"request","user":"ann","http":{"request":{"method":"GET","headers":{"accept":"*/*"}},"status":200},"empty":{}}`
+
+func TestFlatten(t *testing.T) {
+	entry, _ := NewJSONExtractor("", getDefaultFieldMappings()).ParseLogEntry(nestedLine)
+	fields := entry.Fields
+
+	tests := []struct {
+		name      string
+		delimiter string
+		depth     int
+		expected  map[string]any
+		keys      []string
+	}{
+		{
+			name:      "all levels",
+			delimiter: ".",
+			expected: map[string]any{
+				"user":                        "ann",
+				"http.request.method":         "GET",
+				"http.request.headers.accept": "*/*",
+				"http.status":                 float64(200),
+				"empty":                       map[string]any{},
+			},
+			keys: []string{"empty", "http.request.headers.accept", "http.request.method", "http.status", "user"},
+		},
+		{
+			name:      "depth limit and delimiter",
+			delimiter: "_",
+			depth:     2,
+			expected: map[string]any{
+				"user":                 "ann",
+				"http_request_method":  "GET",
+				"http_request_headers": map[string]any{"accept": "*/*"},
+				"http_status":          float64(200),
+				"empty":                map[string]any{},
+			},
+			keys: []string{"empty", "http_request_headers", "http_request_method", "http_status", "user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFlattener(tt.delimiter, tt.depth)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			flat, keys := f.flatten(fields, nil)
+			if !reflect.DeepEqual(flat, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, flat)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("Expected keys %v, got %v", tt.keys, keys)
+			}
+		})
+	}
+
+	if _, err := newFlattener("", 0); err == nil {
+		t.Error("Expected error for an empty delimiter")
+	}
+}
+
+func TestLogProcessorFlattenSourceOrder(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetAttributeOrder(attrOrderSource)
+	flattener, _ := newFlattener(".", 0)
+	processor.SetFlattener(flattener)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	extractor.SetSourceOrder(true)
+	entry, _ := extractor.ParseLogEntry(`{"msg":"request","user":"ann","http":{"status":200,"method":"GET"},"id":7}`)
+	entry.Raw, entry.TimestampSource = "", ""
+	processor.ProcessLogEntry(context.Background(), entry)
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	expected := []string{"user", "http.method", "http.status", "id"}
+	if got := attributeKeys(records[0]); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := recordAttributes(records[0])["http.status"]; got != "200" {
+		t.Errorf("Expected http.status 200, got %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// normalizedRecord is a record laid out for analytics stores: fixed fields
+// plus resource and record attributes flattened into dotted keys
+// ("resource.service.name", "attributes.http.status") holding bool, int64,
+// float64 or string values.
+type normalizedRecord struct {
+	timestamp    time.Time
+	observed     time.Time
+	severity     log.Severity
+	severityText string
+	body         string
+	eventName    string
+	traceID      string
+	spanID       string
+	scope        string
+	attributes   map[string]any
+}
+
+// normalizeRecord flattens an exported record
+func normalizeRecord(record sdklog.Record) normalizedRecord {
+	row := normalizedRecord{
+		timestamp:    record.Timestamp(),
+		observed:     record.ObservedTimestamp(),
+		severity:     record.Severity(),
+		severityText: record.SeverityText(),
+		eventName:    record.EventName(),
+		scope:        record.InstrumentationScope().Name,
+		attributes:   make(map[string]any),
+	}
+	if body := record.Body(); body.Kind() == log.KindString {
+		row.body = body.AsString()
+	} else if !body.Empty() {
+		data, _ := json.Marshal(plainValue(body))
+		row.body = string(data)
+	}
+	if traceID := record.TraceID(); traceID.IsValid() {
+		row.traceID = hex.EncodeToString(traceID[:])
+	}
+	if spanID := record.SpanID(); spanID.IsValid() {
+		row.spanID = hex.EncodeToString(spanID[:])
+	}
+
+	for iter := record.Resource().Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		row.attributes["resource."+string(kv.Key)] = plainAttribute(kv.Value)
+	}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		flattenValue("attributes."+kv.Key, kv.Value, row.attributes)
+		return true
+	})
+	return row
+}
+
+// estimatedSize approximates the bytes the record takes up once stored
+func (r normalizedRecord) estimatedSize() int64 {
+	size := int64(64 + len(r.severityText) + len(r.body) + len(r.eventName) + len(r.traceID) + len(r.spanID) + len(r.scope))
+	for key, value := range r.attributes {
+		size += int64(len(key)) + 8
+		if s, ok := value.(string); ok {
+			size += int64(len(s))
+		}
+	}
+	return size
+}
+
+// flattenValue stores v under key, descending into maps with dotted keys
+func flattenValue(key string, v log.Value, out map[string]any) {
+	switch v.Kind() {
+	case log.KindMap:
+		for _, kv := range v.AsMap() {
+			flattenValue(key+"."+kv.Key, kv.Value, out)
+		}
+	case log.KindSlice:
+		data, _ := json.Marshal(plainValue(v))
+		out[key] = string(data)
+	case log.KindBytes:
+		out[key] = base64.StdEncoding.EncodeToString(v.AsBytes())
+	case log.KindEmpty:
+	default:
+		out[key] = plainValue(v)
+	}
+}
+
+// plainValue converts v to the Go value encoding/json would produce it from
+func plainValue(v log.Value) any {
+	switch v.Kind() {
+	case log.KindBool:
+		return v.AsBool()
+	case log.KindInt64:
+		return v.AsInt64()
+	case log.KindFloat64:
+		return v.AsFloat64()
+	case log.KindString:
+		return v.AsString()
+	case log.KindBytes:
+		return v.AsBytes()
+	case log.KindSlice:
+		values := make([]any, 0, len(v.AsSlice()))
+		for _, item := range v.AsSlice() {
+			values = append(values, plainValue(item))
+		}
+		return values
+	case log.KindMap:
+		values := make(map[string]any, len(v.AsMap()))
+		for _, kv := range v.AsMap() {
+			values[kv.Key] = plainValue(kv.Value)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func plainAttribute(v attribute.Value) any {
+	switch v.Type() {
+	case attribute.BOOL:
+		return v.AsBool()
+	case attribute.INT64:
+		return v.AsInt64()
+	case attribute.FLOAT64:
+		return v.AsFloat64()
+	default:
+		return v.Emit()
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// --object-arrays policies for fields holding an array of objects
+const (
+	objectArraysJSON    = "json"    // one attribute with the array as JSON text
+	objectArraysSlice   = "slice"   // one attribute holding a slice of maps
+	objectArraysExplode = "explode" // one attribute per element key, e.g. errors.0.code
+	objectArraysRecords = "records" // one child record per element
+)
+
+// Attributes linking child records to the record they were split from
+const (
+	recordIDKey    = "otel_logger.record.id"
+	parentIDKey    = "otel_logger.parent.id"
+	parentFieldKey = "otel_logger.parent.field"
+	parentIndexKey = "otel_logger.parent.index"
+)
+
+func validObjectArraysPolicy(policy string) error {
+	switch policy {
+	case "", objectArraysJSON, objectArraysSlice, objectArraysExplode, objectArraysRecords:
+		return nil
+	default:
+		return fmt.Errorf("invalid --object-arrays %q (supported: %s, %s, %s, %s)", policy,
+			objectArraysJSON, objectArraysSlice, objectArraysExplode, objectArraysRecords)
+	}
+}
+
+// objectArray returns the elements of value if it is a non-empty array whose
+// elements are all objects
+func objectArray(value any) ([]map[string]any, bool) {
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	objects := make([]map[string]any, len(items))
+	for i, item := range items {
+		if objects[i], ok = item.(map[string]any); !ok {
+			return nil, false
+		}
+	}
+	return objects, true
+}
+
+// attributeString is the text form of a field value, as --object-arrays json
+// exports arrays of objects; objects and arrays are JSON-encoded
+func attributeString(value any) string {
+	switch v := value.(type) {
+	case map[string]any, []any:
+		if jsonBytes, err := json.Marshal(v); err == nil {
+			return string(jsonBytes)
+		}
+		return fmt.Sprintf("%v", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// explodeObjects turns each element key into its own attribute named
+// <key>.<index>.<element key>
+func explodeObjects(key string, objects []map[string]any) []log.KeyValue {
+	var attrs []log.KeyValue
+	for i, object := range objects {
+		prefix := key + "." + strconv.Itoa(i) + "."
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			attrs = append(attrs, log.KeyValue{Key: prefix + name, Value: fieldValue(object[name])})
+		}
+	}
+	return attrs
+}
+
+// objectArrayField is an array of objects set aside to become child records
+type objectArrayField struct {
+	key     string
+	objects []map[string]any
+}
+
+// childRecords builds one record per element, sharing the parent's timestamp,
+// severity and body. Each carries the element's keys as attributes and is
+// linked to the parent through otel_logger.parent.*, which the allowlist, if
+// set, leaves alone as it does the parent's otel_logger.record.id.
+func childRecords(parent log.Record, parentID string, fields []objectArrayField, extra []log.KeyValue, allowlist *attributeAllowlist) []log.Record {
+	var children []log.Record
+	for _, field := range fields {
+		for i, object := range field.objects {
+			var child log.Record
+			child.SetTimestamp(parent.Timestamp())
+			child.SetObservedTimestamp(parent.ObservedTimestamp())
+			child.SetSeverity(parent.Severity())
+			child.SetSeverityText(parent.SeverityText())
+			child.SetBody(parent.Body())
+
+			var attrs []log.KeyValue
+			for _, name := range slices.Sorted(maps.Keys(object)) {
+				attrs = append(attrs, log.KeyValue{Key: name, Value: fieldValue(object[name])})
+			}
+			attrs = append(attrs, extra...)
+			if allowlist != nil {
+				attrs = allowlist.filter(attrs)
+			}
+			child.AddAttributes(
+				log.String(parentIDKey, parentID),
+				log.String(parentFieldKey, field.key),
+				log.Int(parentIndexKey, i),
+			)
+			child.AddAttributes(attrs...)
+			children = append(children, child)
+		}
+	}
+	return children
+}
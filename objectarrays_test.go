@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+const objectArrayLine = `{"msg":"validation failed","errors":[{"code":"E1","field":"name"},{"code":"E2","field":"age"}],"tags":["a","b"]}`
+
+func processObjectArrayLine(t *testing.T, policy string) []map[string]string {
+	t.Helper()
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetObjectArrays(policy)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, _ := extractor.ParseLogEntry(objectArrayLine)
+	entry.Raw, entry.TimestampSource = "", ""
+	processor.ProcessLogEntry(context.Background(), entry)
+
+	var out []map[string]string
+	for _, record := range exporter.Records() {
+		out = append(out, recordAttributes(record))
+	}
+	return out
+}
+
+func TestObjectArraysJSON(t *testing.T) {
+	records := processObjectArrayLine(t, objectArraysJSON)
+	expected := []map[string]string{{
+		"errors": `[{"code":"E1","field":"name"},{"code":"E2","field":"age"}]`,
+		"tags":   "[a b]",
+	}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %v, got %v", expected, records)
+	}
+}
+
+func TestObjectArraysExplode(t *testing.T) {
+	records := processObjectArrayLine(t, objectArraysExplode)
+	expected := []map[string]string{{
+		"errors.0.code":  "E1",
+		"errors.0.field": "name",
+		"errors.1.code":  "E2",
+		"errors.1.field": "age",
+		"tags":           "[a b]",
+	}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %v, got %v", expected, records)
+	}
+}
+
+func TestObjectArraysSlice(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetObjectArrays(objectArraysSlice)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, _ := extractor.ParseLogEntry(objectArrayLine)
+	processor.ProcessLogEntry(context.Background(), entry)
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	var errors log.Value
+	records[0].WalkAttributes(func(kv log.KeyValue) bool {
+		if kv.Key == "errors" {
+			errors = kv.Value
+		}
+		return true
+	})
+	if errors.Kind() != log.KindSlice {
+		t.Fatalf("Expected errors as a slice, got %v", errors.Kind())
+	}
+	expected := []any{
+		map[string]any{"code": "E1", "field": "name"},
+		map[string]any{"code": "E2", "field": "age"},
+	}
+	if got := plainValue(errors); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestObjectArraysRecords(t *testing.T) {
+	records := processObjectArrayLine(t, objectArraysRecords)
+	if len(records) != 3 {
+		t.Fatalf("Expected parent and 2 child records, got %d", len(records))
+	}
+
+	parent := records[0]
+	id := parent[recordIDKey]
+	if id == "" {
+		t.Fatalf("Expected parent to carry %s, got %v", recordIDKey, parent)
+	}
+	if _, ok := parent["errors"]; ok {
+		t.Errorf("Expected errors to be moved to child records, got %v", parent)
+	}
+	if parent["tags"] != "[a b]" {
+		t.Errorf("Expected arrays of scalars to stay on the parent, got %v", parent)
+	}
+
+	for i, code := range []string{"E1", "E2"} {
+		child := records[i+1]
+		if child[parentIDKey] != id || child[parentFieldKey] != "errors" || child[parentIndexKey] != []string{"0", "1"}[i] {
+			t.Errorf("Expected child %d to link to parent %s, got %v", i, id, child)
+		}
+		if child["code"] != code {
+			t.Errorf("Expected child %d code %s, got %v", i, code, child)
+		}
+	}
+}
+
+func TestObjectArray(t *testing.T) {
+	tests := []struct {
+		value any
+		ok    bool
+	}{
+		{[]any{map[string]any{"a": 1.0}}, true},
+		{[]any{map[string]any{"a": 1.0}, "b"}, false},
+		{[]any{}, false},
+		{map[string]any{"a": 1.0}, false},
+		{"text", false},
+	}
+	for _, tt := range tests {
+		if _, ok := objectArray(tt.value); ok != tt.ok {
+			t.Errorf("objectArray(%v) = %v, expected %v", tt.value, ok, tt.ok)
+		}
+	}
+}
+
+func TestValidObjectArraysPolicy(t *testing.T) {
+	for _, policy := range []string{"", "json", "slice", "explode", "records"} {
+		if err := validObjectArraysPolicy(policy); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", policy, err)
+		}
+	}
+	if err := validObjectArraysPolicy("flatten"); err == nil {
+		t.Error("Expected unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	collogpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// jsonTransport re-encodes the protobuf export requests produced by the
+// otlploghttp exporter as OTLP/JSON before they are sent
+type jsonTransport struct {
+	base http.RoundTripper
+}
+
+// newJSONHTTPClient builds the HTTP client used for http/json export. Because a
+// custom client bypasses the exporter's own TLS setup, the certificate
+// environment variables and the TLS policy are honored here.
+func newJSONHTTPClient(timeout time.Duration, policy *tlsPolicy) (*http.Client, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	cfg, err := otlpTLSConfig("LOGS")
+	if err != nil {
+		return nil, err
+	}
+	base.TLSClientConfig = policy.apply(cfg)
+
+	return &http.Client{
+		Transport: &jsonTransport{base: base},
+		Timeout:   timeout,
+	}, nil
+}
+
+func (t *jsonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Header.Get("Content-Type") != "application/x-protobuf" {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	gzipped := req.Header.Get("Content-Encoding") == "gzip"
+	if gzipped {
+		if body, err = gunzip(body); err != nil {
+			return nil, fmt.Errorf("failed to decompress export request: %w", err)
+		}
+	}
+
+	jsonBody, err := protobufToOTLPJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if gzipped {
+		if jsonBody, err = gzipBytes(jsonBody); err != nil {
+			return nil, err
+		}
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set("Content-Type", "application/json")
+	out.Body = io.NopCloser(bytes.NewReader(jsonBody))
+	out.ContentLength = int64(len(jsonBody))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(jsonBody)), nil
+	}
+
+	return t.base.RoundTrip(out)
+}
+
+// protobufToOTLPJSON converts a serialized ExportLogsServiceRequest to the
+// OTLP/JSON encoding: enums as integers and trace/span IDs as hex strings
+func protobufToOTLPJSON(body []byte) ([]byte, error) {
+	var request collogpb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("failed to decode export request: %w", err)
+	}
+
+	encoded, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export request as JSON: %w", err)
+	}
+
+	// protojson encodes bytes as base64, but OTLP/JSON requires hex IDs
+	var generic any
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	hexEncodeIDs(generic)
+
+	return json.Marshal(generic)
+}
+
+func hexEncodeIDs(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if key == "traceId" || key == "spanId" {
+				if s, ok := child.(string); ok {
+					if raw, err := base64.StdEncoding.DecodeString(s); err == nil {
+						v[key] = hex.EncodeToString(raw)
+					}
+				}
+				continue
+			}
+			hexEncodeIDs(child)
+		}
+	case []any:
+		for _, child := range v {
+			hexEncodeIDs(child)
+		}
+	}
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestJSONExporterSendsOTLPJSON(t *testing.T) {
+	type captured struct {
+		contentType string
+		body        []byte
+	}
+	requests := make(chan captured, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- captured{contentType: r.Header.Get("Content-Type"), body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := newJSONHTTPClient(5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx := context.Background()
+	exporter, err := otlploghttp.New(ctx,
+		otlploghttp.WithEndpointURL(server.URL+"/v1/logs"),
+		otlploghttp.WithHTTPClient(client),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	defer provider.Shutdown(ctx)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	entry, err := extractor.ParseLogEntry(`{"level":"error","message":"json encoded","user":"alice"}`)
+	if err != nil {
+		t.Fatalf("Failed to parse entry: %v", err)
+	}
+	NewLogProcessor(provider.Logger("test")).ProcessLogEntry(ctx, entry)
+
+	select {
+	case req := <-requests:
+		if req.contentType != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %s", req.contentType)
+		}
+
+		var payload struct {
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []struct {
+						SeverityNumber int `json:"severityNumber"`
+						Body           struct {
+							StringValue string `json:"stringValue"`
+						} `json:"body"`
+					} `json:"logRecords"`
+				} `json:"scopeLogs"`
+			} `json:"resourceLogs"`
+		}
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("Body is not JSON: %v\n%s", err, req.body)
+		}
+		if len(payload.ResourceLogs) == 0 || len(payload.ResourceLogs[0].ScopeLogs) == 0 ||
+			len(payload.ResourceLogs[0].ScopeLogs[0].LogRecords) == 0 {
+			t.Fatalf("Expected a log record in %s", req.body)
+		}
+		record := payload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+		if record.Body.StringValue != "json encoded" {
+			t.Errorf("Expected body 'json encoded', got '%s'", record.Body.StringValue)
+		}
+		if record.SeverityNumber != 17 {
+			t.Errorf("Expected numeric severity 17, got %d", record.SeverityNumber)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for export request")
+	}
+}
+
+func TestHexEncodeIDs(t *testing.T) {
+	var payload any
+	if err := json.Unmarshal([]byte(`{"logRecords":[{"traceId":"AAECAwQFBgcICQoLDA0ODw==","spanId":"AAECAwQFBgc=","body":{"stringValue":"traceId"}}]}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	hexEncodeIDs(payload)
+
+	out, _ := json.Marshal(payload)
+	if !strings.Contains(string(out), `"traceId":"000102030405060708090a0b0c0d0e0f"`) {
+		t.Errorf("Expected hex trace ID, got %s", out)
+	}
+	if !strings.Contains(string(out), `"spanId":"0001020304050607"`) {
+		t.Errorf("Expected hex span ID, got %s", out)
+	}
+	if !strings.Contains(string(out), `"stringValue":"traceId"`) {
+		t.Errorf("String values must be left alone, got %s", out)
+	}
+}
@@ -0,0 +1,325 @@
+// Package otlptest provides an in-process OTLP log receiver for end-to-end
+// tests. Point otel-logger (or any OTLP log exporter) at one of its endpoints
+// and assert on the records it receives:
+//
+//	receiver, err := otlptest.NewReceiver()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer receiver.Close()
+//
+//	cmd := exec.Command("otel-logger", "--", "./myapp")
+//	cmd.Env = append(os.Environ(), receiver.Env(otlptest.ProtocolGRPC)...)
+//	...
+//	records, err := receiver.WaitForRecords(ctx, 3)
+package otlptest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLP protocols accepted by the receiver, as used in OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolHTTPJSON     = "http/json"
+)
+
+// Record is a received log record with its values flattened to strings
+type Record struct {
+	Timestamp         time.Time
+	ObservedTimestamp time.Time
+	SeverityNumber    int32
+	SeverityText      string
+	Body              string
+	Attributes        map[string]string
+	Resource          map[string]string
+	Scope             string
+	TraceID           string
+	SpanID            string
+}
+
+// Receiver accepts OTLP logs over gRPC and HTTP (protobuf and JSON) on
+// loopback ports and keeps every record in memory
+type Receiver struct {
+	mu      sync.Mutex
+	records []Record
+	changed chan struct{} // closed and replaced whenever records arrive
+
+	grpcListener net.Listener
+	grpcServer   *grpc.Server
+	httpListener net.Listener
+	httpServer   *http.Server
+}
+
+// NewReceiver starts a receiver listening on 127.0.0.1 with random ports
+func NewReceiver() (*Receiver, error) {
+	r := &Receiver{changed: make(chan struct{})}
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		grpcListener.Close()
+		return nil, fmt.Errorf("failed to listen for HTTP: %w", err)
+	}
+
+	r.grpcListener = grpcListener
+	r.grpcServer = grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(r.grpcServer, &logsService{receiver: r})
+	go r.grpcServer.Serve(grpcListener)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", r.handleHTTP)
+	r.httpListener = httpListener
+	r.httpServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go r.httpServer.Serve(httpListener)
+
+	return r, nil
+}
+
+// GRPCEndpoint returns the endpoint URL for OTLP/gRPC
+func (r *Receiver) GRPCEndpoint() string {
+	return "http://" + r.grpcListener.Addr().String()
+}
+
+// HTTPEndpoint returns the base endpoint URL for OTLP/HTTP; logs are posted
+// to HTTPEndpoint()+"/v1/logs"
+func (r *Receiver) HTTPEndpoint() string {
+	return "http://" + r.httpListener.Addr().String()
+}
+
+// Env returns the OTEL_EXPORTER_OTLP_* variables that point an exporter at
+// the receiver using the given protocol
+func (r *Receiver) Env(protocol string) []string {
+	endpoint := r.HTTPEndpoint()
+	if protocol == ProtocolGRPC {
+		endpoint = r.GRPCEndpoint()
+	}
+	return []string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT=" + endpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL=" + protocol,
+		"OTEL_EXPORTER_OTLP_INSECURE=true",
+	}
+}
+
+// Records returns a copy of everything received so far
+func (r *Receiver) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Reset discards the received records
+func (r *Receiver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+// WaitForRecords blocks until at least n records have been received or ctx
+// is done, returning the records received either way
+func (r *Receiver) WaitForRecords(ctx context.Context, n int) ([]Record, error) {
+	for {
+		r.mu.Lock()
+		records := append([]Record(nil), r.records...)
+		changed := r.changed
+		r.mu.Unlock()
+
+		if len(records) >= n {
+			return records, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return records, fmt.Errorf("received %d of %d records: %w", len(records), n, ctx.Err())
+		}
+	}
+}
+
+// Close stops both servers
+func (r *Receiver) Close() error {
+	r.grpcServer.Stop()
+	return r.httpServer.Close()
+}
+
+func (r *Receiver) add(req *collogspb.ExportLogsServiceRequest) {
+	var received []Record
+	for _, resourceLogs := range req.GetResourceLogs() {
+		resource := flattenAttributes(resourceLogs.GetResource().GetAttributes())
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			scope := scopeLogs.GetScope().GetName()
+			for _, lr := range scopeLogs.GetLogRecords() {
+				received = append(received, Record{
+					Timestamp:         unixNano(lr.GetTimeUnixNano()),
+					ObservedTimestamp: unixNano(lr.GetObservedTimeUnixNano()),
+					SeverityNumber:    int32(lr.GetSeverityNumber()),
+					SeverityText:      lr.GetSeverityText(),
+					Body:              valueString(lr.GetBody()),
+					Attributes:        flattenAttributes(lr.GetAttributes()),
+					Resource:          resource,
+					Scope:             scope,
+					TraceID:           hex.EncodeToString(lr.GetTraceId()),
+					SpanID:            hex.EncodeToString(lr.GetSpanId()),
+				})
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, received...)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+type logsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	receiver *Receiver
+}
+
+func (s *logsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.receiver.add(req)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (r *Receiver) handleHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var exportReq collogspb.ExportLogsServiceRequest
+	isJSON := strings.HasPrefix(req.Header.Get("Content-Type"), "application/json")
+	if isJSON {
+		err = unmarshalJSON(body, &exportReq)
+	} else {
+		err = proto.Unmarshal(body, &exportReq)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.add(&exportReq)
+
+	var resp []byte
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		resp, _ = protojson.Marshal(&collogspb.ExportLogsServiceResponse{})
+	} else {
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		resp, _ = proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	}
+	w.Write(resp)
+}
+
+// unmarshalJSON decodes OTLP/JSON, whose trace and span IDs are hex rather
+// than the base64 protojson expects for bytes fields
+func unmarshalJSON(body []byte, req *collogspb.ExportLogsServiceRequest) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	hexToBase64IDs(doc)
+
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(converted, req)
+}
+
+func hexToBase64IDs(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if s, ok := child.(string); ok && (key == "traceId" || key == "spanId") {
+				if raw, err := hex.DecodeString(s); err == nil {
+					v[key] = base64.StdEncoding.EncodeToString(raw)
+				}
+				continue
+			}
+			hexToBase64IDs(child)
+		}
+	case []any:
+		for _, child := range v {
+			hexToBase64IDs(child)
+		}
+	}
+}
+
+func unixNano(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+func flattenAttributes(attrs []*commonpb.KeyValue) map[string]string {
+	flat := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		flat[kv.GetKey()] = valueString(kv.GetValue())
+	}
+	return flat
+}
+
+// valueString renders an AnyValue the way the OTel Go log API's
+// Value.String does for scalars; composite values are rendered as JSON
+func valueString(v *commonpb.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprintf("%t", value.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprintf("%d", value.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprintf("%g", value.DoubleValue)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	default:
+		data, _ := protojson.Marshal(v)
+		return string(data)
+	}
+}
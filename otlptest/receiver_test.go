@@ -0,0 +1,73 @@
+package otlptest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReceiverHTTPJSON(t *testing.T) {
+	receiver, err := NewReceiver()
+	if err != nil {
+		t.Fatalf("Failed to start receiver: %v", err)
+	}
+	defer receiver.Close()
+
+	body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]},
+		"scopeLogs":[{"scope":{"name":"otel-logger"},"logRecords":[{
+			"timeUnixNano":"1705315845000000000","severityNumber":17,"severityText":"error",
+			"body":{"stringValue":"boom"},"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0102030405060708",
+			"attributes":[{"key":"retries","value":{"intValue":"3"}}]}]}]}]}`
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(body))
+	zw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, receiver.HTTPEndpoint()+"/v1/logs", &compressed)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to post logs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %s", resp.Status)
+	}
+
+	records := receiver.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r.Body != "boom" || r.SeverityNumber != 17 || r.Scope != "otel-logger" {
+		t.Errorf("Unexpected record %+v", r)
+	}
+	if r.TraceID != "0102030405060708090a0b0c0d0e0f10" || r.SpanID != "0102030405060708" {
+		t.Errorf("Expected hex IDs to round-trip, got %s/%s", r.TraceID, r.SpanID)
+	}
+	if r.Attributes["retries"] != "3" || r.Resource["service.name"] != "svc" {
+		t.Errorf("Unexpected attributes %v / resource %v", r.Attributes, r.Resource)
+	}
+	if !r.Timestamp.Equal(time.Unix(1705315845, 0)) {
+		t.Errorf("Unexpected timestamp %v", r.Timestamp)
+	}
+}
+
+func TestReceiverWaitForRecordsTimeout(t *testing.T) {
+	receiver, err := NewReceiver()
+	if err != nil {
+		t.Fatalf("Failed to start receiver: %v", err)
+	}
+	defer receiver.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := receiver.WaitForRecords(ctx, 1); err == nil {
+		t.Error("Expected timeout error with no records")
+	}
+}
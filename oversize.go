@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Attributes linking the parts of a record that was too large to export
+const (
+	chunkIDKey    = "otel_logger.chunk.id"
+	chunkIndexKey = "otel_logger.chunk.index"
+	chunkCountKey = "otel_logger.chunk.count"
+	truncatedKey  = "otel_logger.truncated"
+	fullCopyKey   = "otel_logger.full_copy"
+)
+
+// --oversize modes
+const (
+	oversizeSplit    = "split"
+	oversizeTruncate = "truncate"
+)
+
+// recordOverhead covers the fields of an encoded record besides its body and
+// attributes: timestamps, severity, trace context and request framing
+const recordOverhead = 128
+
+// markOverhead is reserved in each part for the chunk or truncation attributes
+const markOverhead = 192
+
+// kvOverhead is the encoding cost of a key-value pair besides its key and value
+const kvOverhead = 16
+
+// oversizeExporter keeps single records under the collector's maximum message
+// size. Larger records are either split into linked parts, each carrying
+// otel_logger.chunk.* attributes, or cut down to the first part with an
+// optional full copy on local disk. Without this one oversized record fails
+// the whole batch with ResourceExhausted.
+type oversizeExporter struct {
+	next    sdklog.Exporter
+	limit   int
+	mode    string
+	dir     string // full copies of truncated records, if set
+	verbose bool
+}
+
+func (e *oversizeExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	out := make([]sdklog.Record, 0, len(records))
+	for _, record := range records {
+		if recordSize(record) <= e.limit {
+			out = append(out, record)
+			continue
+		}
+
+		id := uuid.NewString()
+		parts := splitRecord(record, e.limit-recordOverhead-markOverhead)
+		if e.mode == oversizeSplit {
+			logDebug(e.verbose, "Splitting oversized record into %d parts (%s)\n", len(parts), id)
+			for i := range parts {
+				prependAttributes(&parts[i],
+					log.String(chunkIDKey, id),
+					log.Int(chunkIndexKey, i),
+					log.Int(chunkCountKey, len(parts)),
+				)
+			}
+			out = append(out, parts...)
+			continue
+		}
+
+		truncated := parts[0]
+		marks := []log.KeyValue{log.Bool(truncatedKey, true)}
+		if e.dir != "" {
+			path, err := e.saveFullCopy(id, record)
+			if err != nil {
+				logError("Failed to save full copy of truncated record: %v\n", err)
+			} else {
+				marks = append(marks, log.String(fullCopyKey, path))
+			}
+		}
+		prependAttributes(&truncated, marks...)
+		out = append(out, truncated)
+	}
+	return e.next.Export(ctx, out)
+}
+
+// saveFullCopy writes the record as OTLP JSON into the --oversize-dir
+func (e *oversizeExporter) saveFullCopy(id string, record sdklog.Record) (string, error) {
+	data, err := protojson.Marshal(otlpRecord(record))
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(e.dir, id+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (e *oversizeExporter) ForceFlush(ctx context.Context) error {
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *oversizeExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+// prependAttributes puts attrs before the existing attributes so they survive
+// the attribute count limit
+func prependAttributes(record *sdklog.Record, attrs ...log.KeyValue) {
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	record.SetAttributes(attrs...)
+}
+
+// otlpRecord converts the record to its OTLP form
+func otlpRecord(record sdklog.Record) *logspb.LogRecord {
+	out := &logspb.LogRecord{
+		TimeUnixNano:           unixNano(record.Timestamp()),
+		ObservedTimeUnixNano:   unixNano(record.ObservedTimestamp()),
+		SeverityNumber:         logspb.SeverityNumber(record.Severity()),
+		SeverityText:           record.SeverityText(),
+		EventName:              record.EventName(),
+		Body:                   otlpValue(record.Body()),
+		DroppedAttributesCount: uint32(record.DroppedAttributes()),
+		Flags:                  uint32(record.TraceFlags()),
+	}
+	if traceID := record.TraceID(); traceID.IsValid() {
+		out.TraceId = traceID[:]
+	}
+	if spanID := record.SpanID(); spanID.IsValid() {
+		out.SpanId = spanID[:]
+	}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		out.Attributes = append(out.Attributes, &commonpb.KeyValue{Key: kv.Key, Value: otlpValue(kv.Value)})
+		return true
+	})
+	return out
+}
+
+// unixNano encodes t for OTLP, where 0 means unknown
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// recordSize estimates the encoded size of the record in an export request
+func recordSize(record sdklog.Record) int {
+	return proto.Size(otlpRecord(record)) + recordOverhead
+}
+
+// splitRecord spreads the body and attributes of the record across parts of
+// at most budget bytes each. String values too large for one part continue
+// in the next parts under the same key; other values are never split.
+func splitRecord(record sdklog.Record, budget int) []sdklog.Record {
+	empty := record.Clone()
+	empty.SetBody(log.Value{})
+	empty.SetAttributes()
+
+	var parts []sdklog.Record
+	current, used := empty.Clone(), 0
+	flush := func() {
+		parts = append(parts, current)
+		current, used = empty.Clone(), 0
+	}
+
+	add := func(key string, value log.Value, set func(*sdklog.Record, log.Value)) {
+		size := proto.Size(otlpValue(value)) + len(key) + kvOverhead
+		if used+size <= budget {
+			set(&current, value)
+			used += size
+			return
+		}
+		if value.Kind() != log.KindString {
+			if used > 0 {
+				flush()
+			}
+			set(&current, value)
+			used += size
+			return
+		}
+
+		rest := value.AsString()
+		for rest != "" {
+			room := budget - used - len(key) - kvOverhead
+			if room <= 0 && used > 0 {
+				flush()
+				continue
+			}
+			chunk := cutString(rest, max(room, 0))
+			if chunk == "" && used > 0 {
+				flush()
+				continue
+			}
+			if chunk == "" {
+				// A single rune wider than the budget
+				_, width := utf8.DecodeRuneInString(rest)
+				chunk = rest[:width]
+			}
+			set(&current, log.StringValue(chunk))
+			used += len(chunk) + len(key) + kvOverhead
+			rest = rest[len(chunk):]
+			if rest != "" {
+				flush()
+			}
+		}
+	}
+
+	if body := record.Body(); !body.Empty() {
+		add("", body, func(r *sdklog.Record, v log.Value) { r.SetBody(v) })
+	}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		add(kv.Key, kv.Value, func(r *sdklog.Record, v log.Value) {
+			r.AddAttributes(log.KeyValue{Key: kv.Key, Value: v})
+		})
+		return true
+	})
+	if used > 0 || len(parts) == 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// cutString returns the longest prefix of s of at most n bytes that does not
+// split a UTF-8 sequence
+func cutString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// withRecordSizeLimit splits or truncates records larger than
+// --max-record-size before they reach exporter
+func withRecordSizeLimit(config *Config, exporter sdklog.Exporter) (sdklog.Exporter, error) {
+	if config.MaxRecordSize <= 0 {
+		return exporter, nil
+	}
+	if minimum := 2 * (recordOverhead + markOverhead); config.MaxRecordSize < minimum {
+		return nil, fmt.Errorf("--max-record-size must be at least %d bytes", minimum)
+	}
+
+	mode := config.Oversize
+	if mode == "" {
+		mode = oversizeSplit
+	}
+	if mode != oversizeSplit && mode != oversizeTruncate {
+		return nil, fmt.Errorf("invalid --oversize %q (supported: %s, %s)", mode, oversizeSplit, oversizeTruncate)
+	}
+	if config.OversizeDir != "" {
+		if mode != oversizeTruncate {
+			return nil, fmt.Errorf("--oversize-dir requires --oversize %s", oversizeTruncate)
+		}
+		if err := os.MkdirAll(config.OversizeDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create --oversize-dir: %w", err)
+		}
+	}
+
+	return &oversizeExporter{
+		next:    exporter,
+		limit:   config.MaxRecordSize,
+		mode:    mode,
+		dir:     config.OversizeDir,
+		verbose: config.Verbose,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// oversizedRecord emits a record through a provider so it keeps its attributes
+func oversizedRecord(t *testing.T, body string, attrs ...log.KeyValue) sdklog.Record {
+	t.Helper()
+	provider, source := newRecordingProvider()
+	var record log.Record
+	record.SetBody(log.StringValue(body))
+	record.AddAttributes(attrs...)
+	provider.Logger("test").Emit(context.Background(), record)
+	return source.Records()[0]
+}
+
+func TestOversizeExporterSplits(t *testing.T) {
+	body := strings.Repeat("héllo wörld ", 500)
+	large := oversizedRecord(t, body,
+		log.String("service", "api"),
+		log.String("stack", strings.Repeat("at frame\n", 300)),
+	)
+	small := oversizedRecord(t, "fine")
+
+	next := &recordingExporter{}
+	exporter, err := withRecordSizeLimit(&Config{MaxRecordSize: 2048, Oversize: oversizeSplit}, next)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := exporter.Export(context.Background(), []sdklog.Record{small, large}); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+
+	records := next.Records()
+	if len(records) < 3 || records[0].Body().AsString() != "fine" {
+		t.Fatalf("Expected the small record followed by parts, got %d records", len(records))
+	}
+	if _, ok := recordAttributes(records[0])[chunkIDKey]; ok {
+		t.Error("Small record should not be marked as a chunk")
+	}
+
+	parts := records[1:]
+	var gotBody, gotStack strings.Builder
+	id := recordAttributes(parts[0])[chunkIDKey]
+	for i, part := range parts {
+		if size := recordSize(part); size > 2048 {
+			t.Errorf("Part %d is %d bytes, over the limit", i, size)
+		}
+		attrs := recordAttributes(part)
+		if attrs[chunkIDKey] != id || id == "" {
+			t.Errorf("Part %d has chunk id %q, expected %q", i, attrs[chunkIDKey], id)
+		}
+		if attrs[chunkIndexKey] != strconv.Itoa(i) || attrs[chunkCountKey] != strconv.Itoa(len(parts)) {
+			t.Errorf("Part %d has index %s of %s", i, attrs[chunkIndexKey], attrs[chunkCountKey])
+		}
+		if part.Body().Kind() == log.KindString {
+			gotBody.WriteString(part.Body().AsString())
+		}
+		gotStack.WriteString(attrs["stack"])
+	}
+	if gotBody.String() != body {
+		t.Error("Body was not reassembled from the parts")
+	}
+	if gotStack.String() != strings.Repeat("at frame\n", 300) {
+		t.Error("Large attribute was not reassembled from the parts")
+	}
+}
+
+func TestOversizeExporterTruncates(t *testing.T) {
+	dir := t.TempDir()
+	large := oversizedRecord(t, strings.Repeat("x", 10000), log.String("service", "api"))
+
+	next := &recordingExporter{}
+	exporter, err := withRecordSizeLimit(&Config{MaxRecordSize: 4096, Oversize: oversizeTruncate, OversizeDir: dir}, next)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := exporter.Export(context.Background(), []sdklog.Record{large}); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+
+	records := next.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected one truncated record, got %d", len(records))
+	}
+	attrs := recordAttributes(records[0])
+	if attrs[truncatedKey] != "true" || recordSize(records[0]) > 4096 {
+		t.Errorf("Expected a truncated record under the limit, got %v", attrs)
+	}
+	copy, err := os.ReadFile(attrs[fullCopyKey])
+	if err != nil {
+		t.Fatalf("Expected a full copy: %v", err)
+	}
+	if !strings.Contains(string(copy), strings.Repeat("x", 10000)) {
+		t.Error("Full copy does not hold the whole body")
+	}
+}
+
+func TestWithRecordSizeLimitValidation(t *testing.T) {
+	for _, config := range []*Config{
+		{MaxRecordSize: 100},
+		{MaxRecordSize: 4096, Oversize: "drop"},
+		{MaxRecordSize: 4096, Oversize: oversizeSplit, OversizeDir: t.TempDir()},
+	} {
+		if _, err := withRecordSizeLimit(config, &recordingExporter{}); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
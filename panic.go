@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+// reportPanic must be deferred directly. On panic it emits a fatal
+// self-diagnostic record with the stack through the processor, flushes it to
+// the exporter and re-panics so the process still crashes visibly.
+func reportPanic(ctx context.Context, processor *LogProcessor, where string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	processor.ReportPanic(ctx, where, recovered, debug.Stack())
+	panic(recovered)
+}
+
+// ReportPanic emits a record describing a recovered panic and flushes it
+func (p *LogProcessor) ReportPanic(ctx context.Context, where string, recovered any, stack []byte) {
+	message := fmt.Sprintf("%v", recovered)
+	entry := &LogEntry{
+		Timestamp: time.Now(),
+		Level:     "fatal",
+		Message:   fmt.Sprintf("otel-logger panic in %s: %s", where, message),
+		Fields: map[string]any{
+			string(semconv.ExceptionTypeKey):       fmt.Sprintf("%T", recovered),
+			string(semconv.ExceptionMessageKey):    message,
+			string(semconv.ExceptionStacktraceKey): string(stack),
+		},
+		Raw:      message,
+		Stream:   "system",
+		Internal: true,
+	}
+
+	// Emitting must not panic again while we are already crashing
+	defer func() {
+		if r := recover(); r != nil {
+			logError("Failed to report panic: %v\n", r)
+		}
+	}()
+
+	p.ProcessLogEntry(ctx, entry)
+	if p.flush != nil {
+		if err := p.flush(ctx); err != nil {
+			logError("Failed to flush panic report: %v\n", err)
+		}
+	}
+}
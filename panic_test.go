@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReportPanic(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	flushed := false
+	processor.SetFlusher(func(ctx context.Context) error {
+		flushed = true
+		return nil
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected the panic to be re-raised, got %v", r)
+			}
+		}()
+		defer reportPanic(context.Background(), processor, "stdout stream")
+		panic("boom")
+	}()
+
+	if !flushed {
+		t.Error("Expected the panic report to be flushed")
+	}
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record.SeverityText() != "fatal" {
+		t.Errorf("Expected fatal severity, got %s", record.SeverityText())
+	}
+	if !strings.Contains(record.Body().AsString(), "stdout stream") {
+		t.Errorf("Expected body to name the goroutine, got %q", record.Body().AsString())
+	}
+
+	attrs := recordAttributes(record)
+	if attrs["exception.message"] != "boom" {
+		t.Errorf("Expected exception.message 'boom', got %q", attrs["exception.message"])
+	}
+	if !strings.Contains(attrs["exception.stacktrace"], "TestReportPanic") {
+		t.Errorf("Expected stack trace to include the test, got %q", attrs["exception.stacktrace"])
+	}
+}
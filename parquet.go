@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// This file holds a small Parquet writer: one row group per file, one PLAIN
+// encoded data page per column, and flat optional columns only. That covers
+// what --export-format parquet produces, without pulling in a Parquet library
+// and its dependencies.
+
+// Parquet physical types
+const (
+	parquetBoolean   int32 = 0
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+)
+
+// Parquet converted types; parquetNoConversion leaves the field out
+const (
+	parquetNoConversion    int32 = -1
+	parquetUTF8            int32 = 0
+	parquetTimestampMicros int32 = 10
+)
+
+// Parquet enum values for the metadata this writer produces
+const (
+	parquetOptional          int32 = 1
+	parquetEncodingPlain     int32 = 0
+	parquetEncodingRLE       int32 = 3
+	parquetCodecUncompressed int32 = 0
+	parquetCodecGzip         int32 = 2
+	parquetDataPage          int32 = 0
+)
+
+const parquetMagic = "PAR1"
+
+// parquetColumn is an optional column; a nil value is null. Values must match
+// the physical type: bool, int32, int64, float64 or string.
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	values    []any
+}
+
+// writeParquet writes the columns as a Parquet file with a single row group.
+// Every column must hold the same number of values.
+func writeParquet(w io.Writer, columns []parquetColumn, compress bool, createdBy string) error {
+	rows := 0
+	if len(columns) > 0 {
+		rows = len(columns[0].values)
+	}
+	codec := parquetCodecUncompressed
+	if compress {
+		codec = parquetCodecGzip
+	}
+
+	out := &countingWriter{w: w}
+	if _, err := io.WriteString(out, parquetMagic); err != nil {
+		return err
+	}
+
+	type chunk struct {
+		offset       int64
+		uncompressed int64
+		compressed   int64
+	}
+	chunks := make([]chunk, len(columns))
+	var totalBytes int64
+	for i, column := range columns {
+		if len(column.values) != rows {
+			return fmt.Errorf("parquet column %s has %d values, expected %d", column.name, len(column.values), rows)
+		}
+		page, err := encodeParquetPage(column)
+		if err != nil {
+			return err
+		}
+		body := page
+		if compress {
+			if body, err = gzipBytes(page); err != nil {
+				return err
+			}
+		}
+
+		header := &thriftCompactWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(body)))
+		header.beginStruct(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.stop()
+
+		chunks[i] = chunk{
+			offset:       out.n,
+			uncompressed: int64(header.buf.Len() + len(page)),
+			compressed:   int64(header.buf.Len() + len(body)),
+		}
+		totalBytes += chunks[i].uncompressed
+		if _, err := out.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := out.Write(body); err != nil {
+			return err
+		}
+	}
+
+	meta := &thriftCompactWriter{}
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(columns)+1)
+	meta.elemBegin()
+	meta.binary(4, []byte("schema"))
+	meta.i32(5, int32(len(columns)))
+	meta.elemEnd()
+	for _, column := range columns {
+		meta.elemBegin()
+		meta.i32(1, column.physical)
+		meta.i32(3, parquetOptional)
+		meta.binary(4, []byte(column.name))
+		if column.converted != parquetNoConversion {
+			meta.i32(6, column.converted)
+		}
+		meta.elemEnd()
+	}
+	meta.i64(3, int64(rows))
+	meta.listBegin(4, thriftStruct, 1)
+	meta.elemBegin()
+	meta.listBegin(1, thriftStruct, len(columns))
+	for i, column := range columns {
+		meta.elemBegin()
+		meta.i64(2, chunks[i].offset)
+		meta.beginStruct(3)
+		meta.i32(1, column.physical)
+		meta.listBegin(2, thriftI32, 2)
+		meta.listI32(parquetEncodingPlain)
+		meta.listI32(parquetEncodingRLE)
+		meta.listBegin(3, thriftBinary, 1)
+		meta.listBinary([]byte(column.name))
+		meta.i32(4, codec)
+		meta.i64(5, int64(rows))
+		meta.i64(6, chunks[i].uncompressed)
+		meta.i64(7, chunks[i].compressed)
+		meta.i64(9, chunks[i].offset)
+		meta.endStruct()
+		meta.elemEnd()
+	}
+	meta.i64(2, totalBytes)
+	meta.i64(3, int64(rows))
+	meta.elemEnd()
+	meta.binary(6, []byte(createdBy))
+	meta.stop()
+
+	if _, err := out.Write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := binary.Write(out, binary.LittleEndian, uint32(meta.buf.Len())); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, parquetMagic)
+	return err
+}
+
+// encodeParquetPage encodes the definition levels (RLE, length-prefixed)
+// followed by the PLAIN encoded non-null values
+func encodeParquetPage(column parquetColumn) ([]byte, error) {
+	var levels bytes.Buffer
+	for i := 0; i < len(column.values); {
+		defined := column.values[i] != nil
+		j := i
+		for j < len(column.values) && (column.values[j] != nil) == defined {
+			j++
+		}
+		levels.Write(binary.AppendUvarint(nil, uint64(j-i)<<1))
+		if defined {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		i = j
+	}
+
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+
+	var bits []bool
+	for _, value := range column.values {
+		if value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case bool:
+			bits = append(bits, v)
+		case int32:
+			binary.Write(&page, binary.LittleEndian, v)
+		case int64:
+			binary.Write(&page, binary.LittleEndian, v)
+		case float64:
+			binary.Write(&page, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			binary.Write(&page, binary.LittleEndian, uint32(len(v)))
+			page.WriteString(v)
+		default:
+			return nil, fmt.Errorf("unsupported parquet value %T in column %s", value, column.name)
+		}
+	}
+	// Booleans are bit-packed, least significant bit first
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8 && i+j < len(bits); j++ {
+			if bits[i+j] {
+				b |= 1 << j
+			}
+		}
+		page.WriteByte(b)
+	}
+	return page.Bytes(), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol type IDs used by the Parquet metadata
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftCompactWriter encodes structs in the Thrift compact protocol, which
+// Parquet uses for page headers and the file footer
+type thriftCompactWriter struct {
+	buf    bytes.Buffer
+	lastID int16
+	stack  []int16
+}
+
+func (w *thriftCompactWriter) field(id int16, typ byte) {
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.lastID = id
+}
+
+func (w *thriftCompactWriter) varint(v int64) {
+	w.buf.Write(binary.AppendVarint(nil, v))
+}
+
+func (w *thriftCompactWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftCompactWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftCompactWriter) binary(id int16, v []byte) {
+	w.field(id, thriftBinary)
+	w.listBinary(v)
+}
+
+func (w *thriftCompactWriter) listBegin(id int16, elem byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elem)
+	w.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+func (w *thriftCompactWriter) listI32(v int32) {
+	w.varint(int64(v))
+}
+
+func (w *thriftCompactWriter) listBinary(v []byte) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	w.buf.Write(v)
+}
+
+// beginStruct starts a struct-typed field; elemBegin starts a struct list element
+func (w *thriftCompactWriter) beginStruct(id int16) {
+	w.field(id, thriftStruct)
+	w.elemBegin()
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.elemEnd()
+}
+
+func (w *thriftCompactWriter) elemBegin() {
+	w.stack = append(w.stack, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftCompactWriter) elemEnd() {
+	w.stop()
+	w.lastID = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+// stop ends the current struct
+func (w *thriftCompactWriter) stop() {
+	w.buf.WriteByte(0)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// thriftReader decodes Thrift compact structs into maps of field ID to value,
+// enough to check the metadata writeParquet produces
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		v := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return v
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) structure() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+// readParquetColumn decodes column i of an uncompressed file written by writeParquet
+func readParquetColumn(t *testing.T, data []byte, meta map[int16]any, i int) (string, []any) {
+	t.Helper()
+	chunk := meta[4].([]any)[0].(map[int16]any)[1].([]any)[i].(map[int16]any)
+	column := chunk[3].(map[int16]any)
+	name := column[3].([]any)[0].(string)
+	physical := column[1].(int64)
+
+	r := &thriftReader{data: data, pos: int(column[9].(int64))}
+	header := r.structure()
+	page := data[r.pos : r.pos+int(header[3].(int64))]
+	rows := int(header[5].(map[int16]any)[1].(int64))
+
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	levels := &thriftReader{data: page[4 : 4+levelsLen]}
+	var defined []bool
+	for levels.pos < len(levels.data) {
+		run := int(levels.uvarint() >> 1)
+		value := levels.data[levels.pos] == 1
+		levels.pos++
+		for range run {
+			defined = append(defined, value)
+		}
+	}
+	values := page[4+levelsLen:]
+
+	out := make([]any, rows)
+	bit := 0
+	for row := range rows {
+		if !defined[row] {
+			continue
+		}
+		switch physical {
+		case int64(parquetBoolean):
+			out[row] = values[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		case int64(parquetInt32):
+			out[row] = int32(binary.LittleEndian.Uint32(values))
+			values = values[4:]
+		case int64(parquetInt64):
+			out[row] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case int64(parquetDouble):
+			out[row] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case int64(parquetByteArray):
+			n := binary.LittleEndian.Uint32(values)
+			out[row] = string(values[4 : 4+n])
+			values = values[4+n:]
+		}
+	}
+	return name, out
+}
+
+func readParquetFooter(t *testing.T, data []byte) map[int16]any {
+	t.Helper()
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("Missing Parquet magic bytes")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	return r.structure()
+}
+
+func TestWriteParquet(t *testing.T) {
+	columns := []parquetColumn{
+		{name: "body", physical: parquetByteArray, converted: parquetUTF8, values: []any{"a", nil, "c"}},
+		{name: "attributes.http.status", physical: parquetInt64, converted: parquetNoConversion, values: []any{int64(200), int64(404), nil}},
+		{name: "attributes.cached", physical: parquetBoolean, converted: parquetNoConversion, values: []any{true, false, true}},
+	}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, false, "test"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := buf.Bytes()
+	meta := readParquetFooter(t, data)
+
+	if meta[3].(int64) != 3 {
+		t.Errorf("Expected 3 rows, got %v", meta[3])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != 4 || schema[0].(map[int16]any)[5].(int64) != 3 || schema[2].(map[int16]any)[4] != "attributes.http.status" {
+		t.Errorf("Unexpected schema %v", schema)
+	}
+
+	for i, column := range columns {
+		name, values := readParquetColumn(t, data, meta, i)
+		if name != column.name {
+			t.Errorf("Column %d is %s, expected %s", i, name, column.name)
+		}
+		for row := range values {
+			if values[row] != column.values[row] {
+				t.Errorf("Column %s row %d = %v, expected %v", name, row, values[row], column.values[row])
+			}
+		}
+	}
+}
+
+func TestParquetExporter(t *testing.T) {
+	dir := t.TempDir()
+	exporter, err := newParquetExporter(filepath.Join(dir, "logs.parquet"), 0, 0, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	start := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	exporter.now = func() time.Time { return start }
+
+	provider, source := newRecordingProvider()
+	logger := provider.Logger("otel-logger")
+	ctx := context.Background()
+	for i, status := range []log.Value{log.Int64Value(200), log.Float64Value(499.5)} {
+		var record log.Record
+		record.SetTimestamp(start.Add(time.Duration(i) * time.Second))
+		record.SetSeverity(log.SeverityWarn)
+		record.SetBody(log.StringValue("request"))
+		record.AddAttributes(
+			log.KeyValue{Key: "status", Value: status},
+			log.Map("user", log.String("id", "u1")),
+		)
+		logger.Emit(ctx, record)
+	}
+	if err := exporter.Export(ctx, source.Records()); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "logs-20250102T150405Z.parquet"))
+	if err != nil {
+		t.Fatalf("Expected a parquet file named after its start time: %v", err)
+	}
+	meta := readParquetFooter(t, data)
+	got := make(map[string][]any)
+	for i := range meta[4].([]any)[0].(map[int16]any)[1].([]any) {
+		name, values := readParquetColumn(t, data, meta, i)
+		got[name] = values
+	}
+
+	if got["timestamp"][1] != start.Add(time.Second).UnixMicro() {
+		t.Errorf("Unexpected timestamps %v", got["timestamp"])
+	}
+	if got["severity_number"][0] != int32(log.SeverityWarn) || got["body"][0] != "request" {
+		t.Errorf("Unexpected severity %v or body %v", got["severity_number"], got["body"])
+	}
+	if got["attributes.status"][0] != float64(200) || got["attributes.status"][1] != 499.5 {
+		t.Errorf("Expected mixed numbers in a double column, got %v", got["attributes.status"])
+	}
+	if got["attributes.user.id"][0] != "u1" {
+		t.Errorf("Expected nested attributes flattened, got %v", got["attributes.user.id"])
+	}
+	if _, ok := got["resource.telemetry.sdk.language"]; !ok {
+		t.Errorf("Expected resource attribute columns, got %v", got)
+	}
+}
+
+func TestNewExportFileExporterFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.parquet")
+	exporter, err := newExportFileExporter(&Config{ExportFile: path, ExportFormat: exportFormatParquet})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p, ok := exporter.(*parquetExporter); !ok || p.maxSize != defaultParquetRotation {
+		t.Errorf("Expected a parquet exporter with the default rotation, got %#v", exporter)
+	}
+	if _, err := newExportFileExporter(&Config{ExportFile: path, ExportFormat: "csv"}); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// Export file formats
+const (
+	exportFormatOTLPJSON = "otlp-json"
+	exportFormatParquet  = "parquet"
+)
+
+// defaultParquetRotation bounds the records held in memory when
+// --export-file-rotate is not set
+const defaultParquetRotation = 128 * 1000 * 1000
+
+// parquetExporter writes records to Parquet files for data lakes. A Parquet
+// file carries its schema in the footer, and attribute columns are only known
+// once every record is in, so records are held in memory and each file is
+// written whole when it is rotated or on shutdown. Files are named after the
+// time their first record arrived, like rotated --export-file files.
+type parquetExporter struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	compress bool
+	now      func() time.Time
+
+	rows   []normalizedRecord
+	size   int64
+	opened time.Time
+}
+
+func newParquetExporter(path string, maxSize int64, maxAge time.Duration, compress bool) (*parquetExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	return &parquetExporter{
+		path:     path,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		compress: compress,
+		now:      time.Now,
+	}, nil
+}
+
+func (e *parquetExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.rows) == 0 {
+		e.opened = e.now()
+	}
+	for _, record := range records {
+		row := normalizeRecord(record)
+		e.rows = append(e.rows, row)
+		e.size += row.estimatedSize()
+	}
+
+	if (e.maxSize > 0 && e.size >= e.maxSize) || (e.maxAge > 0 && e.now().Sub(e.opened) >= e.maxAge) {
+		return e.write()
+	}
+	return nil
+}
+
+// write stores the held records as a Parquet file. The file is written under
+// a temporary name first so readers never pick up a partial file.
+func (e *parquetExporter) write() error {
+	if len(e.rows) == 0 {
+		return nil
+	}
+
+	name := rotatedFileName(e.path, e.opened)
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".otel-logger-*.parquet")
+	if err != nil {
+		return fmt.Errorf("failed to create parquet file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = writeParquet(tmp, parquetColumns(e.rows), e.compress, "otel-logger version "+version)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+
+	e.rows, e.size = nil, 0
+	return nil
+}
+
+func (e *parquetExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func (e *parquetExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.write()
+}
+
+// parquetColumns lays the rows out as columns: the fixed record fields, then
+// one column per resource and attribute key in name order. A key whose values
+// are all booleans, all integers or all numbers gets that type; anything else
+// is stored as text.
+func parquetColumns(rows []normalizedRecord) []parquetColumn {
+	column := func(name string, physical, converted int32, value func(normalizedRecord) any) parquetColumn {
+		c := parquetColumn{name: name, physical: physical, converted: converted, values: make([]any, len(rows))}
+		for i, row := range rows {
+			c.values[i] = value(row)
+		}
+		return c
+	}
+	text := func(s string) any {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	micros := func(t time.Time) any {
+		if t.IsZero() {
+			return nil
+		}
+		return t.UnixMicro()
+	}
+
+	columns := []parquetColumn{
+		column("timestamp", parquetInt64, parquetTimestampMicros, func(r normalizedRecord) any { return micros(r.timestamp) }),
+		column("observed_timestamp", parquetInt64, parquetTimestampMicros, func(r normalizedRecord) any { return micros(r.observed) }),
+		column("severity_number", parquetInt32, parquetNoConversion, func(r normalizedRecord) any { return int32(r.severity) }),
+		column("severity_text", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.severityText) }),
+		column("body", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.body) }),
+		column("event_name", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.eventName) }),
+		column("trace_id", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.traceID) }),
+		column("span_id", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.spanID) }),
+		column("scope_name", parquetByteArray, parquetUTF8, func(r normalizedRecord) any { return text(r.scope) }),
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for key := range row.attributes {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		allBool, allInt, allNumber := true, true, true
+		for _, row := range rows {
+			switch row.attributes[key].(type) {
+			case nil:
+			case bool:
+				allInt, allNumber = false, false
+			case int64:
+				allBool = false
+			case float64:
+				allBool, allInt = false, false
+			default:
+				allBool, allInt, allNumber = false, false, false
+			}
+		}
+
+		switch {
+		case allBool:
+			columns = append(columns, column(key, parquetBoolean, parquetNoConversion, func(r normalizedRecord) any { return r.attributes[key] }))
+		case allInt:
+			columns = append(columns, column(key, parquetInt64, parquetNoConversion, func(r normalizedRecord) any { return r.attributes[key] }))
+		case allNumber:
+			columns = append(columns, column(key, parquetDouble, parquetNoConversion, func(r normalizedRecord) any {
+				if n, ok := r.attributes[key].(int64); ok {
+					return float64(n)
+				}
+				return r.attributes[key]
+			}))
+		default:
+			columns = append(columns, column(key, parquetByteArray, parquetUTF8, func(r normalizedRecord) any {
+				switch v := r.attributes[key].(type) {
+				case nil:
+					return nil
+				case string:
+					return v
+				case bool:
+					return strconv.FormatBool(v)
+				case int64:
+					return strconv.FormatInt(v, 10)
+				case float64:
+					return strconv.FormatFloat(v, 'g', -1, 64)
+				default:
+					return fmt.Sprint(v)
+				}
+			}))
+		}
+	}
+	return columns
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"syscall"
+)
+
+// Policies for a passthrough output that stops accepting writes
+const (
+	passthroughClosedContinue  = "continue"
+	passthroughClosedTerminate = "terminate"
+)
+
+// passthroughSink forwards output to a human-facing writer. When the writer
+// breaks (typically EPIPE from `otel-logger ... | head`) further output is
+// silently discarded so log export carries on, and onClose runs once.
+type passthroughSink struct {
+	mu      sync.Mutex
+	name    string
+	w       io.Writer
+	closed  bool
+	onClose func()
+}
+
+func newPassthroughSink(name string, w io.Writer, onClose func()) *passthroughSink {
+	return &passthroughSink{name: name, w: w, onClose: onClose}
+}
+
+// Write never fails so callers can keep processing after the sink is gone
+func (s *passthroughSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return len(p), nil
+	}
+
+	if _, err := s.w.Write(p); err != nil {
+		s.closed = true
+		if errors.Is(err, syscall.EPIPE) {
+			logError("Passthrough %s closed by reader, continuing to export logs\n", s.name)
+		} else {
+			logError("Passthrough %s failed, continuing to export logs: %v\n", s.name, err)
+		}
+		if s.onClose != nil {
+			s.onClose()
+		}
+	}
+
+	return len(p), nil
+}
+
+// Closed reports whether the sink has stopped forwarding output
+func (s *passthroughSink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// teePassthrough returns the reader the parser should consume and whether the
+// parsed entries still need to be re-emitted. In raw mode the output is copied
+// byte-for-byte as the command writes it, preserving carriage returns,
+// partial lines and interleaving for human viewers.
+func teePassthrough(r io.Reader, enabled, raw bool, sink io.Writer) (io.Reader, bool) {
+	if !enabled {
+		return r, false
+	}
+	if raw {
+		return io.TeeReader(r, sink), false
+	}
+	return r, true
+}
+
+// compilePassthroughGrep compiles --grep, which filters what the passthrough
+// shows without changing what is exported. Raw passthrough copies bytes
+// before entries are split, so it cannot be filtered.
+func compilePassthroughGrep(config *Config) (*regexp.Regexp, error) {
+	if config.Grep == "" {
+		return nil, nil
+	}
+	if !config.PassthroughStdout && !config.PassthroughStderr {
+		return nil, fmt.Errorf("--grep requires --passthrough-stdout or --passthrough-stderr")
+	}
+	if config.PassthroughRaw {
+		return nil, fmt.Errorf("--grep cannot be combined with --passthrough-raw")
+	}
+	grep, err := regexp.Compile(config.Grep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --grep: %w", err)
+	}
+	return grep, nil
+}
+
+func validatePassthroughClosed(policy string) error {
+	switch policy {
+	case "", passthroughClosedContinue, passthroughClosedTerminate:
+		return nil
+	default:
+		return fmt.Errorf("invalid --passthrough-closed %q (supported: %s, %s)", policy, passthroughClosedContinue, passthroughClosedTerminate)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPassthroughSinkBrokenPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer w.Close()
+	r.Close()
+
+	closes := 0
+	sink := newPassthroughSink("stdout", w, func() { closes++ })
+
+	for i := 0; i < 3; i++ {
+		if n, err := sink.Write([]byte("line\n")); err != nil || n != 5 {
+			t.Errorf("Write %d: expected (5, nil), got (%d, %v)", i, n, err)
+		}
+	}
+
+	if !sink.Closed() {
+		t.Error("Expected sink to be closed after EPIPE")
+	}
+	if closes != 1 {
+		t.Errorf("Expected onClose to run once, ran %d times", closes)
+	}
+}
+
+func TestExecuteCommandPassthroughClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		command []string
+		minLogs int
+	}{
+		{
+			name:    "continue keeps exporting",
+			policy:  passthroughClosedContinue,
+			command: []string{"sh", "-c", "for i in 1 2 3 4 5; do echo line $i; done; sleep 0.2"},
+			minLogs: 6, // five lines plus the exit record
+		},
+		{
+			name:    "terminate stops the command",
+			policy:  passthroughClosedTerminate,
+			command: []string{"sh", "-c", "while true; do echo tick; sleep 0.05; done"},
+			minLogs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("Failed to create pipe: %v", err)
+			}
+			r.Close()
+			defer w.Close()
+
+			origStdout := os.Stdout
+			os.Stdout = w
+			defer func() { os.Stdout = origStdout }()
+
+			config := &Config{
+				PassthroughStdout:   true,
+				PassthroughClosed:   tt.policy,
+				ContinuationPattern: "^[ \\t]",
+				Command:             tt.command,
+			}
+
+			provider, exporter := newRecordingProvider()
+			processor := NewLogProcessor(provider.Logger("test"))
+			extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- executeCommand(context.Background(), config, extractor, processor)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("Command did not finish after passthrough closed")
+			}
+
+			if got := len(exporter.Records()); got < tt.minLogs {
+				t.Errorf("Expected at least %d exported records, got %d", tt.minLogs, got)
+			}
+		})
+	}
+}
+
+func TestTeePassthroughRaw(t *testing.T) {
+	input := "Downloading 10%\rDownloading 50%\rDownloading 100%\n{\n  \"level\": \"info\"\n}\npartial"
+
+	var output bytes.Buffer
+	reader, reemit := teePassthrough(strings.NewReader(input), true, true, &output)
+	if reemit {
+		t.Error("Raw passthrough should not re-emit parsed entries")
+	}
+
+	var entries []string
+	for entry := range multilineLogIterator(reader, regexp.MustCompile(`^[ \t]`)) {
+		entries = append(entries, entry)
+	}
+
+	if output.String() != input {
+		t.Errorf("Expected byte-for-byte passthrough %q, got %q", input, output.String())
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected parser to still see 3 entries, got %d: %q", len(entries), entries)
+	}
+
+	if _, reemit := teePassthrough(strings.NewReader(input), true, false, &output); !reemit {
+		t.Error("Entry passthrough should re-emit parsed entries")
+	}
+	if _, reemit := teePassthrough(strings.NewReader(input), false, true, &output); reemit {
+		t.Error("Disabled passthrough should not re-emit parsed entries")
+	}
+}
+
+func TestProcessStreamGrep(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	input := strings.Join([]string{
+		`{"level":"info","msg":"healthcheck ok"}`,
+		`{"level":"error","msg":"payment failed"}`,
+		`{"level":"info","msg":"healthcheck ok"}`,
+		`{"level":"warn","msg":"payment retry"}`,
+	}, "\n")
+
+	var output bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	processStream(context.Background(), strings.NewReader(input), "stdout", extractor, processor, &wg, streamOptions{
+		passthrough:         true,
+		output:              &output,
+		grep:                regexp.MustCompile(`payment`),
+		continuationPattern: defaultContinuationPattern,
+		split:               bufio.ScanLines,
+	})
+
+	expected := `{"level":"error","msg":"payment failed"}` + "\n" + `{"level":"warn","msg":"payment retry"}` + "\n"
+	if output.String() != expected {
+		t.Errorf("Expected only matching entries in passthrough, got %q", output.String())
+	}
+	if got := len(exporter.Records()); got != 4 {
+		t.Errorf("Expected all 4 entries to be exported, got %d", got)
+	}
+}
+
+func TestCompilePassthroughGrep(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{name: "unset", config: Config{}},
+		{name: "with passthrough", config: Config{Grep: "err", PassthroughStderr: true}},
+		{name: "without passthrough", config: Config{Grep: "err"}, expectError: true},
+		{name: "raw passthrough", config: Config{Grep: "err", PassthroughStdout: true, PassthroughRaw: true}, expectError: true},
+		{name: "invalid regex", config: Config{Grep: "(", PassthroughStdout: true}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compilePassthroughGrep(&tt.config)
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
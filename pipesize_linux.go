@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// setPipeSize sets the capacity of the pipe, which the kernel rounds up to
+// a power of two pages, and returns it
+func setPipeSize(pipe *os.File, size int) (int, error) {
+	conn, err := pipe.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var got int
+	var setErr error
+	// Through the raw descriptor, as Fd would put the pipe in blocking mode
+	if err := conn.Control(func(fd uintptr) {
+		got, setErr = unix.FcntlInt(fd, unix.F_SETPIPE_SZ, size)
+	}); err != nil {
+		return 0, err
+	}
+	return got, setErr
+}
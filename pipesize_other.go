@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// setPipeSize is only implemented with F_SETPIPE_SZ on Linux
+func setPipeSize(pipe *os.File, size int) (int, error) {
+	return 0, errors.New("--pipe-buffer-size is only supported on Linux")
+}
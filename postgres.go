@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// defaultLogLinePrefix is PostgreSQL's default log_line_prefix since 13
+const defaultLogLinePrefix = "%m [%p] "
+
+// postgresEscape is what a log_line_prefix escape such as %u matches, and
+// the group it is captured in
+type postgresEscape struct {
+	group   string
+	pattern string
+}
+
+const postgresTimestampPattern = `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`
+
+var postgresEscapes = map[byte]postgresEscape{
+	'a': {"application", `.*?`},
+	'u': {"user", `.*?`},
+	'd': {"database", `.*?`},
+	'r': {"remote", `.*?`},
+	'h': {"host", `.*?`},
+	'L': {"local", `.*?`},
+	'b': {"backend", `.*?`},
+	'i': {"command", `.*?`},
+	'p': {"pid", `\d+`},
+	'P': {"leader", `\d*`},
+	't': {"time", postgresTimestampPattern + ` [^ \]]+`},
+	'm': {"time", postgresTimestampPattern + `\.\d{3} [^ \]]+`},
+	'n': {"epoch", `\d+\.\d{3}`},
+	's': {"start", postgresTimestampPattern + ` [^ \]]+`},
+	'e': {"sqlstate", `[0-9A-Z]{5}`},
+	'c': {"session", `[0-9a-f]+\.[0-9a-f]+`},
+	'l': {"line", `\d+`},
+	'v': {"vxid", `[^ \]]*`},
+	'x': {"xid", `\d+`},
+	'Q': {"query", `-?\d+`},
+}
+
+// Attributes of the log_line_prefix escapes, by group
+var postgresPrefixKeys = map[string]string{
+	"application": "postgresql.application_name",
+	"user":        "user.name",
+	"database":    "db.namespace",
+	"host":        "client.address",
+	"local":       "server.address",
+	"backend":     "postgresql.backend_type",
+	"command":     "postgresql.command_tag",
+	"leader":      "postgresql.leader_pid",
+	"start":       "postgresql.session_start",
+	"sqlstate":    "db.response.status_code",
+	"session":     "postgresql.session_id",
+	"line":        "postgresql.session_line_num",
+	"vxid":        "postgresql.virtual_transaction_id",
+	"xid":         "postgresql.transaction_id",
+	"query":       "postgresql.query_id",
+}
+
+// postgresIntegers are the groups exported as integers
+var postgresIntegers = map[string]bool{"leader": true, "line": true, "xid": true, "query": true}
+
+// postgresSeverities are the severities of PostgreSQL's message levels
+var postgresSeverities = map[string]log.Severity{
+	"DEBUG5":  log.SeverityDebug1,
+	"DEBUG4":  log.SeverityDebug1,
+	"DEBUG3":  log.SeverityDebug2,
+	"DEBUG2":  log.SeverityDebug3,
+	"DEBUG1":  log.SeverityDebug4,
+	"LOG":     log.SeverityInfo1,
+	"INFO":    log.SeverityInfo1,
+	"NOTICE":  log.SeverityInfo2,
+	"WARNING": log.SeverityWarn1,
+	"ERROR":   log.SeverityError1,
+	"FATAL":   log.SeverityFatal1,
+	"PANIC":   log.SeverityFatal2,
+}
+
+// postgresSections are the attributes of the lines PostgreSQL writes after a
+// message, which are grouped with it
+var postgresSections = map[string]string{
+	"DETAIL":    "postgresql.detail",
+	"HINT":      "postgresql.hint",
+	"CONTEXT":   "postgresql.context",
+	"QUERY":     "postgresql.internal_query",
+	"LOCATION":  "postgresql.location",
+	"STATEMENT": "db.query.text",
+}
+
+// Attributes derived from the message
+const (
+	postgresDurationKey = "postgresql.duration_ms"
+	postgresQueryKey    = "db.query.text"
+	postgresSQLStateKey = "db.response.status_code"
+)
+
+var (
+	postgresSQLStatePrefix = regexp.MustCompile(`^([0-9A-Z]{5}): `)
+	postgresDuration       = regexp.MustCompile(`^duration: (\d+(?:\.\d+)?) ms(?:  (?:statement|(?:execute|parse|bind) [^:]*): (?s:(.*)))?$`)
+	postgresStatement      = regexp.MustCompile(`^statement: (?s:(.*))$`)
+)
+
+// postgresLog holds the patterns of a log_line_prefix
+type postgresLog struct {
+	line         *regexp.Regexp // prefix, message level and message
+	section      *regexp.Regexp // prefix, DETAIL, HINT, ... and text
+	continuation *regexp.Regexp // lines to group with the message before them
+}
+
+var (
+	postgresLogsMu sync.Mutex
+	postgresLogs   = map[string]*postgresLog{}
+)
+
+// compilePostgresLog compiles the patterns of a log_line_prefix, caching
+// them as every line needs them
+func compilePostgresLog(prefix string) (*postgresLog, error) {
+	if prefix == "" {
+		prefix = defaultLogLinePrefix
+	}
+	postgresLogsMu.Lock()
+	defer postgresLogsMu.Unlock()
+	if pl, ok := postgresLogs[prefix]; ok {
+		return pl, nil
+	}
+
+	pattern, err := postgresPrefixPattern(prefix)
+	if err != nil {
+		return nil, err
+	}
+	sections := `(?P<section>DETAIL|HINT|CONTEXT|QUERY|LOCATION|STATEMENT):  `
+	pl := &postgresLog{
+		line:         regexp.MustCompile(`^` + pattern + `(?P<level>DEBUG[1-5]|INFO|NOTICE|WARNING|ERROR|LOG|FATAL|PANIC):  (?P<message>.*)$`),
+		section:      regexp.MustCompile(`^` + pattern + sections + `(?P<message>.*)$`),
+		continuation: regexp.MustCompile(`^` + pattern + sections),
+	}
+	postgresLogs[prefix] = pl
+	return pl, nil
+}
+
+// postgresPrefixPattern translates a log_line_prefix into a regular
+// expression with a group per escape. Padding such as %-10u is allowed, and
+// everything after %q is optional, as PostgreSQL leaves it out for
+// processes without a session.
+func postgresPrefixPattern(prefix string) (string, error) {
+	var b strings.Builder
+	optional := false
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] != '%' {
+			b.WriteString(regexp.QuoteMeta(prefix[i : i+1]))
+			continue
+		}
+		i++
+		padded := false
+		for i < len(prefix) && (prefix[i] == '-' || prefix[i] >= '0' && prefix[i] <= '9') {
+			padded, i = true, i+1
+		}
+		if i == len(prefix) {
+			return "", fmt.Errorf("invalid log_line_prefix %q: ends in %%", prefix)
+		}
+		switch c := prefix[i]; c {
+		case '%':
+			b.WriteString("%")
+		case 'q':
+			if !optional {
+				b.WriteString("(?:")
+				optional = true
+			}
+		default:
+			escape, ok := postgresEscapes[c]
+			if !ok {
+				return "", fmt.Errorf("invalid log_line_prefix %q: unknown escape %%%c", prefix, c)
+			}
+			group := "(?P<" + escape.group + ">" + escape.pattern + ")"
+			if padded {
+				group = " *" + group + " *"
+			}
+			b.WriteString(group)
+		}
+	}
+	if optional {
+		b.WriteString(")?")
+	}
+	return b.String(), nil
+}
+
+// ParsePostgresEntry decodes a PostgreSQL server log message as written to
+// stderr with the configured log_line_prefix. The prefix escapes, SQLSTATE
+// and duration become attributes, and DETAIL, HINT, STATEMENT and the other
+// lines grouped with the message become postgresql.* and db.query.text.
+// Lines that are not PostgreSQL messages become plain messages.
+func (je *JSONExtractor) ParsePostgresEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parsePostgres(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parsePostgres(line string) (*LogEntry, bool) {
+	_, fieldMappings := je.snapshot()
+	pl, err := compilePostgresLog(fieldMappings.LogLinePrefix)
+	if err != nil {
+		return nil, false
+	}
+
+	lines := strings.Split(line, "\n")
+	m := pl.line.FindStringSubmatch(lines[0])
+	if m == nil {
+		return nil, false
+	}
+	entry := &LogEntry{Fields: make(map[string]any), Raw: line}
+	for i, group := range pl.line.SubexpNames() {
+		if group != "" && m[i] != "" && m[i] != "[unknown]" {
+			setPostgresGroup(entry, group, m[i])
+		}
+	}
+	if entry.Timestamp.IsZero() {
+		je.stampIngestTime(entry)
+	}
+
+	// Lines after the first are sections such as DETAIL, or tab-indented
+	// continuations of the message or section before them
+	section := ""
+	for _, rest := range lines[1:] {
+		if s := pl.section.FindStringSubmatch(rest); s != nil {
+			section = postgresSections[s[pl.section.SubexpIndex("section")]]
+			entry.Fields[section] = s[pl.section.SubexpIndex("message")]
+			continue
+		}
+		rest = strings.TrimPrefix(rest, "\t")
+		if section == "" {
+			entry.Message += "\n" + rest
+		} else {
+			entry.Fields[section] = entry.Fields[section].(string) + "\n" + rest
+		}
+	}
+
+	if s := postgresSQLStatePrefix.FindStringSubmatch(entry.Message); s != nil {
+		entry.Fields[postgresSQLStateKey] = s[1]
+		entry.Message = entry.Message[len(s[0]):]
+	}
+	if d := postgresDuration.FindStringSubmatch(entry.Message); d != nil {
+		if ms, err := strconv.ParseFloat(d[1], 64); err == nil {
+			entry.Fields[postgresDurationKey] = ms
+		}
+		if d[2] != "" {
+			entry.Fields[postgresQueryKey] = d[2]
+		}
+	} else if s := postgresStatement.FindStringSubmatch(entry.Message); s != nil {
+		entry.Fields[postgresQueryKey] = s[1]
+	}
+	entry.Message = strings.TrimSpace(entry.Message)
+	return entry, true
+}
+
+// setPostgresGroup stores one captured group of the line
+func setPostgresGroup(entry *LogEntry, group, value string) {
+	switch group {
+	case "level":
+		entry.Level = value
+		entry.Severity = postgresSeverities[value]
+	case "message":
+		entry.Message = value
+	case "time":
+		if t, err := parsePostgresTime(value); err == nil {
+			entry.Timestamp = t
+		}
+	case "epoch":
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			entry.Timestamp = epochTime(seconds, timestampSeconds)
+		}
+	case "pid":
+		if pid, err := strconv.Atoi(value); err == nil {
+			entry.Thread = &ThreadInfo{PID: pid}
+		}
+	case "remote":
+		host, port, _ := strings.Cut(strings.TrimSuffix(value, ")"), "(")
+		entry.Fields["client.address"] = host
+		if n, err := strconv.Atoi(port); err == nil {
+			entry.Fields["client.port"] = n
+		}
+	default:
+		key, ok := postgresPrefixKeys[group]
+		if !ok {
+			return
+		}
+		if postgresIntegers[group] {
+			if n, err := strconv.Atoi(value); err == nil {
+				entry.Fields[key] = n
+			}
+			return
+		}
+		entry.Fields[key] = value
+	}
+}
+
+// parsePostgresTime reads the %t and %m timestamps, whose zone is an
+// abbreviation such as UTC or an offset such as +01
+func parsePostgresTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"2006-01-02 15:04:05.000 MST", "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05.000 -07", "2006-01-02 15:04:05 -07"} {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// continuationFor compiles the --continuation-pattern, extended for the
+// extractor's format: with --format postgres the DETAIL, HINT and other
+// lines PostgreSQL writes after a message are grouped with it, with
+// --format mysql-slow every line up to the next "# User@Host:" is, and with
+// --format django-server Python tracebacks are. With --framing cri and
+// docker-json the patterns test the content after each line's CRI prefix.
+func continuationFor(pattern string, extractor *JSONExtractor) (*regexp.Regexp, error) {
+	continuation, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	_, fieldMappings := extractor.snapshot()
+	var extra string
+	switch fieldMappings.Format {
+	case formatPostgres:
+		pl, err := compilePostgresLog(fieldMappings.LogLinePrefix)
+		if err != nil {
+			return nil, err
+		}
+		extra = pl.continuation.String()
+	case formatMySQLSlow:
+		extra = notPrefixPattern(mysqlSlowEntryStart)
+	case formatDjango:
+		extra = pythonTraceback.String()
+	default:
+		if !extractor.cri {
+			return continuation, nil
+		}
+	}
+	if extractor.cri {
+		if pattern, err = criContinuation(pattern); err != nil {
+			return nil, err
+		}
+		if extra != "" {
+			if extra, err = criContinuation(extra); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if extra == "" {
+		return regexp.Compile(pattern)
+	}
+	return regexp.Compile("(?:" + pattern + ")|(?:" + extra + ")")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func postgresExtractor(t *testing.T, prefix string) *JSONExtractor {
+	t.Helper()
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatPostgres
+	mappings.LogLinePrefix = prefix
+	return NewJSONExtractor("", mappings)
+}
+
+func TestParsePostgresEntry(t *testing.T) {
+	extractor := postgresExtractor(t, "%m [%p] %q%u@%d ")
+
+	tests := []struct {
+		name     string
+		line     string
+		message  string
+		level    string
+		severity log.Severity
+		fields   map[string]any
+	}{
+		{
+			name:     "error with sections",
+			line:     "2024-01-15 10:30:45.123 UTC [4242] app@shop ERROR:  duplicate key value violates unique constraint \"users_pkey\"\n2024-01-15 10:30:45.123 UTC [4242] app@shop DETAIL:  Key (id)=(1) already exists.\n2024-01-15 10:30:45.123 UTC [4242] app@shop STATEMENT:  INSERT INTO users (id)\n\tVALUES (1)",
+			message:  `duplicate key value violates unique constraint "users_pkey"`,
+			level:    "ERROR",
+			severity: log.SeverityError1,
+			fields: map[string]any{
+				"user.name":         "app",
+				"db.namespace":      "shop",
+				"postgresql.detail": "Key (id)=(1) already exists.",
+				"db.query.text":     "INSERT INTO users (id)\nVALUES (1)",
+			},
+		},
+		{
+			name:     "duration",
+			line:     "2024-01-15 10:30:45.123 UTC [4242] app@shop LOG:  duration: 1532.012 ms  statement: SELECT pg_sleep(1.5)",
+			message:  "duration: 1532.012 ms  statement: SELECT pg_sleep(1.5)",
+			level:    "LOG",
+			severity: log.SeverityInfo1,
+			fields: map[string]any{
+				"user.name":              "app",
+				"db.namespace":           "shop",
+				"postgresql.duration_ms": 1532.012,
+				"db.query.text":          "SELECT pg_sleep(1.5)",
+			},
+		},
+		{
+			name:     "background process without a session",
+			line:     "2024-01-15 10:30:45.123 UTC [17] LOG:  checkpoint starting: time",
+			message:  "checkpoint starting: time",
+			level:    "LOG",
+			severity: log.SeverityInfo1,
+			fields:   map[string]any{},
+		},
+		{
+			name:     "verbose SQLSTATE",
+			line:     "2024-01-15 10:30:45.123 UTC [4242] app@shop FATAL:  28P01: password authentication failed for user \"app\"",
+			message:  `password authentication failed for user "app"`,
+			level:    "FATAL",
+			severity: log.SeverityFatal1,
+			fields:   map[string]any{"user.name": "app", "db.namespace": "shop", "db.response.status_code": "28P01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, entry.Message)
+			}
+			if entry.Level != tt.level || entry.severity() != tt.severity {
+				t.Errorf("Expected %s at %v, got %s at %v", tt.level, tt.severity, entry.Level, entry.severity())
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.fields) {
+				t.Errorf("Expected fields %v, got %v", tt.fields, entry.Fields)
+			}
+			if expected := time.Date(2024, 1, 15, 10, 30, 45, 123e6, time.UTC); !entry.Timestamp.Equal(expected) {
+				t.Errorf("Expected timestamp %v, got %v", expected, entry.Timestamp)
+			}
+			if entry.Thread == nil || entry.Thread.PID == 0 {
+				t.Errorf("Expected the pid, got %+v", entry.Thread)
+			}
+		})
+	}
+
+	entry, _ := extractor.ParseLogEntry("not a postgres line")
+	if entry.Message != "not a postgres line" {
+		t.Errorf("Expected a plain message, got %q", entry.Message)
+	}
+}
+
+func TestPostgresPrefixEscapes(t *testing.T) {
+	extractor := postgresExtractor(t, "%t [%p]: [%l-1] user=%u,db=%d,app=%a,client=%r,e=%e ")
+	entry, err := extractor.ParseLogEntry("2024-01-15 10:30:45 UTC [4242]: [7-1] user=app,db=shop,app=psql,client=10.0.0.5(53412),e=42P01 ERROR:  relation \"nope\" does not exist")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"postgresql.session_line_num": 7,
+		"user.name":                   "app",
+		"db.namespace":                "shop",
+		"postgresql.application_name": "psql",
+		"client.address":              "10.0.0.5",
+		"client.port":                 53412,
+		"db.response.status_code":     "42P01",
+	}
+	if !reflect.DeepEqual(entry.Fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, entry.Fields)
+	}
+
+	if _, err := postgresPrefixPattern("%m %z "); err == nil {
+		t.Error("Expected error for an unknown escape")
+	}
+}
+
+func TestPostgresContinuation(t *testing.T) {
+	extractor := postgresExtractor(t, "")
+	continuation, err := continuationFor(`^[ \t]`, extractor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := strings.Join([]string{
+		"2024-01-15 10:30:45.123 UTC [42] ERROR:  syntax error at or near \"SELEC\" at character 1",
+		"2024-01-15 10:30:45.123 UTC [42] STATEMENT:  SELEC 1",
+		"2024-01-15 10:30:46.000 UTC [42] LOG:  checkpoint complete",
+	}, "\n")
+	entries := slices.Collect(multilineLogIterator(strings.NewReader(input), continuation))
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 grouped entries, got %d: %q", len(entries), entries)
+	}
+	entry, _ := extractor.ParseLogEntry(entries[0])
+	if entry.Fields["db.query.text"] != "SELEC 1" {
+		t.Errorf("Expected the statement to be grouped, got %v", entry.Fields)
+	}
+
+	if plain, _ := continuationFor(`^[ \t]`, NewJSONExtractor("", getDefaultFieldMappings())); plain.String() != `^[ \t]` {
+		t.Errorf("Expected other formats to keep the pattern, got %s", plain)
+	}
+}
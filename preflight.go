@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// http2Preface is the client connection preface every gRPC (HTTP/2) server expects
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// otlpEndpoint describes where the logs exporter will send data, resolved
+// from the standard OpenTelemetry environment variables
+type otlpEndpoint struct {
+	Protocol string
+	URL      *url.URL
+	Insecure bool
+}
+
+// PreflightError is returned when the OTLP endpoint probe fails. Hint carries
+// an actionable suggestion for the most likely misconfiguration.
+type PreflightError struct {
+	Stage    string // dns, connect, tls, http or grpc
+	Endpoint string
+	Err      error
+	Hint     string
+	Mismatch bool // the endpoint answered with the other OTLP protocol
+}
+
+func (e *PreflightError) Error() string {
+	msg := fmt.Sprintf("OTLP endpoint preflight failed (%s) for %s: %v", e.Stage, e.Endpoint, e.Err)
+	if e.Hint != "" {
+		msg += "\n  hint: " + e.Hint
+	}
+	return msg
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// connectionLevel reports whether the failure means nothing usable is listening
+// for this protocol, as opposed to a DNS, TLS or auth problem that a protocol
+// switch would not fix
+func (e *PreflightError) connectionLevel() bool {
+	return e.Stage == "connect" || e.Mismatch
+}
+
+// resolveProtocol returns the configured OTLP protocol for logs, defaulting to http/protobuf
+func resolveProtocol() string {
+	if proto, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL"); ok {
+		return strings.ToLower(proto)
+	}
+	if proto, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_PROTOCOL"); ok {
+		return strings.ToLower(proto)
+	}
+	return "http/protobuf"
+}
+
+// resolveEndpoint mirrors how the OTLP exporters pick their endpoint so the
+// preflight probes the same address the exporter will use
+func resolveEndpoint(protocol string) (*otlpEndpoint, error) {
+	raw, signalSpecific := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	if !signalSpecific {
+		raw = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	// The generic endpoint is a base URL; the HTTP exporter appends the signal path
+	return parseEndpoint(raw, protocol, !signalSpecific)
+}
+
+// parseEndpoint fills in the scheme, port and, if appendPath is set, the
+// /v1/logs path the exporters would use for an endpoint given as configured.
+// Only a bare host gets the OTLP port; a URL without one uses its scheme's
+// default port, as it does in the exporters.
+func parseEndpoint(raw, protocol string, appendPath bool) (*otlpEndpoint, error) {
+	isGRPC := protocol == "grpc"
+
+	bare := !strings.Contains(raw, "://")
+	if raw == "" {
+		if isGRPC {
+			raw = "http://localhost:4317"
+		} else {
+			raw = "http://localhost:4318"
+		}
+	} else if bare {
+		// gRPC endpoints are commonly given as plain host:port
+		scheme := "https"
+		if envBool("OTEL_EXPORTER_OTLP_LOGS_INSECURE") || envBool("OTEL_EXPORTER_OTLP_INSECURE") {
+			scheme = "http"
+		}
+		raw = scheme + "://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", raw, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: missing host", raw)
+	}
+
+	if bare && u.Port() == "" {
+		port := "4318"
+		if isGRPC {
+			port = "4317"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if !isGRPC && appendPath {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/logs"
+	}
+
+	return &otlpEndpoint{
+		Protocol: protocol,
+		URL:      u,
+		Insecure: u.Scheme == "http",
+	}, nil
+}
+
+func envBool(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(name)), "true")
+}
+
+// address is the host and port to connect to, with the scheme's default
+// port if the URL has none
+func (ep *otlpEndpoint) address() string {
+	if ep.URL.Port() != "" {
+		return ep.URL.Host
+	}
+	port := "443"
+	if ep.Insecure {
+		port = "80"
+	}
+	return net.JoinHostPort(ep.URL.Hostname(), port)
+}
+
+// preflightEndpoint resolves and probes the endpoint, returning a
+// *PreflightError describing the first problem found. It connects with the
+// CA, client certificate and headers the exporter reads from the
+// environment, so it is told apart from the exporter by nothing but the
+// empty request.
+func preflightEndpoint(ctx context.Context, ep *otlpEndpoint, policy *tlsPolicy) error {
+	host := ep.URL.Hostname()
+	addr := ep.address()
+
+	fail := func(stage string, err error, hint string) *PreflightError {
+		return &PreflightError{Stage: stage, Endpoint: ep.URL.String(), Err: err, Hint: hint}
+	}
+
+	tlsConfig, err := otlpTLSConfig("LOGS")
+	if err != nil {
+		return fail("tls", err, "check OTEL_EXPORTER_OTLP_CERTIFICATE and the client certificate settings")
+	}
+	tlsConfig.ServerName = host
+	if ep.Protocol == "grpc" {
+		// gRPC servers may refuse connections that do not negotiate HTTP/2
+		tlsConfig.NextProtos = []string{"h2"}
+	}
+	tlsConfig = policy.apply(tlsConfig)
+	headers, err := otlpHeaders()
+	if err != nil {
+		return fail("http", err, "check the format of OTEL_EXPORTER_OTLP_HEADERS")
+	}
+
+	if net.ParseIP(host) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			return fail("dns", err, fmt.Sprintf("check the host name %q in OTEL_EXPORTER_OTLP_ENDPOINT", host))
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fail("connect", err, "is the collector running and listening on "+addr+"?")
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if !ep.Insecure {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			hint := "check the collector certificate or use an http:// endpoint for a plaintext collector"
+			if isPlaintextTLSError(err) {
+				hint = "the collector does not speak TLS; use an http:// endpoint or set OTEL_EXPORTER_OTLP_INSECURE=true"
+			}
+			return fail("tls", err, hint)
+		}
+		conn = tlsConn
+	}
+
+	if ep.Protocol == "grpc" {
+		return probeGRPC(conn, ep, fail)
+	}
+	return probeHTTP(ctx, ep, tlsConfig, headers, fail)
+}
+
+// probeGRPC sends the HTTP/2 preface and checks the reply is not an HTTP/1 response
+func probeGRPC(conn net.Conn, ep *otlpEndpoint, fail func(string, error, string) *PreflightError) error {
+	if _, err := io.WriteString(conn, http2Preface); err != nil {
+		return fail("grpc", err, "")
+	}
+
+	buf := make([]byte, 5)
+	n, err := io.ReadAtLeast(conn, buf, len(buf))
+	if n >= 5 && string(buf[:5]) == "HTTP/" {
+		_, port, _ := net.SplitHostPort(ep.address())
+		perr := fail("grpc", errors.New("server answered with HTTP/1.x, not gRPC"),
+			fmt.Sprintf("port %s looks like an OTLP/HTTP receiver; set OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf or use the gRPC port (usually 4317)", port))
+		perr.Mismatch = true
+		return perr
+	}
+	if err != nil && n == 0 {
+		return fail("grpc", err, "the server closed the connection without an HTTP/2 reply")
+	}
+	return nil
+}
+
+// probeHTTP posts an empty export request, which a collector accepts with 200
+func probeHTTP(ctx context.Context, ep *otlpEndpoint, tlsConfig *tls.Config, headers map[string]string, fail func(string, error, string) *PreflightError) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL.String(), bytes.NewReader(nil))
+	if err != nil {
+		return fail("http", err, "")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "malformed HTTP response") || strings.Contains(err.Error(), "server gave HTTP response") {
+			_, port, _ := net.SplitHostPort(ep.address())
+			perr := fail("http", err,
+				fmt.Sprintf("port %s looks like a gRPC receiver; set OTEL_EXPORTER_OTLP_PROTOCOL=grpc or use the HTTP port (usually 4318)", port))
+			perr.Mismatch = true
+			return perr
+		}
+		return fail("http", err, "")
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		hint := "the logs path is usually /v1/logs"
+		if _, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"); ok {
+			hint = "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT is used as-is and must include the full path (usually /v1/logs)"
+		} else if strings.Count(ep.URL.Path, "/v1/logs") > 1 {
+			hint = "OTEL_EXPORTER_OTLP_ENDPOINT is a base URL; remove /v1/logs from it"
+		}
+		return fail("http", fmt.Errorf("server returned %s", resp.Status), hint)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fail("http", fmt.Errorf("server returned %s", resp.Status), "check the credentials in OTEL_EXPORTER_OTLP_HEADERS")
+	case resp.StatusCode >= 500:
+		return fail("http", fmt.Errorf("server returned %s", resp.Status), "")
+	}
+
+	return nil
+}
+
+func isPlaintextTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &recordErr)
+}
+
+// runPreflight probes the configured OTLP endpoint and reports problems on stderr.
+// Failures are not fatal: the exporter keeps retrying once the collector is reachable.
+func runPreflight(ctx context.Context, config *Config) {
+	// Protocol negotiation probes the endpoint itself and reports failures,
+	// and export files and BigQuery have no OTLP endpoint to probe
+	if config.SkipPreflight || config.ProtocolFallback || config.ExportFile != "" || config.BigQueryTable != "" {
+		return
+	}
+
+	eps, err := primaryEndpoints(config, resolveProtocol())
+	if err != nil {
+		logError("%v\n", err)
+		return
+	}
+
+	for _, ep := range eps {
+		start := time.Now()
+		if err := probeWithTimeout(ctx, config, ep); err != nil {
+			logError("%v\n", err)
+			continue
+		}
+		logInfo(config.Verbose, "OTLP endpoint %s (%s) reachable in %v\n", ep.URL, ep.Protocol, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+func probeWithTimeout(ctx context.Context, config *Config, ep *otlpEndpoint) error {
+	timeout := config.PreflightTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return preflightEndpoint(ctx, ep, outboundTLS(config))
+}
+
+// alternateEndpoint returns the same host using the other OTLP protocol on its
+// conventional port (gRPC on 4317, HTTP on 4318)
+func alternateEndpoint(ep *otlpEndpoint) *otlpEndpoint {
+	u := *ep.URL
+	alt := &otlpEndpoint{URL: &u, Insecure: ep.Insecure}
+
+	if ep.Protocol == "grpc" {
+		alt.Protocol = "http/protobuf"
+		u.Host = net.JoinHostPort(u.Hostname(), "4318")
+		u.Path = "/v1/logs"
+	} else {
+		alt.Protocol = "grpc"
+		u.Host = net.JoinHostPort(u.Hostname(), "4317")
+		u.Path = ""
+	}
+	u.RawPath = ""
+
+	return alt
+}
+
+// negotiateEndpoint probes the configured endpoint and, if nothing speaking that
+// protocol answers, tries the other OTLP protocol on its conventional port. It
+// returns the endpoint to use instead of the environment configuration, or nil
+// to keep the configured one.
+func negotiateEndpoint(ctx context.Context, config *Config, protocol string) *otlpEndpoint {
+	if protocol != "grpc" && protocol != "http/protobuf" && protocol != "http" {
+		return nil
+	}
+
+	ep, err := resolveEndpoint(protocol)
+	if err != nil {
+		logError("%v\n", err)
+		return nil
+	}
+
+	err = probeWithTimeout(ctx, config, ep)
+	if err == nil {
+		return nil
+	}
+
+	var perr *PreflightError
+	if !errors.As(err, &perr) || !perr.connectionLevel() {
+		logError("%v\n", err)
+		return nil
+	}
+
+	alt := alternateEndpoint(ep)
+	if altErr := probeWithTimeout(ctx, config, alt); altErr != nil {
+		logError("%v\n", err)
+		logInfo(config.Verbose, "Protocol fallback to %s at %s also failed: %v\n", alt.Protocol, alt.URL, altErr)
+		return nil
+	}
+
+	logError("Warning: %s endpoint %s is not usable (%v); falling back to %s at %s\n",
+		ep.Protocol, ep.URL, perr.Err, alt.Protocol, alt.URL)
+	return alt
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		protocol string
+		expected string
+		insecure bool
+	}{
+		{
+			name:     "http default",
+			protocol: "http/protobuf",
+			expected: "http://localhost:4318/v1/logs",
+			insecure: true,
+		},
+		{
+			name:     "grpc default",
+			protocol: "grpc",
+			expected: "http://localhost:4317",
+			insecure: true,
+		},
+		{
+			name:     "generic endpoint gets signal path",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector.example.com:4318"},
+			protocol: "http/protobuf",
+			expected: "https://collector.example.com:4318/v1/logs",
+		},
+		{
+			name:     "https URL without a port keeps the scheme's default",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "https://otlp.vendor.example"},
+			protocol: "http/protobuf",
+			expected: "https://otlp.vendor.example/v1/logs",
+		},
+		{
+			name:     "grpc https URL without a port keeps the scheme's default",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "https://otlp.vendor.example"},
+			protocol: "grpc",
+			expected: "https://otlp.vendor.example",
+		},
+		{
+			name:     "bare host gets the OTLP port",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "collector"},
+			protocol: "grpc",
+			expected: "https://collector:4317",
+		},
+		{
+			name: "signal endpoint used as-is",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":      "http://ignored:4318",
+				"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT": "http://collector:9999/custom",
+			},
+			protocol: "http/protobuf",
+			expected: "http://collector:9999/custom",
+			insecure: true,
+		},
+		{
+			name: "grpc host:port with insecure",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "collector:4317",
+				"OTEL_EXPORTER_OTLP_INSECURE": "true",
+			},
+			protocol: "grpc",
+			expected: "http://collector:4317",
+			insecure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			ep, err := resolveEndpoint(tt.protocol)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ep.URL.String() != tt.expected {
+				t.Errorf("Expected endpoint %s, got %s", tt.expected, ep.URL.String())
+			}
+			if ep.Insecure != tt.insecure {
+				t.Errorf("Expected insecure=%v, got %v", tt.insecure, ep.Insecure)
+			}
+		})
+	}
+}
+
+func TestPreflightEndpoint(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+
+	// Grab a free port and release it so connections are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name      string
+		protocol  string
+		endpoint  string
+		wantStage string
+	}{
+		{
+			name:     "reachable http endpoint",
+			protocol: "http/protobuf",
+			endpoint: okServer.URL,
+		},
+		{
+			name:      "wrong path",
+			protocol:  "http/protobuf",
+			endpoint:  okServer.URL + "/v1/logs",
+			wantStage: "http",
+		},
+		{
+			name:      "connection refused",
+			protocol:  "http/protobuf",
+			endpoint:  "http://" + closedAddr,
+			wantStage: "connect",
+		},
+		{
+			name:      "unresolvable host",
+			protocol:  "http/protobuf",
+			endpoint:  "http://otel-logger-preflight.invalid:4318",
+			wantStage: "dns",
+		},
+		{
+			name:      "grpc against http receiver",
+			protocol:  "grpc",
+			endpoint:  okServer.URL,
+			wantStage: "grpc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tt.endpoint)
+
+			ep, err := resolveEndpoint(tt.protocol)
+			if err != nil {
+				t.Fatalf("Failed to resolve endpoint: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = preflightEndpoint(ctx, ep, nil)
+			if tt.wantStage == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var preflightErr *PreflightError
+			if !errors.As(err, &preflightErr) {
+				t.Fatalf("Expected PreflightError, got %v", err)
+			}
+			if preflightErr.Stage != tt.wantStage {
+				t.Errorf("Expected stage %s, got %s (%v)", tt.wantStage, preflightErr.Stage, err)
+			}
+			if preflightErr.Hint == "" {
+				t.Errorf("Expected an actionable hint for %v", err)
+			}
+		})
+	}
+}
+
+func TestPreflightEndpointUsesExporterSettings(t *testing.T) {
+	cert, key := issueCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, nil, nil)
+	serverCert := tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", caFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20secret")
+
+	probe := func(protocol, endpoint string) error {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+		ep, err := resolveEndpoint(protocol)
+		if err != nil {
+			t.Fatalf("Failed to resolve endpoint: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return preflightEndpoint(ctx, ep, nil)
+	}
+
+	// A collector requiring the headers, with a certificate from the CA
+	httpServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	httpServer.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	httpServer.StartTLS()
+	defer httpServer.Close()
+	if err := probe("http/protobuf", httpServer.URL); err != nil {
+		t.Errorf("Expected the probe to send the headers and trust the CA, got %v", err)
+	}
+
+	// A gRPC server, which may require HTTP/2 to be negotiated
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	negotiated := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tlsConn := conn.(*tls.Conn)
+		tlsConn.Handshake()
+		negotiated <- tlsConn.ConnectionState().NegotiatedProtocol
+		// An empty SETTINGS frame
+		conn.Write([]byte{0, 0, 0, 4, 0, 0, 0, 0, 0})
+	}()
+	if err := probe("grpc", "https://"+listener.Addr().String()); err != nil {
+		t.Errorf("Unexpected gRPC probe error: %v", err)
+	}
+	if protocol := <-negotiated; protocol != "h2" {
+		t.Errorf("Expected the gRPC probe to negotiate h2, got %q", protocol)
+	}
+}
+
+func TestAlternateEndpoint(t *testing.T) {
+	tests := []struct {
+		name             string
+		protocol         string
+		endpoint         string
+		expectedProtocol string
+		expected         string
+	}{
+		{
+			name:             "grpc falls back to http",
+			protocol:         "grpc",
+			endpoint:         "http://collector:4317",
+			expectedProtocol: "http/protobuf",
+			expected:         "http://collector:4318/v1/logs",
+		},
+		{
+			name:             "http falls back to grpc",
+			protocol:         "http/protobuf",
+			endpoint:         "https://collector:4318",
+			expectedProtocol: "grpc",
+			expected:         "https://collector:4317",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tt.endpoint)
+
+			ep, err := resolveEndpoint(tt.protocol)
+			if err != nil {
+				t.Fatalf("Failed to resolve endpoint: %v", err)
+			}
+
+			alt := alternateEndpoint(ep)
+			if alt.Protocol != tt.expectedProtocol {
+				t.Errorf("Expected protocol %s, got %s", tt.expectedProtocol, alt.Protocol)
+			}
+			if alt.URL.String() != tt.expected {
+				t.Errorf("Expected endpoint %s, got %s", tt.expected, alt.URL.String())
+			}
+			if ep.URL.String() == alt.URL.String() {
+				t.Error("Original endpoint should not be modified")
+			}
+		})
+	}
+}
+
+func TestNegotiateEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+	config := &Config{PreflightTimeout: 2 * time.Second}
+
+	if ep := negotiateEndpoint(context.Background(), config, "http/protobuf"); ep != nil {
+		t.Errorf("Expected configured endpoint to be kept, got fallback to %s", ep.URL)
+	}
+
+	// A gRPC client talking to an HTTP receiver is a protocol mismatch
+	ep, err := resolveEndpoint("grpc")
+	if err != nil {
+		t.Fatalf("Failed to resolve endpoint: %v", err)
+	}
+	var preflightErr *PreflightError
+	if err := probeWithTimeout(context.Background(), config, ep); !errors.As(err, &preflightErr) || !preflightErr.connectionLevel() {
+		t.Errorf("Expected connection-level mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alexflint/go-arg"
+)
+
+// presetFiles are ready-made --config files for common logging frameworks.
+// Each starts with a "# description" line shown by `presets list`.
+//
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+const presetDir = "presets"
+
+// PresetsArgs are the arguments of `otel-logger presets`
+type PresetsArgs struct {
+	List *struct{}       `arg:"subcommand:list" help:"List the built-in presets"`
+	Show *PresetShowArgs `arg:"subcommand:show" help:"Print a preset, ready to copy into a --config file"`
+}
+
+type PresetShowArgs struct {
+	Name string `arg:"positional,required" help:"Preset name as shown by presets list"`
+}
+
+func (PresetsArgs) Description() string {
+	return `Inspect the built-in presets: config files with the field mappings of common
+logging frameworks. Use one as-is with --config, or copy it as a starting point.`
+}
+
+type preset struct {
+	Name        string
+	Description string
+}
+
+// listPresets returns the embedded presets sorted by name
+func listPresets() ([]preset, error) {
+	entries, err := fs.ReadDir(presetFiles, presetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var presets []preset
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		data, err := readPreset(name)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset{Name: name, Description: presetDescription(data)})
+	}
+	return presets, nil
+}
+
+func readPreset(name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("unknown preset %q (see `otel-logger presets list`)", name)
+	}
+	data, err := presetFiles.ReadFile(path.Join(presetDir, name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q (see `otel-logger presets list`)", name)
+	}
+	return data, nil
+}
+
+// presetDescription returns the text of the leading comment line
+func presetDescription(data []byte) string {
+	line, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	return strings.TrimSpace(strings.TrimPrefix(string(line), "#"))
+}
+
+func writePresetList(w io.Writer) error {
+	presets, err := listPresets()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, p := range presets {
+		fmt.Fprintf(tw, "%s\t%s\n", p.Name, p.Description)
+	}
+	return tw.Flush()
+}
+
+// runPresets implements `otel-logger presets`
+func runPresets(args []string) error {
+	var presetsArgs PresetsArgs
+	parser, err := arg.NewParser(arg.Config{Program: "otel-logger presets"}, &presetsArgs)
+	if err != nil {
+		return err
+	}
+	parser.MustParse(args)
+
+	switch {
+	case presetsArgs.Show != nil:
+		data, err := readPreset(presetsArgs.Show.Name)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	case presetsArgs.List != nil:
+		return writePresetList(os.Stdout)
+	default:
+		parser.WriteHelp(os.Stdout)
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPresetsAreValidConfigFiles(t *testing.T) {
+	presets, err := listPresets()
+	if err != nil {
+		t.Fatalf("Failed to list presets: %v", err)
+	}
+	if len(presets) == 0 {
+		t.Fatal("Expected embedded presets")
+	}
+
+	for _, p := range presets {
+		t.Run(p.Name, func(t *testing.T) {
+			if p.Description == "" {
+				t.Error("Expected a leading description comment")
+			}
+
+			data, err := readPreset(p.Name)
+			if err != nil {
+				t.Fatalf("Failed to read preset: %v", err)
+			}
+			fc, err := parseFileConfig(data)
+			if err != nil {
+				t.Fatalf("Preset is not a valid config file: %v", err)
+			}
+			// Presets for other formats do not need JSON field mappings
+			if len(fc.MessageFields) == 0 && fc.Format == "" {
+				t.Error("Expected preset to set message_fields")
+			}
+		})
+	}
+}
+
+func TestPresetFieldMappings(t *testing.T) {
+	data, err := readPreset("ecs")
+	if err != nil {
+		t.Fatalf("Failed to read preset: %v", err)
+	}
+	fc, err := parseFileConfig(data)
+	if err != nil {
+		t.Fatalf("Failed to parse preset: %v", err)
+	}
+
+	extractor := NewJSONExtractor(fc.JSONPrefix, fc.fieldMappings())
+	entry, err := extractor.ParseLogEntry(`{"@timestamp":"2024-01-15T10:30:45Z","log.level":"warn","message":"disk almost full"}`)
+	if err != nil {
+		t.Fatalf("Failed to parse entry: %v", err)
+	}
+	if entry.Level != "warn" || entry.Message != "disk almost full" {
+		t.Errorf("Expected warn/disk almost full, got %s/%s", entry.Level, entry.Message)
+	}
+}
+
+func TestReadPresetUnknown(t *testing.T) {
+	for _, name := range []string{"", "nope", "../main"} {
+		if _, err := readPreset(name); err == nil {
+			t.Errorf("Expected error for preset %q", name)
+		}
+	}
+}
+
+func TestWritePresetList(t *testing.T) {
+	var out bytes.Buffer
+	if err := writePresetList(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "logrus") || !strings.Contains(out.String(), "JSONFormatter") {
+		t.Errorf("Expected list to include names and descriptions, got %q", out.String())
+	}
+}
+
+func TestFormatPresets(t *testing.T) {
+	tests := []struct {
+		format    string
+		line      string
+		level     string
+		timestamp time.Time
+		logger    string
+	}{
+		{
+			format:    "bunyan",
+			line:      `{"name":"api","hostname":"web-1","pid":42,"level":50,"msg":"request failed","time":"2024-01-15T10:30:45.123Z","v":0}`,
+			level:     "error",
+			timestamp: time.Date(2024, 1, 15, 10, 30, 45, 123e6, time.UTC),
+		},
+		{
+			format:    "pino",
+			line:      `{"level":30,"time":1705314645123,"pid":42,"hostname":"web-1","msg":"listening"}`,
+			level:     "info",
+			timestamp: time.Date(2024, 1, 15, 10, 30, 45, 123e6, time.UTC),
+		},
+		{
+			format:    "zap",
+			line:      `{"level":"warn","ts":1705314645.123456,"logger":"http","caller":"server/main.go:42","msg":"slow request"}`,
+			level:     "warn",
+			timestamp: time.Date(2024, 1, 15, 10, 30, 45, 123456e3, time.UTC),
+			logger:    "http",
+		},
+		{
+			format:    "jvm-gc",
+			line:      `[2024-01-15T10:30:45.123+0000][info][gc] GC(3) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms`,
+			level:     "info",
+			timestamp: time.Date(2024, 1, 15, 10, 30, 45, 123e6, time.UTC),
+			logger:    "gc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			fc, err := parseFileConfig([]byte("format: " + tt.format + "\n"))
+			if err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+			entry, err := NewJSONExtractor("", fc.fieldMappings()).ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Expected level %q, got %q", tt.level, entry.Level)
+			}
+			if !entry.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.timestamp, entry.Timestamp.UTC())
+			}
+			if entry.LoggerName != tt.logger {
+				t.Errorf("Expected logger %q, got %q", tt.logger, entry.LoggerName)
+			}
+			if _, ok := entry.Fields["level"]; ok {
+				t.Error("Expected the level field to be consumed")
+			}
+		})
+	}
+}
+
+func TestFormatPresetOverrides(t *testing.T) {
+	fc, err := parseFileConfig([]byte("format: pino\nmessage_fields: [message]\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	mappings := fc.fieldMappings()
+	if mappings.Format != "" {
+		t.Errorf("Expected the preset to decode JSON, got format %q", mappings.Format)
+	}
+	if len(mappings.MessageFields) != 1 || mappings.MessageFields[0] != "message" {
+		t.Errorf("Expected message_fields from the config file, got %v", mappings.MessageFields)
+	}
+	if mappings.TimestampUnit != timestampMilliseconds || mappings.LevelNumbers["60"] != "fatal" {
+		t.Errorf("Expected the rest of the preset to apply, got %+v", mappings)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// loadProtoMessage finds the message type of --framing protobuf records in a
+// descriptor set, as written by protoc --include_imports --descriptor_set_out
+func loadProtoMessage(descriptorPath, messageName string) (protoreflect.MessageDescriptor, error) {
+	if descriptorPath == "" || messageName == "" {
+		return nil, fmt.Errorf("--framing %s requires --proto-descriptor and --proto-message", framingProtobuf)
+	}
+	data, err := os.ReadFile(descriptorPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --proto-descriptor: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid --proto-descriptor %s: %w", descriptorPath, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --proto-descriptor %s: %w", descriptorPath, err)
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("--proto-message %q not found in %s", messageName, descriptorPath)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("--proto-message %q is not a message", messageName)
+	}
+	return message, nil
+}
+
+// decodeProtobuf returns a decoder of records of the message type, each
+// prefixed with its length as a varint (as writeDelimitedTo and protodelim
+// write them). Fields keep their .proto names; well-known types such as
+// google.protobuf.Timestamp become their JSON forms.
+func decodeProtobuf(message protoreflect.MessageDescriptor) func(*recordReader) (any, error) {
+	marshal := protojson.MarshalOptions{UseProtoNames: true}
+	return func(r *recordReader) (any, error) {
+		size, n := protowire.ConsumeVarint(r.data[r.pos:])
+		if n < 0 {
+			if len(r.data)-r.pos < binary.MaxVarintLen64 {
+				return nil, errShortRecord
+			}
+			return nil, protowire.ParseError(n)
+		}
+		r.pos += n
+		data, err := r.next(size)
+		if err != nil {
+			return nil, err
+		}
+		record := dynamicpb.NewMessage(message)
+		if err := proto.Unmarshal(data, record); err != nil {
+			return nil, err
+		}
+		encoded, err := marshal.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		// protojson varies its whitespace from build to build
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, encoded); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(compacted.Bytes()), nil
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// batchIDKey tags every record of an exported batch with the ID its receipt
+// is filed under
+const batchIDKey = "otel_logger.batch.id"
+
+// exportReceipt is the line --export-receipts writes for each batch, for
+// reconciling what was sent with what the collector received
+type exportReceipt struct {
+	BatchID    string    `json:"batch_id"`
+	Records    int       `json:"records"`
+	First      time.Time `json:"first_timestamp"`
+	Last       time.Time `json:"last_timestamp"`
+	ExportedAt time.Time `json:"exported_at"`
+	Status     string    `json:"status"` // ok or error
+	Error      string    `json:"error,omitempty"`
+}
+
+// receiptExporter tags batches with a batch ID and writes a receipt for each
+// one once the export has succeeded or failed
+type receiptExporter struct {
+	next sdklog.Exporter
+	now  func() time.Time
+
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // the receipts file, closed on shutdown
+}
+
+func newReceiptExporter(next sdklog.Exporter, w io.Writer) *receiptExporter {
+	return &receiptExporter{next: next, w: w, now: time.Now}
+}
+
+func (e *receiptExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return e.next.Export(ctx, records)
+	}
+
+	receipt := exportReceipt{BatchID: uuid.NewString(), Records: len(records)}
+	tagged := make([]sdklog.Record, len(records))
+	for i, record := range records {
+		tagged[i] = record.Clone()
+		tagged[i].AddAttributes(log.String(batchIDKey, receipt.BatchID))
+
+		timestamp := record.Timestamp()
+		if timestamp.IsZero() {
+			timestamp = record.ObservedTimestamp()
+		}
+		if receipt.First.IsZero() || timestamp.Before(receipt.First) {
+			receipt.First = timestamp
+		}
+		if timestamp.After(receipt.Last) {
+			receipt.Last = timestamp
+		}
+	}
+
+	err := e.next.Export(ctx, tagged)
+	receipt.ExportedAt, receipt.Status = e.now(), "ok"
+	if err != nil {
+		receipt.Status, receipt.Error = "error", err.Error()
+	}
+	e.write(receipt)
+	return err
+}
+
+func (e *receiptExporter) write(receipt exportReceipt) {
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		logError("Failed to write export receipt %s: %v\n", receipt.BatchID, err)
+	}
+}
+
+func (e *receiptExporter) ForceFlush(ctx context.Context) error {
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *receiptExporter) Shutdown(ctx context.Context) error {
+	err := e.next.Shutdown(ctx)
+	if e.closer != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		err = errors.Join(err, e.closer.Close())
+	}
+	return err
+}
+
+// withExportReceipts wraps the exporter to file a receipt for each batch in
+// --export-receipts, if set; - writes them to stderr
+func withExportReceipts(config *Config, exporter sdklog.Exporter) (sdklog.Exporter, error) {
+	switch config.ExportReceipts {
+	case "":
+		return exporter, nil
+	case "-":
+		return newReceiptExporter(exporter, os.Stderr), nil
+	}
+	file, err := os.OpenFile(config.ExportReceipts, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --export-receipts: %w", err)
+	}
+	receipts := newReceiptExporter(exporter, file)
+	receipts.closer = file
+	return receipts, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestReceiptExporter(t *testing.T) {
+	next := &flakyExporter{}
+	var out bytes.Buffer
+	exporter := newReceiptExporter(next, &out)
+	exportedAt := time.Date(2025, 1, 2, 15, 5, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return exportedAt }
+
+	first := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	provider, source := newRecordingProvider()
+	for _, offset := range []time.Duration{time.Second, 0, 2 * time.Second} {
+		var record log.Record
+		record.SetTimestamp(first.Add(offset))
+		provider.Logger("test").Emit(context.Background(), record)
+	}
+	records := source.Records()
+
+	if err := exporter.Export(context.Background(), records); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next.down = true
+	if err := exporter.Export(context.Background(), records[:1]); err == nil {
+		t.Fatal("Expected the export error to be returned")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 receipts, got %q", out.String())
+	}
+	var ok, failed exportReceipt
+	if err := json.Unmarshal([]byte(lines[0]), &ok); err != nil {
+		t.Fatalf("Invalid receipt: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatalf("Invalid receipt: %v", err)
+	}
+
+	if ok.Records != 3 || ok.Status != "ok" || !ok.First.Equal(first) || !ok.Last.Equal(first.Add(2*time.Second)) || !ok.ExportedAt.Equal(exportedAt) {
+		t.Errorf("Unexpected receipt: %+v", ok)
+	}
+	if failed.Status != "error" || failed.Error != "connection refused" || failed.BatchID == ok.BatchID {
+		t.Errorf("Unexpected receipt for the failed batch: %+v", failed)
+	}
+
+	exported := next.Records()
+	if len(exported) != 3 {
+		t.Fatalf("Expected 3 exported records, got %d", len(exported))
+	}
+	for _, record := range exported {
+		if got := recordAttributes(record)[batchIDKey]; got != ok.BatchID {
+			t.Errorf("Expected batch ID %s, got %q", ok.BatchID, got)
+		}
+	}
+	if records[0].AttributesLen() != 0 {
+		t.Error("Expected the batch's own records to be left unchanged")
+	}
+}
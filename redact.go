@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redactionMask replaces every redacted value
+const redactionMask = "[REDACTED]"
+
+// redactionRule masks the matches of a pattern, or only its capture groups
+// when it has any, as in (?i)bearer\s+(\S+) which keeps the word Bearer.
+// valid, if set, rejects matches that only look like secrets.
+type redactionRule struct {
+	name    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// builtinRedactions are the rules --redact enables by name
+var builtinRedactions = map[string]redactionRule{
+	"email": {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	"credit-card": {
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid:   luhnValid,
+	},
+	"bearer-token": {
+		pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	},
+	"jwt": {
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	},
+	"aws-access-key": {
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+}
+
+// redactor masks secrets and personal data in the message, the original
+// line and every string attribute value before a record leaves the process
+type redactor struct {
+	rules []redactionRule
+	audit *ruleAudit
+}
+
+// newRedactor builds the --redact rules, given by built-in name, and the
+// --redact-pattern regular expressions
+func newRedactor(names, patterns []string) (*redactor, error) {
+	r := &redactor{}
+	for _, name := range names {
+		rule, ok := builtinRedactions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown --redact %q (supported: %s)", name, strings.Join(slices.Sorted(maps.Keys(builtinRedactions)), ", "))
+		}
+		rule.name = name
+		r.rules = append(r.rules, rule)
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --redact-pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, redactionRule{name: "pattern:" + pattern, pattern: re})
+	}
+	return r, nil
+}
+
+// setAudit counts the hits of each rule in audit
+func (r *redactor) setAudit(audit *ruleAudit) {
+	r.audit = audit
+	for _, rule := range r.rules {
+		audit.register("redact:" + rule.name)
+	}
+}
+
+// apply returns the entry with every match masked. Entries without matches
+// are returned as they are; otherwise the fields are copied, so the entry
+// the caller holds is left alone.
+func (r *redactor) apply(entry *LogEntry) *LogEntry {
+	redacted := *entry
+	changed := false
+	if message, ok := r.redactString(entry.Message); ok {
+		redacted.Message, changed = message, true
+	}
+	if raw, ok := r.redactString(entry.Raw); ok {
+		redacted.Raw, changed = raw, true
+	}
+	if fields, ok := r.redactValue(entry.Fields); ok {
+		redacted.Fields, changed = fields.(map[string]any), true
+	}
+	if entry.Object != nil {
+		if object, ok := r.redactValue(entry.Object); ok {
+			redacted.Object, changed = object.(map[string]any), true
+		}
+	}
+	if !changed {
+		return entry
+	}
+	return &redacted
+}
+
+// redactValue masks the strings in a field value, descending into objects
+// and arrays; the value is copied only where something was masked
+func (r *redactor) redactValue(value any) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case map[string]any:
+		var copied map[string]any
+		for key, item := range v {
+			if redacted, ok := r.redactValue(item); ok {
+				if copied == nil {
+					copied = maps.Clone(v)
+				}
+				copied[key] = redacted
+			}
+		}
+		return copied, copied != nil
+	case []any:
+		var copied []any
+		for i, item := range v {
+			if redacted, ok := r.redactValue(item); ok {
+				if copied == nil {
+					copied = slices.Clone(v)
+				}
+				copied[i] = redacted
+			}
+		}
+		return copied, copied != nil
+	}
+	return value, false
+}
+
+// redactString applies every rule to s, reporting whether anything matched
+func (r *redactor) redactString(s string) (string, bool) {
+	changed := false
+	for _, rule := range r.rules {
+		masked, hits := rule.mask(s)
+		if hits > 0 {
+			s, changed = masked, true
+			r.audit.hit("redact:"+rule.name, hits)
+		}
+	}
+	return s, changed
+}
+
+// mask replaces the rule's matches in s, returning how many it replaced
+func (rule redactionRule) mask(s string) (string, int) {
+	matches := rule.pattern.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s, 0
+	}
+	var b strings.Builder
+	last, hits := 0, 0
+	for _, m := range matches {
+		if rule.valid != nil && !rule.valid(s[m[0]:m[1]]) {
+			continue
+		}
+		// Without groups the whole match is masked
+		spans := [][2]int{{m[0], m[1]}}
+		if len(m) > 2 {
+			spans = spans[:0]
+			for g := 2; g < len(m); g += 2 {
+				if m[g] >= 0 {
+					spans = append(spans, [2]int{m[g], m[g+1]})
+				}
+			}
+		}
+		for _, span := range spans {
+			if span[0] < last {
+				continue
+			}
+			b.WriteString(s[last:span[0]])
+			b.WriteString(redactionMask)
+			last = span[1]
+		}
+		hits++
+	}
+	if hits == 0 {
+		return s, 0
+	}
+	b.WriteString(s[last:])
+	return b.String(), hits
+}
+
+// luhnValid reports whether the digits of a card-number-like match pass the
+// Luhn checksum, which order numbers and timestamps rarely do
+func luhnValid(match string) bool {
+	sum, double := 0, false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// reexecStateEnv names the file a re-executed otel-logger resumes from
+const reexecStateEnv = "OTEL_LOGGER_INTERNAL_REEXEC_STATE"
+
+// reexecMaxPending is the longest partial line held back for the next
+// process; longer ones are passed on as they are
+const reexecMaxPending = 1 << 20
+
+// reexecState is what a re-executed otel-logger needs to carry on with the
+// wrapped command: its PID, which is still a child after exec, the read ends
+// of its output pipes, which are inherited, and the partial lines read from
+// them
+type reexecState struct {
+	PID     int                     `json:"pid"`
+	Streams map[string]reexecStream `json:"streams"`
+}
+
+type reexecStream struct {
+	FD      int    `json:"fd"`
+	Pending []byte `json:"pending,omitempty"`
+}
+
+// validateReexec rejects combinations --reexec cannot carry over
+func validateReexec(config *Config) error {
+	switch {
+	case !config.Reexec:
+		return nil
+	case reexecSignal == nil:
+		return errors.New("--reexec is only supported on Unix")
+	case len(config.Command) == 0 || len(config.Files) > 0 || config.ReplaySession != "":
+		return errors.New("--reexec requires a wrapped command")
+	case config.Sandbox:
+		return errors.New("--reexec cannot be combined with --sandbox, which forbids exec")
+	case config.RecordSession != "":
+		return errors.New("--reexec cannot be combined with --record-session")
+	case binaryFraming(config.Framing):
+		return fmt.Errorf("--reexec cannot be combined with --framing %s", config.Framing)
+	}
+	return nil
+}
+
+// reexecReader reads a command's output pipe up to the last line break, so
+// reading can stop between lines and the partial line be handed to the
+// next process along with the pipe
+type reexecReader struct {
+	file     *os.File
+	buf      []byte
+	pending  []byte
+	stopping atomic.Bool
+}
+
+func newReexecReader(file *os.File, pending []byte) *reexecReader {
+	return &reexecReader{file: file, buf: make([]byte, 32*1024), pending: pending}
+}
+
+func (r *reexecReader) Read(p []byte) (int, error) {
+	for {
+		if r.stopping.Load() {
+			return 0, io.EOF
+		}
+		if i := bytes.LastIndexByte(r.pending, '\n'); i >= 0 || len(r.pending) >= reexecMaxPending {
+			if i < 0 {
+				i = len(r.pending) - 1
+			}
+			n := copy(p, r.pending[:i+1])
+			r.pending = r.pending[n:]
+			return n, nil
+		}
+
+		n, err := r.file.Read(r.buf)
+		r.pending = append(r.pending, r.buf[:n]...)
+		if err != nil {
+			if r.stopping.Load() && errors.Is(err, os.ErrDeadlineExceeded) {
+				return 0, io.EOF
+			}
+			if len(r.pending) > 0 {
+				// The final line of the stream has no line break
+				n := copy(p, r.pending)
+				r.pending = r.pending[n:]
+				return n, nil
+			}
+			return 0, err
+		}
+	}
+}
+
+// stop makes a pending and any further Read end the stream
+func (r *reexecReader) stop() error {
+	r.stopping.Store(true)
+	return r.file.SetReadDeadline(time.Now())
+}
+
+// resume undoes stop when the re-exec fails
+func (r *reexecReader) resume() {
+	r.stopping.Store(false)
+	r.file.SetReadDeadline(time.Time{})
+}
+
+// stopped reports whether the stream ended for a re-exec rather than
+// because the command closed it
+func (r *reexecReader) stopped() bool {
+	return r != nil && r.stopping.Load()
+}
+
+// reexec replaces this process with the otel-logger binary now at its
+// path, with the same arguments; it only returns on failure
+func reexec(state *reexecState) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate otel-logger executable: %w", err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "otel-logger-reexec-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	env := []string{reexecStateEnv + "=" + file.Name()}
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, reexecStateEnv+"=") {
+			env = append(env, variable)
+		}
+	}
+	err = syscall.Exec(executable, os.Args, env)
+	os.Remove(file.Name())
+	return fmt.Errorf("failed to execute %s: %w", executable, err)
+}
+
+// takeReexecState returns the state left by the process this one replaced,
+// or nil when it was started normally
+func takeReexecState() (*reexecState, error) {
+	path := os.Getenv(reexecStateEnv)
+	if path == "" {
+		return nil, nil
+	}
+	os.Unsetenv(reexecStateEnv)
+	data, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read re-exec state: %w", err)
+	}
+	var state reexecState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to read re-exec state: %w", err)
+	}
+	return &state, nil
+}
+
+// adoptCommand takes over the command and output pipes a previous
+// otel-logger handed over. The command is still a child of this process, so
+// it can be waited for as usual.
+func adoptCommand(state *reexecState) (*commandChild, error) {
+	process, err := os.FindProcess(state.PID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt command (pid %d): %w", state.PID, err)
+	}
+	child := &commandChild{process: process, pending: make(map[string][]byte)}
+	child.wait = func() error {
+		processState, err := process.Wait()
+		if err != nil {
+			// Reaped by the previous process as it re-executed
+			logError("Exit status of command (pid %d) lost in re-exec: %v\n", state.PID, err)
+			return err
+		}
+		if !processState.Success() {
+			return &exec.ExitError{ProcessState: processState}
+		}
+		return nil
+	}
+
+	for name, file := range map[string]**os.File{"stdout": &child.stdout, "stderr": &child.stderr} {
+		stream, ok := state.Streams[name]
+		if !ok {
+			return nil, fmt.Errorf("re-exec state has no %s pipe", name)
+		}
+		pipe, err := adoptPipe(stream.FD, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adopt %s pipe: %w", name, err)
+		}
+		*file = pipe
+		child.pending[name] = stream.Pending
+	}
+	return child, nil
+}
+
+// handOverCommand stops reading the command's output between lines, flushes
+// what was read and re-executes otel-logger with the command and its pipes.
+// It only returns if that failed, once the streams are read again.
+func handOverCommand(ctx context.Context, child *commandChild, readers map[string]*reexecReader, wg *sync.WaitGroup, exited <-chan struct{}, processor *LogProcessor, restart func()) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate otel-logger executable: %w", err)
+	}
+	if err := checkExecutable(executable); err != nil {
+		return fmt.Errorf("cannot execute %s: %w", executable, err)
+	}
+	if commandExited(exited) {
+		return errors.New("command already exited")
+	}
+
+	for _, r := range readers {
+		r.stop()
+	}
+	wg.Wait()
+
+	state := &reexecState{PID: child.process.Pid, Streams: make(map[string]reexecStream)}
+	err = func() error {
+		if err := processor.Flush(ctx); err != nil {
+			return fmt.Errorf("failed to flush logs: %w", err)
+		}
+		for name, r := range readers {
+			stream, err := r.handover()
+			if err != nil {
+				return fmt.Errorf("failed to hand over %s pipe: %w", name, err)
+			}
+			state.Streams[name] = stream
+		}
+		// Its exit status would be lost to this process
+		if commandExited(exited) {
+			return errors.New("command exited during the handover")
+		}
+		return reexec(state)
+	}()
+
+	for _, stream := range state.Streams {
+		closeOnExec(stream.FD)
+	}
+	for _, r := range readers {
+		r.resume()
+	}
+	restart()
+	return err
+}
+
+func commandExited(exited <-chan struct{}) bool {
+	select {
+	case <-exited:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// reexecSignal is nil where --reexec is not supported, as exec and
+// inheriting pipes across it are Unix only
+var reexecSignal os.Signal
+
+var errReexecUnsupported = errors.New("--reexec is only supported on Unix")
+
+func (r *reexecReader) handover() (reexecStream, error) {
+	return reexecStream{}, errReexecUnsupported
+}
+
+func adoptPipe(fd int, name string) (*os.File, error) {
+	return nil, errReexecUnsupported
+}
+
+func checkExecutable(path string) error {
+	return errReexecUnsupported
+}
+
+func closeOnExec(fd int) {}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReexecReader(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	reader := newReexecReader(r, []byte("held "))
+	w.WriteString("over\nnext\npartial")
+	buf := make([]byte, 64)
+	n, err := reader.Read(buf)
+	if err != nil || string(buf[:n]) != "held over\nnext\n" {
+		t.Fatalf("Expected the complete lines, got %q (%v)", buf[:n], err)
+	}
+
+	// Stopping ends a blocked read and keeps the partial line
+	read := make(chan error, 1)
+	go func() {
+		_, err := reader.Read(buf)
+		read <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	reader.stop()
+	select {
+	case err := <-read:
+		if err != io.EOF {
+			t.Fatalf("Expected EOF once stopped, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected stop to end the pending read")
+	}
+	if !reader.stopped() {
+		t.Error("Expected the reader to report it was stopped")
+	}
+	stream, err := reader.handover()
+	if err != nil || string(stream.Pending) != "partial" {
+		t.Fatalf("Expected the partial line to be handed over, got %q (%v)", stream.Pending, err)
+	}
+
+	reader.resume()
+	w.WriteString(" line\n")
+	w.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != "partial line\n" {
+		t.Errorf("Expected reading to resume with the partial line, got %q (%v)", data, err)
+	}
+	if (*reexecReader)(nil).stopped() {
+		t.Error("Expected a nil reader not to be stopped")
+	}
+}
+
+func TestTakeReexecState(t *testing.T) {
+	t.Setenv(reexecStateEnv, "")
+	if state, err := takeReexecState(); state != nil || err != nil {
+		t.Fatalf("Expected no state on a normal start, got %v (%v)", state, err)
+	}
+
+	want := &reexecState{PID: 42, Streams: map[string]reexecStream{"stdout": {FD: 5, Pending: []byte("partial")}, "stderr": {FD: 7}}}
+	path := filepath.Join(t.TempDir(), "state.json")
+	data, _ := json.Marshal(want)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(reexecStateEnv, path)
+	got, err := takeReexecState()
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %+v, got %+v (%v)", want, got, err)
+	}
+	if os.Getenv(reexecStateEnv) != "" {
+		t.Error("Expected the state variable to be cleared")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the state file to be removed")
+	}
+}
+
+func TestValidateReexec(t *testing.T) {
+	command := []string{"app"}
+	if err := validateReexec(&Config{Reexec: true, Command: command}); (err != nil) != (reexecSignal == nil) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, config := range []*Config{
+		{Reexec: true},
+		{Reexec: true, Command: command, Sandbox: true},
+		{Reexec: true, Command: command, RecordSession: "session"},
+		{Reexec: true, Command: command, Framing: framingMsgpack},
+	} {
+		if err := validateReexec(config); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
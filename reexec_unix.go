@@ -0,0 +1,51 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reexecSignal asks otel-logger to re-execute itself, with --reexec
+var reexecSignal os.Signal = syscall.SIGUSR2
+
+// handover lets the pipe survive exec and returns what the next process
+// needs to read it
+func (r *reexecReader) handover() (reexecStream, error) {
+	conn, err := r.file.SyscallConn()
+	if err != nil {
+		return reexecStream{}, err
+	}
+	var fd int
+	var fcntlErr error
+	err = conn.Control(func(raw uintptr) {
+		fd = int(raw)
+		_, fcntlErr = unix.FcntlInt(raw, unix.F_SETFD, 0)
+	})
+	if err == nil {
+		err = fcntlErr
+	}
+	return reexecStream{FD: fd, Pending: bytes.Clone(r.pending)}, err
+}
+
+// adoptPipe opens a pipe inherited across exec
+func adoptPipe(fd int, name string) (*os.File, error) {
+	// Non-blocking, the pipe supports the read deadline stop relies on
+	if err := unix.SetNonblock(fd, true); err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd)
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+func checkExecutable(path string) error {
+	return unix.Access(path, unix.X_OK)
+}
+
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldRenames maps parsed field names onto the names they are exported
+// under, so application keys such as userId can follow the semantic
+// conventions (enduser.id) without a separate processing step
+type fieldRenames map[string]string
+
+// parseFieldRenames reads --rename-field rules of the form old=new
+func parseFieldRenames(rules []string) (fieldRenames, error) {
+	renames := make(fieldRenames, len(rules))
+	for _, rule := range rules {
+		old, new, ok := strings.Cut(rule, "=")
+		old, new = strings.TrimSpace(old), strings.TrimSpace(new)
+		if !ok || old == "" || new == "" {
+			return nil, fmt.Errorf("invalid --rename-field %q: expected old=new", rule)
+		}
+		if previous, ok := renames[old]; ok && previous != new {
+			return nil, fmt.Errorf("invalid --rename-field %q: %s is already renamed to %s", rule, old, previous)
+		}
+		renames[old] = new
+	}
+	return renames, nil
+}
+
+// apply returns the entry with its top-level fields renamed. All rules apply
+// at once, so old=new and new=old swap two fields, and a renamed field
+// replaces one that already has the new name.
+func (r fieldRenames) apply(entry *LogEntry) *LogEntry {
+	renamed := false
+	for key := range entry.Fields {
+		if _, ok := r[key]; ok {
+			renamed = true
+			break
+		}
+	}
+	if !renamed {
+		return entry
+	}
+
+	fields := make(map[string]any, len(entry.Fields))
+	for key, value := range entry.Fields {
+		if _, ok := r[key]; !ok {
+			fields[key] = value
+		}
+	}
+	for key, value := range entry.Fields {
+		if new, ok := r[key]; ok {
+			fields[new] = value
+		}
+	}
+
+	result := *entry
+	result.Fields = fields
+	if entry.FieldOrder != nil {
+		result.FieldOrder = make([]string, len(entry.FieldOrder))
+		for i, key := range entry.FieldOrder {
+			if new, ok := r[key]; ok {
+				key = new
+			}
+			result.FieldOrder[i] = key
+		}
+	}
+	return &result
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldRenames(t *testing.T) {
+	renames, err := parseFieldRenames([]string{"userId=enduser.id", " status = http.response.status_code "})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := fieldRenames{"userId": "enduser.id", "status": "http.response.status_code"}
+	if !reflect.DeepEqual(renames, expected) {
+		t.Errorf("Expected %v, got %v", expected, renames)
+	}
+
+	for _, rules := range [][]string{{"userId"}, {"=enduser.id"}, {"userId="}, {"a=b", "a=c"}} {
+		if _, err := parseFieldRenames(rules); err == nil {
+			t.Errorf("Expected error for %q", rules)
+		}
+	}
+}
+
+func TestFieldRenamesApply(t *testing.T) {
+	renames := fieldRenames{"userId": "enduser.id", "a": "b", "b": "a", "status": "code"}
+	entry := &LogEntry{
+		Fields:     map[string]any{"userId": "u1", "a": 1, "b": 2, "status": 500, "code": "E42", "other": true},
+		FieldOrder: []string{"userId", "a", "b", "status", "code", "other"},
+	}
+
+	renamed := renames.apply(entry)
+	expected := map[string]any{"enduser.id": "u1", "a": 2, "b": 1, "code": 500, "other": true}
+	if !reflect.DeepEqual(renamed.Fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, renamed.Fields)
+	}
+	if order := []string{"enduser.id", "b", "a", "code", "code", "other"}; !reflect.DeepEqual(renamed.FieldOrder, order) {
+		t.Errorf("Expected order %v, got %v", order, renamed.FieldOrder)
+	}
+	if _, ok := entry.Fields["userId"]; !ok {
+		t.Error("Expected the original entry to be left alone")
+	}
+
+	unchanged := &LogEntry{Fields: map[string]any{"x": 1}}
+	if renames.apply(unchanged) != unchanged {
+		t.Error("Expected entries without renamed fields to be returned as is")
+	}
+}
+
+func TestLogProcessorRenamesFields(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetFieldRenames(fieldRenames{"userId": "enduser.id"})
+	processor.SetFieldRouting(newFieldRouting(nil, []string{"userId"}))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{Message: "x", Fields: map[string]any{"userId": "u1"}})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if attrs := recordAttributes(records[0]); attrs["enduser.id"] != "u1" {
+		t.Errorf("Expected the field under its new name, untouched by rules on the old one, got %v", attrs)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// resourceFilePrefix marks a resource attribute value read from a file, as
+// in k8s.pod.uid=@/etc/podinfo/uid, which is how the Kubernetes downward API
+// exposes pod metadata
+const resourceFilePrefix = "@"
+
+// loadResourceAttrs reads --resource-attrs-file: a JSON object, or key=value
+// lines with # comments. Quoted values are unquoted, so the downward API's
+// labels and annotations files can be used as they are.
+func loadResourceAttrs(path string) ([]attribute.KeyValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --resource-attrs-file: %w", err)
+	}
+
+	var attrs []attribute.KeyValue
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var values map[string]any
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return nil, fmt.Errorf("invalid --resource-attrs-file %s: %w", path, err)
+		}
+		for key, value := range values {
+			attrs = append(attrs, resourceAttr(key, value))
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid --resource-attrs-file %s line %d: expected key=value", path, n)
+			}
+			if strings.HasPrefix(value, `"`) {
+				if value, err = strconv.Unquote(value); err != nil {
+					return nil, fmt.Errorf("invalid --resource-attrs-file %s line %d: %w", path, n, err)
+				}
+			}
+			attrs = append(attrs, attribute.String(key, value))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read --resource-attrs-file: %w", err)
+		}
+	}
+	return resolveResourceFiles(attrs)
+}
+
+// resourceAttr converts a JSON value to an attribute; objects and arrays are
+// kept as JSON strings
+func resourceAttr(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return attribute.Int64(key, int64(v))
+		}
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, attributeString(v))
+	}
+}
+
+// resolveResourceFiles replaces values starting with @ by the contents of
+// the file they name, without surrounding whitespace
+func resolveResourceFiles(attrs []attribute.KeyValue) ([]attribute.KeyValue, error) {
+	for i, attr := range attrs {
+		if attr.Value.Type() != attribute.STRING {
+			continue
+		}
+		path, ok := strings.CutPrefix(attr.Value.AsString(), resourceFilePrefix)
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource attribute %s: %w", attr.Key, err)
+		}
+		attrs[i] = attr.Key.String(strings.TrimSpace(string(data)))
+	}
+	return attrs, nil
+}
+
+// environmentResource returns the OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_SERVICE_NAME resource with values starting with @ read from files
+func environmentResource() (*resource.Resource, error) {
+	attrs, err := resolveResourceFiles(resource.Environment().Attributes())
+	if err != nil {
+		return nil, err
+	}
+	return resource.NewSchemaless(attrs...), nil
+}
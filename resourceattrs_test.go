@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestCreateResourceAttrsFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	uid := write("uid", "8f2c1d3e-pod\n")
+	// As written by the Kubernetes downward API
+	labels := write("labels", "app=\"checkout\"\n# comment\n\nteam = payments\nk8s.pod.uid=@"+uid+"\n")
+
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=from-env,k8s.node.name=@"+write("node", "node-1"))
+	res, err := createResource(&Config{ResourceAttrsFile: labels})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for key, want := range map[attribute.Key]string{
+		"app":           "checkout",
+		"team":          "from-env",
+		"k8s.pod.uid":   "8f2c1d3e-pod",
+		"k8s.node.name": "node-1",
+	} {
+		if value, _ := res.Set().Value(key); value.AsString() != want {
+			t.Errorf("Expected %s=%s, got %q", key, want, value.AsString())
+		}
+	}
+
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+	res, err = createResource(&Config{ResourceAttrsFile: write("attrs.json", `{"deployment.environment": "prod", "replicas": 3, "canary": true, "ratio": 0.5}`)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for key, want := range map[attribute.Key]attribute.Value{
+		"deployment.environment": attribute.StringValue("prod"),
+		"replicas":               attribute.Int64Value(3),
+		"canary":                 attribute.BoolValue(true),
+		"ratio":                  attribute.Float64Value(0.5),
+	} {
+		if value, _ := res.Set().Value(key); value != want {
+			t.Errorf("Expected %s=%v, got %v", key, want.Emit(), value.Emit())
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(dir, "missing"),
+		write("bad", "no separator\n"),
+		write("badquote", "app=\"unterminated\n"),
+		write("bad.json", "{"),
+		write("badref", "app=@"+filepath.Join(dir, "missing")),
+	} {
+		if _, err := createResource(&Config{ResourceAttrsFile: path}); err == nil {
+			t.Errorf("Expected error for %s", path)
+		}
+	}
+
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "app=@"+filepath.Join(dir, "missing"))
+	if _, err := createResource(&Config{}); err == nil {
+		t.Error("Expected error for a missing file in OTEL_RESOURCE_ATTRIBUTES")
+	}
+}
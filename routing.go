@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// bodyMessageKey holds the log message in a body built from --body-field
+const bodyMessageKey = "message"
+
+// fieldRouting decides where each parsed field ends up. Following the OTel
+// guidance that the payload belongs in the body and metadata in attributes,
+// fields matching body are moved into a map body next to the message, fields
+// matching drop are not exported at all, and the rest stay attributes.
+type fieldRouting struct {
+	body fieldPatterns
+	drop fieldPatterns
+}
+
+func newFieldRouting(body, drop []string) *fieldRouting {
+	return &fieldRouting{body: newFieldPatterns(body), drop: newFieldPatterns(drop)}
+}
+
+// route splits the entry's fields into body fields and attribute fields.
+// Dropping wins over the body when a field matches both.
+func (r *fieldRouting) route(fields map[string]any) (body, attrs map[string]any) {
+	attrs = make(map[string]any, len(fields))
+	for key, value := range fields {
+		switch {
+		case r.drop.matches(key):
+		case r.body.matches(key):
+			if body == nil {
+				body = make(map[string]any)
+			}
+			body[key] = value
+		default:
+			attrs[key] = value
+		}
+	}
+	return body, attrs
+}
+
+// bodyValue builds a map body from the message and the routed fields, keeping
+// their JSON types. The message keeps its key if a field has the same name.
+func bodyValue(message string, fields map[string]any) log.Value {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != bodyMessageKey {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	kvs := make([]log.KeyValue, 0, len(keys)+1)
+	kvs = append(kvs, log.String(bodyMessageKey, message))
+	for _, key := range keys {
+		kvs = append(kvs, log.KeyValue{Key: key, Value: fieldValue(fields[key])})
+	}
+	return log.MapValue(kvs...)
+}
+
+// fieldValue converts a decoded JSON value to a log value of the same shape.
+// Whole numbers become integers, as JSON does not tell them apart.
+func fieldValue(value any) log.Value {
+	switch v := value.(type) {
+	case nil:
+		return log.Value{}
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return log.Int64Value(int64(v))
+		}
+		return log.Float64Value(v)
+	case int:
+		return log.IntValue(v)
+	case int64:
+		return log.Int64Value(v)
+	case []any:
+		values := make([]log.Value, len(v))
+		for i, item := range v {
+			values[i] = fieldValue(item)
+		}
+		return log.SliceValue(values...)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		kvs := make([]log.KeyValue, len(keys))
+		for i, key := range keys {
+			kvs[i] = log.KeyValue{Key: key, Value: fieldValue(v[key])}
+		}
+		return log.MapValue(kvs...)
+	default:
+		return log.StringValue(fmt.Sprintf("%v", v))
+	}
+}
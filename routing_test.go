@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestLogProcessorFieldRouting(t *testing.T) {
+	tests := []struct {
+		name          string
+		body, drop    []string
+		expectedBody  map[string]any
+		expectedAttrs map[string]string
+	}{
+		{
+			name:         "payload moves into the body",
+			body:         []string{"order", "items"},
+			expectedBody: map[string]any{"message": "order placed", "order": map[string]any{"id": int64(7), "total": 12.5}, "items": []any{"a", "b"}},
+			expectedAttrs: map[string]string{
+				"user_id": "42", "http.method": "POST", "secret": "x",
+			},
+		},
+		{
+			name:          "dropped fields are not exported",
+			drop:          []string{"secret", "http.*"},
+			expectedAttrs: map[string]string{"user_id": "42", "order": "[id:7 total:12.5]", "items": "[a b]"},
+		},
+		{
+			name:          "drop wins over body",
+			body:          []string{"secret", "items"},
+			drop:          []string{"secret"},
+			expectedBody:  map[string]any{"message": "order placed", "items": []any{"a", "b"}},
+			expectedAttrs: map[string]string{"user_id": "42", "http.method": "POST", "order": "[id:7 total:12.5]"},
+		},
+		{
+			name:          "no matching field keeps a string body",
+			body:          []string{"missing"},
+			expectedAttrs: map[string]string{"user_id": "42", "http.method": "POST", "secret": "x", "order": "[id:7 total:12.5]", "items": "[a b]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, exporter := newRecordingProvider()
+			processor := NewLogProcessor(provider.Logger("test"))
+			processor.SetFieldRouting(newFieldRouting(tt.body, tt.drop))
+
+			extractor := NewJSONExtractor("", getDefaultFieldMappings())
+			entry, _ := extractor.ParseLogEntry(`{"msg":"order placed","user_id":42,"http.method":"POST","secret":"x","order":{"id":7,"total":12.5},"items":["a","b"]}`)
+			entry.Raw, entry.TimestampSource = "", ""
+			processor.ProcessLogEntry(context.Background(), entry)
+
+			records := exporter.Records()
+			if len(records) != 1 {
+				t.Fatalf("Expected 1 record, got %d", len(records))
+			}
+			if got := recordAttributes(records[0]); !reflect.DeepEqual(got, tt.expectedAttrs) {
+				t.Errorf("Expected attributes %v, got %v", tt.expectedAttrs, got)
+			}
+
+			body := records[0].Body()
+			if tt.expectedBody == nil {
+				if body.Kind() != log.KindString || body.AsString() != "order placed" {
+					t.Errorf("Expected string body, got %v", body)
+				}
+				return
+			}
+			if body.Kind() != log.KindMap {
+				t.Fatalf("Expected map body, got %v", body.Kind())
+			}
+			if got := plainValue(body); !reflect.DeepEqual(got, tt.expectedBody) {
+				t.Errorf("Expected body %#v, got %#v", tt.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestBodyValueKeepsMessage(t *testing.T) {
+	body := bodyValue("hello", map[string]any{"message": "shadowed", "count": float64(3)})
+	kvs := body.AsMap()
+	if len(kvs) != 2 || kvs[0].Key != "message" || kvs[0].Value.AsString() != "hello" {
+		t.Fatalf("Expected message first and kept, got %v", kvs)
+	}
+	if kvs[1].Key != "count" || kvs[1].Value.Kind() != log.KindInt64 || kvs[1].Value.AsInt64() != 3 {
+		t.Errorf("Expected count as integer, got %v", kvs[1])
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ruleAudit counts how often each redaction or drop rule fires and
+// periodically writes the counts to a local sink. The summaries never go to
+// the collector and never contain the affected values.
+type ruleAudit struct {
+	mu      sync.Mutex
+	w       io.Writer
+	rules   []string // registration order, so idle rules are reported as 0
+	counts  map[string]int64
+	since   time.Time
+	closeFn func() error
+}
+
+// ruleAuditSummary is one line of audit output
+type ruleAuditSummary struct {
+	Time     time.Time        `json:"time"`
+	Interval string           `json:"interval"`
+	Hits     map[string]int64 `json:"hits"`
+}
+
+// openRuleAudit opens the audit sink: "stderr", or a file appended to
+func openRuleAudit(dest string) (*ruleAudit, error) {
+	audit := &ruleAudit{counts: make(map[string]int64), since: time.Now()}
+	if dest == "stderr" {
+		audit.w = os.Stderr
+		return audit, nil
+	}
+
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --rule-audit file: %w", err)
+	}
+	audit.w = file
+	audit.closeFn = file.Close
+	return audit, nil
+}
+
+func (a *ruleAudit) register(rule string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.counts[rule]; !ok {
+		a.rules = append(a.rules, rule)
+		a.counts[rule] = 0
+	}
+}
+
+// hit counts n applications of a rule; a nil audit counts nothing
+func (a *ruleAudit) hit(rule string, n int) {
+	if a == nil || n == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.counts[rule] += int64(n)
+}
+
+// report writes the counts since the last report and resets them
+func (a *ruleAudit) report() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	summary := ruleAuditSummary{
+		Time:     now,
+		Interval: now.Sub(a.since).Round(time.Millisecond).String(),
+		Hits:     make(map[string]int64, len(a.rules)),
+	}
+	for _, rule := range a.rules {
+		summary.Hits[rule] = a.counts[rule]
+		a.counts[rule] = 0
+	}
+	a.since = now
+
+	data, _ := json.Marshal(summary)
+	if _, err := a.w.Write(append(data, '\n')); err != nil {
+		logError("Failed to write rule audit: %v\n", err)
+	}
+}
+
+// run reports every interval until stop is closed
+func (a *ruleAudit) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.report()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (a *ruleAudit) Close() error {
+	if a == nil || a.closeFn == nil {
+		return nil
+	}
+	return a.closeFn()
+}
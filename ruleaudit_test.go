@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleAuditCountsHits(t *testing.T) {
+	t.Setenv("OTEL_LOGGER_TEST_SALT", "pepper")
+	hasher, err := newFieldHasher([]string{"user_id", "email"}, "OTEL_LOGGER_TEST_SALT")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	provider, _ := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetFieldHasher(hasher)
+	processor.SetAttributeAllowlist(newAttributeAllowlist([]string{"user_id", "email"}, false))
+
+	var out bytes.Buffer
+	audit := &ruleAudit{w: &out, counts: make(map[string]int64)}
+	processor.SetRuleAudit(audit, time.Hour)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, line := range []string{
+		`{"msg":"a","user_id":1,"path":"/"}`,
+		`{"msg":"b","user_id":2}`,
+		`plain`,
+	} {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+	processor.FinishRuleAudit()
+
+	var summary ruleAuditSummary
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatalf("Expected one JSON summary, got %q: %v", out.String(), err)
+	}
+
+	expected := map[string]int64{
+		"hash-field:user_id": 2,
+		"hash-field:email":   0, // configured but never fired
+		"attr-allowlist":     2, // path, and the original line of the plain record
+	}
+	for rule, count := range expected {
+		if summary.Hits[rule] != count {
+			t.Errorf("Expected %s=%d, got %d (%v)", rule, count, summary.Hits[rule], summary.Hits)
+		}
+	}
+}
+
+func TestRuleAuditReportResets(t *testing.T) {
+	var out bytes.Buffer
+	audit := &ruleAudit{w: &out, counts: make(map[string]int64)}
+	audit.register("attr-allowlist")
+	audit.hit("attr-allowlist", 3)
+	audit.report()
+	audit.report()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 summaries, got %d", len(lines))
+	}
+	for i, expected := range []int64{3, 0} {
+		var summary ruleAuditSummary
+		json.Unmarshal([]byte(lines[i]), &summary)
+		if summary.Hits["attr-allowlist"] != expected {
+			t.Errorf("Summary %d: expected %d hits, got %d", i, expected, summary.Hits["attr-allowlist"])
+		}
+	}
+
+	var nilAudit *ruleAudit
+	nilAudit.hit("anything", 1)
+	nilAudit.report()
+}
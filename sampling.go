@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// samplingRatioKey is the fraction of records like it that a sampled record
+// stands for, so backends can weigh counts by its inverse
+const samplingRatioKey = "sampling.ratio"
+
+// samplingPeriods are the units of --sample-rate
+var samplingPeriods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// sampler drops a share of the records of each severity, by a fixed ratio,
+// a maximum rate or both. Settings for one level apply to its four severity
+// numbers (info covers INFO to INFO4); settings without a level apply to the
+// levels that have none of their own.
+type sampler struct {
+	ratios map[log.Severity]float64     // by severityGroup; 0 for any other
+	rates  map[log.Severity]*rateWindow // by severityGroup; 0 for any other
+	now    func() time.Time
+	random func() float64
+
+	mu sync.Mutex
+}
+
+// rateWindow admits up to limit records per period. The share of records it
+// admitted in the last full period is the sampling ratio of those admitted
+// in this one, as that of the current period is only known once it ends.
+type rateWindow struct {
+	limit  int
+	period time.Duration
+
+	start     time.Time
+	seen      int
+	kept      int
+	lastRatio float64
+}
+
+// newSampler parses the --sample-ratio and --sample-rate values, each
+// "value" or "level=value"; it returns nil when there are none
+func newSampler(ratios, rates []string) (*sampler, error) {
+	if len(ratios) == 0 && len(rates) == 0 {
+		return nil, nil
+	}
+	s := &sampler{
+		ratios: make(map[log.Severity]float64),
+		rates:  make(map[log.Severity]*rateWindow),
+		now:    time.Now,
+		random: rand.Float64,
+	}
+	for _, value := range ratios {
+		group, text, err := samplingLevel(value, "--sample-ratio")
+		if err != nil {
+			return nil, err
+		}
+		ratio, err := strconv.ParseFloat(text, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid --sample-ratio %q: expected a fraction from 0 to 1", value)
+		}
+		s.ratios[group] = ratio
+	}
+	for _, value := range rates {
+		group, text, err := samplingLevel(value, "--sample-rate")
+		if err != nil {
+			return nil, err
+		}
+		count, unit, _ := strings.Cut(text, "/")
+		limit, err := strconv.Atoi(count)
+		period, ok := samplingPeriods[unit]
+		if err != nil || limit < 0 || !ok {
+			return nil, fmt.Errorf("invalid --sample-rate %q: expected a count per s, m or h, e.g. 100/s", value)
+		}
+		s.rates[group] = &rateWindow{limit: limit, period: period, lastRatio: 1}
+	}
+	return s, nil
+}
+
+// samplingLevel splits the optional "level=" off a sampling setting
+func samplingLevel(value, flag string) (log.Severity, string, error) {
+	level, text, ok := strings.Cut(value, "=")
+	if !ok {
+		return 0, value, nil
+	}
+	severity, err := parseSeverityLevel(level)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid %s %q: %w", flag, value, err)
+	}
+	return severityGroup(severity), text, nil
+}
+
+// severityGroup is the first severity number of the level severity is in,
+// such as SeverityInfo1 for SeverityInfo3
+func severityGroup(severity log.Severity) log.Severity {
+	if severity < log.SeverityTrace1 {
+		return 0
+	}
+	return (severity-1)/4*4 + 1
+}
+
+// sample reports whether the entry is exported and, if so, the fraction of
+// records like it that were: 1 when none were dropped
+func (s *sampler) sample(entry *LogEntry) (bool, float64) {
+	if s == nil {
+		return true, 1
+	}
+	group := severityGroup(entry.severity())
+
+	ratio, sampled := s.ratios[group]
+	if !sampled {
+		ratio, sampled = s.ratios[0]
+	}
+	if sampled && !s.admits(entry, ratio) {
+		return false, 0
+	}
+	if !sampled {
+		ratio = 1
+	}
+
+	window, ok := s.rates[group]
+	if !ok {
+		window, ok = s.rates[0]
+	}
+	if !ok {
+		return true, ratio
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	admitted, rateRatio := window.admit(s.now())
+	return admitted, ratio * rateRatio
+}
+
+// admits decides ratio sampling. Records of a trace are decided by its ID
+// the way OpenTelemetry's TraceIDRatioBased sampler decides spans, so a
+// trace's records are kept or dropped together, and along with its spans
+// where those are sampled at the same ratio.
+func (s *sampler) admits(entry *LogEntry, ratio float64) bool {
+	if entry.Trace != nil {
+		if id, err := hex.DecodeString(entry.Trace.TraceID); err == nil && len(id) == 16 {
+			return binary.BigEndian.Uint64(id[8:])>>1 < uint64(ratio*(1<<63))
+		}
+	}
+	return s.random() < ratio
+}
+
+// admit counts a record against the window at now
+func (w *rateWindow) admit(now time.Time) (bool, float64) {
+	if elapsed := now.Sub(w.start); elapsed >= w.period {
+		w.lastRatio = 1
+		if elapsed < 2*w.period && w.seen > w.kept {
+			w.lastRatio = float64(w.kept) / float64(w.seen)
+		}
+		w.start, w.seen, w.kept = now, 0, 0
+	}
+	w.seen++
+	if w.kept >= w.limit {
+		return false, 0
+	}
+	w.kept++
+	return true, w.lastRatio
+}
+
+// SetSampler drops a share of the records that the keep rules do not
+// exempt, other than otel-logger's own, and marks the exported ones with
+// sampling.ratio
+func (p *LogProcessor) SetSampler(s *sampler) {
+	p.sampler.Store(s)
+}
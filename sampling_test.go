@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestNewSampler(t *testing.T) {
+	s, err := newSampler([]string{"0.5", "debug=0.01"}, []string{"info=100/s", "10/m"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.ratios[0] != 0.5 || s.ratios[log.SeverityDebug1] != 0.01 {
+		t.Errorf("Unexpected ratios %v", s.ratios)
+	}
+	if w := s.rates[log.SeverityInfo1]; w == nil || w.limit != 100 || w.period != time.Second {
+		t.Errorf("Unexpected info rate %+v", w)
+	}
+	if w := s.rates[0]; w == nil || w.limit != 10 || w.period != time.Minute {
+		t.Errorf("Unexpected default rate %+v", w)
+	}
+
+	if s, err := newSampler(nil, nil); s != nil || err != nil {
+		t.Errorf("Expected no sampler without settings, got %v (%v)", s, err)
+	}
+	for _, bad := range [][2][]string{
+		{{"1.5"}, nil},
+		{{"loud=0.1"}, nil},
+		{nil, {"100"}},
+		{nil, {"100/d"}},
+		{nil, {"-1/s"}},
+	} {
+		if _, err := newSampler(bad[0], bad[1]); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+}
+
+func TestSamplerRatio(t *testing.T) {
+	s, _ := newSampler([]string{"info=0.25"}, nil)
+	draws := []float64{0.1, 0.3, 0.2, 0.9}
+	s.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	var kept []bool
+	for range 4 {
+		ok, ratio := s.sample(&LogEntry{Level: "info"})
+		if ok && ratio != 0.25 {
+			t.Errorf("Expected ratio 0.25, got %v", ratio)
+		}
+		kept = append(kept, ok)
+	}
+	if kept[0] != true || kept[1] != false || kept[2] != true || kept[3] != false {
+		t.Errorf("Unexpected decisions %v", kept)
+	}
+
+	// Levels without a setting are not sampled
+	if ok, ratio := s.sample(&LogEntry{Level: "warn"}); !ok || ratio != 1 {
+		t.Errorf("Expected warn to be kept unsampled, got %v %v", ok, ratio)
+	}
+
+	// Records of a trace are decided by its ID
+	low := &LogEntry{Level: "info", Trace: &TraceContext{TraceID: "4bf92f3577b34da6000000000000000f"}}
+	high := &LogEntry{Level: "info", Trace: &TraceContext{TraceID: "4bf92f3577b34da6ffffffffffffffff"}}
+	for range 3 {
+		if ok, _ := s.sample(low); !ok {
+			t.Error("Expected the low trace ID to be kept")
+		}
+		if ok, _ := s.sample(high); ok {
+			t.Error("Expected the high trace ID to be dropped")
+		}
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	s, _ := newSampler(nil, []string{"2/s"})
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+
+	sample := func() (bool, float64) { return s.sample(&LogEntry{Level: "info"}) }
+	for i, expected := range []bool{true, true, false, false} {
+		if ok, ratio := sample(); ok != expected || (ok && ratio != 1) {
+			t.Errorf("Record %d: expected %v with ratio 1, got %v %v", i, expected, ok, ratio)
+		}
+	}
+
+	// The next second's records carry the share kept in the last one
+	now = now.Add(time.Second)
+	if ok, ratio := sample(); !ok || ratio != 0.5 {
+		t.Errorf("Expected ratio 0.5 after a full second, got %v %v", ok, ratio)
+	}
+
+	// After an idle period nothing was dropped
+	now = now.Add(time.Minute)
+	if ok, ratio := sample(); !ok || ratio != 1 {
+		t.Errorf("Expected ratio 1 after an idle period, got %v %v", ok, ratio)
+	}
+}
+
+func TestLogProcessorSampling(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	s, _ := newSampler([]string{"0.5"}, nil)
+	s.random = func() float64 { return 0.4 }
+	processor.SetSampler(s)
+	keep, _ := newKeepRules("error", nil)
+	processor.SetKeepRules(keep)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, line := range []string{`{"level":"info","msg":"sampled"}`, `{"level":"error","msg":"kept"}`} {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+	s.random = func() float64 { return 0.6 }
+	entry, _ := extractor.ParseLogEntry(`{"level":"info","msg":"dropped"}`)
+	processor.ProcessLogEntry(context.Background(), entry)
+	processor.ProcessLogEntries(context.Background(), []*LogEntry{entry, streamClosedEarlyEntry("stdout")})
+	processor.ProcessLogEntry(context.Background(), commandExitEntry([]string{"true"}, 0, false))
+
+	records := exporter.Records()
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(records))
+	}
+	for _, record := range records[2:] {
+		if attrs := recordAttributes(record); attrs[samplingRatioKey] != "" {
+			t.Errorf("Expected otel-logger's own record %q not to be sampled, got %v", record.Body().AsString(), attrs)
+		}
+	}
+	if attrs := recordAttributes(records[0]); attrs[samplingRatioKey] != "0.5" {
+		t.Errorf("Expected sampling.ratio 0.5 on the sampled record, got %v", attrs)
+	}
+	if attrs := recordAttributes(records[1]); attrs[samplingRatioKey] != "" {
+		t.Errorf("Expected no sampling.ratio on the kept error, got %v", attrs)
+	}
+}
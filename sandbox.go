@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+)
+
+// sandboxReadPaths are read after startup by the Go runtime and standard
+// library: DNS and service resolution, CA certificates for TLS handshakes
+// with a restarted collector, time zones and cgroup limits for GOMAXPROCS
+var sandboxReadPaths = []string{
+	"/etc/resolv.conf",
+	"/etc/hosts",
+	"/etc/nsswitch.conf",
+	"/etc/services",
+	"/etc/ssl",
+	"/etc/pki",
+	"/etc/ca-certificates",
+	"/usr/share/ca-certificates",
+	"/etc/localtime",
+	"/usr/share/zoneinfo",
+	"/proc/self",
+	"/sys/fs/cgroup",
+}
+
+// sandboxPaths are the files and directories otel-logger may still open
+// once sandboxed. Files opened at startup, such as --export-receipts and
+// --rule-audit, need no rule; those opened later do: --config is reloaded,
+// possibly by an editor replacing it, --file matches new files as they are
+// created, --export-file is rotated or written
+// through a temporary file, --oversize-dir gets a file per truncated
+// record and --checkpoint-dir's state file is replaced. In a Kubernetes
+// pod, the service account token is reread as it rotates.
+type sandboxPaths struct {
+	read  []string
+	write []string
+}
+
+func sandboxPathsFor(config *Config) sandboxPaths {
+	paths := sandboxPaths{read: append([]string(nil), sandboxReadPaths...)}
+	if config.ConfigFile != "" {
+		paths.read = append(paths.read, filepath.Dir(config.ConfigFile))
+	}
+	paths.read = append(paths.read, fileWatchRoots(config.Files)...)
+	if (len(config.K8sPods) > 0 || config.K8sSelector != "") && config.K8sAPI == "" {
+		paths.read = append(paths.read, k8sServiceAccountDir)
+	}
+	if config.ExportFile != "" {
+		paths.write = append(paths.write, filepath.Dir(config.ExportFile))
+	}
+	if config.OversizeDir != "" {
+		paths.write = append(paths.write, config.OversizeDir)
+	}
+	if config.CheckpointDir != "" {
+		paths.write = append(paths.write, config.CheckpointDir)
+	}
+	paths.write = append(paths.write, config.SandboxAllow...)
+	return paths
+}
+
+// enterSandbox restricts otel-logger's own filesystem access and system
+// calls with --sandbox, once its inputs, exporter and wrapped command are
+// set up. What is already open stays usable.
+func enterSandbox(config *Config) error {
+	if !config.Sandbox {
+		return nil
+	}
+	summary, err := applySandbox(sandboxPathsFor(config))
+	if err != nil {
+		return err
+	}
+	logInfo(config.Verbose, "Sandboxed: %s\n", summary)
+	return nil
+}
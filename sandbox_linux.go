@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Landlock access rights handled by the sandbox, by the ABI version that
+// introduced them. Network access is left alone: the exporter has to reach
+// a restarted or failed-over collector, and any listeners are bound before
+// the sandbox is entered.
+const (
+	landlockReadFile = unix.LANDLOCK_ACCESS_FS_READ_FILE
+	landlockReadDir  = unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockWrite    = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_REG | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE
+	landlockABI1     = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR | unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG | unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	// landlockFileRights are those that apply to a regular file rather than
+	// to what is beneath a directory
+	landlockFileRights = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+		unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+// landlockHandled returns the filesystem rights a ruleset denies unless a
+// rule grants them, for a kernel supporting Landlock ABI abi
+func landlockHandled(abi int) uint64 {
+	handled := uint64(landlockABI1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		handled |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return handled
+}
+
+// sandboxDeniedSyscalls fail with EPERM once sandboxed: running programs,
+// inspecting or entering other processes, and administering the kernel,
+// mounts and namespaces, none of which a log shipper does
+var sandboxDeniedSyscalls = []uintptr{
+	unix.SYS_EXECVE,
+	unix.SYS_EXECVEAT,
+	unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_CHROOT,
+	unix.SYS_UNSHARE,
+	unix.SYS_SETNS,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_KEXEC_FILE_LOAD,
+	unix.SYS_REBOOT,
+	unix.SYS_SWAPON,
+	unix.SYS_SWAPOFF,
+	unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_USERFAULTFD,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_KEYCTL,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_ACCT,
+	unix.SYS_SETTIMEOFDAY,
+	unix.SYS_CLOCK_SETTIME,
+	unix.SYS_SETHOSTNAME,
+	unix.SYS_SETDOMAINNAME,
+}
+
+// seccompSetModeFilter is SECCOMP_SET_MODE_FILTER, the seccomp(2) operation
+const seccompSetModeFilter = 1
+
+// x32SyscallBit marks x32 ABI system calls on amd64, whose numbers would
+// otherwise get past the filter
+const x32SyscallBit = 0x40000000
+
+// applySandbox confines every thread of the process with Landlock to the
+// given paths, and with a seccomp filter. No new privileges can be gained
+// afterwards, and neither can be lifted.
+func applySandbox(paths sandboxPaths) (string, error) {
+	arch, ok := seccompArchs[runtime.GOARCH]
+	if !ok {
+		return "", fmt.Errorf("--sandbox is not supported on linux/%s", runtime.GOARCH)
+	}
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return "", fmt.Errorf("--sandbox needs Landlock (Linux 5.13 or later, with landlock in the lsm= boot parameter): %w", errno)
+	}
+
+	ruleset, err := landlockRuleset(int(abi), paths)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(ruleset)
+
+	// Every thread, including the runtime's, must be restricted, which only
+	// the Go runtime can do and only without cgo
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return "", errors.New("--sandbox needs a build without cgo (CGO_ENABLED=0)")
+		}
+		return "", fmt.Errorf("failed to set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0); errno != 0 {
+		return "", fmt.Errorf("failed to enter the Landlock ruleset: %w", errno)
+	}
+
+	filter := seccompFilter(arch, runtime.GOARCH == "amd64", sandboxDeniedSyscalls)
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&program))); errno != 0 {
+		return "", fmt.Errorf("failed to install the seccomp filter: %w", errno)
+	}
+	return fmt.Sprintf("landlock ABI %d, %d read and %d write paths, %d system calls denied", abi, len(paths.read), len(paths.write), len(sandboxDeniedSyscalls)), nil
+}
+
+// seccompArchs are the audit architectures of the GOARCHes the filter knows
+// the system call numbers of
+var seccompArchs = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+// landlockRuleset creates a ruleset granting read access beneath
+// paths.read and read and write access beneath paths.write. Paths that do
+// not exist are skipped, as on a host without /etc/pki.
+func landlockRuleset(abi int, paths sandboxPaths) (int, error) {
+	handled := landlockHandled(abi)
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("failed to create the Landlock ruleset: %w", errno)
+	}
+	ruleset := int(fd)
+
+	add := func(path string, access uint64) error {
+		err := landlockAllow(ruleset, path, access&handled)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, path := range paths.read {
+		if err := add(path, landlockReadFile|landlockReadDir); err != nil {
+			unix.Close(ruleset)
+			return -1, err
+		}
+	}
+	for _, path := range paths.write {
+		if err := add(path, landlockReadFile|landlockReadDir|landlockWrite|unix.LANDLOCK_ACCESS_FS_TRUNCATE); err != nil {
+			unix.Close(ruleset)
+			return -1, err
+		}
+	}
+	return ruleset, nil
+}
+
+// landlockAllow adds a rule granting access beneath path, or to path itself
+// if it is not a directory
+func landlockAllow(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "sandbox", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return &os.PathError{Op: "sandbox", Path: path, Err: err}
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileRights
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to allow %s in the sandbox: %w", path, errno)
+	}
+	return nil
+}
+
+// seccompFilter assembles a classic BPF program that kills the process on a
+// system call of another architecture, fails the denied calls, and x32 ones
+// when x32 is set, with EPERM, and allows everything else
+func seccompFilter(arch uint32, x32 bool, denied []uintptr) []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 4), // seccomp_data.arch
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: arch},
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 0), // seccomp_data.nr
+	}
+	// Each check jumps to the EPERM return after the final allow
+	checks := len(denied)
+	if x32 {
+		checks++
+	}
+	if x32 {
+		filter = append(filter, unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: uint8(checks), K: x32SyscallBit})
+		checks--
+	}
+	for _, nr := range denied {
+		filter = append(filter, unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: uint8(checks), K: uint32(nr)})
+		checks--
+	}
+	return append(filter,
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM)),
+	)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// runSeccompFilter evaluates the subset of classic BPF seccompFilter emits
+func runSeccompFilter(t *testing.T, filter []unix.SockFilter, arch, nr uint32) uint32 {
+	t.Helper()
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			acc = map[uint32]uint32{0: nr, 4: arch}[ins.K]
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			if acc >= ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_RET | unix.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("Unexpected instruction %#x", ins.Code)
+		}
+	}
+	t.Fatal("Filter ran off its end")
+	return 0
+}
+
+func TestSeccompFilter(t *testing.T) {
+	denied := []uintptr{59, 101, 165}
+	filter := seccompFilter(unix.AUDIT_ARCH_X86_64, true, denied)
+	eperm := unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+
+	for _, tc := range []struct {
+		name     string
+		arch, nr uint32
+		expected uint32
+	}{
+		{"denied first", unix.AUDIT_ARCH_X86_64, 59, eperm},
+		{"denied last", unix.AUDIT_ARCH_X86_64, 165, eperm},
+		{"allowed", unix.AUDIT_ARCH_X86_64, 0, unix.SECCOMP_RET_ALLOW},
+		{"x32", unix.AUDIT_ARCH_X86_64, x32SyscallBit | 59, eperm},
+		{"other architecture", unix.AUDIT_ARCH_AARCH64, 0, unix.SECCOMP_RET_KILL_PROCESS},
+	} {
+		if got := runSeccompFilter(t, filter, tc.arch, tc.nr); got != tc.expected {
+			t.Errorf("%s: expected %#x, got %#x", tc.name, tc.expected, got)
+		}
+	}
+
+	filter = seccompFilter(unix.AUDIT_ARCH_AARCH64, false, denied)
+	if got := runSeccompFilter(t, filter, unix.AUDIT_ARCH_AARCH64, 101); got != eperm {
+		t.Errorf("Expected EPERM without the x32 check, got %#x", got)
+	}
+	if got := runSeccompFilter(t, filter, unix.AUDIT_ARCH_AARCH64, x32SyscallBit|59); got != unix.SECCOMP_RET_ALLOW {
+		t.Errorf("Expected no x32 check on arm64, got %#x", got)
+	}
+}
+
+func TestLandlockHandled(t *testing.T) {
+	if landlockHandled(1)&unix.LANDLOCK_ACCESS_FS_REFER != 0 {
+		t.Error("Expected ABI 1 not to handle refer")
+	}
+	if landlockHandled(3)&unix.LANDLOCK_ACCESS_FS_TRUNCATE == 0 {
+		t.Error("Expected ABI 3 to handle truncate")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// applySandbox is only implemented with Landlock and seccomp on Linux
+func applySandbox(paths sandboxPaths) (string, error) {
+	return "", errors.New("--sandbox is only supported on Linux")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSandboxPaths(t *testing.T) {
+	paths := sandboxPathsFor(&Config{
+		ConfigFile:     "/etc/otel-logger/rules.yaml",
+		ExportFile:     "/var/log/otel/records.jsonl",
+		ExportReceipts: "/var/log/otel/receipts.jsonl",
+		OversizeDir:    "/var/spool/oversize",
+		SandboxAllow:   []string{"/run/otel-logger"},
+	})
+	if !slices.Contains(paths.read, "/etc/otel-logger") || !slices.Contains(paths.read, "/etc/ssl") {
+		t.Errorf("Expected the config directory and CA certificates to be readable, got %v", paths.read)
+	}
+	expected := []string{"/var/log/otel", "/var/spool/oversize", "/run/otel-logger"}
+	if !reflect.DeepEqual(paths.write, expected) {
+		t.Errorf("Expected writable paths %v, got %v", expected, paths.write)
+	}
+
+	if paths := sandboxPathsFor(&Config{}); len(paths.write) != 0 {
+		t.Errorf("Expected no writable paths by default, got %v", paths.write)
+	}
+}
+
+func TestEnterSandboxDisabled(t *testing.T) {
+	if err := enterSandbox(&Config{}); err != nil {
+		t.Errorf("Expected nothing to happen without --sandbox, got %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+)
+
+// latestReleaseURL is the GitHub API endpoint for the newest published release.
+// OTEL_LOGGER_RELEASE_URL overrides it for mirrors.
+const latestReleaseURL = "https://api.github.com/repos/middle-management/otel-logger/releases/latest"
+
+const updateTimeout = 2 * time.Minute
+
+// releasePublicKey is the minisign public key releases are signed with. The
+// release build sets it with -ldflags "-X main.releasePublicKey=..."; builds
+// without one cannot update themselves.
+var releasePublicKey = ""
+
+// maxReleaseSize bounds the binary self-update reads into memory to verify
+const maxReleaseSize = 256 << 20
+
+// SelfUpdateArgs are the arguments of `otel-logger self-update`
+type SelfUpdateArgs struct {
+	Force bool `arg:"--force" help:"Install the latest release even if it is not newer than the running version, or over a development build"`
+}
+
+func (SelfUpdateArgs) Description() string {
+	return `Replace this otel-logger binary with the latest release for the current platform.
+The download must carry a minisign signature, made with the release signing key
+built into this binary, for this platform's asset of that release; anything else
+is refused and the running binary is left in place.`
+}
+
+type githubRelease struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// releaseAssetName is the binary name the release workflow uploads for this platform
+func releaseAssetName() string {
+	return fmt.Sprintf("otel-logger-%s-%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (r *githubRelease) asset(name string) (releaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// updater fetches releases and installs them over the running binary
+type updater struct {
+	client    *http.Client
+	apiURL    string
+	current   string
+	publicKey string // minisign key the releases are signed with
+}
+
+func newUpdater() *updater {
+	apiURL := os.Getenv("OTEL_LOGGER_RELEASE_URL")
+	if apiURL == "" {
+		apiURL = latestReleaseURL
+	}
+	return &updater{
+		client:    &http.Client{Timeout: updateTimeout},
+		apiURL:    apiURL,
+		current:   version,
+		publicKey: releasePublicKey,
+	}
+}
+
+func (u *updater) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "otel-logger/"+u.current)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func (u *updater) latest(ctx context.Context) (*githubRelease, error) {
+	resp, err := u.get(ctx, u.apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, errors.New("latest release has no tag")
+	}
+	return &release, nil
+}
+
+// download fetches an asset of the release, reading at most limit bytes
+func (u *updater) download(ctx context.Context, release *githubRelease, name string, limit int64) ([]byte, error) {
+	asset, ok := release.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, name)
+	}
+	resp, err := u.get(ctx, asset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, limit)
+	}
+	return data, nil
+}
+
+// install downloads the release binary for this platform, verifies its
+// signature and renames it into place next to target
+func (u *updater) install(ctx context.Context, release *githubRelease, target string) error {
+	if u.publicKey == "" {
+		return errors.New("this build has no release signing key to verify updates with; download the release manually")
+	}
+	publicKey, err := parseMinisignPublicKey(u.publicKey)
+	if err != nil {
+		return err
+	}
+
+	name := releaseAssetName()
+	if _, ok := release.asset(name); !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	signature, err := u.download(ctx, release, name+".minisig", 4096)
+	if err != nil {
+		return err
+	}
+	binary, err := u.download(ctx, release, name, maxReleaseSize)
+	if err != nil {
+		return err
+	}
+
+	comment, err := publicKey.verify(binary, signature)
+	if err != nil {
+		return fmt.Errorf("refusing %s of release %s: %w", name, release.TagName, err)
+	}
+	// The signed comment names the asset and release, so a signed binary of
+	// another platform or an older release cannot be passed off as this one
+	if expected := name + " " + release.TagName; comment != expected {
+		return fmt.Errorf("refusing %s of release %s: signed for %q", name, release.TagName, comment)
+	}
+
+	// Same directory so the final rename cannot cross filesystems
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".otel-logger-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("failed to make %s executable: %w", tmp.Name(), err)
+	}
+	if err := replaceExecutable(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return nil
+}
+
+// semver is a version tag such as v1.2.3 or v1.3.0-rc.1
+type semver struct {
+	core       [3]int
+	prerelease []string
+}
+
+// parseSemver parses a tag, with or without the leading v; build metadata
+// after + is ignored. Anything else, such as dev, is not a release.
+func parseSemver(tag string) (semver, bool) {
+	tag, _, _ = strings.Cut(strings.TrimPrefix(tag, "v"), "+")
+	core, prerelease, hasPrerelease := strings.Cut(tag, "-")
+	var v semver
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part != strconv.Itoa(n) {
+			return semver{}, false
+		}
+		v.core[i] = n
+	}
+	if hasPrerelease {
+		if prerelease == "" {
+			return semver{}, false
+		}
+		v.prerelease = strings.Split(prerelease, ".")
+	}
+	return v, true
+}
+
+// compare returns -1, 0 or 1 as v is older than, the same as or newer than
+// w. Pre-releases are older than their release.
+func (v semver) compare(w semver) int {
+	if c := slices.Compare(v.core[:], w.core[:]); c != 0 {
+		return c
+	}
+	switch {
+	case len(v.prerelease) == 0 && len(w.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(w.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(w.prerelease); i++ {
+		a, b := v.prerelease[i], w.prerelease[i]
+		na, errA := strconv.Atoi(a)
+		nb, errB := strconv.Atoi(b)
+		switch {
+		case errA == nil && errB == nil:
+			if c := cmp.Compare(na, nb); c != 0 {
+				return c
+			}
+		case errA == nil:
+			// Numeric identifiers are older than alphanumeric ones
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a, b); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(v.prerelease), len(w.prerelease))
+}
+
+// updateAvailable reports whether the release is newer than the running
+// version, and whether the running version is a release at all; development
+// builds are not compared with releases
+func (u *updater) updateAvailable(release *githubRelease) (newer, isRelease bool, err error) {
+	latest, ok := parseSemver(release.TagName)
+	if !ok {
+		return false, false, fmt.Errorf("latest release tag %q is not a version", release.TagName)
+	}
+	current, ok := parseSemver(u.current)
+	if !ok {
+		return false, false, nil
+	}
+	return latest.compare(current) > 0, true, nil
+}
+
+// checkForUpdate reports whether a newer release than the running one exists
+func checkForUpdate(ctx context.Context, u *updater, out io.Writer) error {
+	release, err := u.latest(ctx)
+	if err != nil {
+		return err
+	}
+	newer, isRelease, err := u.updateAvailable(release)
+	switch {
+	case err != nil:
+		return err
+	case !isRelease:
+		fmt.Fprintf(out, "otel-logger %s is a development build; the latest release is %s\n", u.current, release.TagName)
+	case !newer:
+		fmt.Fprintf(out, "otel-logger %s is up to date\n", u.current)
+	default:
+		fmt.Fprintf(out, "otel-logger %s is available (running %s); update with `otel-logger self-update`\n", release.TagName, u.current)
+	}
+	return nil
+}
+
+// runSelfUpdate implements `otel-logger self-update`
+func runSelfUpdate(args []string) error {
+	var selfUpdateArgs SelfUpdateArgs
+	parser, err := arg.NewParser(arg.Config{Program: "otel-logger self-update"}, &selfUpdateArgs)
+	if err != nil {
+		return err
+	}
+	parser.MustParse(args)
+
+	target, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate running binary: %w", err)
+	}
+	if target, err = filepath.EvalSymlinks(target); err != nil {
+		return fmt.Errorf("failed to locate running binary: %w", err)
+	}
+
+	ctx := context.Background()
+	u := newUpdater()
+	release, err := u.latest(ctx)
+	if err != nil {
+		return err
+	}
+	newer, isRelease, err := u.updateAvailable(release)
+	switch {
+	case err != nil:
+		return err
+	case selfUpdateArgs.Force:
+		// Install the latest release whatever is running
+	case !isRelease:
+		return fmt.Errorf("otel-logger %s is a development build; use --force to replace it with release %s", u.current, release.TagName)
+	case !newer:
+		fmt.Printf("otel-logger %s is already the latest release\n", u.current)
+		return nil
+	}
+
+	if err := u.install(ctx, release, target); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s from %s to %s\n", target, u.current, release.TagName)
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// replaceExecutable moves the binary at path over target, which may be
+// running: the process keeps the old file open while the name moves on
+func replaceExecutable(path, target string) error {
+	return os.Rename(path, target)
+}
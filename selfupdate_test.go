@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newReleaseServer serves a latest release with a binary for this platform
+// and the given signature file contents
+func newReleaseServer(t *testing.T, tag string, binary, signature []byte) *httptest.Server {
+	t.Helper()
+
+	name := releaseAssetName()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(githubRelease{
+			TagName: tag,
+			Assets: []releaseAsset{
+				{Name: name, URL: server.URL + "/download/" + name},
+				{Name: name + ".minisig", URL: server.URL + "/download/" + name + ".minisig"},
+			},
+		})
+	})
+	mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	})
+	mux.HandleFunc("/download/"+name+".minisig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(signature)
+	})
+
+	return server
+}
+
+// minisignKey is a key pair in minisign's format for signing test releases
+type minisignKey struct {
+	id      []byte
+	private ed25519.PrivateKey
+	public  string
+}
+
+func newMinisignKey(t *testing.T, id string) *minisignKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(slices.Concat([]byte(minisignAlgorithm), []byte(id), public))
+	return &minisignKey{id: []byte(id), private: private, public: encoded}
+}
+
+// sign returns a .minisig file for message as minisign -l writes it
+func (k *minisignKey) sign(algorithm string, message []byte, comment string) []byte {
+	signature := ed25519.Sign(k.private, message)
+	global := ed25519.Sign(k.private, slices.Concat(signature, []byte(comment)))
+	return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(slices.Concat([]byte(algorithm), k.id, signature)),
+		comment,
+		base64.StdEncoding.EncodeToString(global)))
+}
+
+func TestUpdaterInstall(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho new\n")
+	key := newMinisignKey(t, "release1")
+	other := newMinisignKey(t, "another1")
+	comment := releaseAssetName() + " v9.9.9"
+
+	tests := []struct {
+		name        string
+		publicKey   string
+		signature   []byte
+		expectError string
+	}{
+		{
+			name:      "verified download replaces binary",
+			publicKey: key.public,
+			signature: key.sign(minisignAlgorithm, binary, comment),
+		},
+		{
+			name:        "signature of other contents keeps old binary",
+			publicKey:   key.public,
+			signature:   key.sign(minisignAlgorithm, []byte("something else"), comment),
+			expectError: "signature does not match",
+		},
+		{
+			name:        "signed with another key",
+			publicKey:   key.public,
+			signature:   other.sign(minisignAlgorithm, binary, comment),
+			expectError: "another key",
+		},
+		{
+			name:        "signed for another release",
+			publicKey:   key.public,
+			signature:   key.sign(minisignAlgorithm, binary, releaseAssetName()+" v1.0.0"),
+			expectError: "signed for",
+		},
+		{
+			name:        "forged trusted comment",
+			publicKey:   key.public,
+			signature:   bytes.Replace(key.sign(minisignAlgorithm, binary, releaseAssetName()+" v1.0.0"), []byte("v1.0.0"), []byte("v9.9.9"), 1),
+			expectError: "trusted comment signature does not match",
+		},
+		{
+			name:        "prehashed signature",
+			publicKey:   key.public,
+			signature:   key.sign("ED", binary, comment),
+			expectError: "minisign -l",
+		},
+		{
+			name:        "malformed signature file",
+			publicKey:   key.public,
+			signature:   []byte("not a signature\n"),
+			expectError: "malformed",
+		},
+		{
+			name:        "build without a key",
+			signature:   key.sign(minisignAlgorithm, binary, comment),
+			expectError: "no release signing key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newReleaseServer(t, "v9.9.9", binary, tt.signature)
+			target := filepath.Join(t.TempDir(), "otel-logger")
+			if err := os.WriteFile(target, []byte("old"), 0o755); err != nil {
+				t.Fatalf("Failed to write target: %v", err)
+			}
+
+			u := &updater{client: server.Client(), apiURL: server.URL + "/latest", current: "v1.0.0", publicKey: tt.publicKey}
+			ctx := context.Background()
+			release, err := u.latest(ctx)
+			if err != nil {
+				t.Fatalf("Failed to fetch release: %v", err)
+			}
+
+			err = u.install(ctx, release, target)
+			got, _ := os.ReadFile(target)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+				}
+				if string(got) != "old" {
+					t.Errorf("Expected old binary to be kept, got %q", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, binary) {
+				t.Errorf("Expected new binary to be installed, got %q", got)
+			}
+			if info, _ := os.Stat(target); info.Mode().Perm()&0o100 == 0 {
+				t.Errorf("Expected installed binary to be executable, got %v", info.Mode())
+			}
+		})
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	server := newReleaseServer(t, "v2.0.0", nil, nil)
+
+	tests := []struct {
+		current  string
+		expected string
+	}{
+		{current: "v2.0.0", expected: "up to date"},
+		{current: "v1.0.0", expected: "v2.0.0 is available"},
+		{current: "v1.10.0", expected: "v2.0.0 is available"},
+		{current: "v2.0.0-rc.1", expected: "v2.0.0 is available"},
+		{current: "v2.0.1", expected: "up to date"},
+		{current: "v10.0.0", expected: "up to date"},
+		{current: "dev", expected: "development build"},
+	}
+
+	for _, tt := range tests {
+		u := &updater{client: server.Client(), apiURL: server.URL + "/latest", current: tt.current}
+		var out bytes.Buffer
+		if err := checkForUpdate(context.Background(), u, &out); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(out.String(), tt.expected) {
+			t.Errorf("Expected output containing %q for %s, got %q", tt.expected, tt.current, out.String())
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Each version is older than the next
+	ordered := []string{"v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0-rc.1", "v1.0.0", "v1.0.1", "v1.2.0", "v1.10.0", "2.0.0+build.5"}
+	for i := range ordered {
+		for j := range ordered {
+			a, okA := parseSemver(ordered[i])
+			b, okB := parseSemver(ordered[j])
+			if !okA || !okB {
+				t.Fatalf("Failed to parse %q or %q", ordered[i], ordered[j])
+			}
+			if got, want := a.compare(b), cmp.Compare(i, j); got != want {
+				t.Errorf("compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	for _, tag := range []string{"dev", "", "v1.2", "v1.2.3.4", "v1.02.3", "v1.2.3-", "latest"} {
+		if _, ok := parseSemver(tag); ok {
+			t.Errorf("Expected %q not to parse as a version", tag)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// replaceExecutable moves the binary at path over target. Windows refuses
+// to replace or remove a running binary but lets it be renamed, so target
+// is moved aside to target.old first; a .old still in use is removed by the
+// next update.
+func replaceExecutable(path, target string) error {
+	old := target + ".old"
+	if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the binary left by the last update: %w", err)
+	}
+	if err := os.Rename(target, old); err != nil {
+		return err
+	}
+	if err := os.Rename(path, target); err != nil {
+		os.Rename(old, target)
+		return err
+	}
+	// Fails while old is the running binary
+	os.Remove(old)
+	return nil
+}
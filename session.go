@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A session directory holds session.json, describing what was captured, and
+// events.jsonl, the raw input chunks in the order and at the offsets they
+// were read. Replaying it feeds the same bytes through the pipeline so parse
+// bugs can be reproduced from a user's capture.
+const (
+	sessionInfoFile   = "session.json"
+	sessionEventsFile = "events.jsonl"
+	sessionVersion    = 1
+)
+
+// Session input modes
+const (
+	sessionModeStdin   = "stdin"
+	sessionModeCommand = "command"
+)
+
+type sessionInfo struct {
+	Version int       `json:"version"`
+	Mode    string    `json:"mode"`
+	Command []string  `json:"command,omitempty"`
+	Args    []string  `json:"args"`
+	Started time.Time `json:"started"`
+}
+
+// sessionEvent is one line of events.jsonl. Data is base64 in the file.
+type sessionEvent struct {
+	Offset time.Duration `json:"t"`
+	Stream string        `json:"stream,omitempty"`
+	Data   []byte        `json:"data,omitempty"`
+	EOF    bool          `json:"eof,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Exit   *sessionExit  `json:"exit,omitempty"`
+}
+
+type sessionExit struct {
+	Code   int  `json:"code"`
+	Failed bool `json:"failed"`
+}
+
+// sessionRecorder appends input events to a session directory. A nil
+// recorder records nothing, so call sites need no checks.
+type sessionRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	start   time.Time
+	failed  bool
+}
+
+// startSessionRecording creates the session directory, or returns a nil
+// recorder when dir is empty
+func startSessionRecording(dir, mode string, config *Config) (*sessionRecorder, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	// The raw input is recorded before any --redact, so it may hold secrets
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	start := time.Now()
+	info, err := json.MarshalIndent(sessionInfo{
+		Version: sessionVersion,
+		Mode:    mode,
+		Command: scrubbedArgs(config, config.Command),
+		Args:    scrubbedArgs(config, os.Args),
+		Started: start,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, sessionInfoFile), append(info, '\n'), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write session info: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, sessionEventsFile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session events: %w", err)
+	}
+	return &sessionRecorder{file: file, encoder: json.NewEncoder(file), start: start}, nil
+}
+
+func (r *sessionRecorder) record(event sessionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Offset = time.Since(r.start)
+	if err := r.encoder.Encode(event); err != nil && !r.failed {
+		// Recording is a debugging aid; never let it stop log export
+		r.failed = true
+		logError("Failed to record session, continuing without it: %v\n", err)
+	}
+}
+
+// Reader records everything read from the stream, including how it ended
+func (r *sessionRecorder) Reader(stream string, reader io.Reader) io.Reader {
+	if r == nil {
+		return reader
+	}
+	return &recordingReader{recorder: r, stream: stream, reader: reader}
+}
+
+// Exit records the wrapped command exiting
+func (r *sessionRecorder) Exit(code int, failed bool) {
+	if r == nil {
+		return
+	}
+	r.record(sessionEvent{Exit: &sessionExit{Code: code, Failed: failed}})
+}
+
+func (r *sessionRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+type recordingReader struct {
+	recorder *sessionRecorder
+	stream   string
+	reader   io.Reader
+	ended    bool
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.reader.Read(p)
+	if n > 0 {
+		rr.recorder.record(sessionEvent{Stream: rr.stream, Data: append([]byte(nil), p[:n]...)})
+	}
+	if err != nil && !rr.ended {
+		rr.ended = true
+		if errors.Is(err, io.EOF) {
+			rr.recorder.record(sessionEvent{Stream: rr.stream, EOF: true})
+		} else {
+			rr.recorder.record(sessionEvent{Stream: rr.stream, Error: err.Error()})
+		}
+	}
+	return n, err
+}
+
+// loadSession reads a recorded session directory
+func loadSession(dir string) (*sessionInfo, []sessionEvent, error) {
+	data, err := os.ReadFile(filepath.Join(dir, sessionInfoFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read session info: %w", err)
+	}
+	var info sessionInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil, fmt.Errorf("invalid session info: %w", err)
+	}
+	if info.Version != sessionVersion {
+		return nil, nil, fmt.Errorf("unsupported session version %d", info.Version)
+	}
+
+	file, err := os.Open(filepath.Join(dir, sessionEventsFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read session events: %w", err)
+	}
+	defer file.Close()
+
+	var events []sessionEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event sessionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// A session cut short by a crash ends in a partial line
+			break
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read session events: %w", err)
+	}
+
+	return &info, events, nil
+}
+
+// sessionReplayer feeds recorded events into per-stream pipes with the
+// original timing
+type sessionReplayer struct {
+	events  []sessionEvent
+	streams map[string]*io.PipeWriter
+	exit    chan sessionExit // receives the recorded exit, if any
+}
+
+func newSessionReplayer(events []sessionEvent, streams ...string) (*sessionReplayer, map[string]io.Reader) {
+	replayer := &sessionReplayer{
+		events:  events,
+		streams: make(map[string]*io.PipeWriter),
+		exit:    make(chan sessionExit, 1),
+	}
+	readers := make(map[string]io.Reader)
+	for _, stream := range streams {
+		pr, pw := io.Pipe()
+		replayer.streams[stream] = pw
+		readers[stream] = pr
+	}
+	return replayer, readers
+}
+
+func (r *sessionReplayer) run(ctx context.Context) {
+	// Streams the capture never saw end are closed once the events run out
+	defer func() {
+		for _, pw := range r.streams {
+			pw.Close()
+		}
+		close(r.exit)
+	}()
+
+	start := time.Now()
+	for _, event := range r.events {
+		if wait := time.Until(start.Add(event.Offset)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if event.Exit != nil {
+			r.exit <- *event.Exit
+			continue
+		}
+
+		pw, ok := r.streams[event.Stream]
+		if !ok {
+			continue
+		}
+		switch {
+		case len(event.Data) > 0:
+			pw.Write(event.Data)
+		case event.EOF:
+			pw.Close()
+		case event.Error != "":
+			pw.CloseWithError(errors.New(event.Error))
+		}
+	}
+}
+
+// replaySession runs a recorded session through the pipeline in place of
+// stdin or the wrapped command
+func replaySession(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	info, events, err := loadSession(config.ReplaySession)
+	if err != nil {
+		return err
+	}
+	logInfo(config.Verbose, "Replaying %s session recorded %s (%d events)\n", info.Mode, info.Started.Format(time.RFC3339), len(events))
+	if err := enterSandbox(config); err != nil {
+		return err
+	}
+
+	if info.Mode == sessionModeStdin {
+		replayer, readers := newSessionReplayer(events, "stdin")
+		go replayer.run(ctx)
+		return processReader(ctx, config, extractor, processor, readers["stdin"])
+	}
+
+	continuationPattern, err := continuationFor(config.ContinuationPattern, extractor)
+	if err != nil {
+		return fmt.Errorf("failed to compile continuation pattern: %w", err)
+	}
+	split, err := splitFunc(config)
+	if err != nil {
+		return err
+	}
+	readBuffer, err := readBufferSize(config)
+	if err != nil {
+		return err
+	}
+	if err := validateBinaryOutput(config.BinaryOutput); err != nil {
+		return err
+	}
+
+	replayer, readers := newSessionReplayer(events, "stdout", "stderr")
+	exited := make(chan struct{})
+	streamOpts := streamOptions{
+		continuationPattern: continuationPattern,
+		split:               split,
+		readBuffer:          readBuffer,
+		binaryPolicy:        config.BinaryOutput,
+		binaryFraming:       binaryFraming(config.Framing),
+		exited:              exited,
+	}
+
+	stdoutSink := newPassthroughSink("stdout", os.Stdout, nil)
+	stderrSink := newPassthroughSink("stderr", os.Stderr, nil)
+	stdoutReader, stdoutPassthrough := teePassthrough(readers["stdout"], config.PassthroughStdout, config.PassthroughRaw, stdoutSink)
+	stderrReader, stderrPassthrough := teePassthrough(readers["stderr"], config.PassthroughStderr, config.PassthroughRaw, stderrSink)
+	stdoutOpts, stderrOpts := streamOpts, streamOpts
+	stdoutOpts.passthrough, stdoutOpts.output = stdoutPassthrough, stdoutSink
+	stderrOpts.passthrough, stderrOpts.output = stderrPassthrough, stderrSink
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go processStream(ctx, stdoutReader, "stdout", extractor, processor, &wg, stdoutOpts)
+	go processStream(ctx, stderrReader, "stderr", extractor, processor, &wg, stderrOpts)
+	go replayer.run(ctx)
+
+	exit, recorded := <-replayer.exit
+	close(exited)
+	wg.Wait()
+
+	if !recorded {
+		logError("Session ended before the command exited; no exit record replayed\n")
+		return nil
+	}
+
+	recordCommandExit(ctx, processor, commandExitEntry(info.Command, exit.Code, exit.Failed))
+	if exit.Failed && exit.Code != 0 {
+		return fmt.Errorf("command failed with exit code %d", exit.Code)
+	}
+	return nil
+}
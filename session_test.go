@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// recordSummary reduces records to what a replay must reproduce
+func recordSummary(records []sdklog.Record) []string {
+	var summary []string
+	for _, r := range records {
+		attrs := recordAttributes(r)
+		summary = append(summary, attrs["log.iostream"]+"|"+r.SeverityText()+"|"+r.Body().String()+"|"+attrs["exit_code"])
+	}
+	return summary
+}
+
+func TestRecordAndReplayCommandSession(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		RecordSession:       dir,
+		Command: []string{"sh", "-c",
+			`echo '{"level":"warn","msg":"first"}'; printf 'Exception\n\tat Foo\n' >&2; sleep 0.1; printf 'progress 10%%\rprogress 100%%\n'; exit 2`},
+	}
+
+	provider, exporter := newRecordingProvider()
+	err := executeCommand(context.Background(), config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test")))
+	if err == nil {
+		t.Fatal("Expected the command's exit code to be reported")
+	}
+	recorded := recordSummary(exporter.Records())
+
+	replayConfig := &Config{ContinuationPattern: "^[ \\t]", ReplaySession: dir}
+	provider, exporter = newRecordingProvider()
+	start := time.Now()
+	err = replaySession(context.Background(), replayConfig, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test")))
+	if err == nil || err.Error() != "command failed with exit code 2" {
+		t.Errorf("Expected replayed exit code 2, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected replay to keep the recorded timing, took %v", elapsed)
+	}
+
+	// Streams are processed concurrently, so only per-stream order is stable
+	replayed := recordSummary(exporter.Records())
+	if !reflect.DeepEqual(slices.Sorted(slices.Values(recorded)), slices.Sorted(slices.Values(replayed))) {
+		t.Errorf("Replay differs from recording:\nrecorded: %q\nreplayed: %q", recorded, replayed)
+	}
+	if len(recorded) != 4 {
+		t.Errorf("Expected 4 records (2 stdout, 1 stderr, exit), got %d: %q", len(recorded), recorded)
+	}
+
+	// The recording is not redacted, so only its owner may read it
+	for path, perm := range map[string]os.FileMode{
+		dir:                                   0o700,
+		filepath.Join(dir, sessionInfoFile):   0o600,
+		filepath.Join(dir, sessionEventsFile): 0o600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != perm {
+			t.Errorf("Expected %s to have mode %v, got %v", path, perm, info.Mode().Perm())
+		}
+	}
+}
+
+func TestRecordAndReplayStdinSession(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	go func() {
+		w.Write([]byte(`{"level":"error","msg":"one"}` + "\n" + "two\n  continued\n"))
+		w.Close()
+	}()
+	origStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = origStdin }()
+
+	config := &Config{ContinuationPattern: "^[ \\t]", RecordSession: dir}
+	provider, exporter := newRecordingProvider()
+	if err := processLogs(context.Background(), config, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	recorded := recordSummary(exporter.Records())
+
+	replayConfig := &Config{ContinuationPattern: "^[ \\t]", ReplaySession: dir}
+	provider, exporter = newRecordingProvider()
+	if err := replaySession(context.Background(), replayConfig, NewJSONExtractor("", getDefaultFieldMappings()), NewLogProcessor(provider.Logger("test"))); err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+
+	if replayed := recordSummary(exporter.Records()); !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("Replay differs from recording:\nrecorded: %q\nreplayed: %q", recorded, replayed)
+	}
+}
+
+func TestLoadSessionErrors(t *testing.T) {
+	if _, _, err := loadSession(t.TempDir()); err == nil {
+		t.Error("Expected error for a directory without a session")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, sessionInfoFile), []byte(`{"version": 99}`), 0o644)
+	if _, _, err := loadSession(dir); err == nil {
+		t.Error("Expected error for an unsupported session version")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// parseSeverityMap reads --severity-map, a comma-separated list of
+// level=severity pairs such as NOTICE=10,CRIT=21,verbose=5
+func parseSeverityMap(s string) (map[string]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	severities := make(map[string]int)
+	for pair := range strings.SplitSeq(s, ",") {
+		level, number, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || level == "" {
+			return nil, fmt.Errorf("invalid severity mapping %q (expected level=number)", pair)
+		}
+		severity, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity mapping %q: %w", pair, err)
+		}
+		severities[strings.TrimSpace(level)] = severity
+	}
+	if err := validSeverityMap(severities); err != nil {
+		return nil, err
+	}
+	return severities, nil
+}
+
+// validSeverityMap checks that every level maps to an OTel severity number
+func validSeverityMap(severities map[string]int) error {
+	for level, severity := range severities {
+		if severity < int(log.SeverityTrace1) || severity > int(log.SeverityFatal4) {
+			return fmt.Errorf("invalid severity %d for level %q (supported: 1-24)", severity, level)
+		}
+	}
+	return nil
+}
+
+// severityTable is the severity map keyed by lowercased level, as levels are
+// matched regardless of case
+func severityTable(severities map[string]int) map[string]log.Severity {
+	if len(severities) == 0 {
+		return nil
+	}
+	table := make(map[string]log.Severity, len(severities))
+	for level, severity := range severities {
+		table[strings.ToLower(level)] = log.Severity(severity)
+	}
+	return table
+}
+
+// applySeverityMap sets the severity of an entry whose level is in the
+// table. Levels a numeric scale already decoded are left alone.
+func applySeverityMap(entry *LogEntry, table map[string]log.Severity) {
+	if entry == nil || entry.Severity != 0 || len(table) == 0 {
+		return
+	}
+	if severity, ok := table[strings.ToLower(entry.Level)]; ok {
+		entry.Severity = severity
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestParseSeverityMap(t *testing.T) {
+	got, err := parseSeverityMap("NOTICE=10, CRIT=21,verbose=5,100=3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]int{"NOTICE": 10, "CRIT": 21, "verbose": 5, "100": 3}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	for _, invalid := range []string{"NOTICE", "NOTICE=high", "NOTICE=0", "NOTICE=25", "=5"} {
+		if _, err := parseSeverityMap(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestSeverityMapLevels(t *testing.T) {
+	mappings := getDefaultFieldMappings()
+	mappings.SeverityMap = severityTable(map[string]int{"NOTICE": 10, "verbose": 5, "100": 3, "35": 14})
+	extractor := NewJSONExtractor("", mappings)
+
+	tests := []struct {
+		line     string
+		level    string
+		severity log.Severity
+	}{
+		{`{"level":"notice","msg":"a"}`, "notice", log.SeverityInfo2},
+		{`{"level":"VERBOSE","msg":"a"}`, "VERBOSE", log.SeverityDebug1},
+		{`{"level":100,"msg":"a"}`, "100", log.SeverityTrace3},
+		{`{"level":35,"msg":"a"}`, "35", log.SeverityWarn2},
+		{`{"level":30,"msg":"a"}`, "info", log.SeverityInfo1},
+		{`{"level":"error","msg":"a"}`, "error", log.SeverityError1},
+	}
+	for _, tt := range tests {
+		entry, err := extractor.ParseLogEntry(tt.line)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if entry.Level != tt.level || entry.severity() != tt.severity {
+			t.Errorf("%s: expected %q at %v, got %q at %v", tt.line, tt.level, tt.severity, entry.Level, entry.severity())
+		}
+	}
+}
+
+func TestSeverityMapMerge(t *testing.T) {
+	file, err := parseFileConfig([]byte("severity_map:\n  NOTICE: 10\n  CRIT: 20\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	merged := mergeFileConfig(file, &Config{SeverityMap: "CRIT=21,verbose=5"})
+	expected := map[string]int{"NOTICE": 10, "CRIT": 21, "verbose": 5}
+	if !reflect.DeepEqual(merged.SeverityMap, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged.SeverityMap)
+	}
+	if file.SeverityMap["CRIT"] != 20 {
+		t.Error("Expected the file config to be left unchanged")
+	}
+
+	if _, err := parseFileConfig([]byte("severity_map:\n  NOTICE: 30\n")); err == nil {
+		t.Error("Expected error for a severity out of range")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Cases for the exported SeverityText
+const (
+	severityTextNone  = "none"
+	severityTextLower = "lower"
+	severityTextUpper = "upper"
+	severityTextTitle = "title"
+)
+
+// severityTextNormalizer returns the function applied to each SeverityText,
+// or nil to pass the text through verbatim
+func severityTextNormalizer(mode string) (func(string) string, error) {
+	switch mode {
+	case "", severityTextNone:
+		return nil, nil
+	case severityTextLower:
+		return strings.ToLower, nil
+	case severityTextUpper:
+		return strings.ToUpper, nil
+	case severityTextTitle:
+		return titleCase, nil
+	default:
+		return nil, fmt.Errorf("invalid --normalize-severity-text %q (supported: %s, %s, %s, %s)", mode, severityTextLower, severityTextUpper, severityTextTitle, severityTextNone)
+	}
+}
+
+// titleCase upper-cases the first letter and lower-cases the rest ("Warn")
+func titleCase(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + strings.ToLower(s[size:])
+}
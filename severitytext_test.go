@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeverityTextNormalizer(t *testing.T) {
+	tests := []struct {
+		mode     string
+		input    string
+		expected string
+	}{
+		{mode: "", input: "Warning", expected: "Warning"},
+		{mode: severityTextNone, input: "iNfO", expected: "iNfO"},
+		{mode: severityTextLower, input: "ERROR", expected: "error"},
+		{mode: severityTextUpper, input: "info", expected: "INFO"},
+		{mode: severityTextTitle, input: "DEBUG", expected: "Debug"},
+		{mode: severityTextTitle, input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		normalize, err := severityTextNormalizer(tt.mode)
+		if err != nil {
+			t.Fatalf("Unexpected error for mode %q: %v", tt.mode, err)
+		}
+		got := tt.input
+		if normalize != nil {
+			got = normalize(tt.input)
+		}
+		if got != tt.expected {
+			t.Errorf("Mode %q: expected %q, got %q", tt.mode, tt.expected, got)
+		}
+	}
+
+	if _, err := severityTextNormalizer("camel"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestLogProcessorNormalizesSeverityText(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	normalize, _ := severityTextNormalizer(severityTextUpper)
+	processor.SetSeverityTextNormalizer(normalize)
+
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	for _, line := range []string{`{"level":"info","msg":"a"}`, `{"level":"Info","msg":"b"}`, `plain`} {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+
+	for _, record := range exporter.Records() {
+		if record.SeverityText() != "INFO" {
+			t.Errorf("Expected INFO, got %q", record.SeverityText())
+		}
+	}
+}
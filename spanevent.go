@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanEventName is the name of the child spans carrying span events
+const spanEventName = "otel-logger.event"
+
+// spanEventEmitter copies records at or above a severity onto the trace they
+// were logged in. The span that was active in the application belongs to
+// another process and may already be exported, so each record becomes a
+// zero-length child span holding an exception event, which trace UIs show
+// inline in the waterfall.
+type spanEventEmitter struct {
+	tracer    trace.Tracer
+	threshold log.Severity
+	flush     func(context.Context) error
+	shutdown  func(context.Context) error
+}
+
+// newSpanEventEmitter exports span events with OTLP to the endpoint the logs
+// go to. http/json is sent as http/protobuf, which every OTLP/HTTP
+// receiver accepts.
+func newSpanEventEmitter(ctx context.Context, threshold log.Severity, res *resource.Resource, policy *tlsPolicy) (*spanEventEmitter, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch protocol := resolveProtocol(); protocol {
+	case "grpc":
+		var opts []otlptracegrpc.Option
+		if opts, err = traceGRPCTLSOptions(policy); err != nil {
+			return nil, err
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case "http", "http/protobuf", "http/json":
+		var opts []otlptracehttp.Option
+		if policy != nil {
+			cfg, err := otlpTLSConfig("TRACES")
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(policy.apply(cfg)))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported protocol (supported: grpc, http/protobuf, http/json): %s", protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span exporter: %w", err)
+	}
+
+	// The application already decided to sample the trace by logging its IDs
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+	)
+	return &spanEventEmitter{
+		tracer:    provider.Tracer("otel-logger"),
+		threshold: threshold,
+		flush:     provider.ForceFlush,
+		shutdown:  provider.Shutdown,
+	}, nil
+}
+
+// traceGRPCTLSOptions applies the TLS policy to the gRPC span exporter,
+// unless its endpoint is plaintext
+func traceGRPCTLSOptions(policy *tlsPolicy) ([]otlptracegrpc.Option, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	raw, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if !ok {
+		raw = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	ep, err := parseEndpoint(raw, "grpc", false)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := policy.grpcCredentials("TRACES", ep.Insecure || envBool("OTEL_EXPORTER_OTLP_TRACES_INSECURE"))
+	if err != nil || creds == nil {
+		return nil, err
+	}
+	return []otlptracegrpc.Option{otlptracegrpc.WithTLSCredentials(creds)}, nil
+}
+
+// emit records the entry on the trace in ctx if it matches
+func (s *spanEventEmitter) emit(ctx context.Context, entry *LogEntry, severity log.Severity) {
+	if severity < s.threshold || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	_, span := s.tracer.Start(ctx, spanEventName,
+		trace.WithTimestamp(entry.Timestamp),
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	span.AddEvent(semconv.ExceptionEventName,
+		trace.WithTimestamp(entry.Timestamp),
+		trace.WithAttributes(semconv.ExceptionMessage(entry.Message)),
+	)
+	if severity >= log.SeverityError1 {
+		span.SetStatus(codes.Error, entry.Message)
+	}
+	span.End(trace.WithTimestamp(entry.Timestamp))
+}
+
+// SetSpanEvents also records matching entries that carry trace context as
+// span events
+func (p *LogProcessor) SetSpanEvents(emitter *spanEventEmitter) {
+	p.spanEvents = emitter
+}
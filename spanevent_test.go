@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLogProcessorSpanEvents(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(spans),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	processor.SetSpanEvents(&spanEventEmitter{
+		tracer:    tracerProvider.Tracer("test"),
+		threshold: log.SeverityError1,
+		shutdown:  tracerProvider.Shutdown,
+	})
+
+	traced := &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	timestamp := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	ctx := context.Background()
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: timestamp, Level: "error", Message: "payment declined", Trace: traced})
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: timestamp, Level: "info", Message: "below threshold", Trace: traced})
+	processor.ProcessLogEntry(ctx, &LogEntry{Timestamp: timestamp, Level: "error", Message: "no trace context"})
+
+	if got := len(exporter.Records()); got != 3 {
+		t.Errorf("Expected all 3 records to be logged, got %d", got)
+	}
+
+	stubs := spans.GetSpans()
+	if len(stubs) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(stubs))
+	}
+	span := stubs[0]
+	if span.Parent.TraceID().String() != traced.TraceID || span.Parent.SpanID().String() != traced.SpanID {
+		t.Errorf("Expected span to be a child of the logged span, got parent %v", span.Parent)
+	}
+	if !span.StartTime.Equal(timestamp) {
+		t.Errorf("Expected span at the record time, got %v", span.StartTime)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected error status, got %v", span.Status)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Fatalf("Expected one exception event, got %+v", span.Events)
+	}
+	var message string
+	for _, attr := range span.Events[0].Attributes {
+		if attr.Key == "exception.message" {
+			message = attr.Value.AsString()
+		}
+	}
+	if message != "payment declined" {
+		t.Errorf("Expected exception.message from the record, got %q", message)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// streamCloseGrace is how long after a stream's EOF the command may take to
+	// exit before the close counts as early
+	streamCloseGrace = 500 * time.Millisecond
+
+	// streamDrainTimeout bounds how long output is read after the command exits
+	streamDrainTimeout = 2 * time.Second
+)
+
+// streamReadErrorEntry reports that reading a stream failed, so "logs stopped
+// arriving" can be told apart from "the app stopped logging"
+func streamReadErrorEntry(stream string, err error) *LogEntry {
+	name := stream
+	if name == "" {
+		name = "stdin"
+	}
+
+	event := "read_error"
+	message := fmt.Sprintf("Error reading %s: %v", name, err)
+	if errors.Is(err, os.ErrClosed) {
+		event = "abandoned"
+		message = fmt.Sprintf("Stopped reading %s: still held open by a background process after the command exited", name)
+	}
+
+	return &LogEntry{
+		Timestamp: time.Now(),
+		Level:     "error",
+		Message:   message,
+		Fields: map[string]any{
+			"stream.event": event,
+			"stream.error": err.Error(),
+		},
+		Raw:      message,
+		Stream:   stream,
+		Internal: true,
+	}
+}
+
+// streamClosedEarlyEntry reports that the command closed a stream while still running
+func streamClosedEarlyEntry(stream string) *LogEntry {
+	message := fmt.Sprintf("Command closed %s while still running", stream)
+	return &LogEntry{
+		Timestamp: time.Now(),
+		Level:     "warn",
+		Message:   message,
+		Fields: map[string]any{
+			"stream.event": "closed_early",
+		},
+		Raw:      message,
+		Stream:   stream,
+		Internal: true,
+	}
+}
+
+// closedWithin reports whether ch is closed within d
+func closedWithin(ch <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// waitGroupWithin waits for wg for at most d and reports whether it finished
+func waitGroupWithin(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return closedWithin(done, d)
+}
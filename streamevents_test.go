@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func runCommandForRecords(t *testing.T, command ...string) []map[string]string {
+	t.Helper()
+
+	config := &Config{
+		ContinuationPattern: "^[ \\t]",
+		Command:             command,
+	}
+
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	if err := executeCommand(context.Background(), config, extractor, processor); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var records []map[string]string
+	for _, record := range exporter.Records() {
+		attrs := recordAttributes(record)
+		attrs["body"] = record.Body().AsString()
+		records = append(records, attrs)
+	}
+	return records
+}
+
+func TestStreamClosedEarly(t *testing.T) {
+	records := runCommandForRecords(t, "sh", "-c", "echo before; exec 1>&-; sleep 1")
+
+	found := false
+	for _, attrs := range records {
+		if attrs["stream.event"] == "closed_early" {
+			found = true
+			if attrs["log.iostream"] != "stdout" {
+				t.Errorf("Expected closed_early on stdout, got %q", attrs["log.iostream"])
+			}
+		}
+		if attrs["stream.event"] != "" && attrs["log.iostream"] == "stderr" {
+			t.Errorf("Unexpected stream event on stderr: %v", attrs)
+		}
+	}
+	if !found {
+		t.Errorf("Expected a closed_early record, got %v", records)
+	}
+}
+
+func TestStreamNormalExitHasNoEvents(t *testing.T) {
+	records := runCommandForRecords(t, "sh", "-c", "for i in $(seq 1 200); do echo line $i; done")
+
+	lines := 0
+	for _, attrs := range records {
+		if attrs["stream.event"] != "" {
+			t.Errorf("Unexpected stream event: %v", attrs)
+		}
+		if attrs["log.iostream"] == "stdout" {
+			lines++
+		}
+	}
+
+	// All output is read before the exit record, even for fast commands
+	if lines != 200 {
+		t.Errorf("Expected 200 stdout records, got %d", lines)
+	}
+	if last := records[len(records)-1]; last["body"] != "Command completed with exit code 0" {
+		t.Errorf("Expected exit record last, got %q", last["body"])
+	}
+}
+
+func TestStreamReadErrorEntry(t *testing.T) {
+	entry := streamReadErrorEntry("stderr", fmt.Errorf("read |0: %w", errors.New("input/output error")))
+	if entry.Fields["stream.event"] != "read_error" || entry.Level != "error" || entry.Stream != "stderr" {
+		t.Errorf("Unexpected read error entry: %+v", entry)
+	}
+
+	abandoned := streamReadErrorEntry("stdout", &os.PathError{Op: "read", Path: "|0", Err: os.ErrClosed})
+	if abandoned.Fields["stream.event"] != "abandoned" {
+		t.Errorf("Expected abandoned event for a pipe we closed, got %v", abandoned.Fields["stream.event"])
+	}
+}
+
+func TestStreamHeldOpenAfterExit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the stream drain timeout")
+	}
+
+	start := time.Now()
+	records := runCommandForRecords(t, "sh", "-c", "sleep 30 & echo started")
+
+	if elapsed := time.Since(start); elapsed > streamDrainTimeout+5*time.Second {
+		t.Errorf("Expected to stop reading after the drain timeout, took %v", elapsed)
+	}
+
+	found := false
+	for _, attrs := range records {
+		if attrs["stream.event"] == "abandoned" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected an abandoned record, got %v", records)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogPriorityFields are level fields whose numeric values are syslog PRI
+// values (facility*8 + severity) rather than level names
+var syslogPriorityFields = map[string]bool{
+	"priority": true,
+	"pri":      true,
+}
+
+// Syslog severity keywords from RFC 5424, indexed by severity code
+var syslogSeverityNames = [8]string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Syslog facility keywords, indexed by facility code
+var syslogFacilityNames = [24]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// decodeSyslogPriority splits a PRI given as a JSON number, a numeric string
+// or "<13>" into facility and severity names. Anything outside 0-191 is not a
+// PRI.
+func decodeSyslogPriority(value any) (facility, severity string, ok bool) {
+	var pri int
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v > 191 {
+			return "", "", false
+		}
+		pri = int(v)
+	case string:
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(v), "<"), ">"))
+		if err != nil || n < 0 || n > 191 {
+			return "", "", false
+		}
+		pri = n
+	default:
+		return "", "", false
+	}
+
+	return syslogFacilityNames[pri/8], syslogSeverityNames[pri%8], true
+}
+
+// Attributes of records read with --format syslog, besides syslog.facility
+const (
+	syslogHostnameKey = "host.name"
+	syslogAppNameKey  = "syslog.appname"
+	syslogProcIDKey   = "syslog.procid" // only when not a numeric process.pid
+	syslogMsgIDKey    = "syslog.msgid"
+	syslogVersionKey  = "syslog.version"
+	syslogSDPrefix    = "syslog.sd." // syslog.sd.<SD-ID>.<PARAM-NAME>
+)
+
+// rfc3164Timestamp is the BSD syslog timestamp, which has no year or zone
+const rfc3164Timestamp = "Jan _2 15:04:05"
+
+// ParseSyslogEntry decodes an RFC 5424 or RFC 3164 (BSD) syslog line, as
+// written by rsyslog or found in /var/log/syslog. The PRI becomes the level
+// and syslog.facility; the header fields and structured data become
+// attributes. The PRI is optional, as files written by syslog daemons leave
+// it out. Lines that are not syslog become plain messages.
+func (je *JSONExtractor) ParseSyslogEntry(line string) (*LogEntry, error) {
+	if entry, ok := je.parseSyslog(line); ok {
+		return entry, nil
+	}
+	return je.plainEntry(line), nil
+}
+
+func (je *JSONExtractor) parseSyslog(line string) (*LogEntry, bool) {
+	entry := &LogEntry{
+		Fields: make(map[string]any),
+		Raw:    line,
+		Level:  "info",
+	}
+
+	rest := line
+	if strings.HasPrefix(rest, "<") {
+		end := strings.IndexByte(rest, '>')
+		if end == -1 {
+			return nil, false
+		}
+		facility, severity, ok := decodeSyslogPriority(rest[:end+1])
+		if !ok {
+			return nil, false
+		}
+		entry.Level = severity
+		entry.Fields["syslog.facility"] = facility
+		rest = rest[end+1:]
+	}
+
+	var ok bool
+	if strings.HasPrefix(rest, "1 ") {
+		ok = parseRFC5424(entry, rest[2:])
+	} else {
+		ok = parseRFC3164(entry, rest, je.now())
+	}
+	if !ok {
+		return nil, false
+	}
+	if entry.Timestamp.IsZero() {
+		je.stampIngestTime(entry)
+	}
+	return entry, true
+}
+
+// plainEntry is a line that could not be parsed, exported as its message
+func (je *JSONExtractor) plainEntry(line string) *LogEntry {
+	return &LogEntry{
+		Fields:          make(map[string]any),
+		Raw:             line,
+		Message:         strings.TrimSpace(line),
+		Timestamp:       je.now(),
+		TimestampSource: timestampSourceIngest,
+		Level:           "info",
+	}
+}
+
+// parseRFC5424 reads the part of an RFC 5424 line after "<PRI>1 ":
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(entry *LogEntry, rest string) bool {
+	header := make([]string, 5)
+	for i := range header {
+		var ok bool
+		header[i], rest, ok = strings.Cut(rest, " ")
+		if !ok || header[i] == "" {
+			return false
+		}
+	}
+	entry.Fields[syslogVersionKey] = "1"
+
+	if header[0] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return false
+		}
+		entry.Timestamp = t
+	}
+	setSyslogField(entry, syslogHostnameKey, header[1])
+	setSyslogField(entry, syslogAppNameKey, header[2])
+	setSyslogProcID(entry, header[3])
+	setSyslogField(entry, syslogMsgIDKey, header[4])
+
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		var ok bool
+		if rest, ok = parseStructuredData(entry.Fields, rest); !ok {
+			return false
+		}
+	}
+	if rest != "" && rest[0] != ' ' {
+		return false
+	}
+	message := strings.TrimPrefix(strings.TrimPrefix(rest, " "), utf8BOM)
+	entry.Message = strings.TrimSpace(message)
+	return true
+}
+
+// parseStructuredData reads SD-ELEMENTs such as [id@32473 key="value"] into
+// syslog.sd.* fields and returns what follows them
+func parseStructuredData(fields map[string]any, s string) (string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return s, false
+	}
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		end := strings.IndexAny(s, " ]")
+		if end <= 0 {
+			return s, false
+		}
+		id := s[:end]
+		s = s[end:]
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			name, rest, ok := strings.Cut(s, `="`)
+			if !ok || name == "" {
+				return s, false
+			}
+			var value strings.Builder
+			i := 0
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				// Only \", \\ and \] are escapes; other backslashes are literal
+				if rest[i] == '\\' && i+1 < len(rest) && strings.IndexByte(`"\]`, rest[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			if i == len(rest) {
+				return s, false
+			}
+			fields[syslogSDPrefix+id+"."+name] = value.String()
+			s = rest[i+1:]
+		}
+		if !strings.HasPrefix(s, "]") {
+			return s, false
+		}
+		s = s[1:]
+	}
+	return s, true
+}
+
+// parseRFC3164 reads the part of a BSD syslog line after the PRI:
+// TIMESTAMP HOSTNAME TAG[PID]: MSG. Besides the classic timestamp without a
+// year, which is taken to be within the last year, rsyslog's RFC 3339
+// timestamps are accepted; those lines need a tag, as many application logs
+// also start with an RFC 3339 timestamp.
+func parseRFC3164(entry *LogEntry, rest string, now time.Time) bool {
+	if len(rest) >= len(rfc3164Timestamp) {
+		if t, err := time.ParseInLocation(rfc3164Timestamp, rest[:len(rfc3164Timestamp)], time.Local); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.AddDate(0, 0, 1)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			entry.Timestamp = t
+			rest = rest[len(rfc3164Timestamp):]
+		}
+	}
+	needTag := entry.Timestamp.IsZero()
+	if needTag {
+		stamp, after, ok := strings.Cut(rest, " ")
+		if !ok {
+			return false
+		}
+		t, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			return false
+		}
+		entry.Timestamp, rest = t, " "+after
+	}
+	if !strings.HasPrefix(rest, " ") {
+		return false
+	}
+
+	hostname, rest, ok := strings.Cut(strings.TrimLeft(rest, " "), " ")
+	if !ok || hostname == "" {
+		return false
+	}
+	setSyslogField(entry, syslogHostnameKey, hostname)
+
+	// The tag is optional; without one the rest is the message
+	if end := strings.IndexAny(rest, ":[ "); end > 0 && (rest[end] == ':' || rest[end] == '[') {
+		tag, after := rest[:end], rest[end:]
+		if strings.HasPrefix(after, "[") {
+			if closing := strings.Index(after, "]:"); closing > 0 {
+				setSyslogProcID(entry, after[1:closing])
+				after = after[closing+1:]
+			}
+		}
+		if strings.HasPrefix(after, ":") {
+			setSyslogField(entry, syslogAppNameKey, tag)
+			rest = after[1:]
+		}
+	}
+	if _, tagged := entry.Fields[syslogAppNameKey]; needTag && !tagged {
+		return false
+	}
+	entry.Message = strings.TrimSpace(rest)
+	return true
+}
+
+// setSyslogField stores a header field unless it is the nil value "-"
+func setSyslogField(entry *LogEntry, key, value string) {
+	if value != "" && value != "-" {
+		entry.Fields[key] = value
+	}
+}
+
+// setSyslogProcID stores a numeric PROCID as process.pid and anything else
+// as syslog.procid
+func setSyslogProcID(entry *LogEntry, procID string) {
+	if pid, err := strconv.Atoi(procID); err == nil && pid > 0 {
+		entry.Thread = &ThreadInfo{PID: pid}
+		return
+	}
+	setSyslogField(entry, syslogProcIDKey, procID)
+}
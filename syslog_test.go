@@ -0,0 +1,198 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestDecodeSyslogPriority(t *testing.T) {
+	tests := []struct {
+		value            any
+		expectedFacility string
+		expectedSeverity string
+		expectOK         bool
+	}{
+		{value: float64(0), expectedFacility: "kern", expectedSeverity: "emerg", expectOK: true},
+		{value: float64(13), expectedFacility: "user", expectedSeverity: "notice", expectOK: true},
+		{value: float64(191), expectedFacility: "local7", expectedSeverity: "debug", expectOK: true},
+		{value: "86", expectedFacility: "authpriv", expectedSeverity: "info", expectOK: true},
+		{value: "<34>", expectedFacility: "auth", expectedSeverity: "crit", expectOK: true},
+		{value: float64(192)},
+		{value: float64(-1)},
+		{value: 13.5},
+		{value: "high"},
+		{value: true},
+	}
+
+	for _, tt := range tests {
+		facility, severity, ok := decodeSyslogPriority(tt.value)
+		if ok != tt.expectOK || facility != tt.expectedFacility || severity != tt.expectedSeverity {
+			t.Errorf("decodeSyslogPriority(%v) = (%q, %q, %v), expected (%q, %q, %v)",
+				tt.value, facility, severity, ok, tt.expectedFacility, tt.expectedSeverity, tt.expectOK)
+		}
+	}
+}
+
+func TestParseLogEntrySyslogPriority(t *testing.T) {
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	entry, err := extractor.ParseLogEntry(`{"priority": 11, "message": "su: authentication failure"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Level != "err" {
+		t.Errorf("Expected level err, got %q", entry.Level)
+	}
+	if logLevelToSeverity(entry.Level) != log.SeverityError1 {
+		t.Errorf("Expected ERROR severity, got %v", logLevelToSeverity(entry.Level))
+	}
+	if entry.Fields["syslog.facility"] != "user" {
+		t.Errorf("Expected facility user, got %v", entry.Fields["syslog.facility"])
+	}
+	if _, ok := entry.Fields["priority"]; ok {
+		t.Error("Expected decoded priority to be removed from attributes")
+	}
+
+	// Non-PRI priorities are still level names
+	entry, _ = extractor.ParseLogEntry(`{"priority": "high", "message": "custom"}`)
+	if entry.Level != "high" || entry.Fields["syslog.facility"] != nil {
+		t.Errorf("Expected level high without facility, got %q / %v", entry.Level, entry.Fields)
+	}
+}
+
+func TestSyslogSeverityMapping(t *testing.T) {
+	expected := map[string]log.Severity{
+		"emerg":   log.SeverityFatal2,
+		"alert":   log.SeverityFatal1,
+		"crit":    log.SeverityError2,
+		"err":     log.SeverityError1,
+		"warning": log.SeverityWarn1,
+		"notice":  log.SeverityInfo2,
+		"info":    log.SeverityInfo1,
+		"debug":   log.SeverityDebug1,
+	}
+	for _, name := range syslogSeverityNames {
+		if got := logLevelToSeverity(name); got != expected[name] {
+			t.Errorf("Expected %s to map to %v, got %v", name, expected[name], got)
+		}
+	}
+}
+
+func TestParseSyslogEntry(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name              string
+		line              string
+		expectedLevel     string
+		expectedMessage   string
+		expectedTimestamp time.Time
+		expectedFields    map[string]any
+		expectedPID       int
+	}{
+		{
+			name:              "RFC 5424 with structured data",
+			line:              `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high \"x\""] An application event`,
+			expectedLevel:     "notice",
+			expectedMessage:   "An application event",
+			expectedTimestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+			expectedFields: map[string]any{
+				"syslog.facility":                         "local4",
+				"syslog.version":                          "1",
+				"host.name":                               "mymachine.example.com",
+				"syslog.appname":                          "evntslog",
+				"syslog.msgid":                            "ID47",
+				"syslog.sd.exampleSDID@32473.iut":         "3",
+				"syslog.sd.exampleSDID@32473.eventSource": "Application",
+				"syslog.sd.exampleSDID@32473.eventID":     "1011",
+				"syslog.sd.examplePriority@32473.class":   `high "x"`,
+			},
+		},
+		{
+			name:              "RFC 5424 without structured data",
+			line:              "<34>1 2003-10-11T22:14:15Z host su 1234 - - \ufeff'su root' failed",
+			expectedLevel:     "crit",
+			expectedMessage:   "'su root' failed",
+			expectedTimestamp: time.Date(2003, 10, 11, 22, 14, 15, 0, time.UTC),
+			expectedFields:    map[string]any{"syslog.facility": "auth", "syslog.version": "1", "host.name": "host", "syslog.appname": "su"},
+			expectedPID:       1234,
+		},
+		{
+			name:              "RFC 3164",
+			line:              "<86>Dec 31 23:59:58 gateway sshd[4721]: Accepted publickey for deploy",
+			expectedLevel:     "info",
+			expectedMessage:   "Accepted publickey for deploy",
+			expectedTimestamp: time.Date(2024, 12, 31, 23, 59, 58, 0, time.Local),
+			expectedFields:    map[string]any{"syslog.facility": "authpriv", "host.name": "gateway", "syslog.appname": "sshd"},
+			expectedPID:       4721,
+		},
+		{
+			name:              "file line without PRI",
+			line:              "Jan  2 11:30:00 web01 kernel: [12.5] eth0: link up",
+			expectedLevel:     "info",
+			expectedMessage:   "[12.5] eth0: link up",
+			expectedTimestamp: time.Date(2025, 1, 2, 11, 30, 0, 0, time.Local),
+			expectedFields:    map[string]any{"host.name": "web01", "syslog.appname": "kernel"},
+		},
+		{
+			name:              "rsyslog RFC 3339 timestamp",
+			line:              "2025-01-02T11:30:00.123456+00:00 web01 cron[99]: (root) CMD (run-parts)",
+			expectedLevel:     "info",
+			expectedMessage:   "(root) CMD (run-parts)",
+			expectedTimestamp: time.Date(2025, 1, 2, 11, 30, 0, 123456000, time.UTC),
+			expectedFields:    map[string]any{"host.name": "web01", "syslog.appname": "cron"},
+			expectedPID:       99,
+		},
+		{
+			name:              "not syslog",
+			line:              "just some text",
+			expectedLevel:     "info",
+			expectedMessage:   "just some text",
+			expectedTimestamp: now,
+			expectedFields:    map[string]any{},
+		},
+		{
+			name:              "invalid PRI",
+			line:              "<999>1 - - - - - - hi",
+			expectedLevel:     "info",
+			expectedMessage:   "<999>1 - - - - - - hi",
+			expectedTimestamp: now,
+			expectedFields:    map[string]any{},
+		},
+	}
+
+	mappings := getDefaultFieldMappings()
+	mappings.Format = formatSyslog
+	extractor := NewJSONExtractor("", mappings)
+	extractor.now = func() time.Time { return now }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := extractor.ParseLogEntry(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.Level != tt.expectedLevel || entry.Message != tt.expectedMessage {
+				t.Errorf("Expected level %q message %q, got %q %q", tt.expectedLevel, tt.expectedMessage, entry.Level, entry.Message)
+			}
+			if !entry.Timestamp.Equal(tt.expectedTimestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.expectedTimestamp, entry.Timestamp)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.expectedFields) {
+				t.Errorf("Expected fields %v, got %v", tt.expectedFields, entry.Fields)
+			}
+			pid := 0
+			if entry.Thread != nil {
+				pid = entry.Thread.PID
+			}
+			if pid != tt.expectedPID {
+				t.Errorf("Expected pid %d, got %d", tt.expectedPID, pid)
+			}
+			if entry.Raw != tt.line {
+				t.Errorf("Expected raw line to be kept, got %q", entry.Raw)
+			}
+		})
+	}
+}
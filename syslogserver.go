@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// syslogMaxMessageSize bounds a syslog message, the largest UDP datagram
+const syslogMaxMessageSize = 64 * 1024
+
+// Attributes of records received with --listen-syslog
+const (
+	syslogClientAddressKey = "client.address"
+	syslogTransportKey     = "network.transport"
+)
+
+// syslogServer receives RFC 5424 and RFC 3164 syslog messages from the
+// network, so devices that can only send syslog can be pointed at
+// otel-logger directly
+type syslogServer struct {
+	extractor *JSONExtractor
+	processor *LogProcessor
+
+	packets   []net.PacketConn
+	listeners []net.Listener
+	wg        sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// listenSyslog opens the --listen-syslog addresses: udp://HOST:PORT,
+// tcp://HOST:PORT or tls://HOST:PORT, the last with --syslog-tls-cert and
+// --syslog-tls-key
+func listenSyslog(config *Config, extractor *JSONExtractor, processor *LogProcessor) (*syslogServer, error) {
+	s := &syslogServer{extractor: extractor, processor: processor, conns: make(map[net.Conn]bool)}
+	var tlsConfig *tls.Config
+	for _, address := range config.ListenSyslog {
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			s.Close()
+			return nil, fmt.Errorf("invalid --listen-syslog %q: expected udp://, tcp:// or tls://HOST:PORT", address)
+		}
+		switch u.Scheme {
+		case "udp":
+			conn, err := net.ListenPacket("udp", u.Host)
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("failed to listen on --listen-syslog %s: %w", address, err)
+			}
+			s.packets = append(s.packets, conn)
+		case "tcp", "tls":
+			listener, err := net.Listen("tcp", u.Host)
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("failed to listen on --listen-syslog %s: %w", address, err)
+			}
+			if u.Scheme == "tls" {
+				if tlsConfig == nil {
+					if tlsConfig, err = syslogTLSConfig(config); err != nil {
+						listener.Close()
+						s.Close()
+						return nil, err
+					}
+				}
+				listener = tls.NewListener(listener, tlsConfig)
+			}
+			s.listeners = append(s.listeners, listener)
+		default:
+			s.Close()
+			return nil, fmt.Errorf("invalid --listen-syslog %q: unsupported scheme %q (supported: udp, tcp, tls)", address, u.Scheme)
+		}
+	}
+	return s, nil
+}
+
+func syslogTLSConfig(config *Config) (*tls.Config, error) {
+	if config.SyslogTLSCert == "" || config.SyslogTLSKey == "" {
+		return nil, errors.New("--listen-syslog tls:// requires --syslog-tls-cert and --syslog-tls-key")
+	}
+	cert, err := tls.LoadX509KeyPair(config.SyslogTLSCert, config.SyslogTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the syslog TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// serve receives messages until ctx is done, then waits for the connections
+// to be closed and the messages read so far to be processed
+func (s *syslogServer) serve(ctx context.Context) {
+	for _, conn := range s.packets {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.receive(ctx, conn)
+		}()
+	}
+	for _, listener := range s.listeners {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.accept(ctx, listener)
+		}()
+	}
+	<-ctx.Done()
+	s.Close()
+	s.wg.Wait()
+}
+
+// Close stops receiving messages
+func (s *syslogServer) Close() {
+	for _, conn := range s.packets {
+		conn.Close()
+	}
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// receive handles UDP, where each datagram is a message
+func (s *syslogServer) receive(ctx context.Context, conn net.PacketConn) {
+	buf := make([]byte, syslogMaxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if n > 0 {
+			s.export(ctx, buf[:n], addr, "udp")
+		}
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logError("Syslog listener failed: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *syslogServer) accept(ctx context.Context, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logError("Syslog listener failed: %v\n", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.read(ctx, conn)
+		}()
+	}
+}
+
+// read handles a TCP or TLS connection, whose messages are framed by octet
+// counting or by newlines (RFC 6587)
+func (s *syslogServer) read(ctx context.Context, conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), syslogMaxMessageSize+len(strconv.Itoa(syslogMaxMessageSize))+1)
+	scanner.Split(splitSyslogFrames)
+	for scanner.Scan() {
+		s.export(ctx, scanner.Bytes(), conn.RemoteAddr(), "tcp")
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logError("Error reading syslog from %s: %v\n", conn.RemoteAddr(), err)
+	}
+}
+
+// splitSyslogFrames splits a syslog stream into messages. A message starting
+// with a digit is octet counted ("LEN MSG"), as the PRI of any other starts
+// with "<"; otherwise it ends at a newline.
+func splitSyslogFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 || data[0] < '1' || data[0] > '9' {
+		return bufio.ScanLines(data, atEOF)
+	}
+	space := bytes.IndexByte(data, ' ')
+	if space < 0 {
+		if atEOF || len(data) > len(strconv.Itoa(syslogMaxMessageSize)) {
+			return 0, nil, errors.New("invalid syslog octet count")
+		}
+		return 0, nil, nil
+	}
+	length, err := strconv.Atoi(string(data[:space]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid syslog octet count %q", data[:space])
+	}
+	if length > syslogMaxMessageSize {
+		return 0, nil, fmt.Errorf("syslog message of %d bytes is too long", length)
+	}
+	end := space + 1 + length
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, errors.New("syslog message cut short")
+		}
+		return 0, nil, nil
+	}
+	return end, data[space+1 : end], nil
+}
+
+// export parses a message and hands it to the processor, along with where it
+// came from
+func (s *syslogServer) export(ctx context.Context, message []byte, addr net.Addr, transport string) {
+	readAt := time.Now()
+	line := strings.TrimRight(string(message), "\r\n\x00")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	entry, err := s.extractor.ParseLogEntryAs(line, formatSyslog)
+	if err != nil {
+		logError("Error parsing syslog message from %s: %v\n", addr, err)
+		return
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	entry.Fields[syslogClientAddressKey] = host
+	entry.Fields[syslogTransportKey] = transport
+	entry.ReadAt = readAt
+	if entry.FieldOrder != nil {
+		entry.FieldOrder = append(entry.FieldOrder, syslogClientAddressKey, syslogTransportKey)
+	}
+	s.processor.ProcessLogEntry(ctx, entry)
+}
+
+// processSyslog receives syslog messages until interrupted
+func processSyslog(ctx context.Context, config *Config, extractor *JSONExtractor, processor *LogProcessor) error {
+	server, err := listenSyslog(config, extractor, processor)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := enterSandbox(config); err != nil {
+		server.Close()
+		return err
+	}
+	server.serve(ctx)
+	return nil
+}
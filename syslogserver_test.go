@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func octetCounted(message string) string {
+	return fmt.Sprintf("%d %s", len(message), message)
+}
+
+func TestSplitSyslogFrames(t *testing.T) {
+	input := "<13>Oct 11 22:14:15 host app: newline framed\n" +
+		octetCounted("<13>1 - host app - - - octet\ncount") +
+		"<14>Oct 11 22:14:16 host app: crlf\r\n\n" +
+		octetCounted("<13>last!")
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(splitSyslogFrames)
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"<13>Oct 11 22:14:15 host app: newline framed",
+		"<13>1 - host app - - - octet\ncount",
+		"<14>Oct 11 22:14:16 host app: crlf",
+		"",
+		"<13>last!",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	for _, bad := range []string{"12 <13>short", "99999999 <13>", "12x <13>", "123456789"} {
+		scanner := bufio.NewScanner(strings.NewReader(bad))
+		scanner.Split(splitSyslogFrames)
+		for scanner.Scan() {
+		}
+		if scanner.Err() == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and
+// its key, returning their paths
+func writeTestCertificate(t *testing.T) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certPath, keyPath = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestSyslogServer(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	certPath, keyPath := writeTestCertificate(t)
+	config := &Config{
+		ListenSyslog:  []string{"udp://127.0.0.1:0", "tcp://127.0.0.1:0", "tls://127.0.0.1:0"},
+		SyslogTLSCert: certPath,
+		SyslogTLSKey:  keyPath,
+	}
+
+	server, err := listenSyslog(config, extractor, processor)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.serve(ctx)
+		close(done)
+	}()
+
+	udp, err := net.Dial("udp", server.packets[0].LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	udp.Write([]byte("<11>Oct 11 22:14:15 router sshd[42]: udp message\n"))
+
+	tcp, err := net.Dial("tcp", server.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp.Write([]byte("<14>1 2024-10-11T22:14:15Z web app - - - tcp message\n"))
+	tcp.Close()
+
+	pool := x509.NewCertPool()
+	pem, _ := os.ReadFile(certPath)
+	pool.AppendCertsFromPEM(pem)
+	secure, err := tls.Dial("tcp", server.listeners[1].Addr().String(), &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secure.Write([]byte(octetCounted("<12>1 - fw app - - - tls message")))
+	secure.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exporter.Records()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	messages := make(map[string]map[string]string)
+	for _, record := range exporter.Records() {
+		messages[record.Body().AsString()] = recordAttributes(record)
+	}
+	for message, want := range map[string]map[string]string{
+		"udp message": {"network.transport": "udp", "host.name": "router", "syslog.facility": "user"},
+		"tcp message": {"network.transport": "tcp", "host.name": "web"},
+		"tls message": {"network.transport": "tcp", "host.name": "fw"},
+	} {
+		attrs, ok := messages[message]
+		if !ok {
+			t.Errorf("Expected a record for %q, got %v", message, messages)
+			continue
+		}
+		want["client.address"] = "127.0.0.1"
+		for key, value := range want {
+			if attrs[key] != value {
+				t.Errorf("Expected %s=%v for %q, got %v", key, value, message, attrs)
+			}
+		}
+	}
+}
+
+func TestListenSyslogErrors(t *testing.T) {
+	for _, config := range []*Config{
+		{ListenSyslog: []string{"127.0.0.1:514"}},
+		{ListenSyslog: []string{"http://127.0.0.1:0"}},
+		{ListenSyslog: []string{"udp://127.0.0.1:0/path"}},
+		{ListenSyslog: []string{"tls://127.0.0.1:0"}},
+		{ListenSyslog: []string{"tls://127.0.0.1:0"}, SyslogTLSCert: "missing.pem", SyslogTLSKey: "missing.key"},
+	} {
+		if server, err := listenSyslog(config, nil, nil); err == nil {
+			server.Close()
+			t.Errorf("Expected error for %v", config.ListenSyslog)
+		}
+	}
+}
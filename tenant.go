@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"gopkg.in/yaml.v3"
+)
+
+// tenantHeadersFile maps the value of a record or resource attribute to the
+// export headers for that tenant:
+//
+//	attribute: k8s.namespace.name
+//	tenants:
+//	  payments:
+//	    x-api-key: key-for-payments
+//	  search:
+//	    x-api-key: key-for-search
+type tenantHeadersFile struct {
+	Attribute string                       `yaml:"attribute"`
+	Tenants   map[string]map[string]string `yaml:"tenants"`
+}
+
+func loadTenantHeaders(path string) (*tenantHeadersFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --tenant-headers: %w", err)
+	}
+
+	file := &tenantHeadersFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid --tenant-headers %s: %w", path, err)
+	}
+	if file.Attribute == "" {
+		return nil, fmt.Errorf("invalid --tenant-headers %s: attribute is required", path)
+	}
+	return file, nil
+}
+
+// otlpHeaders returns the headers configured in the environment, which
+// tenant headers are added to
+func otlpHeaders() (map[string]string, error) {
+	raw, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_HEADERS")
+	if !ok {
+		raw = os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	}
+
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected key=value", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", pair, err)
+		}
+		headers[strings.TrimSpace(key)] = decoded
+	}
+	return headers, nil
+}
+
+// tenantExporter routes each record to the exporter holding its tenant's
+// headers. Records without a known tenant use the default exporter.
+type tenantExporter struct {
+	attribute string
+	tenants   map[string]sdklog.Exporter
+	fallback  sdklog.Exporter
+}
+
+func (e *tenantExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	groups := make(map[sdklog.Exporter][]sdklog.Record)
+	var order []sdklog.Exporter
+	for _, record := range records {
+		exporter, ok := e.tenants[e.tenantOf(record)]
+		if !ok {
+			exporter = e.fallback
+		}
+		if _, seen := groups[exporter]; !seen {
+			order = append(order, exporter)
+		}
+		groups[exporter] = append(groups[exporter], record)
+	}
+
+	var errs []error
+	for _, exporter := range order {
+		errs = append(errs, exporter.Export(ctx, groups[exporter]))
+	}
+	return errors.Join(errs...)
+}
+
+// tenantOf returns the tenant attribute of the record, or of its resource
+func (e *tenantExporter) tenantOf(record sdklog.Record) string {
+	var tenant string
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		if kv.Key == e.attribute {
+			tenant = kv.Value.String()
+			return false
+		}
+		return true
+	})
+	if tenant != "" {
+		return tenant
+	}
+	if value, ok := record.Resource().Set().Value(attribute.Key(e.attribute)); ok {
+		return value.Emit()
+	}
+	return ""
+}
+
+func (e *tenantExporter) ForceFlush(ctx context.Context) error {
+	errs := []error{e.fallback.ForceFlush(ctx)}
+	for _, exporter := range e.tenants {
+		errs = append(errs, exporter.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *tenantExporter) Shutdown(ctx context.Context) error {
+	errs := []error{e.fallback.Shutdown(ctx)}
+	for _, exporter := range e.tenants {
+		errs = append(errs, exporter.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// withTenantHeaders routes records to per-tenant exporters when
+// --tenant-headers is set, keeping exporter for everything else
+func withTenantHeaders(ctx context.Context, config *Config, exporter sdklog.Exporter) (sdklog.Exporter, error) {
+	if config.TenantHeaders == "" {
+		return exporter, nil
+	}
+	if len(config.Endpoints) > 0 || config.EndpointFallback != "" {
+		return nil, fmt.Errorf("--tenant-headers cannot be combined with --endpoints or --endpoint-fallback")
+	}
+
+	file, err := loadTenantHeaders(config.TenantHeaders)
+	if err != nil {
+		return nil, err
+	}
+	base, err := otlpHeaders()
+	if err != nil {
+		return nil, err
+	}
+	protocol := resolveProtocol()
+	ep, err := resolveEndpoint(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	tenants := &tenantExporter{
+		attribute: file.Attribute,
+		tenants:   make(map[string]sdklog.Exporter),
+		fallback:  exporter,
+	}
+	for tenant, extra := range file.Tenants {
+		headers := make(map[string]string, len(base)+len(extra))
+		for key, value := range base {
+			headers[key] = value
+		}
+		for key, value := range extra {
+			headers[key] = value
+		}
+		tenantExporter, err := newEndpointExporter(ctx, config, ep, headers)
+		if err != nil {
+			tenants.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to create exporter for tenant %q: %w", tenant, err)
+		}
+		tenants.tenants[tenant] = tenantExporter
+	}
+	logInfo(config.Verbose, "Routing records to %d tenants by %s\n", len(tenants.tenants), file.Attribute)
+	return tenants, nil
+}
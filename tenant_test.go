@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestOTLPHeaders(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc%3D%3D, x-scope = team")
+	headers, err := otlpHeaders()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if headers["x-api-key"] != "abc==" || headers["x-scope"] != "team" {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "novalue")
+	if _, err := otlpHeaders(); err == nil {
+		t.Error("Expected error for a header without value")
+	}
+}
+
+func TestLoadTenantHeaders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	file, err := loadTenantHeaders(write("ok.yaml", "attribute: team\ntenants:\n  payments:\n    x-api-key: k1\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.Attribute != "team" || file.Tenants["payments"]["x-api-key"] != "k1" {
+		t.Errorf("Unexpected file %+v", file)
+	}
+
+	if _, err := loadTenantHeaders(write("noattr.yaml", "tenants: {}\n")); err == nil {
+		t.Error("Expected error without attribute")
+	}
+	if _, err := loadTenantHeaders(write("typo.yaml", "attribute: team\ntenant: {}\n")); err == nil {
+		t.Error("Expected error for an unknown key")
+	}
+}
+
+func TestTenantExporterSendsTenantHeaders(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("X-Api-Key")+"/"+r.Header.Get("X-Shared"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-shared=yes,x-api-key=default")
+
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	os.WriteFile(path, []byte("attribute: team\ntenants:\n  payments:\n    x-api-key: k-payments\n  search:\n    x-api-key: k-search\n"), 0o600)
+
+	ctx := context.Background()
+	config := &Config{Timeout: 5 * time.Second, TenantHeaders: path}
+	base, err := createExporter(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter, err := withTenantHeaders(ctx, config, base)
+	if err != nil {
+		t.Fatalf("Failed to create tenant exporter: %v", err)
+	}
+	defer exporter.Shutdown(ctx)
+
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	logger := provider.Logger("test")
+	for _, team := range []string{"payments", "search", "unknown"} {
+		var record log.Record
+		record.AddAttributes(log.String("team", team))
+		logger.Emit(ctx, record)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(keys)
+	expected := []string{"default/yes", "k-payments/yes", "k-search/yes"}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected requests with %v, got %v", expected, keys)
+	}
+}
+
+func TestTenantExporterRejectsMultipleEndpoints(t *testing.T) {
+	config := &Config{TenantHeaders: "tenants.yaml", EndpointFallback: "http://backup:4318"}
+	if _, err := withTenantHeaders(context.Background(), config, &recordingExporter{}); err == nil {
+		t.Error("Expected error combining --tenant-headers with --endpoint-fallback")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+// ThreadInfo identifies the process and thread (or goroutine) that wrote a
+// record, which APM backends use to group interleaved output
+type ThreadInfo struct {
+	PID        int
+	ThreadID   int
+	ThreadName string
+}
+
+// extractThreadInfo recognizes pid, tid, thread, thread_name and goroutine
+// fields and removes the ones it used from jsonData. A numeric "thread" is
+// an ID; anything else, such as Java's "http-nio-8080-exec-1", is a name.
+func extractThreadInfo(jsonData map[string]any) *ThreadInfo {
+	var info ThreadInfo
+
+	if pid, ok := positiveInt(jsonData["pid"]); ok {
+		info.PID = pid
+		delete(jsonData, "pid")
+	}
+
+	for _, field := range []string{"tid", "goroutine", "thread"} {
+		if id, ok := positiveInt(jsonData[field]); ok {
+			info.ThreadID = id
+			delete(jsonData, field)
+			break
+		}
+	}
+
+	for _, field := range []string{"thread_name", "thread"} {
+		if name, ok := jsonData[field].(string); ok && name != "" {
+			info.ThreadName = name
+			delete(jsonData, field)
+			break
+		}
+	}
+
+	if info == (ThreadInfo{}) {
+		return nil
+	}
+	return &info
+}
+
+// attributes returns the process.pid, thread.id and thread.name attributes
+func (t *ThreadInfo) attributes() []log.KeyValue {
+	var attrs []log.KeyValue
+	if t.PID > 0 {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.ProcessPID(t.PID)))
+	}
+	if t.ThreadID > 0 {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.ThreadID(t.ThreadID)))
+	}
+	if t.ThreadName != "" {
+		attrs = append(attrs, log.KeyValueFromAttribute(semconv.ThreadName(t.ThreadName)))
+	}
+	return attrs
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestExtractThreadInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		input    string
+		expected *ThreadInfo
+	}{
+		{
+			name:     "pid and tid",
+			input:    `{"msg":"x","pid":4242,"tid":17}`,
+			expected: &ThreadInfo{PID: 4242, ThreadID: 17},
+		},
+		{
+			name:     "java thread name",
+			input:    `{"msg":"x","thread":"http-nio-8080-exec-1"}`,
+			expected: &ThreadInfo{ThreadName: "http-nio-8080-exec-1"},
+		},
+		{
+			name:     "numeric thread",
+			input:    `{"msg":"x","thread":"12"}`,
+			expected: &ThreadInfo{ThreadID: 12},
+		},
+		{
+			name:     "goroutine",
+			input:    `{"msg":"x","goroutine":931}`,
+			expected: &ThreadInfo{ThreadID: 931},
+		},
+		{
+			name:     "prefix capture groups",
+			prefix:   `^\[(?P<pid>\d+):(?P<thread>[\w-]+)\] (.*)$`,
+			input:    `[311:worker-2] {"msg":"x"}`,
+			expected: &ThreadInfo{PID: 311, ThreadName: "worker-2"},
+		},
+		{
+			name:     "json wins over prefix",
+			prefix:   `^\[(?P<pid>\d+)\] (.*)$`,
+			input:    `[311] {"msg":"x","pid":7}`,
+			expected: &ThreadInfo{PID: 7},
+		},
+		{
+			name:  "none",
+			input: `{"msg":"x","pid":"n/a"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := NewJSONExtractor(tt.prefix, getDefaultFieldMappings()).ParseLogEntry(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (entry.Thread == nil) != (tt.expected == nil) || (entry.Thread != nil && *entry.Thread != *tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, entry.Thread)
+			}
+			if entry.Message != "x" {
+				t.Errorf("Expected message x, got %q", entry.Message)
+			}
+		})
+	}
+}
+
+func TestLogProcessorThreadAttributes(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{
+		Message: "x",
+		Thread:  &ThreadInfo{PID: 4242, ThreadID: 17, ThreadName: "main"},
+	})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	values := make(map[string]log.Value)
+	records[0].WalkAttributes(func(kv log.KeyValue) bool {
+		values[kv.Key] = kv.Value
+		return true
+	})
+
+	if v := values["process.pid"]; v.Kind() != log.KindInt64 || v.AsInt64() != 4242 {
+		t.Errorf("Expected integer process.pid=4242, got %v", v)
+	}
+	if v := values["thread.id"]; v.Kind() != log.KindInt64 || v.AsInt64() != 17 {
+		t.Errorf("Expected integer thread.id=17, got %v", v)
+	}
+	if v := values["thread.name"]; v.AsString() != "main" {
+		t.Errorf("Expected thread.name=main, got %v", v)
+	}
+}
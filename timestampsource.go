@@ -0,0 +1,19 @@
+package main
+
+// timestampSourceKey tells records whose timestamp is their own from ones
+// stamped when otel-logger read them, whose order relative to other sources
+// is synthetic
+const timestampSourceKey = "log.timestamp.source"
+
+// Values of log.timestamp.source
+const (
+	timestampSourceRecord = "record" // parsed from the line
+	timestampSourceIngest = "ingest" // the line had none that parsed
+)
+
+// stampIngestTime gives an entry without a usable timestamp of its own the
+// time it was read
+func (je *JSONExtractor) stampIngestTime(entry *LogEntry) {
+	entry.Timestamp = je.now()
+	entry.TimestampSource = timestampSourceIngest
+}
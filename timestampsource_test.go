@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTimestampSource(t *testing.T) {
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	tests := []struct {
+		line     string
+		format   string
+		expected string
+	}{
+		{`{"time":"2025-01-02T15:04:05Z","msg":"parsed"}`, "", timestampSourceRecord},
+		{`{"ts":1735830245,"msg":"epoch"}`, "", timestampSourceRecord},
+		{`{"msg":"no timestamp"}`, "", timestampSourceIngest},
+		{`{"time":"yesterday","msg":"unparseable"}`, "", timestampSourceIngest},
+		{`plain text`, "", timestampSourceIngest},
+		{`<13>1 2025-01-02T15:04:05Z host app - - - parsed`, formatSyslog, timestampSourceRecord},
+		{`<13>1 - host app - - - nil timestamp`, formatSyslog, timestampSourceIngest},
+	}
+	for _, tt := range tests {
+		entry, err := extractor.ParseLogEntryAs(tt.line, tt.format)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tt.line, err)
+		}
+		if entry.TimestampSource != tt.expected {
+			t.Errorf("Expected timestamp source %q for %q, got %q", tt.expected, tt.line, entry.TimestampSource)
+		}
+	}
+}
+
+func TestLogProcessorTimestampSource(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	for _, line := range []string{`{"time":"2025-01-02T15:04:05Z","msg":"a"}`, `b`} {
+		entry, _ := extractor.ParseLogEntry(line)
+		processor.ProcessLogEntry(context.Background(), entry)
+	}
+	processor.ProcessLogEntry(context.Background(), streamClosedEarlyEntry("stdout"))
+
+	records := exporter.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	for i, expected := range []string{timestampSourceRecord, timestampSourceIngest, ""} {
+		if got := recordAttributes(records[i])[timestampSourceKey]; got != expected {
+			t.Errorf("Record %d: expected %s=%q, got %q", i, timestampSourceKey, expected, got)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tlsVersions are the --tls-min-version values
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsPinPrefix marks the hash algorithm of a --tls-pin, as in curl's
+// --pinnedpubkey; it may be left out
+const tlsPinPrefix = "sha256/"
+
+// tlsPolicy narrows the TLS of every connection otel-logger makes while
+// shipping logs: to the collector, for span events and preflight probes, to
+// BigQuery and to the schema registry. Pins are checked in addition to the
+// usual certificate verification, against every certificate the server
+// presents, so pinning an intermediate CA survives leaf rotation.
+type tlsPolicy struct {
+	minVersion   uint16
+	cipherSuites []uint16
+	pins         [][]byte
+}
+
+// newTLSPolicy parses the --tls-min-version, --tls-ciphers and --tls-pin
+// values; it returns nil when there are none
+func newTLSPolicy(minVersion string, ciphers, pins []string) (*tlsPolicy, error) {
+	p := &tlsPolicy{}
+	if minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid --tls-min-version %q (supported: 1.2, 1.3)", minVersion)
+		}
+		p.minVersion = version
+	}
+
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	for _, value := range ciphers {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown or insecure --tls-ciphers suite %q (e.g. TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384)", name)
+			}
+			if isTLS13Suite(id) {
+				return nil, fmt.Errorf("--tls-ciphers suite %q is TLS 1.3, whose suites are not configurable; list TLS 1.2 suites only", name)
+			}
+			p.cipherSuites = append(p.cipherSuites, id)
+		}
+	}
+	if len(p.cipherSuites) > 0 && p.minVersion == tls.VersionTLS13 {
+		return nil, errors.New("--tls-ciphers has no effect with --tls-min-version 1.3")
+	}
+
+	for _, value := range pins {
+		for _, pin := range strings.Split(value, ",") {
+			pin = strings.TrimPrefix(strings.TrimSpace(pin), tlsPinPrefix)
+			if pin == "" {
+				continue
+			}
+			hash, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("invalid --tls-pin %q: expected the base64 SHA-256 hash of a certificate's public key (SPKI)", pin)
+			}
+			p.pins = append(p.pins, hash)
+		}
+	}
+
+	if p.minVersion == 0 && len(p.cipherSuites) == 0 && len(p.pins) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// isTLS13Suite reports whether id is one of the TLS 1.3 suites, which
+// tls.Config.CipherSuites does not apply to
+func isTLS13Suite(id uint16) bool {
+	for _, suite := range tls.CipherSuites() {
+		if suite.ID == id {
+			return len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13
+		}
+	}
+	return false
+}
+
+// outboundTLS returns the policy configured for outbound connections; the
+// flags were validated at startup
+func outboundTLS(config *Config) *tlsPolicy {
+	p, _ := newTLSPolicy(config.TLSMinVersion, config.TLSCiphers, config.TLSPin)
+	return p
+}
+
+// apply returns a copy of cfg, which may be nil, restricted by the policy; a
+// nil policy returns cfg as it is
+func (p *tlsPolicy) apply(cfg *tls.Config) *tls.Config {
+	if p == nil {
+		return cfg
+	}
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if p.minVersion != 0 {
+		cfg.MinVersion = p.minVersion
+	}
+	if len(p.cipherSuites) > 0 {
+		cfg.CipherSuites = p.cipherSuites
+	}
+	if len(p.pins) > 0 {
+		cfg.VerifyConnection = p.verifyPins
+	}
+	return cfg
+}
+
+// verifyPins accepts a connection if a certificate of a chain the server's
+// certificate was verified with has a pinned public key. Other certificates
+// the server presented are ignored: anyone can append a public CA
+// certificate to their own chain.
+func (p *tlsPolicy) verifyPins(state tls.ConnectionState) error {
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range p.pins {
+				if subtle.ConstantTimeCompare(hash[:], pin) == 1 {
+					return nil
+				}
+			}
+		}
+	}
+	var verified []string
+	seen := make(map[string]bool)
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			pin := tlsPinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+			if !seen[pin] {
+				seen[pin] = true
+				verified = append(verified, fmt.Sprintf("%s (%s)", pin, cert.Subject.CommonName))
+			}
+		}
+	}
+	return fmt.Errorf("no verified certificate of %s matches --tls-pin; verified: %s", state.ServerName, strings.Join(verified, ", "))
+}
+
+// httpClient returns an HTTP client with the default transport settings and
+// the policy applied
+func (p *tlsPolicy) httpClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if p != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = p.apply(transport.TLSClientConfig)
+		client.Transport = transport
+	}
+	return client
+}
+
+// otlpTLSConfig loads the CA certificate and client certificate for mTLS
+// that the OTLP exporters read from the environment for signal (LOGS or
+// TRACES). Passing a TLS configuration to an exporter replaces the one it
+// would load itself, so it has to be loaded here.
+func otlpTLSConfig(signal string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_" + signal + "_CERTIFICATE", "OTEL_EXPORTER_OTLP_CERTIFICATE"} {
+		if file := os.Getenv(name); file != "" {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read OTLP certificate: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", file)
+			}
+			cfg.RootCAs = pool
+			break
+		}
+	}
+	for _, prefix := range []string{"OTEL_EXPORTER_OTLP_" + signal + "_CLIENT_", "OTEL_EXPORTER_OTLP_CLIENT_"} {
+		certFile, keyFile := os.Getenv(prefix+"CERTIFICATE"), os.Getenv(prefix+"KEY")
+		if certFile != "" && keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load OTLP client certificate: %w", err)
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+			break
+		}
+	}
+	return cfg, nil
+}
+
+// grpcCredentials returns the transport credentials for an OTLP/gRPC
+// exporter of signal under the policy, or nil when the exporter's own are
+// to be used: without a policy, or for a plaintext endpoint
+func (p *tlsPolicy) grpcCredentials(signal string, insecure bool) (credentials.TransportCredentials, error) {
+	if p == nil || insecure {
+		return nil, nil
+	}
+	cfg, err := otlpTLSConfig(signal)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(p.apply(cfg)), nil
+}
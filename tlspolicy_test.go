@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestNewTLSPolicy(t *testing.T) {
+	pin := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, 32))
+	p, err := newTLSPolicy("1.2", []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, []string{pin})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := p.apply(nil)
+	if cfg.MinVersion != tls.VersionTLS12 || len(cfg.CipherSuites) != 2 || cfg.VerifyConnection == nil {
+		t.Errorf("Unexpected TLS config %+v", cfg)
+	}
+
+	if p, err := newTLSPolicy("", nil, nil); p != nil || err != nil {
+		t.Errorf("Expected no policy without settings, got %v (%v)", p, err)
+	}
+	if cfg := (*tlsPolicy)(nil).apply(nil); cfg != nil {
+		t.Errorf("Expected a nil policy to keep the config, got %+v", cfg)
+	}
+	for _, bad := range []struct {
+		version       string
+		ciphers, pins []string
+	}{
+		{version: "1.1"},
+		{ciphers: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{ciphers: []string{"TLS_AES_128_GCM_SHA256"}},
+		{version: "1.3", ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}},
+		{pins: []string{"sha256/not-base64"}},
+		{pins: []string{base64.StdEncoding.EncodeToString([]byte("short"))}},
+	} {
+		if _, err := newTLSPolicy(bad.version, bad.ciphers, bad.pins); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+}
+
+func spkiPin(server *httptest.Server) string {
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func TestTLSPolicyConnections(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	roots := server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	get := func(version string, pins ...string) error {
+		p, err := newTLSPolicy(version, nil, pins)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		client := p.httpClient(5 * time.Second)
+		client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get("", spkiPin(server)); err != nil {
+		t.Errorf("Expected the pinned server to be accepted, got %v", err)
+	}
+	other := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	if err := get("", other); err == nil || !strings.Contains(err.Error(), "--tls-pin") {
+		t.Errorf("Expected a pin mismatch, got %v", err)
+	}
+	if err := get("", other, spkiPin(server)); err != nil {
+		t.Errorf("Expected any matching pin to be accepted, got %v", err)
+	}
+	if err := get("1.3"); err == nil {
+		t.Error("Expected a TLS 1.2 server to be refused with --tls-min-version 1.3")
+	}
+}
+
+// issueCertificate issues a certificate from template, signed by parent, or
+// self-signed if parent is nil
+func issueCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestTLSPolicyPinsVerifiedChain(t *testing.T) {
+	newCA := func(name string, serial int64) (*x509.Certificate, *ecdsa.PrivateKey) {
+		return issueCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil, nil)
+	}
+	serverCA, serverCAKey := newCA("server CA", 1)
+	pinnedCA, _ := newCA("pinned CA", 2)
+	leaf, leafKey := issueCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "server"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, serverCA, serverCAKey)
+
+	// A valid leaf from another CA, with the pinned CA's public certificate
+	// appended
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leaf.Raw, pinnedCA.Raw},
+		PrivateKey:  leafKey,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA)
+	roots.AddCert(pinnedCA)
+
+	get := func(ca *x509.Certificate) error {
+		hash := sha256.Sum256(ca.RawSubjectPublicKeyInfo)
+		p, err := newTLSPolicy("", nil, []string{"sha256/" + base64.StdEncoding.EncodeToString(hash[:])})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		client := p.httpClient(5 * time.Second)
+		client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(pinnedCA); err == nil || !strings.Contains(err.Error(), "--tls-pin") {
+		t.Errorf("Expected the appended pinned certificate to be ignored, got %v", err)
+	}
+	if err := get(serverCA); err != nil {
+		t.Errorf("Expected the CA the leaf was verified with to match, got %v", err)
+	}
+}
+
+func TestEndpointExporterTLSPolicy(t *testing.T) {
+	requests := make(chan struct{}, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+	}))
+	defer server.Close()
+
+	// The policy replaces the exporter's own TLS setup, so the CA from the
+	// environment must still be trusted
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", caFile)
+
+	u, _ := url.Parse(server.URL + "/v1/logs")
+	export := func(pin string) error {
+		config := &Config{Timeout: 5 * time.Second, TLSMinVersion: "1.2", TLSPin: []string{pin}}
+		exporter, err := newEndpointExporter(context.Background(), config, &otlpEndpoint{Protocol: "http/protobuf", URL: u}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create exporter: %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+		var record sdklog.Record
+		record.SetBody(log.StringValue("hello"))
+		return exporter.Export(context.Background(), []sdklog.Record{record})
+	}
+
+	if err := export(spkiPin(server)); err != nil {
+		t.Fatalf("Expected the export to the pinned collector to succeed, got %v", err)
+	}
+	<-requests
+	if err := export(base64.StdEncoding.EncodeToString(make([]byte, 32))); err == nil {
+		t.Error("Expected the export to fail on a pin mismatch")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names loggers use for the IDs of the span a record was written in
+var (
+	traceIDFields = []string{"trace_id", "traceId", "trace.id"}
+	spanIDFields  = []string{"span_id", "spanId", "span.id"}
+
+	// W3C Trace Context headers, as some frameworks log them
+	traceparentFields = []string{"traceparent", "traceParent", "trace_parent"}
+	tracestateFields  = []string{"tracestate", "traceState", "trace_state"}
+)
+
+var (
+	// traceparentToken finds a traceparent such as
+	// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 in free text
+	traceparentToken = regexp.MustCompile(`(?i)(?:^|[^0-9a-z-])([0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2})(?:$|[^0-9a-z-])`)
+	// tracestateToken finds a tracestate logged next to it, as
+	// tracestate=..., tracestate: ... or "tracestate":"..."
+	tracestateToken = regexp.MustCompile(`(?i)\btrace_?state"?\s*[:=]\s*"?([^"\s]+)`)
+)
+
+// TraceContext is the trace and span a record was written in, as hex IDs,
+// with the trace flags and tracestate when a traceparent carried them
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Flags   trace.TraceFlags
+	State   string
+}
+
+// extractTraceContext removes a valid traceparent field, or else valid trace
+// and span ID fields, from jsonData, along with a tracestate field. A record
+// is only correlated when both IDs are present and well-formed; other values
+// are left as ordinary attributes.
+func extractTraceContext(jsonData map[string]any) *TraceContext {
+	for _, field := range traceparentFields {
+		if s, ok := jsonData[field].(string); ok {
+			if tc := parseTraceparent(s); tc != nil {
+				delete(jsonData, field)
+				tc.State = extractTracestate(jsonData)
+				return tc
+			}
+		}
+	}
+
+	traceField, traceID := findID(jsonData, traceIDFields, func(s string) bool {
+		_, err := trace.TraceIDFromHex(s)
+		return err == nil
+	})
+	spanField, spanID := findID(jsonData, spanIDFields, func(s string) bool {
+		_, err := trace.SpanIDFromHex(s)
+		return err == nil
+	})
+	if traceField == "" || spanField == "" {
+		return nil
+	}
+
+	delete(jsonData, traceField)
+	delete(jsonData, spanField)
+	return &TraceContext{TraceID: traceID, SpanID: spanID, State: extractTracestate(jsonData)}
+}
+
+// extractTracestate removes a valid tracestate field from jsonData
+func extractTracestate(jsonData map[string]any) string {
+	for _, field := range tracestateFields {
+		if s, ok := jsonData[field].(string); ok {
+			if _, err := trace.ParseTraceState(s); err == nil {
+				delete(jsonData, field)
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// parseTraceparent decodes a W3C traceparent header value,
+// version-traceid-spanid-flags. Versions after 00 may append fields, which
+// are ignored; version ff and all-zero IDs are invalid.
+func parseTraceparent(s string) *TraceContext {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[3]) != 2 {
+		return nil
+	}
+	version, err := strconv.ParseUint(parts[0], 16, 8)
+	if err != nil || version == 0xff || version == 0 && len(parts) != 4 {
+		return nil
+	}
+	if _, err := trace.TraceIDFromHex(parts[1]); err != nil {
+		return nil
+	}
+	if _, err := trace.SpanIDFromHex(parts[2]); err != nil {
+		return nil
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil
+	}
+	return &TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: trace.TraceFlags(flags)}
+}
+
+// inlineTraceContext finds a traceparent token, and a tracestate logged with
+// it, in free text such as a plain message or the text of a logfmt line
+func inlineTraceContext(text string) *TraceContext {
+	for _, m := range traceparentToken.FindAllStringSubmatch(text, -1) {
+		if tc := parseTraceparent(m[1]); tc != nil {
+			if s := tracestateToken.FindStringSubmatch(text); s != nil {
+				if _, err := trace.ParseTraceState(s[1]); err == nil {
+					tc.State = s[1]
+				}
+			}
+			return tc
+		}
+	}
+	return nil
+}
+
+func findID(jsonData map[string]any, fields []string, valid func(string) bool) (string, string) {
+	for _, field := range fields {
+		if s, ok := jsonData[field].(string); ok {
+			s = strings.ToLower(s)
+			if valid(s) {
+				return field, s
+			}
+		}
+	}
+	return "", ""
+}
+
+// spanContext returns the remote span context, or an invalid one if the IDs
+// do not parse
+func (tc *TraceContext) spanContext() trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex(tc.TraceID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	spanID, err := trace.SpanIDFromHex(tc.SpanID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	// An invalid tracestate is dropped rather than losing the IDs
+	state, _ := trace.ParseTraceState(tc.State)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: tc.Flags,
+		TraceState: state,
+		Remote:     true,
+	})
+}
+
+// contextWithTrace returns ctx carrying the entry's span context, which the
+// SDK copies onto the emitted record
+func contextWithTrace(ctx context.Context, entry *LogEntry) context.Context {
+	if entry.Trace == nil {
+		return ctx
+	}
+	if sc := entry.Trace.spanContext(); sc.IsValid() {
+		return trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
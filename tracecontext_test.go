@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtractTraceContext(t *testing.T) {
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+
+	entry, err := extractor.ParseLogEntry(`{"msg":"x","trace_id":"4BF92F3577B34DA6A3CE929D0E0E4736","span_id":"00f067aa0ba902b7"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	if entry.Trace == nil || *entry.Trace != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, entry.Trace)
+	}
+	if _, ok := entry.Fields["trace_id"]; ok {
+		t.Error("Expected trace_id to be consumed")
+	}
+
+	// Malformed or incomplete IDs stay ordinary attributes
+	for _, line := range []string{
+		`{"msg":"x","trace_id":"not-a-trace","span_id":"00f067aa0ba902b7"}`,
+		`{"msg":"x","traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}`,
+		`{"msg":"x","trace_id":"00000000000000000000000000000000","span_id":"00f067aa0ba902b7"}`,
+	} {
+		entry, _ := extractor.ParseLogEntry(line)
+		if entry.Trace != nil {
+			t.Errorf("Expected no trace context for %s, got %+v", line, entry.Trace)
+		}
+		if len(entry.Fields) == 0 {
+			t.Errorf("Expected the ID fields to be kept for %s", line)
+		}
+	}
+}
+
+func TestLogProcessorCorrelatesRecordsWithTrace(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{
+		Message: "x",
+		Trace:   &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+	})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if got := records[0].TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected record trace ID, got %s", got)
+	}
+	if got := records[0].SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected record span ID, got %s", got)
+	}
+}
+
+func TestExtractTraceparent(t *testing.T) {
+	extractor := NewJSONExtractor("", getDefaultFieldMappings())
+	expected := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: trace.FlagsSampled, State: "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"}
+
+	entry, err := extractor.ParseLogEntry(`{"msg":"x","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01","tracestate":"rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Trace == nil || *entry.Trace != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, entry.Trace)
+	}
+	if len(entry.Fields) != 0 {
+		t.Errorf("Expected traceparent and tracestate to be consumed, got %v", entry.Fields)
+	}
+
+	for _, line := range []string{
+		`handled request traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 tracestate=rojo=00f067aa0ba902b7,congo=t61rcWkgMzE`,
+		`{"msg":"handled request traceparent: 00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01 tracestate: rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"}`,
+	} {
+		entry, _ := extractor.ParseLogEntry(line)
+		if entry.Trace == nil || *entry.Trace != expected {
+			t.Errorf("Expected %+v from %s, got %+v", expected, line, entry.Trace)
+		}
+	}
+
+	// Invalid versions, IDs and field counts are not trace contexts
+	for _, value := range []string{
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+	} {
+		if tc := parseTraceparent(value); tc != nil {
+			t.Errorf("Expected %s to be rejected, got %+v", value, tc)
+		}
+	}
+	if tc := parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); tc == nil {
+		t.Error("Expected a later version to allow extra fields")
+	}
+}
+
+func TestLogProcessorCarriesTraceFlags(t *testing.T) {
+	provider, exporter := newRecordingProvider()
+	processor := NewLogProcessor(provider.Logger("test"))
+
+	processor.ProcessLogEntry(context.Background(), &LogEntry{
+		Message: "x",
+		Trace:   &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: trace.FlagsSampled, State: "rojo=1"},
+	})
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if !records[0].TraceFlags().IsSampled() {
+		t.Error("Expected the sampled flag to be carried")
+	}
+}